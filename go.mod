@@ -1,15 +1,22 @@
 module gopq
 
-go 1.24.3
+go 1.25.0
 
 require (
-	github.com/cloudflare/circl v1.6.1
+	filippo.io/age v1.3.1
+	github.com/ProtonMail/go-crypto v1.5.2
+	github.com/cloudflare/circl v1.6.3
+	github.com/go-jose/go-jose/v4 v4.1.4
+	github.com/katzenpost/circl v1.3.9-0.20240222183521-1cd9a34e9a0c
+	github.com/katzenpost/hpqc v0.0.9
 	github.com/stretchr/testify v1.10.0
+	golang.org/x/crypto v0.45.0
 )
 
 require (
+	filippo.io/hpke v0.4.0 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/sys v0.38.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
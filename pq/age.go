@@ -0,0 +1,210 @@
+package pq
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"filippo.io/age"
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+	"github.com/cloudflare/circl/kem/xwing"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// age's hybrid ML-KEM-768+X25519 recipient type wraps the 16-byte file key of a file with HPKE over MLKEM768-X25519,
+// which is X-Wing, HKDF-SHA256 and ChaCha20-Poly1305, in a stanza:
+//
+//	-> mlkem768x25519 <base64 encapsulated key>
+//	<base64 HPKE ciphertext of the file key>
+//
+// Recipients are Bech32 encoded with the "age1pq" prefix and identities with "AGE-SECRET-KEY-PQ-". The key material is
+// the X-Wing public key and the 32-byte X-Wing private key of this package.
+
+const (
+	// ageHybridLabel is the HPKE info of the hybrid recipient type.
+	ageHybridLabel = "age-encryption.org/mlkem768x25519"
+	// ageHybridStanzaType is the stanza type of the hybrid recipient type.
+	ageHybridStanzaType = "mlkem768x25519"
+	// ageRecipientPrefix is the Bech32 human-readable part of a hybrid recipient.
+	ageRecipientPrefix = "age1pq"
+	// ageIdentityPrefix is the Bech32 human-readable part of a hybrid identity.
+	ageIdentityPrefix = "AGE-SECRET-KEY-PQ-"
+	// ageFileKeySize is the size of an age file key.
+	ageFileKeySize = 16
+	// agePostQuantumLabel is the label that keeps age from mixing the recipient with classical ones.
+	agePostQuantumLabel = "postquantum"
+)
+
+// ageHybridSuite is the HPKE suite of the hybrid recipient type.
+var ageHybridSuite = HPKESuite{KEM: HPKEKEMXWing, KDF: HPKEKDFHKDFSHA256, AEAD: HPKEAEADChaCha20Poly1305}
+
+// AgeRecipient is an age recipient of the hybrid ML-KEM-768+X25519 type, which is compatible with age's
+// HybridRecipient. It implements age.Recipient and age.RecipientWithLabels.
+type AgeRecipient struct {
+	publicKey kem.PublicKey
+}
+
+var _ age.RecipientWithLabels = (*AgeRecipient)(nil)
+
+// NewAgeRecipient returns the age recipient of an X-Wing public key.
+func NewAgeRecipient(publicKey kem.PublicKey) (*AgeRecipient, error) {
+	if publicKey == nil || publicKey.Scheme() != xwing.Scheme() {
+		return nil, fmt.Errorf("%w: age recipient needs an X-Wing public key", ErrInvalidKey)
+	}
+	publicKeyBytes, err := publicKey.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateEncapsulationKey(MLKEM768, publicKeyBytes[:mlkem768.PublicKeySize]); err != nil {
+		return nil, err
+	}
+	return &AgeRecipient{publicKey: publicKey}, nil
+}
+
+// ParseAgeRecipient parses the Bech32 encoding of a recipient, which starts with "age1pq1".
+func ParseAgeRecipient(encoded string) (*AgeRecipient, error) {
+	humanReadablePart, publicKeyBytes, err := bech32Decode(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("malformed age recipient %q: %w", encoded, err)
+	}
+	if humanReadablePart != ageRecipientPrefix {
+		return nil, fmt.Errorf("malformed age recipient %q: type %q is not %q", encoded, humanReadablePart, ageRecipientPrefix)
+	}
+	publicKey, err := UnmarshalHPKEPublicKey(HPKEKEMXWing, publicKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("malformed age recipient %q: %w", encoded, err)
+	}
+	return NewAgeRecipient(publicKey)
+}
+
+// PublicKey returns the X-Wing public key of the recipient.
+func (recipient *AgeRecipient) PublicKey() kem.PublicKey {
+	return recipient.publicKey
+}
+
+// Wrap wraps a file key for the recipient.
+func (recipient *AgeRecipient) Wrap(fileKey []byte) ([]*age.Stanza, error) {
+	stanzas, _, err := recipient.WrapWithLabels(fileKey)
+	return stanzas, err
+}
+
+// WrapWithLabels wraps a file key for the recipient, with the "postquantum" label so that age refuses to mix it with
+// recipients that would defeat its post-quantum security.
+func (recipient *AgeRecipient) WrapWithLabels(fileKey []byte) ([]*age.Stanza, []string, error) {
+	encapsulatedKey, wrappedKey, err := HPKESeal(ageHybridSuite, recipient.publicKey, []byte(ageHybridLabel), nil, fileKey, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap age file key: %w", err)
+	}
+	stanza := &age.Stanza{
+		Type: ageHybridStanzaType,
+		Args: []string{base64.RawStdEncoding.EncodeToString(encapsulatedKey)},
+		Body: wrappedKey,
+	}
+	return []*age.Stanza{stanza}, []string{agePostQuantumLabel}, nil
+}
+
+// String returns the Bech32 encoding of the recipient.
+func (recipient *AgeRecipient) String() string {
+	publicKeyBytes, _ := recipient.publicKey.MarshalBinary()
+	encoded, _ := bech32Encode(ageRecipientPrefix, publicKeyBytes)
+	return encoded
+}
+
+// AgeIdentity is an age identity of the hybrid ML-KEM-768+X25519 type, which is compatible with age's HybridIdentity.
+// It implements age.Identity.
+type AgeIdentity struct {
+	privateKey kem.PrivateKey
+}
+
+var _ age.Identity = (*AgeIdentity)(nil)
+
+// NewAgeIdentity returns the age identity of an X-Wing private key.
+func NewAgeIdentity(privateKey kem.PrivateKey) (*AgeIdentity, error) {
+	if privateKey == nil || privateKey.Scheme() != xwing.Scheme() {
+		return nil, fmt.Errorf("%w: age identity needs an X-Wing private key", ErrInvalidKey)
+	}
+	return &AgeIdentity{privateKey: privateKey}, nil
+}
+
+// GenerateAgeIdentity generates an age identity with a new X-Wing key pair.
+func GenerateAgeIdentity() (*AgeIdentity, error) {
+	keyPair, err := GenerateXWingKeyPair()
+	if err != nil {
+		return nil, err
+	}
+	return NewAgeIdentity(keyPair.PrivateKey)
+}
+
+// ParseAgeIdentity parses the Bech32 encoding of an identity, which starts with "AGE-SECRET-KEY-PQ-1". Errors do not
+// include the encoding, which is secret.
+func ParseAgeIdentity(encoded string) (*AgeIdentity, error) {
+	humanReadablePart, privateKeyBytes, err := bech32Decode(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("malformed age identity: %w", err)
+	}
+	if humanReadablePart != ageIdentityPrefix {
+		return nil, fmt.Errorf("malformed age identity: type %q is not %q", humanReadablePart, ageIdentityPrefix)
+	}
+	privateKey, err := UnmarshalHPKEPrivateKey(HPKEKEMXWing, privateKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("malformed age identity: %w", err)
+	}
+	return NewAgeIdentity(privateKey)
+}
+
+// PrivateKey returns the X-Wing private key of the identity.
+func (identity *AgeIdentity) PrivateKey() kem.PrivateKey {
+	return identity.privateKey
+}
+
+// Recipient returns the recipient of the identity.
+func (identity *AgeIdentity) Recipient() *AgeRecipient {
+	return &AgeRecipient{publicKey: identity.privateKey.Public()}
+}
+
+// Unwrap returns the file key from the first hybrid stanza the identity can open. It returns age.ErrIncorrectIdentity
+// if there is none, and an error for a malformed hybrid stanza.
+func (identity *AgeIdentity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	for _, stanza := range stanzas {
+		fileKey, err := identity.unwrap(stanza)
+		if errors.Is(err, age.ErrIncorrectIdentity) {
+			continue
+		}
+		return fileKey, err
+	}
+	return nil, age.ErrIncorrectIdentity
+}
+
+// unwrap opens one stanza.
+func (identity *AgeIdentity) unwrap(stanza *age.Stanza) ([]byte, error) {
+	if stanza.Type != ageHybridStanzaType {
+		return nil, age.ErrIncorrectIdentity
+	}
+	if len(stanza.Args) != 1 {
+		return nil, fmt.Errorf("invalid %s stanza: %d arguments", ageHybridStanzaType, len(stanza.Args))
+	}
+	encapsulatedKey, err := base64.RawStdEncoding.Strict().DecodeString(stanza.Args[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s stanza: %w", ageHybridStanzaType, err)
+	}
+	if len(encapsulatedKey) != XWingCiphertextSize {
+		return nil, fmt.Errorf("invalid %s stanza: %w", ageHybridStanzaType, newSizeError(ErrInvalidCiphertext, "encapsulated key", len(encapsulatedKey), XWingCiphertextSize))
+	}
+	if len(stanza.Body) != ageFileKeySize+chacha20poly1305.Overhead {
+		return nil, fmt.Errorf("invalid %s stanza: %w", ageHybridStanzaType, newSizeError(ErrInvalidCiphertext, "wrapped file key", len(stanza.Body), ageFileKeySize+chacha20poly1305.Overhead))
+	}
+	fileKey, err := HPKEOpen(ageHybridSuite, identity.privateKey, encapsulatedKey, []byte(ageHybridLabel), nil, stanza.Body, nil)
+	if errors.Is(err, ErrDecryptionFailed) {
+		// X-Wing rejects implicitly, so a stanza for another key only fails here.
+		return nil, age.ErrIncorrectIdentity
+	}
+	return fileKey, err
+}
+
+// String returns the Bech32 encoding of the identity, which is secret.
+func (identity *AgeIdentity) String() string {
+	privateKeyBytes, _ := identity.privateKey.MarshalBinary()
+	encoded, _ := bech32Encode(ageIdentityPrefix, privateKeyBytes)
+	return encoded
+}
@@ -0,0 +1,69 @@
+package pq
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func BenchmarkAgeEncrypt(b *testing.B) {
+	identity, err := GenerateAgeIdentity()
+	if err != nil {
+		b.Fatalf("failed to generate age identity: %v", err)
+	}
+	plaintext := make([]byte, 1024)
+	for b.Loop() {
+		writer, err := age.Encrypt(io.Discard, identity.Recipient())
+		if err != nil {
+			b.Fatalf("failed to encrypt: %v", err)
+		}
+		if _, err := writer.Write(plaintext); err != nil {
+			b.Fatalf("failed to write: %v", err)
+		}
+		if err := writer.Close(); err != nil {
+			b.Fatalf("failed to close: %v", err)
+		}
+	}
+}
+
+func BenchmarkAgeDecrypt(b *testing.B) {
+	identity, err := GenerateAgeIdentity()
+	if err != nil {
+		b.Fatalf("failed to generate age identity: %v", err)
+	}
+	var encrypted bytes.Buffer
+	writer, err := age.Encrypt(&encrypted, identity.Recipient())
+	if err != nil {
+		b.Fatalf("failed to encrypt: %v", err)
+	}
+	if _, err := writer.Write(make([]byte, 1024)); err != nil {
+		b.Fatalf("failed to write: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		b.Fatalf("failed to close: %v", err)
+	}
+	for b.Loop() {
+		reader, err := age.Decrypt(bytes.NewReader(encrypted.Bytes()), identity)
+		if err != nil {
+			b.Fatalf("failed to decrypt: %v", err)
+		}
+		if _, err := io.Copy(io.Discard, reader); err != nil {
+			b.Fatalf("failed to read: %v", err)
+		}
+	}
+}
+
+func BenchmarkParseAgeRecipient(b *testing.B) {
+	identity, err := GenerateAgeIdentity()
+	if err != nil {
+		b.Fatalf("failed to generate age identity: %v", err)
+	}
+	encoded := identity.Recipient().String()
+	for b.Loop() {
+		if _, err := ParseAgeRecipient(encoded); err != nil {
+			b.Fatalf("failed to parse age recipient: %v", err)
+		}
+	}
+}
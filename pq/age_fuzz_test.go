@@ -0,0 +1,55 @@
+package pq
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func FuzzParseAgeRecipient(f *testing.F) {
+	identity, _ := GenerateAgeIdentity()
+	f.Add(identity.Recipient().String())
+	f.Add(identity.String())
+	f.Add("age1pq1")
+	f.Fuzz(func(t *testing.T, encoded string) {
+		recipient, err := ParseAgeRecipient(encoded)
+		if err != nil {
+			return
+		}
+		if recipient.String() != encoded {
+			t.Errorf("recipient %q encodes as %q", encoded, recipient.String())
+		}
+		if _, err := age.ParseHybridRecipient(encoded); err != nil {
+			t.Errorf("age rejects recipient %q: %v", encoded, err)
+		}
+	})
+}
+
+func FuzzParseAgeIdentity(f *testing.F) {
+	identity, _ := GenerateAgeIdentity()
+	f.Add(identity.String())
+	f.Add(identity.Recipient().String())
+	f.Fuzz(func(t *testing.T, encoded string) {
+		parsedIdentity, err := ParseAgeIdentity(encoded)
+		if err != nil {
+			return
+		}
+		if parsedIdentity.String() != encoded {
+			t.Errorf("identity encodes differently")
+		}
+	})
+}
+
+func FuzzAgeUnwrap(f *testing.F) {
+	identity, _ := GenerateAgeIdentity()
+	stanzas, _ := identity.Recipient().Wrap([]byte("0123456789abcdef"))
+	f.Add(stanzas[0].Args[0], stanzas[0].Body)
+	f.Add("", []byte{})
+	f.Fuzz(func(t *testing.T, argument string, body []byte) {
+		fileKey, err := identity.Unwrap([]*age.Stanza{{Type: ageHybridStanzaType, Args: []string{argument}, Body: body}})
+		if err == nil && !bytes.Equal(fileKey, []byte("0123456789abcdef")) {
+			t.Errorf("unwrapped a forged stanza: %x", fileKey)
+		}
+	})
+}
@@ -0,0 +1,271 @@
+package pq
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/stretchr/testify/require"
+)
+
+// For the age fixture tests: testdata/age holds files made with the reference age v1.3.1 tools and with this package.
+//   - age-identity.txt and age-recipient.txt were made by age-keygen -pq, and age-encrypted.age and
+//     age-encrypted-armored.age by age -R age-recipient.txt [-a] plaintext.txt.
+//   - gopq-identity.txt, gopq-recipient.txt and gopq-encrypted.age were made by GenerateAgeIdentity and age.Encrypt with
+//     an AgeRecipient, and checked with age -d -i gopq-identity.txt and age-keygen -y gopq-identity.txt.
+const ageTestDirectory = "testdata/age/"
+
+// readAgeTestKey returns the last line of a key file that is not a comment.
+func readAgeTestKey(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(ageTestDirectory + name)
+	require.NoError(t, err)
+	var key string
+	for line := range strings.Lines(string(data)) {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			key = line
+		}
+	}
+	require.NotEmpty(t, key, name)
+	return key
+}
+
+// decryptAgeTestFile decrypts a file of testdata/age, armored or not, with the identities.
+func decryptAgeTestFile(t *testing.T, name string, identities ...age.Identity) ([]byte, error) {
+	t.Helper()
+	encrypted, err := os.ReadFile(ageTestDirectory + name)
+	require.NoError(t, err)
+	var source io.Reader = bytes.NewReader(encrypted)
+	if bytes.HasPrefix(encrypted, []byte(armor.Header)) {
+		source = armor.NewReader(source)
+	}
+	decrypted, err := age.Decrypt(source, identities...)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(decrypted)
+}
+
+func TestAgeDecryptsReferenceFiles(t *testing.T) {
+	plaintext, err := os.ReadFile(ageTestDirectory + "plaintext.txt")
+	require.NoError(t, err)
+	identity, err := ParseAgeIdentity(readAgeTestKey(t, "age-identity.txt"))
+	require.NoError(t, err)
+	require.Equal(t, readAgeTestKey(t, "age-identity.txt"), identity.String())
+	require.Equal(t, readAgeTestKey(t, "age-recipient.txt"), identity.Recipient().String())
+
+	for _, name := range []string{"age-encrypted.age", "age-encrypted-armored.age"} {
+		decrypted, err := decryptAgeTestFile(t, name, identity)
+		require.NoError(t, err, name)
+		require.Equal(t, plaintext, decrypted, name)
+	}
+}
+
+func TestAgeReferenceDecryptsFiles(t *testing.T) {
+	plaintext, err := os.ReadFile(ageTestDirectory + "plaintext.txt")
+	require.NoError(t, err)
+	identityFile, err := os.Open(ageTestDirectory + "gopq-identity.txt")
+	require.NoError(t, err)
+	defer identityFile.Close()
+	referenceIdentities, err := age.ParseIdentities(identityFile)
+	require.NoError(t, err)
+	require.Len(t, referenceIdentities, 1)
+	referenceIdentity, ok := referenceIdentities[0].(*age.HybridIdentity)
+	require.True(t, ok)
+	require.Equal(t, readAgeTestKey(t, "gopq-recipient.txt"), referenceIdentity.Recipient().String())
+
+	decrypted, err := decryptAgeTestFile(t, "gopq-encrypted.age", referenceIdentity)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, decrypted)
+
+	identity, err := ParseAgeIdentity(readAgeTestKey(t, "gopq-identity.txt"))
+	require.NoError(t, err)
+	decrypted, err = decryptAgeTestFile(t, "gopq-encrypted.age", identity)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, decrypted)
+}
+
+// For TestAgeInterop: fresh keys encrypt with one implementation and decrypt with the other, and the Bech32 encodings
+// parse in both.
+func TestAgeInterop(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("age interop "), 10000)
+	encrypt := func(recipients ...age.Recipient) []byte {
+		var encrypted bytes.Buffer
+		writer, err := age.Encrypt(&encrypted, recipients...)
+		require.NoError(t, err)
+		_, err = writer.Write(plaintext)
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+		return encrypted.Bytes()
+	}
+	decrypt := func(encrypted []byte, identities ...age.Identity) []byte {
+		decrypted, err := age.Decrypt(bytes.NewReader(encrypted), identities...)
+		require.NoError(t, err)
+		data, err := io.ReadAll(decrypted)
+		require.NoError(t, err)
+		return data
+	}
+
+	identity, err := GenerateAgeIdentity()
+	require.NoError(t, err)
+	referenceIdentity, err := age.ParseHybridIdentity(identity.String())
+	require.NoError(t, err)
+	referenceRecipient, err := age.ParseHybridRecipient(identity.Recipient().String())
+	require.NoError(t, err)
+	require.Equal(t, identity.Recipient().String(), referenceIdentity.Recipient().String())
+	require.Equal(t, plaintext, decrypt(encrypt(identity.Recipient()), referenceIdentity))
+	require.Equal(t, plaintext, decrypt(encrypt(referenceRecipient), identity))
+
+	generatedIdentity, err := age.GenerateHybridIdentity()
+	require.NoError(t, err)
+	parsedIdentity, err := ParseAgeIdentity(generatedIdentity.String())
+	require.NoError(t, err)
+	parsedRecipient, err := ParseAgeRecipient(generatedIdentity.Recipient().String())
+	require.NoError(t, err)
+	require.Equal(t, generatedIdentity.String(), parsedIdentity.String())
+	require.Equal(t, plaintext, decrypt(encrypt(parsedRecipient), generatedIdentity))
+	require.Equal(t, plaintext, decrypt(encrypt(generatedIdentity.Recipient()), parsedIdentity))
+
+	require.Equal(t, plaintext, decrypt(encrypt(identity.Recipient(), parsedRecipient), parsedIdentity))
+}
+
+func TestAgeKeys(t *testing.T) {
+	keyPair, err := GenerateXWingKeyPair()
+	require.NoError(t, err)
+	identity, err := NewAgeIdentity(keyPair.PrivateKey)
+	require.NoError(t, err)
+	require.True(t, keyPair.PrivateKey.Equal(identity.PrivateKey()))
+	require.True(t, keyPair.PublicKey.Equal(identity.Recipient().PublicKey()))
+	recipient, err := NewAgeRecipient(keyPair.PublicKey)
+	require.NoError(t, err)
+	require.Equal(t, identity.Recipient().String(), recipient.String())
+	require.True(t, strings.HasPrefix(recipient.String(), "age1pq1"))
+	require.True(t, strings.HasPrefix(identity.String(), "AGE-SECRET-KEY-PQ-1"))
+
+	mlkemKeyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	require.NoError(t, err)
+	_, err = NewAgeRecipient(mlkemKeyPair.PublicKey)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = NewAgeIdentity(mlkemKeyPair.PrivateKey)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = NewAgeRecipient(nil)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = NewAgeIdentity(nil)
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestAgeParseErrors(t *testing.T) {
+	identity, err := GenerateAgeIdentity()
+	require.NoError(t, err)
+	recipientString := identity.Recipient().String()
+	identityString := identity.String()
+	classicIdentity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	truncatedRecipient, err := bech32Encode(ageRecipientPrefix, make([]byte, 1000))
+	require.NoError(t, err)
+	truncatedIdentity, err := bech32Encode(ageIdentityPrefix, make([]byte, 31))
+	require.NoError(t, err)
+
+	for _, encoded := range []string{
+		"",
+		recipientString[:len(recipientString)-1],
+		recipientString[:10] + strings.ToUpper(recipientString[10:]),
+		strings.Replace(recipientString, "q", "p", 1),
+		classicIdentity.Recipient().String(),
+		identityString,
+		truncatedRecipient,
+	} {
+		_, err := ParseAgeRecipient(encoded)
+		require.Error(t, err, encoded)
+	}
+	for _, encoded := range []string{
+		"",
+		identityString[:len(identityString)-1],
+		strings.ToLower(identityString[:30]) + identityString[30:],
+		classicIdentity.String(),
+		recipientString,
+		truncatedIdentity,
+	} {
+		_, err := ParseAgeIdentity(encoded)
+		require.Error(t, err)
+		require.NotContains(t, err.Error(), identityString[20:])
+	}
+
+	_, err = ParseAgeRecipient(strings.ToUpper(recipientString))
+	require.Error(t, err)
+	parsed, err := ParseAgeIdentity(strings.ToLower(identityString))
+	require.Error(t, err, "the identity prefix is uppercase")
+	require.Nil(t, parsed)
+}
+
+// For TestAgeUnwrap: an identity skips stanzas of other types and keys with age.ErrIncorrectIdentity, and rejects a
+// malformed hybrid stanza with an error of its own.
+func TestAgeUnwrap(t *testing.T) {
+	identity, err := GenerateAgeIdentity()
+	require.NoError(t, err)
+	otherIdentity, err := GenerateAgeIdentity()
+	require.NoError(t, err)
+	fileKey := []byte("0123456789abcdef")
+	stanzas, labels, err := identity.Recipient().WrapWithLabels(fileKey)
+	require.NoError(t, err)
+	require.Equal(t, []string{"postquantum"}, labels)
+	require.Len(t, stanzas, 1)
+	require.Equal(t, "mlkem768x25519", stanzas[0].Type)
+
+	classicIdentity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	classicStanzas, err := classicIdentity.Recipient().Wrap(fileKey)
+	require.NoError(t, err)
+	otherStanzas, err := otherIdentity.Recipient().Wrap(fileKey)
+	require.NoError(t, err)
+
+	unwrapped, err := identity.Unwrap(append(append(classicStanzas, otherStanzas...), stanzas...))
+	require.NoError(t, err)
+	require.Equal(t, fileKey, unwrapped)
+	_, err = identity.Unwrap(append(classicStanzas, otherStanzas...))
+	require.ErrorIs(t, err, age.ErrIncorrectIdentity)
+	_, err = identity.Unwrap(nil)
+	require.ErrorIs(t, err, age.ErrIncorrectIdentity)
+
+	testCases := []struct {
+		name   string
+		modify func(stanza *age.Stanza)
+	}{
+		{"no arguments", func(stanza *age.Stanza) { stanza.Args = nil }},
+		{"two arguments", func(stanza *age.Stanza) { stanza.Args = append(stanza.Args, "AAAA") }},
+		{"padded argument", func(stanza *age.Stanza) { stanza.Args[0] += "==" }},
+		{"short encapsulated key", func(stanza *age.Stanza) { stanza.Args[0] = base64.RawStdEncoding.EncodeToString(make([]byte, 32)) }},
+		{"short body", func(stanza *age.Stanza) { stanza.Body = stanza.Body[:31] }},
+		{"long body", func(stanza *age.Stanza) { stanza.Body = append(stanza.Body, 0) }},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			stanza := &age.Stanza{Type: stanzas[0].Type, Args: append([]string(nil), stanzas[0].Args...), Body: append([]byte(nil), stanzas[0].Body...)}
+			testCase.modify(stanza)
+			_, err := identity.Unwrap([]*age.Stanza{stanza})
+			require.Error(t, err)
+			require.False(t, errors.Is(err, age.ErrIncorrectIdentity), "%v", err)
+		})
+	}
+
+	tampered := &age.Stanza{Type: stanzas[0].Type, Args: stanzas[0].Args, Body: append([]byte(nil), stanzas[0].Body...)}
+	tampered.Body[0] ^= 1
+	_, err = identity.Unwrap([]*age.Stanza{tampered})
+	require.ErrorIs(t, err, age.ErrIncorrectIdentity)
+}
+
+// For TestAgePostQuantumLabel: age refuses to mix the hybrid recipient with a classical one.
+func TestAgePostQuantumLabel(t *testing.T) {
+	identity, err := GenerateAgeIdentity()
+	require.NoError(t, err)
+	classicIdentity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	_, err = age.Encrypt(io.Discard, identity.Recipient(), classicIdentity.Recipient())
+	require.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package pq
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Bech32 (BIP 173) as used by age for recipients and identities. Unlike BIP 173 it has no length limit, since age keys
+// are far longer than 90 characters.
+
+// bech32Charset maps 5-bit values to characters.
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Generator is the generator of the BCH checksum code.
+var bech32Generator = [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+// bech32Polymod returns the BCH checksum of 5-bit values.
+func bech32Polymod(values []byte) uint32 {
+	checksum := uint32(1)
+	for _, value := range values {
+		top := checksum >> 25
+		checksum = (checksum&0x1ffffff)<<5 ^ uint32(value)
+		for i, generator := range bech32Generator {
+			if top>>i&1 == 1 {
+				checksum ^= generator
+			}
+		}
+	}
+	return checksum
+}
+
+// bech32ChecksumValues returns the lowercase human-readable part expanded to 5-bit values, followed by data.
+func bech32ChecksumValues(humanReadablePart string, data []byte) []byte {
+	humanReadablePart = strings.ToLower(humanReadablePart)
+	values := make([]byte, 0, 2*len(humanReadablePart)+1+len(data)+6)
+	for i := range len(humanReadablePart) {
+		values = append(values, humanReadablePart[i]>>5)
+	}
+	values = append(values, 0)
+	for i := range len(humanReadablePart) {
+		values = append(values, humanReadablePart[i]&31)
+	}
+	return append(values, data...)
+}
+
+// bech32ConvertBits regroups data from groups of fromBits bits into groups of toBits bits. Without padding, leftover
+// bits must be fewer than fromBits and zero.
+func bech32ConvertBits(data []byte, fromBits uint, toBits uint, pad bool) ([]byte, error) {
+	var converted []byte
+	accumulator, bits := uint32(0), uint(0)
+	mask := uint32(1)<<toBits - 1
+	for _, value := range data {
+		if value>>fromBits != 0 {
+			return nil, fmt.Errorf("invalid %d-bit value %d", fromBits, value)
+		}
+		accumulator = accumulator<<fromBits | uint32(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			converted = append(converted, byte(accumulator>>bits&mask))
+		}
+	}
+	switch {
+	case pad && bits > 0:
+		converted = append(converted, byte(accumulator<<(toBits-bits)&mask))
+	case !pad && bits >= fromBits:
+		return nil, errors.New("illegal zero padding")
+	case !pad && accumulator<<(toBits-bits)&mask != 0:
+		return nil, errors.New("non-zero padding")
+	}
+	return converted, nil
+}
+
+// bech32Encode encodes data with the human-readable part. The result is uppercase if humanReadablePart is.
+func bech32Encode(humanReadablePart string, data []byte) (string, error) {
+	if len(humanReadablePart) == 0 {
+		return "", errors.New("empty human-readable part")
+	}
+	for i := range len(humanReadablePart) {
+		if humanReadablePart[i] < 33 || humanReadablePart[i] > 126 {
+			return "", fmt.Errorf("invalid human-readable part character %q", humanReadablePart[i])
+		}
+	}
+	lowercase := strings.ToLower(humanReadablePart)
+	if humanReadablePart != lowercase && humanReadablePart != strings.ToUpper(humanReadablePart) {
+		return "", fmt.Errorf("mixed case human-readable part %q", humanReadablePart)
+	}
+	values, err := bech32ConvertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	checksum := bech32Polymod(append(bech32ChecksumValues(lowercase, values), 0, 0, 0, 0, 0, 0)) ^ 1
+	var encoded strings.Builder
+	encoded.WriteString(lowercase)
+	encoded.WriteByte('1')
+	for _, value := range values {
+		encoded.WriteByte(bech32Charset[value])
+	}
+	for i := range 6 {
+		encoded.WriteByte(bech32Charset[checksum>>(5*(5-i))&31])
+	}
+	if humanReadablePart != lowercase {
+		return strings.ToUpper(encoded.String()), nil
+	}
+	return encoded.String(), nil
+}
+
+// bech32Decode decodes a Bech32 string into its human-readable part, uppercase if the string is, and data.
+func bech32Decode(encoded string) (string, []byte, error) {
+	if encoded != strings.ToLower(encoded) && encoded != strings.ToUpper(encoded) {
+		return "", nil, errors.New("mixed case")
+	}
+	separator := strings.LastIndexByte(encoded, '1')
+	if separator < 1 || separator+7 > len(encoded) {
+		return "", nil, errors.New("missing or misplaced separator")
+	}
+	humanReadablePart := encoded[:separator]
+	for i := range len(humanReadablePart) {
+		if humanReadablePart[i] < 33 || humanReadablePart[i] > 126 {
+			return "", nil, fmt.Errorf("invalid human-readable part character %q", humanReadablePart[i])
+		}
+	}
+	dataPart := strings.ToLower(encoded[separator+1:])
+	values := make([]byte, len(dataPart))
+	for i := range len(dataPart) {
+		value := strings.IndexByte(bech32Charset, dataPart[i])
+		if value < 0 {
+			return "", nil, fmt.Errorf("invalid data character %q", dataPart[i])
+		}
+		values[i] = byte(value)
+	}
+	if bech32Polymod(bech32ChecksumValues(humanReadablePart, values)) != 1 {
+		return "", nil, errors.New("invalid checksum")
+	}
+	data, err := bech32ConvertBits(values[:len(values)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, err
+	}
+	return humanReadablePart, data, nil
+}
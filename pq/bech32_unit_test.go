@@ -0,0 +1,74 @@
+package pq
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// For TestBech32Vectors: the valid and invalid Bech32 strings of BIP 173.
+var (
+	bech32ValidVectors = []string{
+		"A12UEL5L",
+		"a12uel5l",
+		"an83characterlonghumanreadablepartthatcontainsthenumber1andtheexcludedcharactersbio1tt5tgs",
+		"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw",
+		"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+		"?1ezyfcl",
+	}
+	bech32InvalidVectors = []string{
+		"\x201nwldj5",
+		"\x7f1axkwrx",
+		"\x801eym55h",
+		"pzry9x0s0muk",
+		"1pzry9x0s0muk",
+		"x1b4n0q5v",
+		"li1dgmt3",
+		"de1lg7wt\xff",
+		"A1G7SGD8",
+		"10a06t8",
+		"1qzzfhee",
+		"a12UEL5L",
+	}
+)
+
+func TestBech32Vectors(t *testing.T) {
+	for _, vector := range bech32ValidVectors {
+		humanReadablePart, data, err := bech32Decode(vector)
+		require.NoError(t, err, vector)
+		require.Equal(t, vector[:strings.LastIndexByte(vector, '1')], humanReadablePart)
+		encoded, err := bech32Encode(humanReadablePart, data)
+		require.NoError(t, err, vector)
+		require.Equal(t, vector, encoded)
+	}
+	for _, vector := range bech32InvalidVectors {
+		_, _, err := bech32Decode(vector)
+		require.Error(t, err, "%q", vector)
+	}
+}
+
+func TestBech32RoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 5, 32, 1216} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+		for _, humanReadablePart := range []string{"age1pq", "AGE-SECRET-KEY-PQ-"} {
+			encoded, err := bech32Encode(humanReadablePart, data)
+			require.NoError(t, err)
+			decodedHumanReadablePart, decoded, err := bech32Decode(encoded)
+			require.NoError(t, err)
+			require.Equal(t, humanReadablePart, decodedHumanReadablePart)
+			require.Equal(t, len(data), len(decoded))
+			require.Equal(t, string(data), string(decoded))
+		}
+	}
+
+	_, err := bech32Encode("", nil)
+	require.Error(t, err)
+	_, err = bech32Encode("Mixed", nil)
+	require.Error(t, err)
+	_, err = bech32Encode("a b", nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,402 @@
+package pq
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/cloudflare/circl/kem"
+	"github.com/katzenpost/circl/kem/mceliece/mceliece348864"
+	"github.com/katzenpost/circl/kem/mceliece/mceliece348864f"
+	"github.com/katzenpost/circl/kem/mceliece/mceliece460896"
+	"github.com/katzenpost/circl/kem/mceliece/mceliece460896f"
+	"github.com/katzenpost/circl/kem/mceliece/mceliece6688128"
+	"github.com/katzenpost/circl/kem/mceliece/mceliece6688128f"
+	"github.com/katzenpost/circl/kem/mceliece/mceliece6960119"
+	"github.com/katzenpost/circl/kem/mceliece/mceliece6960119f"
+	"github.com/katzenpost/circl/kem/mceliece/mceliece8192128"
+	"github.com/katzenpost/circl/kem/mceliece/mceliece8192128f"
+	mceliecekem "github.com/katzenpost/hpqc/kem"
+)
+
+// ClassicMcElieceParameterSet identifies a Classic McEliece parameter set from the round 4 submission. The "f" variants
+// use a faster key generation that derives other keys from the same seed; their keys and ciphertexts have the same sizes.
+//
+// Classic McEliece is based on binary Goppa codes, studied since 1978. Its public keys are very large (261 KB to 1.3 MB)
+// but its ciphertexts are the smallest of any post-quantum KEM, which suits long-lived keys that receive many messages.
+type ClassicMcElieceParameterSet int
+
+const (
+	// ClassicMcEliece348864 is mceliece348864 (NIST security category 1).
+	ClassicMcEliece348864 ClassicMcElieceParameterSet = iota + 1
+	// ClassicMcEliece348864f is mceliece348864f (NIST security category 1).
+	ClassicMcEliece348864f
+	// ClassicMcEliece460896 is mceliece460896 (NIST security category 3).
+	ClassicMcEliece460896
+	// ClassicMcEliece460896f is mceliece460896f (NIST security category 3).
+	ClassicMcEliece460896f
+	// ClassicMcEliece6688128 is mceliece6688128 (NIST security category 5).
+	ClassicMcEliece6688128
+	// ClassicMcEliece6688128f is mceliece6688128f (NIST security category 5).
+	ClassicMcEliece6688128f
+	// ClassicMcEliece6960119 is mceliece6960119 (NIST security category 5).
+	ClassicMcEliece6960119
+	// ClassicMcEliece6960119f is mceliece6960119f (NIST security category 5).
+	ClassicMcEliece6960119f
+	// ClassicMcEliece8192128 is mceliece8192128 (NIST security category 5).
+	ClassicMcEliece8192128
+	// ClassicMcEliece8192128f is mceliece8192128f (NIST security category 5).
+	ClassicMcEliece8192128f
+)
+
+// ClassicMcElieceParameterSets lists the supported Classic McEliece parameter sets.
+var ClassicMcElieceParameterSets = []ClassicMcElieceParameterSet{
+	ClassicMcEliece348864, ClassicMcEliece348864f, ClassicMcEliece460896, ClassicMcEliece460896f,
+	ClassicMcEliece6688128, ClassicMcEliece6688128f, ClassicMcEliece6960119, ClassicMcEliece6960119f,
+	ClassicMcEliece8192128, ClassicMcEliece8192128f,
+}
+
+// mcelieceSeedSize is the size in bytes of the seed delta that a Classic McEliece private key starts with.
+const mcelieceSeedSize = 32
+
+// String returns the name of the parameter set, for example "mceliece6960119f".
+func (parameterSet ClassicMcElieceParameterSet) String() string {
+	if scheme, err := parameterSet.scheme(); err == nil {
+		return scheme.Name()
+	}
+	return fmt.Sprintf("ClassicMcElieceParameterSet(%d)", int(parameterSet))
+}
+
+// scheme returns the kem.Scheme implementing the parameter set.
+func (parameterSet ClassicMcElieceParameterSet) scheme() (*mcelieceScheme, error) {
+	for _, scheme := range mcelieceSchemes {
+		if scheme.parameterSet == parameterSet {
+			return scheme, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: Classic McEliece parameter set %d", ErrUnknownAlgorithm, int(parameterSet))
+}
+
+// mcelieceSchemes holds one scheme per parameter set, so that schemes can be compared by identity like CIRCL's.
+var mcelieceSchemes = []*mcelieceScheme{
+	{parameterSet: ClassicMcEliece348864, scheme: mceliece348864.Scheme()},
+	{parameterSet: ClassicMcEliece348864f, scheme: mceliece348864f.Scheme()},
+	{parameterSet: ClassicMcEliece460896, scheme: mceliece460896.Scheme()},
+	{parameterSet: ClassicMcEliece460896f, scheme: mceliece460896f.Scheme()},
+	{parameterSet: ClassicMcEliece6688128, scheme: mceliece6688128.Scheme()},
+	{parameterSet: ClassicMcEliece6688128f, scheme: mceliece6688128f.Scheme()},
+	{parameterSet: ClassicMcEliece6960119, scheme: mceliece6960119.Scheme()},
+	{parameterSet: ClassicMcEliece6960119f, scheme: mceliece6960119f.Scheme()},
+	{parameterSet: ClassicMcEliece8192128, scheme: mceliece8192128.Scheme()},
+	{parameterSet: ClassicMcEliece8192128f, scheme: mceliece8192128f.Scheme()},
+}
+
+// mcelieceSchemeOf returns the Classic McEliece scheme behind a KEM scheme, rejecting schemes that are not Classic McEliece.
+func mcelieceSchemeOf(scheme kem.Scheme) (*mcelieceScheme, error) {
+	if mcelieceScheme, ok := scheme.(*mcelieceScheme); ok {
+		return mcelieceScheme, nil
+	}
+	return nil, fmt.Errorf("%w: KEM scheme is not Classic McEliece", ErrUnknownAlgorithm)
+}
+
+// ClassicMcElieceKeyPair represents a Classic McEliece key pair, and the parameter set it belongs to.
+// The keys are serialized with MarshalPublicKey and MarshalPrivateKey, like ML-KEM keys.
+type ClassicMcElieceKeyPair struct {
+	ParameterSet ClassicMcElieceParameterSet
+	PublicKey    kem.PublicKey
+	PrivateKey   kem.PrivateKey
+}
+
+// GenerateDeterministicClassicMcElieceKeyPair generates a Classic McEliece key pair from a seed (for KATs).
+// The seed must be of length ClassicMcElieceSeedSize(parameterSet), and is the seed delta of the key generation.
+func GenerateDeterministicClassicMcElieceKeyPair(parameterSet ClassicMcElieceParameterSet, seed []byte) (keyPair *ClassicMcElieceKeyPair, keyGenerationError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			keyPair, keyGenerationError = nil, newPanicError("GenerateDeterministicClassicMcElieceKeyPair", recoveredPanic)
+		}
+	}()
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return nil, err
+	}
+	if len(seed) != scheme.SeedSize() {
+		return nil, newSizeError(ErrInvalidSeedSize, parameterSet.String()+" seed", len(seed), scheme.SeedSize())
+	}
+	publicKey, privateKey := scheme.DeriveKeyPair(seed)
+	return &ClassicMcElieceKeyPair{ParameterSet: parameterSet, PublicKey: publicKey, PrivateKey: privateKey}, nil
+}
+
+// GenerateClassicMcElieceKeyPair generates a new Classic McEliece key pair for the given parameter set from a fresh random seed.
+func GenerateClassicMcElieceKeyPair(parameterSet ClassicMcElieceParameterSet) (*ClassicMcElieceKeyPair, error) {
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return nil, err
+	}
+	seed := make([]byte, scheme.SeedSize())
+	if _, err := rand.Read(seed); err != nil {
+		return nil, err
+	}
+	return GenerateDeterministicClassicMcElieceKeyPair(parameterSet, seed)
+}
+
+// UnmarshalClassicMcEliecePublicKey deserializes bytes into a Classic McEliece public key of the given parameter set.
+// A key of the wrong length returns a *SizeError matching ErrInvalidKeySize.
+func UnmarshalClassicMcEliecePublicKey(parameterSet ClassicMcElieceParameterSet, data []byte) (publicKey kem.PublicKey, unmarshalError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			publicKey, unmarshalError = nil, newPanicError("UnmarshalClassicMcEliecePublicKey", recoveredPanic)
+		}
+	}()
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != scheme.PublicKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, parameterSet.String()+" public key", len(data), scheme.PublicKeySize())
+	}
+	return scheme.UnmarshalBinaryPublicKey(data)
+}
+
+// UnmarshalClassicMcEliecePrivateKey deserializes bytes into a Classic McEliece private key of the given parameter set.
+// A key of the wrong length returns a *SizeError matching ErrInvalidKeySize, and a key that differs from the key generated
+// from its seed returns a *KeyValidationError matching ErrInvalidDecapsulationKey. The check repeats the key generation,
+// so it costs as much as GenerateClassicMcElieceKeyPair.
+func UnmarshalClassicMcEliecePrivateKey(parameterSet ClassicMcElieceParameterSet, data []byte) (privateKey kem.PrivateKey, unmarshalError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			privateKey, unmarshalError = nil, newPanicError("UnmarshalClassicMcEliecePrivateKey", recoveredPanic)
+		}
+	}()
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != scheme.PrivateKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, parameterSet.String()+" private key", len(data), scheme.PrivateKeySize())
+	}
+	_, derivedKey := scheme.DeriveKeyPair(data[:mcelieceSeedSize])
+	derivedBytes, err := derivedKey.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(derivedBytes, data) != 1 {
+		return nil, &KeyValidationError{
+			Err:    ErrInvalidDecapsulationKey,
+			Input:  parameterSet.String() + " private key",
+			Reason: "key does not match the key generated from its seed",
+		}
+	}
+	return derivedKey, nil
+}
+
+// ClassicMcElieceEncapsulate encapsulates a shared secret using the Classic McEliece public key.
+// The parameter set is taken from the public key.
+func ClassicMcElieceEncapsulate(publicKey kem.PublicKey) (ciphertext []byte, sharedSecret []byte, err error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			ciphertext, sharedSecret, err = nil, nil, newPanicError("ClassicMcElieceEncapsulate", recoveredPanic)
+		}
+	}()
+	if publicKey == nil {
+		return nil, nil, fmt.Errorf("%w: Classic McEliece public key is nil", ErrInvalidKey)
+	}
+	scheme, err := mcelieceSchemeOf(publicKey.Scheme())
+	if err != nil {
+		return nil, nil, err
+	}
+	return scheme.Encapsulate(publicKey)
+}
+
+// ClassicMcElieceEncapsulateDeterministic encapsulates a shared secret using the Classic McEliece public key and a seed
+// (for KATs). The seed must be of length ClassicMcElieceEncapsulationSeedSize(parameterSet). It seeds the NIST AES-256
+// CTR DRBG of the reference KAT generator, whose first 32 bytes the reference key generation has already used.
+func ClassicMcElieceEncapsulateDeterministic(publicKey kem.PublicKey, seed []byte) (ciphertext []byte, sharedSecret []byte, err error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			ciphertext, sharedSecret, err = nil, nil, newPanicError("ClassicMcElieceEncapsulateDeterministic", recoveredPanic)
+		}
+	}()
+	if publicKey == nil {
+		return nil, nil, fmt.Errorf("%w: Classic McEliece public key is nil", ErrInvalidKey)
+	}
+	scheme, err := mcelieceSchemeOf(publicKey.Scheme())
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(seed) != scheme.EncapsulationSeedSize() {
+		return nil, nil, newSizeError(ErrInvalidSeedSize, scheme.Name()+" encapsulation seed", len(seed), scheme.EncapsulationSeedSize())
+	}
+	return scheme.EncapsulateDeterministically(publicKey, seed)
+}
+
+// ClassicMcElieceDecapsulate decapsulates a shared secret using the Classic McEliece private key.
+// The parameter set is taken from the private key. A ciphertext of the wrong size returns an error matching ErrInvalidCiphertext.
+//
+// Like ML-KEM, decapsulation uses implicit rejection: a tampered ciphertext of the right size is not an error, it yields
+// the pseudorandom shared secret derived from the private value s instead.
+func ClassicMcElieceDecapsulate(privateKey kem.PrivateKey, ciphertext []byte) (sharedSecret []byte, err error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			sharedSecret, err = nil, newPanicError("ClassicMcElieceDecapsulate", recoveredPanic)
+		}
+	}()
+	if privateKey == nil {
+		return nil, fmt.Errorf("%w: Classic McEliece private key is nil", ErrInvalidKey)
+	}
+	scheme, err := mcelieceSchemeOf(privateKey.Scheme())
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) != scheme.CiphertextSize() {
+		return nil, newSizeError(ErrInvalidCiphertext, scheme.Name()+" ciphertext", len(ciphertext), scheme.CiphertextSize())
+	}
+	return scheme.Decapsulate(privateKey, ciphertext)
+}
+
+// ClassicMcElieceSeedSize returns the size in bytes of the seed for GenerateDeterministicClassicMcElieceKeyPair, or 0
+// for an unknown parameter set.
+func ClassicMcElieceSeedSize(parameterSet ClassicMcElieceParameterSet) int {
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return 0
+	}
+	return scheme.SeedSize()
+}
+
+// ClassicMcElieceEncapsulationSeedSize returns the size in bytes of the seed for ClassicMcElieceEncapsulateDeterministic,
+// or 0 for an unknown parameter set.
+func ClassicMcElieceEncapsulationSeedSize(parameterSet ClassicMcElieceParameterSet) int {
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return 0
+	}
+	return scheme.EncapsulationSeedSize()
+}
+
+// mcelieceScheme implements CIRCL's kem.Scheme for one Classic McEliece parameter set, on top of the implementation in
+// github.com/katzenpost/circl, which is a port of the reference implementation with its own KEM interfaces.
+type mcelieceScheme struct {
+	parameterSet ClassicMcElieceParameterSet
+	scheme       mceliecekem.Scheme
+}
+
+// mceliecePublicKey is a Classic McEliece public key: the systematic part T of the public matrix.
+type mceliecePublicKey struct {
+	scheme *mcelieceScheme
+	key    mceliecekem.PublicKey
+}
+
+// mceliecePrivateKey is a Classic McEliece private key: delta, the pivots, the Goppa polynomial g, the control bits of
+// the support permutation, and s.
+type mceliecePrivateKey struct {
+	scheme *mcelieceScheme
+	key    mceliecekem.PrivateKey
+}
+
+func (scheme *mcelieceScheme) Name() string { return scheme.scheme.Name() }
+
+func (scheme *mcelieceScheme) PublicKeySize() int { return scheme.scheme.PublicKeySize() }
+
+func (scheme *mcelieceScheme) PrivateKeySize() int { return scheme.scheme.PrivateKeySize() }
+
+func (scheme *mcelieceScheme) SeedSize() int { return scheme.scheme.SeedSize() }
+
+func (scheme *mcelieceScheme) SharedKeySize() int { return scheme.scheme.SharedKeySize() }
+
+func (scheme *mcelieceScheme) CiphertextSize() int { return scheme.scheme.CiphertextSize() }
+
+func (scheme *mcelieceScheme) EncapsulationSeedSize() int {
+	return scheme.scheme.EncapsulationSeedSize()
+}
+
+func (scheme *mcelieceScheme) GenerateKeyPair() (kem.PublicKey, kem.PrivateKey, error) {
+	seed := make([]byte, scheme.SeedSize())
+	if _, err := rand.Read(seed); err != nil {
+		return nil, nil, err
+	}
+	publicKey, privateKey := scheme.DeriveKeyPair(seed)
+	return publicKey, privateKey, nil
+}
+
+// DeriveKeyPair implements SeededKeyGen with delta taken from seed.
+func (scheme *mcelieceScheme) DeriveKeyPair(seed []byte) (kem.PublicKey, kem.PrivateKey) {
+	if len(seed) != scheme.SeedSize() {
+		panic(kem.ErrSeedSize)
+	}
+	publicKey, privateKey := scheme.scheme.DeriveKeyPair(seed)
+	return &mceliecePublicKey{scheme: scheme, key: publicKey}, &mceliecePrivateKey{scheme: scheme, key: privateKey}
+}
+
+func (scheme *mcelieceScheme) Encapsulate(publicKey kem.PublicKey) ([]byte, []byte, error) {
+	mceliecePublic, ok := publicKey.(*mceliecePublicKey)
+	if !ok || mceliecePublic.scheme != scheme {
+		return nil, nil, kem.ErrTypeMismatch
+	}
+	return scheme.scheme.Encapsulate(mceliecePublic.key)
+}
+
+func (scheme *mcelieceScheme) EncapsulateDeterministically(publicKey kem.PublicKey, seed []byte) ([]byte, []byte, error) {
+	if len(seed) != scheme.EncapsulationSeedSize() {
+		return nil, nil, kem.ErrSeedSize
+	}
+	mceliecePublic, ok := publicKey.(*mceliecePublicKey)
+	if !ok || mceliecePublic.scheme != scheme {
+		return nil, nil, kem.ErrTypeMismatch
+	}
+	return scheme.scheme.EncapsulateDeterministically(mceliecePublic.key, seed)
+}
+
+func (scheme *mcelieceScheme) Decapsulate(privateKey kem.PrivateKey, ciphertext []byte) ([]byte, error) {
+	mceliecePrivate, ok := privateKey.(*mceliecePrivateKey)
+	if !ok || mceliecePrivate.scheme != scheme {
+		return nil, kem.ErrTypeMismatch
+	}
+	if len(ciphertext) != scheme.CiphertextSize() {
+		return nil, kem.ErrCiphertextSize
+	}
+	return scheme.scheme.Decapsulate(mceliecePrivate.key, ciphertext)
+}
+
+func (scheme *mcelieceScheme) UnmarshalBinaryPublicKey(data []byte) (kem.PublicKey, error) {
+	publicKey, err := scheme.scheme.UnmarshalBinaryPublicKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return &mceliecePublicKey{scheme: scheme, key: publicKey}, nil
+}
+
+func (scheme *mcelieceScheme) UnmarshalBinaryPrivateKey(data []byte) (kem.PrivateKey, error) {
+	privateKey, err := scheme.scheme.UnmarshalBinaryPrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return &mceliecePrivateKey{scheme: scheme, key: privateKey}, nil
+}
+
+func (publicKey *mceliecePublicKey) Scheme() kem.Scheme { return publicKey.scheme }
+
+func (publicKey *mceliecePublicKey) MarshalBinary() ([]byte, error) {
+	return publicKey.key.MarshalBinary()
+}
+
+func (publicKey *mceliecePublicKey) Equal(other kem.PublicKey) bool {
+	otherKey, ok := other.(*mceliecePublicKey)
+	return ok && otherKey.scheme == publicKey.scheme && publicKey.key.Equal(otherKey.key)
+}
+
+func (privateKey *mceliecePrivateKey) Scheme() kem.Scheme { return privateKey.scheme }
+
+func (privateKey *mceliecePrivateKey) MarshalBinary() ([]byte, error) {
+	return privateKey.key.MarshalBinary()
+}
+
+func (privateKey *mceliecePrivateKey) Equal(other kem.PrivateKey) bool {
+	otherKey, ok := other.(*mceliecePrivateKey)
+	return ok && otherKey.scheme == privateKey.scheme && privateKey.key.Equal(otherKey.key)
+}
+
+// Public returns the public key, which Classic McEliece private keys do not store: it repeats the key generation from
+// the seed delta.
+func (privateKey *mceliecePrivateKey) Public() kem.PublicKey {
+	return &mceliecePublicKey{scheme: privateKey.scheme, key: privateKey.key.Public()}
+}
@@ -0,0 +1,51 @@
+package pq
+
+import "testing"
+
+func BenchmarkGenerateClassicMcElieceKeyPair(b *testing.B) {
+	for _, parameterSet := range ClassicMcElieceParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := GenerateClassicMcElieceKeyPair(parameterSet); err != nil {
+					b.Fatalf("failed to generate Classic McEliece key pair: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkClassicMcElieceEncapsulate(b *testing.B) {
+	for _, parameterSet := range ClassicMcElieceParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			keyPair, err := GenerateClassicMcElieceKeyPair(parameterSet)
+			if err != nil {
+				b.Fatalf("failed to generate Classic McEliece key pair: %v", err)
+			}
+			for b.Loop() {
+				if _, _, err := ClassicMcElieceEncapsulate(keyPair.PublicKey); err != nil {
+					b.Fatalf("failed to encapsulate: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkClassicMcElieceDecapsulate(b *testing.B) {
+	for _, parameterSet := range ClassicMcElieceParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			keyPair, err := GenerateClassicMcElieceKeyPair(parameterSet)
+			if err != nil {
+				b.Fatalf("failed to generate Classic McEliece key pair: %v", err)
+			}
+			ciphertext, _, err := ClassicMcElieceEncapsulate(keyPair.PublicKey)
+			if err != nil {
+				b.Fatalf("failed to encapsulate: %v", err)
+			}
+			for b.Loop() {
+				if _, err := ClassicMcElieceDecapsulate(keyPair.PrivateKey, ciphertext); err != nil {
+					b.Fatalf("failed to decapsulate: %v", err)
+				}
+			}
+		})
+	}
+}
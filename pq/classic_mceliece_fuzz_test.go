@@ -0,0 +1,39 @@
+package pq
+
+import "testing"
+
+func FuzzClassicMcElieceDecapsulate(f *testing.F) {
+	key, _ := GenerateClassicMcElieceKeyPair(ClassicMcEliece348864)
+	ciphertext, _, _ := ClassicMcElieceEncapsulate(key.PublicKey)
+	f.Add(ciphertext)
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, ciphertext []byte) {
+		sharedSecret, err := ClassicMcElieceDecapsulate(key.PrivateKey, ciphertext)
+		if len(ciphertext) != key.PrivateKey.Scheme().CiphertextSize() {
+			if err == nil {
+				t.Error("decapsulation should fail for a ciphertext of the wrong size")
+			}
+			return
+		}
+		if err != nil || len(sharedSecret) != key.PrivateKey.Scheme().SharedKeySize() {
+			t.Errorf("decapsulation should not fail for a ciphertext of the right size: %v", err)
+		}
+	})
+}
+
+func FuzzUnmarshalClassicMcEliecePrivateKey(f *testing.F) {
+	key, _ := GenerateClassicMcElieceKeyPair(ClassicMcEliece348864f)
+	privateKeyBytes, _ := MarshalPrivateKey(key.PrivateKey)
+	f.Add(privateKeyBytes)
+	f.Add([]byte{0x01})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		privateKey, err := UnmarshalClassicMcEliecePrivateKey(ClassicMcEliece348864f, data)
+		if err != nil {
+			return
+		}
+		roundTrip, err := MarshalPrivateKey(privateKey)
+		if err != nil || string(roundTrip) != string(data) {
+			t.Error("an accepted private key should marshal back to the same bytes")
+		}
+	})
+}
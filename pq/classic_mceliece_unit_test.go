@@ -0,0 +1,187 @@
+package pq
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// For TestClassicMcElieceSizes: the key, ciphertext and shared secret sizes of the Classic McEliece round 4
+// specification, section 4.4. The "f" variants have the sizes of the parameter set they speed up.
+var classicMcElieceExpectedSizes = map[ClassicMcElieceParameterSet]struct {
+	publicKey, privateKey, ciphertext, sharedSecret int
+}{
+	ClassicMcEliece348864:   {261120, 6492, 96, 32},
+	ClassicMcEliece348864f:  {261120, 6492, 96, 32},
+	ClassicMcEliece460896:   {524160, 13608, 156, 32},
+	ClassicMcEliece460896f:  {524160, 13608, 156, 32},
+	ClassicMcEliece6688128:  {1044992, 13932, 208, 32},
+	ClassicMcEliece6688128f: {1044992, 13932, 208, 32},
+	ClassicMcEliece6960119:  {1047319, 13948, 194, 32},
+	ClassicMcEliece6960119f: {1047319, 13948, 194, 32},
+	ClassicMcEliece8192128:  {1357824, 14120, 208, 32},
+	ClassicMcEliece8192128f: {1357824, 14120, 208, 32},
+}
+
+func TestClassicMcElieceSizesAndRoundTrip(t *testing.T) {
+	for _, parameterSet := range ClassicMcElieceParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			t.Parallel()
+			expected := classicMcElieceExpectedSizes[parameterSet]
+			keyPair, err := GenerateClassicMcElieceKeyPair(parameterSet)
+			require.NoError(t, err, "failed to generate Classic McEliece key pair")
+			require.Equal(t, parameterSet, keyPair.ParameterSet, "expected key pair to carry its parameter set")
+
+			publicKeyBytes, err := MarshalPublicKey(keyPair.PublicKey)
+			require.NoError(t, err, "failed to marshal public key")
+			require.Len(t, publicKeyBytes, expected.publicKey, "unexpected public key size")
+			privateKeyBytes, err := MarshalPrivateKey(keyPair.PrivateKey)
+			require.NoError(t, err, "failed to marshal private key")
+			require.Len(t, privateKeyBytes, expected.privateKey, "unexpected private key size")
+
+			publicKey, err := UnmarshalClassicMcEliecePublicKey(parameterSet, publicKeyBytes)
+			require.NoError(t, err, "failed to unmarshal public key")
+			require.True(t, publicKey.Equal(keyPair.PublicKey), "expected unmarshalled public key to match the original public key")
+			privateKey, err := UnmarshalClassicMcEliecePrivateKey(parameterSet, privateKeyBytes)
+			require.NoError(t, err, "failed to unmarshal private key")
+			require.True(t, privateKey.Equal(keyPair.PrivateKey), "expected unmarshalled private key to match the original private key")
+			require.True(t, privateKey.Public().Equal(publicKey), "expected the private key to regenerate the public key")
+
+			ciphertext, sharedSecret, err := ClassicMcElieceEncapsulate(publicKey)
+			require.NoError(t, err, "failed to encapsulate")
+			require.Len(t, ciphertext, expected.ciphertext, "unexpected ciphertext size")
+			require.Len(t, sharedSecret, expected.sharedSecret, "unexpected shared secret size")
+			decapsulatedSecret, err := ClassicMcElieceDecapsulate(privateKey, ciphertext)
+			require.NoError(t, err, "failed to decapsulate")
+			require.Equal(t, sharedSecret, decapsulatedSecret, "expected both sides to agree on the shared secret")
+
+			ciphertext[0] ^= 0x01
+			rejectionSecret, err := ClassicMcElieceDecapsulate(privateKey, ciphertext)
+			require.NoError(t, err, "a tampered ciphertext of the right size must not be an error")
+			require.NotEqual(t, sharedSecret, rejectionSecret, "expected a tampered ciphertext to yield another secret")
+		})
+	}
+}
+
+// For TestClassicMcElieceKnownAnswers: the SHA-256 of the first 10 entries of a KAT file in the PQCkemKAT .rsp format,
+// as published in kem/mceliece/kat_test.go of github.com/katzenpost/circl, which states that they were computed from the
+// reference implementation. They are not digests of the NIST round 4 kat_kem.rsp files, which hold 100 entries each.
+var classicMcElieceKATDigests = map[ClassicMcElieceParameterSet]string{
+	ClassicMcEliece348864f:  "d0d5ea348a181740862dcc8476ff7d00ce44d1c6e36b2145289d97f580f2cd7d",
+	ClassicMcEliece348864:   "76351ed2e95a616ca76230bac579cead21012d89181c7398381d0bbe904ab92c",
+	ClassicMcEliece460896f:  "552da50baff2666db7b64486c88da4e2b65b25c3d5424be682ca08ffce15a356",
+	ClassicMcEliece460896:   "fd785edfe1b721fb24fe159cb9f30cc17daec3d188d59a4bf47a83388880192e",
+	ClassicMcEliece6688128f: "7b64c9882a00bc984e0ca9d3748d0b1bd9215d1bcf921643ee88d28d539303d8",
+	ClassicMcEliece6688128:  "3f926328959729c61a11b11ab6326246a42d9b3e76943bba2625342ea33723e2",
+	ClassicMcEliece6960119f: "d6d3e929ff505108fd545d14df5f5bac234cd6d882f0eed3fd628f122e3093c6",
+	ClassicMcEliece6960119:  "e4d608fa9795c1a1704709ab9df3940ae1dbf0f708cc0dbdf76c8f3173088e46",
+	ClassicMcEliece8192128f: "3fdb40d47705829c16de4fb5a81f7c095eb4dadc306cfc2c89eff2f483c42402",
+	ClassicMcEliece8192128:  "beb28fc0d1555a0028afeb6ebc72b8337f424a826be3d49b47759b8bda50db90",
+}
+
+func TestClassicMcElieceKnownAnswers(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping Classic McEliece KAT entries in short mode")
+	}
+	for _, parameterSet := range ClassicMcElieceParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, classicMcElieceKATDigests[parameterSet], classicMcElieceKATDigest(t, parameterSet), "KAT digest mismatch")
+		})
+	}
+}
+
+// classicMcElieceKATDigest hashes the first 10 KAT entries of the parameter set, formatted like the reference .rsp file.
+func classicMcElieceKATDigest(t *testing.T, parameterSet ClassicMcElieceParameterSet) string {
+	t.Helper()
+	var seed [48]byte
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	digest := sha256.New()
+	fmt.Fprintf(digest, "# kem/%s\n\n", parameterSet)
+	drbg := newNISTKATDRBG(seed[:])
+	for count := range 10 {
+		drbg.fill(seed[:])
+		fmt.Fprintf(digest, "count = %d\nseed = %X\n", count, seed)
+		keySeed := make([]byte, ClassicMcElieceSeedSize(parameterSet))
+		newNISTKATDRBG(seed[:]).fill(keySeed)
+		keyPair, err := GenerateDeterministicClassicMcElieceKeyPair(parameterSet, keySeed)
+		require.NoError(t, err, "failed to derive Classic McEliece key pair")
+		ciphertext, sharedSecret, err := ClassicMcElieceEncapsulateDeterministic(keyPair.PublicKey, seed[:])
+		require.NoError(t, err, "failed to encapsulate")
+		decapsulatedSecret, err := ClassicMcElieceDecapsulate(keyPair.PrivateKey, ciphertext)
+		require.NoError(t, err, "failed to decapsulate")
+		require.Equal(t, sharedSecret, decapsulatedSecret, "KAT entry %d does not round-trip", count)
+
+		publicKeyBytes, _ := MarshalPublicKey(keyPair.PublicKey)
+		privateKeyBytes, _ := MarshalPrivateKey(keyPair.PrivateKey)
+		fmt.Fprintf(digest, "pk = %X\nsk = %X\nct = %X\nss = %X\n\n", publicKeyBytes, privateKeyBytes, ciphertext, sharedSecret)
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+func TestClassicMcElieceInvalidInputs(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x42}, ClassicMcElieceSeedSize(ClassicMcEliece348864))
+	keyPair, err := GenerateDeterministicClassicMcElieceKeyPair(ClassicMcEliece348864, seed)
+	require.NoError(t, err, "failed to derive Classic McEliece key pair")
+	publicKeyBytes, _ := MarshalPublicKey(keyPair.PublicKey)
+	privateKeyBytes, _ := MarshalPrivateKey(keyPair.PrivateKey)
+	ciphertext, _, err := ClassicMcElieceEncapsulate(keyPair.PublicKey)
+	require.NoError(t, err, "failed to encapsulate")
+
+	_, err = GenerateDeterministicClassicMcElieceKeyPair(ClassicMcEliece348864, seed[1:])
+	require.ErrorIs(t, err, ErrInvalidSeedSize)
+	_, _, err = ClassicMcElieceEncapsulateDeterministic(keyPair.PublicKey, make([]byte, 32))
+	require.ErrorIs(t, err, ErrInvalidSeedSize)
+	_, err = GenerateClassicMcElieceKeyPair(ClassicMcElieceParameterSet(0))
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+	require.Equal(t, "ClassicMcElieceParameterSet(0)", ClassicMcElieceParameterSet(0).String())
+
+	_, err = UnmarshalClassicMcEliecePublicKey(ClassicMcEliece460896, publicKeyBytes)
+	var sizeError *SizeError
+	require.ErrorAs(t, err, &sizeError)
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	require.Equal(t, 261120, sizeError.Got)
+	require.Equal(t, 524160, sizeError.Want)
+	_, err = UnmarshalClassicMcEliecePrivateKey(ClassicMcEliece460896, privateKeyBytes)
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	_, err = ClassicMcElieceDecapsulate(keyPair.PrivateKey, ciphertext[:len(ciphertext)-1])
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	corrupted := append([]byte(nil), privateKeyBytes...)
+	corrupted[len(corrupted)-1] ^= 0x01 // last byte of s
+	_, err = UnmarshalClassicMcEliecePrivateKey(ClassicMcEliece348864, corrupted)
+	var keyValidationError *KeyValidationError
+	require.ErrorAs(t, err, &keyValidationError)
+	require.ErrorIs(t, err, ErrInvalidDecapsulationKey)
+
+	// Keys of the other KEMs are rejected in both directions, and so are keys of another Classic McEliece parameter set.
+	frodoKeyPair, err := GenerateFrodoKEMKeyPair(FrodoKEM640SHAKE)
+	require.NoError(t, err, "failed to generate FrodoKEM key pair")
+	_, _, err = ClassicMcElieceEncapsulate(frodoKeyPair.PublicKey)
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+	_, _, err = FrodoKEMEncapsulate(keyPair.PublicKey)
+	require.Error(t, err, "expected FrodoKEMEncapsulate to reject a Classic McEliece public key")
+	_, _, err = ClassicMcElieceEncapsulate(nil)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = ClassicMcElieceDecapsulate(nil, ciphertext)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	fastKeyPair, err := GenerateDeterministicClassicMcElieceKeyPair(ClassicMcEliece348864f, seed)
+	require.NoError(t, err, "failed to derive Classic McEliece key pair")
+	_, err = fastKeyPair.PrivateKey.Scheme().Decapsulate(keyPair.PrivateKey, ciphertext)
+	require.Error(t, err, "expected a mceliece348864 key to be rejected by mceliece348864f")
+	require.False(t, fastKeyPair.PublicKey.Equal(keyPair.PublicKey), "keys of different parameter sets must not be equal")
+}
+
+func TestClassicMcElieceRegistry(t *testing.T) {
+	scheme, err := LookupKEMScheme("mceliece6960119f")
+	require.NoError(t, err, "expected mceliece6960119f to be registered")
+	require.Nil(t, scheme.OID(), "Classic McEliece has no standard OID")
+	require.Equal(t, 194, scheme.CiphertextSize())
+	require.Equal(t, 32, scheme.SharedSecretSize())
+}
@@ -0,0 +1,433 @@
+package pq
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+)
+
+// CompositeMLDSAAlgorithm identifies a composite ML-DSA signature algorithm from the IETF LAMPS draft
+// draft-ietf-lamps-pq-composite-sigs, pairing an ML-DSA parameter set with a traditional signature algorithm.
+type CompositeMLDSAAlgorithm int
+
+const (
+	// CompositeMLDSA65Ed25519 is id-MLDSA65-Ed25519-SHA512.
+	CompositeMLDSA65Ed25519 CompositeMLDSAAlgorithm = iota + 1
+	// CompositeMLDSA65ECDSAP256 is id-MLDSA65-ECDSA-P256-SHA512.
+	CompositeMLDSA65ECDSAP256
+	// CompositeMLDSA65ECDSAP384 is id-MLDSA65-ECDSA-P384-SHA512.
+	CompositeMLDSA65ECDSAP384
+	// CompositeMLDSA87ECDSAP384 is id-MLDSA87-ECDSA-P384-SHA512.
+	CompositeMLDSA87ECDSAP384
+)
+
+// compositeMLDSAPrefix is the fixed prefix of every composite message representative M'.
+const compositeMLDSAPrefix = "CompositeAlgorithmSignatures2025"
+
+// CompositeMLDSAAlgorithms lists the supported composite ML-DSA algorithms.
+var CompositeMLDSAAlgorithms = []CompositeMLDSAAlgorithm{
+	CompositeMLDSA65Ed25519, CompositeMLDSA65ECDSAP256, CompositeMLDSA65ECDSAP384, CompositeMLDSA87ECDSAP384,
+}
+
+// String returns the name of the algorithm, for example "MLDSA65-Ed25519-SHA512".
+func (algorithm CompositeMLDSAAlgorithm) String() string {
+	switch algorithm {
+	case CompositeMLDSA65Ed25519:
+		return "MLDSA65-Ed25519-SHA512"
+	case CompositeMLDSA65ECDSAP256:
+		return "MLDSA65-ECDSA-P256-SHA512"
+	case CompositeMLDSA65ECDSAP384:
+		return "MLDSA65-ECDSA-P384-SHA512"
+	case CompositeMLDSA87ECDSAP384:
+		return "MLDSA87-ECDSA-P384-SHA512"
+	default:
+		return fmt.Sprintf("CompositeMLDSAAlgorithm(%d)", int(algorithm))
+	}
+}
+
+// OID returns the object identifier of the algorithm, under id-alg (1.3.6.1.5.5.7.6), used in keys and certificates.
+func (algorithm CompositeMLDSAAlgorithm) OID() (asn1.ObjectIdentifier, error) {
+	var arc int
+	switch algorithm {
+	case CompositeMLDSA65ECDSAP256:
+		arc = 45
+	case CompositeMLDSA65ECDSAP384:
+		arc = 46
+	case CompositeMLDSA65Ed25519:
+		arc = 48
+	case CompositeMLDSA87ECDSAP384:
+		arc = 49
+	default:
+		return nil, fmt.Errorf("%w: composite ML-DSA algorithm %v", ErrUnknownAlgorithm, algorithm)
+	}
+	return asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 6, arc}, nil
+}
+
+// label returns the domain separator bound into the message representative and used as the ML-DSA context string.
+func (algorithm CompositeMLDSAAlgorithm) label() string {
+	return "COMPSIG-" + algorithm.String()
+}
+
+// components returns the ML-DSA parameter set and, for ECDSA, the curve of the algorithm. A nil curve means Ed25519.
+func (algorithm CompositeMLDSAAlgorithm) components() (MLDSAParameterSet, elliptic.Curve, error) {
+	switch algorithm {
+	case CompositeMLDSA65Ed25519:
+		return MLDSA65, nil, nil
+	case CompositeMLDSA65ECDSAP256:
+		return MLDSA65, elliptic.P256(), nil
+	case CompositeMLDSA65ECDSAP384:
+		return MLDSA65, elliptic.P384(), nil
+	case CompositeMLDSA87ECDSAP384:
+		return MLDSA87, elliptic.P384(), nil
+	default:
+		return 0, nil, fmt.Errorf("%w: composite ML-DSA algorithm %v", ErrUnknownAlgorithm, algorithm)
+	}
+}
+
+// CompositeMLDSAPublicKey is a composite public key: an ML-DSA public key and an Ed25519 (ed25519.PublicKey) or
+// ECDSA (*ecdsa.PublicKey) public key.
+type CompositeMLDSAPublicKey struct {
+	Algorithm            CompositeMLDSAAlgorithm
+	MLDSAPublicKey       []byte
+	TraditionalPublicKey crypto.PublicKey
+}
+
+// CompositeMLDSAPrivateKey is a composite private key: an ML-DSA private key, with the seed it was derived from, and an
+// Ed25519 (ed25519.PrivateKey) or ECDSA (*ecdsa.PrivateKey) private key.
+type CompositeMLDSAPrivateKey struct {
+	Algorithm             CompositeMLDSAAlgorithm
+	MLDSASeed             [MLDSASeedSize]byte
+	MLDSAPrivateKey       []byte
+	TraditionalPrivateKey crypto.Signer
+	publicKey             *CompositeMLDSAPublicKey
+}
+
+// Public returns the composite public key corresponding to the private key. A private key that was not built by this
+// package has its public key derived from MLDSASeed and TraditionalPrivateKey; Public returns nil if they are invalid.
+func (privateKey *CompositeMLDSAPrivateKey) Public() *CompositeMLDSAPublicKey {
+	if privateKey.publicKey != nil {
+		return privateKey.publicKey
+	}
+	if privateKey.TraditionalPrivateKey == nil {
+		return nil
+	}
+	parameterSet, _, componentsError := privateKey.Algorithm.components()
+	if componentsError != nil {
+		return nil
+	}
+	mldsaKeyPair, deriveError := DeriveMLDSAKeyPair(parameterSet, &privateKey.MLDSASeed)
+	if deriveError != nil {
+		return nil
+	}
+	return &CompositeMLDSAPublicKey{
+		Algorithm:            privateKey.Algorithm,
+		MLDSAPublicKey:       mldsaKeyPair.PublicKey,
+		TraditionalPublicKey: privateKey.TraditionalPrivateKey.Public(),
+	}
+}
+
+// CompositeMLDSAKeyPair represents a composite ML-DSA key pair.
+type CompositeMLDSAKeyPair struct {
+	PublicKey  *CompositeMLDSAPublicKey
+	PrivateKey *CompositeMLDSAPrivateKey
+}
+
+// newCompositeMLDSAPrivateKey derives the ML-DSA component from its seed and pairs it with the traditional private key.
+func newCompositeMLDSAPrivateKey(algorithm CompositeMLDSAAlgorithm, seed *[MLDSASeedSize]byte, traditionalPrivateKey crypto.Signer) (*CompositeMLDSAPrivateKey, error) {
+	parameterSet, _, componentsError := algorithm.components()
+	if componentsError != nil {
+		return nil, componentsError
+	}
+	mldsaKeyPair, deriveError := DeriveMLDSAKeyPair(parameterSet, seed)
+	if deriveError != nil {
+		return nil, deriveError
+	}
+	privateKey := &CompositeMLDSAPrivateKey{
+		Algorithm:             algorithm,
+		MLDSASeed:             *seed,
+		MLDSAPrivateKey:       mldsaKeyPair.PrivateKey,
+		TraditionalPrivateKey: traditionalPrivateKey,
+	}
+	privateKey.publicKey = &CompositeMLDSAPublicKey{
+		Algorithm:            algorithm,
+		MLDSAPublicKey:       mldsaKeyPair.PublicKey,
+		TraditionalPublicKey: traditionalPrivateKey.Public(),
+	}
+	return privateKey, nil
+}
+
+// GenerateCompositeMLDSAKeyPair generates a new composite ML-DSA key pair for the given algorithm.
+func GenerateCompositeMLDSAKeyPair(algorithm CompositeMLDSAAlgorithm) (keyPair *CompositeMLDSAKeyPair, keyGenerationError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			keyGenerationError = newPanicError("GenerateCompositeMLDSAKeyPair", recoveredPanic)
+		}
+	}()
+	_, curve, componentsError := algorithm.components()
+	if componentsError != nil {
+		return nil, componentsError
+	}
+	var traditionalPrivateKey crypto.Signer
+	if curve == nil {
+		_, traditionalPrivateKey, keyGenerationError = ed25519.GenerateKey(rand.Reader)
+	} else {
+		traditionalPrivateKey, keyGenerationError = ecdsa.GenerateKey(curve, rand.Reader)
+	}
+	if keyGenerationError != nil {
+		return nil, fmt.Errorf("%v traditional GenerateKey: %w", algorithm, keyGenerationError)
+	}
+	var seed [MLDSASeedSize]byte
+	if _, randomError := rand.Read(seed[:]); randomError != nil {
+		return nil, fmt.Errorf("rand.Read: %w", randomError)
+	}
+	privateKey, privateKeyError := newCompositeMLDSAPrivateKey(algorithm, &seed, traditionalPrivateKey)
+	if privateKeyError != nil {
+		return nil, privateKeyError
+	}
+	return &CompositeMLDSAKeyPair{PublicKey: privateKey.Public(), PrivateKey: privateKey}, nil
+}
+
+// MarshalCompositeMLDSAPublicKey serializes a composite public key as the ML-DSA public key followed by the raw Ed25519
+// public key or the uncompressed ECDSA point.
+func MarshalCompositeMLDSAPublicKey(publicKey *CompositeMLDSAPublicKey) ([]byte, error) {
+	if publicKey == nil {
+		return nil, fmt.Errorf("%w: composite ML-DSA public key is nil or incomplete", ErrInvalidKey)
+	}
+	var traditionalBytes []byte
+	switch traditionalPublicKey := publicKey.TraditionalPublicKey.(type) {
+	case ed25519.PublicKey:
+		traditionalBytes = traditionalPublicKey
+	case *ecdsa.PublicKey:
+		var marshalError error
+		if traditionalBytes, marshalError = traditionalPublicKey.Bytes(); marshalError != nil {
+			return nil, fmt.Errorf("ECDSA public key: %w", marshalError)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported traditional public key type %T", ErrInvalidKey, publicKey.TraditionalPublicKey)
+	}
+	return append(append([]byte(nil), publicKey.MLDSAPublicKey...), traditionalBytes...), nil
+}
+
+// UnmarshalCompositeMLDSAPublicKey deserializes a composite public key produced by MarshalCompositeMLDSAPublicKey.
+func UnmarshalCompositeMLDSAPublicKey(algorithm CompositeMLDSAAlgorithm, data []byte) (publicKey *CompositeMLDSAPublicKey, unmarshalError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			unmarshalError = newPanicError("UnmarshalCompositeMLDSAPublicKey", recoveredPanic)
+		}
+	}()
+	parameterSet, curve, componentsError := algorithm.components()
+	if componentsError != nil {
+		return nil, componentsError
+	}
+	parameters, _ := parameterSet.internalParameters()
+	if len(data) <= parameters.PublicKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v public key", algorithm), len(data), 0)
+	}
+	mldsaPublicKey, traditionalBytes := data[:parameters.PublicKeySize()], data[parameters.PublicKeySize():]
+	var traditionalPublicKey crypto.PublicKey
+	if curve == nil {
+		if len(traditionalBytes) != ed25519.PublicKeySize {
+			return nil, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v Ed25519 public key", algorithm), len(traditionalBytes), ed25519.PublicKeySize)
+		}
+		traditionalPublicKey = ed25519.PublicKey(bytes.Clone(traditionalBytes))
+	} else {
+		ecdsaPublicKey, parseError := ecdsa.ParseUncompressedPublicKey(curve, traditionalBytes)
+		if parseError != nil {
+			return nil, fmt.Errorf("%v ECDSA public key: %w", algorithm, parseError)
+		}
+		traditionalPublicKey = ecdsaPublicKey
+	}
+	return &CompositeMLDSAPublicKey{Algorithm: algorithm, MLDSAPublicKey: bytes.Clone(mldsaPublicKey), TraditionalPublicKey: traditionalPublicKey}, nil
+}
+
+// MarshalCompositeMLDSAPrivateKey serializes a composite private key as the 32-byte ML-DSA seed followed by the 32-byte
+// Ed25519 seed or, for ECDSA, the DER ECPrivateKey structure of RFC 5915, as the draft requires.
+func MarshalCompositeMLDSAPrivateKey(privateKey *CompositeMLDSAPrivateKey) ([]byte, error) {
+	if privateKey == nil {
+		return nil, fmt.Errorf("%w: composite ML-DSA private key is nil or incomplete", ErrInvalidKey)
+	}
+	var traditionalBytes []byte
+	switch traditionalPrivateKey := privateKey.TraditionalPrivateKey.(type) {
+	case ed25519.PrivateKey:
+		traditionalBytes = traditionalPrivateKey.Seed()
+	case *ecdsa.PrivateKey:
+		var marshalError error
+		if traditionalBytes, marshalError = x509.MarshalECPrivateKey(traditionalPrivateKey); marshalError != nil {
+			return nil, fmt.Errorf("ECDSA private key: %w", marshalError)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported traditional private key type %T", ErrInvalidKey, privateKey.TraditionalPrivateKey)
+	}
+	return append(append([]byte(nil), privateKey.MLDSASeed[:]...), traditionalBytes...), nil
+}
+
+// UnmarshalCompositeMLDSAPrivateKey deserializes a composite private key produced by MarshalCompositeMLDSAPrivateKey.
+func UnmarshalCompositeMLDSAPrivateKey(algorithm CompositeMLDSAAlgorithm, data []byte) (privateKey *CompositeMLDSAPrivateKey, unmarshalError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			unmarshalError = newPanicError("UnmarshalCompositeMLDSAPrivateKey", recoveredPanic)
+		}
+	}()
+	_, curve, componentsError := algorithm.components()
+	if componentsError != nil {
+		return nil, componentsError
+	}
+	if len(data) <= MLDSASeedSize {
+		return nil, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v private key", algorithm), len(data), 0)
+	}
+	seed, traditionalBytes := (*[MLDSASeedSize]byte)(data[:MLDSASeedSize]), data[MLDSASeedSize:]
+	var traditionalPrivateKey crypto.Signer
+	if curve == nil {
+		if len(traditionalBytes) != ed25519.SeedSize {
+			return nil, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v Ed25519 private key", algorithm), len(traditionalBytes), ed25519.SeedSize)
+		}
+		traditionalPrivateKey = ed25519.NewKeyFromSeed(traditionalBytes)
+	} else {
+		ecdsaPrivateKey, parseError := x509.ParseECPrivateKey(traditionalBytes)
+		if parseError != nil {
+			return nil, fmt.Errorf("%w: %v ECDSA private key: %w", ErrInvalidKey, algorithm, parseError)
+		}
+		if ecdsaPrivateKey.Curve != curve {
+			return nil, fmt.Errorf("%w: %v ECDSA private key is on %s", ErrInvalidKey, algorithm, ecdsaPrivateKey.Curve.Params().Name)
+		}
+		traditionalPrivateKey = ecdsaPrivateKey
+	}
+	return newCompositeMLDSAPrivateKey(algorithm, seed, traditionalPrivateKey)
+}
+
+// compositeMLDSAMessageRepresentative computes M' = Prefix || Label || len(ctx) || ctx || SHA-512(M), which both
+// components sign, so neither component signature can be separated and reused on its own.
+func compositeMLDSAMessageRepresentative(algorithm CompositeMLDSAAlgorithm, messageBytes []byte, contextBytes []byte) ([]byte, error) {
+	if len(contextBytes) > MLDSAMaxContextSize {
+		return nil, fmt.Errorf("%w: got %d bytes, maximum is %d", ErrContextTooLong, len(contextBytes), MLDSAMaxContextSize)
+	}
+	digest := sha512.Sum512(messageBytes)
+	label := algorithm.label()
+	representative := make([]byte, 0, len(compositeMLDSAPrefix)+len(label)+1+len(contextBytes)+len(digest))
+	representative = append(representative, compositeMLDSAPrefix...)
+	representative = append(representative, label...)
+	representative = append(representative, byte(len(contextBytes)))
+	representative = append(representative, contextBytes...)
+	return append(representative, digest[:]...), nil
+}
+
+// ecdsaDigest hashes M' for the ECDSA component: SHA-256 on P-256 and SHA-384 on P-384.
+func ecdsaDigest(curve elliptic.Curve, representative []byte) []byte {
+	if curve == elliptic.P256() {
+		digest := sha256.Sum256(representative)
+		return digest[:]
+	}
+	digest := sha512.Sum384(representative)
+	return digest[:]
+}
+
+// CompositeMLDSASign signs a message with a composite private key, bound to a context string of at most MLDSAMaxContextSize bytes.
+// The signature is the ML-DSA signature followed by the Ed25519 signature or the DER-encoded ECDSA signature.
+func CompositeMLDSASign(privateKey *CompositeMLDSAPrivateKey, messageBytes []byte, contextBytes []byte) (signatureBytes []byte, signError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			signError = newPanicError("CompositeMLDSASign", recoveredPanic)
+		}
+	}()
+	if privateKey == nil || privateKey.TraditionalPrivateKey == nil {
+		return nil, fmt.Errorf("%w: composite ML-DSA private key is nil or incomplete", ErrInvalidKey)
+	}
+	parameterSet, curve, componentsError := privateKey.Algorithm.components()
+	if componentsError != nil {
+		return nil, componentsError
+	}
+	representative, representativeError := compositeMLDSAMessageRepresentative(privateKey.Algorithm, messageBytes, contextBytes)
+	if representativeError != nil {
+		return nil, representativeError
+	}
+	mldsaSignature, mldsaError := MLDSASignWithContext(parameterSet, privateKey.MLDSAPrivateKey, representative, []byte(privateKey.Algorithm.label()))
+	if mldsaError != nil {
+		return nil, fmt.Errorf("%v ML-DSA component: %w", privateKey.Algorithm, mldsaError)
+	}
+	var traditionalSignature []byte
+	var traditionalError error
+	switch traditionalPrivateKey := privateKey.TraditionalPrivateKey.(type) {
+	case ed25519.PrivateKey:
+		if curve != nil {
+			return nil, fmt.Errorf("%w: %v requires an ECDSA private key, got Ed25519", ErrInvalidKey, privateKey.Algorithm)
+		}
+		traditionalSignature = ed25519.Sign(traditionalPrivateKey, representative)
+	case *ecdsa.PrivateKey:
+		if curve == nil || traditionalPrivateKey.Curve != curve {
+			return nil, fmt.Errorf("%w: %v ECDSA private key is on the wrong curve", ErrInvalidKey, privateKey.Algorithm)
+		}
+		traditionalSignature, traditionalError = ecdsa.SignASN1(rand.Reader, traditionalPrivateKey, ecdsaDigest(curve, representative))
+	default:
+		return nil, fmt.Errorf("%w: unsupported traditional private key type %T", ErrInvalidKey, privateKey.TraditionalPrivateKey)
+	}
+	if traditionalError != nil {
+		return nil, fmt.Errorf("%v traditional component: %w", privateKey.Algorithm, traditionalError)
+	}
+	return append(mldsaSignature, traditionalSignature...), nil
+}
+
+// CheckSignature is like CompositeMLDSAVerify, but reports an invalid signature as an error matching ErrVerificationFailed.
+func (publicKey *CompositeMLDSAPublicKey) CheckSignature(messageBytes []byte, signatureBytes []byte, contextBytes []byte) error {
+	isSignatureValid, verifyError := CompositeMLDSAVerify(publicKey, messageBytes, signatureBytes, contextBytes)
+	if verifyError != nil {
+		return verifyError
+	}
+	if !isSignatureValid {
+		return fmt.Errorf("%v: %w", publicKey.Algorithm, ErrVerificationFailed)
+	}
+	return nil
+}
+
+// CompositeMLDSAVerify verifies a composite signature. It is valid only if both the ML-DSA and the traditional component
+// signatures verify over the message and context string.
+func CompositeMLDSAVerify(publicKey *CompositeMLDSAPublicKey, messageBytes []byte, signatureBytes []byte, contextBytes []byte) (isSignatureValid bool, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			verifyError = newPanicError("CompositeMLDSAVerify", recoveredPanic)
+		}
+	}()
+	if publicKey == nil || publicKey.TraditionalPublicKey == nil {
+		return false, fmt.Errorf("%w: composite ML-DSA public key is nil or incomplete", ErrInvalidKey)
+	}
+	parameterSet, curve, componentsError := publicKey.Algorithm.components()
+	if componentsError != nil {
+		return false, componentsError
+	}
+	representative, representativeError := compositeMLDSAMessageRepresentative(publicKey.Algorithm, messageBytes, contextBytes)
+	if representativeError != nil {
+		return false, representativeError
+	}
+	parameters, _ := parameterSet.internalParameters()
+	if len(signatureBytes) <= parameters.SignatureSize() {
+		return false, nil
+	}
+	mldsaSignature, traditionalSignature := signatureBytes[:parameters.SignatureSize()], signatureBytes[parameters.SignatureSize():]
+	isMLDSAValid, mldsaError := MLDSAVerifyWithContext(parameterSet, publicKey.MLDSAPublicKey, representative, mldsaSignature, []byte(publicKey.Algorithm.label()))
+	if mldsaError != nil {
+		return false, fmt.Errorf("%v ML-DSA component: %w", publicKey.Algorithm, mldsaError)
+	}
+	var isTraditionalValid bool
+	switch traditionalPublicKey := publicKey.TraditionalPublicKey.(type) {
+	case ed25519.PublicKey:
+		if curve != nil || len(traditionalPublicKey) != ed25519.PublicKeySize {
+			return false, fmt.Errorf("%v requires an ECDSA public key", publicKey.Algorithm)
+		}
+		isTraditionalValid = ed25519.Verify(traditionalPublicKey, representative, traditionalSignature)
+	case *ecdsa.PublicKey:
+		if curve == nil || traditionalPublicKey.Curve != curve {
+			return false, fmt.Errorf("%v ECDSA public key is on the wrong curve", publicKey.Algorithm)
+		}
+		isTraditionalValid = ecdsa.VerifyASN1(traditionalPublicKey, ecdsaDigest(curve, representative), traditionalSignature)
+	default:
+		return false, fmt.Errorf("%w: unsupported traditional public key type %T", ErrInvalidKey, publicKey.TraditionalPublicKey)
+	}
+	return isMLDSAValid && isTraditionalValid, nil
+}
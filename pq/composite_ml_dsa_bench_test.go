@@ -0,0 +1,45 @@
+package pq
+
+import "testing"
+
+func BenchmarkCompositeMLDSASign(b *testing.B) {
+	for _, algorithm := range CompositeMLDSAAlgorithms {
+		b.Run(algorithm.String(), func(b *testing.B) {
+			keyPair, err := GenerateCompositeMLDSAKeyPair(algorithm)
+			if err != nil {
+				b.Fatalf("failed to generate composite key pair: %v", err)
+			}
+			message := []byte("benchmark message")
+			b.ResetTimer()
+			for b.Loop() {
+				_, signErr := CompositeMLDSASign(keyPair.PrivateKey, message, nil)
+				if signErr != nil {
+					b.Fatalf("signing failed: %v", signErr)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCompositeMLDSAVerify(b *testing.B) {
+	for _, algorithm := range CompositeMLDSAAlgorithms {
+		b.Run(algorithm.String(), func(b *testing.B) {
+			keyPair, err := GenerateCompositeMLDSAKeyPair(algorithm)
+			if err != nil {
+				b.Fatalf("failed to generate composite key pair: %v", err)
+			}
+			message := []byte("benchmark message")
+			signature, signErr := CompositeMLDSASign(keyPair.PrivateKey, message, nil)
+			if signErr != nil {
+				b.Fatalf("signing failed: %v", signErr)
+			}
+			b.ResetTimer()
+			for b.Loop() {
+				_, verifyErr := CompositeMLDSAVerify(keyPair.PublicKey, message, signature, nil)
+				if verifyErr != nil {
+					b.Fatalf("verifying failed: %v", verifyErr)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package pq
+
+import "testing"
+
+func FuzzCompositeMLDSAVerify(f *testing.F) {
+	keyPair, _ := GenerateCompositeMLDSAKeyPair(CompositeMLDSA65Ed25519)
+	signature, _ := CompositeMLDSASign(keyPair.PrivateKey, []byte("msg"), nil)
+	f.Add([]byte("msg"), signature)
+	f.Fuzz(func(t *testing.T, msg []byte, fuzzedSignature []byte) {
+		isValid, err := CompositeMLDSAVerify(keyPair.PublicKey, msg, fuzzedSignature, nil)
+		if err != nil {
+			t.Fatalf("verifying failed: %v", err)
+		}
+		if isValid && (string(msg) != "msg" || string(fuzzedSignature) != string(signature)) {
+			t.Error("only the original message and signature should verify")
+		}
+	})
+}
@@ -0,0 +1,237 @@
+package pq
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompositeMLDSASignAndVerify(t *testing.T) {
+	for _, algorithm := range CompositeMLDSAAlgorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			keyPair, err := GenerateCompositeMLDSAKeyPair(algorithm)
+			require.NoError(t, err, "failed to generate composite key pair")
+			require.Equal(t, algorithm, keyPair.PublicKey.Algorithm, "expected public key to carry its algorithm")
+
+			msg := []byte("test message")
+			ctx := []byte("ctx")
+			signature, err := CompositeMLDSASign(keyPair.PrivateKey, msg, ctx)
+			require.NoError(t, err, "failed to sign")
+
+			isValid, err := CompositeMLDSAVerify(keyPair.PublicKey, msg, signature, ctx)
+			require.NoError(t, err, "failed to verify")
+			require.True(t, isValid, "expected signature to verify")
+
+			isValid, err = CompositeMLDSAVerify(keyPair.PublicKey, []byte("other message"), signature, ctx)
+			require.NoError(t, err, "failed to verify")
+			require.False(t, isValid, "expected signature over a different message to fail")
+
+			isValid, err = CompositeMLDSAVerify(keyPair.PublicKey, msg, signature, nil)
+			require.NoError(t, err, "failed to verify")
+			require.False(t, isValid, "expected signature under a different context to fail")
+
+			publicKeyBytes, err := MarshalCompositeMLDSAPublicKey(keyPair.PublicKey)
+			require.NoError(t, err, "failed to marshal public key")
+			publicKey, err := UnmarshalCompositeMLDSAPublicKey(algorithm, publicKeyBytes)
+			require.NoError(t, err, "failed to unmarshal public key")
+			require.Equal(t, keyPair.PublicKey.MLDSAPublicKey, publicKey.MLDSAPublicKey, "expected ML-DSA public key to match")
+
+			privateKeyBytes, err := MarshalCompositeMLDSAPrivateKey(keyPair.PrivateKey)
+			require.NoError(t, err, "failed to marshal private key")
+			privateKey, err := UnmarshalCompositeMLDSAPrivateKey(algorithm, privateKeyBytes)
+			require.NoError(t, err, "failed to unmarshal private key")
+			require.Equal(t, keyPair.PrivateKey.MLDSAPrivateKey, privateKey.MLDSAPrivateKey, "expected ML-DSA private key to be rederived from its seed")
+			if ecdsaPrivateKey, ok := keyPair.PrivateKey.TraditionalPrivateKey.(*ecdsa.PrivateKey); ok {
+				parsedPrivateKey, err := x509.ParseECPrivateKey(privateKeyBytes[MLDSASeedSize:])
+				require.NoError(t, err, "expected the ECDSA component to be an ECPrivateKey")
+				require.True(t, ecdsaPrivateKey.Equal(parsedPrivateKey), "expected the ECPrivateKey to hold the ECDSA key")
+			}
+			rederivedPublicKeyBytes, err := MarshalCompositeMLDSAPublicKey(privateKey.Public())
+			require.NoError(t, err, "failed to marshal rederived public key")
+			require.Equal(t, publicKeyBytes, rederivedPublicKeyBytes, "expected rederived public key to match")
+
+			signature, err = CompositeMLDSASign(privateKey, msg, ctx)
+			require.NoError(t, err, "failed to sign with unmarshalled private key")
+			isValid, err = CompositeMLDSAVerify(publicKey, msg, signature, ctx)
+			require.NoError(t, err, "failed to verify with unmarshalled public key")
+			require.True(t, isValid, "expected signature to verify")
+		})
+	}
+}
+
+// TestCompositeMLDSAComponents checks each component signature independently against M' = Prefix || Label || len(ctx) || ctx || SHA-512(M),
+// with the ML-DSA component signed under the label as its context string.
+func TestCompositeMLDSAComponents(t *testing.T) {
+	for _, algorithm := range CompositeMLDSAAlgorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			keyPair, err := GenerateCompositeMLDSAKeyPair(algorithm)
+			require.NoError(t, err, "failed to generate composite key pair")
+			msg := []byte("test message")
+			signature, err := CompositeMLDSASign(keyPair.PrivateKey, msg, []byte("ctx"))
+			require.NoError(t, err, "failed to sign")
+
+			digest := sha512.Sum512(msg)
+			label := "COMPSIG-" + algorithm.String()
+			representative := append([]byte("CompositeAlgorithmSignatures2025"+label+"\x03ctx"), digest[:]...)
+
+			parameterSet, curve, err := algorithm.components()
+			require.NoError(t, err, "failed to get components")
+			parameters, err := parameterSet.internalParameters()
+			require.NoError(t, err, "failed to get parameters")
+			mldsaSignature, traditionalSignature := signature[:parameters.SignatureSize()], signature[parameters.SignatureSize():]
+
+			isValid, err := MLDSAVerifyWithContext(parameterSet, keyPair.PublicKey.MLDSAPublicKey, representative, mldsaSignature, []byte(label))
+			require.NoError(t, err, "failed to verify ML-DSA component")
+			require.True(t, isValid, "expected ML-DSA component to verify over M'")
+
+			if curve == nil {
+				require.True(t, ed25519.Verify(keyPair.PublicKey.TraditionalPublicKey.(ed25519.PublicKey), representative, traditionalSignature), "expected Ed25519 component to verify over M'")
+			} else {
+				require.True(t, ecdsa.VerifyASN1(keyPair.PublicKey.TraditionalPublicKey.(*ecdsa.PublicKey), ecdsaDigest(curve, representative), traditionalSignature), "expected ECDSA component to verify over M'")
+			}
+		})
+	}
+}
+
+// For TestCompositeMLDSADraftVectors: the draft-ietf-lamps-pq-composite-sigs-14 vectors of the supported algorithms. The
+// traditional private key is the raw Ed25519 seed or ECDSA scalar, and the traditional public key the raw Ed25519 key or
+// uncompressed ECDSA point. Each signature is over the shared message with an empty context.
+func TestCompositeMLDSADraftVectors(t *testing.T) {
+	data, err := os.ReadFile("testdata/composite-sigs/test-vectors-subset.json")
+	require.NoError(t, err, "failed to read composite signature test vectors")
+	var file struct {
+		Message hexBytes `json:"message"`
+		Vectors []struct {
+			Algorithm             string   `json:"algorithm"`
+			MLDSASeed             hexBytes `json:"mldsaSeed"`
+			MLDSAPublicKey        hexBytes `json:"mldsaPublicKey"`
+			TraditionalPublicKey  hexBytes `json:"traditionalPublicKey"`
+			TraditionalPrivateKey hexBytes `json:"traditionalPrivateKey"`
+			Signature             hexBytes `json:"signature"`
+		} `json:"vectors"`
+	}
+	require.NoError(t, json.Unmarshal(data, &file), "failed to parse composite signature test vectors")
+	require.Len(t, file.Vectors, len(CompositeMLDSAAlgorithms))
+
+	for i, vector := range file.Vectors {
+		algorithm := CompositeMLDSAAlgorithms[i]
+		t.Run(vector.Algorithm, func(t *testing.T) {
+			require.Equal(t, algorithm.String(), vector.Algorithm, "unexpected vector order")
+			_, curve, err := algorithm.components()
+			require.NoError(t, err, "failed to get components")
+			var traditionalPrivateKey crypto.Signer
+			if curve == nil {
+				traditionalPrivateKey = ed25519.NewKeyFromSeed(vector.TraditionalPrivateKey)
+			} else {
+				traditionalPrivateKey, err = ecdsa.ParseRawPrivateKey(curve, vector.TraditionalPrivateKey)
+				require.NoError(t, err, "failed to parse ECDSA private key")
+			}
+			privateKey := &CompositeMLDSAPrivateKey{Algorithm: algorithm, TraditionalPrivateKey: traditionalPrivateKey}
+			require.Len(t, vector.MLDSASeed, MLDSASeedSize)
+			copy(privateKey.MLDSASeed[:], vector.MLDSASeed)
+
+			publicKeyBytes, err := MarshalCompositeMLDSAPublicKey(privateKey.Public())
+			require.NoError(t, err, "failed to marshal public key derived from the private key")
+			require.Equal(t, append(append([]byte{}, vector.MLDSAPublicKey...), vector.TraditionalPublicKey...), publicKeyBytes, "composite public key mismatch")
+
+			publicKey, err := UnmarshalCompositeMLDSAPublicKey(algorithm, publicKeyBytes)
+			require.NoError(t, err, "failed to unmarshal public key")
+			isValid, err := CompositeMLDSAVerify(publicKey, file.Message, vector.Signature, nil)
+			require.NoError(t, err, "failed to verify")
+			require.True(t, isValid, "expected draft signature to verify")
+			isValid, err = CompositeMLDSAVerify(publicKey, file.Message, vector.Signature, []byte("ctx"))
+			require.NoError(t, err, "failed to verify")
+			require.False(t, isValid, "expected draft signature under a different context to fail")
+		})
+	}
+}
+
+func TestCompositeMLDSARequiresBothComponents(t *testing.T) {
+	keyPair, err := GenerateCompositeMLDSAKeyPair(CompositeMLDSA65ECDSAP256)
+	require.NoError(t, err, "failed to generate composite key pair")
+	otherKeyPair, err := GenerateCompositeMLDSAKeyPair(CompositeMLDSA65ECDSAP256)
+	require.NoError(t, err, "failed to generate composite key pair")
+	msg := []byte("test message")
+	signature, err := CompositeMLDSASign(keyPair.PrivateKey, msg, nil)
+	require.NoError(t, err, "failed to sign")
+	otherSignature, err := CompositeMLDSASign(otherKeyPair.PrivateKey, msg, nil)
+	require.NoError(t, err, "failed to sign")
+
+	mldsaSize := mldsa65.SignatureSize
+	// A valid ML-DSA component with a foreign ECDSA component, and the reverse, must both fail.
+	mixedSignatures := [][]byte{
+		append(bytes.Clone(signature[:mldsaSize]), otherSignature[mldsaSize:]...),
+		append(bytes.Clone(otherSignature[:mldsaSize]), signature[mldsaSize:]...),
+	}
+	for i, mixedSignature := range mixedSignatures {
+		isValid, err := CompositeMLDSAVerify(keyPair.PublicKey, msg, mixedSignature, nil)
+		require.NoError(t, err, "failed to verify")
+		require.False(t, isValid, "expected mixed signature %d to fail", i)
+	}
+
+	// A component key stripped out and used with a different composite algorithm must not verify.
+	strippedPublicKey := &CompositeMLDSAPublicKey{Algorithm: CompositeMLDSA65ECDSAP384, MLDSAPublicKey: keyPair.PublicKey.MLDSAPublicKey, TraditionalPublicKey: keyPair.PublicKey.TraditionalPublicKey}
+	isValid, err := CompositeMLDSAVerify(strippedPublicKey, msg, signature, nil)
+	require.Error(t, err, "expected error for an ECDSA P-256 key used as P-384")
+	require.False(t, isValid, "expected invalid result")
+
+	isValid, err = MLDSAVerify(MLDSA65, keyPair.PublicKey.MLDSAPublicKey, msg, signature[:mldsaSize])
+	require.NoError(t, err, "failed to verify")
+	require.False(t, isValid, "expected the ML-DSA component not to verify as a plain ML-DSA signature")
+
+	isValid, err = CompositeMLDSAVerify(keyPair.PublicKey, msg, signature[:mldsaSize], nil)
+	require.NoError(t, err, "failed to verify")
+	require.False(t, isValid, "expected signature without a traditional component to fail")
+}
+
+func TestCompositeMLDSAInvalidInput(t *testing.T) {
+	unsupported := CompositeMLDSAAlgorithm(0)
+	require.Equal(t, "CompositeMLDSAAlgorithm(0)", unsupported.String(), "unexpected name for unsupported algorithm")
+	_, err := unsupported.OID()
+	require.Error(t, err, "expected error for OID of unsupported algorithm")
+	_, err = GenerateCompositeMLDSAKeyPair(unsupported)
+	require.Error(t, err, "expected error for unsupported algorithm")
+
+	oid, err := CompositeMLDSA65Ed25519.OID()
+	require.NoError(t, err, "failed to get OID")
+	require.Equal(t, "1.3.6.1.5.5.7.6.48", oid.String(), "unexpected OID for MLDSA65-Ed25519-SHA512")
+
+	keyPair, err := GenerateCompositeMLDSAKeyPair(CompositeMLDSA65Ed25519)
+	require.NoError(t, err, "failed to generate composite key pair")
+	_, err = CompositeMLDSASign(nil, []byte("msg"), nil)
+	require.Error(t, err, "expected error for nil private key")
+	_, err = CompositeMLDSAVerify(nil, []byte("msg"), nil, nil)
+	require.Error(t, err, "expected error for nil public key")
+	_, err = CompositeMLDSASign(keyPair.PrivateKey, []byte("msg"), make([]byte, MLDSAMaxContextSize+1))
+	require.True(t, errors.Is(err, ErrContextTooLong), "expected ErrContextTooLong, got %v", err)
+
+	publicKeyBytes, err := MarshalCompositeMLDSAPublicKey(keyPair.PublicKey)
+	require.NoError(t, err, "failed to marshal public key")
+	_, err = UnmarshalCompositeMLDSAPublicKey(CompositeMLDSA65ECDSAP256, publicKeyBytes)
+	require.Error(t, err, "expected error for an Ed25519 composite public key unmarshalled as ECDSA P-256")
+	_, err = UnmarshalCompositeMLDSAPublicKey(CompositeMLDSA65Ed25519, publicKeyBytes[:len(publicKeyBytes)-1])
+	require.Error(t, err, "expected error for truncated public key")
+	privateKeyBytes, err := MarshalCompositeMLDSAPrivateKey(keyPair.PrivateKey)
+	require.NoError(t, err, "failed to marshal private key")
+	_, err = UnmarshalCompositeMLDSAPrivateKey(CompositeMLDSA65Ed25519, privateKeyBytes[:MLDSASeedSize])
+	require.Error(t, err, "expected error for private key without a traditional component")
+	_, err = UnmarshalCompositeMLDSAPrivateKey(CompositeMLDSA87ECDSAP384, privateKeyBytes)
+	require.ErrorIs(t, err, ErrInvalidKey, "expected error for an Ed25519 seed unmarshalled as an ECDSA P-384 key")
+
+	ecdsaKeyPair, err := GenerateCompositeMLDSAKeyPair(CompositeMLDSA65ECDSAP256)
+	require.NoError(t, err, "failed to generate composite key pair")
+	privateKeyBytes, err = MarshalCompositeMLDSAPrivateKey(ecdsaKeyPair.PrivateKey)
+	require.NoError(t, err, "failed to marshal private key")
+	_, err = UnmarshalCompositeMLDSAPrivateKey(CompositeMLDSA65ECDSAP384, privateKeyBytes)
+	require.ErrorIs(t, err, ErrInvalidKey, "expected error for a P-256 ECPrivateKey unmarshalled as P-384")
+}
@@ -0,0 +1,281 @@
+package pq
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/cloudflare/circl/kem"
+)
+
+// An envelope encrypts a payload once under a fresh random data key and wraps that key for every recipient with HPKE
+// (RFC 9180) over the recipient's ML-KEM key. Version 1 is encoded as:
+//
+//	"PQEV" || version (1 byte) || recipient count (uint16)
+//	per recipient: key ID length (1 byte) || key ID || HPKE KEM ID (uint16) || encapsulated key || wrapped data key
+//	AES-256-GCM(data key, zero nonce, payload, AAD = everything above || associated data)
+//
+// Integers are big-endian. The data key is never reused, so the payload needs no random nonce. Because the payload is
+// authenticated together with the whole header, removing, reordering or altering a recipient makes opening fail.
+
+const (
+	// envelopeMagic starts every envelope.
+	envelopeMagic = "PQEV"
+	// envelopeVersion1 is the only envelope version.
+	envelopeVersion1 = 1
+	// envelopeDataKeySize is the size of the AES-256-GCM data key.
+	envelopeDataKeySize = 32
+	// envelopeWrappedKeySize is the size of the data key sealed with AES-256-GCM by HPKE.
+	envelopeWrappedKeySize = envelopeDataKeySize + 16
+	// EnvelopeMaxKeyIDSize is the maximum size in bytes of a recipient key ID.
+	EnvelopeMaxKeyIDSize = 255
+	// EnvelopeMaxRecipients is the maximum number of recipients of an envelope.
+	EnvelopeMaxRecipients = 65535
+)
+
+// envelopeHPKEInfo is the HPKE info string that binds a wrapped data key to this envelope format.
+var envelopeHPKEInfo = []byte("gopq envelope v1 data key")
+
+// EnvelopeRecipient is a recipient of an envelope: an ML-KEM public key and the key ID the recipient finds it by.
+type EnvelopeRecipient struct {
+	// KeyID identifies the recipient's key, 1 to EnvelopeMaxKeyIDSize bytes, unique within the envelope.
+	KeyID string
+	// PublicKey is the recipient's ML-KEM-512, ML-KEM-768 or ML-KEM-1024 public key.
+	PublicKey kem.PublicKey
+}
+
+// envelopeEntry is a parsed recipient entry.
+type envelopeEntry struct {
+	keyID           string
+	kemID           HPKEKEM
+	encapsulatedKey []byte
+	wrappedKey      []byte
+}
+
+// envelopeHPKESuite returns the suite wrapping the data key for a recipient key of the KEM.
+func envelopeHPKESuite(kemID HPKEKEM) HPKESuite {
+	return HPKESuite{KEM: kemID, KDF: HPKEKDFHKDFSHA256, AEAD: HPKEAEADAES256GCM}
+}
+
+// envelopeKEMOf returns the HPKE KEM of an envelope recipient key, which must be a FIPS 203 ML-KEM key.
+func envelopeKEMOf(scheme kem.Scheme) (HPKEKEM, error) {
+	parameterSet, err := mlkemParameterSetOf(scheme)
+	if err != nil {
+		return 0, err
+	}
+	switch parameterSet {
+	case MLKEM512:
+		return HPKEKEMMLKEM512, nil
+	case MLKEM768:
+		return HPKEKEMMLKEM768, nil
+	case MLKEM1024:
+		return HPKEKEMMLKEM1024, nil
+	default:
+		return 0, fmt.Errorf("%w: %v keys cannot receive envelopes", ErrUnknownAlgorithm, parameterSet)
+	}
+}
+
+// SealEnvelope encrypts plaintext for every recipient. associatedData is authenticated but not encrypted, and must be
+// given again to OpenEnvelope.
+func SealEnvelope(recipients []EnvelopeRecipient, plaintext []byte, associatedData []byte) (envelope []byte, sealError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			envelope, sealError = nil, newPanicError("SealEnvelope", recoveredPanic)
+		}
+	}()
+	if len(recipients) == 0 || len(recipients) > EnvelopeMaxRecipients {
+		return nil, fmt.Errorf("%w: an envelope needs 1 to %d recipients, got %d", ErrInvalidOptions, EnvelopeMaxRecipients, len(recipients))
+	}
+	dataKey := make([]byte, envelopeDataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, fmt.Errorf("generating envelope data key: %w", err)
+	}
+
+	envelope = append([]byte(envelopeMagic), envelopeVersion1)
+	envelope = binary.BigEndian.AppendUint16(envelope, uint16(len(recipients)))
+	keyIDs := make(map[string]bool, len(recipients))
+	for _, recipient := range recipients {
+		if len(recipient.KeyID) == 0 || len(recipient.KeyID) > EnvelopeMaxKeyIDSize {
+			return nil, fmt.Errorf("%w: envelope key ID must be 1 to %d bytes, got %d", ErrInvalidOptions, EnvelopeMaxKeyIDSize, len(recipient.KeyID))
+		}
+		if keyIDs[recipient.KeyID] {
+			return nil, fmt.Errorf("%w: duplicate envelope key ID %q", ErrInvalidOptions, recipient.KeyID)
+		}
+		keyIDs[recipient.KeyID] = true
+		if recipient.PublicKey == nil {
+			return nil, fmt.Errorf("%w: nil public key for envelope key ID %q", ErrInvalidKey, recipient.KeyID)
+		}
+		kemID, err := envelopeKEMOf(recipient.PublicKey.Scheme())
+		if err != nil {
+			return nil, fmt.Errorf("envelope key ID %q: %w", recipient.KeyID, err)
+		}
+		encapsulatedKey, wrappedKey, err := HPKESeal(envelopeHPKESuite(kemID), recipient.PublicKey, envelopeHPKEInfo, []byte(recipient.KeyID), dataKey, nil)
+		if err != nil {
+			return nil, fmt.Errorf("envelope key ID %q: %w", recipient.KeyID, err)
+		}
+		envelope = append(envelope, byte(len(recipient.KeyID)))
+		envelope = append(envelope, recipient.KeyID...)
+		envelope = binary.BigEndian.AppendUint16(envelope, uint16(kemID))
+		envelope = append(append(envelope, encapsulatedKey...), wrappedKey...)
+	}
+
+	payloadAEAD, err := newEnvelopePayloadAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	header := envelope[:len(envelope):len(envelope)]
+	envelope = payloadAEAD.Seal(envelope, make([]byte, payloadAEAD.NonceSize()), plaintext, append(header, associatedData...))
+	Logger().Debug("SealEnvelope", "recipients", len(recipients), "plaintext_size", len(plaintext), "envelope_size", len(envelope))
+	return envelope, nil
+}
+
+// OpenEnvelope decrypts an envelope with the private key of the recipient with the given key ID.
+//
+// An envelope whose header is truncated or malformed, or whose payload is shorter than the AES-GCM tag, returns a
+// *FormatError matching ErrInvalidEnvelope, and an envelope
+// without the key ID returns ErrRecipientNotFound. Any other change to the envelope, including a truncated payload, and
+// a private key that does not match the key ID, including one of another parameter set, return ErrDecryptionFailed.
+func OpenEnvelope(privateKey kem.PrivateKey, keyID string, envelope []byte, associatedData []byte) (plaintext []byte, openError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			plaintext, openError = nil, newPanicError("OpenEnvelope", recoveredPanic)
+		}
+	}()
+	if privateKey == nil {
+		return nil, fmt.Errorf("%w: envelope private key is nil", ErrInvalidKey)
+	}
+	kemID, err := envelopeKEMOf(privateKey.Scheme())
+	if err != nil {
+		return nil, err
+	}
+	entries, headerSize, err := parseEnvelope(envelope)
+	if err != nil {
+		return nil, err
+	}
+	var entry *envelopeEntry
+	for i := range entries {
+		if entries[i].keyID == keyID {
+			entry = &entries[i]
+			break
+		}
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("%w: key ID %q", ErrRecipientNotFound, keyID)
+	}
+	if entry.kemID != kemID {
+		return nil, fmt.Errorf("%w: envelope key ID %q is a %v recipient, not %v", ErrDecryptionFailed, keyID, entry.kemID, kemID)
+	}
+
+	dataKey, err := HPKEOpen(envelopeHPKESuite(kemID), privateKey, entry.encapsulatedKey, envelopeHPKEInfo, []byte(keyID), entry.wrappedKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	payloadAEAD, err := newEnvelopePayloadAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	header := envelope[:headerSize:headerSize]
+	plaintext, err = payloadAEAD.Open(nil, make([]byte, payloadAEAD.NonceSize()), envelope[headerSize:], append(header, associatedData...))
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	Logger().Debug("OpenEnvelope", "recipients", len(entries), "plaintext_size", len(plaintext))
+	return plaintext, nil
+}
+
+// EnvelopeKeyIDs returns the key IDs of the recipients of an envelope, in order, so that a reader can pick its key.
+func EnvelopeKeyIDs(envelope []byte) ([]string, error) {
+	entries, _, err := parseEnvelope(envelope)
+	if err != nil {
+		return nil, err
+	}
+	keyIDs := make([]string, len(entries))
+	for i, entry := range entries {
+		keyIDs[i] = entry.keyID
+	}
+	return keyIDs, nil
+}
+
+// newEnvelopePayloadAEAD returns AES-256-GCM keyed with the data key.
+func newEnvelopePayloadAEAD(dataKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// parseEnvelope parses the header of an envelope and returns its recipient entries and size. It checks that a payload
+// of at least the AES-GCM tag size follows.
+func parseEnvelope(envelope []byte) ([]envelopeEntry, int, error) {
+	// fieldOffset is the offset of the last field taken, which errors report.
+	offset, fieldOffset := 0, 0
+	formatError := func(reason string, arguments ...any) error {
+		return &FormatError{Err: ErrInvalidEnvelope, Offset: fieldOffset, Reason: fmt.Sprintf(reason, arguments...)}
+	}
+	take := func(size int) ([]byte, bool) {
+		fieldOffset = offset
+		if size > len(envelope)-offset {
+			return nil, false
+		}
+		field := envelope[offset : offset+size]
+		offset += size
+		return field, true
+	}
+
+	magic, ok := take(len(envelopeMagic))
+	if !ok || string(magic) != envelopeMagic {
+		return nil, 0, formatError("missing %q magic", envelopeMagic)
+	}
+	version, ok := take(1)
+	if !ok {
+		return nil, 0, formatError("truncated version")
+	}
+	if version[0] != envelopeVersion1 {
+		return nil, 0, formatError("unsupported version %d", version[0])
+	}
+	countBytes, ok := take(2)
+	if !ok {
+		return nil, 0, formatError("truncated recipient count")
+	}
+	count := int(binary.BigEndian.Uint16(countBytes))
+	if count == 0 {
+		return nil, 0, formatError("no recipients")
+	}
+
+	entries := make([]envelopeEntry, 0, min(count, 64))
+	for range count {
+		keyIDSize, ok := take(1)
+		if !ok || keyIDSize[0] == 0 {
+			return nil, 0, formatError("truncated or empty key ID")
+		}
+		keyID, ok := take(int(keyIDSize[0]))
+		if !ok {
+			return nil, 0, formatError("truncated key ID")
+		}
+		kemIDBytes, ok := take(2)
+		if !ok {
+			return nil, 0, formatError("truncated KEM ID")
+		}
+		kemID := HPKEKEM(binary.BigEndian.Uint16(kemIDBytes))
+		if _, isMLKEM := kemID.mlkemParameterSet(); !isMLKEM {
+			return nil, 0, formatError("unsupported KEM ID 0x%04x", uint16(kemID))
+		}
+		scheme, _ := kemID.scheme()
+		encapsulatedKey, ok := take(scheme.CiphertextSize())
+		if !ok {
+			return nil, 0, formatError("truncated %v encapsulated key", kemID)
+		}
+		wrappedKey, ok := take(envelopeWrappedKeySize)
+		if !ok {
+			return nil, 0, formatError("truncated wrapped data key")
+		}
+		entries = append(entries, envelopeEntry{keyID: string(keyID), kemID: kemID, encapsulatedKey: encapsulatedKey, wrappedKey: wrappedKey})
+	}
+	if _, ok := take(16); !ok {
+		return nil, 0, formatError("truncated payload")
+	}
+	return entries, fieldOffset, nil
+}
@@ -0,0 +1,44 @@
+package pq
+
+import (
+	"fmt"
+	"testing"
+)
+
+func BenchmarkSealEnvelope(b *testing.B) {
+	message := make([]byte, 1024)
+	for _, recipientCount := range []int{1, 10} {
+		b.Run(fmt.Sprintf("%d recipients", recipientCount), func(b *testing.B) {
+			recipients := make([]EnvelopeRecipient, recipientCount)
+			for i := range recipients {
+				keyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+				if err != nil {
+					b.Fatalf("failed to generate ML-KEM key pair: %v", err)
+				}
+				recipients[i] = EnvelopeRecipient{KeyID: fmt.Sprintf("key-%d", i), PublicKey: keyPair.PublicKey}
+			}
+			for b.Loop() {
+				if _, err := SealEnvelope(recipients, message, nil); err != nil {
+					b.Fatalf("failed to seal: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkOpenEnvelope(b *testing.B) {
+	message := make([]byte, 1024)
+	keyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	if err != nil {
+		b.Fatalf("failed to generate ML-KEM key pair: %v", err)
+	}
+	envelope, err := SealEnvelope([]EnvelopeRecipient{{KeyID: "key", PublicKey: keyPair.PublicKey}}, message, nil)
+	if err != nil {
+		b.Fatalf("failed to seal: %v", err)
+	}
+	for b.Loop() {
+		if _, err := OpenEnvelope(keyPair.PrivateKey, "key", envelope, nil); err != nil {
+			b.Fatalf("failed to open: %v", err)
+		}
+	}
+}
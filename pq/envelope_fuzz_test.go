@@ -0,0 +1,45 @@
+package pq
+
+import "testing"
+
+func FuzzOpenEnvelope(f *testing.F) {
+	keyPair, _ := GenerateMLKEMKeyPair(MLKEM768)
+	envelope, _ := SealEnvelope([]EnvelopeRecipient{{KeyID: "fuzz", PublicKey: keyPair.PublicKey}}, []byte("seed message"), nil)
+	f.Add(envelope)
+	f.Add(envelope[:len(envelope)-1])
+	f.Add([]byte(envelopeMagic))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, envelope []byte) {
+		if _, err := EnvelopeKeyIDs(envelope); err != nil {
+			if _, openError := OpenEnvelope(keyPair.PrivateKey, "fuzz", envelope, nil); openError == nil {
+				t.Errorf("opened an envelope whose header does not parse: %v", err)
+			}
+			return
+		}
+		plaintext, err := OpenEnvelope(keyPair.PrivateKey, "fuzz", envelope, nil)
+		if err == nil && string(plaintext) != "seed message" {
+			t.Errorf("opened a forged envelope: %x", plaintext)
+		}
+	})
+}
+
+func FuzzSealOpenEnvelope(f *testing.F) {
+	keyPair, _ := GenerateMLKEMKeyPair(MLKEM512)
+	f.Add("key", []byte("message"), []byte("associated data"))
+	f.Fuzz(func(t *testing.T, keyID string, message []byte, associatedData []byte) {
+		envelope, err := SealEnvelope([]EnvelopeRecipient{{KeyID: keyID, PublicKey: keyPair.PublicKey}}, message, associatedData)
+		if len(keyID) == 0 || len(keyID) > EnvelopeMaxKeyIDSize {
+			if err == nil {
+				t.Errorf("sealed with an invalid key ID of %d bytes", len(keyID))
+			}
+			return
+		}
+		if err != nil {
+			t.Fatalf("failed to seal: %v", err)
+		}
+		plaintext, err := OpenEnvelope(keyPair.PrivateKey, keyID, envelope, associatedData)
+		if err != nil || string(plaintext) != string(message) {
+			t.Errorf("round trip failed: %v", err)
+		}
+	})
+}
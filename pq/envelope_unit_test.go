@@ -0,0 +1,216 @@
+package pq
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// For the envelope tests: one recipient of every ML-KEM parameter set, with its key ID.
+type envelopeTestRecipient struct {
+	keyID   string
+	keyPair *MLKEMKeyPair
+}
+
+// newEnvelopeTestRecipients returns an ML-KEM-512, an ML-KEM-768 and an ML-KEM-1024 recipient.
+func newEnvelopeTestRecipients(t *testing.T) ([]envelopeTestRecipient, []EnvelopeRecipient) {
+	t.Helper()
+	var testRecipients []envelopeTestRecipient
+	var recipients []EnvelopeRecipient
+	for _, parameterSet := range []MLKEMParameterSet{MLKEM512, MLKEM768, MLKEM1024} {
+		keyPair, err := GenerateMLKEMKeyPair(parameterSet)
+		require.NoError(t, err)
+		keyID := "key-" + parameterSet.String()
+		testRecipients = append(testRecipients, envelopeTestRecipient{keyID: keyID, keyPair: keyPair})
+		recipients = append(recipients, EnvelopeRecipient{KeyID: keyID, PublicKey: keyPair.PublicKey})
+	}
+	return testRecipients, recipients
+}
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	testRecipients, recipients := newEnvelopeTestRecipients(t)
+	for _, plaintext := range [][]byte{nil, []byte("envelope message"), make([]byte, 1<<16)} {
+		envelope, err := SealEnvelope(recipients, plaintext, []byte("associated data"))
+		require.NoError(t, err)
+
+		keyIDs, err := EnvelopeKeyIDs(envelope)
+		require.NoError(t, err)
+		require.Equal(t, []string{"key-ML-KEM-512", "key-ML-KEM-768", "key-ML-KEM-1024"}, keyIDs)
+
+		for _, recipient := range testRecipients {
+			opened, err := OpenEnvelope(recipient.keyPair.PrivateKey, recipient.keyID, envelope, []byte("associated data"))
+			require.NoError(t, err, recipient.keyID)
+			require.Equal(t, len(plaintext), len(opened))
+			require.Equal(t, string(plaintext), string(opened))
+		}
+	}
+}
+
+// For TestEnvelopeFreshDataKey: sealing the same plaintext twice never produces the same payload.
+func TestEnvelopeFreshDataKey(t *testing.T) {
+	_, recipients := newEnvelopeTestRecipients(t)
+	first, err := SealEnvelope(recipients[:1], []byte("same message"), nil)
+	require.NoError(t, err)
+	second, err := SealEnvelope(recipients[:1], []byte("same message"), nil)
+	require.NoError(t, err)
+	require.Equal(t, len(first), len(second))
+	require.NotEqual(t, first[len(first)-28:], second[len(second)-28:])
+}
+
+func TestEnvelopeAssociatedData(t *testing.T) {
+	testRecipients, recipients := newEnvelopeTestRecipients(t)
+	envelope, err := SealEnvelope(recipients, []byte("message"), []byte("associated data"))
+	require.NoError(t, err)
+	for _, associatedData := range [][]byte{nil, []byte("associated datA"), []byte("associated data ")} {
+		_, err := OpenEnvelope(testRecipients[1].keyPair.PrivateKey, testRecipients[1].keyID, envelope, associatedData)
+		require.ErrorIs(t, err, ErrDecryptionFailed)
+	}
+}
+
+// For TestEnvelopeTampering: flipping any bit of the envelope makes every recipient's open fail, either while parsing
+// the header or while authenticating.
+func TestEnvelopeTampering(t *testing.T) {
+	testRecipients, recipients := newEnvelopeTestRecipients(t)
+	envelope, err := SealEnvelope(recipients[:2], []byte("message"), nil)
+	require.NoError(t, err)
+	recipient := testRecipients[1]
+	for offset := range envelope {
+		tampered := append([]byte(nil), envelope...)
+		tampered[offset] ^= 0x01
+		_, err := OpenEnvelope(recipient.keyPair.PrivateKey, recipient.keyID, tampered, nil)
+		require.Error(t, err, "offset %d", offset)
+		var formatError *FormatError
+		if !errors.Is(err, ErrDecryptionFailed) && !errors.Is(err, ErrRecipientNotFound) {
+			require.ErrorAs(t, err, &formatError, "offset %d: %v", offset, err)
+			require.ErrorIs(t, err, ErrInvalidEnvelope)
+		}
+	}
+}
+
+// For TestEnvelopeTruncation: a truncated header is a *FormatError at the truncation offset, and a truncated payload
+// fails authentication.
+func TestEnvelopeTruncation(t *testing.T) {
+	testRecipients, recipients := newEnvelopeTestRecipients(t)
+	envelope, err := SealEnvelope(recipients, []byte("message"), nil)
+	require.NoError(t, err)
+	_, headerSize, err := parseEnvelope(envelope)
+	require.NoError(t, err)
+	recipient := testRecipients[0]
+
+	for size := range headerSize + 16 {
+		_, err := OpenEnvelope(recipient.keyPair.PrivateKey, recipient.keyID, envelope[:size], nil)
+		var formatError *FormatError
+		require.ErrorAs(t, err, &formatError, "size %d", size)
+		require.ErrorIs(t, err, ErrInvalidEnvelope)
+		require.LessOrEqual(t, formatError.Offset, size)
+		_, err = EnvelopeKeyIDs(envelope[:size])
+		require.ErrorIs(t, err, ErrInvalidEnvelope)
+	}
+	for size := headerSize + 16; size < len(envelope); size++ {
+		_, err := OpenEnvelope(recipient.keyPair.PrivateKey, recipient.keyID, envelope[:size], nil)
+		require.ErrorIs(t, err, ErrDecryptionFailed, "size %d", size)
+	}
+	_, err = OpenEnvelope(recipient.keyPair.PrivateKey, recipient.keyID, append(envelope, 0), nil)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+}
+
+// For TestEnvelopeFormatErrors: malformed headers are reported with the offset and reason of the first bad field. With a
+// second recipient announced, the 23-byte payload of "message" is parsed as a 255-byte key ID and found truncated.
+func TestEnvelopeFormatErrors(t *testing.T) {
+	_, recipients := newEnvelopeTestRecipients(t)
+	envelope, err := SealEnvelope(recipients[1:2], []byte("message"), nil)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		modify func(envelope []byte) []byte
+		offset int
+		reason string
+	}{
+		{"magic", func(envelope []byte) []byte { envelope[0] = 'X'; return envelope }, 0, "magic"},
+		{"version", func(envelope []byte) []byte { envelope[4] = 2; return envelope }, 4, "unsupported version 2"},
+		{"no recipients", func(envelope []byte) []byte { binary.BigEndian.PutUint16(envelope[5:], 0); return envelope }, 5, "no recipients"},
+		{"extra recipient", func(envelope []byte) []byte {
+			binary.BigEndian.PutUint16(envelope[5:], 2)
+			envelope[len(envelope)-23] = 0xff
+			return envelope
+		}, len(envelope) - 22, "truncated key ID"},
+		{"empty key ID", func(envelope []byte) []byte { envelope[7] = 0; return envelope }, 7, "empty key ID"},
+		{"KEM ID", func(envelope []byte) []byte {
+			binary.BigEndian.PutUint16(envelope[8+len("key-ML-KEM-768"):], uint16(HPKEKEMXWing))
+			return envelope
+		}, 8 + len("key-ML-KEM-768"), "unsupported KEM ID 0x647a"},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := EnvelopeKeyIDs(testCase.modify(append([]byte(nil), envelope...)))
+			var formatError *FormatError
+			require.ErrorAs(t, err, &formatError)
+			require.ErrorIs(t, err, ErrInvalidEnvelope)
+			require.Contains(t, formatError.Reason, testCase.reason)
+			require.Equal(t, testCase.offset, formatError.Offset)
+		})
+	}
+}
+
+func TestEnvelopeRecipientErrors(t *testing.T) {
+	testRecipients, recipients := newEnvelopeTestRecipients(t)
+	envelope, err := SealEnvelope(recipients[:2], []byte("message"), nil)
+	require.NoError(t, err)
+
+	_, err = OpenEnvelope(testRecipients[0].keyPair.PrivateKey, "unknown", envelope, nil)
+	require.ErrorIs(t, err, ErrRecipientNotFound)
+
+	otherKeyPair, err := GenerateMLKEMKeyPair(MLKEM512)
+	require.NoError(t, err)
+	_, err = OpenEnvelope(otherKeyPair.PrivateKey, testRecipients[0].keyID, envelope, nil)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+
+	_, err = OpenEnvelope(testRecipients[1].keyPair.PrivateKey, testRecipients[0].keyID, envelope, nil)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+
+	_, err = OpenEnvelope(testRecipients[2].keyPair.PrivateKey, testRecipients[2].keyID, envelope, nil)
+	require.ErrorIs(t, err, ErrRecipientNotFound)
+
+	_, err = OpenEnvelope(nil, testRecipients[0].keyID, envelope, nil)
+	require.Error(t, err)
+}
+
+func TestSealEnvelopeInvalidRecipients(t *testing.T) {
+	_, recipients := newEnvelopeTestRecipients(t)
+	legacyKeyPair, err := GenerateMLKEMKeyPair(LegacyKyber1024)
+	require.NoError(t, err)
+	xwingKeyPair, err := GenerateXWingKeyPair()
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		recipients []EnvelopeRecipient
+		want       error
+	}{
+		{"no recipients", nil, ErrInvalidOptions},
+		{"empty key ID", []EnvelopeRecipient{{KeyID: "", PublicKey: recipients[0].PublicKey}}, ErrInvalidOptions},
+		{"long key ID", []EnvelopeRecipient{{KeyID: strings.Repeat("k", EnvelopeMaxKeyIDSize+1), PublicKey: recipients[0].PublicKey}}, ErrInvalidOptions},
+		{"duplicate key ID", []EnvelopeRecipient{recipients[0], {KeyID: recipients[0].KeyID, PublicKey: recipients[1].PublicKey}}, ErrInvalidOptions},
+		{"nil public key", []EnvelopeRecipient{{KeyID: "nil"}}, ErrInvalidKey},
+		{"legacy Kyber", []EnvelopeRecipient{{KeyID: "kyber", PublicKey: legacyKeyPair.PublicKey}}, ErrUnknownAlgorithm},
+		{"X-Wing", []EnvelopeRecipient{{KeyID: "x-wing", PublicKey: xwingKeyPair.PublicKey}}, ErrUnknownAlgorithm},
+		{"panicking public key", []EnvelopeRecipient{{KeyID: "panic", PublicKey: panickingPublicKey{}}}, ErrInternalPanic},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			envelope, err := SealEnvelope(testCase.recipients, []byte("message"), nil)
+			require.ErrorIs(t, err, testCase.want)
+			require.Nil(t, envelope)
+		})
+	}
+
+	envelope, err := SealEnvelope([]EnvelopeRecipient{{KeyID: strings.Repeat("k", EnvelopeMaxKeyIDSize), PublicKey: recipients[0].PublicKey}}, nil, nil)
+	require.NoError(t, err)
+	keyIDs, err := EnvelopeKeyIDs(envelope)
+	require.NoError(t, err)
+	require.Equal(t, []string{strings.Repeat("k", EnvelopeMaxKeyIDSize)}, keyIDs)
+}
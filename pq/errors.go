@@ -0,0 +1,186 @@
+package pq
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// Sentinel errors returned (wrapped) by the functions of this package. Match them with errors.Is; the concrete
+// error usually is a *SizeError or *PanicError carrying the details, which can be extracted with errors.As.
+var (
+	// ErrInvalidKeySize is returned for a public or private key of the wrong length for its parameter set.
+	ErrInvalidKeySize = errors.New("invalid key size")
+	// ErrInvalidSeedSize is returned for a key generation or encapsulation seed of the wrong length.
+	ErrInvalidSeedSize = errors.New("invalid seed size")
+	// ErrInvalidMuSize is returned for an external ML-DSA message representative mu that is not MLDSAMuSize bytes long.
+	ErrInvalidMuSize = errors.New("invalid mu size")
+	// ErrInvalidDigestSize is returned for a HashML-DSA digest whose length does not match its pre-hash function.
+	ErrInvalidDigestSize = errors.New("invalid digest size")
+	// ErrInvalidKey is returned for a key that is nil, incomplete or of another scheme than the function expects.
+	ErrInvalidKey = errors.New("invalid key")
+	// ErrInvalidCiphertext is returned for a KEM ciphertext that cannot be decapsulated, for example because of its length.
+	// A well-formed but tampered ciphertext is not an error: KEMs implicitly reject it with an unrelated shared secret.
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+	// ErrVerificationFailed is returned by the CheckSignature methods when a signature does not verify.
+	// The Verify functions report an invalid signature as false with a nil error instead.
+	ErrVerificationFailed = errors.New("signature verification failed")
+	// ErrInternalPanic is returned when a panic inside this package or a dependency was recovered; see PanicError.
+	ErrInternalPanic = errors.New("internal panic")
+	// ErrInvalidEncapsulationKey is returned for an ML-KEM encapsulation key that fails the FIPS 203 modulus check; see KeyValidationError.
+	ErrInvalidEncapsulationKey = errors.New("invalid encapsulation key")
+	// ErrInvalidDecapsulationKey is returned for an ML-KEM decapsulation key that fails the FIPS 203 hash check, a FrodoKEM private key whose stored public key hash does not match, or a Classic McEliece private key that differs from the key generated from its seed; see KeyValidationError.
+	ErrInvalidDecapsulationKey = errors.New("invalid decapsulation key")
+	// ErrUnknownAlgorithm is returned for a parameter set or KEM scheme this package does not support, and when no
+	// registered SignatureScheme or KEMScheme has the requested name or OID.
+	ErrUnknownAlgorithm = errors.New("unknown algorithm")
+	// ErrContextTooLong is returned when a context string is longer than MLDSAMaxContextSize bytes.
+	ErrContextTooLong = errors.New("context string too long")
+	// ErrInvalidOptions is returned for sign options that contradict each other or the function they are passed to.
+	ErrInvalidOptions = errors.New("invalid options")
+	// ErrStateExhausted is returned when a stateful hash-based signature key has no unused one-time signature left.
+	ErrStateExhausted = errors.New("signature state exhausted")
+	// ErrUnsupportedTreeHeight is returned when generating or signing with a stateful hash-based signature key whose trees
+	// are higher than LMSMaxTreeHeight or XMSSMaxTreeHeight. Verification supports every height.
+	ErrUnsupportedTreeHeight = errors.New("unsupported tree height")
+	// ErrStateLocked is returned when a signature state file is already owned by another signer.
+	ErrStateLocked = errors.New("signature state locked")
+	// ErrStateMismatch is returned when a SignatureStateStore is used with a key other than the one it counts for.
+	ErrStateMismatch = errors.New("signature state belongs to another key")
+	// ErrDecryptionFailed is returned when a ciphertext fails authentication, for example in HPKE Open.
+	ErrDecryptionFailed = errors.New("decryption failed")
+	// ErrInvalidEnvelope is returned for an envelope that is truncated, malformed or of an unsupported version; see FormatError.
+	ErrInvalidEnvelope = errors.New("invalid envelope")
+	// ErrRecipientNotFound is returned when an envelope has no recipient with the requested key ID.
+	ErrRecipientNotFound = errors.New("recipient not found")
+	// ErrInvalidStream is returned for an encrypted stream whose header or chunk layout is malformed; see FormatError.
+	ErrInvalidStream = errors.New("invalid stream")
+	// ErrInvalidOpenPGPPacket is returned for OpenPGP data that is truncated, malformed or of an unsupported version or
+	// algorithm; see FormatError.
+	ErrInvalidOpenPGPPacket = errors.New("invalid OpenPGP packet")
+	// ErrInvalidJOSE is returned for a JWS, JWE, JWK or JWT that is malformed or uses an unsupported feature; see
+	// FormatError.
+	ErrInvalidJOSE = errors.New("invalid JOSE object")
+	// ErrKeyNotFound is returned when a JWK set has no key with the key ID and algorithm of a JWS or JWE.
+	ErrKeyNotFound = errors.New("key not found")
+	// ErrInvalidClaims is returned for a JWT whose claims fail validation; see ClaimError.
+	ErrInvalidClaims = errors.New("invalid claims")
+)
+
+// SizeError reports an input of the wrong length. It matches its Err sentinel with errors.Is.
+type SizeError struct {
+	// Err is ErrInvalidKeySize, ErrInvalidSeedSize, ErrInvalidMuSize or ErrInvalidCiphertext.
+	Err error
+	// Input names the rejected input, for example "ML-KEM-768 public key".
+	Input string
+	// Got is the length of the rejected input in bytes.
+	Got int
+	// Want is the required length in bytes, or 0 if there is no single required length.
+	Want int
+}
+
+// Error returns a message naming the input and both lengths.
+func (sizeError *SizeError) Error() string {
+	if sizeError.Want == 0 {
+		return fmt.Sprintf("invalid %s size: got %d bytes", sizeError.Input, sizeError.Got)
+	}
+	return fmt.Sprintf("invalid %s size: got %d bytes, want %d", sizeError.Input, sizeError.Got, sizeError.Want)
+}
+
+// Unwrap returns the sentinel error.
+func (sizeError *SizeError) Unwrap() error {
+	return sizeError.Err
+}
+
+// newSizeError returns a *SizeError for the sentinel err.
+func newSizeError(err error, input string, got int, want int) error {
+	return &SizeError{Err: err, Input: input, Got: got, Want: want}
+}
+
+// KeyValidationError reports a key of the right length whose contents fail a validity check. It matches its Err sentinel with errors.Is.
+type KeyValidationError struct {
+	// Err is ErrInvalidEncapsulationKey or ErrInvalidDecapsulationKey.
+	Err error
+	// Input names the rejected key, for example "ML-KEM-768 encapsulation key".
+	Input string
+	// Reason describes the failed check.
+	Reason string
+}
+
+// Error returns a message naming the key and the failed check.
+func (keyValidationError *KeyValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", keyValidationError.Input, keyValidationError.Reason)
+}
+
+// Unwrap returns the sentinel error.
+func (keyValidationError *KeyValidationError) Unwrap() error {
+	return keyValidationError.Err
+}
+
+// FormatError reports an encoded message that cannot be parsed. It matches its Err sentinel with errors.Is.
+type FormatError struct {
+	// Err is the sentinel of the format, for example ErrInvalidEnvelope.
+	Err error
+	// Offset is the byte offset at which parsing failed.
+	Offset int
+	// Reason describes what is wrong.
+	Reason string
+}
+
+// Error returns a message naming the sentinel, the offset and the reason.
+func (formatError *FormatError) Error() string {
+	return fmt.Sprintf("%v at offset %d: %s", formatError.Err, formatError.Offset, formatError.Reason)
+}
+
+// Unwrap returns the sentinel error.
+func (formatError *FormatError) Unwrap() error {
+	return formatError.Err
+}
+
+// ClaimError reports a JWT claim that fails validation, for example an expired token. It matches ErrInvalidClaims with
+// errors.Is.
+type ClaimError struct {
+	// Claim is the name of the claim, for example "exp".
+	Claim string
+	// Reason describes the failed check.
+	Reason string
+}
+
+// Error returns a message naming the claim and the failed check.
+func (claimError *ClaimError) Error() string {
+	return fmt.Sprintf("invalid JWT claim %q: %s", claimError.Claim, claimError.Reason)
+}
+
+// Unwrap returns ErrInvalidClaims.
+func (claimError *ClaimError) Unwrap() error {
+	return ErrInvalidClaims
+}
+
+// PanicError reports a panic recovered at an entry point of this package, so that a crash is never mistaken for success.
+// It matches ErrInternalPanic with errors.Is.
+type PanicError struct {
+	// Function is the entry point that recovered the panic.
+	Function string
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the panicking goroutine.
+	Stack []byte
+}
+
+// Error returns a message naming the function and the panic value. The stack trace is available in Stack.
+func (panicError *PanicError) Error() string {
+	return fmt.Sprintf("panic in %s: %v", panicError.Function, panicError.Value)
+}
+
+// Unwrap returns ErrInternalPanic.
+func (panicError *PanicError) Unwrap() error {
+	return ErrInternalPanic
+}
+
+// newPanicError returns a *PanicError for a value recovered in function, and logs it at slog.LevelError.
+// It must be called from the deferred function that recovered, so that the stack trace includes the panic site.
+func newPanicError(function string, recoveredPanic any) error {
+	stack := debug.Stack()
+	logPanic(Logger(), function, recoveredPanic, stack)
+	return &PanicError{Function: function, Value: recoveredPanic, Stack: stack}
+}
@@ -0,0 +1,184 @@
+package pq
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/cloudflare/circl/kem"
+	"github.com/stretchr/testify/require"
+)
+
+// panickingPublicKey is a kem.PublicKey whose methods panic, to exercise the recover paths of the ML-KEM entry points.
+type panickingPublicKey struct{ kem.PublicKey }
+
+func (panickingPublicKey) Scheme() kem.Scheme             { panic("scheme unavailable") }
+func (panickingPublicKey) MarshalBinary() ([]byte, error) { panic("marshal unavailable") }
+
+// For TestSizeErrors: every entry point reports wrong-length inputs with a *SizeError wrapping the matching sentinel.
+func TestSizeErrors(t *testing.T) {
+	mlkemKeyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	require.NoError(t, err)
+	mldsaKeyPair, err := GenerateMLDSAKeyPair(MLDSA44)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		call     func() error
+		sentinel error
+	}{
+		{"ML-KEM seed", func() error { _, err := GenerateDeterministicMLKEMKeyPair(MLKEM768, make([]byte, 10)); return err }, ErrInvalidSeedSize},
+		{"ML-KEM public key", func() error { _, err := UnmarshalPublicKey(MLKEM768, make([]byte, 10)); return err }, ErrInvalidKeySize},
+		{"ML-KEM private key", func() error { _, err := UnmarshalPrivateKey(MLKEM768, make([]byte, 10)); return err }, ErrInvalidKeySize},
+		{"ML-KEM ciphertext", func() error { _, err := MLKEMDecapsulate(mlkemKeyPair.PrivateKey, make([]byte, 10)); return err }, ErrInvalidCiphertext},
+		{"ML-KEM empty ciphertext", func() error { _, err := MLKEMDecapsulate(mlkemKeyPair.PrivateKey, nil); return err }, ErrInvalidCiphertext},
+		{"ML-KEM encapsulation seed", func() error {
+			_, _, err := MLKEMEncapsulateDeterministic(mlkemKeyPair.PublicKey, make([]byte, 10))
+			return err
+		}, ErrInvalidSeedSize},
+		{"ML-DSA private key", func() error { _, err := MLDSASign(MLDSA44, make([]byte, 10), []byte("m")); return err }, ErrInvalidKeySize},
+		{"ML-DSA public key", func() error { _, err := MLDSAVerify(MLDSA44, make([]byte, 10), []byte("m"), nil); return err }, ErrInvalidKeySize},
+		{"ML-DSA typed private key", func() error { _, err := NewMLDSAPrivateKey(MLDSA65, mldsaKeyPair.PrivateKey); return err }, ErrInvalidKeySize},
+		{"ML-DSA seed", func() error { _, err := NewMLDSAPrivateKeyFromSeed(MLDSA44, make([]byte, 10)); return err }, ErrInvalidSeedSize},
+		{"ML-DSA sign mu", func() error { _, err := MLDSASignMu(MLDSA44, mldsaKeyPair.PrivateKey, make([]byte, 10)); return err }, ErrInvalidMuSize},
+		{"ML-DSA verify mu", func() error {
+			_, err := MLDSAVerifyMu(MLDSA44, mldsaKeyPair.PublicKey, make([]byte, 10), nil)
+			return err
+		}, ErrInvalidMuSize},
+		{"HashML-DSA digest", func() error {
+			_, err := HashMLDSASignDigest(MLDSA44, PreHashSHA256, mldsaKeyPair.PrivateKey, make([]byte, 10), nil)
+			return err
+		}, ErrInvalidDigestSize},
+		{"X-Wing ciphertext", func() error {
+			xwingKeyPair, err := GenerateXWingKeyPair()
+			require.NoError(t, err)
+			_, err = XWingDecapsulate(xwingKeyPair.PrivateKey, make([]byte, 10))
+			return err
+		}, ErrInvalidCiphertext},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			err := testCase.call()
+			require.ErrorIs(t, err, testCase.sentinel)
+			var sizeError *SizeError
+			require.ErrorAs(t, err, &sizeError)
+			require.NotZero(t, sizeError.Want, "the required size must be reported")
+			require.NotEqual(t, sizeError.Got, sizeError.Want)
+		})
+	}
+}
+
+// For TestInvalidKeyErrors: nil or malformed keys, unsupported parameter sets or schemes, and contradictory options wrap
+// ErrInvalidKey, ErrUnknownAlgorithm and ErrInvalidOptions.
+func TestInvalidKeyErrors(t *testing.T) {
+	frodoKeyPair, err := GenerateFrodoKEMKeyPair(FrodoKEM640SHAKE)
+	require.NoError(t, err)
+	mlkemKeyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	require.NoError(t, err)
+	mldsaKeyPair, err := GenerateMLDSAKeyPair(MLDSA44)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		call     func() error
+		sentinel error
+	}{
+		{"ML-KEM marshal nil public key", func() error { _, err := MarshalPublicKey(nil); return err }, ErrInvalidKey},
+		{"ML-KEM marshal nil private key", func() error { _, err := MarshalPrivateKey(nil); return err }, ErrInvalidKey},
+		{"ML-KEM encapsulate nil key", func() error { _, _, err := MLKEMEncapsulate(nil); return err }, ErrInvalidKey},
+		{"ML-KEM decapsulate nil key", func() error { _, err := MLKEMDecapsulate(nil, nil); return err }, ErrInvalidKey},
+		{"ML-KEM deterministic encapsulate nil key", func() error {
+			_, _, err := MLKEMEncapsulateDeterministic(nil, make([]byte, MLKEMEncapsulationSeedSize))
+			return err
+		}, ErrInvalidKey},
+		{"ML-KEM encapsulate FrodoKEM key", func() error { _, _, err := MLKEMEncapsulate(frodoKeyPair.PublicKey); return err }, ErrUnknownAlgorithm},
+		{"ML-KEM decapsulate FrodoKEM key", func() error { _, err := MLKEMDecapsulate(frodoKeyPair.PrivateKey, nil); return err }, ErrUnknownAlgorithm},
+		{"ML-KEM parameter set", func() error { _, err := GenerateMLKEMKeyPair(MLKEMParameterSet(0)); return err }, ErrUnknownAlgorithm},
+		{"ML-KEM OID", func() error { _, err := LegacyKyber1024.OID(); return err }, ErrUnknownAlgorithm},
+		{"ML-DSA parameter set", func() error { _, err := GenerateMLDSAKeyPair(MLDSAParameterSet(0)); return err }, ErrUnknownAlgorithm},
+		{"FrodoKEM encapsulate nil key", func() error { _, _, err := FrodoKEMEncapsulate(nil); return err }, ErrInvalidKey},
+		{"FrodoKEM decapsulate nil key", func() error { _, err := FrodoKEMDecapsulate(nil, nil); return err }, ErrInvalidKey},
+		{"FrodoKEM parameter set", func() error { _, err := GenerateFrodoKEMKeyPair(FrodoKEMParameterSet(0)); return err }, ErrUnknownAlgorithm},
+		{"X-Wing encapsulate nil key", func() error { _, _, err := XWingEncapsulate(nil); return err }, ErrInvalidKey},
+		{"X-Wing decapsulate nil key", func() error { _, err := XWingDecapsulate(nil, nil); return err }, ErrInvalidKey},
+		{"hybrid KEM encapsulate nil key", func() error { _, _, err := HybridKEMEncapsulate(nil); return err }, ErrInvalidKey},
+		{"hybrid KEM decapsulate nil key", func() error { _, err := HybridKEMDecapsulate(nil, nil); return err }, ErrInvalidKey},
+		{"envelope nil key", func() error { _, err := OpenEnvelope(nil, "", nil, nil); return err }, ErrInvalidKey},
+		{"hybrid KEM legacy Kyber", func() error { _, err := GenerateHybridKEMKeyPair(ECDHX25519, LegacyKyber1024); return err }, ErrUnknownAlgorithm},
+		{"HashML-DSA pre-hash", func() error { _, err := MLDSAPreHash(0).Digest(nil); return err }, ErrUnknownAlgorithm},
+		{"ML-DSA nil key pair", func() error { _, err := NewMLDSAPrivateKeyFromKeyPair(nil); return err }, ErrInvalidKey},
+		{"ML-DSA PKCS#8 nil key pair", func() error { _, err := MarshalMLDSAPrivateKeyPKCS8(nil, PrivateKeyFormatSeed); return err }, ErrInvalidKey},
+		{"ML-KEM PKIX nil key", func() error { _, err := MarshalMLKEMPublicKeyPKIX(nil); return err }, ErrInvalidKey},
+		{"X-Wing PKCS#8 ML-KEM key", func() error {
+			der, err := MarshalMLKEMPrivateKeyPKCS8(mlkemKeyPair, PrivateKeyFormatSeed)
+			require.NoError(t, err)
+			_, err = ParseXWingPrivateKeyPKCS8(der)
+			return err
+		}, ErrUnknownAlgorithm},
+		{"PKCS#8 trailing data", func() error {
+			der, err := MarshalMLKEMPrivateKeyPKCS8(mlkemKeyPair, PrivateKeyFormatSeed)
+			require.NoError(t, err)
+			_, err = ParseMLKEMPrivateKeyPKCS8(append(der, 0))
+			return err
+		}, ErrInvalidKey},
+		{"PEM without block", func() error { _, err := DecodePrivateKeyPEM([]byte("no PEM here")); return err }, ErrInvalidKey},
+		{"composite algorithm", func() error { _, err := GenerateCompositeMLDSAKeyPair(CompositeMLDSAAlgorithm(0)); return err }, ErrUnknownAlgorithm},
+		{"composite sign nil key", func() error { _, err := CompositeMLDSASign(nil, nil, nil); return err }, ErrInvalidKey},
+		{"composite verify nil key", func() error { _, err := CompositeMLDSAVerify(nil, nil, nil, nil); return err }, ErrInvalidKey},
+		{"composite marshal nil key", func() error { _, err := MarshalCompositeMLDSAPrivateKey(nil); return err }, ErrInvalidKey},
+		{"ML-DSA Deterministic and Rand", func() error {
+			_, err := MLDSASignWithOptions(MLDSA44, mldsaKeyPair.PrivateKey, nil, &MLDSASignOptions{Deterministic: true, Rand: rand.Reader})
+			return err
+		}, ErrInvalidOptions},
+		{"ML-DSA mu with context", func() error {
+			_, err := MLDSASignMuWithOptions(MLDSA44, mldsaKeyPair.PrivateKey, make([]byte, MLDSAMuSize), &MLDSASignOptions{Context: []byte("ctx")})
+			return err
+		}, ErrInvalidOptions},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.ErrorIs(t, testCase.call(), testCase.sentinel)
+		})
+	}
+}
+
+// For TestMLKEMPanicsAreErrors: a panic inside an ML-KEM entry point is returned as a *PanicError, never as nil, nil.
+func TestMLKEMPanicsAreErrors(t *testing.T) {
+	_, _, err := MLKEMEncapsulate(panickingPublicKey{})
+	require.ErrorIs(t, err, ErrInternalPanic)
+	var panicError *PanicError
+	require.ErrorAs(t, err, &panicError)
+	require.Equal(t, "MLKEMEncapsulate", panicError.Function)
+	require.Equal(t, "scheme unavailable", panicError.Value)
+	require.NotEmpty(t, panicError.Stack)
+
+	_, err = MarshalPublicKey(panickingPublicKey{})
+	require.ErrorIs(t, err, ErrInternalPanic)
+	_, _, err = MLKEMEncapsulateDeterministic(panickingPublicKey{}, make([]byte, 32))
+	require.ErrorIs(t, err, ErrInternalPanic)
+}
+
+// For TestCheckSignature: CheckSignature reports forged signatures with ErrVerificationFailed and malformed input with
+// the size sentinels.
+func TestCheckSignature(t *testing.T) {
+	message := []byte("check signature")
+	privateKey, err := GenerateMLDSAPrivateKey(MLDSA44)
+	require.NoError(t, err)
+	signature, err := privateKey.Sign(nil, message, nil)
+	require.NoError(t, err)
+	publicKey := privateKey.PublicKey()
+	require.NoError(t, publicKey.CheckSignature(message, signature, nil))
+
+	forged := append([]byte(nil), signature...)
+	forged[0] ^= 1
+	err = publicKey.CheckSignature(message, forged, nil)
+	require.ErrorIs(t, err, ErrVerificationFailed)
+	require.False(t, errors.Is(err, ErrInvalidKeySize))
+
+	compositeKeyPair, err := GenerateCompositeMLDSAKeyPair(CompositeMLDSA65Ed25519)
+	require.NoError(t, err)
+	compositeSignature, err := CompositeMLDSASign(compositeKeyPair.PrivateKey, message, nil)
+	require.NoError(t, err)
+	require.NoError(t, compositeKeyPair.PublicKey.CheckSignature(message, compositeSignature, nil))
+	require.ErrorIs(t, compositeKeyPair.PublicKey.CheckSignature([]byte("other"), compositeSignature, nil), ErrVerificationFailed)
+}
@@ -0,0 +1,231 @@
+package pq
+
+import (
+	"crypto/rand"
+	"crypto/sha3"
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/kem/frodo/frodo640shake"
+)
+
+// FrodoKEMParameterSet identifies a FrodoKEM parameter set from the FrodoKEM round 3 specification.
+//
+// FrodoKEM is based on plain (unstructured) LWE. It is far slower and its keys and ciphertexts are far larger than ML-KEM's,
+// but it does not depend on the module lattice structure ML-KEM relies on, which makes it a conservative choice for data
+// that must stay confidential for decades.
+//
+// The parameter sets are implemented by CIRCL, which only provides FrodoKEM-640-SHAKE.
+type FrodoKEMParameterSet int
+
+const (
+	// FrodoKEM640SHAKE is FrodoKEM-640-SHAKE (NIST security category 1), with the public matrix A generated from SHAKE128.
+	FrodoKEM640SHAKE FrodoKEMParameterSet = iota + 1
+)
+
+// FrodoKEMParameterSets lists the supported FrodoKEM parameter sets.
+var FrodoKEMParameterSets = []FrodoKEMParameterSet{FrodoKEM640SHAKE}
+
+// frodoPublicKeyHashSize is the size in bytes of the public key hash pkh stored at the end of a FrodoKEM-640 private key.
+const frodoPublicKeyHashSize = 16
+
+// String returns the name of the parameter set, for example "FrodoKEM-640-SHAKE".
+func (parameterSet FrodoKEMParameterSet) String() string {
+	switch parameterSet {
+	case FrodoKEM640SHAKE:
+		return "FrodoKEM-640-SHAKE"
+	default:
+		return fmt.Sprintf("FrodoKEMParameterSet(%d)", int(parameterSet))
+	}
+}
+
+// scheme returns the CIRCL KEM scheme implementing the parameter set.
+func (parameterSet FrodoKEMParameterSet) scheme() (kem.Scheme, error) {
+	switch parameterSet {
+	case FrodoKEM640SHAKE:
+		return frodo640shake.Scheme(), nil
+	default:
+		return nil, fmt.Errorf("%w: FrodoKEM parameter set %v", ErrUnknownAlgorithm, parameterSet)
+	}
+}
+
+// frodoParameterSetOf returns the parameter set of a CIRCL KEM scheme, rejecting schemes that are not FrodoKEM.
+func frodoParameterSetOf(scheme kem.Scheme) (FrodoKEMParameterSet, error) {
+	switch scheme {
+	case frodo640shake.Scheme():
+		return FrodoKEM640SHAKE, nil
+	default:
+		return 0, fmt.Errorf("%w: KEM scheme is not FrodoKEM", ErrUnknownAlgorithm)
+	}
+}
+
+// FrodoKEMKeyPair represents a FrodoKEM key pair, and the parameter set it belongs to.
+// The keys are serialized with MarshalPublicKey and MarshalPrivateKey, like ML-KEM keys.
+type FrodoKEMKeyPair struct {
+	ParameterSet FrodoKEMParameterSet
+	PublicKey    kem.PublicKey
+	PrivateKey   kem.PrivateKey
+}
+
+// GenerateDeterministicFrodoKEMKeyPair generates a FrodoKEM key pair from a seed (for KATs).
+// The seed must be of length FrodoKEMSeedSize(parameterSet), and is the concatenation s || seedSE || z of the key generation.
+func GenerateDeterministicFrodoKEMKeyPair(parameterSet FrodoKEMParameterSet, seed []byte) (keyPair *FrodoKEMKeyPair, keyGenerationError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			keyPair, keyGenerationError = nil, newPanicError("GenerateDeterministicFrodoKEMKeyPair", recoveredPanic)
+		}
+	}()
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return nil, err
+	}
+	if len(seed) != scheme.SeedSize() {
+		return nil, newSizeError(ErrInvalidSeedSize, parameterSet.String()+" seed", len(seed), scheme.SeedSize())
+	}
+	publicKey, privateKey := scheme.DeriveKeyPair(seed)
+	return &FrodoKEMKeyPair{ParameterSet: parameterSet, PublicKey: publicKey, PrivateKey: privateKey}, nil
+}
+
+// GenerateFrodoKEMKeyPair generates a new FrodoKEM key pair for the given parameter set from a fresh random seed.
+func GenerateFrodoKEMKeyPair(parameterSet FrodoKEMParameterSet) (*FrodoKEMKeyPair, error) {
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return nil, err
+	}
+	seed := make([]byte, scheme.SeedSize())
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("FrodoKEM key generation: %w", err)
+	}
+	return GenerateDeterministicFrodoKEMKeyPair(parameterSet, seed)
+}
+
+// UnmarshalFrodoKEMPublicKey deserializes bytes into a FrodoKEM public key of the given parameter set.
+// A key of the wrong length returns a *SizeError matching ErrInvalidKeySize.
+func UnmarshalFrodoKEMPublicKey(parameterSet FrodoKEMParameterSet, data []byte) (publicKey kem.PublicKey, unmarshalError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			publicKey, unmarshalError = nil, newPanicError("UnmarshalFrodoKEMPublicKey", recoveredPanic)
+		}
+	}()
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != scheme.PublicKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, parameterSet.String()+" public key", len(data), scheme.PublicKeySize())
+	}
+	return scheme.UnmarshalBinaryPublicKey(data)
+}
+
+// UnmarshalFrodoKEMPrivateKey deserializes bytes into a FrodoKEM private key of the given parameter set.
+// A key of the wrong length returns a *SizeError matching ErrInvalidKeySize, and a key whose stored public key hash does
+// not match its embedded public key returns a *KeyValidationError matching ErrInvalidDecapsulationKey.
+func UnmarshalFrodoKEMPrivateKey(parameterSet FrodoKEMParameterSet, data []byte) (privateKey kem.PrivateKey, unmarshalError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			privateKey, unmarshalError = nil, newPanicError("UnmarshalFrodoKEMPrivateKey", recoveredPanic)
+		}
+	}()
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != scheme.PrivateKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, parameterSet.String()+" private key", len(data), scheme.PrivateKeySize())
+	}
+	// The private key is s || pk || S^T || pkh, with len(s) = len(pkh) and pkh = SHAKE128(pk) for FrodoKEM-640.
+	publicKeyBytes := data[frodoPublicKeyHashSize : frodoPublicKeyHashSize+scheme.PublicKeySize()]
+	storedHash := data[len(data)-frodoPublicKeyHashSize:]
+	if subtle.ConstantTimeCompare(sha3.SumSHAKE128(publicKeyBytes, frodoPublicKeyHashSize), storedHash) != 1 {
+		return nil, &KeyValidationError{
+			Err:    ErrInvalidDecapsulationKey,
+			Input:  parameterSet.String() + " private key",
+			Reason: "stored public key hash does not match the embedded public key",
+		}
+	}
+	return scheme.UnmarshalBinaryPrivateKey(data)
+}
+
+// FrodoKEMEncapsulate encapsulates a shared secret using the FrodoKEM public key.
+// The parameter set is taken from the public key.
+func FrodoKEMEncapsulate(publicKey kem.PublicKey) (ciphertext []byte, sharedSecret []byte, err error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			ciphertext, sharedSecret, err = nil, nil, newPanicError("FrodoKEMEncapsulate", recoveredPanic)
+		}
+	}()
+	if publicKey == nil {
+		return nil, nil, fmt.Errorf("%w: FrodoKEM public key is nil", ErrInvalidKey)
+	}
+	if _, err = frodoParameterSetOf(publicKey.Scheme()); err != nil {
+		return nil, nil, err
+	}
+	return publicKey.Scheme().Encapsulate(publicKey)
+}
+
+// FrodoKEMEncapsulateDeterministic encapsulates a shared secret using the FrodoKEM public key and a seed (for KATs).
+// The seed must be of length FrodoKEMEncapsulationSeedSize(parameterSet), and is the message mu of the encapsulation.
+func FrodoKEMEncapsulateDeterministic(publicKey kem.PublicKey, seed []byte) (ciphertext []byte, sharedSecret []byte, err error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			ciphertext, sharedSecret, err = nil, nil, newPanicError("FrodoKEMEncapsulateDeterministic", recoveredPanic)
+		}
+	}()
+	if publicKey == nil {
+		return nil, nil, fmt.Errorf("%w: FrodoKEM public key is nil", ErrInvalidKey)
+	}
+	parameterSet, err := frodoParameterSetOf(publicKey.Scheme())
+	if err != nil {
+		return nil, nil, err
+	}
+	scheme := publicKey.Scheme()
+	if len(seed) != scheme.EncapsulationSeedSize() {
+		return nil, nil, newSizeError(ErrInvalidSeedSize, parameterSet.String()+" encapsulation seed", len(seed), scheme.EncapsulationSeedSize())
+	}
+	return scheme.EncapsulateDeterministically(publicKey, seed)
+}
+
+// FrodoKEMDecapsulate decapsulates a shared secret using the FrodoKEM private key.
+// The parameter set is taken from the private key. A ciphertext of the wrong size returns an error matching ErrInvalidCiphertext.
+//
+// Like ML-KEM, decapsulation uses implicit rejection: a tampered ciphertext of the right size is not an error, it yields
+// the pseudorandom shared secret derived from the private value s instead, selected in constant time.
+func FrodoKEMDecapsulate(privateKey kem.PrivateKey, ciphertext []byte) (sharedSecret []byte, err error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			sharedSecret, err = nil, newPanicError("FrodoKEMDecapsulate", recoveredPanic)
+		}
+	}()
+	if privateKey == nil {
+		return nil, fmt.Errorf("%w: FrodoKEM private key is nil", ErrInvalidKey)
+	}
+	parameterSet, err := frodoParameterSetOf(privateKey.Scheme())
+	if err != nil {
+		return nil, err
+	}
+	scheme := privateKey.Scheme()
+	if len(ciphertext) != scheme.CiphertextSize() {
+		return nil, newSizeError(ErrInvalidCiphertext, parameterSet.String()+" ciphertext", len(ciphertext), scheme.CiphertextSize())
+	}
+	return scheme.Decapsulate(privateKey, ciphertext)
+}
+
+// FrodoKEMSeedSize returns the size in bytes of the seed for GenerateDeterministicFrodoKEMKeyPair, or 0 for an unknown parameter set.
+func FrodoKEMSeedSize(parameterSet FrodoKEMParameterSet) int {
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return 0
+	}
+	return scheme.SeedSize()
+}
+
+// FrodoKEMEncapsulationSeedSize returns the size in bytes of the seed for FrodoKEMEncapsulateDeterministic, or 0 for an
+// unknown parameter set.
+func FrodoKEMEncapsulationSeedSize(parameterSet FrodoKEMParameterSet) int {
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return 0
+	}
+	return scheme.EncapsulationSeedSize()
+}
@@ -0,0 +1,51 @@
+package pq
+
+import "testing"
+
+func BenchmarkGenerateFrodoKEMKeyPair(b *testing.B) {
+	for _, parameterSet := range FrodoKEMParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			for b.Loop() {
+				if _, err := GenerateFrodoKEMKeyPair(parameterSet); err != nil {
+					b.Fatalf("failed to generate FrodoKEM key pair: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFrodoKEMEncapsulate(b *testing.B) {
+	for _, parameterSet := range FrodoKEMParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			keyPair, err := GenerateFrodoKEMKeyPair(parameterSet)
+			if err != nil {
+				b.Fatalf("failed to generate FrodoKEM key pair: %v", err)
+			}
+			for b.Loop() {
+				if _, _, err := FrodoKEMEncapsulate(keyPair.PublicKey); err != nil {
+					b.Fatalf("failed to encapsulate: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFrodoKEMDecapsulate(b *testing.B) {
+	for _, parameterSet := range FrodoKEMParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			keyPair, err := GenerateFrodoKEMKeyPair(parameterSet)
+			if err != nil {
+				b.Fatalf("failed to generate FrodoKEM key pair: %v", err)
+			}
+			ciphertext, _, err := FrodoKEMEncapsulate(keyPair.PublicKey)
+			if err != nil {
+				b.Fatalf("failed to encapsulate: %v", err)
+			}
+			for b.Loop() {
+				if _, err := FrodoKEMDecapsulate(keyPair.PrivateKey, ciphertext); err != nil {
+					b.Fatalf("failed to decapsulate: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package pq
+
+import "testing"
+
+func FuzzFrodoKEMDecapsulate(f *testing.F) {
+	key, _ := GenerateFrodoKEMKeyPair(FrodoKEM640SHAKE)
+	ciphertext, _, _ := FrodoKEMEncapsulate(key.PublicKey)
+	f.Add(ciphertext)
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, ciphertext []byte) {
+		sharedSecret, err := FrodoKEMDecapsulate(key.PrivateKey, ciphertext)
+		if len(ciphertext) != key.PrivateKey.Scheme().CiphertextSize() {
+			if err == nil {
+				t.Error("decapsulation should fail for a ciphertext of the wrong size")
+			}
+			return
+		}
+		if err != nil || len(sharedSecret) != key.PrivateKey.Scheme().SharedKeySize() {
+			t.Errorf("decapsulation should not fail for a ciphertext of the right size: %v", err)
+		}
+	})
+}
+
+func FuzzUnmarshalFrodoKEMPrivateKey(f *testing.F) {
+	key, _ := GenerateFrodoKEMKeyPair(FrodoKEM640SHAKE)
+	privateKeyBytes, _ := MarshalPrivateKey(key.PrivateKey)
+	f.Add(privateKeyBytes)
+	f.Add([]byte{0x01})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		privateKey, err := UnmarshalFrodoKEMPrivateKey(FrodoKEM640SHAKE, data)
+		if err != nil {
+			return
+		}
+		roundTrip, err := MarshalPrivateKey(privateKey)
+		if err != nil || string(roundTrip) != string(data) {
+			t.Error("an accepted private key should marshal back to the same bytes")
+		}
+	})
+}
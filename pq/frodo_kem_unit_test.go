@@ -0,0 +1,291 @@
+package pq
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// For TestFrodoKEMSizes: the key, ciphertext and shared secret sizes of the FrodoKEM round 3 specification, table 1.
+var frodoKEMExpectedSizes = map[FrodoKEMParameterSet]struct {
+	publicKey, privateKey, ciphertext, sharedSecret int
+}{
+	FrodoKEM640SHAKE: {9616, 19888, 9720, 16},
+}
+
+func TestFrodoKEMSizes(t *testing.T) {
+	for _, parameterSet := range FrodoKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			expected := frodoKEMExpectedSizes[parameterSet]
+			keyPair, err := GenerateFrodoKEMKeyPair(parameterSet)
+			require.NoError(t, err, "failed to generate FrodoKEM key pair")
+
+			publicKeyBytes, err := MarshalPublicKey(keyPair.PublicKey)
+			require.NoError(t, err, "failed to marshal public key")
+			require.Len(t, publicKeyBytes, expected.publicKey, "unexpected public key size")
+			privateKeyBytes, err := MarshalPrivateKey(keyPair.PrivateKey)
+			require.NoError(t, err, "failed to marshal private key")
+			require.Len(t, privateKeyBytes, expected.privateKey, "unexpected private key size")
+
+			ciphertext, sharedSecret, err := FrodoKEMEncapsulate(keyPair.PublicKey)
+			require.NoError(t, err, "failed to encapsulate")
+			require.Len(t, ciphertext, expected.ciphertext, "unexpected ciphertext size")
+			require.Len(t, sharedSecret, expected.sharedSecret, "unexpected shared secret size")
+			require.Equal(t, expected.ciphertext, keyPair.PublicKey.Scheme().CiphertextSize())
+			require.Equal(t, expected.sharedSecret, keyPair.PublicKey.Scheme().SharedKeySize())
+		})
+	}
+}
+
+func TestFrodoKEMMarshalUnmarshalRoundTrip(t *testing.T) {
+	for _, parameterSet := range FrodoKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			keyPair, err := GenerateFrodoKEMKeyPair(parameterSet)
+			require.NoError(t, err, "failed to generate FrodoKEM key pair")
+			require.Equal(t, parameterSet, keyPair.ParameterSet, "expected key pair to carry its parameter set")
+
+			publicKeyBytes, err := MarshalPublicKey(keyPair.PublicKey)
+			require.NoError(t, err, "failed to marshal public key")
+			privateKeyBytes, err := MarshalPrivateKey(keyPair.PrivateKey)
+			require.NoError(t, err, "failed to marshal private key")
+
+			publicKey, err := UnmarshalFrodoKEMPublicKey(parameterSet, publicKeyBytes)
+			require.NoError(t, err, "failed to unmarshal public key")
+			require.True(t, publicKey.Equal(keyPair.PublicKey), "expected unmarshalled public key to match the original public key")
+			privateKey, err := UnmarshalFrodoKEMPrivateKey(parameterSet, privateKeyBytes)
+			require.NoError(t, err, "failed to unmarshal private key")
+			require.True(t, privateKey.Equal(keyPair.PrivateKey), "expected unmarshalled private key to match the original private key")
+			require.True(t, privateKey.Public().Equal(publicKey), "expected the private key to embed the public key")
+
+			ciphertext, sharedSecret, err := FrodoKEMEncapsulate(publicKey)
+			require.NoError(t, err, "failed to encapsulate")
+			decapsulatedSecret, err := FrodoKEMDecapsulate(privateKey, ciphertext)
+			require.NoError(t, err, "failed to decapsulate")
+			require.Equal(t, sharedSecret, decapsulatedSecret, "expected both sides to agree on the shared secret")
+		})
+	}
+}
+
+func TestFrodoKEMDeterministic(t *testing.T) {
+	for _, parameterSet := range FrodoKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			seed := bytes.Repeat([]byte{0x42}, FrodoKEMSeedSize(parameterSet))
+			first, err := GenerateDeterministicFrodoKEMKeyPair(parameterSet, seed)
+			require.NoError(t, err, "failed to derive FrodoKEM key pair")
+			second, err := GenerateDeterministicFrodoKEMKeyPair(parameterSet, seed)
+			require.NoError(t, err, "failed to derive FrodoKEM key pair")
+			require.True(t, first.PrivateKey.Equal(second.PrivateKey), "expected the same seed to derive the same key")
+
+			encapsulationSeed := bytes.Repeat([]byte{0x24}, FrodoKEMEncapsulationSeedSize(parameterSet))
+			ciphertext1, sharedSecret1, err := FrodoKEMEncapsulateDeterministic(first.PublicKey, encapsulationSeed)
+			require.NoError(t, err, "failed to encapsulate")
+			ciphertext2, sharedSecret2, err := FrodoKEMEncapsulateDeterministic(second.PublicKey, encapsulationSeed)
+			require.NoError(t, err, "failed to encapsulate")
+			require.Equal(t, ciphertext1, ciphertext2, "expected deterministic ciphertexts")
+			require.Equal(t, sharedSecret1, sharedSecret2, "expected deterministic shared secrets")
+		})
+	}
+}
+
+// For TestFrodoKEMKnownAnswers: the SHA-256 of the first 100 entries of the PQCkemKAT_<private key size>_<aes|shake>.rsp
+// files of the FrodoKEM reference implementation, regenerated with the NIST AES-256 CTR DRBG the reference KAT generator
+// uses. The FrodoKEM-640-SHAKE digest is the one CIRCL records for KAT/PQCkemKAT_19888_shake.rsp of
+// github.com/microsoft/PQCrypto-LWEKE at commit 66fc7744. Every supported parameter set must have one.
+var frodoKEMKATDigests = map[FrodoKEMParameterSet]string{
+	FrodoKEM640SHAKE: "604a10cfc871dfaed9cb5b057c644ab03b16852cea7f39bc7f9831513b5b1cfa",
+}
+
+func TestFrodoKEMKnownAnswers(t *testing.T) {
+	for _, parameterSet := range FrodoKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			expectedDigest, ok := frodoKEMKATDigests[parameterSet]
+			require.True(t, ok, "no reference KAT digest recorded for %v", parameterSet)
+			if testing.Short() {
+				t.Skip("skipping 100 FrodoKEM KAT entries in short mode")
+			}
+			require.Equal(t, expectedDigest, frodoKEMKATDigest(t, parameterSet), "KAT digest mismatch")
+		})
+	}
+}
+
+// frodoKEMKATDigest hashes the first 100 KAT entries of the parameter set, formatted like the reference .rsp file.
+func frodoKEMKATDigest(t *testing.T, parameterSet FrodoKEMParameterSet) string {
+	t.Helper()
+	var seed [48]byte
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	digest := sha256.New()
+	fmt.Fprintf(digest, "# %s\n\n", parameterSet)
+	drbg := newNISTKATDRBG(seed[:])
+	for count := range 100 {
+		drbg.fill(seed[:])
+		fmt.Fprintf(digest, "count = %d\nseed = %X\n", count, seed)
+		entryDRBG := newNISTKATDRBG(seed[:])
+		keySeed := make([]byte, FrodoKEMSeedSize(parameterSet))
+		entryDRBG.fill(keySeed)
+		keyPair, err := GenerateDeterministicFrodoKEMKeyPair(parameterSet, keySeed)
+		require.NoError(t, err, "failed to derive FrodoKEM key pair")
+		encapsulationSeed := make([]byte, FrodoKEMEncapsulationSeedSize(parameterSet))
+		entryDRBG.fill(encapsulationSeed)
+		ciphertext, sharedSecret, err := FrodoKEMEncapsulateDeterministic(keyPair.PublicKey, encapsulationSeed)
+		require.NoError(t, err, "failed to encapsulate")
+		decapsulatedSecret, err := FrodoKEMDecapsulate(keyPair.PrivateKey, ciphertext)
+		require.NoError(t, err, "failed to decapsulate")
+		require.Equal(t, sharedSecret, decapsulatedSecret, "KAT entry %d does not round-trip", count)
+
+		publicKeyBytes, _ := MarshalPublicKey(keyPair.PublicKey)
+		privateKeyBytes, _ := MarshalPrivateKey(keyPair.PrivateKey)
+		fmt.Fprintf(digest, "pk = %X\nsk = %X\nct = %X\nss = %X\n\n", publicKeyBytes, privateKeyBytes, ciphertext, sharedSecret)
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// nistKATDRBG is the AES-256 CTR DRBG without derivation function of the NIST PQC KAT generators (randombytes.c).
+type nistKATDRBG struct {
+	key [32]byte
+	v   [16]byte
+}
+
+func newNISTKATDRBG(entropy []byte) *nistKATDRBG {
+	drbg := &nistKATDRBG{}
+	drbg.update(entropy)
+	return drbg
+}
+
+func (drbg *nistKATDRBG) nextBlock(block []byte) {
+	for i := len(drbg.v) - 1; i >= 0; i-- {
+		drbg.v[i]++
+		if drbg.v[i] != 0 {
+			break
+		}
+	}
+	cipher, err := aes.NewCipher(drbg.key[:])
+	if err != nil {
+		panic(err)
+	}
+	cipher.Encrypt(block, drbg.v[:])
+}
+
+func (drbg *nistKATDRBG) update(providedData []byte) {
+	var temporary [48]byte
+	for i := 0; i < 3; i++ {
+		drbg.nextBlock(temporary[16*i : 16*(i+1)])
+	}
+	for i := range providedData {
+		temporary[i] ^= providedData[i]
+	}
+	copy(drbg.key[:], temporary[:32])
+	copy(drbg.v[:], temporary[32:])
+}
+
+func (drbg *nistKATDRBG) fill(output []byte) {
+	var block [16]byte
+	for offset := 0; offset < len(output); offset += 16 {
+		drbg.nextBlock(block[:])
+		copy(output[offset:], block[:])
+	}
+	drbg.update(nil)
+}
+
+func TestFrodoKEMImplicitRejection(t *testing.T) {
+	for _, parameterSet := range FrodoKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			keyPair, err := GenerateFrodoKEMKeyPair(parameterSet)
+			require.NoError(t, err, "failed to generate FrodoKEM key pair")
+			ciphertext, sharedSecret, err := FrodoKEMEncapsulate(keyPair.PublicKey)
+			require.NoError(t, err, "failed to encapsulate")
+
+			for _, position := range []int{0, len(ciphertext) / 2, len(ciphertext) - 1} {
+				tampered := append([]byte(nil), ciphertext...)
+				tampered[position] ^= 0x80
+				rejectionSecret, err := FrodoKEMDecapsulate(keyPair.PrivateKey, tampered)
+				require.NoError(t, err, "a tampered ciphertext of the right size must not be an error")
+				require.Len(t, rejectionSecret, len(sharedSecret), "unexpected rejection secret size")
+				require.NotEqual(t, sharedSecret, rejectionSecret, "expected a tampered ciphertext to yield another secret")
+				again, err := FrodoKEMDecapsulate(keyPair.PrivateKey, tampered)
+				require.NoError(t, err, "failed to decapsulate")
+				require.Equal(t, rejectionSecret, again, "expected the rejection secret to be deterministic")
+			}
+		})
+	}
+}
+
+func TestFrodoKEMInvalidInputs(t *testing.T) {
+	keyPair, err := GenerateFrodoKEMKeyPair(FrodoKEM640SHAKE)
+	require.NoError(t, err, "failed to generate FrodoKEM key pair")
+	publicKeyBytes, _ := MarshalPublicKey(keyPair.PublicKey)
+	privateKeyBytes, _ := MarshalPrivateKey(keyPair.PrivateKey)
+	ciphertext, _, err := FrodoKEMEncapsulate(keyPair.PublicKey)
+	require.NoError(t, err, "failed to encapsulate")
+
+	_, err = GenerateDeterministicFrodoKEMKeyPair(FrodoKEM640SHAKE, make([]byte, 47))
+	require.ErrorIs(t, err, ErrInvalidSeedSize)
+	_, _, err = FrodoKEMEncapsulateDeterministic(keyPair.PublicKey, make([]byte, 24))
+	require.ErrorIs(t, err, ErrInvalidSeedSize)
+	_, err = GenerateFrodoKEMKeyPair(FrodoKEMParameterSet(0))
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+	_, err = UnmarshalFrodoKEMPublicKey(FrodoKEMParameterSet(0), publicKeyBytes)
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+
+	_, err = UnmarshalFrodoKEMPublicKey(FrodoKEM640SHAKE, publicKeyBytes[:len(publicKeyBytes)-1])
+	var sizeError *SizeError
+	require.ErrorAs(t, err, &sizeError)
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	require.Equal(t, 9615, sizeError.Got)
+	require.Equal(t, 9616, sizeError.Want)
+	_, err = UnmarshalFrodoKEMPrivateKey(FrodoKEM640SHAKE, append(privateKeyBytes, 0))
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	_, err = FrodoKEMDecapsulate(keyPair.PrivateKey, ciphertext[:len(ciphertext)-1])
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+	_, err = FrodoKEMDecapsulate(keyPair.PrivateKey, append(ciphertext, 0))
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	corrupted := append([]byte(nil), privateKeyBytes...)
+	corrupted[16] ^= 0x01 // first byte of the embedded public key
+	_, err = UnmarshalFrodoKEMPrivateKey(FrodoKEM640SHAKE, corrupted)
+	var keyValidationError *KeyValidationError
+	require.ErrorAs(t, err, &keyValidationError)
+	require.ErrorIs(t, err, ErrInvalidDecapsulationKey)
+
+	// Keys of the other KEMs are rejected in both directions.
+	mlkemKeyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	require.NoError(t, err, "failed to generate ML-KEM key pair")
+	_, _, err = FrodoKEMEncapsulate(mlkemKeyPair.PublicKey)
+	require.Error(t, err, "expected an error for an ML-KEM public key")
+	_, err = FrodoKEMDecapsulate(mlkemKeyPair.PrivateKey, ciphertext)
+	require.Error(t, err, "expected an error for an ML-KEM private key")
+	_, _, err = MLKEMEncapsulate(keyPair.PublicKey)
+	require.Error(t, err, "expected MLKEMEncapsulate to reject a FrodoKEM public key")
+	_, _, err = FrodoKEMEncapsulate(nil)
+	require.Error(t, err, "expected an error for a nil public key")
+	_, err = FrodoKEMDecapsulate(nil, ciphertext)
+	require.Error(t, err, "expected an error for a nil private key")
+}
+
+func TestFrodoKEMRegistry(t *testing.T) {
+	for _, parameterSet := range FrodoKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			scheme, err := LookupKEMScheme(parameterSet.String())
+			require.NoError(t, err, "expected %v to be registered", parameterSet)
+			require.Nil(t, scheme.OID(), "FrodoKEM has no standard OID")
+			require.Equal(t, frodoKEMExpectedSizes[parameterSet].ciphertext, scheme.CiphertextSize())
+			require.Equal(t, frodoKEMExpectedSizes[parameterSet].sharedSecret, scheme.SharedSecretSize())
+
+			publicKey, privateKey, err := scheme.GenerateKey()
+			require.NoError(t, err, "failed to generate key")
+			require.Len(t, publicKey, frodoKEMExpectedSizes[parameterSet].publicKey)
+			require.Len(t, privateKey, frodoKEMExpectedSizes[parameterSet].privateKey)
+			ciphertext, sharedSecret, err := scheme.Encapsulate(publicKey)
+			require.NoError(t, err, "failed to encapsulate")
+			decapsulatedSecret, err := scheme.Decapsulate(privateKey, ciphertext)
+			require.NoError(t, err, "failed to decapsulate")
+			require.Equal(t, sharedSecret, decapsulatedSecret, "expected both sides to agree on the shared secret")
+		})
+	}
+}
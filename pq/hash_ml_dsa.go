@@ -0,0 +1,241 @@
+package pq
+
+import (
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"fmt"
+
+	"gopq/pq/internal/mldsa"
+)
+
+// MLDSAPreHash identifies the pre-hash function PH used by HashML-DSA (FIPS 204 Section 5.4).
+type MLDSAPreHash int
+
+const (
+	// PreHashSHA256 pre-hashes the message with SHA-256.
+	PreHashSHA256 MLDSAPreHash = iota + 1
+	// PreHashSHA384 pre-hashes the message with SHA-384.
+	PreHashSHA384
+	// PreHashSHA512 pre-hashes the message with SHA-512.
+	PreHashSHA512
+	// PreHashSHA3_256 pre-hashes the message with SHA3-256.
+	PreHashSHA3_256
+	// PreHashSHA3_384 pre-hashes the message with SHA3-384.
+	PreHashSHA3_384
+	// PreHashSHA3_512 pre-hashes the message with SHA3-512.
+	PreHashSHA3_512
+	// PreHashSHAKE128 pre-hashes the message with SHAKE128, with 256 bits of output.
+	PreHashSHAKE128
+	// PreHashSHAKE256 pre-hashes the message with SHAKE256, with 512 bits of output.
+	PreHashSHAKE256
+)
+
+// MLDSAPreHashes lists the pre-hash functions supported by HashML-DSA.
+var MLDSAPreHashes = []MLDSAPreHash{
+	PreHashSHA256, PreHashSHA384, PreHashSHA512,
+	PreHashSHA3_256, PreHashSHA3_384, PreHashSHA3_512,
+	PreHashSHAKE128, PreHashSHAKE256,
+}
+
+// String returns the name of the pre-hash function, for example "SHA-512".
+func (preHash MLDSAPreHash) String() string {
+	switch preHash {
+	case PreHashSHA256:
+		return "SHA-256"
+	case PreHashSHA384:
+		return "SHA-384"
+	case PreHashSHA512:
+		return "SHA-512"
+	case PreHashSHA3_256:
+		return "SHA3-256"
+	case PreHashSHA3_384:
+		return "SHA3-384"
+	case PreHashSHA3_512:
+		return "SHA3-512"
+	case PreHashSHAKE128:
+		return "SHAKE128"
+	case PreHashSHAKE256:
+		return "SHAKE256"
+	default:
+		return fmt.Sprintf("MLDSAPreHash(%d)", int(preHash))
+	}
+}
+
+// DigestSize returns the size in bytes of the digest PH(M), or 0 for an unsupported pre-hash function.
+func (preHash MLDSAPreHash) DigestSize() int {
+	switch preHash {
+	case PreHashSHA256, PreHashSHA3_256, PreHashSHAKE128:
+		return 32
+	case PreHashSHA384, PreHashSHA3_384:
+		return 48
+	case PreHashSHA512, PreHashSHA3_512, PreHashSHAKE256:
+		return 64
+	default:
+		return 0
+	}
+}
+
+// oid returns the DER encoding of the pre-hash function's NIST object identifier (2.16.840.1.101.3.4.2.x),
+// which HashML-DSA binds into every signature.
+func (preHash MLDSAPreHash) oid() ([]byte, error) {
+	var arc byte
+	switch preHash {
+	case PreHashSHA256:
+		arc = 0x01
+	case PreHashSHA384:
+		arc = 0x02
+	case PreHashSHA512:
+		arc = 0x03
+	case PreHashSHA3_256:
+		arc = 0x08
+	case PreHashSHA3_384:
+		arc = 0x09
+	case PreHashSHA3_512:
+		arc = 0x0A
+	case PreHashSHAKE128:
+		arc = 0x0B
+	case PreHashSHAKE256:
+		arc = 0x0C
+	default:
+		return nil, fmt.Errorf("%w: HashML-DSA pre-hash function %v", ErrUnknownAlgorithm, preHash)
+	}
+	return []byte{0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, arc}, nil
+}
+
+// Digest computes PH(M) for the pre-hash function, for callers that hash the message themselves.
+func (preHash MLDSAPreHash) Digest(messageBytes []byte) ([]byte, error) {
+	switch preHash {
+	case PreHashSHA256:
+		digest := sha256.Sum256(messageBytes)
+		return digest[:], nil
+	case PreHashSHA384:
+		digest := sha512.Sum384(messageBytes)
+		return digest[:], nil
+	case PreHashSHA512:
+		digest := sha512.Sum512(messageBytes)
+		return digest[:], nil
+	case PreHashSHA3_256:
+		digest := sha3.Sum256(messageBytes)
+		return digest[:], nil
+	case PreHashSHA3_384:
+		digest := sha3.Sum384(messageBytes)
+		return digest[:], nil
+	case PreHashSHA3_512:
+		digest := sha3.Sum512(messageBytes)
+		return digest[:], nil
+	case PreHashSHAKE128:
+		return sha3.SumSHAKE128(messageBytes, 32), nil
+	case PreHashSHAKE256:
+		return sha3.SumSHAKE256(messageBytes, 64), nil
+	default:
+		return nil, fmt.Errorf("%w: HashML-DSA pre-hash function %v", ErrUnknownAlgorithm, preHash)
+	}
+}
+
+// hashMLDSAMessageRepresentative computes mu = H(tr || M', 64) with the HashML-DSA formatted message
+// M' = 1 || len(ctx) || ctx || OID(PH) || PH(M) (FIPS 204 Algorithm 4, line 23).
+func hashMLDSAMessageRepresentative(tr []byte, preHash MLDSAPreHash, digestBytes []byte, contextBytes []byte) (*[mldsa.MuSize]byte, error) {
+	if len(contextBytes) > MLDSAMaxContextSize {
+		return nil, fmt.Errorf("%w: got %d bytes, maximum is %d", ErrContextTooLong, len(contextBytes), MLDSAMaxContextSize)
+	}
+	oid, oidError := preHash.oid()
+	if oidError != nil {
+		return nil, oidError
+	}
+	if len(digestBytes) != preHash.DigestSize() {
+		return nil, newSizeError(ErrInvalidDigestSize, fmt.Sprintf("%v digest", preHash), len(digestBytes), preHash.DigestSize())
+	}
+	var mu [mldsa.MuSize]byte
+	messageHash := mldsa.NewMessageHash(tr)
+	_, _ = messageHash.Write([]byte{1, byte(len(contextBytes))})
+	_, _ = messageHash.Write(contextBytes)
+	_, _ = messageHash.Write(oid)
+	_, _ = messageHash.Write(digestBytes)
+	_, _ = messageHash.Read(mu[:])
+	return &mu, nil
+}
+
+// HashMLDSASign signs a message with HashML-DSA (FIPS 204 Algorithm 4), using the hedged variant.
+// The message is first hashed with preHash, and the signature binds the pre-hash function's OID, so it never verifies as a pure ML-DSA signature.
+// The context must be at most MLDSAMaxContextSize bytes; nil and empty are equivalent.
+func HashMLDSASign(parameterSet MLDSAParameterSet, preHash MLDSAPreHash, privateKeyBytes []byte, messageBytes []byte, contextBytes []byte) (signatureBytes []byte, signError error) {
+	return HashMLDSASignWithOptions(parameterSet, preHash, privateKeyBytes, messageBytes, &MLDSASignOptions{Context: contextBytes})
+}
+
+// HashMLDSASignWithOptions signs a message with HashML-DSA, with the context string and signing randomness selected by the options.
+func HashMLDSASignWithOptions(parameterSet MLDSAParameterSet, preHash MLDSAPreHash, privateKeyBytes []byte, messageBytes []byte, options *MLDSASignOptions) (signatureBytes []byte, signError error) {
+	digestBytes, digestError := preHash.Digest(messageBytes)
+	if digestError != nil {
+		return nil, digestError
+	}
+	return HashMLDSASignDigestWithOptions(parameterSet, preHash, privateKeyBytes, digestBytes, options)
+}
+
+// HashMLDSASignDigest signs a digest PH(M) that the caller has already computed with preHash, as done by HSMs and toolchains that only sign digests.
+// The digest must be exactly preHash.DigestSize() bytes.
+func HashMLDSASignDigest(parameterSet MLDSAParameterSet, preHash MLDSAPreHash, privateKeyBytes []byte, digestBytes []byte, contextBytes []byte) (signatureBytes []byte, signError error) {
+	return HashMLDSASignDigestWithOptions(parameterSet, preHash, privateKeyBytes, digestBytes, &MLDSASignOptions{Context: contextBytes})
+}
+
+// HashMLDSASignDigestWithOptions signs a digest PH(M) with HashML-DSA, with the context string and signing randomness selected by the options.
+func HashMLDSASignDigestWithOptions(parameterSet MLDSAParameterSet, preHash MLDSAPreHash, privateKeyBytes []byte, digestBytes []byte, options *MLDSASignOptions) (signatureBytes []byte, signError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			signError = newPanicError("HashMLDSASignDigestWithOptions", recoveredPanic)
+		}
+	}()
+	parameters, parametersError := parameterSet.internalParameters()
+	if parametersError != nil {
+		return nil, parametersError
+	}
+	tr, trError := mldsaPrivateKeyHash(parameterSet, parameters, privateKeyBytes)
+	if trError != nil {
+		return nil, trError
+	}
+	mu, muError := hashMLDSAMessageRepresentative(tr[:], preHash, digestBytes, options.context())
+	if muError != nil {
+		return nil, muError
+	}
+	signatureBytes, signError = signMLDSAMu(parameters, privateKeyBytes, mu, options)
+	if signError != nil {
+		return nil, fmt.Errorf("HashML-DSA: %w", signError)
+	}
+	return signatureBytes, nil
+}
+
+// HashMLDSAVerify verifies a HashML-DSA signature (FIPS 204 Algorithm 5) over a message, which is hashed with preHash.
+// The signature is only valid for the same pre-hash function and context string used to sign.
+func HashMLDSAVerify(parameterSet MLDSAParameterSet, preHash MLDSAPreHash, publicKeyBytes []byte, messageBytes []byte, signatureBytes []byte, contextBytes []byte) (isSignatureValid bool, verifyError error) {
+	digestBytes, digestError := preHash.Digest(messageBytes)
+	if digestError != nil {
+		return false, digestError
+	}
+	return HashMLDSAVerifyDigest(parameterSet, preHash, publicKeyBytes, digestBytes, signatureBytes, contextBytes)
+}
+
+// HashMLDSAVerifyDigest verifies a HashML-DSA signature over a digest PH(M) that the caller has already computed with preHash.
+func HashMLDSAVerifyDigest(parameterSet MLDSAParameterSet, preHash MLDSAPreHash, publicKeyBytes []byte, digestBytes []byte, signatureBytes []byte, contextBytes []byte) (isSignatureValid bool, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			verifyError = newPanicError("HashMLDSAVerifyDigest", recoveredPanic)
+		}
+	}()
+	parameters, parametersError := parameterSet.internalParameters()
+	if parametersError != nil {
+		return false, parametersError
+	}
+	if len(publicKeyBytes) != parameters.PublicKeySize() {
+		return false, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v public key", parameterSet), len(publicKeyBytes), parameters.PublicKeySize())
+	}
+	tr := mldsa.PublicKeyHash(publicKeyBytes)
+	mu, muError := hashMLDSAMessageRepresentative(tr[:], preHash, digestBytes, contextBytes)
+	if muError != nil {
+		return false, muError
+	}
+	isSignatureValid, verifyError = mldsa.VerifyMu(parameters, publicKeyBytes, mu, signatureBytes)
+	if verifyError != nil {
+		return false, fmt.Errorf("HashML-DSA verify failed: %w", verifyError)
+	}
+	return isSignatureValid, nil
+}
@@ -0,0 +1,45 @@
+package pq
+
+import "testing"
+
+func BenchmarkHashMLDSASign(b *testing.B) {
+	for _, parameterSet := range MLDSAParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			key, err := GenerateMLDSAKeyPair(parameterSet)
+			if err != nil {
+				b.Fatalf("failed to generate ML-DSA key pair: %v", err)
+			}
+			message := []byte("benchmark message")
+			b.ResetTimer()
+			for b.Loop() {
+				_, signErr := HashMLDSASign(parameterSet, PreHashSHA512, key.PrivateKey, message, nil)
+				if signErr != nil {
+					b.Fatalf("signing failed: %v", signErr)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkHashMLDSAVerify(b *testing.B) {
+	for _, parameterSet := range MLDSAParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			key, err := GenerateMLDSAKeyPair(parameterSet)
+			if err != nil {
+				b.Fatalf("failed to generate ML-DSA key pair: %v", err)
+			}
+			message := []byte("benchmark message")
+			signature, signErr := HashMLDSASign(parameterSet, PreHashSHA512, key.PrivateKey, message, nil)
+			if signErr != nil {
+				b.Fatalf("signing failed: %v", signErr)
+			}
+			b.ResetTimer()
+			for b.Loop() {
+				_, verifyErr := HashMLDSAVerify(parameterSet, PreHashSHA512, key.PublicKey, message, signature, nil)
+				if verifyErr != nil {
+					b.Fatalf("verifying failed: %v", verifyErr)
+				}
+			}
+		})
+	}
+}
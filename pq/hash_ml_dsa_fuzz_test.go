@@ -0,0 +1,28 @@
+package pq
+
+import "testing"
+
+func FuzzHashMLDSASignAndVerify(f *testing.F) {
+	key, _ := GenerateMLDSAKeyPair(MLDSA44)
+	f.Add(uint8(PreHashSHA256), []byte("msg"), []byte("ctx"))
+	f.Fuzz(func(t *testing.T, preHashByte uint8, msg []byte, ctx []byte) {
+		preHash := MLDSAPreHashes[int(preHashByte)%len(MLDSAPreHashes)]
+		signature, signErr := HashMLDSASign(MLDSA44, preHash, key.PrivateKey, msg, ctx)
+		if len(ctx) > MLDSAMaxContextSize {
+			if signErr == nil {
+				t.Fatalf("expected error for %d byte context", len(ctx))
+			}
+			return
+		}
+		if signErr != nil {
+			t.Fatalf("signing failed: %v", signErr)
+		}
+		isValid, verifyErr := HashMLDSAVerify(MLDSA44, preHash, key.PublicKey, msg, signature, ctx)
+		if verifyErr != nil {
+			t.Fatalf("verifying failed: %v", verifyErr)
+		}
+		if !isValid {
+			t.Errorf("%v signature should verify for fuzzed input", preHash)
+		}
+	})
+}
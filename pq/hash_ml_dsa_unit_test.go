@@ -0,0 +1,264 @@
+package pq
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// For TestHashMLDSASignAndVerify:
+func TestHashMLDSASignAndVerify(t *testing.T) {
+	for _, parameterSet := range MLDSAParameterSets {
+		key, err := GenerateMLDSAKeyPair(parameterSet)
+		if err != nil {
+			t.Fatalf("failed to generate %v key pair: %v", parameterSet, err)
+		}
+		for _, preHash := range MLDSAPreHashes {
+			t.Run(parameterSet.String()+"/"+preHash.String(), func(t *testing.T) {
+				msg := []byte("test message")
+				ctx := []byte("hsm-v1")
+				sig, err := HashMLDSASign(parameterSet, preHash, key.PrivateKey, msg, ctx)
+				if err != nil {
+					t.Fatalf("signing failed: %v", err)
+				}
+				isVerify, err := HashMLDSAVerify(parameterSet, preHash, key.PublicKey, msg, sig, ctx)
+				if err != nil {
+					t.Fatalf("verifying failed: %v", err)
+				}
+				if !isVerify {
+					t.Error("signature should verify")
+				}
+				isVerify, err = HashMLDSAVerify(parameterSet, preHash, key.PublicKey, []byte("other message"), sig, ctx)
+				if err != nil {
+					t.Fatalf("verifying failed: %v", err)
+				}
+				if isVerify {
+					t.Error("signature should not verify for a different message")
+				}
+				isVerify, err = HashMLDSAVerify(parameterSet, preHash, key.PublicKey, msg, sig, []byte("other-ctx"))
+				if err != nil {
+					t.Fatalf("verifying failed: %v", err)
+				}
+				if isVerify {
+					t.Error("signature should not verify under a different context")
+				}
+			})
+		}
+	}
+}
+
+// For TestHashMLDSADigestMatchesMessage:
+func TestHashMLDSADigestMatchesMessage(t *testing.T) {
+	key, err := GenerateMLDSAKeyPair(MLDSA65)
+	if err != nil {
+		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+	}
+	msg := []byte("firmware image")
+	for _, preHash := range MLDSAPreHashes {
+		digest, err := preHash.Digest(msg)
+		if err != nil {
+			t.Fatalf("%v digest failed: %v", preHash, err)
+		}
+		if len(digest) != preHash.DigestSize() {
+			t.Errorf("%v digest len = %d, want %d", preHash, len(digest), preHash.DigestSize())
+		}
+		sig, err := HashMLDSASignDigest(MLDSA65, preHash, key.PrivateKey, digest, nil)
+		if err != nil {
+			t.Fatalf("%v signing digest failed: %v", preHash, err)
+		}
+		isVerify, err := HashMLDSAVerify(MLDSA65, preHash, key.PublicKey, msg, sig, nil)
+		if err != nil {
+			t.Fatalf("%v verifying failed: %v", preHash, err)
+		}
+		if !isVerify {
+			t.Errorf("%v signature over a digest should verify over the message", preHash)
+		}
+		sig, err = HashMLDSASign(MLDSA65, preHash, key.PrivateKey, msg, nil)
+		if err != nil {
+			t.Fatalf("%v signing failed: %v", preHash, err)
+		}
+		isVerify, err = HashMLDSAVerifyDigest(MLDSA65, preHash, key.PublicKey, digest, sig, nil)
+		if err != nil {
+			t.Fatalf("%v verifying digest failed: %v", preHash, err)
+		}
+		if !isVerify {
+			t.Errorf("%v signature over a message should verify over the digest", preHash)
+		}
+	}
+}
+
+// For TestHashMLDSAIsDomainSeparated:
+func TestHashMLDSAIsDomainSeparated(t *testing.T) {
+	key, err := GenerateMLDSAKeyPair(MLDSA44)
+	if err != nil {
+		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+	}
+	msg := []byte("test message")
+	hashSig, err := HashMLDSASign(MLDSA44, PreHashSHA512, key.PrivateKey, msg, nil)
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	isVerify, err := MLDSAVerify(MLDSA44, key.PublicKey, msg, hashSig)
+	if err != nil {
+		t.Fatalf("verifying failed: %v", err)
+	}
+	if isVerify {
+		t.Error("HashML-DSA signature should not verify as pure ML-DSA")
+	}
+	digest, _ := PreHashSHA512.Digest(msg)
+	isVerify, err = MLDSAVerify(MLDSA44, key.PublicKey, digest, hashSig)
+	if err != nil {
+		t.Fatalf("verifying failed: %v", err)
+	}
+	if isVerify {
+		t.Error("HashML-DSA signature should not verify as pure ML-DSA over the digest")
+	}
+	for _, otherPreHash := range MLDSAPreHashes {
+		if otherPreHash == PreHashSHA512 {
+			continue
+		}
+		isVerify, err = HashMLDSAVerify(MLDSA44, otherPreHash, key.PublicKey, msg, hashSig, nil)
+		if err != nil {
+			t.Fatalf("verifying failed: %v", err)
+		}
+		if isVerify {
+			t.Errorf("SHA-512 HashML-DSA signature should not verify with %v", otherPreHash)
+		}
+	}
+
+	pureSig, err := MLDSASign(MLDSA44, key.PrivateKey, msg)
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	isVerify, err = HashMLDSAVerify(MLDSA44, PreHashSHA512, key.PublicKey, msg, pureSig, nil)
+	if err != nil {
+		t.Fatalf("verifying failed: %v", err)
+	}
+	if isVerify {
+		t.Error("pure ML-DSA signature should not verify as HashML-DSA")
+	}
+}
+
+// For TestHashMLDSADigestVector:
+func TestHashMLDSADigestVector(t *testing.T) {
+	// SHA-256("abc") from FIPS 180-2 Appendix B.1.
+	expected := []byte{
+		0xba, 0x78, 0x16, 0xbf, 0x8f, 0x01, 0xcf, 0xea, 0x41, 0x41, 0x40, 0xde, 0x5d, 0xae, 0x22, 0x23,
+		0xb0, 0x03, 0x61, 0xa3, 0x96, 0x17, 0x7a, 0x9c, 0xb4, 0x10, 0xff, 0x61, 0xf2, 0x00, 0x15, 0xad,
+	}
+	digest, err := PreHashSHA256.Digest([]byte("abc"))
+	if err != nil {
+		t.Fatalf("digest failed: %v", err)
+	}
+	if !bytes.Equal(digest, expected) {
+		t.Errorf("SHA-256 digest = %x, want %x", digest, expected)
+	}
+	oid, err := PreHashSHAKE256.oid()
+	if err != nil {
+		t.Fatalf("oid failed: %v", err)
+	}
+	// 2.16.840.1.101.3.4.2.12 (id-shake256)
+	if !bytes.Equal(oid, []byte{0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x02, 0x0C}) {
+		t.Errorf("unexpected SHAKE256 OID encoding: %x", oid)
+	}
+}
+
+// For TestHashMLDSAInvalidInput:
+func TestHashMLDSAInvalidInput(t *testing.T) {
+	key, err := GenerateMLDSAKeyPair(MLDSA44)
+	if err != nil {
+		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+	}
+	msg := []byte("test message")
+	unsupported := MLDSAPreHash(0)
+	if got := unsupported.String(); got != "MLDSAPreHash(0)" {
+		t.Errorf("unexpected name for unsupported pre-hash: %q", got)
+	}
+	if unsupported.DigestSize() != 0 {
+		t.Error("expected 0 digest size for unsupported pre-hash")
+	}
+	if sig, err := HashMLDSASign(MLDSA44, unsupported, key.PrivateKey, msg, nil); err == nil || sig != nil {
+		t.Error("expected error for unsupported pre-hash")
+	}
+	if sig, err := HashMLDSASignDigest(MLDSA44, unsupported, key.PrivateKey, make([]byte, 32), nil); err == nil || sig != nil {
+		t.Error("expected error for unsupported pre-hash digest")
+	}
+	if sig, err := HashMLDSASignDigest(MLDSA44, PreHashSHA512, key.PrivateKey, make([]byte, 32), nil); err == nil || sig != nil {
+		t.Error("expected error for a digest of the wrong size")
+	}
+	if sig, err := HashMLDSASign(MLDSAParameterSet(0), PreHashSHA256, key.PrivateKey, msg, nil); err == nil || sig != nil {
+		t.Error("expected error for unsupported parameter set")
+	}
+	if sig, err := HashMLDSASign(MLDSA44, PreHashSHA256, []byte{}, msg, nil); err == nil || sig != nil {
+		t.Error("expected error for empty private key")
+	}
+	if sig, err := HashMLDSASign(MLDSA65, PreHashSHA256, key.PrivateKey, msg, nil); err == nil || sig != nil {
+		t.Error("expected error for a private key of another parameter set")
+	}
+	tooLongContext := make([]byte, MLDSAMaxContextSize+1)
+	sig, err := HashMLDSASign(MLDSA44, PreHashSHA256, key.PrivateKey, msg, tooLongContext)
+	if !errors.Is(err, ErrContextTooLong) {
+		t.Errorf("expected ErrContextTooLong, got %v", err)
+	}
+	if sig != nil {
+		t.Error("expected nil signature for a context that is too long")
+	}
+
+	sig, err = HashMLDSASign(MLDSA44, PreHashSHA256, key.PrivateKey, msg, nil)
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if isVerify, err := HashMLDSAVerify(MLDSA44, PreHashSHA256, []byte{}, msg, sig, nil); err == nil || isVerify {
+		t.Error("expected error for empty public key")
+	}
+	if isVerify, err := HashMLDSAVerify(MLDSA44, unsupported, key.PublicKey, msg, sig, nil); err == nil || isVerify {
+		t.Error("expected error verifying with an unsupported pre-hash")
+	}
+	if isVerify, err := HashMLDSAVerifyDigest(MLDSA44, PreHashSHA256, key.PublicKey, make([]byte, 31), sig, nil); err == nil || isVerify {
+		t.Error("expected error verifying a digest of the wrong size")
+	}
+	if isVerify, err := HashMLDSAVerify(MLDSA44, PreHashSHA256, key.PublicKey, msg, sig, tooLongContext); !errors.Is(err, ErrContextTooLong) || isVerify {
+		t.Errorf("expected ErrContextTooLong verifying, got %v", err)
+	}
+	sig[0] ^= 0xFF
+	isVerify, err := HashMLDSAVerify(MLDSA44, PreHashSHA256, key.PublicKey, msg, sig, nil)
+	if err != nil {
+		t.Fatalf("verifying failed: %v", err)
+	}
+	if isVerify {
+		t.Error("verify should fail with tampered signature")
+	}
+}
+
+// For TestHashMLDSASignWithOptions:
+func TestHashMLDSASignWithOptions(t *testing.T) {
+	key, err := GenerateMLDSAKeyPair(MLDSA87)
+	if err != nil {
+		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+	}
+	msg := []byte("test message")
+	options := &MLDSASignOptions{Context: []byte("ctx"), Deterministic: true}
+	sig1, err := HashMLDSASignWithOptions(MLDSA87, PreHashSHA384, key.PrivateKey, msg, options)
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	digest, _ := PreHashSHA384.Digest(msg)
+	sig2, err := HashMLDSASignDigestWithOptions(MLDSA87, PreHashSHA384, key.PrivateKey, digest, options)
+	if err != nil {
+		t.Fatalf("signing digest failed: %v", err)
+	}
+	if !bytes.Equal(sig1, sig2) {
+		t.Error("deterministic HashML-DSA signatures should be identical")
+	}
+	isVerify, err := HashMLDSAVerify(MLDSA87, PreHashSHA384, key.PublicKey, msg, sig1, options.Context)
+	if err != nil {
+		t.Fatalf("verifying failed: %v", err)
+	}
+	if !isVerify {
+		t.Error("deterministic HashML-DSA signature should verify")
+	}
+	sig, err := HashMLDSASignWithOptions(MLDSA87, PreHashSHA384, key.PrivateKey, msg, &MLDSASignOptions{Rand: bytes.NewReader(nil)})
+	if err == nil || sig != nil {
+		t.Error("expected error for empty randomness")
+	}
+}
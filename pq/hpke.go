@@ -0,0 +1,741 @@
+package pq
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/cloudflare/circl/hpke"
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/kem/xwing"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// HPKEKEM identifies an HPKE KEM by its RFC 9180 KEM identifier.
+type HPKEKEM uint16
+
+const (
+	// HPKEKEMP256HKDFSHA256 is DHKEM(P-256, HKDF-SHA256).
+	HPKEKEMP256HKDFSHA256 HPKEKEM = 0x0010
+	// HPKEKEMP384HKDFSHA384 is DHKEM(P-384, HKDF-SHA384).
+	HPKEKEMP384HKDFSHA384 HPKEKEM = 0x0011
+	// HPKEKEMP521HKDFSHA512 is DHKEM(P-521, HKDF-SHA512).
+	HPKEKEMP521HKDFSHA512 HPKEKEM = 0x0012
+	// HPKEKEMX25519HKDFSHA256 is DHKEM(X25519, HKDF-SHA256).
+	HPKEKEMX25519HKDFSHA256 HPKEKEM = 0x0020
+	// HPKEKEMX448HKDFSHA512 is DHKEM(X448, HKDF-SHA512).
+	HPKEKEMX448HKDFSHA512 HPKEKEM = 0x0021
+	// HPKEKEMMLKEM512 is ML-KEM-512 from draft-ietf-hpke-pq, with the keys of MLKEM512.
+	HPKEKEMMLKEM512 HPKEKEM = 0x0040
+	// HPKEKEMMLKEM768 is ML-KEM-768 from draft-ietf-hpke-pq, with the keys of MLKEM768.
+	HPKEKEMMLKEM768 HPKEKEM = 0x0041
+	// HPKEKEMMLKEM1024 is ML-KEM-1024 from draft-ietf-hpke-pq, with the keys of MLKEM1024.
+	HPKEKEMMLKEM1024 HPKEKEM = 0x0042
+	// HPKEKEMXWing is the X25519 + ML-KEM-768 hybrid X-Wing, with the keys of GenerateXWingKeyPair.
+	HPKEKEMXWing HPKEKEM = 0x647a
+)
+
+// HPKEKDF identifies an HPKE key derivation function by its RFC 9180 KDF identifier.
+type HPKEKDF uint16
+
+const (
+	// HPKEKDFHKDFSHA256 is HKDF-SHA256.
+	HPKEKDFHKDFSHA256 HPKEKDF = 0x0001
+	// HPKEKDFHKDFSHA384 is HKDF-SHA384.
+	HPKEKDFHKDFSHA384 HPKEKDF = 0x0002
+	// HPKEKDFHKDFSHA512 is HKDF-SHA512.
+	HPKEKDFHKDFSHA512 HPKEKDF = 0x0003
+	// HPKEKDFSHAKE128 is the one-stage SHAKE128 KDF from draft-ietf-hpke-pq.
+	HPKEKDFSHAKE128 HPKEKDF = 0x0010
+	// HPKEKDFSHAKE256 is the one-stage SHAKE256 KDF from draft-ietf-hpke-pq.
+	HPKEKDFSHAKE256 HPKEKDF = 0x0011
+)
+
+// HPKEAEAD identifies an HPKE AEAD by its RFC 9180 AEAD identifier.
+type HPKEAEAD uint16
+
+const (
+	// HPKEAEADAES128GCM is AES-128-GCM.
+	HPKEAEADAES128GCM HPKEAEAD = 0x0001
+	// HPKEAEADAES256GCM is AES-256-GCM.
+	HPKEAEADAES256GCM HPKEAEAD = 0x0002
+	// HPKEAEADChaCha20Poly1305 is ChaCha20-Poly1305.
+	HPKEAEADChaCha20Poly1305 HPKEAEAD = 0x0003
+	// HPKEAEADExportOnly is the export-only AEAD: the context can only derive secrets with Export.
+	HPKEAEADExportOnly HPKEAEAD = 0xffff
+)
+
+// HPKEMode is an RFC 9180 mode, selected by the HPKEOptions given to the setup.
+type HPKEMode uint8
+
+const (
+	// HPKEModeBase authenticates nothing but the recipient.
+	HPKEModeBase HPKEMode = 0x00
+	// HPKEModePSK also authenticates the sender as a holder of the pre-shared key.
+	HPKEModePSK HPKEMode = 0x01
+	// HPKEModeAuth also authenticates the sender's KEM key. Only the DHKEMs support it.
+	HPKEModeAuth HPKEMode = 0x02
+	// HPKEModeAuthPSK combines HPKEModeAuth and HPKEModePSK.
+	HPKEModeAuthPSK HPKEMode = 0x03
+)
+
+// HPKESuite is an HPKE cipher suite.
+type HPKESuite struct {
+	KEM  HPKEKEM
+	KDF  HPKEKDF
+	AEAD HPKEAEAD
+}
+
+// HPKEOptions supplies the inputs of the PSK and auth modes. A nil *HPKEOptions, or an empty one, selects the base mode.
+type HPKEOptions struct {
+	// PSK and PSKID select the PSK or auth-PSK mode. They must be given together.
+	PSK   []byte
+	PSKID []byte
+	// SenderPrivateKey selects the auth or auth-PSK mode in SetupHPKESender and HPKESeal.
+	SenderPrivateKey kem.PrivateKey
+	// SenderPublicKey selects the auth or auth-PSK mode in SetupHPKERecipient and HPKEOpen.
+	SenderPublicKey kem.PublicKey
+	// Rand is the source of the encapsulation randomness in SetupHPKESender and HPKESeal, for example to reproduce test
+	// vectors. If nil, crypto/rand is used.
+	Rand io.Reader
+}
+
+// hpkeVersionLabel is the RFC 9180 version label prefixed to every labeled KDF input.
+var hpkeVersionLabel = []byte("HPKE-v1")
+
+// hpkeNonceSize is Nn, the nonce size in bytes of every AEAD but the export-only one.
+const hpkeNonceSize = 12
+
+// String returns the name of the KEM, for example "ML-KEM-768".
+func (kemID HPKEKEM) String() string {
+	switch kemID {
+	case HPKEKEMP256HKDFSHA256:
+		return "DHKEM(P-256, HKDF-SHA256)"
+	case HPKEKEMP384HKDFSHA384:
+		return "DHKEM(P-384, HKDF-SHA384)"
+	case HPKEKEMP521HKDFSHA512:
+		return "DHKEM(P-521, HKDF-SHA512)"
+	case HPKEKEMX25519HKDFSHA256:
+		return "DHKEM(X25519, HKDF-SHA256)"
+	case HPKEKEMX448HKDFSHA512:
+		return "DHKEM(X448, HKDF-SHA512)"
+	case HPKEKEMMLKEM512:
+		return MLKEM512.String()
+	case HPKEKEMMLKEM768:
+		return MLKEM768.String()
+	case HPKEKEMMLKEM1024:
+		return MLKEM1024.String()
+	case HPKEKEMXWing:
+		return "X-Wing"
+	default:
+		return fmt.Sprintf("HPKEKEM(0x%04x)", uint16(kemID))
+	}
+}
+
+// mlkemParameterSet returns the ML-KEM parameter set of an ML-KEM KEM identifier.
+func (kemID HPKEKEM) mlkemParameterSet() (MLKEMParameterSet, bool) {
+	switch kemID {
+	case HPKEKEMMLKEM512:
+		return MLKEM512, true
+	case HPKEKEMMLKEM768:
+		return MLKEM768, true
+	case HPKEKEMMLKEM1024:
+		return MLKEM1024, true
+	default:
+		return 0, false
+	}
+}
+
+// scheme returns the KEM scheme: CIRCL's RFC 9180 DHKEMs, or the package's ML-KEM and X-Wing schemes, so that their
+// keys are used directly.
+func (kemID HPKEKEM) scheme() (kem.Scheme, error) {
+	if parameterSet, ok := kemID.mlkemParameterSet(); ok {
+		return parameterSet.scheme()
+	}
+	switch kemID {
+	case HPKEKEMP256HKDFSHA256, HPKEKEMP384HKDFSHA384, HPKEKEMP521HKDFSHA512, HPKEKEMX25519HKDFSHA256, HPKEKEMX448HKDFSHA512:
+		return hpke.KEM(kemID).Scheme(), nil
+	case HPKEKEMXWing:
+		return xwing.Scheme(), nil
+	default:
+		return nil, fmt.Errorf("unsupported HPKE KEM: %v", kemID)
+	}
+}
+
+// String returns the name of the KDF, for example "HKDF-SHA256".
+func (kdfID HPKEKDF) String() string {
+	switch kdfID {
+	case HPKEKDFHKDFSHA256:
+		return "HKDF-SHA256"
+	case HPKEKDFHKDFSHA384:
+		return "HKDF-SHA384"
+	case HPKEKDFHKDFSHA512:
+		return "HKDF-SHA512"
+	case HPKEKDFSHAKE128:
+		return "SHAKE128"
+	case HPKEKDFSHAKE256:
+		return "SHAKE256"
+	default:
+		return fmt.Sprintf("HPKEKDF(0x%04x)", uint16(kdfID))
+	}
+}
+
+// hash returns the hash function of the HKDF.
+func (kdfID HPKEKDF) hash() (func() hash.Hash, error) {
+	switch kdfID {
+	case HPKEKDFHKDFSHA256:
+		return sha256.New, nil
+	case HPKEKDFHKDFSHA384:
+		return sha512.New384, nil
+	case HPKEKDFHKDFSHA512:
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("%w: HPKE KDF %v", ErrUnknownAlgorithm, kdfID)
+	}
+}
+
+// shake returns the SHAKE of a one-stage KDF, or nil for an HKDF.
+func (kdfID HPKEKDF) shake() func() *sha3.SHAKE {
+	switch kdfID {
+	case HPKEKDFSHAKE128:
+		return sha3.NewSHAKE128
+	case HPKEKDFSHAKE256:
+		return sha3.NewSHAKE256
+	default:
+		return nil
+	}
+}
+
+// size returns Nh, the size in bytes of the exporter secret: the HKDF hash size, or twice the SHAKE security level.
+func (kdfID HPKEKDF) size() (int, error) {
+	switch kdfID {
+	case HPKEKDFSHAKE128:
+		return 32, nil
+	case HPKEKDFSHAKE256:
+		return 64, nil
+	}
+	newHash, err := kdfID.hash()
+	if err != nil {
+		return 0, err
+	}
+	return newHash().Size(), nil
+}
+
+// String returns the name of the AEAD, for example "AES-128-GCM".
+func (aeadID HPKEAEAD) String() string {
+	switch aeadID {
+	case HPKEAEADAES128GCM:
+		return "AES-128-GCM"
+	case HPKEAEADAES256GCM:
+		return "AES-256-GCM"
+	case HPKEAEADChaCha20Poly1305:
+		return "ChaCha20-Poly1305"
+	case HPKEAEADExportOnly:
+		return "Export-only"
+	default:
+		return fmt.Sprintf("HPKEAEAD(0x%04x)", uint16(aeadID))
+	}
+}
+
+// keySize returns Nk, the key size in bytes, or 0 for the export-only AEAD.
+func (aeadID HPKEAEAD) keySize() (int, error) {
+	switch aeadID {
+	case HPKEAEADAES128GCM:
+		return 16, nil
+	case HPKEAEADAES256GCM, HPKEAEADChaCha20Poly1305:
+		return 32, nil
+	case HPKEAEADExportOnly:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("unsupported HPKE AEAD: %v", aeadID)
+	}
+}
+
+// newAEAD returns the AEAD keyed with key.
+func (aeadID HPKEAEAD) newAEAD(key []byte) (cipher.AEAD, error) {
+	switch aeadID {
+	case HPKEAEADAES128GCM, HPKEAEADAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case HPKEAEADChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	default:
+		return nil, fmt.Errorf("unsupported HPKE AEAD: %v", aeadID)
+	}
+}
+
+// String returns the name of the mode, for example "auth_psk".
+func (mode HPKEMode) String() string {
+	switch mode {
+	case HPKEModeBase:
+		return "base"
+	case HPKEModePSK:
+		return "psk"
+	case HPKEModeAuth:
+		return "auth"
+	case HPKEModeAuthPSK:
+		return "auth_psk"
+	default:
+		return fmt.Sprintf("HPKEMode(%d)", uint8(mode))
+	}
+}
+
+// String returns the suite as its three algorithm names, for example "ML-KEM-768/HKDF-SHA256/AES-128-GCM".
+func (suite HPKESuite) String() string {
+	return fmt.Sprintf("%v/%v/%v", suite.KEM, suite.KDF, suite.AEAD)
+}
+
+// suiteID returns the RFC 9180 suite_id "HPKE" || kem_id || kdf_id || aead_id.
+func (suite HPKESuite) suiteID() []byte {
+	suiteID := append([]byte(nil), "HPKE"...)
+	suiteID = binary.BigEndian.AppendUint16(suiteID, uint16(suite.KEM))
+	suiteID = binary.BigEndian.AppendUint16(suiteID, uint16(suite.KDF))
+	return binary.BigEndian.AppendUint16(suiteID, uint16(suite.AEAD))
+}
+
+// GenerateHPKEKeyPair generates a new recipient or sender key pair for the KEM. The keys of the ML-KEM KEMs are ML-KEM
+// keys, serialized with MarshalPublicKey and MarshalPrivateKey like every other KEM key.
+func GenerateHPKEKeyPair(kemID HPKEKEM) (publicKey kem.PublicKey, privateKey kem.PrivateKey, keyGenerationError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			publicKey, privateKey, keyGenerationError = nil, nil, newPanicError("GenerateHPKEKeyPair", recoveredPanic)
+		}
+	}()
+	scheme, err := kemID.scheme()
+	if err != nil {
+		return nil, nil, err
+	}
+	return scheme.GenerateKeyPair()
+}
+
+// DeriveHPKEKeyPair derives a key pair from input keying material, with the RFC 9180 DeriveKeyPair of the DHKEMs. For
+// the ML-KEM and X-Wing KEMs, the ikm is expanded into the seed (d || z for ML-KEM) with the SHAKE256 LabeledDerive of
+// draft-ietf-hpke-pq, as its test vectors require.
+func DeriveHPKEKeyPair(kemID HPKEKEM, ikm []byte) (publicKey kem.PublicKey, privateKey kem.PrivateKey, keyGenerationError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			publicKey, privateKey, keyGenerationError = nil, nil, newPanicError("DeriveHPKEKeyPair", recoveredPanic)
+		}
+	}()
+	scheme, err := kemID.scheme()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(ikm) != scheme.SeedSize() {
+		return nil, nil, newSizeError(ErrInvalidSeedSize, kemID.String()+" ikm", len(ikm), scheme.SeedSize())
+	}
+	seed := ikm
+	if _, ok := kemID.mlkemParameterSet(); ok || kemID == HPKEKEMXWing {
+		seed = labeledDerive(sha3.NewSHAKE256, binary.BigEndian.AppendUint16([]byte("KEM"), uint16(kemID)), ikm, "DeriveKeyPair", nil, scheme.SeedSize())
+	}
+	publicKey, privateKey = scheme.DeriveKeyPair(seed)
+	return publicKey, privateKey, nil
+}
+
+// labeledDerive is the SHAKE LabeledDerive of draft-ietf-hpke-pq:
+// SHAKE(ikm || "HPKE-v1" || suite_id || len(label) || label || length || context, length). The KEMs use SHAKE256 with
+// the suite_id "KEM" || kem_id, and the one-stage KDFs their own SHAKE with the full suite_id.
+func labeledDerive(newSHAKE func() *sha3.SHAKE, suiteID []byte, ikm []byte, label string, context []byte, length int) []byte {
+	shake := newSHAKE()
+	shake.Write(ikm)
+	shake.Write(hpkeVersionLabel)
+	shake.Write(suiteID)
+	shake.Write(binary.BigEndian.AppendUint16(nil, uint16(len(label))))
+	shake.Write([]byte(label))
+	shake.Write(binary.BigEndian.AppendUint16(nil, uint16(length)))
+	shake.Write(context)
+	output := make([]byte, length)
+	shake.Read(output)
+	return output
+}
+
+// UnmarshalHPKEPublicKey deserializes bytes into a public key of the KEM. ML-KEM keys are validated like UnmarshalPublicKey does.
+func UnmarshalHPKEPublicKey(kemID HPKEKEM, data []byte) (publicKey kem.PublicKey, unmarshalError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			publicKey, unmarshalError = nil, newPanicError("UnmarshalHPKEPublicKey", recoveredPanic)
+		}
+	}()
+	if parameterSet, ok := kemID.mlkemParameterSet(); ok {
+		return UnmarshalPublicKey(parameterSet, data)
+	}
+	scheme, err := kemID.scheme()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != scheme.PublicKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, kemID.String()+" public key", len(data), scheme.PublicKeySize())
+	}
+	return scheme.UnmarshalBinaryPublicKey(data)
+}
+
+// UnmarshalHPKEPrivateKey deserializes bytes into a private key of the KEM. ML-KEM keys are validated like UnmarshalPrivateKey does.
+func UnmarshalHPKEPrivateKey(kemID HPKEKEM, data []byte) (privateKey kem.PrivateKey, unmarshalError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			privateKey, unmarshalError = nil, newPanicError("UnmarshalHPKEPrivateKey", recoveredPanic)
+		}
+	}()
+	if parameterSet, ok := kemID.mlkemParameterSet(); ok {
+		return UnmarshalPrivateKey(parameterSet, data)
+	}
+	scheme, err := kemID.scheme()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != scheme.PrivateKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, kemID.String()+" private key", len(data), scheme.PrivateKeySize())
+	}
+	return scheme.UnmarshalBinaryPrivateKey(data)
+}
+
+// HPKEContext is an RFC 9180 encryption context. A sender context seals and a recipient context opens, each message
+// with the next nonce of the sequence; both can export secrets. It is safe for concurrent use.
+type HPKEContext struct {
+	suite          HPKESuite
+	mode           HPKEMode
+	sender         bool
+	aead           cipher.AEAD
+	baseNonce      []byte
+	exporterSecret []byte
+	mutex          sync.Mutex
+	sequence       uint64
+}
+
+// SetupHPKESender encapsulates a shared secret to the recipient's public key and returns the encapsulated key, to be sent
+// to the recipient, and the sender context. The mode is selected by options.
+func SetupHPKESender(suite HPKESuite, recipientPublicKey kem.PublicKey, info []byte, options *HPKEOptions) (encapsulatedKey []byte, context *HPKEContext, setupError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			encapsulatedKey, context, setupError = nil, nil, newPanicError("SetupHPKESender", recoveredPanic)
+		}
+	}()
+	if options == nil {
+		options = &HPKEOptions{}
+	}
+	if options.SenderPublicKey != nil {
+		return nil, nil, fmt.Errorf("%w: HPKE sender setup takes SenderPrivateKey, not SenderPublicKey", ErrInvalidOptions)
+	}
+	scheme, mode, err := suite.checkSetup(recipientPublicKey, options.SenderPrivateKey != nil, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	randomSource := options.Rand
+	if randomSource == nil {
+		randomSource = rand.Reader
+	}
+	seed := make([]byte, scheme.EncapsulationSeedSize())
+	if _, err := io.ReadFull(randomSource, seed); err != nil {
+		return nil, nil, fmt.Errorf("failed to read HPKE encapsulation randomness: %w", err)
+	}
+
+	var sharedSecret []byte
+	if options.SenderPrivateKey != nil {
+		if options.SenderPrivateKey.Scheme() != scheme {
+			return nil, nil, fmt.Errorf("HPKE sender private key is not a %v key", suite.KEM)
+		}
+		encapsulatedKey, sharedSecret, err = scheme.(kem.AuthScheme).AuthEncapsulateDeterministically(recipientPublicKey, options.SenderPrivateKey, seed)
+	} else {
+		encapsulatedKey, sharedSecret, err = scheme.EncapsulateDeterministically(recipientPublicKey, seed)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("HPKE encapsulation failed: %w", err)
+	}
+	context, err = suite.keySchedule(mode, true, sharedSecret, info, options.PSK, options.PSKID)
+	if err != nil {
+		return nil, nil, err
+	}
+	Logger().Debug("SetupHPKESender", "suite", suite.String(), "mode", mode.String(), "encapsulated_key_size", len(encapsulatedKey))
+	return encapsulatedKey, context, nil
+}
+
+// SetupHPKERecipient decapsulates the shared secret from the encapsulated key with the recipient's private key and returns
+// the recipient context. The mode is selected by options and must match the sender's.
+func SetupHPKERecipient(suite HPKESuite, recipientPrivateKey kem.PrivateKey, encapsulatedKey []byte, info []byte, options *HPKEOptions) (context *HPKEContext, setupError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			context, setupError = nil, newPanicError("SetupHPKERecipient", recoveredPanic)
+		}
+	}()
+	if options == nil {
+		options = &HPKEOptions{}
+	}
+	if options.SenderPrivateKey != nil {
+		return nil, fmt.Errorf("%w: HPKE recipient setup takes SenderPublicKey, not SenderPrivateKey", ErrInvalidOptions)
+	}
+	if recipientPrivateKey == nil {
+		return nil, fmt.Errorf("%w: HPKE recipient private key is nil", ErrInvalidKey)
+	}
+	scheme, mode, err := suite.checkSetup(recipientPrivateKey.Public(), options.SenderPublicKey != nil, options)
+	if err != nil {
+		return nil, err
+	}
+	if len(encapsulatedKey) != scheme.CiphertextSize() {
+		return nil, newSizeError(ErrInvalidCiphertext, suite.KEM.String()+" encapsulated key", len(encapsulatedKey), scheme.CiphertextSize())
+	}
+
+	var sharedSecret []byte
+	if options.SenderPublicKey != nil {
+		if options.SenderPublicKey.Scheme() != scheme {
+			return nil, fmt.Errorf("HPKE sender public key is not a %v key", suite.KEM)
+		}
+		sharedSecret, err = scheme.(kem.AuthScheme).AuthDecapsulate(recipientPrivateKey, encapsulatedKey, options.SenderPublicKey)
+	} else {
+		sharedSecret, err = scheme.Decapsulate(recipientPrivateKey, encapsulatedKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("HPKE decapsulation failed: %w", err)
+	}
+	context, err = suite.keySchedule(mode, false, sharedSecret, info, options.PSK, options.PSKID)
+	if err != nil {
+		return nil, err
+	}
+	Logger().Debug("SetupHPKERecipient", "suite", suite.String(), "mode", mode.String())
+	return context, nil
+}
+
+// HPKESeal encrypts a single message to the recipient's public key, returning the encapsulated key and the ciphertext.
+func HPKESeal(suite HPKESuite, recipientPublicKey kem.PublicKey, info []byte, additionalData []byte, plaintext []byte, options *HPKEOptions) (encapsulatedKey []byte, ciphertext []byte, err error) {
+	encapsulatedKey, context, err := SetupHPKESender(suite, recipientPublicKey, info, options)
+	if err != nil {
+		return nil, nil, err
+	}
+	ciphertext, err = context.Seal(additionalData, plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encapsulatedKey, ciphertext, nil
+}
+
+// HPKEOpen decrypts a single message sealed with HPKESeal. A ciphertext that fails authentication returns ErrDecryptionFailed.
+func HPKEOpen(suite HPKESuite, recipientPrivateKey kem.PrivateKey, encapsulatedKey []byte, info []byte, additionalData []byte, ciphertext []byte, options *HPKEOptions) ([]byte, error) {
+	context, err := SetupHPKERecipient(suite, recipientPrivateKey, encapsulatedKey, info, options)
+	if err != nil {
+		return nil, err
+	}
+	return context.Open(additionalData, ciphertext)
+}
+
+// checkSetup checks the suite, the recipient key and the mode inputs, and returns the KEM scheme and the mode.
+func (suite HPKESuite) checkSetup(recipientPublicKey kem.PublicKey, authenticated bool, options *HPKEOptions) (kem.Scheme, HPKEMode, error) {
+	scheme, err := suite.KEM.scheme()
+	if err != nil {
+		return nil, 0, err
+	}
+	if _, err := suite.KDF.size(); err != nil {
+		return nil, 0, err
+	}
+	if _, err := suite.AEAD.keySize(); err != nil {
+		return nil, 0, err
+	}
+	if recipientPublicKey == nil || recipientPublicKey.Scheme() != scheme {
+		return nil, 0, fmt.Errorf("HPKE recipient key is not a %v key", suite.KEM)
+	}
+	if (len(options.PSK) == 0) != (len(options.PSKID) == 0) {
+		return nil, 0, fmt.Errorf("%w: HPKE PSK and PSK ID must be given together", ErrInvalidOptions)
+	}
+	if suite.KDF.shake() != nil && (len(options.PSK) > math.MaxUint16 || len(options.PSKID) > math.MaxUint16) {
+		return nil, 0, fmt.Errorf("%w: HPKE PSK and PSK ID of a one-stage KDF are at most %d bytes", ErrInvalidOptions, math.MaxUint16)
+	}
+	mode := HPKEModeBase
+	if len(options.PSK) != 0 {
+		mode = HPKEModePSK
+	}
+	if authenticated {
+		if _, ok := scheme.(kem.AuthScheme); !ok {
+			return nil, 0, fmt.Errorf("HPKE auth modes are not supported by %v", suite.KEM)
+		}
+		mode |= HPKEModeAuth
+	}
+	return scheme, mode, nil
+}
+
+// keySchedule is the RFC 9180 KeySchedule: it derives the AEAD key, base nonce and exporter secret of the context.
+func (suite HPKESuite) keySchedule(mode HPKEMode, sender bool, sharedSecret []byte, info []byte, psk []byte, pskID []byte) (*HPKEContext, error) {
+	if suite.KDF.shake() != nil {
+		return suite.oneStageKeySchedule(mode, sender, sharedSecret, info, psk, pskID)
+	}
+	newHash, _ := suite.KDF.hash()
+	keySize, _ := suite.AEAD.keySize()
+	pskIDHash, err := suite.labeledExtract(nil, "psk_id_hash", pskID)
+	if err != nil {
+		return nil, err
+	}
+	infoHash, err := suite.labeledExtract(nil, "info_hash", info)
+	if err != nil {
+		return nil, err
+	}
+	keyScheduleContext := append(append([]byte{byte(mode)}, pskIDHash...), infoHash...)
+	secret, err := suite.labeledExtract(sharedSecret, "secret", psk)
+	if err != nil {
+		return nil, err
+	}
+
+	context := &HPKEContext{suite: suite, mode: mode, sender: sender}
+	if suite.AEAD != HPKEAEADExportOnly {
+		key, err := suite.labeledExpand(secret, "key", keyScheduleContext, keySize)
+		if err != nil {
+			return nil, err
+		}
+		if context.aead, err = suite.AEAD.newAEAD(key); err != nil {
+			return nil, err
+		}
+		if context.baseNonce, err = suite.labeledExpand(secret, "base_nonce", keyScheduleContext, context.aead.NonceSize()); err != nil {
+			return nil, err
+		}
+	}
+	if context.exporterSecret, err = suite.labeledExpand(secret, "exp", keyScheduleContext, newHash().Size()); err != nil {
+		return nil, err
+	}
+	return context, nil
+}
+
+// oneStageKeySchedule is the key schedule of the one-stage KDFs of draft-ietf-hpke-pq: a single LabeledDerive of
+// len(psk) || psk || len(shared_secret) || shared_secret, with the context mode || len(psk_id) || psk_id || len(info) ||
+// info, is split into the AEAD key, the base nonce and the exporter secret.
+func (suite HPKESuite) oneStageKeySchedule(mode HPKEMode, sender bool, sharedSecret []byte, info []byte, psk []byte, pskID []byte) (*HPKEContext, error) {
+	if len(info) > math.MaxUint16 {
+		return nil, fmt.Errorf("%w: HPKE info of a one-stage KDF is at most %d bytes", ErrInvalidOptions, math.MaxUint16)
+	}
+	exporterSecretSize, _ := suite.KDF.size()
+	keySize, _ := suite.AEAD.keySize()
+	nonceSize := 0
+	if suite.AEAD != HPKEAEADExportOnly {
+		nonceSize = hpkeNonceSize
+	}
+	secrets := binary.BigEndian.AppendUint16(nil, uint16(len(psk)))
+	secrets = append(secrets, psk...)
+	secrets = binary.BigEndian.AppendUint16(secrets, uint16(len(sharedSecret)))
+	secrets = append(secrets, sharedSecret...)
+	keyScheduleContext := binary.BigEndian.AppendUint16([]byte{byte(mode)}, uint16(len(pskID)))
+	keyScheduleContext = append(keyScheduleContext, pskID...)
+	keyScheduleContext = binary.BigEndian.AppendUint16(keyScheduleContext, uint16(len(info)))
+	keyScheduleContext = append(keyScheduleContext, info...)
+	secret := labeledDerive(suite.KDF.shake(), suite.suiteID(), secrets, "secret", keyScheduleContext, keySize+nonceSize+exporterSecretSize)
+
+	context := &HPKEContext{suite: suite, mode: mode, sender: sender, exporterSecret: secret[keySize+nonceSize:]}
+	if suite.AEAD != HPKEAEADExportOnly {
+		var err error
+		if context.aead, err = suite.AEAD.newAEAD(secret[:keySize]); err != nil {
+			return nil, err
+		}
+		context.baseNonce = secret[keySize : keySize+nonceSize]
+	}
+	return context, nil
+}
+
+// labeledExtract is the RFC 9180 LabeledExtract: Extract(salt, "HPKE-v1" || suite_id || label || ikm).
+func (suite HPKESuite) labeledExtract(salt []byte, label string, ikm []byte) ([]byte, error) {
+	newHash, err := suite.KDF.hash()
+	if err != nil {
+		return nil, err
+	}
+	labeledIKM := append(append(append(append([]byte(nil), hpkeVersionLabel...), suite.suiteID()...), label...), ikm...)
+	return hkdf.Extract(newHash, labeledIKM, salt)
+}
+
+// labeledExpand is the RFC 9180 LabeledExpand: Expand(prk, I2OSP(L, 2) || "HPKE-v1" || suite_id || label || info, L).
+func (suite HPKESuite) labeledExpand(prk []byte, label string, info []byte, length int) ([]byte, error) {
+	newHash, err := suite.KDF.hash()
+	if err != nil {
+		return nil, err
+	}
+	if length > math.MaxUint16 {
+		return nil, fmt.Errorf("HPKE expand length %d exceeds %d", length, math.MaxUint16)
+	}
+	labeledInfo := binary.BigEndian.AppendUint16(nil, uint16(length))
+	labeledInfo = append(append(append(append(labeledInfo, hpkeVersionLabel...), suite.suiteID()...), label...), info...)
+	return hkdf.Expand(newHash, prk, string(labeledInfo), length)
+}
+
+// Suite returns the cipher suite of the context.
+func (context *HPKEContext) Suite() HPKESuite { return context.suite }
+
+// Mode returns the mode the context was set up in.
+func (context *HPKEContext) Mode() HPKEMode { return context.mode }
+
+// Seal encrypts the next message of a sender context.
+func (context *HPKEContext) Seal(additionalData []byte, plaintext []byte) ([]byte, error) {
+	if !context.sender {
+		return nil, errors.New("HPKE recipient context cannot seal")
+	}
+	if context.aead == nil {
+		return nil, errors.New("HPKE export-only context cannot seal")
+	}
+	context.mutex.Lock()
+	defer context.mutex.Unlock()
+	nonce, err := context.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := context.aead.Seal(nil, nonce, plaintext, additionalData)
+	context.sequence++
+	return ciphertext, nil
+}
+
+// Open decrypts the next message of a recipient context. A ciphertext that fails authentication returns
+// ErrDecryptionFailed and does not advance the sequence, so the next message can still be opened.
+func (context *HPKEContext) Open(additionalData []byte, ciphertext []byte) ([]byte, error) {
+	if context.sender {
+		return nil, errors.New("HPKE sender context cannot open")
+	}
+	if context.aead == nil {
+		return nil, errors.New("HPKE export-only context cannot open")
+	}
+	context.mutex.Lock()
+	defer context.mutex.Unlock()
+	nonce, err := context.nextNonce()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := context.aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	context.sequence++
+	return plaintext, nil
+}
+
+// Export derives a secret of length bytes bound to exporterContext, the RFC 9180 secret export. length is at most 255
+// times the KDF hash size.
+func (context *HPKEContext) Export(exporterContext []byte, length int) ([]byte, error) {
+	if newSHAKE := context.suite.KDF.shake(); newSHAKE != nil {
+		if length < 0 || length > math.MaxUint16 {
+			return nil, fmt.Errorf("HPKE export length %d is out of range", length)
+		}
+		return labeledDerive(newSHAKE, context.suite.suiteID(), context.exporterSecret, "sec", exporterContext, length), nil
+	}
+	newHash, _ := context.suite.KDF.hash()
+	if length < 0 || length > 255*newHash().Size() {
+		return nil, fmt.Errorf("HPKE export length %d is out of range", length)
+	}
+	return context.suite.labeledExpand(context.exporterSecret, "sec", exporterContext, length)
+}
+
+// nextNonce returns the base nonce XORed with the sequence number. The caller holds the mutex.
+func (context *HPKEContext) nextNonce() ([]byte, error) {
+	if context.sequence == math.MaxUint64 {
+		return nil, errors.New("HPKE message limit reached")
+	}
+	nonce := append([]byte(nil), context.baseNonce...)
+	var sequence [8]byte
+	binary.BigEndian.PutUint64(sequence[:], context.sequence)
+	for i := range sequence {
+		nonce[len(nonce)-8+i] ^= sequence[i]
+	}
+	return nonce, nil
+}
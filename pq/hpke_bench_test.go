@@ -0,0 +1,43 @@
+package pq
+
+import "testing"
+
+func BenchmarkHPKESeal(b *testing.B) {
+	message := make([]byte, 1024)
+	for _, kemID := range []HPKEKEM{HPKEKEMX25519HKDFSHA256, HPKEKEMMLKEM768, HPKEKEMMLKEM1024, HPKEKEMXWing} {
+		suite := HPKESuite{KEM: kemID, KDF: HPKEKDFHKDFSHA256, AEAD: HPKEAEADAES128GCM}
+		b.Run(suite.String(), func(b *testing.B) {
+			publicKey, _, err := GenerateHPKEKeyPair(kemID)
+			if err != nil {
+				b.Fatalf("failed to generate HPKE key pair: %v", err)
+			}
+			for b.Loop() {
+				if _, _, err := HPKESeal(suite, publicKey, nil, nil, message, nil); err != nil {
+					b.Fatalf("failed to seal: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkHPKEOpen(b *testing.B) {
+	message := make([]byte, 1024)
+	for _, kemID := range []HPKEKEM{HPKEKEMX25519HKDFSHA256, HPKEKEMMLKEM768, HPKEKEMMLKEM1024, HPKEKEMXWing} {
+		suite := HPKESuite{KEM: kemID, KDF: HPKEKDFHKDFSHA256, AEAD: HPKEAEADAES128GCM}
+		b.Run(suite.String(), func(b *testing.B) {
+			publicKey, privateKey, err := GenerateHPKEKeyPair(kemID)
+			if err != nil {
+				b.Fatalf("failed to generate HPKE key pair: %v", err)
+			}
+			encapsulatedKey, ciphertext, err := HPKESeal(suite, publicKey, nil, nil, message, nil)
+			if err != nil {
+				b.Fatalf("failed to seal: %v", err)
+			}
+			for b.Loop() {
+				if _, err := HPKEOpen(suite, privateKey, encapsulatedKey, nil, nil, ciphertext, nil); err != nil {
+					b.Fatalf("failed to open: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package pq
+
+import "testing"
+
+func FuzzHPKEOpen(f *testing.F) {
+	suite := HPKESuite{KEM: HPKEKEMMLKEM768, KDF: HPKEKDFHKDFSHA256, AEAD: HPKEAEADChaCha20Poly1305}
+	publicKey, privateKey, _ := GenerateHPKEKeyPair(suite.KEM)
+	encapsulatedKey, ciphertext, _ := HPKESeal(suite, publicKey, nil, nil, []byte("seed message"), nil)
+	f.Add(encapsulatedKey, ciphertext)
+	f.Add([]byte{}, []byte{})
+	f.Fuzz(func(t *testing.T, encapsulatedKey []byte, ciphertext []byte) {
+		plaintext, err := HPKEOpen(suite, privateKey, encapsulatedKey, nil, nil, ciphertext, nil)
+		if err == nil && string(plaintext) != "seed message" {
+			t.Errorf("opened a forged ciphertext: %x", plaintext)
+		}
+	})
+}
+
+func FuzzHPKESealOpen(f *testing.F) {
+	suite := HPKESuite{KEM: HPKEKEMXWing, KDF: HPKEKDFHKDFSHA512, AEAD: HPKEAEADAES256GCM}
+	publicKey, privateKey, _ := GenerateHPKEKeyPair(suite.KEM)
+	f.Add([]byte("info"), []byte("aad"), []byte("message"))
+	f.Fuzz(func(t *testing.T, info []byte, additionalData []byte, message []byte) {
+		encapsulatedKey, ciphertext, err := HPKESeal(suite, publicKey, info, additionalData, message, nil)
+		if err != nil {
+			t.Fatalf("failed to seal: %v", err)
+		}
+		plaintext, err := HPKEOpen(suite, privateKey, encapsulatedKey, info, additionalData, ciphertext, nil)
+		if err != nil || string(plaintext) != string(message) {
+			t.Errorf("round trip failed: %v", err)
+		}
+	})
+}
@@ -0,0 +1,370 @@
+package pq
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"testing"
+
+	"github.com/cloudflare/circl/hpke"
+	"github.com/stretchr/testify/require"
+)
+
+// For TestHPKERFC9180Vectors: a test vector of RFC 9180, with the fields this package can check.
+type hpkeTestVector struct {
+	Mode           HPKEMode `json:"mode"`
+	KEMID          HPKEKEM  `json:"kem_id"`
+	KDFID          HPKEKDF  `json:"kdf_id"`
+	AEADID         HPKEAEAD `json:"aead_id"`
+	Info           hexBytes `json:"info"`
+	IKMR           hexBytes `json:"ikmR"`
+	IKMS           hexBytes `json:"ikmS"`
+	IKME           hexBytes `json:"ikmE"`
+	SKRm           hexBytes `json:"skRm"`
+	SKSm           hexBytes `json:"skSm"`
+	PKRm           hexBytes `json:"pkRm"`
+	PKSm           hexBytes `json:"pkSm"`
+	PSK            hexBytes `json:"psk"`
+	PSKID          hexBytes `json:"psk_id"`
+	Enc            hexBytes `json:"enc"`
+	BaseNonce      hexBytes `json:"base_nonce"`
+	ExporterSecret hexBytes `json:"exporter_secret"`
+	Encryptions    []struct {
+		Sequence   uint64   `json:"seq"`
+		AAD        hexBytes `json:"aad"`
+		Plaintext  hexBytes `json:"pt"`
+		Ciphertext hexBytes `json:"ct"`
+	} `json:"encryptions"`
+	Exports []struct {
+		ExporterContext hexBytes `json:"exporter_context"`
+		Length          int      `json:"L"`
+		ExportedValue   hexBytes `json:"exported_value"`
+	} `json:"exports"`
+}
+
+// hexBytes is a hex-encoded JSON string.
+type hexBytes []byte
+
+func (value *hexBytes) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(text)
+	*value = decoded
+	return err
+}
+
+func TestHPKERFC9180Vectors(t *testing.T) {
+	data, err := os.ReadFile("testdata/rfc9180/test-vectors-subset.json")
+	require.NoError(t, err, "failed to read RFC 9180 test vectors")
+	var file struct {
+		Vectors []hpkeTestVector `json:"vectors"`
+	}
+	require.NoError(t, json.Unmarshal(data, &file), "failed to parse RFC 9180 test vectors")
+	require.Len(t, file.Vectors, 128)
+
+	for i, vector := range file.Vectors {
+		suite := HPKESuite{KEM: vector.KEMID, KDF: vector.KDFID, AEAD: vector.AEADID}
+		t.Run(fmt.Sprintf("%d/%v/%v", i, vector.Mode, suite), func(t *testing.T) {
+			recipientPublicKey, recipientPrivateKey, err := DeriveHPKEKeyPair(vector.KEMID, vector.IKMR)
+			require.NoError(t, err, "failed to derive recipient key pair")
+			publicKeyBytes, _ := MarshalPublicKey(recipientPublicKey)
+			require.Equal(t, []byte(vector.PKRm), publicKeyBytes, "recipient public key mismatch")
+			privateKeyBytes, _ := MarshalPrivateKey(recipientPrivateKey)
+			require.Equal(t, []byte(vector.SKRm), privateKeyBytes, "recipient private key mismatch")
+
+			senderOptions := &HPKEOptions{PSK: vector.PSK, PSKID: vector.PSKID, Rand: bytes.NewReader(vector.IKME)}
+			recipientOptions := &HPKEOptions{PSK: vector.PSK, PSKID: vector.PSKID}
+			if vector.Mode == HPKEModeAuth || vector.Mode == HPKEModeAuthPSK {
+				senderPublicKey, senderPrivateKey, err := DeriveHPKEKeyPair(vector.KEMID, vector.IKMS)
+				require.NoError(t, err, "failed to derive sender key pair")
+				senderPublicKeyBytes, _ := MarshalPublicKey(senderPublicKey)
+				require.Equal(t, []byte(vector.PKSm), senderPublicKeyBytes, "sender public key mismatch")
+				senderOptions.SenderPrivateKey = senderPrivateKey
+				recipientOptions.SenderPublicKey = senderPublicKey
+			}
+
+			encapsulatedKey, sender, err := SetupHPKESender(suite, recipientPublicKey, vector.Info, senderOptions)
+			require.NoError(t, err, "failed to set up sender")
+			require.Equal(t, vector.Mode, sender.Mode())
+			require.Equal(t, []byte(vector.Enc), encapsulatedKey, "encapsulated key mismatch")
+			recipient, err := SetupHPKERecipient(suite, recipientPrivateKey, encapsulatedKey, vector.Info, recipientOptions)
+			require.NoError(t, err, "failed to set up recipient")
+
+			for _, context := range []*HPKEContext{sender, recipient} {
+				require.Equal(t, []byte(vector.ExporterSecret), context.exporterSecret, "exporter secret mismatch")
+				if vector.AEADID != HPKEAEADExportOnly {
+					require.Equal(t, []byte(vector.BaseNonce), context.baseNonce, "base nonce mismatch")
+				}
+			}
+			for _, encryption := range vector.Encryptions {
+				sender.sequence = encryption.Sequence
+				ciphertext, err := sender.Seal(encryption.AAD, encryption.Plaintext)
+				require.NoError(t, err, "failed to seal message %d", encryption.Sequence)
+				require.Equal(t, []byte(encryption.Ciphertext), ciphertext, "ciphertext %d mismatch", encryption.Sequence)
+				recipient.sequence = encryption.Sequence
+				plaintext, err := recipient.Open(encryption.AAD, encryption.Ciphertext)
+				require.NoError(t, err, "failed to open message %d", encryption.Sequence)
+				require.Equal(t, []byte(encryption.Plaintext), plaintext, "plaintext %d mismatch", encryption.Sequence)
+			}
+			for _, export := range vector.Exports {
+				for _, context := range []*HPKEContext{sender, recipient} {
+					exported, err := context.Export(export.ExporterContext, export.Length)
+					require.NoError(t, err, "failed to export")
+					require.Equal(t, []byte(export.ExportedValue), exported, "exported value mismatch")
+				}
+			}
+		})
+	}
+}
+
+// For TestHPKEPostQuantumVectors: the draft-ietf-hpke-pq vectors of the ML-KEM and X-Wing KEMs and the SHAKE KDFs. The recipient key pair
+// is derived from ikmR, skRm is the seed it expands to, and ikmE is the encapsulation randomness.
+func TestHPKEPostQuantumVectors(t *testing.T) {
+	data, err := os.ReadFile("testdata/hpke-pq/test-vectors-subset.json")
+	require.NoError(t, err, "failed to read draft-ietf-hpke-pq test vectors")
+	var file struct {
+		Vectors []hpkeTestVector `json:"vectors"`
+	}
+	require.NoError(t, json.Unmarshal(data, &file), "failed to parse draft-ietf-hpke-pq test vectors")
+	require.Len(t, file.Vectors, 7)
+
+	for _, vector := range file.Vectors {
+		suite := HPKESuite{KEM: vector.KEMID, KDF: vector.KDFID, AEAD: vector.AEADID}
+		t.Run(suite.String(), func(t *testing.T) {
+			recipientPublicKey, recipientPrivateKey, err := DeriveHPKEKeyPair(vector.KEMID, vector.IKMR)
+			require.NoError(t, err, "failed to derive recipient key pair")
+			publicKeyBytes, _ := MarshalPublicKey(recipientPublicKey)
+			require.Equal(t, []byte(vector.PKRm), publicKeyBytes, "recipient public key mismatch")
+			privateKeyBytes, _ := MarshalPrivateKey(recipientPrivateKey)
+			if parameterSet, ok := vector.KEMID.mlkemParameterSet(); ok {
+				keyPair, err := GenerateDeterministicMLKEMKeyPair(parameterSet, vector.SKRm)
+				require.NoError(t, err, "failed to expand the ML-KEM seed")
+				expandedPrivateKeyBytes, _ := MarshalPrivateKey(keyPair.PrivateKey)
+				require.Equal(t, expandedPrivateKeyBytes, privateKeyBytes, "recipient private key mismatch")
+			} else {
+				require.Equal(t, []byte(vector.SKRm), privateKeyBytes, "recipient private key mismatch")
+			}
+
+			encapsulatedKey, sender, err := SetupHPKESender(suite, recipientPublicKey, vector.Info, &HPKEOptions{Rand: bytes.NewReader(vector.IKME)})
+			require.NoError(t, err, "failed to set up sender")
+			require.Equal(t, []byte(vector.Enc), encapsulatedKey, "encapsulated key mismatch")
+			recipient, err := SetupHPKERecipient(suite, recipientPrivateKey, encapsulatedKey, vector.Info, nil)
+			require.NoError(t, err, "failed to set up recipient")
+			for _, context := range []*HPKEContext{sender, recipient} {
+				require.Equal(t, []byte(vector.ExporterSecret), context.exporterSecret, "exporter secret mismatch")
+				require.Equal(t, []byte(vector.BaseNonce), context.baseNonce, "base nonce mismatch")
+			}
+			for i, encryption := range vector.Encryptions {
+				ciphertext, err := sender.Seal(encryption.AAD, encryption.Plaintext)
+				require.NoError(t, err, "failed to seal message %d", i)
+				require.Equal(t, []byte(encryption.Ciphertext), ciphertext, "ciphertext %d mismatch", i)
+				plaintext, err := recipient.Open(encryption.AAD, encryption.Ciphertext)
+				require.NoError(t, err, "failed to open message %d", i)
+				require.Equal(t, []byte(encryption.Plaintext), plaintext, "plaintext %d mismatch", i)
+			}
+			for _, export := range vector.Exports {
+				exported, err := recipient.Export(export.ExporterContext, export.Length)
+				require.NoError(t, err, "failed to export")
+				require.Equal(t, []byte(export.ExportedValue), exported, "exported value mismatch")
+			}
+		})
+	}
+}
+
+// For TestHPKEXWingMatchesCIRCL: CIRCL's HPKE implements X-Wing, and must agree with this package byte for byte.
+func TestHPKEXWingMatchesCIRCL(t *testing.T) {
+	keyPair, err := GenerateXWingKeyPair()
+	require.NoError(t, err, "failed to generate X-Wing key pair")
+	info, aad, message := []byte("xwing info"), []byte("aad"), []byte("a message for the X-Wing recipient")
+	randomness := bytes.Repeat([]byte{0x3c}, 64)
+
+	for _, aeadID := range []HPKEAEAD{HPKEAEADAES128GCM, HPKEAEADChaCha20Poly1305} {
+		suite := HPKESuite{KEM: HPKEKEMXWing, KDF: HPKEKDFHKDFSHA256, AEAD: aeadID}
+		t.Run(suite.String(), func(t *testing.T) {
+			encapsulatedKey, sender, err := SetupHPKESender(suite, keyPair.PublicKey, info, &HPKEOptions{Rand: bytes.NewReader(randomness)})
+			require.NoError(t, err, "failed to set up sender")
+			ciphertext, err := sender.Seal(aad, message)
+			require.NoError(t, err, "failed to seal")
+			exported, err := sender.Export([]byte("exporter"), 42)
+			require.NoError(t, err, "failed to export")
+
+			circlSender, err := hpke.NewSuite(hpke.KEM_XWING, hpke.KDF(suite.KDF), hpke.AEAD(suite.AEAD)).NewSender(keyPair.PublicKey, info)
+			require.NoError(t, err, "CIRCL failed to create sender")
+			circlEncapsulatedKey, circlSealer, err := circlSender.Setup(bytes.NewReader(randomness))
+			require.NoError(t, err, "CIRCL failed to set up sender")
+			circlCiphertext, err := circlSealer.Seal(message, aad)
+			require.NoError(t, err, "CIRCL failed to seal")
+			require.Equal(t, circlEncapsulatedKey, encapsulatedKey, "encapsulated keys differ from CIRCL")
+			require.Equal(t, circlCiphertext, ciphertext, "ciphertexts differ from CIRCL")
+			require.Equal(t, circlSealer.Export([]byte("exporter"), 42), exported, "exported values differ from CIRCL")
+		})
+	}
+}
+
+func TestHPKEPostQuantumSuites(t *testing.T) {
+	info, aad, message := []byte("application info"), []byte("header"), []byte("archival payload")
+	for _, kemID := range []HPKEKEM{HPKEKEMMLKEM768, HPKEKEMMLKEM1024, HPKEKEMXWing} {
+		for _, kdfID := range []HPKEKDF{HPKEKDFHKDFSHA256, HPKEKDFHKDFSHA384, HPKEKDFHKDFSHA512, HPKEKDFSHAKE128, HPKEKDFSHAKE256} {
+			for _, aeadID := range []HPKEAEAD{HPKEAEADAES128GCM, HPKEAEADAES256GCM, HPKEAEADChaCha20Poly1305} {
+				suite := HPKESuite{KEM: kemID, KDF: kdfID, AEAD: aeadID}
+				t.Run(suite.String(), func(t *testing.T) {
+					publicKey, privateKey, err := GenerateHPKEKeyPair(kemID)
+					require.NoError(t, err, "failed to generate key pair")
+					for _, options := range []*HPKEOptions{nil, {PSK: bytes.Repeat([]byte{7}, 32), PSKID: []byte("psk id")}} {
+						encapsulatedKey, ciphertext, err := HPKESeal(suite, publicKey, info, aad, message, options)
+						require.NoError(t, err, "failed to seal")
+						plaintext, err := HPKEOpen(suite, privateKey, encapsulatedKey, info, aad, ciphertext, options)
+						require.NoError(t, err, "failed to open")
+						require.Equal(t, message, plaintext, "round trip mismatch")
+					}
+				})
+			}
+		}
+	}
+}
+
+// For TestHPKEReusesMLKEMKeys: keys from GenerateMLKEMKeyPair and UnmarshalPublicKey are HPKE keys as they are.
+func TestHPKEReusesMLKEMKeys(t *testing.T) {
+	keyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	require.NoError(t, err, "failed to generate ML-KEM key pair")
+	publicKeyBytes, _ := MarshalPublicKey(keyPair.PublicKey)
+	publicKey, err := UnmarshalHPKEPublicKey(HPKEKEMMLKEM768, publicKeyBytes)
+	require.NoError(t, err, "failed to unmarshal ML-KEM key as an HPKE key")
+	require.True(t, publicKey.Equal(keyPair.PublicKey))
+
+	suite := HPKESuite{KEM: HPKEKEMMLKEM768, KDF: HPKEKDFHKDFSHA256, AEAD: HPKEAEADAES128GCM}
+	encapsulatedKey, sender, err := SetupHPKESender(suite, publicKey, nil, nil)
+	require.NoError(t, err, "failed to set up sender")
+	require.Len(t, encapsulatedKey, keyPair.PublicKey.Scheme().CiphertextSize(), "the encapsulated key is the ML-KEM ciphertext")
+	recipient, err := SetupHPKERecipient(suite, keyPair.PrivateKey, encapsulatedKey, nil, nil)
+	require.NoError(t, err, "failed to set up recipient with the ML-KEM private key")
+
+	for i := range 3 {
+		message := []byte(fmt.Sprintf("message %d", i))
+		ciphertext, err := sender.Seal(nil, message)
+		require.NoError(t, err, "failed to seal")
+		plaintext, err := recipient.Open(nil, ciphertext)
+		require.NoError(t, err, "failed to open")
+		require.Equal(t, message, plaintext)
+	}
+	senderSecret, _ := sender.Export([]byte("context"), 32)
+	recipientSecret, _ := recipient.Export([]byte("context"), 32)
+	require.Equal(t, senderSecret, recipientSecret, "exported secrets differ")
+
+	// The ML-KEM-768 key does not belong to the ML-KEM-1024 or X-Wing suites.
+	for _, kemID := range []HPKEKEM{HPKEKEMMLKEM1024, HPKEKEMXWing} {
+		_, _, err = SetupHPKESender(HPKESuite{KEM: kemID, KDF: HPKEKDFHKDFSHA256, AEAD: HPKEAEADAES128GCM}, keyPair.PublicKey, nil, nil)
+		require.Error(t, err, "expected an ML-KEM-768 key to be rejected by %v", kemID)
+	}
+}
+
+func TestHPKEAuthModes(t *testing.T) {
+	suite := HPKESuite{KEM: HPKEKEMX25519HKDFSHA256, KDF: HPKEKDFHKDFSHA256, AEAD: HPKEAEADChaCha20Poly1305}
+	recipientPublicKey, recipientPrivateKey, err := GenerateHPKEKeyPair(suite.KEM)
+	require.NoError(t, err, "failed to generate recipient key pair")
+	senderPublicKey, senderPrivateKey, err := GenerateHPKEKeyPair(suite.KEM)
+	require.NoError(t, err, "failed to generate sender key pair")
+	otherPublicKey, _, err := GenerateHPKEKeyPair(suite.KEM)
+	require.NoError(t, err, "failed to generate key pair")
+
+	for _, psk := range [][]byte{nil, bytes.Repeat([]byte{1}, 32)} {
+		var pskID []byte
+		if psk != nil {
+			pskID = []byte("id")
+		}
+		encapsulatedKey, ciphertext, err := HPKESeal(suite, recipientPublicKey, nil, nil, []byte("authenticated"), &HPKEOptions{PSK: psk, PSKID: pskID, SenderPrivateKey: senderPrivateKey})
+		require.NoError(t, err, "failed to seal")
+		plaintext, err := HPKEOpen(suite, recipientPrivateKey, encapsulatedKey, nil, nil, ciphertext, &HPKEOptions{PSK: psk, PSKID: pskID, SenderPublicKey: senderPublicKey})
+		require.NoError(t, err, "failed to open")
+		require.Equal(t, []byte("authenticated"), plaintext)
+
+		_, err = HPKEOpen(suite, recipientPrivateKey, encapsulatedKey, nil, nil, ciphertext, &HPKEOptions{PSK: psk, PSKID: pskID, SenderPublicKey: otherPublicKey})
+		require.ErrorIs(t, err, ErrDecryptionFailed, "expected another sender key to fail")
+		_, err = HPKEOpen(suite, recipientPrivateKey, encapsulatedKey, nil, nil, ciphertext, &HPKEOptions{PSK: psk, PSKID: pskID})
+		require.ErrorIs(t, err, ErrDecryptionFailed, "expected the unauthenticated mode to fail")
+	}
+
+	// Post-quantum KEMs have no authenticated encapsulation.
+	mlkemPublicKey, _, err := GenerateHPKEKeyPair(HPKEKEMMLKEM768)
+	require.NoError(t, err, "failed to generate key pair")
+	_, mlkemSenderPrivateKey, err := GenerateHPKEKeyPair(HPKEKEMMLKEM768)
+	require.NoError(t, err, "failed to generate key pair")
+	_, _, err = SetupHPKESender(HPKESuite{KEM: HPKEKEMMLKEM768, KDF: HPKEKDFHKDFSHA256, AEAD: HPKEAEADAES128GCM}, mlkemPublicKey, nil, &HPKEOptions{SenderPrivateKey: mlkemSenderPrivateKey})
+	require.ErrorContains(t, err, "auth modes are not supported")
+}
+
+func TestHPKEInvalidInputs(t *testing.T) {
+	suite := HPKESuite{KEM: HPKEKEMMLKEM768, KDF: HPKEKDFHKDFSHA256, AEAD: HPKEAEADAES256GCM}
+	publicKey, privateKey, err := GenerateHPKEKeyPair(suite.KEM)
+	require.NoError(t, err, "failed to generate key pair")
+	encapsulatedKey, sender, err := SetupHPKESender(suite, publicKey, []byte("info"), nil)
+	require.NoError(t, err, "failed to set up sender")
+	recipient, err := SetupHPKERecipient(suite, privateKey, encapsulatedKey, []byte("info"), nil)
+	require.NoError(t, err, "failed to set up recipient")
+
+	first, err := sender.Seal(nil, []byte("first"))
+	require.NoError(t, err, "failed to seal")
+	second, err := sender.Seal(nil, []byte("second"))
+	require.NoError(t, err, "failed to seal")
+	tampered := append([]byte(nil), first...)
+	tampered[0] ^= 0x01
+	_, err = recipient.Open(nil, tampered)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+	_, err = recipient.Open(nil, second)
+	require.ErrorIs(t, err, ErrDecryptionFailed, "messages must be opened in order")
+	plaintext, err := recipient.Open(nil, first)
+	require.NoError(t, err, "a failed open must not advance the sequence")
+	require.Equal(t, []byte("first"), plaintext)
+
+	_, err = recipient.Seal(nil, []byte("x"))
+	require.Error(t, err, "expected a recipient context not to seal")
+	_, err = sender.Open(nil, first)
+	require.Error(t, err, "expected a sender context not to open")
+	_, err = sender.Export(nil, 255*32+1)
+	require.Error(t, err, "expected an export longer than 255 hash blocks to fail")
+
+	_, err = SetupHPKERecipient(suite, privateKey, encapsulatedKey[1:], nil, nil)
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+	_, _, err = SetupHPKESender(suite, publicKey, nil, &HPKEOptions{PSK: bytes.Repeat([]byte{1}, 32)})
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	require.ErrorContains(t, err, "must be given together")
+	_, _, err = SetupHPKESender(HPKESuite{KEM: suite.KEM, KDF: 0x0009, AEAD: suite.AEAD}, publicKey, nil, nil)
+	require.ErrorIs(t, err, ErrUnknownAlgorithm, "expected an unknown KDF to fail")
+	_, _, err = SetupHPKESender(HPKESuite{KEM: 0x0099, KDF: suite.KDF, AEAD: suite.AEAD}, publicKey, nil, nil)
+	require.Error(t, err, "expected an unknown KEM to fail")
+	_, _, err = SetupHPKESender(suite, nil, nil, nil)
+	require.Error(t, err, "expected a nil key to fail")
+	_, err = SetupHPKERecipient(suite, nil, encapsulatedKey, nil, nil)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = SetupHPKERecipient(suite, privateKey, encapsulatedKey, nil, &HPKEOptions{SenderPrivateKey: privateKey})
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, _, err = DeriveHPKEKeyPair(HPKEKEMX25519HKDFSHA256, make([]byte, 31))
+	require.ErrorIs(t, err, ErrInvalidSeedSize)
+	_, err = UnmarshalHPKEPublicKey(HPKEKEMMLKEM1024, make([]byte, 10))
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+
+	exportOnly := HPKESuite{KEM: suite.KEM, KDF: suite.KDF, AEAD: HPKEAEADExportOnly}
+	_, exportContext, err := SetupHPKESender(exportOnly, publicKey, nil, nil)
+	require.NoError(t, err, "failed to set up export-only sender")
+	_, err = exportContext.Seal(nil, []byte("x"))
+	require.Error(t, err, "expected an export-only context not to seal")
+
+	// The one-stage KDFs length-prefix their inputs with two bytes.
+	shakeSuite := HPKESuite{KEM: suite.KEM, KDF: HPKEKDFSHAKE256, AEAD: HPKEAEADExportOnly}
+	_, _, err = SetupHPKESender(shakeSuite, publicKey, make([]byte, math.MaxUint16+1), nil)
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, _, err = SetupHPKESender(shakeSuite, publicKey, nil, &HPKEOptions{PSK: make([]byte, math.MaxUint16+1), PSKID: []byte("id")})
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, shakeContext, err := SetupHPKESender(shakeSuite, publicKey, nil, nil)
+	require.NoError(t, err, "failed to set up SHAKE256 sender")
+	exported, err := shakeContext.Export(nil, 255*64+1)
+	require.NoError(t, err, "a one-stage KDF exports up to 65535 bytes")
+	require.Len(t, exported, 255*64+1)
+	_, err = shakeContext.Export(nil, math.MaxUint16+1)
+	require.Error(t, err, "expected an export longer than 65535 bytes to fail")
+}
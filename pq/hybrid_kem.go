@@ -0,0 +1,330 @@
+package pq
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha3"
+	"fmt"
+
+	"github.com/cloudflare/circl/kem"
+)
+
+// ECDHCurve identifies the classical elliptic-curve Diffie-Hellman component of a hybrid KEM.
+type ECDHCurve int
+
+const (
+	// ECDHX25519 is X25519 (RFC 7748).
+	ECDHX25519 ECDHCurve = iota + 1
+	// ECDHP256 is ECDH over NIST P-256, with uncompressed points.
+	ECDHP256
+	// ECDHP384 is ECDH over NIST P-384, with uncompressed points.
+	ECDHP384
+)
+
+// HybridKEMSharedSecretSize is the size in bytes of the combined shared secret, for all hybrid KEMs.
+const HybridKEMSharedSecretSize = 32
+
+// ECDHCurves lists the ECDH curves supported by the hybrid KEM combiner.
+var ECDHCurves = []ECDHCurve{ECDHX25519, ECDHP256, ECDHP384}
+
+// String returns the name of the curve, for example "P-256".
+func (curve ECDHCurve) String() string {
+	switch curve {
+	case ECDHX25519:
+		return "X25519"
+	case ECDHP256:
+		return "P-256"
+	case ECDHP384:
+		return "P-384"
+	default:
+		return fmt.Sprintf("ECDHCurve(%d)", int(curve))
+	}
+}
+
+// ecdhCurve returns the crypto/ecdh curve implementing the curve.
+func (curve ECDHCurve) ecdhCurve() (ecdh.Curve, error) {
+	switch curve {
+	case ECDHX25519:
+		return ecdh.X25519(), nil
+	case ECDHP256:
+		return ecdh.P256(), nil
+	case ECDHP384:
+		return ecdh.P384(), nil
+	default:
+		return nil, fmt.Errorf("%w: ECDH curve %v", ErrUnknownAlgorithm, curve)
+	}
+}
+
+// publicKeySize returns the size in bytes of an encoded public key on the curve.
+func (curve ECDHCurve) publicKeySize() int {
+	switch curve {
+	case ECDHX25519:
+		return 32
+	case ECDHP256:
+		return 65
+	case ECDHP384:
+		return 97
+	default:
+		return 0
+	}
+}
+
+// privateKeySize returns the size in bytes of an encoded private key (scalar) on the curve.
+func (curve ECDHCurve) privateKeySize() int {
+	switch curve {
+	case ECDHX25519, ECDHP256:
+		return 32
+	case ECDHP384:
+		return 48
+	default:
+		return 0
+	}
+}
+
+// hybridKEMSchemes returns the ECDH curve and ML-KEM scheme of a hybrid KEM, rejecting LegacyKyber1024.
+func hybridKEMSchemes(curve ECDHCurve, parameterSet MLKEMParameterSet) (ecdh.Curve, kem.Scheme, error) {
+	ecdhCurve, curveError := curve.ecdhCurve()
+	if curveError != nil {
+		return nil, nil, curveError
+	}
+	if parameterSet == LegacyKyber1024 {
+		return nil, nil, fmt.Errorf("%w: hybrid KEM requires a FIPS 203 ML-KEM parameter set, not legacy Kyber1024", ErrUnknownAlgorithm)
+	}
+	scheme, schemeError := parameterSet.scheme()
+	if schemeError != nil {
+		return nil, nil, schemeError
+	}
+	return ecdhCurve, scheme, nil
+}
+
+// HybridKEMPublicKey is the public key of a hybrid KEM that combines ECDH on Curve with ML-KEM of ParameterSet.
+type HybridKEMPublicKey struct {
+	Curve          ECDHCurve
+	ParameterSet   MLKEMParameterSet
+	ECDHPublicKey  *ecdh.PublicKey
+	MLKEMPublicKey kem.PublicKey
+}
+
+// HybridKEMPrivateKey is the private key of a hybrid KEM that combines ECDH on Curve with ML-KEM of ParameterSet.
+type HybridKEMPrivateKey struct {
+	Curve           ECDHCurve
+	ParameterSet    MLKEMParameterSet
+	ECDHPrivateKey  *ecdh.PrivateKey
+	MLKEMPrivateKey kem.PrivateKey
+}
+
+// HybridKEMKeyPair represents a hybrid ECDH + ML-KEM key pair.
+type HybridKEMKeyPair struct {
+	PublicKey  *HybridKEMPublicKey
+	PrivateKey *HybridKEMPrivateKey
+}
+
+// GenerateHybridKEMKeyPair generates a new key pair for the hybrid KEM combining ECDH on curve with ML-KEM of parameterSet.
+func GenerateHybridKEMKeyPair(curve ECDHCurve, parameterSet MLKEMParameterSet) (keyPair *HybridKEMKeyPair, keyGenerationError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			keyGenerationError = newPanicError("GenerateHybridKEMKeyPair", recoveredPanic)
+		}
+	}()
+	ecdhCurve, scheme, schemesError := hybridKEMSchemes(curve, parameterSet)
+	if schemesError != nil {
+		return nil, schemesError
+	}
+	ecdhPrivateKey, ecdhError := ecdhCurve.GenerateKey(rand.Reader)
+	if ecdhError != nil {
+		return nil, fmt.Errorf("%v GenerateKey: %w", curve, ecdhError)
+	}
+	mlkemPublicKey, mlkemPrivateKey, mlkemError := scheme.GenerateKeyPair()
+	if mlkemError != nil {
+		return nil, fmt.Errorf("%v GenerateKeyPair: %w", parameterSet, mlkemError)
+	}
+	return &HybridKEMKeyPair{
+		PublicKey: &HybridKEMPublicKey{
+			Curve:          curve,
+			ParameterSet:   parameterSet,
+			ECDHPublicKey:  ecdhPrivateKey.PublicKey(),
+			MLKEMPublicKey: mlkemPublicKey,
+		},
+		PrivateKey: &HybridKEMPrivateKey{
+			Curve:           curve,
+			ParameterSet:    parameterSet,
+			ECDHPrivateKey:  ecdhPrivateKey,
+			MLKEMPrivateKey: mlkemPrivateKey,
+		},
+	}, nil
+}
+
+// MarshalHybridKEMPublicKey serializes a hybrid KEM public key as the ML-KEM encapsulation key followed by the ECDH public key.
+func MarshalHybridKEMPublicKey(publicKey *HybridKEMPublicKey) ([]byte, error) {
+	if publicKey == nil || publicKey.ECDHPublicKey == nil || publicKey.MLKEMPublicKey == nil {
+		return nil, fmt.Errorf("%w: incomplete hybrid KEM public key", ErrInvalidKey)
+	}
+	mlkemBytes, marshalError := publicKey.MLKEMPublicKey.MarshalBinary()
+	if marshalError != nil {
+		return nil, fmt.Errorf("ML-KEM public key MarshalBinary: %w", marshalError)
+	}
+	return append(mlkemBytes, publicKey.ECDHPublicKey.Bytes()...), nil
+}
+
+// UnmarshalHybridKEMPublicKey deserializes a hybrid KEM public key produced by MarshalHybridKEMPublicKey.
+func UnmarshalHybridKEMPublicKey(curve ECDHCurve, parameterSet MLKEMParameterSet, data []byte) (publicKey *HybridKEMPublicKey, unmarshalError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			unmarshalError = newPanicError("UnmarshalHybridKEMPublicKey", recoveredPanic)
+		}
+	}()
+	ecdhCurve, scheme, schemesError := hybridKEMSchemes(curve, parameterSet)
+	if schemesError != nil {
+		return nil, schemesError
+	}
+	if len(data) != scheme.PublicKeySize()+curve.publicKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v+%v public key", parameterSet, curve), len(data), scheme.PublicKeySize()+curve.publicKeySize())
+	}
+	mlkemPublicKey, mlkemError := UnmarshalPublicKey(parameterSet, data[:scheme.PublicKeySize()])
+	if mlkemError != nil {
+		return nil, fmt.Errorf("ML-KEM public key: %w", mlkemError)
+	}
+	ecdhPublicKey, ecdhError := ecdhCurve.NewPublicKey(data[scheme.PublicKeySize():])
+	if ecdhError != nil {
+		return nil, fmt.Errorf("%v public key: %w", curve, ecdhError)
+	}
+	return &HybridKEMPublicKey{Curve: curve, ParameterSet: parameterSet, ECDHPublicKey: ecdhPublicKey, MLKEMPublicKey: mlkemPublicKey}, nil
+}
+
+// MarshalHybridKEMPrivateKey serializes a hybrid KEM private key as the ML-KEM decapsulation key followed by the ECDH private key.
+func MarshalHybridKEMPrivateKey(privateKey *HybridKEMPrivateKey) ([]byte, error) {
+	if privateKey == nil || privateKey.ECDHPrivateKey == nil || privateKey.MLKEMPrivateKey == nil {
+		return nil, fmt.Errorf("%w: incomplete hybrid KEM private key", ErrInvalidKey)
+	}
+	mlkemBytes, marshalError := privateKey.MLKEMPrivateKey.MarshalBinary()
+	if marshalError != nil {
+		return nil, fmt.Errorf("ML-KEM private key MarshalBinary: %w", marshalError)
+	}
+	return append(mlkemBytes, privateKey.ECDHPrivateKey.Bytes()...), nil
+}
+
+// UnmarshalHybridKEMPrivateKey deserializes a hybrid KEM private key produced by MarshalHybridKEMPrivateKey.
+func UnmarshalHybridKEMPrivateKey(curve ECDHCurve, parameterSet MLKEMParameterSet, data []byte) (privateKey *HybridKEMPrivateKey, unmarshalError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			unmarshalError = newPanicError("UnmarshalHybridKEMPrivateKey", recoveredPanic)
+		}
+	}()
+	ecdhCurve, scheme, schemesError := hybridKEMSchemes(curve, parameterSet)
+	if schemesError != nil {
+		return nil, schemesError
+	}
+	if len(data) != scheme.PrivateKeySize()+curve.privateKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v+%v private key", parameterSet, curve), len(data), scheme.PrivateKeySize()+curve.privateKeySize())
+	}
+	mlkemPrivateKey, mlkemError := UnmarshalPrivateKey(parameterSet, data[:scheme.PrivateKeySize()])
+	if mlkemError != nil {
+		return nil, fmt.Errorf("ML-KEM private key: %w", mlkemError)
+	}
+	ecdhPrivateKey, ecdhError := ecdhCurve.NewPrivateKey(data[scheme.PrivateKeySize():])
+	if ecdhError != nil {
+		return nil, fmt.Errorf("%v private key: %w", curve, ecdhError)
+	}
+	return &HybridKEMPrivateKey{Curve: curve, ParameterSet: parameterSet, ECDHPrivateKey: ecdhPrivateKey, MLKEMPrivateKey: mlkemPrivateKey}, nil
+}
+
+// Public returns the public key corresponding to the private key.
+func (privateKey *HybridKEMPrivateKey) Public() *HybridKEMPublicKey {
+	return &HybridKEMPublicKey{
+		Curve:          privateKey.Curve,
+		ParameterSet:   privateKey.ParameterSet,
+		ECDHPublicKey:  privateKey.ECDHPrivateKey.PublicKey(),
+		MLKEMPublicKey: privateKey.MLKEMPrivateKey.Public(),
+	}
+}
+
+// combineHybridKEMSharedSecrets derives the hybrid shared secret
+//
+//	SHA3-256(label || ss_MLKEM || ss_ECDH || ct_MLKEM || ct_ECDH || pk_ECDH)
+//
+// binding both shared secrets, both ciphertexts and the recipient's ECDH public key, in the style of the X-Wing combiner.
+// The label names the curve and parameter set, so secrets of different combinations never collide.
+func combineHybridKEMSharedSecrets(curve ECDHCurve, parameterSet MLKEMParameterSet, mlkemSharedSecret, ecdhSharedSecret, mlkemCiphertext, ecdhCiphertext, ecdhPublicKey []byte) []byte {
+	label := fmt.Sprintf("gopq hybrid KEM %v+%v", parameterSet, curve)
+	kdf := sha3.New256()
+	_, _ = kdf.Write([]byte{byte(len(label))})
+	_, _ = kdf.Write([]byte(label))
+	_, _ = kdf.Write(mlkemSharedSecret)
+	_, _ = kdf.Write(ecdhSharedSecret)
+	_, _ = kdf.Write(mlkemCiphertext)
+	_, _ = kdf.Write(ecdhCiphertext)
+	_, _ = kdf.Write(ecdhPublicKey)
+	return kdf.Sum(nil)
+}
+
+// HybridKEMEncapsulate encapsulates a shared secret to a hybrid KEM public key.
+// The ciphertext is the ML-KEM ciphertext followed by an ephemeral ECDH public key, and the shared secret is
+// HybridKEMSharedSecretSize bytes derived from both component shared secrets and ciphertexts.
+func HybridKEMEncapsulate(publicKey *HybridKEMPublicKey) (ciphertext []byte, sharedSecret []byte, encapsulateError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			encapsulateError = newPanicError("HybridKEMEncapsulate", recoveredPanic)
+		}
+	}()
+	if publicKey == nil || publicKey.ECDHPublicKey == nil || publicKey.MLKEMPublicKey == nil {
+		return nil, nil, fmt.Errorf("%w: incomplete hybrid KEM public key", ErrInvalidKey)
+	}
+	ecdhCurve, scheme, schemesError := hybridKEMSchemes(publicKey.Curve, publicKey.ParameterSet)
+	if schemesError != nil {
+		return nil, nil, schemesError
+	}
+	if publicKey.ECDHPublicKey.Curve() != ecdhCurve || publicKey.MLKEMPublicKey.Scheme() != scheme {
+		return nil, nil, fmt.Errorf("%w: hybrid KEM public key components do not match %v+%v", ErrInvalidKey, publicKey.ParameterSet, publicKey.Curve)
+	}
+	mlkemCiphertext, mlkemSharedSecret, mlkemError := scheme.Encapsulate(publicKey.MLKEMPublicKey)
+	if mlkemError != nil {
+		return nil, nil, fmt.Errorf("%v Encapsulate: %w", publicKey.ParameterSet, mlkemError)
+	}
+	ephemeralPrivateKey, ecdhError := ecdhCurve.GenerateKey(rand.Reader)
+	if ecdhError != nil {
+		return nil, nil, fmt.Errorf("%v GenerateKey: %w", publicKey.Curve, ecdhError)
+	}
+	ecdhSharedSecret, ecdhError := ephemeralPrivateKey.ECDH(publicKey.ECDHPublicKey)
+	if ecdhError != nil {
+		return nil, nil, fmt.Errorf("%v ECDH: %w", publicKey.Curve, ecdhError)
+	}
+	ecdhCiphertext := ephemeralPrivateKey.PublicKey().Bytes()
+	sharedSecret = combineHybridKEMSharedSecrets(publicKey.Curve, publicKey.ParameterSet, mlkemSharedSecret, ecdhSharedSecret, mlkemCiphertext, ecdhCiphertext, publicKey.ECDHPublicKey.Bytes())
+	return append(mlkemCiphertext, ecdhCiphertext...), sharedSecret, nil
+}
+
+// HybridKEMDecapsulate decapsulates a shared secret from a hybrid KEM ciphertext produced by HybridKEMEncapsulate.
+func HybridKEMDecapsulate(privateKey *HybridKEMPrivateKey, ciphertext []byte) (sharedSecret []byte, decapsulateError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			decapsulateError = newPanicError("HybridKEMDecapsulate", recoveredPanic)
+		}
+	}()
+	if privateKey == nil || privateKey.ECDHPrivateKey == nil || privateKey.MLKEMPrivateKey == nil {
+		return nil, fmt.Errorf("%w: incomplete hybrid KEM private key", ErrInvalidKey)
+	}
+	ecdhCurve, scheme, schemesError := hybridKEMSchemes(privateKey.Curve, privateKey.ParameterSet)
+	if schemesError != nil {
+		return nil, schemesError
+	}
+	if privateKey.ECDHPrivateKey.Curve() != ecdhCurve || privateKey.MLKEMPrivateKey.Scheme() != scheme {
+		return nil, fmt.Errorf("%w: hybrid KEM private key components do not match %v+%v", ErrInvalidKey, privateKey.ParameterSet, privateKey.Curve)
+	}
+	if len(ciphertext) != scheme.CiphertextSize()+privateKey.Curve.publicKeySize() {
+		return nil, newSizeError(ErrInvalidCiphertext, fmt.Sprintf("%v+%v ciphertext", privateKey.ParameterSet, privateKey.Curve), len(ciphertext), scheme.CiphertextSize()+privateKey.Curve.publicKeySize())
+	}
+	mlkemCiphertext, ecdhCiphertext := ciphertext[:scheme.CiphertextSize()], ciphertext[scheme.CiphertextSize():]
+	mlkemSharedSecret, mlkemError := scheme.Decapsulate(privateKey.MLKEMPrivateKey, mlkemCiphertext)
+	if mlkemError != nil {
+		return nil, fmt.Errorf("%v Decapsulate: %w", privateKey.ParameterSet, mlkemError)
+	}
+	ephemeralPublicKey, ecdhError := ecdhCurve.NewPublicKey(ecdhCiphertext)
+	if ecdhError != nil {
+		return nil, fmt.Errorf("%v ephemeral public key: %w", privateKey.Curve, ecdhError)
+	}
+	ecdhSharedSecret, ecdhError := privateKey.ECDHPrivateKey.ECDH(ephemeralPublicKey)
+	if ecdhError != nil {
+		return nil, fmt.Errorf("%v ECDH: %w", privateKey.Curve, ecdhError)
+	}
+	return combineHybridKEMSharedSecrets(privateKey.Curve, privateKey.ParameterSet, mlkemSharedSecret, ecdhSharedSecret, mlkemCiphertext, ecdhCiphertext, privateKey.ECDHPrivateKey.PublicKey().Bytes()), nil
+}
@@ -0,0 +1,37 @@
+package pq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkHybridKEMEncapsulate(b *testing.B) {
+	for _, curve := range ECDHCurves {
+		b.Run(MLKEM768.String()+"+"+curve.String(), func(b *testing.B) {
+			keyPair, err := GenerateHybridKEMKeyPair(curve, MLKEM768)
+			require.NoError(b, err, "Key pair generation should not error")
+			b.ResetTimer()
+			for b.Loop() {
+				_, _, err := HybridKEMEncapsulate(keyPair.PublicKey)
+				require.NoError(b, err, "Encapsulation should not error")
+			}
+		})
+	}
+}
+
+func BenchmarkHybridKEMDecapsulate(b *testing.B) {
+	for _, curve := range ECDHCurves {
+		b.Run(MLKEM768.String()+"+"+curve.String(), func(b *testing.B) {
+			keyPair, err := GenerateHybridKEMKeyPair(curve, MLKEM768)
+			require.NoError(b, err, "Key pair generation should not error")
+			ciphertext, _, err := HybridKEMEncapsulate(keyPair.PublicKey)
+			require.NoError(b, err, "Encapsulation should not error")
+			b.ResetTimer()
+			for b.Loop() {
+				_, err := HybridKEMDecapsulate(keyPair.PrivateKey, ciphertext)
+				require.NoError(b, err, "Decapsulation should not error")
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package pq
+
+import "testing"
+
+func FuzzHybridKEMDecapsulate(f *testing.F) {
+	keyPair, _ := GenerateHybridKEMKeyPair(ECDHP256, MLKEM768)
+	ciphertext, _, _ := HybridKEMEncapsulate(keyPair.PublicKey)
+	f.Add(ciphertext)
+	f.Fuzz(func(t *testing.T, fuzzedCiphertext []byte) {
+		sharedSecret, err := HybridKEMDecapsulate(keyPair.PrivateKey, fuzzedCiphertext)
+		if err != nil {
+			if sharedSecret != nil {
+				t.Error("expected nil shared secret on error")
+			}
+			return
+		}
+		if len(sharedSecret) != HybridKEMSharedSecretSize {
+			t.Errorf("shared secret len = %d, want %d", len(sharedSecret), HybridKEMSharedSecretSize)
+		}
+	})
+}
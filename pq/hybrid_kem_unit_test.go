@@ -0,0 +1,124 @@
+package pq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHybridKEMEncapsulateAndDecapsulate(t *testing.T) {
+	for _, curve := range ECDHCurves {
+		for _, parameterSet := range MLKEMParameterSets {
+			t.Run(parameterSet.String()+"+"+curve.String(), func(t *testing.T) {
+				keyPair, err := GenerateHybridKEMKeyPair(curve, parameterSet)
+				require.NoError(t, err, "failed to generate hybrid KEM key pair")
+				require.Equal(t, curve, keyPair.PublicKey.Curve, "expected public key to carry its curve")
+				require.Equal(t, parameterSet, keyPair.PrivateKey.ParameterSet, "expected private key to carry its parameter set")
+
+				ciphertext, sharedSecret, err := HybridKEMEncapsulate(keyPair.PublicKey)
+				require.NoError(t, err, "failed to encapsulate")
+				require.Len(t, sharedSecret, HybridKEMSharedSecretSize, "unexpected shared secret size")
+
+				recoveredSecret, err := HybridKEMDecapsulate(keyPair.PrivateKey, ciphertext)
+				require.NoError(t, err, "failed to decapsulate")
+				require.Equal(t, sharedSecret, recoveredSecret, "expected decapsulated shared secret to match")
+
+				publicKeyBytes, err := MarshalHybridKEMPublicKey(keyPair.PublicKey)
+				require.NoError(t, err, "failed to marshal public key")
+				publicKey, err := UnmarshalHybridKEMPublicKey(curve, parameterSet, publicKeyBytes)
+				require.NoError(t, err, "failed to unmarshal public key")
+				require.True(t, publicKey.MLKEMPublicKey.Equal(keyPair.PublicKey.MLKEMPublicKey), "expected ML-KEM public key to match")
+				require.True(t, publicKey.ECDHPublicKey.Equal(keyPair.PublicKey.ECDHPublicKey), "expected ECDH public key to match")
+
+				privateKeyBytes, err := MarshalHybridKEMPrivateKey(keyPair.PrivateKey)
+				require.NoError(t, err, "failed to marshal private key")
+				privateKey, err := UnmarshalHybridKEMPrivateKey(curve, parameterSet, privateKeyBytes)
+				require.NoError(t, err, "failed to unmarshal private key")
+				require.True(t, privateKey.Public().ECDHPublicKey.Equal(keyPair.PublicKey.ECDHPublicKey), "expected derived public key to match")
+
+				ciphertext, sharedSecret, err = HybridKEMEncapsulate(publicKey)
+				require.NoError(t, err, "failed to encapsulate to unmarshalled public key")
+				recoveredSecret, err = HybridKEMDecapsulate(privateKey, ciphertext)
+				require.NoError(t, err, "failed to decapsulate with unmarshalled private key")
+				require.Equal(t, sharedSecret, recoveredSecret, "expected decapsulated shared secret to match")
+			})
+		}
+	}
+}
+
+func TestHybridKEMBindsBothComponents(t *testing.T) {
+	keyPair, err := GenerateHybridKEMKeyPair(ECDHP256, MLKEM768)
+	require.NoError(t, err, "failed to generate hybrid KEM key pair")
+	ciphertext, sharedSecret, err := HybridKEMEncapsulate(keyPair.PublicKey)
+	require.NoError(t, err, "failed to encapsulate")
+
+	// Tampering with the ML-KEM ciphertext triggers implicit rejection; tampering with the ECDH ciphertext
+	// changes the ECDH shared secret or is rejected as an invalid point. Either way the secret must not match.
+	for _, offset := range []int{0, len(ciphertext) - 1} {
+		tampered := append([]byte(nil), ciphertext...)
+		tampered[offset] ^= 0x01
+		recoveredSecret, err := HybridKEMDecapsulate(keyPair.PrivateKey, tampered)
+		if err == nil {
+			require.NotEqual(t, sharedSecret, recoveredSecret, "expected tampered ciphertext at offset %d to yield a different shared secret", offset)
+		}
+	}
+
+	otherKeyPair, err := GenerateHybridKEMKeyPair(ECDHP256, MLKEM768)
+	require.NoError(t, err, "failed to generate hybrid KEM key pair")
+	recoveredSecret, err := HybridKEMDecapsulate(otherKeyPair.PrivateKey, ciphertext)
+	require.NoError(t, err, "expected decapsulation with the wrong key to succeed")
+	require.NotEqual(t, sharedSecret, recoveredSecret, "expected the wrong key to yield a different shared secret")
+
+	// The label binds the combination: the same component secrets give different outputs for different curves or parameter sets.
+	mlkemSecret, ecdhSecret := make([]byte, 32), make([]byte, 32)
+	require.NotEqual(t,
+		combineHybridKEMSharedSecrets(ECDHX25519, MLKEM768, mlkemSecret, ecdhSecret, nil, nil, nil),
+		combineHybridKEMSharedSecrets(ECDHP256, MLKEM768, mlkemSecret, ecdhSecret, nil, nil, nil),
+		"expected the combiner to bind the curve")
+	require.NotEqual(t,
+		combineHybridKEMSharedSecrets(ECDHX25519, MLKEM768, mlkemSecret, ecdhSecret, nil, nil, nil),
+		combineHybridKEMSharedSecrets(ECDHX25519, MLKEM1024, mlkemSecret, ecdhSecret, nil, nil, nil),
+		"expected the combiner to bind the ML-KEM parameter set")
+}
+
+func TestHybridKEMInvalidInput(t *testing.T) {
+	_, err := GenerateHybridKEMKeyPair(ECDHCurve(0), MLKEM768)
+	require.Error(t, err, "expected error for unsupported curve")
+	require.Equal(t, "ECDHCurve(0)", ECDHCurve(0).String(), "unexpected name for unsupported curve")
+	_, err = GenerateHybridKEMKeyPair(ECDHX25519, LegacyKyber1024)
+	require.Error(t, err, "expected error for legacy Kyber1024")
+	_, err = GenerateHybridKEMKeyPair(ECDHX25519, MLKEMParameterSet(0))
+	require.Error(t, err, "expected error for unsupported parameter set")
+
+	keyPair, err := GenerateHybridKEMKeyPair(ECDHX25519, MLKEM768)
+	require.NoError(t, err, "failed to generate hybrid KEM key pair")
+	_, _, err = HybridKEMEncapsulate(nil)
+	require.Error(t, err, "expected error for nil public key")
+	_, err = HybridKEMDecapsulate(nil, nil)
+	require.Error(t, err, "expected error for nil private key")
+
+	mismatched := *keyPair.PublicKey
+	mismatched.ParameterSet = MLKEM1024
+	_, _, err = HybridKEMEncapsulate(&mismatched)
+	require.Error(t, err, "expected error for a public key with mismatched components")
+
+	ciphertext, _, err := HybridKEMEncapsulate(keyPair.PublicKey)
+	require.NoError(t, err, "failed to encapsulate")
+	_, err = HybridKEMDecapsulate(keyPair.PrivateKey, ciphertext[:len(ciphertext)-1])
+	require.Error(t, err, "expected error for truncated ciphertext")
+
+	publicKeyBytes, err := MarshalHybridKEMPublicKey(keyPair.PublicKey)
+	require.NoError(t, err, "failed to marshal public key")
+	_, err = UnmarshalHybridKEMPublicKey(ECDHP256, MLKEM768, publicKeyBytes)
+	require.Error(t, err, "expected error for an X25519 public key unmarshalled as P-256")
+	_, err = UnmarshalHybridKEMPublicKey(ECDHX25519, MLKEM1024, publicKeyBytes)
+	require.Error(t, err, "expected error for an ML-KEM-768 public key unmarshalled as ML-KEM-1024")
+	privateKeyBytes, err := MarshalHybridKEMPrivateKey(keyPair.PrivateKey)
+	require.NoError(t, err, "failed to marshal private key")
+	_, err = UnmarshalHybridKEMPrivateKey(ECDHP384, MLKEM768, privateKeyBytes)
+	require.Error(t, err, "expected error for an X25519 private key unmarshalled as P-384")
+	_, err = MarshalHybridKEMPublicKey(nil)
+	require.Error(t, err, "expected error marshalling nil public key")
+	_, err = MarshalHybridKEMPrivateKey(nil)
+	require.Error(t, err, "expected error marshalling nil private key")
+}
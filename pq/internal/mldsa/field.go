@@ -0,0 +1,210 @@
+package mldsa
+
+// ringElement is a polynomial of R_q = Z_q[X]/(X^256 + 1), either in coefficient or in NTT representation.
+// Every coefficient is kept fully reduced in [0, q).
+type ringElement [n]uint32
+
+// zetas holds 1753^BitRev8(k) mod q, the powers of the 512th root of unity used by the NTT (FIPS 204 Appendix B).
+var zetas = func() (table [n]uint32) {
+	for k := range table {
+		table[k] = 1
+		exponent := bitReverse8(uint8(k))
+		base := uint32(1753)
+		for ; exponent > 0; exponent >>= 1 {
+			if exponent&1 == 1 {
+				table[k] = fieldMul(table[k], base)
+			}
+			base = fieldMul(base, base)
+		}
+	}
+	return table
+}()
+
+// bitReverse8 reverses the bits of an 8-bit integer.
+func bitReverse8(x uint8) uint8 {
+	var reversed uint8
+	for i := 0; i < 8; i++ {
+		reversed = reversed<<1 | (x>>i)&1
+	}
+	return reversed
+}
+
+// reduceOnce maps x in [0, 2q) to [0, q) without branching.
+func reduceOnce(x uint32) uint32 {
+	x -= q
+	return x + (q & uint32(int32(x)>>31))
+}
+
+func fieldAdd(a, b uint32) uint32 { return reduceOnce(a + b) }
+
+func fieldSub(a, b uint32) uint32 { return reduceOnce(a - b + q) }
+
+func fieldMul(a, b uint32) uint32 { return uint32(uint64(a) * uint64(b) % q) }
+
+// centered returns the representative of a in (-(q-1)/2, (q-1)/2], without branching.
+func centered(a uint32) int32 {
+	x := int32(a)
+	return x - (q & ((int32((q-1)/2) - x) >> 31))
+}
+
+// absolute returns |x| without branching.
+func absolute(x int32) uint32 {
+	mask := x >> 31
+	return uint32((x ^ mask) - mask)
+}
+
+// infinityNorm returns the largest absolute centered coefficient of the element.
+func infinityNorm(element *ringElement) uint32 {
+	norm := uint32(0)
+	for _, coefficient := range element {
+		norm = max(norm, absolute(centered(coefficient)))
+	}
+	return norm
+}
+
+func addTo(accumulator, element *ringElement) {
+	for i := range accumulator {
+		accumulator[i] = fieldAdd(accumulator[i], element[i])
+	}
+}
+
+func subtractFrom(accumulator, element *ringElement) {
+	for i := range accumulator {
+		accumulator[i] = fieldSub(accumulator[i], element[i])
+	}
+}
+
+// multiplyNTT implements FIPS 204 Algorithm 45 MultiplyNTT.
+func multiplyNTT(out, a, b *ringElement) {
+	for i := range out {
+		out[i] = fieldMul(a[i], b[i])
+	}
+}
+
+// ntt implements FIPS 204 Algorithm 41 NTT in place.
+func ntt(element *ringElement) {
+	m := 0
+	for length := 128; length >= 1; length /= 2 {
+		for start := 0; start < n; start += 2 * length {
+			m++
+			zeta := zetas[m]
+			for j := start; j < start+length; j++ {
+				t := fieldMul(zeta, element[j+length])
+				element[j+length] = fieldSub(element[j], t)
+				element[j] = fieldAdd(element[j], t)
+			}
+		}
+	}
+}
+
+// inverseNTT implements FIPS 204 Algorithm 42 NTT^-1 in place.
+func inverseNTT(element *ringElement) {
+	m := n
+	for length := 1; length < n; length *= 2 {
+		for start := 0; start < n; start += 2 * length {
+			m--
+			zeta := q - zetas[m]
+			for j := start; j < start+length; j++ {
+				t := element[j]
+				element[j] = fieldAdd(t, element[j+length])
+				element[j+length] = fieldMul(zeta, fieldSub(t, element[j+length]))
+			}
+		}
+	}
+	const f = 8347681 // 256^-1 mod q
+	for j := range element {
+		element[j] = fieldMul(f, element[j])
+	}
+}
+
+// decompose implements FIPS 204 Algorithm 36 Decompose without branching on r.
+// The divisions use constant divisors, which compile to multiplications.
+func decompose(r uint32, gamma2 int) (r1 uint32, r0 int32) {
+	var alpha, quotient, remainder uint32
+	if gamma2 == gamma2Q88 {
+		alpha, quotient, remainder = 2*gamma2Q88, r/(2*gamma2Q88), r%(2*gamma2Q88)
+	} else {
+		alpha, quotient, remainder = 2*gamma2Q32, r/(2*gamma2Q32), r%(2*gamma2Q32)
+	}
+	// r0 = r mod± alpha, in (-alpha/2, alpha/2].
+	r0 = int32(remainder)
+	mask := (int32(alpha/2) - r0) >> 31
+	r0 -= int32(alpha) & mask
+	r1 = quotient + uint32(mask&1)
+	// If r - r0 = q - 1, then r1 = 0 and r0 = r0 - 1.
+	edge := ((r1 ^ ((q - 1) / alpha)) - 1) >> 31
+	r1 &= edge - 1
+	r0 -= int32(edge)
+	return r1, r0
+}
+
+// highBits implements FIPS 204 Algorithm 37 HighBits.
+func highBits(r uint32, gamma2 int) uint32 {
+	r1, _ := decompose(r, gamma2)
+	return r1
+}
+
+// makeHint implements FIPS 204 Algorithm 39 MakeHint, returning 1 if adding z to r alters the high bits of r.
+func makeHint(z, r uint32, gamma2 int) uint32 {
+	difference := highBits(r, gamma2) ^ highBits(fieldAdd(r, z), gamma2)
+	return (difference | -difference) >> 31
+}
+
+// useHint implements FIPS 204 Algorithm 40 UseHint. It is only used during verification, on public data.
+func useHint(hint, r uint32, gamma2 int) uint32 {
+	m := uint32((q - 1) / (2 * gamma2))
+	r1, r0 := decompose(r, gamma2)
+	if hint == 0 {
+		return r1
+	}
+	if r0 > 0 {
+		return (r1 + 1) % m
+	}
+	return (r1 + m - 1) % m
+}
+
+// packSimple implements FIPS 204 Algorithm 16 SimpleBitPack for coefficients of the given bit width.
+func packSimple(element *ringElement, bits int) []byte {
+	out := make([]byte, 0, 32*bits)
+	var accumulator uint64
+	accumulatedBits := 0
+	for _, coefficient := range element {
+		accumulator |= uint64(coefficient) << accumulatedBits
+		accumulatedBits += bits
+		for accumulatedBits >= 8 {
+			out = append(out, byte(accumulator))
+			accumulator >>= 8
+			accumulatedBits -= 8
+		}
+	}
+	return out
+}
+
+// unpackSimple implements FIPS 204 Algorithm 18 SimpleBitUnpack for coefficients of the given bit width.
+func unpackSimple(data []byte, bits int) ringElement {
+	var element ringElement
+	var accumulator uint64
+	accumulatedBits := 0
+	offset := 0
+	mask := uint64(1)<<bits - 1
+	for i := range element {
+		for accumulatedBits < bits {
+			accumulator |= uint64(data[offset]) << accumulatedBits
+			offset++
+			accumulatedBits += 8
+		}
+		element[i] = uint32(accumulator & mask)
+		accumulator >>= bits
+		accumulatedBits -= bits
+	}
+	return element
+}
+
+// unpackCentered implements FIPS 204 Algorithm 19 BitUnpack, mapping each packed value v to b - v mod q.
+func unpackCentered(data []byte, bits int, b uint32) ringElement {
+	element := unpackSimple(data, bits)
+	for i := range element {
+		element[i] = fieldSub(b, element[i])
+	}
+	return element
+}
@@ -0,0 +1,469 @@
+// Package mldsa implements the FIPS 204 ML-DSA internal signing and verification algorithms
+// (Algorithm 7 ML-DSA.Sign_internal and Algorithm 8 ML-DSA.Verify_internal) over an externally computed message representative mu.
+//
+// Key generation and the encodings of keys are provided by CIRCL; this package only decodes them.
+// Computing mu outside the signing algorithm is what allows HashML-DSA, streaming ("external mu") signing and
+// caller-controlled signing randomness, none of which are exposed by CIRCL.
+//
+// Reference: https://nvlpubs.nist.gov/nistpubs/FIPS/NIST.FIPS.204.pdf
+package mldsa
+
+import (
+	"crypto/sha3"
+	"crypto/subtle"
+	"errors"
+)
+
+const (
+	q = 8380417 // field modulus 2^23 - 2^13 + 1
+	n = 256     // coefficients per polynomial
+	d = 13      // dropped bits from t
+
+	// MuSize is the size in bytes of the message representative mu.
+	MuSize = 64
+	// RandomSize is the size in bytes of the per-signature randomness rnd; all zeroes selects the deterministic variant.
+	RandomSize = 32
+	// PublicKeyHashSize is the size in bytes of tr = H(pk, 64).
+	PublicKeyHashSize = 64
+
+	gamma2Q88 = (q - 1) / 88
+	gamma2Q32 = (q - 1) / 32
+)
+
+// Parameters holds the constants of an ML-DSA parameter set (FIPS 204 Table 1).
+type Parameters struct {
+	name   string
+	k, l   int
+	eta    int
+	tau    int
+	beta   int
+	gamma1 int
+	gamma2 int
+	omega  int
+	lambda int
+}
+
+var (
+	// MLDSA44 is the ML-DSA-44 parameter set.
+	MLDSA44 = &Parameters{name: "ML-DSA-44", k: 4, l: 4, eta: 2, tau: 39, beta: 78, gamma1: 1 << 17, gamma2: gamma2Q88, omega: 80, lambda: 128}
+	// MLDSA65 is the ML-DSA-65 parameter set.
+	MLDSA65 = &Parameters{name: "ML-DSA-65", k: 6, l: 5, eta: 4, tau: 49, beta: 196, gamma1: 1 << 19, gamma2: gamma2Q32, omega: 55, lambda: 192}
+	// MLDSA87 is the ML-DSA-87 parameter set.
+	MLDSA87 = &Parameters{name: "ML-DSA-87", k: 8, l: 7, eta: 2, tau: 60, beta: 120, gamma1: 1 << 19, gamma2: gamma2Q32, omega: 75, lambda: 256}
+)
+
+var (
+	// ErrPrivateKeyRange is returned for an encoded private key whose s1 or s2 coefficients lie outside [-eta, eta].
+	ErrPrivateKeyRange = errors.New("mldsa: private key coefficient out of range")
+
+	errPrivateKeySize = errors.New("mldsa: invalid private key size")
+	errPublicKeySize  = errors.New("mldsa: invalid public key size")
+)
+
+// String returns the name of the parameter set.
+func (params *Parameters) String() string { return params.name }
+
+// PublicKeySize returns the size in bytes of an encoded public key.
+func (params *Parameters) PublicKeySize() int { return 32 + 32*params.k*(bitLength(q-1)-d) }
+
+// PrivateKeySize returns the size in bytes of an encoded (expanded) private key.
+func (params *Parameters) PrivateKeySize() int {
+	return 32 + 32 + PublicKeyHashSize + 32*((params.k+params.l)*bitLength(2*params.eta)+d*params.k)
+}
+
+// SignatureSize returns the size in bytes of an encoded signature.
+func (params *Parameters) SignatureSize() int {
+	return params.lambda/4 + params.l*32*(1+bitLength(params.gamma1-1)) + params.omega + params.k
+}
+
+// PublicKeyHash returns tr = H(pk, 64), which prefixes every message representative.
+func PublicKeyHash(publicKey []byte) [PublicKeyHashSize]byte {
+	var tr [PublicKeyHashSize]byte
+	h := sha3.NewSHAKE256()
+	_, _ = h.Write(publicKey)
+	_, _ = h.Read(tr[:])
+	return tr
+}
+
+// PrivateKeyHash returns tr as stored in an encoded private key, after checking the key as SignMu does.
+func PrivateKeyHash(params *Parameters, privateKey []byte) ([PublicKeyHashSize]byte, error) {
+	var tr [PublicKeyHashSize]byte
+	if len(privateKey) != params.PrivateKeySize() {
+		return tr, errPrivateKeySize
+	}
+	if _, _, _, ok := decodeSecretVectors(params, privateKey[128:]); !ok {
+		return tr, ErrPrivateKeyRange
+	}
+	copy(tr[:], privateKey[64:128])
+	return tr, nil
+}
+
+// NewMessageHash returns a SHAKE256 instance primed with tr. The caller writes the formatted message M' to it and
+// reads MuSize bytes to obtain mu = H(tr || M', 64), which allows M' to be absorbed incrementally.
+func NewMessageHash(tr []byte) *sha3.SHAKE {
+	h := sha3.NewSHAKE256()
+	_, _ = h.Write(tr)
+	return h
+}
+
+// SignMu implements ML-DSA.Sign_internal (FIPS 204 Algorithm 7) given the message representative mu.
+// An all-zero rnd yields the deterministic variant; a fresh random rnd yields the hedged variant.
+func SignMu(params *Parameters, privateKey []byte, mu *[MuSize]byte, rnd *[RandomSize]byte) ([]byte, error) {
+	if len(privateKey) != params.PrivateKeySize() {
+		return nil, errPrivateKeySize
+	}
+	rho, key := privateKey[0:32], privateKey[32:64]
+	s1, s2, t0, ok := decodeSecretVectors(params, privateKey[128:])
+	if !ok {
+		return nil, ErrPrivateKeyRange
+	}
+	for i := range s1 {
+		ntt(&s1[i])
+	}
+	for i := range s2 {
+		ntt(&s2[i])
+	}
+	for i := range t0 {
+		ntt(&t0[i])
+	}
+	matrix := expandA(params, rho)
+
+	var rhoPrimePrime [64]byte
+	h := sha3.NewSHAKE256()
+	_, _ = h.Write(key)
+	_, _ = h.Write(rnd[:])
+	_, _ = h.Write(mu[:])
+	_, _ = h.Read(rhoPrimePrime[:])
+
+	commitmentHashSize := params.lambda / 4
+	w1Encoded := make([]byte, 32*params.k*w1Bits(params))
+	y := make([]ringElement, params.l)
+	yHat := make([]ringElement, params.l)
+	w := make([]ringElement, params.k)
+	w1 := make([]ringElement, params.k)
+	z := make([]ringElement, params.l)
+	r0Vector := make([]ringElement, params.k)
+	hint := make([]ringElement, params.k)
+	commitmentHash := make([]byte, commitmentHashSize)
+	for kappa := 0; ; kappa += params.l {
+		expandMask(params, &rhoPrimePrime, kappa, y)
+		for i := range y {
+			yHat[i] = y[i]
+			ntt(&yHat[i])
+		}
+		for i := range w {
+			w[i] = ringElement{}
+			for j := range yHat {
+				var product ringElement
+				multiplyNTT(&product, &matrix[i][j], &yHat[j])
+				addTo(&w[i], &product)
+			}
+			inverseNTT(&w[i])
+			for c := range w[i] {
+				w1[i][c] = highBits(w[i][c], params.gamma2)
+			}
+		}
+		encodeW1(params, w1, w1Encoded)
+
+		h.Reset()
+		_, _ = h.Write(mu[:])
+		_, _ = h.Write(w1Encoded)
+		_, _ = h.Read(commitmentHash)
+		challenge := sampleInBall(params, commitmentHash)
+		ntt(&challenge)
+
+		// z = y + c*s1
+		rejected := false
+		for i := range z {
+			var cs1 ringElement
+			multiplyNTT(&cs1, &challenge, &s1[i])
+			inverseNTT(&cs1)
+			z[i] = y[i]
+			addTo(&z[i], &cs1)
+			if infinityNorm(&z[i]) >= uint32(params.gamma1-params.beta) {
+				rejected = true
+			}
+		}
+		// r0 = LowBits(w - c*s2)
+		for i := range r0Vector {
+			var cs2 ringElement
+			multiplyNTT(&cs2, &challenge, &s2[i])
+			inverseNTT(&cs2)
+			r0Vector[i] = w[i]
+			subtractFrom(&r0Vector[i], &cs2)
+			for c := range r0Vector[i] {
+				_, lowBits := decompose(r0Vector[i][c], params.gamma2)
+				if absolute(lowBits) >= uint32(params.gamma2-params.beta) {
+					rejected = true
+				}
+			}
+		}
+		if rejected {
+			continue
+		}
+		// h = MakeHint(-c*t0, w - c*s2 + c*t0)
+		hintCount := 0
+		for i := range hint {
+			var ct0 ringElement
+			multiplyNTT(&ct0, &challenge, &t0[i])
+			inverseNTT(&ct0)
+			if infinityNorm(&ct0) >= uint32(params.gamma2) {
+				rejected = true
+			}
+			for c := range hint[i] {
+				r := fieldAdd(r0Vector[i][c], ct0[c])
+				hint[i][c] = makeHint(fieldSub(0, ct0[c]), r, params.gamma2)
+				hintCount += int(hint[i][c])
+			}
+		}
+		if rejected || hintCount > params.omega {
+			continue
+		}
+		return encodeSignature(params, commitmentHash, z, hint), nil
+	}
+}
+
+// VerifyMu implements ML-DSA.Verify_internal (FIPS 204 Algorithm 8) given the message representative mu.
+func VerifyMu(params *Parameters, publicKey []byte, mu *[MuSize]byte, signature []byte) (bool, error) {
+	if len(publicKey) != params.PublicKeySize() {
+		return false, errPublicKeySize
+	}
+	if len(signature) != params.SignatureSize() {
+		return false, nil
+	}
+	rho := publicKey[:32]
+	t1 := make([]ringElement, params.k)
+	for i := range t1 {
+		t1[i] = unpackSimple(publicKey[32+320*i:32+320*(i+1)], 10)
+		for c := range t1[i] {
+			t1[i][c] <<= d
+		}
+		ntt(&t1[i])
+	}
+	commitmentHashSize := params.lambda / 4
+	commitmentHash := signature[:commitmentHashSize]
+	z, hint, ok := decodeSignature(params, signature[commitmentHashSize:])
+	if !ok {
+		return false, nil
+	}
+	for i := range z {
+		if infinityNorm(&z[i]) >= uint32(params.gamma1-params.beta) {
+			return false, nil
+		}
+	}
+	matrix := expandA(params, rho)
+	challenge := sampleInBall(params, commitmentHash)
+	ntt(&challenge)
+	for i := range z {
+		ntt(&z[i])
+	}
+	w1 := make([]ringElement, params.k)
+	for i := range w1 {
+		var wApprox ringElement
+		for j := range z {
+			var product ringElement
+			multiplyNTT(&product, &matrix[i][j], &z[j])
+			addTo(&wApprox, &product)
+		}
+		var ct1 ringElement
+		multiplyNTT(&ct1, &challenge, &t1[i])
+		subtractFrom(&wApprox, &ct1)
+		inverseNTT(&wApprox)
+		for c := range wApprox {
+			w1[i][c] = useHint(hint[i][c], wApprox[c], params.gamma2)
+		}
+	}
+	w1Encoded := make([]byte, 32*params.k*w1Bits(params))
+	encodeW1(params, w1, w1Encoded)
+	expectedCommitmentHash := make([]byte, commitmentHashSize)
+	h := sha3.NewSHAKE256()
+	_, _ = h.Write(mu[:])
+	_, _ = h.Write(w1Encoded)
+	_, _ = h.Read(expectedCommitmentHash)
+	return subtle.ConstantTimeCompare(commitmentHash, expectedCommitmentHash) == 1, nil
+}
+
+// decodeSecretVectors decodes s1, s2 and t0 from the tail of an encoded private key (FIPS 204 Algorithm 25 skDecode),
+// and reports whether the coefficients of s1 and s2 lie in [-eta, eta].
+func decodeSecretVectors(params *Parameters, encoded []byte) (s1, s2, t0 []ringElement, ok bool) {
+	etaBits := bitLength(2 * params.eta)
+	etaSize := 32 * etaBits
+	s1 = make([]ringElement, params.l)
+	s2 = make([]ringElement, params.k)
+	t0 = make([]ringElement, params.k)
+	ok = true
+	offset := 0
+	for _, vector := range [][]ringElement{s1, s2} {
+		for i := range vector {
+			// BitUnpack with b = eta reads etaBits-bit values, of which only 0 to 2*eta are valid (FIPS 204 Algorithm 25
+			// skDecode). t0 needs no such check: every 13-bit value maps into (-2^12, 2^12].
+			packed := unpackSimple(encoded[offset:offset+etaSize], etaBits)
+			for c := range packed {
+				ok = ok && packed[c] <= uint32(2*params.eta)
+				vector[i][c] = fieldSub(uint32(params.eta), packed[c])
+			}
+			offset += etaSize
+		}
+	}
+	for i := range t0 {
+		t0[i] = unpackCentered(encoded[offset:offset+32*d], d, 1<<(d-1))
+		offset += 32 * d
+	}
+	return s1, s2, t0, ok
+}
+
+// encodeSignature implements FIPS 204 Algorithm 26 sigEncode.
+func encodeSignature(params *Parameters, commitmentHash []byte, z, hint []ringElement) []byte {
+	signature := make([]byte, 0, params.SignatureSize())
+	signature = append(signature, commitmentHash...)
+	zBits := 1 + bitLength(params.gamma1-1)
+	for i := range z {
+		var packed ringElement
+		for c := range z[i] {
+			packed[c] = fieldSub(uint32(params.gamma1), z[i][c])
+		}
+		signature = append(signature, packSimple(&packed, zBits)...)
+	}
+	hintEncoded := make([]byte, params.omega+params.k)
+	index := 0
+	for i := range hint {
+		for c := range hint[i] {
+			if hint[i][c] != 0 {
+				hintEncoded[index] = byte(c)
+				index++
+			}
+		}
+		hintEncoded[params.omega+i] = byte(index)
+	}
+	return append(signature, hintEncoded...)
+}
+
+// decodeSignature implements FIPS 204 Algorithm 27 sigDecode, returning false for a malformed hint encoding.
+func decodeSignature(params *Parameters, encoded []byte) (z, hint []ringElement, ok bool) {
+	zBits := 1 + bitLength(params.gamma1-1)
+	zSize := 32 * zBits
+	z = make([]ringElement, params.l)
+	for i := range z {
+		z[i] = unpackCentered(encoded[i*zSize:(i+1)*zSize], zBits, uint32(params.gamma1))
+	}
+	hintEncoded := encoded[params.l*zSize:]
+	hint = make([]ringElement, params.k)
+	index := 0
+	for i := range hint {
+		limit := int(hintEncoded[params.omega+i])
+		if limit < index || limit > params.omega {
+			return nil, nil, false
+		}
+		first := index
+		for index < limit {
+			if index > first && hintEncoded[index-1] >= hintEncoded[index] {
+				return nil, nil, false
+			}
+			hint[i][hintEncoded[index]] = 1
+			index++
+		}
+	}
+	for i := index; i < params.omega; i++ {
+		if hintEncoded[i] != 0 {
+			return nil, nil, false
+		}
+	}
+	return z, hint, true
+}
+
+// encodeW1 implements FIPS 204 Algorithm 28 w1Encode.
+func encodeW1(params *Parameters, w1 []ringElement, out []byte) {
+	bits := w1Bits(params)
+	for i := range w1 {
+		copy(out[32*bits*i:], packSimple(&w1[i], bits))
+	}
+}
+
+// w1Bits returns the bit length of (q-1)/(2*gamma2) - 1.
+func w1Bits(params *Parameters) int {
+	return bitLength((q-1)/(2*params.gamma2) - 1)
+}
+
+// expandA implements FIPS 204 Algorithm 32 ExpandA, returning the matrix in the NTT domain.
+func expandA(params *Parameters, rho []byte) [][]ringElement {
+	matrix := make([][]ringElement, params.k)
+	for r := range matrix {
+		matrix[r] = make([]ringElement, params.l)
+		for s := range matrix[r] {
+			matrix[r][s] = rejectionSampleNTT(rho, byte(s), byte(r))
+		}
+	}
+	return matrix
+}
+
+// rejectionSampleNTT implements FIPS 204 Algorithm 30 RejNTTPoly.
+func rejectionSampleNTT(rho []byte, column, row byte) ringElement {
+	h := sha3.NewSHAKE128()
+	_, _ = h.Write(rho)
+	_, _ = h.Write([]byte{column, row})
+	var element ringElement
+	var buffer [168]byte // SHAKE128 rate
+	for j := 0; j < n; {
+		_, _ = h.Read(buffer[:])
+		for offset := 0; offset+3 <= len(buffer) && j < n; offset += 3 {
+			candidate := uint32(buffer[offset]) | uint32(buffer[offset+1])<<8 | uint32(buffer[offset+2]&0x7f)<<16
+			if candidate < q {
+				element[j] = candidate
+				j++
+			}
+		}
+	}
+	return element
+}
+
+// expandMask implements FIPS 204 Algorithm 34 ExpandMask.
+func expandMask(params *Parameters, rho *[64]byte, kappa int, y []ringElement) {
+	bits := 1 + bitLength(params.gamma1-1)
+	buffer := make([]byte, 32*bits)
+	for r := range y {
+		h := sha3.NewSHAKE256()
+		_, _ = h.Write(rho[:])
+		_, _ = h.Write([]byte{byte(kappa + r), byte((kappa + r) >> 8)})
+		_, _ = h.Read(buffer)
+		y[r] = unpackCentered(buffer, bits, uint32(params.gamma1))
+	}
+}
+
+// sampleInBall implements FIPS 204 Algorithm 29 SampleInBall.
+func sampleInBall(params *Parameters, seed []byte) ringElement {
+	h := sha3.NewSHAKE256()
+	_, _ = h.Write(seed)
+	var signBytes [8]byte
+	_, _ = h.Read(signBytes[:])
+	signs := uint64(0)
+	for i := range signBytes {
+		signs |= uint64(signBytes[i]) << (8 * i)
+	}
+	var challenge ringElement
+	var next [1]byte
+	for i := n - params.tau; i < n; i++ {
+		for {
+			_, _ = h.Read(next[:])
+			if int(next[0]) <= i {
+				break
+			}
+		}
+		j := next[0]
+		challenge[i] = challenge[j]
+		challenge[j] = 1
+		if signs&1 == 1 {
+			challenge[j] = q - 1
+		}
+		signs >>= 1
+	}
+	return challenge
+}
+
+// bitLength returns the number of bits needed to represent x.
+func bitLength(x int) int {
+	length := 0
+	for ; x > 0; x >>= 1 {
+		length++
+	}
+	return length
+}
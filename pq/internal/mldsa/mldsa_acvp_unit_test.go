@@ -0,0 +1,91 @@
+package mldsa
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// hexBytes decodes the upper-case hex strings used by the ACVP JSON files.
+type hexBytes []byte
+
+func (h *hexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(s)
+	*h = decoded
+	return err
+}
+
+var acvpParameterSets = map[string]*Parameters{"ML-DSA-44": MLDSA44, "ML-DSA-65": MLDSA65, "ML-DSA-87": MLDSA87}
+
+// internalMu computes mu for the ACVP internal interface, where the test message is M' itself.
+func internalMu(tr []byte, message []byte) *[MuSize]byte {
+	var mu [MuSize]byte
+	h := NewMessageHash(tr)
+	_, _ = h.Write(message)
+	_, _ = h.Read(mu[:])
+	return &mu
+}
+
+func readACVPVectors(t *testing.T, path string, vectors any) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "failed to read %s", path)
+	require.NoError(t, json.Unmarshal(data, vectors), "failed to parse %s", path)
+}
+
+func TestACVPSigGen(t *testing.T) {
+	var vectors struct {
+		Tests []struct {
+			TcID          int      `json:"tcId"`
+			ParameterSet  string   `json:"parameterSet"`
+			PrivateKey    hexBytes `json:"sk"`
+			Message       hexBytes `json:"message"`
+			Random        hexBytes `json:"rnd"`
+			SignatureWant hexBytes `json:"signature"`
+		} `json:"tests"`
+	}
+	readACVPVectors(t, "testdata/acvp_siggen.json", &vectors)
+	require.NotEmpty(t, vectors.Tests, "expected sigGen vectors")
+	for _, vector := range vectors.Tests {
+		params := acvpParameterSets[vector.ParameterSet]
+		require.NotNil(t, params, "unknown parameter set %q", vector.ParameterSet)
+		tr, err := PrivateKeyHash(params, vector.PrivateKey)
+		require.NoError(t, err, "tcId %d: failed to read tr", vector.TcID)
+		var random [RandomSize]byte
+		require.Len(t, vector.Random, RandomSize, "tcId %d: unexpected rnd size", vector.TcID)
+		copy(random[:], vector.Random)
+		signature, err := SignMu(params, vector.PrivateKey, internalMu(tr[:], vector.Message), &random)
+		require.NoError(t, err, "tcId %d: failed to sign", vector.TcID)
+		require.Equal(t, []byte(vector.SignatureWant), signature, "tcId %d: unexpected signature", vector.TcID)
+	}
+}
+
+func TestACVPSigVer(t *testing.T) {
+	var vectors struct {
+		Tests []struct {
+			TcID         int      `json:"tcId"`
+			ParameterSet string   `json:"parameterSet"`
+			PublicKey    hexBytes `json:"pk"`
+			Message      hexBytes `json:"message"`
+			Signature    hexBytes `json:"signature"`
+			TestPassed   bool     `json:"testPassed"`
+		} `json:"tests"`
+	}
+	readACVPVectors(t, "testdata/acvp_sigver.json", &vectors)
+	require.NotEmpty(t, vectors.Tests, "expected sigVer vectors")
+	for _, vector := range vectors.Tests {
+		params := acvpParameterSets[vector.ParameterSet]
+		require.NotNil(t, params, "unknown parameter set %q", vector.ParameterSet)
+		tr := PublicKeyHash(vector.PublicKey)
+		isValid, err := VerifyMu(params, vector.PublicKey, internalMu(tr[:], vector.Message), vector.Signature)
+		require.NoError(t, err, "tcId %d: failed to verify", vector.TcID)
+		require.Equal(t, vector.TestPassed, isValid, "tcId %d: unexpected verification result", vector.TcID)
+	}
+}
@@ -0,0 +1,205 @@
+package mldsa
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"github.com/cloudflare/circl/sign"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa87"
+	"github.com/stretchr/testify/require"
+)
+
+var testCases = []struct {
+	params *Parameters
+	scheme sign.Scheme
+}{
+	{MLDSA44, mldsa44.Scheme()},
+	{MLDSA65, mldsa65.Scheme()},
+	{MLDSA87, mldsa87.Scheme()},
+}
+
+// pureMu computes mu for pure ML-DSA (FIPS 204 Algorithm 2, line 10): M' = 0 || len(ctx) || ctx || M.
+func pureMu(tr []byte, message, context []byte) *[MuSize]byte {
+	var mu [MuSize]byte
+	h := NewMessageHash(tr)
+	_, _ = h.Write([]byte{0, byte(len(context))})
+	_, _ = h.Write(context)
+	_, _ = h.Write(message)
+	_, _ = h.Read(mu[:])
+	return &mu
+}
+
+func deriveTestKey(t *testing.T, scheme sign.Scheme, seedByte byte) (publicKey, privateKey []byte) {
+	t.Helper()
+	seed := make([]byte, scheme.SeedSize())
+	for i := range seed {
+		seed[i] = seedByte + byte(i)
+	}
+	circlPublicKey, circlPrivateKey := scheme.DeriveKey(seed)
+	publicKey, err := circlPublicKey.MarshalBinary()
+	require.NoError(t, err, "failed to marshal public key")
+	privateKey, err = circlPrivateKey.MarshalBinary()
+	require.NoError(t, err, "failed to marshal private key")
+	return publicKey, privateKey
+}
+
+func TestSizesMatchCIRCL(t *testing.T) {
+	for _, testCase := range testCases {
+		require.Equal(t, testCase.scheme.PublicKeySize(), testCase.params.PublicKeySize(), "public key size of %v", testCase.params)
+		require.Equal(t, testCase.scheme.PrivateKeySize(), testCase.params.PrivateKeySize(), "private key size of %v", testCase.params)
+		require.Equal(t, testCase.scheme.SignatureSize(), testCase.params.SignatureSize(), "signature size of %v", testCase.params)
+		require.Equal(t, testCase.scheme.Name(), testCase.params.String(), "name of %v", testCase.params)
+	}
+}
+
+func TestDeterministicSignMuMatchesCIRCL(t *testing.T) {
+	for _, testCase := range testCases {
+		t.Run(testCase.params.String(), func(t *testing.T) {
+			for seedByte := byte(0); seedByte < 4; seedByte++ {
+				publicKey, privateKey := deriveTestKey(t, testCase.scheme, seedByte)
+				circlPrivateKey, err := testCase.scheme.UnmarshalBinaryPrivateKey(privateKey)
+				require.NoError(t, err, "failed to unmarshal private key")
+
+				message := []byte{seedByte, 'm', 's', 'g'}
+				context := []byte("context")
+				expectedSignature := testCase.scheme.Sign(circlPrivateKey, message, &sign.SignatureOpts{Context: string(context)})
+
+				tr, err := PrivateKeyHash(testCase.params, privateKey)
+				require.NoError(t, err, "failed to read tr from private key")
+				require.Equal(t, PublicKeyHash(publicKey), tr, "expected tr in private key to equal H(pk, 64)")
+
+				var zeroRandom [RandomSize]byte
+				signature, err := SignMu(testCase.params, privateKey, pureMu(tr[:], message, context), &zeroRandom)
+				require.NoError(t, err, "failed to sign")
+				require.Equal(t, expectedSignature, signature, "expected deterministic signature identical to CIRCL")
+			}
+		})
+	}
+}
+
+func TestHedgedSignMuVerifiesWithCIRCL(t *testing.T) {
+	for _, testCase := range testCases {
+		t.Run(testCase.params.String(), func(t *testing.T) {
+			publicKey, privateKey := deriveTestKey(t, testCase.scheme, 0x10)
+			circlPublicKey, err := testCase.scheme.UnmarshalBinaryPublicKey(publicKey)
+			require.NoError(t, err, "failed to unmarshal public key")
+
+			tr := PublicKeyHash(publicKey)
+			message := []byte("hedged message")
+			var random [RandomSize]byte
+			_, err = rand.Read(random[:])
+			require.NoError(t, err, "failed to read randomness")
+
+			signature, err := SignMu(testCase.params, privateKey, pureMu(tr[:], message, nil), &random)
+			require.NoError(t, err, "failed to sign")
+			require.Len(t, signature, testCase.params.SignatureSize(), "unexpected signature size")
+			require.True(t, testCase.scheme.Verify(circlPublicKey, message, signature, nil), "expected CIRCL to verify hedged signature")
+		})
+	}
+}
+
+func TestVerifyMu(t *testing.T) {
+	for _, testCase := range testCases {
+		t.Run(testCase.params.String(), func(t *testing.T) {
+			publicKey, privateKey := deriveTestKey(t, testCase.scheme, 0x20)
+			circlPrivateKey, err := testCase.scheme.UnmarshalBinaryPrivateKey(privateKey)
+			require.NoError(t, err, "failed to unmarshal private key")
+
+			tr := PublicKeyHash(publicKey)
+			message := []byte("verified message")
+			signature := testCase.scheme.Sign(circlPrivateKey, message, nil)
+
+			isValid, err := VerifyMu(testCase.params, publicKey, pureMu(tr[:], message, nil), signature)
+			require.NoError(t, err, "failed to verify")
+			require.True(t, isValid, "expected CIRCL signature to verify")
+
+			isValid, err = VerifyMu(testCase.params, publicKey, pureMu(tr[:], []byte("other message"), nil), signature)
+			require.NoError(t, err, "failed to verify")
+			require.False(t, isValid, "expected signature over a different message to fail")
+
+			isValid, err = VerifyMu(testCase.params, publicKey, pureMu(tr[:], message, []byte("ctx")), signature)
+			require.NoError(t, err, "failed to verify")
+			require.False(t, isValid, "expected signature under a different context to fail")
+
+			for _, offset := range []int{0, testCase.params.lambda / 4, len(signature) - 1} {
+				tampered := append([]byte(nil), signature...)
+				tampered[offset] ^= 0x01
+				isValid, err = VerifyMu(testCase.params, publicKey, pureMu(tr[:], message, nil), tampered)
+				require.NoError(t, err, "failed to verify")
+				require.False(t, isValid, "expected tampered signature at offset %d to fail", offset)
+			}
+
+			isValid, err = VerifyMu(testCase.params, publicKey, pureMu(tr[:], message, nil), signature[1:])
+			require.NoError(t, err, "failed to verify")
+			require.False(t, isValid, "expected truncated signature to fail")
+		})
+	}
+}
+
+func TestInvalidKeySizes(t *testing.T) {
+	var mu [MuSize]byte
+	var random [RandomSize]byte
+	signature, err := SignMu(MLDSA44, make([]byte, 10), &mu, &random)
+	require.ErrorIs(t, err, errPrivateKeySize, "expected private key size error")
+	require.Nil(t, signature, "expected nil signature")
+
+	isValid, err := VerifyMu(MLDSA44, make([]byte, 10), &mu, make([]byte, MLDSA44.SignatureSize()))
+	require.ErrorIs(t, err, errPublicKeySize, "expected public key size error")
+	require.False(t, isValid, "expected invalid result")
+
+	_, err = PrivateKeyHash(MLDSA44, make([]byte, 10))
+	require.ErrorIs(t, err, errPrivateKeySize, "expected private key size error")
+}
+
+func TestOutOfRangePrivateKey(t *testing.T) {
+	for _, testCase := range testCases {
+		t.Run(testCase.params.String(), func(t *testing.T) {
+			_, privateKey := deriveTestKey(t, testCase.scheme, 0x30)
+			etaSize := 32 * bitLength(2*testCase.params.eta)
+			var mu [MuSize]byte
+			var random [RandomSize]byte
+			// The first coefficient of s1, then of s2, packed as all ones: 7 for eta = 2 and 15 for eta = 4, both above 2*eta.
+			for _, offset := range []int{128, 128 + testCase.params.l*etaSize} {
+				malformed := append([]byte(nil), privateKey...)
+				malformed[offset] |= 0x0f
+				_, err := PrivateKeyHash(testCase.params, malformed)
+				require.ErrorIs(t, err, ErrPrivateKeyRange, "expected out-of-range coefficient at offset %d to be rejected", offset)
+				signature, err := SignMu(testCase.params, malformed, &mu, &random)
+				require.ErrorIs(t, err, ErrPrivateKeyRange, "expected out-of-range coefficient at offset %d to be rejected", offset)
+				require.Nil(t, signature, "expected nil signature")
+			}
+
+			// The largest valid packed value, 2*eta, is accepted.
+			malformed := append([]byte(nil), privateKey...)
+			malformed[128] = malformed[128]&^0x0f | byte(2*testCase.params.eta)
+			_, err := SignMu(testCase.params, malformed, &mu, &random)
+			require.NoError(t, err, "expected coefficient -eta to be accepted")
+		})
+	}
+}
+
+func TestDecompose(t *testing.T) {
+	for _, gamma2 := range []int{gamma2Q88, gamma2Q32} {
+		alpha := int64(2 * gamma2)
+		for r := uint32(0); r < q; r++ {
+			r1, r0 := decompose(r, gamma2)
+			// Reference Decompose from FIPS 204 Algorithm 36.
+			expectedR0 := int64(r) % alpha
+			if expectedR0 > alpha/2 {
+				expectedR0 -= alpha
+			}
+			var expectedR1 int64
+			if int64(r)-expectedR0 == q-1 {
+				expectedR1 = 0
+				expectedR0--
+			} else {
+				expectedR1 = (int64(r) - expectedR0) / alpha
+			}
+			if uint32(expectedR1) != r1 || int32(expectedR0) != r0 {
+				require.Failf(t, "decompose mismatch", "r=%d gamma2=%d: got (%d, %d), want (%d, %d)", r, gamma2, r1, r0, expectedR1, expectedR0)
+			}
+		}
+	}
+}
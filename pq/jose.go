@@ -0,0 +1,756 @@
+package pq
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// JOSE support follows RFC 7515 (JWS) and RFC 7517 (JWK) with the ML-DSA algorithms of the JOSE and COSE ML-DSA draft
+// (draft-ietf-cose-dilithium). Keys use the AKP ("algorithm key pair") key type, which binds a key to the single
+// algorithm in its "alg" member:
+//
+//	{"kty": "AKP", "alg": "ML-DSA-65", "kid": ..., "pub": base64url(public key), "priv": base64url(32-byte seed)}
+//
+// A JWS signature is pure ML-DSA with an empty context over the ASCII signing input
+// base64url(protected header) || "." || base64url(payload). JWE key agreement algorithms and keys are in jwe.go.
+
+// JOSEAlgorithm is the "alg" value of a JWS, JWE or JWK.
+type JOSEAlgorithm string
+
+const (
+	// JOSEMLDSA44 signs with ML-DSA-44.
+	JOSEMLDSA44 JOSEAlgorithm = "ML-DSA-44"
+	// JOSEMLDSA65 signs with ML-DSA-65.
+	JOSEMLDSA65 JOSEAlgorithm = "ML-DSA-65"
+	// JOSEMLDSA87 signs with ML-DSA-87.
+	JOSEMLDSA87 JOSEAlgorithm = "ML-DSA-87"
+)
+
+// joseKeyTypeAKP is the "kty" of algorithm key pairs.
+const joseKeyTypeAKP = "AKP"
+
+// joseBase64 is the unpadded base64url encoding of JOSE, which rejects non-zero trailing bits.
+var joseBase64 = base64.RawURLEncoding.Strict()
+
+// mldsaParameterSet returns the ML-DSA parameter set of a signature algorithm.
+func (algorithm JOSEAlgorithm) mldsaParameterSet() (MLDSAParameterSet, bool) {
+	switch algorithm {
+	case JOSEMLDSA44:
+		return MLDSA44, true
+	case JOSEMLDSA65:
+		return MLDSA65, true
+	case JOSEMLDSA87:
+		return MLDSA87, true
+	default:
+		return 0, false
+	}
+}
+
+// joseAlgorithmOfMLDSA returns the signature algorithm of an ML-DSA parameter set.
+func joseAlgorithmOfMLDSA(parameterSet MLDSAParameterSet) (JOSEAlgorithm, error) {
+	switch parameterSet {
+	case MLDSA44:
+		return JOSEMLDSA44, nil
+	case MLDSA65:
+		return JOSEMLDSA65, nil
+	case MLDSA87:
+		return JOSEMLDSA87, nil
+	default:
+		return "", fmt.Errorf("%w: ML-DSA parameter set %v", ErrUnknownAlgorithm, parameterSet)
+	}
+}
+
+// newJOSEFormatError returns a *FormatError matching ErrInvalidJOSE.
+func newJOSEFormatError(offset int, reason string, arguments ...any) error {
+	return &FormatError{Err: ErrInvalidJOSE, Offset: offset, Reason: fmt.Sprintf(reason, arguments...)}
+}
+
+// newJOSEJSONError returns a *FormatError for a JSON value at offset that encoding/json rejected.
+func newJOSEJSONError(offset int, name string, err error) error {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxError):
+		return newJOSEFormatError(offset+int(syntaxError.Offset), "malformed %s: %v", name, syntaxError)
+	case errors.As(err, &typeError):
+		return newJOSEFormatError(offset+int(typeError.Offset), "malformed %s: %v", name, typeError)
+	default:
+		return newJOSEFormatError(offset, "malformed %s: %v", name, err)
+	}
+}
+
+// decodeJOSEBase64 decodes a base64url value at offset. Unlike base64.Encoding, it rejects line breaks.
+func decodeJOSEBase64(encoded string, offset int, name string) ([]byte, error) {
+	if index := strings.IndexAny(encoded, "\r\n"); index >= 0 {
+		return nil, newJOSEFormatError(offset+index, "line break in %s", name)
+	}
+	decoded, err := joseBase64.DecodeString(encoded)
+	if err != nil {
+		var corruptInputError base64.CorruptInputError
+		if errors.As(err, &corruptInputError) {
+			offset += int(corruptInputError)
+		}
+		return nil, newJOSEFormatError(offset, "malformed base64url %s", name)
+	}
+	return decoded, nil
+}
+
+// JWK is a JSON Web Key of the AKP key type. It implements json.Marshaler and json.Unmarshaler.
+type JWK struct {
+	// KeyID is the optional "kid" member. JWKSet finds the key of a JWS by it.
+	KeyID string
+	// Use is the optional "use" member, for example "sig".
+	Use string
+	// Algorithm is the "alg" member, the only algorithm the key may be used with.
+	Algorithm JOSEAlgorithm
+	// PublicKey is the *MLDSAPublicKey of an ML-DSA key, or the kem.PublicKey of an ML-KEM or X-Wing key.
+	PublicKey crypto.PublicKey
+	// PrivateKey is the *MLDSAPrivateKey of an ML-DSA key, the *MLKEMKeyPair of an ML-KEM key or the *XWingKeyPair of an
+	// X-Wing key, or nil for a public key.
+	// It is exported as its seed, so a private key parsed from its expanded form cannot be marshaled.
+	PrivateKey crypto.PrivateKey
+}
+
+// jwkJSON is the JSON encoding of a JWK.
+type jwkJSON struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid,omitempty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg"`
+	Public    string `json:"pub"`
+	Private   string `json:"priv,omitempty"`
+}
+
+// jwkThumbprintJSON holds the required members of an AKP key, in the lexicographic order of RFC 7638.
+type jwkThumbprintJSON struct {
+	Algorithm string `json:"alg"`
+	KeyType   string `json:"kty"`
+	Public    string `json:"pub"`
+}
+
+// NewMLDSAJWK returns the JWK of an ML-DSA private key.
+func NewMLDSAJWK(privateKey *MLDSAPrivateKey, keyID string) (*JWK, error) {
+	if privateKey == nil {
+		return nil, fmt.Errorf("%w: ML-DSA private key is nil", ErrInvalidKey)
+	}
+	jwk, err := NewMLDSAPublicJWK(privateKey.PublicKey(), keyID)
+	if err != nil {
+		return nil, err
+	}
+	jwk.PrivateKey = privateKey
+	return jwk, nil
+}
+
+// NewMLDSAPublicJWK returns the JWK of an ML-DSA public key.
+func NewMLDSAPublicJWK(publicKey *MLDSAPublicKey, keyID string) (*JWK, error) {
+	if publicKey == nil {
+		return nil, fmt.Errorf("%w: ML-DSA public key is nil", ErrInvalidKey)
+	}
+	algorithm, err := joseAlgorithmOfMLDSA(publicKey.ParameterSet())
+	if err != nil {
+		return nil, err
+	}
+	return &JWK{KeyID: keyID, Use: "sig", Algorithm: algorithm, PublicKey: publicKey}, nil
+}
+
+// Public returns a copy of the key without its private key.
+func (jwk *JWK) Public() *JWK {
+	return &JWK{KeyID: jwk.KeyID, Use: jwk.Use, Algorithm: jwk.Algorithm, PublicKey: jwk.PublicKey}
+}
+
+// IsPrivate reports whether the key has its private key.
+func (jwk *JWK) IsPrivate() bool {
+	return jwk.PrivateKey != nil
+}
+
+// keyMaterial returns the encoded public key and the private key seed, or nil for a public key, after checking that
+// both keys belong to the algorithm.
+func (jwk *JWK) keyMaterial() (publicKeyBytes []byte, seed []byte, err error) {
+	if parameterSet, ok := jwk.Algorithm.mldsaParameterSet(); ok {
+		publicKey, privateKey, err := jwk.mldsaKeys(parameterSet)
+		if err != nil {
+			return nil, nil, err
+		}
+		if privateKey != nil {
+			if seed = privateKey.Seed(); seed == nil {
+				return nil, nil, fmt.Errorf("%w: JWK %q: %v private key has no seed", ErrInvalidKey, jwk.KeyID, parameterSet)
+			}
+		}
+		return publicKey.Bytes(), seed, nil
+	}
+	if keyManagement, ok := jwk.Algorithm.jweKeyManagement(); ok {
+		publicKey, _, seed, err := jwk.kemKeys(keyManagement)
+		if err != nil {
+			return nil, nil, err
+		}
+		if jwk.PrivateKey != nil && seed == nil {
+			return nil, nil, fmt.Errorf("%w: JWK %q: %v private key has no seed", ErrInvalidKey, jwk.KeyID, keyManagement)
+		}
+		publicKeyBytes, err := publicKey.MarshalBinary()
+		if err != nil {
+			return nil, nil, err
+		}
+		return publicKeyBytes, seed, nil
+	}
+	return nil, nil, fmt.Errorf("%w: JWK algorithm %q", ErrUnknownAlgorithm, jwk.Algorithm)
+}
+
+// mldsaKeys returns the keys of an ML-DSA JWK; the private key is nil for a public key.
+func (jwk *JWK) mldsaKeys(parameterSet MLDSAParameterSet) (*MLDSAPublicKey, *MLDSAPrivateKey, error) {
+	publicKey, ok := jwk.PublicKey.(*MLDSAPublicKey)
+	if !ok || publicKey == nil || publicKey.ParameterSet() != parameterSet {
+		return nil, nil, fmt.Errorf("%w: JWK %q: %s key needs an %v public key, got %T", ErrInvalidKey, jwk.KeyID, jwk.Algorithm, parameterSet, jwk.PublicKey)
+	}
+	if jwk.PrivateKey == nil {
+		return publicKey, nil, nil
+	}
+	privateKey, ok := jwk.PrivateKey.(*MLDSAPrivateKey)
+	if !ok || privateKey == nil || !privateKey.PublicKey().Equal(publicKey) {
+		return nil, nil, fmt.Errorf("%w: JWK %q: private key does not belong to the %v public key", ErrInvalidKey, jwk.KeyID, parameterSet)
+	}
+	return publicKey, privateKey, nil
+}
+
+// MarshalJSON returns the JSON encoding of the key, with the "priv" member only if the key has its private key.
+func (jwk *JWK) MarshalJSON() ([]byte, error) {
+	publicKeyBytes, seed, err := jwk.keyMaterial()
+	if err != nil {
+		return nil, err
+	}
+	encoded := jwkJSON{KeyType: joseKeyTypeAKP, KeyID: jwk.KeyID, Use: jwk.Use, Algorithm: string(jwk.Algorithm), Public: joseBase64.EncodeToString(publicKeyBytes)}
+	if seed != nil {
+		encoded.Private = joseBase64.EncodeToString(seed)
+	}
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON parses the key like ParseJWK.
+func (jwk *JWK) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseJWK(data)
+	if err != nil {
+		return err
+	}
+	*jwk = *parsed
+	return nil
+}
+
+// Thumbprint returns the RFC 7638 SHA-256 thumbprint of the key, computed over its "alg", "kty" and "pub" members.
+func (jwk *JWK) Thumbprint() ([]byte, error) {
+	publicKeyBytes, _, err := jwk.keyMaterial()
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(jwkThumbprintJSON{Algorithm: string(jwk.Algorithm), KeyType: joseKeyTypeAKP, Public: joseBase64.EncodeToString(publicKeyBytes)})
+	if err != nil {
+		return nil, err
+	}
+	thumbprint := sha256.Sum256(encoded)
+	return thumbprint[:], nil
+}
+
+// ParseJWK parses an AKP JSON Web Key. A key with a "priv" member is a private key, whose seed must derive the "pub"
+// member. A key of another type or algorithm returns an error matching ErrUnknownAlgorithm, and a malformed key returns
+// a *FormatError matching ErrInvalidJOSE.
+func ParseJWK(data []byte) (jwk *JWK, parseError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			jwk, parseError = nil, newPanicError("ParseJWK", recoveredPanic)
+		}
+	}()
+	var encoded jwkJSON
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, newJOSEJSONError(0, "JWK", err)
+	}
+	if encoded.KeyType != joseKeyTypeAKP {
+		return nil, fmt.Errorf("%w: JWK key type %q", ErrUnknownAlgorithm, encoded.KeyType)
+	}
+	publicKeyBytes, err := decodeJOSEBase64(encoded.Public, 0, "JWK pub")
+	if err != nil {
+		return nil, err
+	}
+	var seed []byte
+	if encoded.Private != "" {
+		if seed, err = decodeJOSEBase64(encoded.Private, 0, "JWK priv"); err != nil {
+			return nil, err
+		}
+	}
+	jwk = &JWK{KeyID: encoded.KeyID, Use: encoded.Use, Algorithm: JOSEAlgorithm(encoded.Algorithm)}
+	if parameterSet, ok := jwk.Algorithm.mldsaParameterSet(); ok {
+		if jwk.PublicKey, err = NewMLDSAPublicKey(parameterSet, publicKeyBytes); err != nil {
+			return nil, fmt.Errorf("JWK %q: %w", jwk.KeyID, err)
+		}
+		if seed != nil {
+			privateKey, err := NewMLDSAPrivateKeyFromSeed(parameterSet, seed)
+			if err != nil {
+				return nil, fmt.Errorf("JWK %q: %w", jwk.KeyID, err)
+			}
+			if !privateKey.PublicKey().Equal(jwk.PublicKey) {
+				return nil, newJOSEFormatError(0, "JWK %q: pub does not match the public key of priv", jwk.KeyID)
+			}
+			jwk.PrivateKey = privateKey
+		}
+		Logger().Debug("ParseJWK", "algorithm", encoded.Algorithm, "private", seed != nil)
+		return jwk, nil
+	}
+	if keyManagement, ok := jwk.Algorithm.jweKeyManagement(); ok {
+		if err := keyManagement.setKeys(jwk, publicKeyBytes, seed); err != nil {
+			return nil, err
+		}
+		Logger().Debug("ParseJWK", "algorithm", encoded.Algorithm, "private", seed != nil)
+		return jwk, nil
+	}
+	return nil, fmt.Errorf("%w: JWK algorithm %q", ErrUnknownAlgorithm, encoded.Algorithm)
+}
+
+// JWKSet is a JSON Web Key Set. It implements json.Marshaler and json.Unmarshaler.
+type JWKSet struct {
+	// Keys are the keys of the set.
+	Keys []*JWK
+}
+
+// jwkSetJSON is the JSON encoding of a JWKSet; the keys are parsed one by one.
+type jwkSetJSON struct {
+	Keys []json.RawMessage `json:"keys"`
+}
+
+// ParseJWKSet parses a JSON Web Key Set. As RFC 7517 recommends, keys of other types or algorithms, for example RSA or
+// EC keys, are skipped, but a malformed AKP key fails the whole set.
+func ParseJWKSet(data []byte) (set *JWKSet, parseError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			set, parseError = nil, newPanicError("ParseJWKSet", recoveredPanic)
+		}
+	}()
+	var encoded jwkSetJSON
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, newJOSEJSONError(0, "JWK set", err)
+	}
+	if encoded.Keys == nil {
+		return nil, newJOSEFormatError(0, "JWK set has no keys member")
+	}
+	set = &JWKSet{}
+	for index, encodedKey := range encoded.Keys {
+		jwk, err := ParseJWK(encodedKey)
+		if errors.Is(err, ErrUnknownAlgorithm) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("JWK set key %d: %w", index, err)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	Logger().Debug("ParseJWKSet", "keys", len(encoded.Keys), "supported_keys", len(set.Keys))
+	return set, nil
+}
+
+// MarshalJSON returns the JSON encoding of the set.
+func (set *JWKSet) MarshalJSON() ([]byte, error) {
+	keys := set.Keys
+	if keys == nil {
+		keys = []*JWK{}
+	}
+	return json.Marshal(struct {
+		Keys []*JWK `json:"keys"`
+	}{keys})
+}
+
+// UnmarshalJSON parses the set like ParseJWKSet.
+func (set *JWKSet) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseJWKSet(data)
+	if err != nil {
+		return err
+	}
+	*set = *parsed
+	return nil
+}
+
+// Public returns a copy of the set without private keys, for publishing.
+func (set *JWKSet) Public() *JWKSet {
+	public := &JWKSet{Keys: make([]*JWK, len(set.Keys))}
+	for index, jwk := range set.Keys {
+		public.Keys[index] = jwk.Public()
+	}
+	return public
+}
+
+// Key returns the first key with the key ID, or an error matching ErrKeyNotFound.
+func (set *JWKSet) Key(keyID string) (*JWK, error) {
+	for _, jwk := range set.Keys {
+		if jwk.KeyID == keyID {
+			return jwk, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no JWK with key ID %q", ErrKeyNotFound, keyID)
+}
+
+// verificationKey returns the key a JWS signature names by its key ID and algorithm, or nil.
+func (set *JWKSet) verificationKey(header *JWSHeader) *JWK {
+	if header.KeyID == "" {
+		return nil
+	}
+	for _, jwk := range set.Keys {
+		if jwk.KeyID == header.KeyID && jwk.Algorithm == header.Algorithm {
+			return jwk
+		}
+	}
+	return nil
+}
+
+// JWSHeader holds the header parameters of a JWS that this package understands. Other parameters are ignored, except
+// "crit", which is rejected because no extension is supported.
+type JWSHeader struct {
+	// Algorithm is the "alg" parameter. It is always integrity protected.
+	Algorithm JOSEAlgorithm `json:"alg"`
+	// KeyID is the "kid" parameter, which JWKSet uses to find the verification key.
+	KeyID string `json:"kid,omitempty"`
+	// Type is the "typ" parameter, for example "JWT".
+	Type string `json:"typ,omitempty"`
+	// ContentType is the "cty" parameter.
+	ContentType string `json:"cty,omitempty"`
+}
+
+// jwsSignature is a parsed signature of a JWS, with the merged header and the signing input it covers.
+type jwsSignature struct {
+	header       *JWSHeader
+	signingInput []byte
+	signature    []byte
+}
+
+// jwsJSON is the general and flattened JWS JSON serialization.
+type jwsJSON struct {
+	Payload    *string            `json:"payload"`
+	Signatures []jwsJSONSignature `json:"signatures,omitempty"`
+	jwsJSONSignature
+}
+
+// jwsJSONSignature is a signature of the JWS JSON serialization.
+type jwsJSONSignature struct {
+	Protected string                     `json:"protected,omitempty"`
+	Header    map[string]json.RawMessage `json:"header,omitempty"`
+	Signature *string                    `json:"signature,omitempty"`
+}
+
+// encodeJWSProtectedHeader returns the base64url protected header of a signature by jwk, with the algorithm of the key and,
+// unless header names one, its key ID.
+func encodeJWSProtectedHeader(jwk *JWK, header *JWSHeader) (string, error) {
+	var protected JWSHeader
+	if header != nil {
+		protected = *header
+	}
+	if protected.Algorithm != "" && protected.Algorithm != jwk.Algorithm {
+		return "", fmt.Errorf("%w: JWS algorithm %q does not match the %q key", ErrInvalidOptions, protected.Algorithm, jwk.Algorithm)
+	}
+	protected.Algorithm = jwk.Algorithm
+	if protected.KeyID == "" {
+		protected.KeyID = jwk.KeyID
+	}
+	encoded, err := json.Marshal(protected)
+	if err != nil {
+		return "", err
+	}
+	return joseBase64.EncodeToString(encoded), nil
+}
+
+// signJWS signs a signing input with a private JWK.
+func signJWS(jwk *JWK, signingInput string) ([]byte, error) {
+	if jwk == nil || jwk.PrivateKey == nil {
+		return nil, fmt.Errorf("%w: signing a JWS needs a private JWK", ErrInvalidKey)
+	}
+	parameterSet, ok := jwk.Algorithm.mldsaParameterSet()
+	if !ok {
+		return nil, fmt.Errorf("%w: JWK algorithm %q cannot sign", ErrUnknownAlgorithm, jwk.Algorithm)
+	}
+	_, privateKey, err := jwk.mldsaKeys(parameterSet)
+	if err != nil {
+		return nil, err
+	}
+	return privateKey.Sign(rand.Reader, []byte(signingInput), nil)
+}
+
+// verifyJWSSignature reports whether a signature was made by jwk with the algorithm of the key.
+func verifyJWSSignature(jwk *JWK, signature *jwsSignature) (bool, error) {
+	parameterSet, ok := jwk.Algorithm.mldsaParameterSet()
+	if !ok || signature.header.Algorithm != jwk.Algorithm {
+		return false, nil
+	}
+	publicKey, _, err := jwk.mldsaKeys(parameterSet)
+	if err != nil {
+		return false, err
+	}
+	return publicKey.Verify(signature.signingInput, signature.signature, nil)
+}
+
+// verifyJWSSignatures returns the header of the first signature that verifies with the key findKey returns for it. It
+// returns ErrKeyNotFound if findKey finds no key at all, and ErrVerificationFailed if no signature verifies.
+func verifyJWSSignatures(signatures []jwsSignature, findKey func(header *JWSHeader) *JWK) (*JWSHeader, error) {
+	keyFound := false
+	for index := range signatures {
+		jwk := findKey(signatures[index].header)
+		if jwk == nil {
+			continue
+		}
+		keyFound = true
+		valid, err := verifyJWSSignature(jwk, &signatures[index])
+		if err != nil {
+			return nil, err
+		}
+		if valid {
+			return signatures[index].header, nil
+		}
+	}
+	if !keyFound {
+		keyIDs := make([]string, len(signatures))
+		for index := range signatures {
+			keyIDs[index] = signatures[index].header.KeyID
+		}
+		return nil, fmt.Errorf("%w: no JWK for JWS key IDs %q", ErrKeyNotFound, keyIDs)
+	}
+	return nil, fmt.Errorf("%w: no JWS signature verifies", ErrVerificationFailed)
+}
+
+// parseJWSHeader parses the base64url protected header at offset and merges the unprotected header into it. The
+// algorithm must be protected, and the two headers must not share a parameter.
+func parseJWSHeader(encodedProtected string, offset int, unprotected map[string]json.RawMessage) (*JWSHeader, error) {
+	protectedJSON, err := decodeJOSEBase64(encodedProtected, offset, "JWS protected header")
+	if err != nil {
+		return nil, err
+	}
+	var protected map[string]json.RawMessage
+	if err := json.Unmarshal(protectedJSON, &protected); err != nil || protected == nil {
+		return nil, newJOSEFormatError(offset, "JWS protected header is not a JSON object")
+	}
+	if _, ok := protected["alg"]; !ok {
+		return nil, newJOSEFormatError(offset, "JWS protected header has no alg")
+	}
+	for name := range unprotected {
+		if _, ok := protected[name]; ok {
+			return nil, newJOSEFormatError(offset, "JWS header parameter %q is both protected and unprotected", name)
+		}
+	}
+	if _, ok := protected["crit"]; ok {
+		return nil, newJOSEFormatError(offset, "unsupported critical JWS header parameters")
+	}
+	if _, ok := unprotected["crit"]; ok {
+		return nil, newJOSEFormatError(offset, "crit must be integrity protected")
+	}
+	header := &JWSHeader{}
+	if err := json.Unmarshal(protectedJSON, header); err != nil {
+		return nil, newJOSEFormatError(offset, "malformed JWS protected header: %v", err)
+	}
+	if unprotected != nil {
+		unprotectedJSON, err := json.Marshal(unprotected)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(unprotectedJSON, header); err != nil {
+			return nil, newJOSEFormatError(offset, "malformed JWS unprotected header: %v", err)
+		}
+	}
+	return header, nil
+}
+
+// parseJWSCompact parses a JWS in the compact serialization.
+func parseJWSCompact(jws string) (payload []byte, signature *jwsSignature, err error) {
+	segments := strings.Split(jws, ".")
+	if len(segments) != 3 {
+		return nil, nil, newJOSEFormatError(0, "compact JWS has %d segments, want 3", len(segments))
+	}
+	payloadOffset := len(segments[0]) + 1
+	signatureOffset := payloadOffset + len(segments[1]) + 1
+	header, err := parseJWSHeader(segments[0], 0, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if payload, err = decodeJOSEBase64(segments[1], payloadOffset, "JWS payload"); err != nil {
+		return nil, nil, err
+	}
+	signatureBytes, err := decodeJOSEBase64(segments[2], signatureOffset, "JWS signature")
+	if err != nil {
+		return nil, nil, err
+	}
+	return payload, &jwsSignature{header: header, signingInput: []byte(jws[:signatureOffset-1]), signature: signatureBytes}, nil
+}
+
+// parseJWSJSON parses a JWS in the general or flattened JSON serialization.
+func parseJWSJSON(data []byte) (payload []byte, signatures []jwsSignature, err error) {
+	var encoded jwsJSON
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, nil, newJOSEJSONError(0, "JWS JSON serialization", err)
+	}
+	if encoded.Payload == nil {
+		return nil, nil, newJOSEFormatError(0, "JWS JSON serialization has no payload")
+	}
+	flattened := encoded.Signature != nil || encoded.Protected != "" || encoded.Header != nil
+	switch {
+	case flattened && encoded.Signatures != nil:
+		return nil, nil, newJOSEFormatError(0, "JWS JSON serialization is both general and flattened")
+	case flattened:
+		encoded.Signatures = []jwsJSONSignature{encoded.jwsJSONSignature}
+	case len(encoded.Signatures) == 0:
+		return nil, nil, newJOSEFormatError(0, "JWS JSON serialization has no signatures")
+	}
+	if payload, err = decodeJOSEBase64(*encoded.Payload, 0, "JWS payload"); err != nil {
+		return nil, nil, err
+	}
+	for index, encodedSignature := range encoded.Signatures {
+		if encodedSignature.Signature == nil {
+			return nil, nil, newJOSEFormatError(0, "JWS signature %d has no signature member", index)
+		}
+		header, err := parseJWSHeader(encodedSignature.Protected, 0, encodedSignature.Header)
+		if err != nil {
+			return nil, nil, fmt.Errorf("JWS signature %d: %w", index, err)
+		}
+		signatureBytes, err := decodeJOSEBase64(*encodedSignature.Signature, 0, "JWS signature")
+		if err != nil {
+			return nil, nil, fmt.Errorf("JWS signature %d: %w", index, err)
+		}
+		signingInput := []byte(encodedSignature.Protected + "." + *encoded.Payload)
+		signatures = append(signatures, jwsSignature{header: header, signingInput: signingInput, signature: signatureBytes})
+	}
+	return payload, signatures, nil
+}
+
+// SignJWS signs payload with a private JWK and returns the JWS in the compact serialization. The protected header holds
+// the algorithm of the key, the key ID of the key unless header names another one, and the other parameters of header,
+// which may be nil.
+func SignJWS(key *JWK, payload []byte, header *JWSHeader) (jws string, signError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			jws, signError = "", newPanicError("SignJWS", recoveredPanic)
+		}
+	}()
+	if key == nil {
+		return "", fmt.Errorf("%w: signing a JWS needs a private JWK", ErrInvalidKey)
+	}
+	encodedHeader, err := encodeJWSProtectedHeader(key, header)
+	if err != nil {
+		return "", err
+	}
+	signingInput := encodedHeader + "." + joseBase64.EncodeToString(payload)
+	signature, err := signJWS(key, signingInput)
+	if err != nil {
+		return "", err
+	}
+	Logger().Debug("SignJWS", "algorithm", string(key.Algorithm), "payload_size", len(payload), "signature_size", len(signature))
+	return signingInput + "." + joseBase64.EncodeToString(signature), nil
+}
+
+// VerifyJWS verifies a JWS in the compact serialization with a JWK and returns its payload and header. A malformed JWS
+// returns a *FormatError matching ErrInvalidJOSE, and a signature that does not verify, including one with another
+// algorithm than the key, returns ErrVerificationFailed.
+func VerifyJWS(key *JWK, jws string) (payload []byte, header *JWSHeader, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			payload, header, verifyError = nil, nil, newPanicError("VerifyJWS", recoveredPanic)
+		}
+	}()
+	if key == nil {
+		return nil, nil, fmt.Errorf("%w: verifying a JWS needs a JWK", ErrInvalidKey)
+	}
+	return verifyJWSCompact(jws, func(*JWSHeader) *JWK { return key })
+}
+
+// VerifyJWS verifies a JWS in the compact serialization with the key of the set that has its key ID and algorithm. A JWS
+// without a key ID, or whose key is not in the set, returns ErrKeyNotFound; otherwise it behaves like VerifyJWS.
+func (set *JWKSet) VerifyJWS(jws string) (payload []byte, header *JWSHeader, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			payload, header, verifyError = nil, nil, newPanicError("JWKSet.VerifyJWS", recoveredPanic)
+		}
+	}()
+	return verifyJWSCompact(jws, set.verificationKey)
+}
+
+// verifyJWSCompact verifies a compact JWS with the key findKey returns for it.
+func verifyJWSCompact(jws string, findKey func(header *JWSHeader) *JWK) ([]byte, *JWSHeader, error) {
+	payload, signature, err := parseJWSCompact(jws)
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err := verifyJWSSignatures([]jwsSignature{*signature}, findKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	Logger().Debug("VerifyJWS", "algorithm", string(header.Algorithm), "payload_size", len(payload))
+	return payload, header, nil
+}
+
+// SignJWSJSON signs payload with every private JWK and returns the JWS in the general JSON serialization, with one
+// signature per key. Every protected header is made as by SignJWS.
+func SignJWSJSON(keys []*JWK, payload []byte, header *JWSHeader) (jws []byte, signError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			jws, signError = nil, newPanicError("SignJWSJSON", recoveredPanic)
+		}
+	}()
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: a JWS needs at least one signing key", ErrInvalidOptions)
+	}
+	encodedPayload := joseBase64.EncodeToString(payload)
+	encoded := jwsJSON{Payload: &encodedPayload, Signatures: make([]jwsJSONSignature, len(keys))}
+	for index, key := range keys {
+		if key == nil {
+			return nil, fmt.Errorf("%w: signing a JWS needs a private JWK", ErrInvalidKey)
+		}
+		encodedHeader, err := encodeJWSProtectedHeader(key, header)
+		if err != nil {
+			return nil, err
+		}
+		signature, err := signJWS(key, encodedHeader+"."+encodedPayload)
+		if err != nil {
+			return nil, err
+		}
+		encodedSignature := joseBase64.EncodeToString(signature)
+		encoded.Signatures[index] = jwsJSONSignature{Protected: encodedHeader, Signature: &encodedSignature}
+	}
+	Logger().Debug("SignJWSJSON", "signatures", len(keys), "payload_size", len(payload))
+	return json.Marshal(encoded)
+}
+
+// VerifyJWSJSON verifies a JWS in the general or flattened JSON serialization with a JWK. It returns the payload and the
+// header of the first signature that verifies, and ErrVerificationFailed if none does. Unprotected header parameters are
+// merged into the returned header, but the algorithm must be protected.
+func VerifyJWSJSON(key *JWK, jws []byte) (payload []byte, header *JWSHeader, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			payload, header, verifyError = nil, nil, newPanicError("VerifyJWSJSON", recoveredPanic)
+		}
+	}()
+	if key == nil {
+		return nil, nil, fmt.Errorf("%w: verifying a JWS needs a JWK", ErrInvalidKey)
+	}
+	return verifyJWSJSON(jws, func(*JWSHeader) *JWK { return key })
+}
+
+// VerifyJWSJSON verifies a JWS in the general or flattened JSON serialization with the keys of the set, which are found
+// by the key ID and algorithm of each signature. A JWS none of whose key IDs is in the set returns ErrKeyNotFound;
+// otherwise it behaves like VerifyJWSJSON.
+func (set *JWKSet) VerifyJWSJSON(jws []byte) (payload []byte, header *JWSHeader, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			payload, header, verifyError = nil, nil, newPanicError("JWKSet.VerifyJWSJSON", recoveredPanic)
+		}
+	}()
+	return verifyJWSJSON(jws, set.verificationKey)
+}
+
+// verifyJWSJSON verifies a JSON-serialized JWS with the keys findKey returns for its signatures.
+func verifyJWSJSON(jws []byte, findKey func(header *JWSHeader) *JWK) ([]byte, *JWSHeader, error) {
+	payload, signatures, err := parseJWSJSON(bytes.TrimSpace(jws))
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err := verifyJWSSignatures(signatures, findKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	Logger().Debug("VerifyJWSJSON", "algorithm", string(header.Algorithm), "signatures", len(signatures), "payload_size", len(payload))
+	return payload, header, nil
+}
@@ -0,0 +1,56 @@
+package pq
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// newBenchmarkJWK returns a private ML-DSA-65 JWK.
+func newBenchmarkJWK(b *testing.B) *JWK {
+	b.Helper()
+	privateKey, err := GenerateMLDSAPrivateKey(MLDSA65)
+	if err != nil {
+		b.Fatalf("failed to generate ML-DSA key: %v", err)
+	}
+	jwk, err := NewMLDSAJWK(privateKey, "bench")
+	if err != nil {
+		b.Fatalf("failed to make JWK: %v", err)
+	}
+	return jwk
+}
+
+func BenchmarkSignJWS(b *testing.B) {
+	jwk := newBenchmarkJWK(b)
+	payload := make([]byte, 1024)
+	for b.Loop() {
+		if _, err := SignJWS(jwk, payload, nil); err != nil {
+			b.Fatalf("failed to sign: %v", err)
+		}
+	}
+}
+
+func BenchmarkVerifyJWS(b *testing.B) {
+	jwk := newBenchmarkJWK(b)
+	jws, err := SignJWS(jwk, make([]byte, 1024), nil)
+	if err != nil {
+		b.Fatalf("failed to sign: %v", err)
+	}
+	set := &JWKSet{Keys: []*JWK{jwk.Public()}}
+	for b.Loop() {
+		if _, _, err := set.VerifyJWS(jws); err != nil {
+			b.Fatalf("failed to verify: %v", err)
+		}
+	}
+}
+
+func BenchmarkParseJWK(b *testing.B) {
+	privateJSON, err := json.Marshal(newBenchmarkJWK(b))
+	if err != nil {
+		b.Fatalf("failed to marshal JWK: %v", err)
+	}
+	for b.Loop() {
+		if _, err := ParseJWK(privateJSON); err != nil {
+			b.Fatalf("failed to parse JWK: %v", err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package pq
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func FuzzParseJWK(f *testing.F) {
+	privateKey, _ := GenerateMLDSAPrivateKey(MLDSA44)
+	jwk, _ := NewMLDSAJWK(privateKey, "fuzz")
+	privateJSON, _ := json.Marshal(jwk)
+	publicJSON, _ := json.Marshal(jwk.Public())
+	f.Add(privateJSON)
+	f.Add(publicJSON)
+	f.Add([]byte(`{"kty":"AKP","alg":"ML-DSA-44","pub":""}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parsed, err := ParseJWK(data)
+		if errors.Is(err, ErrInternalPanic) {
+			t.Fatalf("panic parsing JWK: %v", err)
+		}
+		if err != nil {
+			return
+		}
+		encoded, err := json.Marshal(parsed)
+		if err != nil {
+			t.Fatalf("failed to marshal parsed JWK: %v", err)
+		}
+		reparsed, err := ParseJWK(encoded)
+		if err != nil {
+			t.Fatalf("failed to parse marshaled JWK: %v", err)
+		}
+		if !reparsed.PublicKey.(*MLDSAPublicKey).Equal(parsed.PublicKey) {
+			t.Errorf("JWK public key changed in a round trip")
+		}
+	})
+}
+
+func FuzzVerifyJWS(f *testing.F) {
+	privateKey, _ := GenerateMLDSAPrivateKey(MLDSA44)
+	jwk, _ := NewMLDSAJWK(privateKey, "fuzz")
+	jws, _ := SignJWS(jwk, []byte("fuzz"), nil)
+	f.Add(jws)
+	f.Add("..")
+	f.Fuzz(func(t *testing.T, jws string) {
+		payload, _, err := VerifyJWS(jwk, jws)
+		if errors.Is(err, ErrInternalPanic) {
+			t.Fatalf("panic verifying JWS: %v", err)
+		}
+		if err == nil && !bytes.Equal(payload, []byte("fuzz")) {
+			t.Errorf("verified a forged JWS with payload %q", payload)
+		}
+	})
+}
+
+func FuzzVerifyJWSJSON(f *testing.F) {
+	privateKey, _ := GenerateMLDSAPrivateKey(MLDSA44)
+	jwk, _ := NewMLDSAJWK(privateKey, "fuzz")
+	jws, _ := SignJWSJSON([]*JWK{jwk}, []byte("fuzz"), nil)
+	f.Add(jws)
+	f.Add([]byte(`{"payload":"","signature":""}`))
+	f.Fuzz(func(t *testing.T, jws []byte) {
+		payload, _, err := (&JWKSet{Keys: []*JWK{jwk}}).VerifyJWSJSON(jws)
+		if errors.Is(err, ErrInternalPanic) {
+			t.Fatalf("panic verifying JWS: %v", err)
+		}
+		if err == nil && !bytes.Equal(payload, []byte("fuzz")) {
+			t.Errorf("verified a forged JWS with payload %q", payload)
+		}
+	})
+}
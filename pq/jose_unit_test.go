@@ -0,0 +1,407 @@
+package pq
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// For the JOSE fixture tests: testdata/jose holds keys derived from the seed 00 01 ... 1f for each ML-DSA parameter set,
+// as a private and a public JWK set, one JWT signed by each of them and a general JSON JWS signed by all three. gopq
+// made them, so they pin the AKP and JWS encodings against regressions rather than against another implementation.
+const joseTestDirectory = "testdata/jose/"
+
+// readJOSETestFile returns a file of testdata/jose.
+func readJOSETestFile(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(joseTestDirectory + name)
+	require.NoError(t, err)
+	return data
+}
+
+// newTestJWK returns a private JWK of a new key of the parameter set.
+func newTestJWK(t *testing.T, parameterSet MLDSAParameterSet, keyID string) *JWK {
+	t.Helper()
+	privateKey, err := GenerateMLDSAPrivateKey(parameterSet)
+	require.NoError(t, err)
+	jwk, err := NewMLDSAJWK(privateKey, keyID)
+	require.NoError(t, err)
+	return jwk
+}
+
+func TestJOSEFixtureKeys(t *testing.T) {
+	privateSet, err := ParseJWKSet(readJOSETestFile(t, "private-jwks.json"))
+	require.NoError(t, err)
+	publicSet, err := ParseJWKSet(readJOSETestFile(t, "public-jwks.json"))
+	require.NoError(t, err)
+	require.Len(t, privateSet.Keys, 3)
+	require.Len(t, publicSet.Keys, 3)
+
+	seed := make([]byte, MLDSASeedSize)
+	for index := range seed {
+		seed[index] = byte(index)
+	}
+	for index, parameterSet := range MLDSAParameterSets {
+		privateKey, err := NewMLDSAPrivateKeyFromSeed(parameterSet, seed)
+		require.NoError(t, err)
+		jwk := privateSet.Keys[index]
+		require.True(t, jwk.IsPrivate())
+		require.Equal(t, parameterSet.String(), string(jwk.Algorithm))
+		require.True(t, privateKey.Equal(jwk.PrivateKey))
+		require.True(t, privateKey.PublicKey().Equal(publicSet.Keys[index].PublicKey))
+		require.False(t, publicSet.Keys[index].IsPrivate())
+	}
+
+	// Marshaling gives back the fixture byte for byte.
+	encoded, err := json.MarshalIndent(privateSet, "", "  ")
+	require.NoError(t, err)
+	require.Equal(t, string(readJOSETestFile(t, "private-jwks.json")), string(encoded)+"\n")
+	encoded, err = json.MarshalIndent(privateSet.Public(), "", "  ")
+	require.NoError(t, err)
+	require.Equal(t, string(readJOSETestFile(t, "public-jwks.json")), string(encoded)+"\n")
+}
+
+func TestJOSEFixtureJWS(t *testing.T) {
+	publicSet, err := ParseJWKSet(readJOSETestFile(t, "public-jwks.json"))
+	require.NoError(t, err)
+	jws := readJOSETestFile(t, "jws-general.json")
+	payload, header, err := publicSet.VerifyJWSJSON(jws)
+	require.NoError(t, err)
+	require.Equal(t, "Hello, post-quantum JOSE!", string(payload))
+	require.Equal(t, "text/plain", header.ContentType)
+
+	// Each signature verifies on its own with its key.
+	for _, jwk := range publicSet.Keys {
+		payload, header, err := VerifyJWSJSON(jwk, jws)
+		require.NoError(t, err)
+		require.Equal(t, "Hello, post-quantum JOSE!", string(payload))
+		require.Equal(t, jwk.KeyID, header.KeyID)
+		require.Equal(t, jwk.Algorithm, header.Algorithm)
+	}
+}
+
+// For TestJOSEThumbprint: RFC 7638 hashes exactly the alg, kty and pub members, in that order and without whitespace.
+func TestJOSEThumbprint(t *testing.T) {
+	jwk := newTestJWK(t, MLDSA65, "thumbprint")
+	thumbprint, err := jwk.Thumbprint()
+	require.NoError(t, err)
+	publicKey := jwk.PublicKey.(*MLDSAPublicKey)
+	want := sha256.Sum256([]byte(`{"alg":"ML-DSA-65","kty":"AKP","pub":"` + joseBase64.EncodeToString(publicKey.Bytes()) + `"}`))
+	require.Equal(t, want[:], thumbprint)
+
+	// The key ID, use and private key do not change the thumbprint.
+	public := jwk.Public()
+	public.KeyID, public.Use = "other", ""
+	publicThumbprint, err := public.Thumbprint()
+	require.NoError(t, err)
+	require.Equal(t, thumbprint, publicThumbprint)
+}
+
+func TestJOSEJWKRoundTrip(t *testing.T) {
+	for _, parameterSet := range MLDSAParameterSets {
+		jwk := newTestJWK(t, parameterSet, "key-"+parameterSet.String())
+		privateJSON, err := json.Marshal(jwk)
+		require.NoError(t, err)
+		require.Contains(t, string(privateJSON), `"priv":`)
+		parsed, err := ParseJWK(privateJSON)
+		require.NoError(t, err)
+		require.Equal(t, jwk.KeyID, parsed.KeyID)
+		require.Equal(t, jwk.Algorithm, parsed.Algorithm)
+		require.True(t, parsed.IsPrivate())
+		require.True(t, jwk.PrivateKey.(*MLDSAPrivateKey).Equal(parsed.PrivateKey))
+
+		publicJSON, err := json.Marshal(jwk.Public())
+		require.NoError(t, err)
+		require.NotContains(t, string(publicJSON), `"priv"`)
+		var public JWK
+		require.NoError(t, json.Unmarshal(publicJSON, &public))
+		require.False(t, public.IsPrivate())
+		require.True(t, jwk.PublicKey.(*MLDSAPublicKey).Equal(public.PublicKey))
+	}
+}
+
+// For TestJOSEJWKErrors: keys that cannot be marshaled or parsed.
+func TestJOSEJWKErrors(t *testing.T) {
+	jwk := newTestJWK(t, MLDSA44, "k")
+	privateKey := jwk.PrivateKey.(*MLDSAPrivateKey)
+
+	// An expanded private key has no seed for "priv".
+	expandedKey, err := NewMLDSAPrivateKey(MLDSA44, privateKey.Bytes())
+	require.NoError(t, err)
+	_, err = json.Marshal(&JWK{Algorithm: JOSEMLDSA44, PublicKey: expandedKey.PublicKey(), PrivateKey: expandedKey})
+	require.ErrorIs(t, err, ErrInvalidKey)
+	// The algorithm must match the key.
+	_, err = json.Marshal(&JWK{Algorithm: JOSEMLDSA65, PublicKey: privateKey.PublicKey()})
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = json.Marshal(&JWK{Algorithm: "ES256", PublicKey: privateKey.PublicKey()})
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+	otherKey := newTestJWK(t, MLDSA44, "other")
+	_, err = json.Marshal(&JWK{Algorithm: JOSEMLDSA44, PublicKey: privateKey.PublicKey(), PrivateKey: otherKey.PrivateKey})
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = NewMLDSAJWK(nil, "k")
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = NewMLDSAPublicJWK(nil, "k")
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = joseAlgorithmOfMLDSA(MLDSAParameterSet(0))
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+
+	privateJSON, err := json.Marshal(jwk)
+	require.NoError(t, err)
+	var encoded map[string]string
+	require.NoError(t, json.Unmarshal(privateJSON, &encoded))
+	modified := func(name string, value string) []byte {
+		copied := make(map[string]string, len(encoded))
+		for key, element := range encoded {
+			copied[key] = element
+		}
+		copied[name] = value
+		data, err := json.Marshal(copied)
+		require.NoError(t, err)
+		return data
+	}
+	otherJSON, err := json.Marshal(otherKey)
+	require.NoError(t, err)
+	var otherEncoded map[string]string
+	require.NoError(t, json.Unmarshal(otherJSON, &otherEncoded))
+
+	for _, test := range []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"not JSON", []byte("{"), ErrInvalidJOSE},
+		{"EC key", []byte(`{"kty":"EC","crv":"P-256","x":"","y":""}`), ErrUnknownAlgorithm},
+		{"unknown algorithm", modified("alg", "ML-DSA-99"), ErrUnknownAlgorithm},
+		{"padded pub", modified("pub", encoded["pub"]+"="), ErrInvalidJOSE},
+		{"line break", modified("pub", encoded["pub"][:10]+"\n"+encoded["pub"][10:]), ErrInvalidJOSE},
+		{"short pub", modified("pub", encoded["pub"][:100]), ErrInvalidKeySize},
+		{"short priv", modified("priv", encoded["priv"][:20]), ErrInvalidSeedSize},
+		{"mismatched priv", modified("priv", otherEncoded["priv"]), ErrInvalidJOSE},
+		{"wrong type", []byte(`{"kty":"AKP","alg":"ML-DSA-44","pub":1}`), ErrInvalidJOSE},
+	} {
+		_, err := ParseJWK(test.data)
+		require.ErrorIs(t, err, test.want, test.name)
+	}
+}
+
+func TestJOSEJWKSet(t *testing.T) {
+	first := newTestJWK(t, MLDSA44, "first")
+	second := newTestJWK(t, MLDSA87, "second")
+	setJSON, err := json.Marshal(&JWKSet{Keys: []*JWK{first.Public(), second.Public()}})
+	require.NoError(t, err)
+
+	// Keys of other types are skipped.
+	var encoded map[string][]json.RawMessage
+	require.NoError(t, json.Unmarshal(setJSON, &encoded))
+	encoded["keys"] = append([]json.RawMessage{json.RawMessage(`{"kty":"RSA","kid":"rsa","n":"AQAB","e":"AQAB"}`)}, encoded["keys"]...)
+	setJSON, err = json.Marshal(encoded)
+	require.NoError(t, err)
+	set, err := ParseJWKSet(setJSON)
+	require.NoError(t, err)
+	require.Len(t, set.Keys, 2)
+
+	key, err := set.Key("second")
+	require.NoError(t, err)
+	require.Equal(t, JOSEMLDSA87, key.Algorithm)
+	_, err = set.Key("rsa")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	// A malformed AKP key fails the whole set.
+	_, err = ParseJWKSet([]byte(`{"keys":[{"kty":"AKP","alg":"ML-DSA-44","pub":"AA"}]}`))
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	_, err = ParseJWKSet([]byte(`{}`))
+	require.ErrorIs(t, err, ErrInvalidJOSE)
+	emptyJSON, err := json.Marshal(&JWKSet{})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"keys":[]}`, string(emptyJSON))
+}
+
+func TestJOSECompactRoundTrip(t *testing.T) {
+	for _, parameterSet := range MLDSAParameterSets {
+		jwk := newTestJWK(t, parameterSet, "compact")
+		jws, err := SignJWS(jwk, []byte(`{"hello":"world"}`), &JWSHeader{Type: "example+jws"})
+		require.NoError(t, err)
+		require.Equal(t, 2, strings.Count(jws, "."))
+
+		payload, header, err := VerifyJWS(jwk.Public(), jws)
+		require.NoError(t, err)
+		require.Equal(t, `{"hello":"world"}`, string(payload))
+		require.Equal(t, &JWSHeader{Algorithm: jwk.Algorithm, KeyID: "compact", Type: "example+jws"}, header)
+
+		set := &JWKSet{Keys: []*JWK{newTestJWK(t, parameterSet, "other").Public(), jwk.Public()}}
+		payload, _, err = set.VerifyJWS(jws)
+		require.NoError(t, err)
+		require.Equal(t, `{"hello":"world"}`, string(payload))
+	}
+
+	// An empty payload is signed as an empty segment.
+	jwk := newTestJWK(t, MLDSA44, "")
+	jws, err := SignJWS(jwk, nil, nil)
+	require.NoError(t, err)
+	require.Contains(t, jws, "..")
+	payload, _, err := VerifyJWS(jwk, jws)
+	require.NoError(t, err)
+	require.Empty(t, payload)
+	_, err = SignJWS(jwk.Public(), nil, nil)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = SignJWS(jwk, nil, &JWSHeader{Algorithm: JOSEMLDSA65})
+	require.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// For TestJOSEVerificationFailures: a JWS fails with ErrVerificationFailed for the wrong key or a changed signing input,
+// and with ErrKeyNotFound when a key set cannot name its key.
+func TestJOSEVerificationFailures(t *testing.T) {
+	jwk := newTestJWK(t, MLDSA44, "signer")
+	jws, err := SignJWS(jwk, []byte("payload"), nil)
+	require.NoError(t, err)
+	segments := strings.Split(jws, ".")
+
+	otherKey := newTestJWK(t, MLDSA44, "signer")
+	_, _, err = VerifyJWS(otherKey, jws)
+	require.ErrorIs(t, err, ErrVerificationFailed)
+	// A key of another algorithm never verifies, whatever the header says.
+	_, _, err = VerifyJWS(newTestJWK(t, MLDSA65, "signer"), jws)
+	require.ErrorIs(t, err, ErrVerificationFailed)
+
+	changedPayload := segments[0] + "." + joseBase64.EncodeToString([]byte("Payload")) + "." + segments[2]
+	_, _, err = VerifyJWS(jwk, changedPayload)
+	require.ErrorIs(t, err, ErrVerificationFailed)
+	changedHeader := joseBase64.EncodeToString([]byte(`{"alg":"ML-DSA-44","kid":"signer","typ":"JWT"}`)) + "." + segments[1] + "." + segments[2]
+	_, _, err = VerifyJWS(jwk, changedHeader)
+	require.ErrorIs(t, err, ErrVerificationFailed)
+	signature, err := joseBase64.DecodeString(segments[2])
+	require.NoError(t, err)
+	signature[len(signature)/2] ^= 0x01
+	_, _, err = VerifyJWS(jwk, segments[0]+"."+segments[1]+"."+joseBase64.EncodeToString(signature))
+	require.ErrorIs(t, err, ErrVerificationFailed)
+
+	set := &JWKSet{Keys: []*JWK{otherKey.Public()}}
+	_, _, err = set.VerifyJWS(jws)
+	require.ErrorIs(t, err, ErrVerificationFailed)
+	set = &JWKSet{Keys: []*JWK{newTestJWK(t, MLDSA44, "someone else")}}
+	_, _, err = set.VerifyJWS(jws)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	anonymousJWS, err := SignJWS(newTestJWK(t, MLDSA44, ""), []byte("payload"), nil)
+	require.NoError(t, err)
+	_, _, err = (&JWKSet{Keys: []*JWK{jwk}}).VerifyJWS(anonymousJWS)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestJOSEJSONSerialization(t *testing.T) {
+	first := newTestJWK(t, MLDSA44, "first")
+	second := newTestJWK(t, MLDSA65, "second")
+	jws, err := SignJWSJSON([]*JWK{first, second}, []byte("payload"), nil)
+	require.NoError(t, err)
+
+	for _, jwk := range []*JWK{first, second} {
+		payload, header, err := VerifyJWSJSON(jwk.Public(), jws)
+		require.NoError(t, err)
+		require.Equal(t, "payload", string(payload))
+		require.Equal(t, jwk.KeyID, header.KeyID)
+	}
+	_, _, err = VerifyJWSJSON(newTestJWK(t, MLDSA87, "third"), jws)
+	require.ErrorIs(t, err, ErrVerificationFailed)
+	set := &JWKSet{Keys: []*JWK{second.Public()}}
+	_, header, err := set.VerifyJWSJSON(jws)
+	require.NoError(t, err)
+	require.Equal(t, "second", header.KeyID)
+	_, _, err = (&JWKSet{}).VerifyJWSJSON(jws)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	// The flattened serialization of the compact JWS, with the key ID in the unprotected header.
+	compact, err := SignJWS(first, []byte("payload"), &JWSHeader{KeyID: ""})
+	require.NoError(t, err)
+	segments := strings.Split(compact, ".")
+	protectedJSON, err := joseBase64.DecodeString(segments[0])
+	require.NoError(t, err)
+	require.Contains(t, string(protectedJSON), `"kid":"first"`)
+	flattened, err := json.Marshal(map[string]any{"payload": segments[1], "protected": segments[0], "header": map[string]string{"x-note": "unprotected"}, "signature": segments[2]})
+	require.NoError(t, err)
+	payload, header, err := VerifyJWSJSON(first, flattened)
+	require.NoError(t, err)
+	require.Equal(t, "payload", string(payload))
+	require.Equal(t, "first", header.KeyID)
+
+	unprotectedKeyID := joseBase64.EncodeToString([]byte(`{"alg":"ML-DSA-44"}`))
+	signature, err := signJWS(first, unprotectedKeyID+"."+segments[1])
+	require.NoError(t, err)
+	flattened, err = json.Marshal(map[string]any{"payload": segments[1], "protected": unprotectedKeyID, "header": map[string]string{"kid": "first"}, "signature": joseBase64.EncodeToString(signature)})
+	require.NoError(t, err)
+	_, header, err = (&JWKSet{Keys: []*JWK{first}}).VerifyJWSJSON(flattened)
+	require.NoError(t, err)
+	require.Equal(t, "first", header.KeyID)
+
+	_, err = SignJWSJSON(nil, []byte("payload"), nil)
+	require.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// For TestJOSEFormatErrors: malformed JWS input is reported with the offset of the bad segment.
+func TestJOSEFormatErrors(t *testing.T) {
+	jwk := newTestJWK(t, MLDSA44, "k")
+	jws, err := SignJWS(jwk, []byte("payload"), nil)
+	require.NoError(t, err)
+	segments := strings.Split(jws, ".")
+	encode := func(value string) string { return joseBase64.EncodeToString([]byte(value)) }
+
+	for _, test := range []struct {
+		name   string
+		jws    string
+		offset int
+		reason string
+	}{
+		{"two segments", segments[0] + "." + segments[1], 0, "2 segments"},
+		{"JWE", jws + ".a.b", 0, "5 segments"},
+		{"header not base64", "!" + jws[1:], 0, "malformed base64url JWS protected header"},
+		{"header not an object", encode(`[]`) + "." + segments[1] + "." + segments[2], 0, "not a JSON object"},
+		{"no alg", encode(`{"kid":"k"}`) + "." + segments[1] + "." + segments[2], 0, "no alg"},
+		{"alg not a string", encode(`{"alg":1}`) + "." + segments[1] + "." + segments[2], 0, "malformed JWS protected header"},
+		{"crit", encode(`{"alg":"ML-DSA-44","crit":["b64"],"b64":false}`) + "." + segments[1] + "." + segments[2], 0, "critical"},
+		{"payload not base64", segments[0] + ".p=." + segments[2], len(segments[0]) + 2, "malformed base64url JWS payload"},
+		{"signature not base64", segments[0] + "." + segments[1] + ".*", len(segments[0]) + len(segments[1]) + 2, "malformed base64url JWS signature"},
+	} {
+		_, _, err := VerifyJWS(jwk, test.jws)
+		require.ErrorIs(t, err, ErrInvalidJOSE, test.name)
+		var formatError *FormatError
+		require.True(t, errors.As(err, &formatError), test.name)
+		require.Equal(t, test.offset, formatError.Offset, test.name)
+		require.Contains(t, formatError.Reason, test.reason, test.name)
+	}
+
+	for _, test := range []struct {
+		name string
+		jws  string
+	}{
+		{"not JSON", `{"payload":`},
+		{"no payload", `{"signatures":[]}`},
+		{"no signatures", `{"payload":"","signatures":[]}`},
+		{"general and flattened", `{"payload":"","signatures":[{"signature":""}],"signature":""}`},
+		{"no signature member", `{"payload":"","signatures":[{"protected":"` + segments[0] + `"}]}`},
+		{"shared parameter", `{"payload":"","protected":"` + segments[0] + `","header":{"kid":"k"},"signature":""}`},
+		{"unprotected alg", `{"payload":"","protected":"` + encode(`{"kid":"k"}`) + `","header":{"alg":"ML-DSA-44"},"signature":""}`},
+	} {
+		_, _, err := VerifyJWSJSON(jwk, []byte(test.jws))
+		require.ErrorIs(t, err, ErrInvalidJOSE, test.name)
+	}
+}
+
+// For TestJOSEJWKUnmarshalJSONInStruct: JWK and JWKSet work as fields of application types.
+func TestJOSEJWKUnmarshalJSONInStruct(t *testing.T) {
+	jwk := newTestJWK(t, MLDSA87, "embedded")
+	type configuration struct {
+		SigningKey *JWK    `json:"signing_key"`
+		TrustedSet *JWKSet `json:"trusted"`
+	}
+	encoded, err := json.Marshal(configuration{SigningKey: jwk, TrustedSet: &JWKSet{Keys: []*JWK{jwk.Public()}}})
+	require.NoError(t, err)
+	var decoded configuration
+	require.NoError(t, json.NewDecoder(bytes.NewReader(encoded)).Decode(&decoded))
+	require.True(t, decoded.SigningKey.IsPrivate())
+	require.Len(t, decoded.TrustedSet.Keys, 1)
+	require.True(t, jwk.PublicKey.(*MLDSAPublicKey).Equal(decoded.TrustedSet.Keys[0].PublicKey))
+}
@@ -0,0 +1,1041 @@
+package pq
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp/aes/keywrap"
+	"github.com/cloudflare/circl/kem"
+	josecipher "github.com/go-jose/go-jose/v4/cipher"
+)
+
+// JWE support follows RFC 7516 with the ML-KEM key agreement of the JOSE PQC KEM draft (draft-ietf-jose-pqc-kem). The
+// sender encapsulates a shared secret to the public key of the recipient and sends the KEM ciphertext in the "ek"
+// header parameter. The shared secret is the Z input of the Concat KDF of RFC 7518 section 4.6.2 with SHA-256, whose
+// PartyUInfo and PartyVInfo are the "apu" and "apv" parameters, as for ECDH-ES:
+//
+//   - direct key agreement (ML-KEM-768, ML-KEM-1024) derives the content encryption key, with the "enc" value as
+//     AlgorithmID, and leaves the encrypted key empty;
+//   - key wrapping (ML-KEM-768+A192KW, ML-KEM-1024+A256KW) derives an AES key wrap key, with the "alg" value as
+//     AlgorithmID, which wraps a random content encryption key for each recipient.
+//
+// Hybrid key agreement follows the hybrid HPKE draft (draft-reddy-cose-jose-pqc-hybrid-hpke), whose HPKE-10-KE and
+// HPKE-11-KE algorithms are the key encryption of JOSE HPKE (draft-ietf-jose-hpke-encrypt) with X-Wing and the
+// SHAKE256 KDF of draft-ietf-hpke-pq. A random content encryption key is sealed to each recipient with the HPKE info
+// "JOSE-HPKE rcpt" || 0xFF || enc || 0xFF and no additional data; the HPKE encapsulated key is the "ek" parameter
+// and the sealed key is the encrypted key. The "apu" and "apv" parameters are not bound.
+//
+// Keys are AKP JWKs whose "pub" member is the encapsulation key and whose "priv" member is the seed: the 64 bytes
+// d || z of ML-KEM, or the 32-byte X-Wing private key.
+
+const (
+	// JOSEMLKEM768 is direct key agreement with ML-KEM-768.
+	JOSEMLKEM768 JOSEAlgorithm = "ML-KEM-768"
+	// JOSEMLKEM1024 is direct key agreement with ML-KEM-1024.
+	JOSEMLKEM1024 JOSEAlgorithm = "ML-KEM-1024"
+	// JOSEMLKEM768A192KW is ML-KEM-768 key agreement with AES-192 key wrapping.
+	JOSEMLKEM768A192KW JOSEAlgorithm = "ML-KEM-768+A192KW"
+	// JOSEMLKEM1024A256KW is ML-KEM-1024 key agreement with AES-256 key wrapping.
+	JOSEMLKEM1024A256KW JOSEAlgorithm = "ML-KEM-1024+A256KW"
+	// JOSEHPKE10KE is HPKE key encryption with X-Wing, SHAKE256 and AES-256-GCM.
+	JOSEHPKE10KE JOSEAlgorithm = "HPKE-10-KE"
+	// JOSEHPKE11KE is HPKE key encryption with X-Wing, SHAKE256 and ChaCha20-Poly1305.
+	JOSEHPKE11KE JOSEAlgorithm = "HPKE-11-KE"
+)
+
+// jweHPKEInfoLabel is the label of the HPKE info of JOSE HPKE key encryption.
+const jweHPKEInfoLabel = "JOSE-HPKE rcpt"
+
+// JOSEEncryption is the "enc" value of a JWE, its content encryption algorithm.
+type JOSEEncryption string
+
+const (
+	// JOSEA128GCM is AES-128-GCM.
+	JOSEA128GCM JOSEEncryption = "A128GCM"
+	// JOSEA192GCM is AES-192-GCM.
+	JOSEA192GCM JOSEEncryption = "A192GCM"
+	// JOSEA256GCM is AES-256-GCM, the content encryption EncryptJWE uses unless the header names another.
+	JOSEA256GCM JOSEEncryption = "A256GCM"
+	// JOSEA128CBCHS256 is AES-128-CBC with HMAC-SHA-256, as defined in RFC 7518 section 5.2.
+	JOSEA128CBCHS256 JOSEEncryption = "A128CBC-HS256"
+	// JOSEA192CBCHS384 is AES-192-CBC with HMAC-SHA-384.
+	JOSEA192CBCHS384 JOSEEncryption = "A192CBC-HS384"
+	// JOSEA256CBCHS512 is AES-256-CBC with HMAC-SHA-512.
+	JOSEA256CBCHS512 JOSEEncryption = "A256CBC-HS512"
+)
+
+// sizes returns the sizes in bytes of the content encryption key, initialization vector and authentication tag of the
+// algorithm, or zeros for an unknown algorithm.
+func (encryption JOSEEncryption) sizes() (keySize int, ivSize int, tagSize int) {
+	switch encryption {
+	case JOSEA128GCM:
+		return 16, 12, 16
+	case JOSEA192GCM:
+		return 24, 12, 16
+	case JOSEA256GCM:
+		return 32, 12, 16
+	case JOSEA128CBCHS256:
+		return 32, 16, 16
+	case JOSEA192CBCHS384:
+		return 48, 16, 24
+	case JOSEA256CBCHS512:
+		return 64, 16, 32
+	default:
+		return 0, 0, 0
+	}
+}
+
+// check returns an error matching ErrUnknownAlgorithm for an unknown algorithm.
+func (encryption JOSEEncryption) check() error {
+	if keySize, _, _ := encryption.sizes(); keySize == 0 {
+		return fmt.Errorf("%w: JWE encryption %q", ErrUnknownAlgorithm, encryption)
+	}
+	return nil
+}
+
+// aead returns the AEAD of the algorithm keyed with cek.
+func (encryption JOSEEncryption) aead(cek []byte) (cipher.AEAD, error) {
+	keySize, _, _ := encryption.sizes()
+	if keySize == 0 || len(cek) != keySize {
+		return nil, fmt.Errorf("%s needs a %d-byte content encryption key, got %d bytes", encryption, keySize, len(cek))
+	}
+	if strings.HasSuffix(string(encryption), "GCM") {
+		block, err := aes.NewCipher(cek)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	}
+	return josecipher.NewCBCHMAC(cek, aes.NewCipher)
+}
+
+// jweKeyManagement is a KEM-based key management algorithm of a JWE.
+type jweKeyManagement struct {
+	// mlkemParameterSet is the ML-KEM parameter set, or 0 for HPKE key encryption.
+	mlkemParameterSet MLKEMParameterSet
+	// keyWrapSize is the size in bytes of the AES key wrap key, or 0 for direct key agreement and HPKE key encryption.
+	keyWrapSize int
+	// hpkeSuite is the HPKE suite of HPKE key encryption, or the zero suite for ML-KEM key agreement.
+	hpkeSuite HPKESuite
+}
+
+// jweKeyManagement returns the key management of a JWE key agreement algorithm.
+func (algorithm JOSEAlgorithm) jweKeyManagement() (*jweKeyManagement, bool) {
+	switch algorithm {
+	case JOSEMLKEM768:
+		return &jweKeyManagement{mlkemParameterSet: MLKEM768}, true
+	case JOSEMLKEM1024:
+		return &jweKeyManagement{mlkemParameterSet: MLKEM1024}, true
+	case JOSEMLKEM768A192KW:
+		return &jweKeyManagement{mlkemParameterSet: MLKEM768, keyWrapSize: 24}, true
+	case JOSEMLKEM1024A256KW:
+		return &jweKeyManagement{mlkemParameterSet: MLKEM1024, keyWrapSize: 32}, true
+	case JOSEHPKE10KE:
+		return &jweKeyManagement{hpkeSuite: HPKESuite{KEM: HPKEKEMXWing, KDF: HPKEKDFSHAKE256, AEAD: HPKEAEADAES256GCM}}, true
+	case JOSEHPKE11KE:
+		return &jweKeyManagement{hpkeSuite: HPKESuite{KEM: HPKEKEMXWing, KDF: HPKEKDFSHAKE256, AEAD: HPKEAEADChaCha20Poly1305}}, true
+	default:
+		return nil, false
+	}
+}
+
+// jweKeyManagementOf returns the key management of the algorithm of a JWK, or an error matching ErrUnknownAlgorithm.
+func jweKeyManagementOf(jwk *JWK) (*jweKeyManagement, error) {
+	keyManagement, ok := jwk.Algorithm.jweKeyManagement()
+	if !ok {
+		return nil, fmt.Errorf("%w: JWK algorithm %q cannot encrypt", ErrUnknownAlgorithm, jwk.Algorithm)
+	}
+	return keyManagement, nil
+}
+
+// isHPKE reports whether the key management is HPKE key encryption.
+func (keyManagement *jweKeyManagement) isHPKE() bool {
+	return keyManagement.hpkeSuite.KEM != 0
+}
+
+// isDirect reports whether the key management is direct key agreement, which derives the content encryption key.
+func (keyManagement *jweKeyManagement) isDirect() bool {
+	return keyManagement.keyWrapSize == 0 && !keyManagement.isHPKE()
+}
+
+// String returns the name of the KEM.
+func (keyManagement *jweKeyManagement) String() string {
+	if keyManagement.isHPKE() {
+		return keyManagement.hpkeSuite.KEM.String()
+	}
+	return keyManagement.mlkemParameterSet.String()
+}
+
+// ownsPublicKey reports whether a public key belongs to the KEM.
+func (keyManagement *jweKeyManagement) ownsPublicKey(publicKey kem.PublicKey) bool {
+	if keyManagement.isHPKE() {
+		scheme, err := keyManagement.hpkeSuite.KEM.scheme()
+		return err == nil && publicKey.Scheme() == scheme
+	}
+	parameterSet, err := mlkemParameterSetOf(publicKey.Scheme())
+	return err == nil && parameterSet == keyManagement.mlkemParameterSet
+}
+
+// unmarshalPublicKey parses an encoded public key of the KEM.
+func (keyManagement *jweKeyManagement) unmarshalPublicKey(publicKeyBytes []byte) (kem.PublicKey, error) {
+	if keyManagement.isHPKE() {
+		return UnmarshalHPKEPublicKey(keyManagement.hpkeSuite.KEM, publicKeyBytes)
+	}
+	return UnmarshalPublicKey(keyManagement.mlkemParameterSet, publicKeyBytes)
+}
+
+// deriveKeyPair derives a key pair of the KEM from its seed, and returns the *MLKEMKeyPair or *XWingKeyPair and its
+// public key.
+func (keyManagement *jweKeyManagement) deriveKeyPair(seed []byte) (crypto.PrivateKey, kem.PublicKey, error) {
+	if keyManagement.isHPKE() {
+		keyPair, err := GenerateDeterministicXWingKeyPair(seed)
+		if err != nil {
+			return nil, nil, err
+		}
+		return keyPair, keyPair.PublicKey, nil
+	}
+	keyPair, err := GenerateDeterministicMLKEMKeyPair(keyManagement.mlkemParameterSet, seed)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keyPair, keyPair.PublicKey, nil
+}
+
+// deriveKey derives the key of header from a shared secret: the content encryption key for direct key agreement, or
+// else the key wrap key.
+func (keyManagement *jweKeyManagement) deriveKey(sharedSecret []byte, header *JWEHeader) []byte {
+	if keyManagement.keyWrapSize == 0 {
+		keySize, _, _ := header.Encryption.sizes()
+		return jweConcatKDF(sharedSecret, string(header.Encryption), header.PartyUInfo, header.PartyVInfo, keySize)
+	}
+	return jweConcatKDF(sharedSecret, string(header.Algorithm), header.PartyUInfo, header.PartyVInfo, keyManagement.keyWrapSize)
+}
+
+// encapsulateKey encapsulates a shared secret to a JWK and returns the KEM ciphertext and the key derived for header.
+func (keyManagement *jweKeyManagement) encapsulateKey(jwk *JWK, header *JWEHeader) (encapsulatedKey []byte, derivedKey []byte, err error) {
+	publicKey, _, _, err := jwk.kemKeys(keyManagement)
+	if err != nil {
+		return nil, nil, err
+	}
+	encapsulatedKey, sharedSecret, err := MLKEMEncapsulate(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encapsulatedKey, keyManagement.deriveKey(sharedSecret, header), nil
+}
+
+// decapsulateKey decapsulates the shared secret of header with a private JWK and returns the key derived for header.
+func (keyManagement *jweKeyManagement) decapsulateKey(jwk *JWK, header *JWEHeader) ([]byte, error) {
+	_, privateKey, _, err := jwk.kemKeys(keyManagement)
+	if err != nil {
+		return nil, err
+	}
+	if privateKey == nil {
+		return nil, fmt.Errorf("%w: decrypting a JWE needs a private JWK", ErrInvalidKey)
+	}
+	sharedSecret, err := MLKEMDecapsulate(privateKey, header.EncapsulatedKey)
+	if err != nil {
+		return nil, fmt.Errorf("JWE ek: %w", err)
+	}
+	return keyManagement.deriveKey(sharedSecret, header), nil
+}
+
+// encryptKey returns the content encryption key and the encrypted key of a recipient from the key derived for it: the
+// derived key and no encrypted key for direct key agreement, or else cek, generated if it is nil, wrapped with the
+// derived key.
+func (keyManagement *jweKeyManagement) encryptKey(derivedKey []byte, encryption JOSEEncryption, cek []byte) ([]byte, []byte, error) {
+	if keyManagement.keyWrapSize == 0 {
+		return derivedKey, nil, nil
+	}
+	if cek == nil {
+		var err error
+		if cek, err = newJWEContentKey(encryption); err != nil {
+			return nil, nil, err
+		}
+	}
+	encryptedKey, err := keywrap.Wrap(derivedKey, cek)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cek, encryptedKey, nil
+}
+
+// wrapKey returns the content encryption key, the KEM ciphertext and the encrypted key of a recipient whose JWK and
+// header are given. cek is the content encryption key of the other recipients, or nil for the first one.
+func (keyManagement *jweKeyManagement) wrapKey(jwk *JWK, header *JWEHeader, cek []byte) ([]byte, []byte, []byte, error) {
+	if keyManagement.isHPKE() {
+		return keyManagement.sealKey(jwk, header, cek)
+	}
+	encapsulatedKey, derivedKey, err := keyManagement.encapsulateKey(jwk, header)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	cek, encryptedKey, err := keyManagement.encryptKey(derivedKey, header.Encryption, cek)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return cek, encapsulatedKey, encryptedKey, nil
+}
+
+// sealKey seals the content encryption key, generated if cek is nil, to a JWK with HPKE key encryption, and returns it
+// with the HPKE encapsulated key and the sealed key.
+func (keyManagement *jweKeyManagement) sealKey(jwk *JWK, header *JWEHeader, cek []byte) ([]byte, []byte, []byte, error) {
+	publicKey, _, _, err := jwk.kemKeys(keyManagement)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if cek == nil {
+		if cek, err = newJWEContentKey(header.Encryption); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	encapsulatedKey, encryptedKey, err := HPKESeal(keyManagement.hpkeSuite, publicKey, jweHPKEInfo(header.Encryption), nil, cek, nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return cek, encapsulatedKey, encryptedKey, nil
+}
+
+// openKey opens the content encryption key of a recipient with a private JWK and HPKE key encryption, and returns
+// ErrDecryptionFailed if it does not authenticate.
+func (keyManagement *jweKeyManagement) openKey(jwk *JWK, header *JWEHeader, encryptedKey []byte) ([]byte, error) {
+	_, privateKey, _, err := jwk.kemKeys(keyManagement)
+	if err != nil {
+		return nil, err
+	}
+	if privateKey == nil {
+		return nil, fmt.Errorf("%w: decrypting a JWE needs a private JWK", ErrInvalidKey)
+	}
+	cek, err := HPKEOpen(keyManagement.hpkeSuite, privateKey, header.EncapsulatedKey, jweHPKEInfo(header.Encryption), nil, encryptedKey, nil)
+	if errors.Is(err, ErrDecryptionFailed) {
+		return nil, fmt.Errorf("%w: JWE encrypted key does not open", ErrDecryptionFailed)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("JWE ek: %w", err)
+	}
+	return cek, nil
+}
+
+// jweHPKEInfo returns the HPKE info of HPKE key encryption, "JOSE-HPKE rcpt" || 0xFF || enc || 0xFF.
+func jweHPKEInfo(encryption JOSEEncryption) []byte {
+	info := append([]byte(jweHPKEInfoLabel), 0xff)
+	return append(append(info, encryption...), 0xff)
+}
+
+// newJWEContentKey returns a random content encryption key for the algorithm.
+func newJWEContentKey(encryption JOSEEncryption) ([]byte, error) {
+	keySize, _, _ := encryption.sizes()
+	cek := make([]byte, keySize)
+	if _, err := rand.Read(cek); err != nil {
+		return nil, err
+	}
+	return cek, nil
+}
+
+// jweConcatKDF derives size bytes from a shared secret with the Concat KDF of RFC 7518 section 4.6.2 (NIST SP 800-56A)
+// and SHA-256. AlgorithmID, PartyUInfo and PartyVInfo are length prefixed, and SuppPubInfo is the size in bits.
+func jweConcatKDF(sharedSecret []byte, algorithmID string, partyUInfo []byte, partyVInfo []byte, size int) []byte {
+	var otherInfo []byte
+	for _, field := range [][]byte{[]byte(algorithmID), partyUInfo, partyVInfo} {
+		otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(len(field)))
+		otherInfo = append(otherInfo, field...)
+	}
+	otherInfo = binary.BigEndian.AppendUint32(otherInfo, uint32(size*8))
+	derived := make([]byte, 0, size+sha256.Size)
+	for counter := uint32(1); len(derived) < size; counter++ {
+		digest := sha256.New()
+		digest.Write(binary.BigEndian.AppendUint32(nil, counter))
+		digest.Write(sharedSecret)
+		digest.Write(otherInfo)
+		derived = digest.Sum(derived)
+	}
+	return derived[:size]
+}
+
+// NewMLKEMJWK returns the JWK of an ML-KEM key pair for a JWE key agreement algorithm, for example JOSEMLKEM768A192KW.
+// The key pair must have its seed, which is the "priv" member.
+func NewMLKEMJWK(keyPair *MLKEMKeyPair, algorithm JOSEAlgorithm, keyID string) (*JWK, error) {
+	if keyPair == nil {
+		return nil, fmt.Errorf("%w: ML-KEM key pair is nil", ErrInvalidKey)
+	}
+	return newKEMJWK(&JWK{KeyID: keyID, Use: "enc", Algorithm: algorithm, PublicKey: keyPair.PublicKey, PrivateKey: keyPair})
+}
+
+// NewXWingJWK returns the JWK of an X-Wing key pair for an HPKE key encryption algorithm, JOSEHPKE10KE or JOSEHPKE11KE.
+// The "priv" member is the X-Wing private key, which is its own seed.
+func NewXWingJWK(keyPair *XWingKeyPair, algorithm JOSEAlgorithm, keyID string) (*JWK, error) {
+	if keyPair == nil {
+		return nil, fmt.Errorf("%w: X-Wing key pair is nil", ErrInvalidKey)
+	}
+	return newKEMJWK(&JWK{KeyID: keyID, Use: "enc", Algorithm: algorithm, PublicKey: keyPair.PublicKey, PrivateKey: keyPair})
+}
+
+// NewKEMPublicJWK returns the JWK of an ML-KEM or X-Wing public key for a JWE key agreement or HPKE key encryption
+// algorithm.
+func NewKEMPublicJWK(publicKey kem.PublicKey, algorithm JOSEAlgorithm, keyID string) (*JWK, error) {
+	return newKEMJWK(&JWK{KeyID: keyID, Use: "enc", Algorithm: algorithm, PublicKey: publicKey})
+}
+
+// newKEMJWK checks that the keys of jwk belong to its algorithm and can be encoded.
+func newKEMJWK(jwk *JWK) (*JWK, error) {
+	if _, err := jweKeyManagementOf(jwk); err != nil {
+		return nil, err
+	}
+	if _, _, err := jwk.keyMaterial(); err != nil {
+		return nil, err
+	}
+	return jwk, nil
+}
+
+// kemKeys returns the keys of a KEM JWK and the seed of its private key; the private key and seed are nil for a public
+// key.
+func (jwk *JWK) kemKeys(keyManagement *jweKeyManagement) (publicKey kem.PublicKey, privateKey kem.PrivateKey, seed []byte, err error) {
+	publicKey, ok := jwk.PublicKey.(kem.PublicKey)
+	if !ok || publicKey == nil || !keyManagement.ownsPublicKey(publicKey) {
+		return nil, nil, nil, fmt.Errorf("%w: JWK %q: %s key needs an %v public key, got %T", ErrInvalidKey, jwk.KeyID, jwk.Algorithm, keyManagement, jwk.PublicKey)
+	}
+	switch keyPair := jwk.PrivateKey.(type) {
+	case nil:
+		return publicKey, nil, nil, nil
+	case *MLKEMKeyPair:
+		if keyPair != nil && keyPair.PrivateKey != nil && keyPair.PublicKey != nil && keyPair.PublicKey.Equal(publicKey) {
+			return publicKey, keyPair.PrivateKey, keyPair.Seed, nil
+		}
+	case *XWingKeyPair:
+		if keyPair != nil && keyPair.PrivateKey != nil && keyPair.PublicKey != nil && keyPair.PublicKey.Equal(publicKey) {
+			seed, err := keyPair.PrivateKey.MarshalBinary()
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			return publicKey, keyPair.PrivateKey, seed, nil
+		}
+	}
+	return nil, nil, nil, fmt.Errorf("%w: JWK %q: private key does not belong to the %v public key", ErrInvalidKey, jwk.KeyID, keyManagement)
+}
+
+// setKeys sets the keys of a KEM JWK from its "pub" and "priv" members. The seed, if not nil, must derive the public key.
+func (keyManagement *jweKeyManagement) setKeys(jwk *JWK, publicKeyBytes []byte, seed []byte) error {
+	publicKey, err := keyManagement.unmarshalPublicKey(publicKeyBytes)
+	if err != nil {
+		return fmt.Errorf("JWK %q: %w", jwk.KeyID, err)
+	}
+	jwk.PublicKey = publicKey
+	if seed == nil {
+		return nil
+	}
+	privateKey, derivedPublicKey, err := keyManagement.deriveKeyPair(seed)
+	if err != nil {
+		return fmt.Errorf("JWK %q: %w", jwk.KeyID, err)
+	}
+	if !derivedPublicKey.Equal(publicKey) {
+		return newJOSEFormatError(0, "JWK %q: pub does not match the public key of priv", jwk.KeyID)
+	}
+	jwk.PrivateKey = privateKey
+	return nil
+}
+
+// decryptionKey returns the private key a JWE recipient names by its key ID and algorithm, or nil.
+func (set *JWKSet) decryptionKey(header *JWEHeader) *JWK {
+	if header.KeyID == "" {
+		return nil
+	}
+	for _, jwk := range set.Keys {
+		if jwk.KeyID == header.KeyID && jwk.Algorithm == header.Algorithm && jwk.PrivateKey != nil {
+			return jwk
+		}
+	}
+	return nil
+}
+
+// JWEHeader holds the header parameters of a JWE that this package understands. Other parameters are ignored, except
+// "crit" and "zip", which are rejected because no extension and no compression is supported.
+type JWEHeader struct {
+	// Algorithm is the "alg" parameter, the key agreement algorithm of the recipient.
+	Algorithm JOSEAlgorithm
+	// Encryption is the "enc" parameter, the content encryption algorithm.
+	Encryption JOSEEncryption
+	// KeyID is the "kid" parameter, which JWKSet uses to find the decryption key.
+	KeyID string
+	// Type is the "typ" parameter.
+	Type string
+	// ContentType is the "cty" parameter, for example "JWT" for a nested JWT.
+	ContentType string
+	// EncapsulatedKey is the "ek" parameter, the KEM ciphertext of the recipient. The encryption functions set it.
+	EncapsulatedKey []byte
+	// PartyUInfo is the "apu" parameter, which the key derivation binds, for example to the name of the sender.
+	PartyUInfo []byte
+	// PartyVInfo is the "apv" parameter, which the key derivation binds, for example to the name of the recipient.
+	PartyVInfo []byte
+}
+
+// jweHeaderJSON is the JSON encoding of a JWEHeader.
+type jweHeaderJSON struct {
+	Algorithm       string `json:"alg,omitempty"`
+	Encryption      string `json:"enc,omitempty"`
+	KeyID           string `json:"kid,omitempty"`
+	Type            string `json:"typ,omitempty"`
+	ContentType     string `json:"cty,omitempty"`
+	EncapsulatedKey string `json:"ek,omitempty"`
+	PartyUInfo      string `json:"apu,omitempty"`
+	PartyVInfo      string `json:"apv,omitempty"`
+}
+
+// encode returns the JSON encoding of the header, without its empty parameters.
+func (header *JWEHeader) encode() ([]byte, error) {
+	return json.Marshal(jweHeaderJSON{
+		Algorithm:       string(header.Algorithm),
+		Encryption:      string(header.Encryption),
+		KeyID:           header.KeyID,
+		Type:            header.Type,
+		ContentType:     header.ContentType,
+		EncapsulatedKey: joseBase64.EncodeToString(header.EncapsulatedKey),
+		PartyUInfo:      joseBase64.EncodeToString(header.PartyUInfo),
+		PartyVInfo:      joseBase64.EncodeToString(header.PartyVInfo),
+	})
+}
+
+// newJWEHeader returns a copy of the header an encryption function was given, with the default content encryption if
+// it names none.
+func newJWEHeader(header *JWEHeader) (*JWEHeader, error) {
+	copied := &JWEHeader{}
+	if header != nil {
+		*copied = *header
+	}
+	if copied.Encryption == "" {
+		copied.Encryption = JOSEA256GCM
+	}
+	if err := copied.Encryption.check(); err != nil {
+		return nil, err
+	}
+	if copied.EncapsulatedKey != nil {
+		return nil, fmt.Errorf("%w: the JWE encapsulated key is set by the encryption", ErrInvalidOptions)
+	}
+	return copied, nil
+}
+
+// jweRecipient is a parsed recipient of a JWE, with the header merged from all the headers that apply to it.
+type jweRecipient struct {
+	header       *JWEHeader
+	encryptedKey []byte
+}
+
+// jweMessage is a parsed JWE.
+type jweMessage struct {
+	recipients []jweRecipient
+	// authenticatedData is the additional authenticated data of the content encryption.
+	authenticatedData []byte
+	// additionalData is the decoded "aad" member of the JSON serialization, or nil.
+	additionalData []byte
+	iv             []byte
+	ciphertext     []byte
+	tag            []byte
+}
+
+// jweJSON is the general and flattened JWE JSON serialization.
+type jweJSON struct {
+	Protected   string                     `json:"protected,omitempty"`
+	Unprotected map[string]json.RawMessage `json:"unprotected,omitempty"`
+	Recipients  []jweJSONRecipient         `json:"recipients,omitempty"`
+	jweJSONRecipient
+	AdditionalData *string `json:"aad,omitempty"`
+	IV             string  `json:"iv,omitempty"`
+	Ciphertext     *string `json:"ciphertext"`
+	Tag            string  `json:"tag,omitempty"`
+}
+
+// jweJSONRecipient is a recipient of the JWE JSON serialization.
+type jweJSONRecipient struct {
+	Header       map[string]json.RawMessage `json:"header,omitempty"`
+	EncryptedKey string                     `json:"encrypted_key,omitempty"`
+}
+
+// parseJWEHeader parses the base64url protected header at offset, if not empty, and merges the unprotected headers
+// into it. The headers must not share a parameter, and together they must have the algorithm and content encryption.
+func parseJWEHeader(encodedProtected string, offset int, unprotectedHeaders ...map[string]json.RawMessage) (*JWEHeader, error) {
+	merged := make(map[string]json.RawMessage)
+	if encodedProtected != "" {
+		protectedJSON, err := decodeJOSEBase64(encodedProtected, offset, "JWE protected header")
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(protectedJSON, &merged); err != nil || merged == nil {
+			return nil, newJOSEFormatError(offset, "JWE protected header is not a JSON object")
+		}
+	}
+	for _, unprotected := range unprotectedHeaders {
+		for name, value := range unprotected {
+			if _, ok := merged[name]; ok {
+				return nil, newJOSEFormatError(offset, "JWE header parameter %q appears in more than one header", name)
+			}
+			merged[name] = value
+		}
+	}
+	for _, name := range []string{"crit", "zip"} {
+		if _, ok := merged[name]; ok {
+			return nil, newJOSEFormatError(offset, "unsupported JWE header parameter %q", name)
+		}
+	}
+	mergedJSON, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	var encoded jweHeaderJSON
+	if err := json.Unmarshal(mergedJSON, &encoded); err != nil {
+		return nil, newJOSEFormatError(offset, "malformed JWE header: %v", err)
+	}
+	if encoded.Algorithm == "" || encoded.Encryption == "" {
+		return nil, newJOSEFormatError(offset, "JWE header needs both alg and enc")
+	}
+	header := &JWEHeader{
+		Algorithm:   JOSEAlgorithm(encoded.Algorithm),
+		Encryption:  JOSEEncryption(encoded.Encryption),
+		KeyID:       encoded.KeyID,
+		Type:        encoded.Type,
+		ContentType: encoded.ContentType,
+	}
+	for _, parameter := range []struct {
+		name    string
+		encoded string
+		decoded *[]byte
+	}{
+		{"ek", encoded.EncapsulatedKey, &header.EncapsulatedKey},
+		{"apu", encoded.PartyUInfo, &header.PartyUInfo},
+		{"apv", encoded.PartyVInfo, &header.PartyVInfo},
+	} {
+		if parameter.encoded == "" {
+			continue
+		}
+		if *parameter.decoded, err = decodeJOSEBase64(parameter.encoded, offset, "JWE "+parameter.name); err != nil {
+			return nil, err
+		}
+	}
+	return header, nil
+}
+
+// check checks that the recipients share a known content encryption, and that the initialization vector and
+// authentication tag have its sizes.
+func (message *jweMessage) check() error {
+	encryption := message.recipients[0].header.Encryption
+	for _, recipient := range message.recipients[1:] {
+		if recipient.header.Encryption != encryption {
+			return newJOSEFormatError(0, "JWE recipients have the content encryptions %q and %q", encryption, recipient.header.Encryption)
+		}
+	}
+	if err := encryption.check(); err != nil {
+		return err
+	}
+	_, ivSize, tagSize := encryption.sizes()
+	if len(message.iv) != ivSize {
+		return newJOSEFormatError(0, "JWE initialization vector has %d bytes, %s needs %d", len(message.iv), encryption, ivSize)
+	}
+	if len(message.tag) != tagSize {
+		return newJOSEFormatError(0, "JWE authentication tag has %d bytes, %s needs %d", len(message.tag), encryption, tagSize)
+	}
+	return nil
+}
+
+// parseJWECompact parses a JWE in the compact serialization.
+func parseJWECompact(jwe string) (*jweMessage, error) {
+	segments := strings.Split(jwe, ".")
+	if len(segments) != 5 {
+		return nil, newJOSEFormatError(0, "compact JWE has %d segments, want 5", len(segments))
+	}
+	header, err := parseJWEHeader(segments[0], 0)
+	if err != nil {
+		return nil, err
+	}
+	message := &jweMessage{authenticatedData: []byte(segments[0])}
+	recipient := jweRecipient{header: header}
+	offset := len(segments[0]) + 1
+	for index, segment := range []struct {
+		name    string
+		decoded *[]byte
+	}{
+		{"encrypted key", &recipient.encryptedKey},
+		{"initialization vector", &message.iv},
+		{"ciphertext", &message.ciphertext},
+		{"authentication tag", &message.tag},
+	} {
+		if *segment.decoded, err = decodeJOSEBase64(segments[index+1], offset, "JWE "+segment.name); err != nil {
+			return nil, err
+		}
+		offset += len(segments[index+1]) + 1
+	}
+	message.recipients = []jweRecipient{recipient}
+	if err := message.check(); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// parseJWEJSON parses a JWE in the general or flattened JSON serialization.
+func parseJWEJSON(data []byte) (*jweMessage, error) {
+	var encoded jweJSON
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, newJOSEJSONError(0, "JWE JSON serialization", err)
+	}
+	if encoded.Ciphertext == nil {
+		return nil, newJOSEFormatError(0, "JWE JSON serialization has no ciphertext")
+	}
+	flattened := encoded.Header != nil || encoded.EncryptedKey != ""
+	switch {
+	case flattened && encoded.Recipients != nil:
+		return nil, newJOSEFormatError(0, "JWE JSON serialization is both general and flattened")
+	case encoded.Recipients == nil:
+		encoded.Recipients = []jweJSONRecipient{encoded.jweJSONRecipient}
+	case len(encoded.Recipients) == 0:
+		return nil, newJOSEFormatError(0, "JWE JSON serialization has no recipients")
+	}
+	message := &jweMessage{authenticatedData: []byte(encoded.Protected)}
+	if encoded.AdditionalData != nil {
+		additionalData, err := decodeJOSEBase64(*encoded.AdditionalData, 0, "JWE aad")
+		if err != nil {
+			return nil, err
+		}
+		message.additionalData = additionalData
+		message.authenticatedData = append(message.authenticatedData, "."+*encoded.AdditionalData...)
+	}
+	var err error
+	for _, member := range []struct {
+		name    string
+		encoded string
+		decoded *[]byte
+	}{
+		{"iv", encoded.IV, &message.iv},
+		{"ciphertext", *encoded.Ciphertext, &message.ciphertext},
+		{"tag", encoded.Tag, &message.tag},
+	} {
+		if *member.decoded, err = decodeJOSEBase64(member.encoded, 0, "JWE "+member.name); err != nil {
+			return nil, err
+		}
+	}
+	for index, encodedRecipient := range encoded.Recipients {
+		header, err := parseJWEHeader(encoded.Protected, 0, encoded.Unprotected, encodedRecipient.Header)
+		if err != nil {
+			return nil, fmt.Errorf("JWE recipient %d: %w", index, err)
+		}
+		encryptedKey, err := decodeJOSEBase64(encodedRecipient.EncryptedKey, 0, "JWE encrypted key")
+		if err != nil {
+			return nil, fmt.Errorf("JWE recipient %d: %w", index, err)
+		}
+		message.recipients = append(message.recipients, jweRecipient{header: header, encryptedKey: encryptedKey})
+	}
+	if err := message.check(); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// sealJWE encrypts plaintext with cek and returns the initialization vector, ciphertext and authentication tag.
+func sealJWE(encryption JOSEEncryption, cek []byte, plaintext []byte, authenticatedData []byte) (iv []byte, ciphertext []byte, tag []byte, err error) {
+	aead, err := encryption.aead(cek)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	_, ivSize, tagSize := encryption.sizes()
+	iv = make([]byte, ivSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, nil, nil, err
+	}
+	sealed := aead.Seal(nil, iv, plaintext, authenticatedData)
+	return iv, sealed[:len(sealed)-tagSize], sealed[len(sealed)-tagSize:], nil
+}
+
+// sealJWECompact encrypts plaintext with cek and returns the compact JWE with header as its protected header.
+func sealJWECompact(header *JWEHeader, cek []byte, encryptedKey []byte, plaintext []byte) (string, error) {
+	headerJSON, err := header.encode()
+	if err != nil {
+		return "", err
+	}
+	encodedHeader := joseBase64.EncodeToString(headerJSON)
+	iv, ciphertext, tag, err := sealJWE(header.Encryption, cek, plaintext, []byte(encodedHeader))
+	if err != nil {
+		return "", err
+	}
+	segments := []string{encodedHeader}
+	for _, segment := range [][]byte{encryptedKey, iv, ciphertext, tag} {
+		segments = append(segments, joseBase64.EncodeToString(segment))
+	}
+	return strings.Join(segments, "."), nil
+}
+
+// open decrypts the content of the message with cek, returning ErrDecryptionFailed if it does not authenticate.
+func (message *jweMessage) open(encryption JOSEEncryption, cek []byte) ([]byte, error) {
+	aead, err := encryption.aead(cek)
+	if err != nil {
+		return nil, err
+	}
+	sealed := append(append([]byte(nil), message.ciphertext...), message.tag...)
+	plaintext, err := aead.Open(nil, message.iv, sealed, message.authenticatedData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: JWE content does not authenticate", ErrDecryptionFailed)
+	}
+	return plaintext, nil
+}
+
+// decryptKey returns the content encryption key of a recipient with a private JWK, and ErrDecryptionFailed if the
+// recipient is not for the key.
+func (recipient *jweRecipient) decryptKey(jwk *JWK) ([]byte, error) {
+	if recipient.header.Algorithm != jwk.Algorithm {
+		return nil, fmt.Errorf("%w: JWE recipient algorithm %q does not match the %q key", ErrDecryptionFailed, recipient.header.Algorithm, jwk.Algorithm)
+	}
+	keyManagement, err := jweKeyManagementOf(jwk)
+	if err != nil {
+		return nil, err
+	}
+	if keyManagement.isHPKE() {
+		return keyManagement.openKey(jwk, recipient.header, recipient.encryptedKey)
+	}
+	derivedKey, err := keyManagement.decapsulateKey(jwk, recipient.header)
+	if err != nil {
+		return nil, err
+	}
+	if keyManagement.keyWrapSize == 0 {
+		if len(recipient.encryptedKey) != 0 {
+			return nil, newJOSEFormatError(0, "JWE with direct key agreement %q has an encrypted key", jwk.Algorithm)
+		}
+		return derivedKey, nil
+	}
+	// AES key wrap adds 8 bytes to the key, and go-crypto's keywrap panics on a ciphertext shorter than that.
+	keySize, _, _ := recipient.header.Encryption.sizes()
+	if len(recipient.encryptedKey) != keySize+8 {
+		return nil, newJOSEFormatError(0, "JWE encrypted key has %d bytes, want %d", len(recipient.encryptedKey), keySize+8)
+	}
+	cek, err := keywrap.Unwrap(derivedKey, recipient.encryptedKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: JWE encrypted key does not unwrap", ErrDecryptionFailed)
+	}
+	return cek, nil
+}
+
+// decrypt decrypts the message with the first recipient that the key findKey returns for it decrypts. It returns
+// ErrKeyNotFound if findKey finds no key at all, and ErrDecryptionFailed if no recipient decrypts.
+func (message *jweMessage) decrypt(findKey func(header *JWEHeader) *JWK) ([]byte, *JWEHeader, error) {
+	keyFound := false
+	for index := range message.recipients {
+		recipient := &message.recipients[index]
+		jwk := findKey(recipient.header)
+		if jwk == nil {
+			continue
+		}
+		keyFound = true
+		cek, err := recipient.decryptKey(jwk)
+		if errors.Is(err, ErrDecryptionFailed) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if plaintext, err := message.open(recipient.header.Encryption, cek); err == nil {
+			return plaintext, recipient.header, nil
+		}
+	}
+	if !keyFound {
+		keyIDs := make([]string, len(message.recipients))
+		for index := range message.recipients {
+			keyIDs[index] = message.recipients[index].header.KeyID
+		}
+		return nil, nil, fmt.Errorf("%w: no JWK for JWE key IDs %q", ErrKeyNotFound, keyIDs)
+	}
+	return nil, nil, fmt.Errorf("%w: no JWE recipient decrypts", ErrDecryptionFailed)
+}
+
+// EncryptJWE encrypts plaintext to a JWK and returns the JWE in the compact serialization. The protected header holds
+// the algorithm of the key, the key ID of the key unless header names another one, the KEM ciphertext and the other
+// parameters of header, which may be nil. The content encryption is A256GCM unless header names another.
+func EncryptJWE(key *JWK, plaintext []byte, header *JWEHeader) (jwe string, encryptError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			jwe, encryptError = "", newPanicError("EncryptJWE", recoveredPanic)
+		}
+	}()
+	if key == nil {
+		return "", fmt.Errorf("%w: encrypting a JWE needs a JWK", ErrInvalidKey)
+	}
+	protected, err := newJWEHeader(header)
+	if err != nil {
+		return "", err
+	}
+	if protected.Algorithm != "" && protected.Algorithm != key.Algorithm {
+		return "", fmt.Errorf("%w: JWE algorithm %q does not match the %q key", ErrInvalidOptions, protected.Algorithm, key.Algorithm)
+	}
+	protected.Algorithm = key.Algorithm
+	if protected.KeyID == "" {
+		protected.KeyID = key.KeyID
+	}
+	keyManagement, err := jweKeyManagementOf(key)
+	if err != nil {
+		return "", err
+	}
+	cek, encapsulatedKey, encryptedKey, err := keyManagement.wrapKey(key, protected, nil)
+	if err != nil {
+		return "", err
+	}
+	protected.EncapsulatedKey = encapsulatedKey
+	if jwe, err = sealJWECompact(protected, cek, encryptedKey, plaintext); err != nil {
+		return "", err
+	}
+	Logger().Debug("EncryptJWE", "algorithm", string(key.Algorithm), "encryption", string(protected.Encryption), "plaintext_size", len(plaintext))
+	return jwe, nil
+}
+
+// DecryptJWE decrypts a JWE in the compact serialization with a private JWK and returns its plaintext and header. A
+// malformed JWE returns a *FormatError matching ErrInvalidJOSE, and a JWE that does not decrypt, including one with
+// another algorithm than the key, returns ErrDecryptionFailed.
+func DecryptJWE(key *JWK, jwe string) (plaintext []byte, header *JWEHeader, decryptError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			plaintext, header, decryptError = nil, nil, newPanicError("DecryptJWE", recoveredPanic)
+		}
+	}()
+	if key == nil {
+		return nil, nil, fmt.Errorf("%w: decrypting a JWE needs a private JWK", ErrInvalidKey)
+	}
+	return decryptJWECompact(jwe, func(*JWEHeader) *JWK { return key })
+}
+
+// DecryptJWE decrypts a JWE in the compact serialization with the private key of the set that has its key ID and
+// algorithm. A JWE without a key ID, or whose key is not in the set, returns ErrKeyNotFound; otherwise it behaves like
+// DecryptJWE.
+func (set *JWKSet) DecryptJWE(jwe string) (plaintext []byte, header *JWEHeader, decryptError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			plaintext, header, decryptError = nil, nil, newPanicError("JWKSet.DecryptJWE", recoveredPanic)
+		}
+	}()
+	return decryptJWECompact(jwe, set.decryptionKey)
+}
+
+// decryptJWECompact decrypts a compact JWE with the key findKey returns for it.
+func decryptJWECompact(jwe string, findKey func(header *JWEHeader) *JWK) ([]byte, *JWEHeader, error) {
+	message, err := parseJWECompact(jwe)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, header, err := message.decrypt(findKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	Logger().Debug("DecryptJWE", "algorithm", string(header.Algorithm), "encryption", string(header.Encryption), "plaintext_size", len(plaintext))
+	return plaintext, header, nil
+}
+
+// EncryptJWEJSON encrypts plaintext to every JWK and returns the JWE in the general JSON serialization. The protected
+// header holds the parameters of header, which may be nil but must not name an algorithm or key ID; each recipient
+// has an unprotected header with the algorithm, key ID and KEM ciphertext of its key. additionalData, if not nil, is
+// the "aad" member, which is authenticated but not encrypted. Direct key agreement only allows a single key.
+func EncryptJWEJSON(keys []*JWK, plaintext []byte, additionalData []byte, header *JWEHeader) (jwe []byte, encryptError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			jwe, encryptError = nil, newPanicError("EncryptJWEJSON", recoveredPanic)
+		}
+	}()
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: a JWE needs at least one recipient key", ErrInvalidOptions)
+	}
+	protected, err := newJWEHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	if protected.Algorithm != "" || protected.KeyID != "" {
+		return nil, fmt.Errorf("%w: the JWE JSON serialization takes the algorithm and key ID of each recipient from its key", ErrInvalidOptions)
+	}
+	protectedJSON, err := protected.encode()
+	if err != nil {
+		return nil, err
+	}
+	encodedProtected := joseBase64.EncodeToString(protectedJSON)
+	encoded := jweJSON{Protected: encodedProtected, Recipients: make([]jweJSONRecipient, len(keys))}
+	var cek []byte
+	for index, key := range keys {
+		if key == nil {
+			return nil, fmt.Errorf("%w: encrypting a JWE needs a JWK", ErrInvalidKey)
+		}
+		keyManagement, err := jweKeyManagementOf(key)
+		if err != nil {
+			return nil, err
+		}
+		if keyManagement.isDirect() && len(keys) != 1 {
+			return nil, fmt.Errorf("%w: direct key agreement %q needs a JWE with a single recipient", ErrInvalidOptions, key.Algorithm)
+		}
+		recipientHeader := *protected
+		recipientHeader.Algorithm, recipientHeader.KeyID = key.Algorithm, key.KeyID
+		var encapsulatedKey, encryptedKey []byte
+		if cek, encapsulatedKey, encryptedKey, err = keyManagement.wrapKey(key, &recipientHeader, cek); err != nil {
+			return nil, err
+		}
+		headerJSON, err := (&JWEHeader{Algorithm: key.Algorithm, KeyID: key.KeyID, EncapsulatedKey: encapsulatedKey}).encode()
+		if err != nil {
+			return nil, err
+		}
+		recipient := jweJSONRecipient{EncryptedKey: joseBase64.EncodeToString(encryptedKey)}
+		if err := json.Unmarshal(headerJSON, &recipient.Header); err != nil {
+			return nil, err
+		}
+		encoded.Recipients[index] = recipient
+	}
+	authenticatedData := encodedProtected
+	if additionalData != nil {
+		encodedAdditionalData := joseBase64.EncodeToString(additionalData)
+		encoded.AdditionalData = &encodedAdditionalData
+		authenticatedData += "." + encodedAdditionalData
+	}
+	iv, ciphertext, tag, err := sealJWE(protected.Encryption, cek, plaintext, []byte(authenticatedData))
+	if err != nil {
+		return nil, err
+	}
+	encodedCiphertext := joseBase64.EncodeToString(ciphertext)
+	encoded.IV, encoded.Ciphertext, encoded.Tag = joseBase64.EncodeToString(iv), &encodedCiphertext, joseBase64.EncodeToString(tag)
+	Logger().Debug("EncryptJWEJSON", "recipients", len(keys), "encryption", string(protected.Encryption), "plaintext_size", len(plaintext))
+	return json.Marshal(encoded)
+}
+
+// DecryptJWEJSON decrypts a JWE in the general or flattened JSON serialization with a private JWK. It returns the
+// plaintext, the decoded "aad" member or nil, and the header of the recipient that decrypts, merged from the protected,
+// shared unprotected and recipient headers. It returns ErrDecryptionFailed if no recipient decrypts.
+func DecryptJWEJSON(key *JWK, jwe []byte) (plaintext []byte, additionalData []byte, header *JWEHeader, decryptError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			plaintext, additionalData, header, decryptError = nil, nil, nil, newPanicError("DecryptJWEJSON", recoveredPanic)
+		}
+	}()
+	if key == nil {
+		return nil, nil, nil, fmt.Errorf("%w: decrypting a JWE needs a private JWK", ErrInvalidKey)
+	}
+	return decryptJWEJSON(jwe, func(*JWEHeader) *JWK { return key })
+}
+
+// DecryptJWEJSON decrypts a JWE in the general or flattened JSON serialization with the private keys of the set, which
+// are found by the key ID and algorithm of each recipient. A JWE none of whose key IDs is in the set returns
+// ErrKeyNotFound; otherwise it behaves like DecryptJWEJSON.
+func (set *JWKSet) DecryptJWEJSON(jwe []byte) (plaintext []byte, additionalData []byte, header *JWEHeader, decryptError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			plaintext, additionalData, header, decryptError = nil, nil, nil, newPanicError("JWKSet.DecryptJWEJSON", recoveredPanic)
+		}
+	}()
+	return decryptJWEJSON(jwe, set.decryptionKey)
+}
+
+// decryptJWEJSON decrypts a JSON-serialized JWE with the keys findKey returns for its recipients.
+func decryptJWEJSON(jwe []byte, findKey func(header *JWEHeader) *JWK) ([]byte, []byte, *JWEHeader, error) {
+	message, err := parseJWEJSON(bytes.TrimSpace(jwe))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	plaintext, header, err := message.decrypt(findKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	Logger().Debug("DecryptJWEJSON", "algorithm", string(header.Algorithm), "recipients", len(message.recipients), "plaintext_size", len(plaintext))
+	return plaintext, message.additionalData, header, nil
+}
@@ -0,0 +1,41 @@
+package pq
+
+import "testing"
+
+// newBenchmarkKEMJWK returns a private ML-KEM-768 JWK for key agreement with AES-192 key wrapping.
+func newBenchmarkKEMJWK(b *testing.B) *JWK {
+	b.Helper()
+	keyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	if err != nil {
+		b.Fatalf("failed to generate ML-KEM key pair: %v", err)
+	}
+	jwk, err := NewMLKEMJWK(keyPair, JOSEMLKEM768A192KW, "bench")
+	if err != nil {
+		b.Fatalf("failed to make JWK: %v", err)
+	}
+	return jwk
+}
+
+func BenchmarkEncryptJWE(b *testing.B) {
+	jwk := newBenchmarkKEMJWK(b).Public()
+	plaintext := make([]byte, 1024)
+	for b.Loop() {
+		if _, err := EncryptJWE(jwk, plaintext, nil); err != nil {
+			b.Fatalf("failed to encrypt: %v", err)
+		}
+	}
+}
+
+func BenchmarkDecryptJWE(b *testing.B) {
+	jwk := newBenchmarkKEMJWK(b)
+	jwe, err := EncryptJWE(jwk, make([]byte, 1024), nil)
+	if err != nil {
+		b.Fatalf("failed to encrypt: %v", err)
+	}
+	set := &JWKSet{Keys: []*JWK{jwk}}
+	for b.Loop() {
+		if _, _, err := set.DecryptJWE(jwe); err != nil {
+			b.Fatalf("failed to decrypt: %v", err)
+		}
+	}
+}
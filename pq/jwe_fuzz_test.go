@@ -0,0 +1,41 @@
+package pq
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func FuzzDecryptJWE(f *testing.F) {
+	keyPair, _ := GenerateMLKEMKeyPair(MLKEM768)
+	jwk, _ := NewMLKEMJWK(keyPair, JOSEMLKEM768A192KW, "fuzz")
+	jwe, _ := EncryptJWE(jwk, []byte("fuzz"), nil)
+	f.Add(jwe)
+	f.Add("....")
+	f.Fuzz(func(t *testing.T, jwe string) {
+		plaintext, _, err := DecryptJWE(jwk, jwe)
+		if errors.Is(err, ErrInternalPanic) {
+			t.Fatalf("panic decrypting JWE: %v", err)
+		}
+		if err == nil && !bytes.Equal(plaintext, []byte("fuzz")) {
+			t.Errorf("decrypted a forged JWE to %q", plaintext)
+		}
+	})
+}
+
+func FuzzDecryptJWEJSON(f *testing.F) {
+	keyPair, _ := GenerateMLKEMKeyPair(MLKEM1024)
+	jwk, _ := NewMLKEMJWK(keyPair, JOSEMLKEM1024A256KW, "fuzz")
+	jwe, _ := EncryptJWEJSON([]*JWK{jwk}, []byte("fuzz"), []byte("aad"), nil)
+	f.Add(jwe)
+	f.Add([]byte(`{"ciphertext":"","header":{"alg":"ML-KEM-1024+A256KW","enc":"A256GCM","kid":"fuzz"}}`))
+	f.Fuzz(func(t *testing.T, jwe []byte) {
+		plaintext, _, _, err := (&JWKSet{Keys: []*JWK{jwk}}).DecryptJWEJSON(jwe)
+		if errors.Is(err, ErrInternalPanic) {
+			t.Fatalf("panic decrypting JWE: %v", err)
+		}
+		if err == nil && !bytes.Equal(plaintext, []byte("fuzz")) {
+			t.Errorf("decrypted a forged JWE to %q", plaintext)
+		}
+	})
+}
@@ -0,0 +1,770 @@
+package pq
+
+import (
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha3"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp/aes/keywrap"
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem1024"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+	"github.com/cloudflare/circl/kem/xwing"
+	jose "github.com/go-jose/go-jose/v4"
+	josecipher "github.com/go-jose/go-jose/v4/cipher"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// jweAlgorithms are the JWE key agreement algorithms, in the order of the fixture keys.
+var jweAlgorithms = []JOSEAlgorithm{JOSEMLKEM768, JOSEMLKEM1024, JOSEMLKEM768A192KW, JOSEMLKEM1024A256KW, JOSEHPKE10KE, JOSEHPKE11KE}
+
+// jweEncryptions are the JWE content encryption algorithms.
+var jweEncryptions = []JOSEEncryption{JOSEA128GCM, JOSEA192GCM, JOSEA256GCM, JOSEA128CBCHS256, JOSEA192CBCHS384, JOSEA256CBCHS512}
+
+// newTestKEMJWK returns a private JWK of a new key for a JWE key agreement algorithm.
+func newTestKEMJWK(t *testing.T, algorithm JOSEAlgorithm, keyID string) *JWK {
+	t.Helper()
+	keyManagement, ok := algorithm.jweKeyManagement()
+	require.True(t, ok)
+	if keyManagement.isHPKE() {
+		keyPair, err := GenerateXWingKeyPair()
+		require.NoError(t, err)
+		jwk, err := NewXWingJWK(keyPair, algorithm, keyID)
+		require.NoError(t, err)
+		return jwk
+	}
+	keyPair, err := GenerateMLKEMKeyPair(keyManagement.mlkemParameterSet)
+	require.NoError(t, err)
+	jwk, err := NewMLKEMJWK(keyPair, algorithm, keyID)
+	require.NoError(t, err)
+	return jwk
+}
+
+// For TestJWEFixtures: testdata/jose/jwe-jwks.json holds one key per JWE algorithm, derived from the seed 00 01 ... 3f
+// (its first 32 bytes for X-Wing), jwes.txt one compact JWE to each of them and jwe-general.json a JWE to the two key
+// wrapping keys. They were made with EncryptJWE and EncryptJWEJSON, not taken from the drafts, whose example vectors
+// could not be obtained, so besides pinning the encodings each JWE is also decrypted by openJWEFixture, which builds
+// the key agreement from circl ML-KEM and X-Wing, the Concat KDF, AES key unwrapping and CBC-HMAC of go-jose, and the
+// SHAKE256 HPKE key schedule written out instead of from jwe.go and hpke.go.
+func TestJWEFixtures(t *testing.T) {
+	set, err := ParseJWKSet(readJOSETestFile(t, "jwe-jwks.json"))
+	require.NoError(t, err)
+	require.Len(t, set.Keys, len(jweAlgorithms))
+	seed := make([]byte, MLKEMSeedSize)
+	for index := range seed {
+		seed[index] = byte(index)
+	}
+	for index, algorithm := range jweAlgorithms {
+		jwk := set.Keys[index]
+		require.Equal(t, algorithm, jwk.Algorithm)
+		require.Equal(t, string(algorithm)+"-test", jwk.KeyID)
+		require.True(t, jwk.IsPrivate())
+		keyManagement, _ := algorithm.jweKeyManagement()
+		keySeed := seed
+		if keyManagement.isHPKE() {
+			keySeed = seed[:XWingSeedSize]
+		}
+		_, publicKey, err := keyManagement.deriveKeyPair(keySeed)
+		require.NoError(t, err)
+		require.True(t, publicKey.Equal(jwk.PublicKey.(kem.PublicKey)), algorithm)
+	}
+	encoded, err := json.MarshalIndent(set, "", "  ")
+	require.NoError(t, err)
+	require.Equal(t, string(readJOSETestFile(t, "jwe-jwks.json")), string(encoded)+"\n")
+
+	jwes := strings.Fields(string(readJOSETestFile(t, "jwes.txt")))
+	require.Len(t, jwes, len(jweAlgorithms))
+	for index, jwe := range jwes {
+		plaintext, header, err := set.DecryptJWE(jwe)
+		require.NoError(t, err)
+		require.Equal(t, "Hello, post-quantum JOSE!", string(plaintext))
+		require.Equal(t, jweAlgorithms[index], header.Algorithm)
+		require.Equal(t, jweEncryptions[index], header.Encryption)
+		segments := strings.Split(jwe, ".")
+		require.Len(t, segments, 5)
+		plaintext = openJWEFixture(t, seed, segments[0], nil, segments[0], segments[1], segments[2], segments[3], segments[4])
+		require.Equal(t, "Hello, post-quantum JOSE!", string(plaintext))
+	}
+
+	plaintext, additionalData, _, err := set.DecryptJWEJSON(readJOSETestFile(t, "jwe-general.json"))
+	require.NoError(t, err)
+	require.Equal(t, "Hello, post-quantum JOSE!", string(plaintext))
+	require.Equal(t, "fixture", string(additionalData))
+	for _, index := range []int{2, 3} {
+		_, _, header, err := DecryptJWEJSON(set.Keys[index], readJOSETestFile(t, "jwe-general.json"))
+		require.NoError(t, err)
+		require.Equal(t, set.Keys[index].KeyID, header.KeyID)
+	}
+	var general struct {
+		Protected  string `json:"protected"`
+		AAD        string `json:"aad"`
+		IV         string `json:"iv"`
+		Ciphertext string `json:"ciphertext"`
+		Tag        string `json:"tag"`
+		Recipients []struct {
+			Header       json.RawMessage `json:"header"`
+			EncryptedKey string          `json:"encrypted_key"`
+		} `json:"recipients"`
+	}
+	require.NoError(t, json.Unmarshal(readJOSETestFile(t, "jwe-general.json"), &general))
+	require.Len(t, general.Recipients, 2)
+	for _, recipient := range general.Recipients {
+		plaintext = openJWEFixture(t, seed, general.Protected+"."+general.AAD, recipient.Header, general.Protected, recipient.EncryptedKey, general.IV, general.Ciphertext, general.Tag)
+		require.Equal(t, "Hello, post-quantum JOSE!", string(plaintext))
+	}
+}
+
+// openJWEFixture decrypts a JWE of TestJWEFixtures to the key derived from seed without the JWE code of gopq, from its
+// additional authenticated data, unprotected recipient header and base64url segments.
+func openJWEFixture(t *testing.T, seed []byte, authenticatedData string, unprotected json.RawMessage, protected string, encryptedKey string, iv string, ciphertext string, tag string) []byte {
+	t.Helper()
+	decode := func(segment string) []byte {
+		decoded, err := base64.RawURLEncoding.DecodeString(segment)
+		require.NoError(t, err)
+		return decoded
+	}
+	header := map[string]string{}
+	require.NoError(t, json.Unmarshal(decode(protected), &header))
+	if unprotected != nil {
+		require.NoError(t, json.Unmarshal(unprotected, &header))
+	}
+
+	cekSizes := map[string]int{"A128GCM": 16, "A192GCM": 24, "A256GCM": 32, "A128CBC-HS256": 32, "A192CBC-HS384": 48, "A256CBC-HS512": 64}
+	cekSize := cekSizes[header["enc"]]
+	require.NotZero(t, cekSize, header["enc"])
+	var cek []byte
+	if strings.HasPrefix(header["alg"], "HPKE-") {
+		cek = openHPKEFixtureKey(t, seed[:xwing.SeedSize], header["alg"], header["enc"], decode(header["ek"]), decode(encryptedKey))
+	} else {
+		schemes := map[string]kem.Scheme{"ML-KEM-768": mlkem768.Scheme(), "ML-KEM-1024": mlkem1024.Scheme()}
+		keyWrapSizes := map[string]int{"": 0, "A192KW": 24, "A256KW": 32}
+		kemName, keyWrap, _ := strings.Cut(header["alg"], "+")
+		scheme, keyWrapSize := schemes[kemName], keyWrapSizes[keyWrap]
+		require.NotNil(t, scheme, header["alg"])
+
+		_, privateKey := scheme.DeriveKeyPair(seed)
+		sharedSecret, err := scheme.Decapsulate(privateKey, decode(header["ek"]))
+		require.NoError(t, err)
+		algorithmID, size := header["enc"], cekSize
+		if keyWrapSize != 0 {
+			algorithmID, size = header["alg"], keyWrapSize
+		}
+		lengthPrefixed := func(data []byte) []byte {
+			return append(binary.BigEndian.AppendUint32(nil, uint32(len(data))), data...)
+		}
+		derivedKey := make([]byte, size)
+		kdf := josecipher.NewConcatKDF(crypto.SHA256, sharedSecret, lengthPrefixed([]byte(algorithmID)), lengthPrefixed(decode(header["apu"])), lengthPrefixed(decode(header["apv"])), binary.BigEndian.AppendUint32(nil, uint32(size*8)), nil)
+		_, err = io.ReadFull(kdf, derivedKey)
+		require.NoError(t, err)
+
+		cek = derivedKey
+		if keyWrapSize == 0 {
+			require.Empty(t, encryptedKey)
+		} else {
+			block, err := aes.NewCipher(derivedKey)
+			require.NoError(t, err)
+			cek, err = josecipher.KeyUnwrap(block, decode(encryptedKey))
+			require.NoError(t, err)
+		}
+	}
+	require.Len(t, cek, cekSize)
+
+	var aead cipher.AEAD
+	var err error
+	if strings.HasSuffix(header["enc"], "GCM") {
+		block, err := aes.NewCipher(cek)
+		require.NoError(t, err)
+		aead, err = cipher.NewGCM(block)
+		require.NoError(t, err)
+	} else {
+		aead, err = josecipher.NewCBCHMAC(cek, aes.NewCipher)
+		require.NoError(t, err)
+	}
+	plaintext, err := aead.Open(nil, decode(iv), append(decode(ciphertext), decode(tag)...), []byte(authenticatedData))
+	require.NoError(t, err)
+	return plaintext
+}
+
+// openHPKEFixtureKey opens the content encryption key of an HPKE-10-KE or HPKE-11-KE recipient of TestJWEFixtures
+// with circl X-Wing and the base mode of the one-stage SHAKE256 key schedule of draft-ietf-hpke-pq: the AEAD key and
+// base nonce are the first bytes of LabeledDerive(len(psk) || psk || len(shared_secret) || shared_secret, "secret",
+// mode || len(psk_id) || psk_id || len(info) || info, Nk + Nn + Nh).
+func openHPKEFixtureKey(t *testing.T, seed []byte, algorithm string, encryption string, encapsulatedKey []byte, encryptedKey []byte) []byte {
+	t.Helper()
+	aeadIDs := map[string]uint16{"HPKE-10-KE": 0x0002, "HPKE-11-KE": 0x0003}
+	aeadID := aeadIDs[algorithm]
+	require.NotZero(t, aeadID, algorithm)
+	_, privateKey := xwing.Scheme().DeriveKeyPair(seed)
+	sharedSecret, err := xwing.Scheme().Decapsulate(privateKey, encapsulatedKey)
+	require.NoError(t, err)
+
+	suiteID := binary.BigEndian.AppendUint16(binary.BigEndian.AppendUint16([]byte("HPKE"), 0x647a), 0x0011)
+	suiteID = binary.BigEndian.AppendUint16(suiteID, aeadID)
+	info := []byte("JOSE-HPKE rcpt\xff" + encryption + "\xff")
+	shake := sha3.NewSHAKE256()
+	for _, input := range [][]byte{
+		{0, 0}, binary.BigEndian.AppendUint16(nil, uint16(len(sharedSecret))), sharedSecret,
+		[]byte("HPKE-v1"), suiteID, binary.BigEndian.AppendUint16(nil, uint16(len("secret"))), []byte("secret"),
+		binary.BigEndian.AppendUint16(nil, 32+12+64),
+		{0, 0, 0}, binary.BigEndian.AppendUint16(nil, uint16(len(info))), info,
+	} {
+		shake.Write(input)
+	}
+	secret := make([]byte, 32+12)
+	shake.Read(secret)
+
+	var aead cipher.AEAD
+	if aeadID == 0x0002 {
+		block, err := aes.NewCipher(secret[:32])
+		require.NoError(t, err)
+		aead, err = cipher.NewGCM(block)
+		require.NoError(t, err)
+	} else {
+		aead, err = chacha20poly1305.New(secret[:32])
+		require.NoError(t, err)
+	}
+	cek, err := aead.Open(nil, secret[32:], encryptedKey, nil)
+	require.NoError(t, err)
+	return cek
+}
+
+// For TestJWEConcatKDF: the Concat KDF of ECDH-ES in go-jose, with the ECDH shared secret as Z, must agree with
+// jweConcatKDF, including for outputs longer than one SHA-256 block.
+func TestJWEConcatKDF(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	peerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecdhPrivateKey, err := privateKey.ECDH()
+	require.NoError(t, err)
+	ecdhPeerKey, err := peerKey.PublicKey.ECDH()
+	require.NoError(t, err)
+	sharedSecret, err := ecdhPrivateKey.ECDH(ecdhPeerKey)
+	require.NoError(t, err)
+
+	for _, size := range []int{16, 24, 32, 48, 64, 100} {
+		for _, partyInfo := range [][]byte{nil, []byte("Alice")} {
+			want := josecipher.DeriveECDHES("A256GCM", partyInfo, []byte("Bob"), privateKey, &peerKey.PublicKey, size)
+			require.Equal(t, want, jweConcatKDF(sharedSecret, "A256GCM", partyInfo, []byte("Bob"), size), size)
+		}
+	}
+}
+
+// For TestJWEGoJOSEInterop: JWEs with the A256KW key wrapping of RFC 7518, whose key wrap key is shared, take the KEM
+// out of the construction, so go-jose can check the content encryption, the protected header as additional
+// authenticated data, and the compact and JSON serializations in both directions.
+func TestJWEGoJOSEInterop(t *testing.T) {
+	keyWrapKey := make([]byte, 32)
+	_, err := rand.Read(keyWrapKey)
+	require.NoError(t, err)
+	plaintext := []byte("interoperable plaintext of some length")
+
+	for _, encryption := range jweEncryptions {
+		encrypter, err := jose.NewEncrypter(jose.ContentEncryption(encryption), jose.Recipient{Algorithm: jose.A256KW, Key: keyWrapKey}, nil)
+		require.NoError(t, err)
+		object, err := encrypter.EncryptWithAuthData(plaintext, []byte("aad"))
+		require.NoError(t, err)
+
+		// go-jose to gopq.
+		compact, err := encrypter.Encrypt(plaintext)
+		require.NoError(t, err)
+		serialized, err := compact.CompactSerialize()
+		require.NoError(t, err)
+		message, err := parseJWECompact(serialized)
+		require.NoError(t, err)
+		cek, err := keywrap.Unwrap(keyWrapKey, message.recipients[0].encryptedKey)
+		require.NoError(t, err)
+		decrypted, err := message.open(encryption, cek)
+		require.NoError(t, err, encryption)
+		require.Equal(t, plaintext, decrypted)
+
+		message, err = parseJWEJSON([]byte(object.FullSerialize()))
+		require.NoError(t, err)
+		require.Equal(t, "aad", string(message.additionalData))
+		cek, err = keywrap.Unwrap(keyWrapKey, message.recipients[0].encryptedKey)
+		require.NoError(t, err)
+		decrypted, err = message.open(encryption, cek)
+		require.NoError(t, err, encryption)
+		require.Equal(t, plaintext, decrypted)
+
+		// gopq to go-jose.
+		keySize, _, _ := encryption.sizes()
+		cek = make([]byte, keySize)
+		_, err = rand.Read(cek)
+		require.NoError(t, err)
+		encryptedKey, err := keywrap.Wrap(keyWrapKey, cek)
+		require.NoError(t, err)
+		serialized, err = sealJWECompact(&JWEHeader{Algorithm: "A256KW", Encryption: encryption, KeyID: "interop"}, cek, encryptedKey, plaintext)
+		require.NoError(t, err)
+		parsed, err := jose.ParseEncrypted(serialized, []jose.KeyAlgorithm{jose.A256KW}, []jose.ContentEncryption{jose.ContentEncryption(encryption)})
+		require.NoError(t, err)
+		require.Equal(t, "interop", parsed.Header.KeyID)
+		decrypted, err = parsed.Decrypt(keyWrapKey)
+		require.NoError(t, err, encryption)
+		require.Equal(t, plaintext, decrypted)
+	}
+}
+
+func TestJWECompactRoundTrip(t *testing.T) {
+	for index, algorithm := range jweAlgorithms {
+		jwk := newTestKEMJWK(t, algorithm, "jwe-"+string(algorithm))
+		for _, encryption := range []JOSEEncryption{"", jweEncryptions[index]} {
+			jwe, err := EncryptJWE(jwk.Public(), []byte("secret"), &JWEHeader{Encryption: encryption, ContentType: "text/plain", PartyUInfo: []byte("Alice"), PartyVInfo: []byte("Bob")})
+			require.NoError(t, err)
+			require.Len(t, strings.Split(jwe, "."), 5)
+			plaintext, header, err := DecryptJWE(jwk, jwe)
+			require.NoError(t, err, algorithm)
+			require.Equal(t, "secret", string(plaintext))
+			require.Equal(t, algorithm, header.Algorithm)
+			require.Equal(t, jwk.KeyID, header.KeyID)
+			require.Equal(t, "text/plain", header.ContentType)
+			require.Equal(t, "Alice", string(header.PartyUInfo))
+			require.Equal(t, "Bob", string(header.PartyVInfo))
+			require.NotEmpty(t, header.EncapsulatedKey)
+			if encryption == "" {
+				require.Equal(t, JOSEA256GCM, header.Encryption)
+			}
+
+			// The encrypted key is empty for direct key agreement only.
+			keyManagement, _ := algorithm.jweKeyManagement()
+			require.Equal(t, keyManagement.isDirect(), strings.Split(jwe, ".")[1] == "", algorithm)
+
+			set := &JWKSet{Keys: []*JWK{newTestKEMJWK(t, algorithm, "other"), jwk}}
+			plaintext, _, err = set.DecryptJWE(jwe)
+			require.NoError(t, err)
+			require.Equal(t, "secret", string(plaintext))
+		}
+	}
+
+	// An empty plaintext and a key ID from the header.
+	jwk := newTestKEMJWK(t, JOSEMLKEM768, "key")
+	jwe, err := EncryptJWE(jwk, nil, &JWEHeader{KeyID: "other", Type: "JWT"})
+	require.NoError(t, err)
+	plaintext, header, err := DecryptJWE(jwk, jwe)
+	require.NoError(t, err)
+	require.Empty(t, plaintext)
+	require.Equal(t, "other", header.KeyID)
+	require.Equal(t, "JWT", header.Type)
+}
+
+// For TestJWEJSONSerialization: a JWE to several key wrapping recipients decrypts with each of their keys, and the
+// flattened serialization of a single recipient decrypts too.
+func TestJWEJSONSerialization(t *testing.T) {
+	keys := []*JWK{
+		newTestKEMJWK(t, JOSEMLKEM768A192KW, "alice"),
+		newTestKEMJWK(t, JOSEMLKEM1024A256KW, "bob"),
+		newTestKEMJWK(t, JOSEMLKEM1024A256KW, "carol"),
+	}
+	publicKeys := []*JWK{keys[0].Public(), keys[1].Public(), keys[2].Public()}
+	jwe, err := EncryptJWEJSON(publicKeys, []byte("to all"), []byte("metadata"), &JWEHeader{Encryption: JOSEA128CBCHS256, Type: "example"})
+	require.NoError(t, err)
+
+	var encoded map[string]any
+	require.NoError(t, json.Unmarshal(jwe, &encoded))
+	require.Len(t, encoded["recipients"], 3)
+	require.Equal(t, joseBase64.EncodeToString([]byte("metadata")), encoded["aad"])
+	for _, key := range keys {
+		plaintext, additionalData, header, err := DecryptJWEJSON(key, jwe)
+		require.NoError(t, err)
+		require.Equal(t, "to all", string(plaintext))
+		require.Equal(t, "metadata", string(additionalData))
+		require.Equal(t, key.KeyID, header.KeyID)
+		require.Equal(t, key.Algorithm, header.Algorithm)
+		require.Equal(t, "example", header.Type)
+		require.Equal(t, JOSEA128CBCHS256, header.Encryption)
+	}
+	plaintext, _, header, err := (&JWKSet{Keys: keys[1:2]}).DecryptJWEJSON(jwe)
+	require.NoError(t, err)
+	require.Equal(t, "to all", string(plaintext))
+	require.Equal(t, "bob", header.KeyID)
+	_, _, _, err = DecryptJWEJSON(newTestKEMJWK(t, JOSEMLKEM768A192KW, "alice"), jwe)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+	_, _, _, err = (&JWKSet{Keys: publicKeys}).DecryptJWEJSON(jwe)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	// Direct key agreement with a single recipient, without additional data, in the flattened serialization.
+	jwk := newTestKEMJWK(t, JOSEMLKEM1024, "direct")
+	jwe, err = EncryptJWEJSON([]*JWK{jwk}, []byte("just one"), nil, nil)
+	require.NoError(t, err)
+	var general map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(jwe, &general))
+	require.NotContains(t, general, "aad")
+	var recipients []map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(general["recipients"], &recipients))
+	require.Len(t, recipients, 1)
+	require.NotContains(t, recipients[0], "encrypted_key")
+	delete(general, "recipients")
+	general["header"] = recipients[0]["header"]
+	flattened, err := json.Marshal(general)
+	require.NoError(t, err)
+	plaintext, additionalData, header, err := DecryptJWEJSON(jwk, flattened)
+	require.NoError(t, err)
+	require.Equal(t, "just one", string(plaintext))
+	require.Nil(t, additionalData)
+	require.Equal(t, JOSEMLKEM1024, header.Algorithm)
+}
+
+// For TestJWEDecryptionFailures: a JWE changed anywhere, or decrypted with another key, fails to decrypt.
+func TestJWEDecryptionFailures(t *testing.T) {
+	for _, algorithm := range []JOSEAlgorithm{JOSEMLKEM768, JOSEMLKEM1024A256KW} {
+		jwk := newTestKEMJWK(t, algorithm, "key")
+		jwe, err := EncryptJWE(jwk, []byte("secret"), &JWEHeader{Encryption: JOSEA128CBCHS256})
+		require.NoError(t, err)
+		segments := strings.Split(jwe, ".")
+
+		for index := range segments {
+			decoded, err := joseBase64.DecodeString(segments[index])
+			require.NoError(t, err)
+			if len(decoded) == 0 {
+				continue
+			}
+			decoded[len(decoded)/2] ^= 0x01
+			tampered := append([]string(nil), segments...)
+			tampered[index] = joseBase64.EncodeToString(decoded)
+			_, _, err = DecryptJWE(jwk, strings.Join(tampered, "."))
+			require.Error(t, err, "%s segment %d", algorithm, index)
+			if index > 0 {
+				require.ErrorIs(t, err, ErrDecryptionFailed, "%s segment %d", algorithm, index)
+			}
+		}
+
+		// A changed KEM ciphertext decapsulates to another shared secret (ML-KEM implicit rejection).
+		header, err := parseJWEHeader(segments[0], 0)
+		require.NoError(t, err)
+		header.EncapsulatedKey[0] ^= 0x01
+		headerJSON, err := header.encode()
+		require.NoError(t, err)
+		tampered := append([]string{joseBase64.EncodeToString(headerJSON)}, segments[1:]...)
+		_, _, err = DecryptJWE(jwk, strings.Join(tampered, "."))
+		require.ErrorIs(t, err, ErrDecryptionFailed)
+
+		_, _, err = DecryptJWE(newTestKEMJWK(t, algorithm, "key"), jwe)
+		require.ErrorIs(t, err, ErrDecryptionFailed)
+		_, _, err = DecryptJWE(newTestKEMJWK(t, JOSEMLKEM768A192KW, "key"), jwe)
+		require.ErrorIs(t, err, ErrDecryptionFailed)
+		_, _, err = DecryptJWE(newTestJWK(t, MLDSA65, "key"), jwe)
+		require.ErrorIs(t, err, ErrDecryptionFailed)
+		_, _, err = DecryptJWE(jwk.Public(), jwe)
+		require.ErrorIs(t, err, ErrInvalidKey)
+		_, _, err = (&JWKSet{Keys: []*JWK{newTestKEMJWK(t, algorithm, "other")}}).DecryptJWE(jwe)
+		require.ErrorIs(t, err, ErrKeyNotFound)
+		_, _, err = (&JWKSet{Keys: []*JWK{jwk.Public()}}).DecryptJWE(jwe)
+		require.ErrorIs(t, err, ErrKeyNotFound)
+	}
+}
+
+// For TestJWEFormatErrors: malformed JWEs are rejected before any decapsulation.
+func TestJWEFormatErrors(t *testing.T) {
+	jwk := newTestKEMJWK(t, JOSEMLKEM768A192KW, "key")
+	jwe, err := EncryptJWE(jwk, []byte("secret"), nil)
+	require.NoError(t, err)
+	segments := strings.Split(jwe, ".")
+	withHeader := func(header string) string {
+		return strings.Join(append([]string{joseBase64.EncodeToString([]byte(header))}, segments[1:]...), ".")
+	}
+	withSegment := func(index int, segment string) string {
+		modified := append([]string(nil), segments...)
+		modified[index] = segment
+		return strings.Join(modified, ".")
+	}
+
+	for _, test := range []struct {
+		name string
+		jwe  string
+		err  error
+	}{
+		{"empty", "", ErrInvalidJOSE},
+		{"JWS", "a.b.c", ErrInvalidJOSE},
+		{"padded header", withSegment(0, segments[0]+"="), ErrInvalidJOSE},
+		{"line break", withSegment(3, segments[3][:4]+"\n"+segments[3][4:]), ErrInvalidJOSE},
+		{"header not an object", withHeader(`"ML-KEM-768+A192KW"`), ErrInvalidJOSE},
+		{"no enc", withHeader(`{"alg":"ML-KEM-768+A192KW"}`), ErrInvalidJOSE},
+		{"no alg", withHeader(`{"enc":"A256GCM"}`), ErrInvalidJOSE},
+		{"crit", withHeader(`{"alg":"ML-KEM-768+A192KW","enc":"A256GCM","crit":["exp"],"exp":1}`), ErrInvalidJOSE},
+		{"zip", withHeader(`{"alg":"ML-KEM-768+A192KW","enc":"A256GCM","zip":"DEF"}`), ErrInvalidJOSE},
+		{"numeric kid", withHeader(`{"alg":"ML-KEM-768+A192KW","enc":"A256GCM","kid":1}`), ErrInvalidJOSE},
+		{"malformed ek", withHeader(`{"alg":"ML-KEM-768+A192KW","enc":"A256GCM","ek":"!"}`), ErrInvalidJOSE},
+		{"unknown enc", withHeader(`{"alg":"ML-KEM-768+A192KW","enc":"A256CCM"}`), ErrUnknownAlgorithm},
+		{"short iv", withSegment(2, segments[2][:8]), ErrInvalidJOSE},
+		{"short tag", withSegment(4, segments[4][:8]), ErrInvalidJOSE},
+		{"short encrypted key", withSegment(1, segments[1][:8]), ErrInvalidJOSE},
+		{"missing encrypted key", withSegment(1, ""), ErrInvalidJOSE},
+	} {
+		_, _, err := DecryptJWE(jwk, test.jwe)
+		require.ErrorIs(t, err, test.err, test.name)
+	}
+
+	// The KEM ciphertext must have the size of the parameter set.
+	header, err := parseJWEHeader(segments[0], 0)
+	require.NoError(t, err)
+	header.EncapsulatedKey = header.EncapsulatedKey[1:]
+	headerJSON, err := header.encode()
+	require.NoError(t, err)
+	_, _, err = DecryptJWE(jwk, withHeader(string(headerJSON)))
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	// A direct key agreement JWE must not have an encrypted key.
+	direct := newTestKEMJWK(t, JOSEMLKEM768, "direct")
+	jwe, err = EncryptJWE(direct, []byte("secret"), nil)
+	require.NoError(t, err)
+	directSegments := strings.Split(jwe, ".")
+	directSegments[1] = segments[1]
+	_, _, err = DecryptJWE(direct, strings.Join(directSegments, "."))
+	require.ErrorIs(t, err, ErrInvalidJOSE)
+
+	jsonJWE, err := EncryptJWEJSON([]*JWK{jwk}, []byte("secret"), nil, nil)
+	require.NoError(t, err)
+	modified := func(change func(encoded map[string]any)) []byte {
+		var encoded map[string]any
+		require.NoError(t, json.Unmarshal(jsonJWE, &encoded))
+		change(encoded)
+		data, err := json.Marshal(encoded)
+		require.NoError(t, err)
+		return data
+	}
+	recipient := func(encoded map[string]any) map[string]any {
+		return encoded["recipients"].([]any)[0].(map[string]any)
+	}
+	for _, test := range []struct {
+		name string
+		jwe  []byte
+		err  error
+	}{
+		{"not JSON", []byte("{"), ErrInvalidJOSE},
+		{"no ciphertext", modified(func(encoded map[string]any) { delete(encoded, "ciphertext") }), ErrInvalidJOSE},
+		{"no recipients", modified(func(encoded map[string]any) { encoded["recipients"] = []any{} }), ErrInvalidJOSE},
+		{"general and flattened", modified(func(encoded map[string]any) { encoded["header"] = map[string]any{"kid": "x"} }), ErrInvalidJOSE},
+		{"repeated parameter", modified(func(encoded map[string]any) { encoded["unprotected"] = map[string]any{"enc": "A256GCM"} }), ErrInvalidJOSE},
+		{"recipient enc", modified(func(encoded map[string]any) { recipient(encoded)["header"].(map[string]any)["enc"] = "A128GCM" }), ErrInvalidJOSE},
+		{"unprotected crit", modified(func(encoded map[string]any) { encoded["unprotected"] = map[string]any{"crit": []string{"x"}} }), ErrInvalidJOSE},
+		{"malformed aad", modified(func(encoded map[string]any) { encoded["aad"] = "!" }), ErrInvalidJOSE},
+		{"added aad", modified(func(encoded map[string]any) { encoded["aad"] = "" }), ErrDecryptionFailed},
+	} {
+		_, _, _, err := DecryptJWEJSON(jwk, test.jwe)
+		require.ErrorIs(t, err, test.err, test.name)
+	}
+}
+
+func TestJWEEncryptErrors(t *testing.T) {
+	jwk := newTestKEMJWK(t, JOSEMLKEM1024, "key")
+	keyWrap := newTestKEMJWK(t, JOSEMLKEM1024A256KW, "wrap")
+
+	_, err := EncryptJWE(nil, nil, nil)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = EncryptJWE(newTestJWK(t, MLDSA44, "sig"), nil, nil)
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+	_, err = EncryptJWE(jwk, nil, &JWEHeader{Encryption: "A256CCM"})
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+	_, err = EncryptJWE(jwk, nil, &JWEHeader{Algorithm: JOSEMLKEM768})
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = EncryptJWE(jwk, nil, &JWEHeader{EncapsulatedKey: []byte{1}})
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = EncryptJWE(&JWK{Algorithm: JOSEMLKEM768, PublicKey: jwk.PublicKey}, nil, nil)
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = EncryptJWEJSON(nil, nil, nil, nil)
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = EncryptJWEJSON([]*JWK{jwk, keyWrap}, nil, nil, nil)
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = EncryptJWEJSON([]*JWK{keyWrap, nil}, nil, nil, nil)
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = EncryptJWEJSON([]*JWK{keyWrap}, nil, nil, &JWEHeader{KeyID: "wrap"})
+	require.ErrorIs(t, err, ErrInvalidOptions)
+}
+
+// For TestJWEHybridAlgorithms: hybrid key agreement is left out. JWKs cannot be made or parsed for the algorithms of the
+// HPKE-based hybrid draft or for X-Wing, and a JWE recipient naming one is never decrypted.
+func TestJWEHybridAlgorithms(t *testing.T) {
+	xwingKeyPair, err := GenerateXWingKeyPair()
+	require.NoError(t, err)
+	mlkemKeyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	require.NoError(t, err)
+	_, err = NewXWingJWK(nil, JOSEHPKE10KE, "hybrid")
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	// HPKE key encryption needs an X-Wing key, and ML-KEM key agreement an ML-KEM one.
+	for _, algorithm := range []JOSEAlgorithm{JOSEHPKE10KE, JOSEHPKE11KE} {
+		_, err = NewMLKEMJWK(mlkemKeyPair, algorithm, "hybrid")
+		require.ErrorIs(t, err, ErrInvalidKey, algorithm)
+		_, err = NewKEMPublicJWK(mlkemKeyPair.PublicKey, algorithm, "hybrid")
+		require.ErrorIs(t, err, ErrInvalidKey, algorithm)
+	}
+	_, err = NewXWingJWK(xwingKeyPair, JOSEMLKEM768, "hybrid")
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = NewKEMPublicJWK(xwingKeyPair.PublicKey, JOSEMLKEM768A192KW, "hybrid")
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	// X-Wing has no ML-KEM style key agreement algorithms.
+	publicKeyBytes, err := xwingKeyPair.PublicKey.MarshalBinary()
+	require.NoError(t, err)
+	for _, algorithm := range []JOSEAlgorithm{"X-Wing", "X-Wing+A256KW"} {
+		_, err = NewXWingJWK(xwingKeyPair, algorithm, "hybrid")
+		require.ErrorIs(t, err, ErrUnknownAlgorithm, algorithm)
+		_, err = EncryptJWE(&JWK{Algorithm: algorithm, PublicKey: xwingKeyPair.PublicKey}, []byte("secret"), nil)
+		require.ErrorIs(t, err, ErrUnknownAlgorithm, algorithm)
+		_, err = ParseJWK([]byte(`{"kty":"AKP","alg":"` + string(algorithm) + `","pub":"` + joseBase64.EncodeToString(publicKeyBytes) + `"}`))
+		require.ErrorIs(t, err, ErrUnknownAlgorithm, algorithm)
+	}
+
+	// The content encryption algorithm is in the HPKE info, so changing "enc" fails to open the encrypted key, as does
+	// changing the encrypted key, while a missing or short "ek" is no HPKE encapsulated key at all.
+	jwk, err := NewXWingJWK(xwingKeyPair, JOSEHPKE11KE, "hybrid")
+	require.NoError(t, err)
+	jwe, err := EncryptJWE(jwk.Public(), []byte("secret"), &JWEHeader{Encryption: JOSEA256GCM})
+	require.NoError(t, err)
+	plaintext, header, err := DecryptJWE(jwk, jwe)
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(plaintext))
+	require.Len(t, header.EncapsulatedKey, XWingCiphertextSize)
+	segments := strings.Split(jwe, ".")
+	withHeader := func(header *JWEHeader) string {
+		headerJSON, err := header.encode()
+		require.NoError(t, err)
+		return strings.Join(append([]string{joseBase64.EncodeToString(headerJSON)}, segments[1:]...), ".")
+	}
+	changed := *header
+	changed.Encryption = JOSEA128GCM
+	_, _, err = DecryptJWE(jwk, withHeader(&changed))
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+	encryptedKey, err := joseBase64.DecodeString(segments[1])
+	require.NoError(t, err)
+	encryptedKey[0] ^= 1
+	_, _, err = DecryptJWE(jwk, strings.Join([]string{segments[0], joseBase64.EncodeToString(encryptedKey), segments[2], segments[3], segments[4]}, "."))
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+	changed = *header
+	changed.EncapsulatedKey = header.EncapsulatedKey[:XWingCiphertextSize-1]
+	_, _, err = DecryptJWE(jwk, withHeader(&changed))
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+	changed.EncapsulatedKey = nil
+	_, _, err = DecryptJWE(jwk, withHeader(&changed))
+	require.Error(t, err)
+	_, _, err = DecryptJWE(newTestKEMJWK(t, JOSEHPKE11KE, "hybrid"), jwe)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+	_, _, err = DecryptJWE(newTestKEMJWK(t, JOSEHPKE10KE, "hybrid"), jwe)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+
+	// HPKE key encryption and ML-KEM key wrapping recipients share one content encryption key.
+	keys := []*JWK{jwk, newTestKEMJWK(t, JOSEMLKEM1024A256KW, "wrapped")}
+	jsonJWE, err := EncryptJWEJSON([]*JWK{keys[0].Public(), keys[1].Public()}, []byte("to both"), nil, nil)
+	require.NoError(t, err)
+	for _, key := range keys {
+		plaintext, _, header, err := DecryptJWEJSON(key, jsonJWE)
+		require.NoError(t, err)
+		require.Equal(t, "to both", string(plaintext))
+		require.Equal(t, key.Algorithm, header.Algorithm)
+	}
+}
+
+func TestJWEJWK(t *testing.T) {
+	for _, algorithm := range jweAlgorithms {
+		jwk := newTestKEMJWK(t, algorithm, "kem")
+		require.Equal(t, "enc", jwk.Use)
+		encoded, err := json.Marshal(jwk)
+		require.NoError(t, err)
+		parsed, err := ParseJWK(encoded)
+		require.NoError(t, err)
+		require.True(t, parsed.IsPrivate())
+		reencoded, err := json.Marshal(parsed)
+		require.NoError(t, err)
+		require.JSONEq(t, string(encoded), string(reencoded))
+
+		var members map[string]string
+		require.NoError(t, json.Unmarshal(encoded, &members))
+		require.Equal(t, "AKP", members["kty"])
+		seed, err := joseBase64.DecodeString(members["priv"])
+		require.NoError(t, err)
+		if keyManagement, _ := algorithm.jweKeyManagement(); keyManagement.isHPKE() {
+			require.Len(t, seed, XWingSeedSize)
+		} else {
+			require.Len(t, seed, MLKEMSeedSize)
+		}
+
+		publicEncoded, err := json.Marshal(jwk.Public())
+		require.NoError(t, err)
+		require.NotContains(t, string(publicEncoded), "priv")
+		thumbprint, err := jwk.Thumbprint()
+		require.NoError(t, err)
+		publicParsed, err := ParseJWK(publicEncoded)
+		require.NoError(t, err)
+		publicThumbprint, err := publicParsed.Thumbprint()
+		require.NoError(t, err)
+		require.Equal(t, thumbprint, publicThumbprint)
+
+		fromPublic, err := NewKEMPublicJWK(jwk.PublicKey.(kem.PublicKey), algorithm, "kem")
+		require.NoError(t, err)
+		fromPublicEncoded, err := json.Marshal(fromPublic)
+		require.NoError(t, err)
+		require.JSONEq(t, string(publicEncoded), string(fromPublicEncoded))
+	}
+
+	// Keys must belong to the algorithm, and private keys must have their seed.
+	keyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	require.NoError(t, err)
+	_, err = NewMLKEMJWK(keyPair, JOSEMLKEM1024, "kem")
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = NewMLKEMJWK(keyPair, JOSEMLDSA65, "kem")
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+	_, err = NewMLKEMJWK(&MLKEMKeyPair{ParameterSet: MLKEM768, PublicKey: keyPair.PublicKey, PrivateKey: keyPair.PrivateKey}, JOSEMLKEM768, "kem")
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = NewMLKEMJWK(nil, JOSEMLKEM768, "kem")
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, err = NewKEMPublicJWK(keyPair.PublicKey, JOSEMLKEM1024A256KW, "kem")
+	require.ErrorIs(t, err, ErrInvalidKey)
+	otherKeyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	require.NoError(t, err)
+	_, err = json.Marshal(&JWK{Algorithm: JOSEMLKEM768, PublicKey: keyPair.PublicKey, PrivateKey: otherKeyPair})
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	jwk, err := NewMLKEMJWK(keyPair, JOSEMLKEM768, "kem")
+	require.NoError(t, err)
+	encoded, err := json.Marshal(jwk)
+	require.NoError(t, err)
+	var members map[string]string
+	require.NoError(t, json.Unmarshal(encoded, &members))
+	otherEncoded, err := json.Marshal(&JWK{Algorithm: JOSEMLKEM768, PublicKey: otherKeyPair.PublicKey, PrivateKey: otherKeyPair})
+	require.NoError(t, err)
+	var otherMembers map[string]string
+	require.NoError(t, json.Unmarshal(otherEncoded, &otherMembers))
+	for _, test := range []struct {
+		name    string
+		members map[string]string
+		err     error
+	}{
+		{"mismatched priv", map[string]string{"priv": otherMembers["priv"]}, ErrInvalidJOSE},
+		{"short seed", map[string]string{"priv": members["priv"][:40]}, ErrInvalidSeedSize},
+		{"short pub", map[string]string{"pub": members["pub"][:100]}, ErrInvalidKeySize},
+		{"pub of another parameter set", map[string]string{"alg": string(JOSEMLKEM1024)}, ErrInvalidKeySize},
+	} {
+		changed := map[string]string{}
+		for name, value := range members {
+			changed[name] = value
+		}
+		for name, value := range test.members {
+			changed[name] = value
+		}
+		data, err := json.Marshal(changed)
+		require.NoError(t, err)
+		_, err = ParseJWK(data)
+		require.ErrorIs(t, err, test.err, test.name)
+	}
+
+	// A set may mix signing and encryption keys.
+	set := &JWKSet{Keys: []*JWK{newTestJWK(t, MLDSA65, "sig"), jwk}}
+	setJSON, err := json.Marshal(set)
+	require.NoError(t, err)
+	parsedSet, err := ParseJWKSet(setJSON)
+	require.NoError(t, err)
+	require.Len(t, parsedSet.Keys, 2)
+	require.Equal(t, JOSEMLKEM768, parsedSet.Keys[1].Algorithm)
+}
@@ -0,0 +1,262 @@
+package pq
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// jwtType is the "typ" header parameter of the JWTs issued by IssueJWT.
+const jwtType = "JWT"
+
+// jwtRegisteredClaims are the RFC 7519 claims that JWTClaims holds in its own fields.
+var jwtRegisteredClaims = []string{"iss", "sub", "aud", "exp", "nbf", "iat", "jti"}
+
+// JWTClaims is the claims set of a JSON Web Token (RFC 7519). It implements json.Marshaler and json.Unmarshaler. Times
+// are encoded as NumericDate, whole seconds since the Unix epoch, and the zero time omits the claim.
+type JWTClaims struct {
+	// Issuer is the "iss" claim.
+	Issuer string
+	// Subject is the "sub" claim.
+	Subject string
+	// Audience is the "aud" claim, encoded as a string if it has a single element.
+	Audience []string
+	// ExpiresAt is the "exp" claim: the token is not valid at or after it.
+	ExpiresAt time.Time
+	// NotBefore is the "nbf" claim: the token is not valid before it.
+	NotBefore time.Time
+	// IssuedAt is the "iat" claim.
+	IssuedAt time.Time
+	// ID is the "jti" claim.
+	ID string
+	// Extra holds the other claims. It must not repeat the registered claims above.
+	Extra map[string]any
+}
+
+// MarshalJSON returns the JSON encoding of the claims.
+func (claims *JWTClaims) MarshalJSON() ([]byte, error) {
+	encoded := make(map[string]any, len(claims.Extra)+len(jwtRegisteredClaims))
+	for name, value := range claims.Extra {
+		for _, registeredName := range jwtRegisteredClaims {
+			if name == registeredName {
+				return nil, fmt.Errorf("JWT claim %q must be set in its JWTClaims field, not in Extra", name)
+			}
+		}
+		encoded[name] = value
+	}
+	setString := func(name string, value string) {
+		if value != "" {
+			encoded[name] = value
+		}
+	}
+	setTime := func(name string, value time.Time) {
+		if !value.IsZero() {
+			encoded[name] = value.Unix()
+		}
+	}
+	setString("iss", claims.Issuer)
+	setString("sub", claims.Subject)
+	switch len(claims.Audience) {
+	case 0:
+	case 1:
+		encoded["aud"] = claims.Audience[0]
+	default:
+		encoded["aud"] = claims.Audience
+	}
+	setTime("exp", claims.ExpiresAt)
+	setTime("nbf", claims.NotBefore)
+	setTime("iat", claims.IssuedAt)
+	setString("jti", claims.ID)
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON parses a claims set. The registered claims must have their RFC 7519 types, and other claims are decoded
+// into Extra with encoding/json, numbers as float64.
+func (claims *JWTClaims) UnmarshalJSON(data []byte) error {
+	var encoded map[string]json.RawMessage
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return newJOSEJSONError(0, "JWT claims", err)
+	}
+	if encoded == nil {
+		return newJOSEFormatError(0, "JWT claims are not a JSON object")
+	}
+	*claims = JWTClaims{}
+	parseString := func(name string, value *string) error {
+		if raw, ok := encoded[name]; ok {
+			delete(encoded, name)
+			if err := json.Unmarshal(raw, value); err != nil {
+				return newJOSEFormatError(0, "JWT claim %q is not a string", name)
+			}
+		}
+		return nil
+	}
+	parseTime := func(name string, value *time.Time) error {
+		if raw, ok := encoded[name]; ok {
+			delete(encoded, name)
+			var seconds float64
+			if err := json.Unmarshal(raw, &seconds); err != nil || math.IsInf(seconds, 0) || math.Abs(seconds) > 1e15 {
+				return newJOSEFormatError(0, "JWT claim %q is not a NumericDate", name)
+			}
+			whole, fraction := math.Modf(seconds)
+			*value = time.Unix(int64(whole), int64(fraction*1e9))
+		}
+		return nil
+	}
+	for _, err := range []error{
+		parseString("iss", &claims.Issuer),
+		parseString("sub", &claims.Subject),
+		parseTime("exp", &claims.ExpiresAt),
+		parseTime("nbf", &claims.NotBefore),
+		parseTime("iat", &claims.IssuedAt),
+		parseString("jti", &claims.ID),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	if raw, ok := encoded["aud"]; ok {
+		delete(encoded, "aud")
+		var audience string
+		if err := json.Unmarshal(raw, &audience); err == nil {
+			claims.Audience = []string{audience}
+		} else if err := json.Unmarshal(raw, &claims.Audience); err != nil {
+			return newJOSEFormatError(0, "JWT claim \"aud\" is neither a string nor an array of strings")
+		}
+	}
+	if len(encoded) > 0 {
+		claims.Extra = make(map[string]any, len(encoded))
+		for name, raw := range encoded {
+			var value any
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return newJOSEJSONError(0, "JWT claims", err)
+			}
+			claims.Extra[name] = value
+		}
+	}
+	return nil
+}
+
+// JWTValidation selects the claim checks of VerifyJWT. The expiration and not-before times are always checked when the
+// token has them.
+type JWTValidation struct {
+	// Issuer, if not empty, must equal the "iss" claim.
+	Issuer string
+	// Audience, if not empty, must be one of the "aud" claim values. If empty, tokens with an "aud" claim are rejected,
+	// as RFC 7519 Section 4.1.3 requires of a recipient that cannot identify itself with one of the values.
+	Audience string
+	// IgnoreAudience accepts tokens with any "aud" claim when Audience is empty, for recipients that check it themselves.
+	IgnoreAudience bool
+	// Time is the time the token must be valid at; the zero time selects time.Now().
+	Time time.Time
+	// Leeway is the clock skew allowed when checking "exp" and "nbf".
+	Leeway time.Duration
+	// RequireExpiration rejects tokens without an "exp" claim.
+	RequireExpiration bool
+}
+
+// check validates claims, returning a *ClaimError for the first check that fails.
+func (validation *JWTValidation) check(claims *JWTClaims) error {
+	now := validation.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if claims.ExpiresAt.IsZero() && validation.RequireExpiration {
+		return &ClaimError{Claim: "exp", Reason: "token has no expiration time"}
+	}
+	if !claims.ExpiresAt.IsZero() && !now.Before(claims.ExpiresAt.Add(validation.Leeway)) {
+		return &ClaimError{Claim: "exp", Reason: fmt.Sprintf("token expired at %s", claims.ExpiresAt.UTC().Format(time.RFC3339))}
+	}
+	if !claims.NotBefore.IsZero() && now.Add(validation.Leeway).Before(claims.NotBefore) {
+		return &ClaimError{Claim: "nbf", Reason: fmt.Sprintf("token is not valid before %s", claims.NotBefore.UTC().Format(time.RFC3339))}
+	}
+	if validation.Issuer != "" && claims.Issuer != validation.Issuer {
+		return &ClaimError{Claim: "iss", Reason: fmt.Sprintf("issuer %q, want %q", claims.Issuer, validation.Issuer)}
+	}
+	if validation.Audience != "" {
+		for _, audience := range claims.Audience {
+			if audience == validation.Audience {
+				return nil
+			}
+		}
+		return &ClaimError{Claim: "aud", Reason: fmt.Sprintf("audience %q does not include %q", claims.Audience, validation.Audience)}
+	}
+	if len(claims.Audience) != 0 && !validation.IgnoreAudience {
+		return &ClaimError{Claim: "aud", Reason: fmt.Sprintf("audience %q given but no audience to check it against", claims.Audience)}
+	}
+	return nil
+}
+
+// IssueJWT signs claims with a private JWK and returns the token, a compact JWS with the "typ" header parameter "JWT"
+// and the key ID of the key.
+func IssueJWT(key *JWK, claims *JWTClaims) (token string, issueError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			token, issueError = "", newPanicError("IssueJWT", recoveredPanic)
+		}
+	}()
+	if claims == nil {
+		return "", errors.New("a JWT needs a claims set")
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	return SignJWS(key, payload, &JWSHeader{Type: jwtType})
+}
+
+// VerifyJWT verifies a token with a JWK and validates its claims. validation may be nil, which checks only the
+// expiration and not-before times against time.Now(). A signature that does not verify returns ErrVerificationFailed,
+// claims that fail validation return a *ClaimError matching ErrInvalidClaims, and a malformed token returns a
+// *FormatError matching ErrInvalidJOSE.
+func VerifyJWT(key *JWK, token string, validation *JWTValidation) (claims *JWTClaims, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			claims, verifyError = nil, newPanicError("VerifyJWT", recoveredPanic)
+		}
+	}()
+	if key == nil {
+		return nil, errors.New("verifying a JWT needs a JWK")
+	}
+	return verifyJWT(token, validation, func(*JWSHeader) *JWK { return key })
+}
+
+// VerifyJWT verifies a token with the key of the set that has its key ID and algorithm, and validates its claims. A
+// token without a key ID, or whose key is not in the set, returns ErrKeyNotFound; otherwise it behaves like VerifyJWT.
+func (set *JWKSet) VerifyJWT(token string, validation *JWTValidation) (claims *JWTClaims, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			claims, verifyError = nil, newPanicError("JWKSet.VerifyJWT", recoveredPanic)
+		}
+	}()
+	return verifyJWT(token, validation, set.verificationKey)
+}
+
+// verifyJWT verifies a token with the key findKey returns for it and validates its claims.
+func verifyJWT(token string, validation *JWTValidation, findKey func(header *JWSHeader) *JWK) (*JWTClaims, error) {
+	payload, header, err := verifyJWSCompact(token, findKey)
+	if err != nil {
+		return nil, err
+	}
+	if header.Type != "" && !strings.EqualFold(header.Type, jwtType) {
+		return nil, newJOSEFormatError(0, "JWT has type %q", header.Type)
+	}
+	if header.ContentType != "" {
+		return nil, newJOSEFormatError(0, "nested JWTs are not supported")
+	}
+	claims := &JWTClaims{}
+	if err := claims.UnmarshalJSON(bytes.TrimSpace(payload)); err != nil {
+		return nil, err
+	}
+	if validation == nil {
+		validation = &JWTValidation{}
+	}
+	if err := validation.check(claims); err != nil {
+		return nil, err
+	}
+	Logger().Debug("VerifyJWT", "algorithm", string(header.Algorithm), "claims_size", len(payload))
+	return claims, nil
+}
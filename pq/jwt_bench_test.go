@@ -0,0 +1,30 @@
+package pq
+
+import (
+	"testing"
+	"time"
+)
+
+func BenchmarkIssueJWT(b *testing.B) {
+	jwk := newBenchmarkJWK(b)
+	claims := &JWTClaims{Issuer: "https://issuer.example", Subject: "user-1", ExpiresAt: time.Now().Add(time.Hour)}
+	for b.Loop() {
+		if _, err := IssueJWT(jwk, claims); err != nil {
+			b.Fatalf("failed to issue: %v", err)
+		}
+	}
+}
+
+func BenchmarkVerifyJWT(b *testing.B) {
+	jwk := newBenchmarkJWK(b)
+	token, err := IssueJWT(jwk, &JWTClaims{Issuer: "https://issuer.example", Subject: "user-1", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		b.Fatalf("failed to issue: %v", err)
+	}
+	validation := &JWTValidation{Issuer: "https://issuer.example"}
+	for b.Loop() {
+		if _, err := VerifyJWT(jwk, token, validation); err != nil {
+			b.Fatalf("failed to verify: %v", err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package pq
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func FuzzJWTClaimsUnmarshalJSON(f *testing.F) {
+	f.Add([]byte(`{"iss":"i","sub":"s","aud":["a","b"],"exp":1700000000,"nbf":1.5,"iat":0,"jti":"j","x":{"y":[1]}}`))
+	f.Add([]byte(`{"aud":"a"}`))
+	f.Add([]byte(`null`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var claims JWTClaims
+		if err := json.Unmarshal(data, &claims); err != nil {
+			return
+		}
+		encoded, err := json.Marshal(&claims)
+		if err != nil {
+			t.Fatalf("failed to marshal parsed claims: %v", err)
+		}
+		var reparsed JWTClaims
+		if err := json.Unmarshal(encoded, &reparsed); err != nil {
+			t.Fatalf("failed to parse marshaled claims %s: %v", encoded, err)
+		}
+		if reparsed.Issuer != claims.Issuer || reparsed.ExpiresAt.Unix() != claims.ExpiresAt.Unix() || len(reparsed.Audience) != len(claims.Audience) {
+			t.Errorf("claims changed in a round trip: %+v, then %+v", claims, reparsed)
+		}
+	})
+}
+
+func FuzzVerifyJWT(f *testing.F) {
+	privateKey, _ := GenerateMLDSAPrivateKey(MLDSA44)
+	jwk, _ := NewMLDSAJWK(privateKey, "fuzz")
+	token, _ := IssueJWT(jwk, &JWTClaims{Subject: "fuzz"})
+	f.Add(token)
+	f.Fuzz(func(t *testing.T, token string) {
+		claims, err := VerifyJWT(jwk, token, nil)
+		if err == nil && claims.Subject != "fuzz" {
+			t.Errorf("verified a forged JWT for subject %q", claims.Subject)
+		}
+	})
+}
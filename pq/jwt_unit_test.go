@@ -0,0 +1,188 @@
+package pq
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// For the JWT tests: a fixed validation time, so that the tokens never expire during a test run.
+var jwtTestTime = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// issueTestJWT returns a token signed by jwk with claims valid for an hour from jwtTestTime.
+func issueTestJWT(t *testing.T, jwk *JWK, modify func(claims *JWTClaims)) string {
+	t.Helper()
+	claims := &JWTClaims{
+		Issuer:    "https://issuer.example",
+		Subject:   "user-1",
+		Audience:  []string{"https://api.example"},
+		IssuedAt:  jwtTestTime,
+		NotBefore: jwtTestTime,
+		ExpiresAt: jwtTestTime.Add(time.Hour),
+	}
+	if modify != nil {
+		modify(claims)
+	}
+	token, err := IssueJWT(jwk, claims)
+	require.NoError(t, err)
+	return token
+}
+
+func TestJWTFixtures(t *testing.T) {
+	publicSet, err := ParseJWKSet(readJOSETestFile(t, "public-jwks.json"))
+	require.NoError(t, err)
+	tokens := strings.Fields(string(readJOSETestFile(t, "jwts.txt")))
+	require.Len(t, tokens, 3)
+	for index, token := range tokens {
+		claims, err := publicSet.VerifyJWT(token, &JWTValidation{Issuer: "https://issuer.example", Audience: "https://api.example"})
+		require.NoError(t, err)
+		require.Equal(t, "user-1", claims.Subject)
+		require.Equal(t, map[string]any{"scope": "read"}, claims.Extra)
+		require.Equal(t, int64(1767225600), claims.IssuedAt.Unix())
+
+		_, header, err := VerifyJWS(publicSet.Keys[index], token)
+		require.NoError(t, err)
+		require.Equal(t, "JWT", header.Type)
+	}
+}
+
+func TestJWTRoundTrip(t *testing.T) {
+	for _, parameterSet := range MLDSAParameterSets {
+		jwk := newTestJWK(t, parameterSet, "jwt-"+parameterSet.String())
+		token := issueTestJWT(t, jwk, func(claims *JWTClaims) {
+			claims.ID = "token-1"
+			claims.Extra = map[string]any{"scope": "read write", "admin": false, "level": 3}
+		})
+		claims, err := VerifyJWT(jwk.Public(), token, &JWTValidation{Issuer: "https://issuer.example", Audience: "https://api.example", Time: jwtTestTime})
+		require.NoError(t, err)
+		require.Equal(t, "https://issuer.example", claims.Issuer)
+		require.Equal(t, "user-1", claims.Subject)
+		require.Equal(t, []string{"https://api.example"}, claims.Audience)
+		require.True(t, claims.ExpiresAt.Equal(jwtTestTime.Add(time.Hour)))
+		require.True(t, claims.NotBefore.Equal(jwtTestTime))
+		require.True(t, claims.IssuedAt.Equal(jwtTestTime))
+		require.Equal(t, "token-1", claims.ID)
+		require.Equal(t, map[string]any{"scope": "read write", "admin": false, "level": float64(3)}, claims.Extra)
+
+		set := &JWKSet{Keys: []*JWK{jwk.Public()}}
+		claims, err = set.VerifyJWT(token, &JWTValidation{Time: jwtTestTime, Audience: "https://api.example"})
+		require.NoError(t, err)
+		require.Equal(t, "user-1", claims.Subject)
+	}
+}
+
+// For TestJWTClaimsValidation: each check rejects the token with a *ClaimError naming the claim.
+func TestJWTClaimsValidation(t *testing.T) {
+	jwk := newTestJWK(t, MLDSA44, "validation")
+	token := issueTestJWT(t, jwk, nil)
+	noExpiration := issueTestJWT(t, jwk, func(claims *JWTClaims) { claims.ExpiresAt = time.Time{} })
+	twoAudiences := issueTestJWT(t, jwk, func(claims *JWTClaims) {
+		claims.Audience = []string{"https://other.example", "https://api.example"}
+	})
+	noAudience := issueTestJWT(t, jwk, func(claims *JWTClaims) { claims.Audience = nil })
+	audience := "https://api.example"
+
+	for _, test := range []struct {
+		name       string
+		token      string
+		validation *JWTValidation
+		claim      string
+	}{
+		{"valid", token, &JWTValidation{Time: jwtTestTime, Audience: audience}, ""},
+		{"expired", token, &JWTValidation{Time: jwtTestTime.Add(time.Hour), Audience: audience}, "exp"},
+		{"expired within leeway", token, &JWTValidation{Time: jwtTestTime.Add(time.Hour), Leeway: time.Minute, Audience: audience}, ""},
+		{"not yet valid", token, &JWTValidation{Time: jwtTestTime.Add(-time.Second), Audience: audience}, "nbf"},
+		{"not yet valid within leeway", token, &JWTValidation{Time: jwtTestTime.Add(-time.Second), Leeway: time.Minute, Audience: audience}, ""},
+		{"wrong issuer", token, &JWTValidation{Time: jwtTestTime, Issuer: "https://evil.example", Audience: audience}, "iss"},
+		{"wrong audience", token, &JWTValidation{Time: jwtTestTime, Audience: "https://other.example"}, "aud"},
+		{"one of two audiences", twoAudiences, &JWTValidation{Time: jwtTestTime, Audience: "https://other.example"}, ""},
+		{"unchecked audience", token, &JWTValidation{Time: jwtTestTime}, "aud"},
+		{"ignored audience", twoAudiences, &JWTValidation{Time: jwtTestTime, IgnoreAudience: true}, ""},
+		{"no audience", noAudience, &JWTValidation{Time: jwtTestTime}, ""},
+		{"no expiration", noExpiration, &JWTValidation{Time: jwtTestTime.Add(1000 * time.Hour), Audience: audience}, ""},
+		{"expiration required", noExpiration, &JWTValidation{Time: jwtTestTime, RequireExpiration: true, Audience: audience}, "exp"},
+		{"expired now", token, nil, "exp"},
+	} {
+		_, err := VerifyJWT(jwk, test.token, test.validation)
+		if test.claim == "" {
+			require.NoError(t, err, test.name)
+			continue
+		}
+		require.ErrorIs(t, err, ErrInvalidClaims, test.name)
+		var claimError *ClaimError
+		require.True(t, errors.As(err, &claimError), test.name)
+		require.Equal(t, test.claim, claimError.Claim, test.name)
+	}
+}
+
+// For TestJWTClaimsJSON: the encoding of the registered claims.
+func TestJWTClaimsJSON(t *testing.T) {
+	encoded, err := json.Marshal(&JWTClaims{Audience: []string{"one"}, ExpiresAt: time.Unix(1700000000, 999999999), Extra: map[string]any{"x": 1}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"aud":"one","exp":1700000000,"x":1}`, string(encoded))
+	encoded, err = json.Marshal(&JWTClaims{Audience: []string{"one", "two"}})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"aud":["one","two"]}`, string(encoded))
+	_, err = json.Marshal(&JWTClaims{Extra: map[string]any{"exp": 1}})
+	require.Error(t, err)
+
+	var claims JWTClaims
+	require.NoError(t, json.Unmarshal([]byte(`{"exp":1700000000.5,"aud":["a","b"],"iss":"i"}`), &claims))
+	require.Equal(t, int64(1700000000), claims.ExpiresAt.Unix())
+	require.Equal(t, 500*time.Millisecond, time.Duration(claims.ExpiresAt.Nanosecond()))
+	require.Equal(t, []string{"a", "b"}, claims.Audience)
+	require.Nil(t, claims.Extra)
+
+	for _, test := range []struct {
+		name string
+		data string
+	}{
+		{"not an object", `[]`},
+		{"null", `null`},
+		{"string exp", `{"exp":"tomorrow"}`},
+		{"huge exp", `{"exp":1e300}`},
+		{"numeric iss", `{"iss":1}`},
+		{"numeric aud", `{"aud":1}`},
+		{"mixed aud", `{"aud":["a",1]}`},
+	} {
+		require.ErrorIs(t, json.Unmarshal([]byte(test.data), &claims), ErrInvalidJOSE, test.name)
+	}
+}
+
+// For TestJWTRejectsOtherTokens: tokens that verify as a JWS but are not plain JWTs, and JWTs signed by another key.
+func TestJWTRejectsOtherTokens(t *testing.T) {
+	jwk := newTestJWK(t, MLDSA65, "issuer")
+	validation := &JWTValidation{Time: jwtTestTime}
+
+	otherType, err := SignJWS(jwk, []byte(`{}`), &JWSHeader{Type: "dpop+jwt"})
+	require.NoError(t, err)
+	_, err = VerifyJWT(jwk, otherType, validation)
+	require.ErrorIs(t, err, ErrInvalidJOSE)
+	nested, err := SignJWS(jwk, []byte(`{}`), &JWSHeader{Type: "JWT", ContentType: "JWT"})
+	require.NoError(t, err)
+	_, err = VerifyJWT(jwk, nested, validation)
+	require.ErrorIs(t, err, ErrInvalidJOSE)
+	notClaims, err := SignJWS(jwk, []byte(`"claims"`), nil)
+	require.NoError(t, err)
+	_, err = VerifyJWT(jwk, notClaims, validation)
+	require.ErrorIs(t, err, ErrInvalidJOSE)
+	untyped, err := SignJWS(jwk, []byte(`{"sub":"untyped"}`), nil)
+	require.NoError(t, err)
+	claims, err := VerifyJWT(jwk, untyped, validation)
+	require.NoError(t, err)
+	require.Equal(t, "untyped", claims.Subject)
+
+	token := issueTestJWT(t, jwk, nil)
+	_, err = VerifyJWT(newTestJWK(t, MLDSA65, "issuer"), token, validation)
+	require.ErrorIs(t, err, ErrVerificationFailed)
+	_, err = (&JWKSet{Keys: []*JWK{newTestJWK(t, MLDSA65, "someone else")}}).VerifyJWT(token, validation)
+	require.ErrorIs(t, err, ErrKeyNotFound)
+	_, err = IssueJWT(jwk.Public(), &JWTClaims{})
+	require.Error(t, err)
+	_, err = IssueJWT(jwk, nil)
+	require.Error(t, err)
+}
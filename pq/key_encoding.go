@@ -0,0 +1,583 @@
+package pq
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/kem/xwing"
+)
+
+// PEM block types used by the PEM wrappers below (RFC 7468).
+const (
+	PublicKeyPEMType  = "PUBLIC KEY"
+	PrivateKeyPEMType = "PRIVATE KEY"
+)
+
+// PrivateKeyFormat selects which CHOICE of the ML-DSA / ML-KEM private key ASN.1 structure is written into PKCS#8
+// (RFC 9881 for ML-DSA, draft-ietf-lamps-kyber-certificates for ML-KEM).
+type PrivateKeyFormat int
+
+const (
+	// PrivateKeyFormatSeed writes only the seed ([0] IMPLICIT OCTET STRING). This is the smallest and recommended form.
+	PrivateKeyFormatSeed PrivateKeyFormat = iota + 1
+	// PrivateKeyFormatExpanded writes only the expanded private key (OCTET STRING).
+	PrivateKeyFormatExpanded
+	// PrivateKeyFormatBoth writes SEQUENCE { seed OCTET STRING, expandedKey OCTET STRING }.
+	PrivateKeyFormatBoth
+)
+
+// String returns the name of the private key format.
+func (format PrivateKeyFormat) String() string {
+	switch format {
+	case PrivateKeyFormatSeed:
+		return "seed"
+	case PrivateKeyFormatExpanded:
+		return "expanded"
+	case PrivateKeyFormatBoth:
+		return "both"
+	default:
+		return fmt.Sprintf("PrivateKeyFormat(%d)", int(format))
+	}
+}
+
+// oidXWing is the X-Wing object identifier from draft-connolly-cfrg-xwing-kem.
+var oidXWing = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 62253, 25722}
+
+// subjectPublicKeyInfo is the RFC 5280 SubjectPublicKeyInfo structure.
+type subjectPublicKeyInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// oneAsymmetricKey is the RFC 5958 OneAsymmetricKey structure; version 0 is the RFC 5208 PrivateKeyInfo.
+type oneAsymmetricKey struct {
+	Version    int
+	Algorithm  pkix.AlgorithmIdentifier
+	PrivateKey []byte
+	Attributes asn1.RawValue  `asn1:"optional,tag:0"`
+	PublicKey  asn1.BitString `asn1:"optional,tag:1"`
+}
+
+// bothPrivateKey is the "both" CHOICE of the ML-DSA / ML-KEM private key.
+type bothPrivateKey struct {
+	Seed        []byte
+	ExpandedKey []byte
+}
+
+// marshalSubjectPublicKeyInfo encodes a raw public key as a SubjectPublicKeyInfo with absent algorithm parameters.
+func marshalSubjectPublicKeyInfo(oid asn1.ObjectIdentifier, publicKeyBytes []byte) ([]byte, error) {
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oid},
+		PublicKey: asn1.BitString{Bytes: publicKeyBytes, BitLength: 8 * len(publicKeyBytes)},
+	})
+}
+
+// parseSubjectPublicKeyInfo decodes a SubjectPublicKeyInfo and returns its algorithm OID and raw public key.
+// The algorithm parameters must be absent, as required for all algorithms supported here.
+func parseSubjectPublicKeyInfo(der []byte) (asn1.ObjectIdentifier, []byte, error) {
+	var info subjectPublicKeyInfo
+	rest, unmarshalError := asn1.Unmarshal(der, &info)
+	if unmarshalError != nil {
+		return nil, nil, fmt.Errorf("invalid SubjectPublicKeyInfo: %w", unmarshalError)
+	}
+	if len(rest) != 0 {
+		return nil, nil, fmt.Errorf("%w: SubjectPublicKeyInfo has trailing data", ErrInvalidKey)
+	}
+	if len(info.Algorithm.Parameters.FullBytes) != 0 {
+		return nil, nil, fmt.Errorf("%w: SubjectPublicKeyInfo has unexpected parameters for algorithm %v", ErrInvalidKey, info.Algorithm.Algorithm)
+	}
+	if info.PublicKey.BitLength%8 != 0 {
+		return nil, nil, fmt.Errorf("%w: SubjectPublicKeyInfo public key is not a whole number of bytes", ErrInvalidKey)
+	}
+	return info.Algorithm.Algorithm, info.PublicKey.Bytes, nil
+}
+
+// marshalPKCS8 encodes a private key as a version 0 OneAsymmetricKey with absent algorithm parameters.
+func marshalPKCS8(oid asn1.ObjectIdentifier, privateKeyBytes []byte) ([]byte, error) {
+	return asn1.Marshal(oneAsymmetricKey{
+		Algorithm:  pkix.AlgorithmIdentifier{Algorithm: oid},
+		PrivateKey: privateKeyBytes,
+	})
+}
+
+// parsePKCS8 decodes a OneAsymmetricKey (version 0 or 1) and returns its algorithm OID and the content of its privateKey OCTET STRING.
+// An optional embedded publicKey is returned as well, or nil if absent.
+func parsePKCS8(der []byte) (oid asn1.ObjectIdentifier, privateKeyBytes []byte, publicKeyBytes []byte, parseError error) {
+	var key oneAsymmetricKey
+	rest, unmarshalError := asn1.Unmarshal(der, &key)
+	if unmarshalError != nil {
+		return nil, nil, nil, fmt.Errorf("invalid PKCS#8 private key: %w", unmarshalError)
+	}
+	if len(rest) != 0 {
+		return nil, nil, nil, fmt.Errorf("%w: PKCS#8 private key has trailing data", ErrInvalidKey)
+	}
+	if key.Version != 0 && key.Version != 1 {
+		return nil, nil, nil, fmt.Errorf("%w: PKCS#8 private key has unsupported version %d", ErrInvalidKey, key.Version)
+	}
+	if len(key.Algorithm.Parameters.FullBytes) != 0 {
+		return nil, nil, nil, fmt.Errorf("%w: PKCS#8 private key has unexpected parameters for algorithm %v", ErrInvalidKey, key.Algorithm.Algorithm)
+	}
+	if key.PublicKey.BitLength != 0 {
+		if key.Version != 1 {
+			return nil, nil, nil, fmt.Errorf("%w: PKCS#8 private key: publicKey requires version 1", ErrInvalidKey)
+		}
+		if key.PublicKey.BitLength%8 != 0 {
+			return nil, nil, nil, fmt.Errorf("%w: PKCS#8 private key: public key is not a whole number of bytes", ErrInvalidKey)
+		}
+		publicKeyBytes = key.PublicKey.Bytes
+	}
+	return key.Algorithm.Algorithm, key.PrivateKey, publicKeyBytes, nil
+}
+
+// marshalSeedPrivateKey encodes the ML-DSA / ML-KEM private key CHOICE selected by format.
+func marshalSeedPrivateKey(format PrivateKeyFormat, seed []byte, expandedKey []byte) ([]byte, error) {
+	switch format {
+	case PrivateKeyFormatSeed:
+		if seed == nil {
+			return nil, fmt.Errorf("%w: seed format requested but the key pair has no seed", ErrInvalidKey)
+		}
+		return asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: seed})
+	case PrivateKeyFormatExpanded:
+		return asn1.Marshal(expandedKey)
+	case PrivateKeyFormatBoth:
+		if seed == nil {
+			return nil, fmt.Errorf("%w: both format requested but the key pair has no seed", ErrInvalidKey)
+		}
+		return asn1.Marshal(bothPrivateKey{Seed: seed, ExpandedKey: expandedKey})
+	default:
+		return nil, fmt.Errorf("%w: private key format %v", ErrUnknownAlgorithm, format)
+	}
+}
+
+// parseSeedPrivateKey decodes any CHOICE of the ML-DSA / ML-KEM private key. Either returned value may be nil,
+// but not both; the caller checks sizes and, for the "both" form, consistency.
+func parseSeedPrivateKey(data []byte) (seed []byte, expandedKey []byte, parseError error) {
+	var raw asn1.RawValue
+	rest, unmarshalError := asn1.Unmarshal(data, &raw)
+	if unmarshalError != nil {
+		return nil, nil, fmt.Errorf("%w: private key encoding: %w", ErrInvalidKey, unmarshalError)
+	}
+	if len(rest) != 0 {
+		return nil, nil, fmt.Errorf("%w: private key encoding has trailing data", ErrInvalidKey)
+	}
+	switch {
+	case raw.Class == asn1.ClassContextSpecific && raw.Tag == 0 && !raw.IsCompound:
+		return raw.Bytes, nil, nil
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagOctetString && !raw.IsCompound:
+		return nil, raw.Bytes, nil
+	case raw.Class == asn1.ClassUniversal && raw.Tag == asn1.TagSequence && raw.IsCompound:
+		var both bothPrivateKey
+		if rest, unmarshalError = asn1.Unmarshal(data, &both); unmarshalError != nil {
+			return nil, nil, fmt.Errorf("%w: private key encoding: %w", ErrInvalidKey, unmarshalError)
+		}
+		if len(rest) != 0 {
+			return nil, nil, fmt.Errorf("%w: private key encoding has trailing data", ErrInvalidKey)
+		}
+		return both.Seed, both.ExpandedKey, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: private key encoding has unexpected tag %d (class %d)", ErrInvalidKey, raw.Tag, raw.Class)
+	}
+}
+
+// mldsaParameterSetOfOID returns the ML-DSA parameter set identified by oid.
+func mldsaParameterSetOfOID(oid asn1.ObjectIdentifier) (MLDSAParameterSet, bool) {
+	for _, parameterSet := range MLDSAParameterSets {
+		if parameterSetOID, _ := parameterSet.OID(); oid.Equal(parameterSetOID) {
+			return parameterSet, true
+		}
+	}
+	return 0, false
+}
+
+// mlkemParameterSetOfOID returns the ML-KEM parameter set identified by oid.
+func mlkemParameterSetOfOID(oid asn1.ObjectIdentifier) (MLKEMParameterSet, bool) {
+	for _, parameterSet := range MLKEMParameterSets {
+		if parameterSetOID, _ := parameterSet.OID(); oid.Equal(parameterSetOID) {
+			return parameterSet, true
+		}
+	}
+	return 0, false
+}
+
+// compositeMLDSAAlgorithmOfOID returns the composite ML-DSA algorithm identified by oid.
+func compositeMLDSAAlgorithmOfOID(oid asn1.ObjectIdentifier) (CompositeMLDSAAlgorithm, bool) {
+	for _, algorithm := range CompositeMLDSAAlgorithms {
+		if algorithmOID, _ := algorithm.OID(); oid.Equal(algorithmOID) {
+			return algorithm, true
+		}
+	}
+	return 0, false
+}
+
+// MarshalMLDSAPublicKeyPKIX encodes an ML-DSA public key as a DER SubjectPublicKeyInfo (RFC 9881).
+func MarshalMLDSAPublicKeyPKIX(parameterSet MLDSAParameterSet, publicKeyBytes []byte) ([]byte, error) {
+	oid, oidError := parameterSet.OID()
+	if oidError != nil {
+		return nil, oidError
+	}
+	scheme, schemeError := parameterSet.scheme()
+	if schemeError != nil {
+		return nil, schemeError
+	}
+	if len(publicKeyBytes) != scheme.PublicKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v public key", parameterSet), len(publicKeyBytes), scheme.PublicKeySize())
+	}
+	return marshalSubjectPublicKeyInfo(oid, publicKeyBytes)
+}
+
+// ParseMLDSAPublicKeyPKIX decodes a DER SubjectPublicKeyInfo holding an ML-DSA public key and returns its parameter set and raw public key.
+func ParseMLDSAPublicKeyPKIX(der []byte) (parameterSet MLDSAParameterSet, publicKeyBytes []byte, parseError error) {
+	oid, publicKeyBytes, parseError := parseSubjectPublicKeyInfo(der)
+	if parseError != nil {
+		return 0, nil, parseError
+	}
+	parameterSet, ok := mldsaParameterSetOfOID(oid)
+	if !ok {
+		return 0, nil, fmt.Errorf("%w: not an ML-DSA public key: algorithm %v", ErrUnknownAlgorithm, oid)
+	}
+	scheme, _ := parameterSet.scheme()
+	if len(publicKeyBytes) != scheme.PublicKeySize() {
+		return 0, nil, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v public key", parameterSet), len(publicKeyBytes), scheme.PublicKeySize())
+	}
+	return parameterSet, publicKeyBytes, nil
+}
+
+// MarshalMLDSAPrivateKeyPKCS8 encodes an ML-DSA key pair as a DER PKCS#8 OneAsymmetricKey (RFC 9881) using the given private key format.
+// The seed and both formats require keyPair.Seed.
+func MarshalMLDSAPrivateKeyPKCS8(keyPair *MLDSAKeyPair, format PrivateKeyFormat) ([]byte, error) {
+	if keyPair == nil {
+		return nil, fmt.Errorf("%w: ML-DSA key pair is nil or incomplete", ErrInvalidKey)
+	}
+	oid, oidError := keyPair.ParameterSet.OID()
+	if oidError != nil {
+		return nil, oidError
+	}
+	if keyPair.Seed != nil && len(keyPair.Seed) != MLDSASeedSize {
+		return nil, newSizeError(ErrInvalidSeedSize, "ML-DSA seed", len(keyPair.Seed), MLDSASeedSize)
+	}
+	privateKeyBytes, marshalError := marshalSeedPrivateKey(format, keyPair.Seed, keyPair.PrivateKey)
+	if marshalError != nil {
+		return nil, marshalError
+	}
+	return marshalPKCS8(oid, privateKeyBytes)
+}
+
+// ParseMLDSAPrivateKeyPKCS8 decodes a DER PKCS#8 OneAsymmetricKey holding an ML-DSA private key in any of the seed, expanded or both forms.
+// The public key is recomputed from the private key. For the both form the seed must expand to the given expanded key,
+// and an embedded public key, if present, must match.
+func ParseMLDSAPrivateKeyPKCS8(der []byte) (keyPair *MLDSAKeyPair, parseError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			parseError = newPanicError("ParseMLDSAPrivateKeyPKCS8", recoveredPanic)
+		}
+	}()
+	oid, privateKeyBytes, embeddedPublicKey, parseError := parsePKCS8(der)
+	if parseError != nil {
+		return nil, parseError
+	}
+	parameterSet, ok := mldsaParameterSetOfOID(oid)
+	if !ok {
+		return nil, fmt.Errorf("%w: not an ML-DSA private key: algorithm %v", ErrUnknownAlgorithm, oid)
+	}
+	seed, expandedKey, parseError := parseSeedPrivateKey(privateKeyBytes)
+	if parseError != nil {
+		return nil, parseError
+	}
+	if seed != nil {
+		if len(seed) != MLDSASeedSize {
+			return nil, newSizeError(ErrInvalidSeedSize, fmt.Sprintf("%v seed", parameterSet), len(seed), MLDSASeedSize)
+		}
+		if keyPair, parseError = DeriveMLDSAKeyPair(parameterSet, (*[MLDSASeedSize]byte)(seed)); parseError != nil {
+			return nil, parseError
+		}
+		if expandedKey != nil && !bytes.Equal(expandedKey, keyPair.PrivateKey) {
+			return nil, fmt.Errorf("%w: inconsistent %v private key: seed does not match expanded key", ErrInvalidKey, parameterSet)
+		}
+	} else {
+		publicKeyBytes, publicKeyError := mldsaPublicKeyFromPrivateKey(parameterSet, expandedKey)
+		if publicKeyError != nil {
+			return nil, publicKeyError
+		}
+		keyPair = &MLDSAKeyPair{ParameterSet: parameterSet, PublicKey: publicKeyBytes, PrivateKey: append([]byte(nil), expandedKey...)}
+	}
+	if embeddedPublicKey != nil && !bytes.Equal(embeddedPublicKey, keyPair.PublicKey) {
+		return nil, fmt.Errorf("%w: inconsistent %v private key: embedded public key does not match", ErrInvalidKey, parameterSet)
+	}
+	return keyPair, nil
+}
+
+// MarshalMLKEMPublicKeyPKIX encodes an ML-KEM encapsulation key as a DER SubjectPublicKeyInfo (draft-ietf-lamps-kyber-certificates).
+func MarshalMLKEMPublicKeyPKIX(publicKey kem.PublicKey) ([]byte, error) {
+	if publicKey == nil {
+		return nil, fmt.Errorf("%w: ML-KEM public key is nil", ErrInvalidKey)
+	}
+	parameterSet, parameterSetError := mlkemParameterSetOf(publicKey.Scheme())
+	if parameterSetError != nil {
+		return nil, parameterSetError
+	}
+	oid, oidError := parameterSet.OID()
+	if oidError != nil {
+		return nil, oidError
+	}
+	publicKeyBytes, marshalError := publicKey.MarshalBinary()
+	if marshalError != nil {
+		return nil, marshalError
+	}
+	return marshalSubjectPublicKeyInfo(oid, publicKeyBytes)
+}
+
+// ParseMLKEMPublicKeyPKIX decodes a DER SubjectPublicKeyInfo holding an ML-KEM encapsulation key.
+func ParseMLKEMPublicKeyPKIX(der []byte) (publicKey kem.PublicKey, parseError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			parseError = newPanicError("ParseMLKEMPublicKeyPKIX", recoveredPanic)
+		}
+	}()
+	oid, publicKeyBytes, parseError := parseSubjectPublicKeyInfo(der)
+	if parseError != nil {
+		return nil, parseError
+	}
+	parameterSet, ok := mlkemParameterSetOfOID(oid)
+	if !ok {
+		return nil, fmt.Errorf("%w: not an ML-KEM public key: algorithm %v", ErrUnknownAlgorithm, oid)
+	}
+	return UnmarshalPublicKey(parameterSet, publicKeyBytes)
+}
+
+// MarshalMLKEMPrivateKeyPKCS8 encodes an ML-KEM key pair as a DER PKCS#8 OneAsymmetricKey using the given private key format.
+// The seed (d || z) and both formats require keyPair.Seed.
+func MarshalMLKEMPrivateKeyPKCS8(keyPair *MLKEMKeyPair, format PrivateKeyFormat) ([]byte, error) {
+	if keyPair == nil || keyPair.PrivateKey == nil {
+		return nil, fmt.Errorf("%w: ML-KEM key pair is nil or incomplete", ErrInvalidKey)
+	}
+	oid, oidError := keyPair.ParameterSet.OID()
+	if oidError != nil {
+		return nil, oidError
+	}
+	if keyPair.Seed != nil && len(keyPair.Seed) != MLKEMSeedSize {
+		return nil, newSizeError(ErrInvalidSeedSize, "ML-KEM seed", len(keyPair.Seed), MLKEMSeedSize)
+	}
+	expandedKey, marshalError := keyPair.PrivateKey.MarshalBinary()
+	if marshalError != nil {
+		return nil, marshalError
+	}
+	privateKeyBytes, marshalError := marshalSeedPrivateKey(format, keyPair.Seed, expandedKey)
+	if marshalError != nil {
+		return nil, marshalError
+	}
+	return marshalPKCS8(oid, privateKeyBytes)
+}
+
+// ParseMLKEMPrivateKeyPKCS8 decodes a DER PKCS#8 OneAsymmetricKey holding an ML-KEM decapsulation key in any of the seed, expanded or both forms.
+// For the both form the seed must expand to the given expanded key, and an embedded public key, if present, must match.
+func ParseMLKEMPrivateKeyPKCS8(der []byte) (keyPair *MLKEMKeyPair, parseError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			parseError = newPanicError("ParseMLKEMPrivateKeyPKCS8", recoveredPanic)
+		}
+	}()
+	oid, privateKeyBytes, embeddedPublicKey, parseError := parsePKCS8(der)
+	if parseError != nil {
+		return nil, parseError
+	}
+	parameterSet, ok := mlkemParameterSetOfOID(oid)
+	if !ok {
+		return nil, fmt.Errorf("%w: not an ML-KEM private key: algorithm %v", ErrUnknownAlgorithm, oid)
+	}
+	seed, expandedKey, parseError := parseSeedPrivateKey(privateKeyBytes)
+	if parseError != nil {
+		return nil, parseError
+	}
+	if seed != nil {
+		if len(seed) != MLKEMSeedSize {
+			return nil, newSizeError(ErrInvalidSeedSize, fmt.Sprintf("%v seed", parameterSet), len(seed), MLKEMSeedSize)
+		}
+		if keyPair, parseError = GenerateDeterministicMLKEMKeyPair(parameterSet, seed); parseError != nil {
+			return nil, parseError
+		}
+		if expandedKey != nil {
+			derivedKey, marshalError := keyPair.PrivateKey.MarshalBinary()
+			if marshalError != nil {
+				return nil, marshalError
+			}
+			if !bytes.Equal(expandedKey, derivedKey) {
+				return nil, fmt.Errorf("%w: inconsistent %v private key: seed does not match expanded key", ErrInvalidKey, parameterSet)
+			}
+		}
+	} else {
+		privateKey, unmarshalError := UnmarshalPrivateKey(parameterSet, expandedKey)
+		if unmarshalError != nil {
+			return nil, unmarshalError
+		}
+		keyPair = &MLKEMKeyPair{ParameterSet: parameterSet, PublicKey: privateKey.Public(), PrivateKey: privateKey}
+	}
+	if embeddedPublicKey != nil {
+		publicKeyBytes, marshalError := keyPair.PublicKey.MarshalBinary()
+		if marshalError != nil {
+			return nil, marshalError
+		}
+		if !bytes.Equal(embeddedPublicKey, publicKeyBytes) {
+			return nil, fmt.Errorf("%w: inconsistent %v private key: embedded public key does not match", ErrInvalidKey, parameterSet)
+		}
+	}
+	return keyPair, nil
+}
+
+// MarshalXWingPublicKeyPKIX encodes an X-Wing public key as a DER SubjectPublicKeyInfo (draft-connolly-cfrg-xwing-kem).
+func MarshalXWingPublicKeyPKIX(publicKey kem.PublicKey) ([]byte, error) {
+	if publicKey == nil || publicKey.Scheme() != xwing.Scheme() {
+		return nil, fmt.Errorf("%w: not an X-Wing public key", ErrInvalidKey)
+	}
+	publicKeyBytes, marshalError := publicKey.MarshalBinary()
+	if marshalError != nil {
+		return nil, marshalError
+	}
+	return marshalSubjectPublicKeyInfo(oidXWing, publicKeyBytes)
+}
+
+// ParseXWingPublicKeyPKIX decodes a DER SubjectPublicKeyInfo holding an X-Wing public key.
+func ParseXWingPublicKeyPKIX(der []byte) (publicKey kem.PublicKey, parseError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			parseError = newPanicError("ParseXWingPublicKeyPKIX", recoveredPanic)
+		}
+	}()
+	oid, publicKeyBytes, parseError := parseSubjectPublicKeyInfo(der)
+	if parseError != nil {
+		return nil, parseError
+	}
+	if !oid.Equal(oidXWing) {
+		return nil, fmt.Errorf("%w: not an X-Wing public key: algorithm %v", ErrUnknownAlgorithm, oid)
+	}
+	return UnmarshalXWingPublicKey(publicKeyBytes)
+}
+
+// MarshalXWingPrivateKeyPKCS8 encodes an X-Wing private key as a DER PKCS#8 OneAsymmetricKey whose privateKey is the raw 32-byte seed.
+func MarshalXWingPrivateKeyPKCS8(privateKey kem.PrivateKey) ([]byte, error) {
+	if privateKey == nil || privateKey.Scheme() != xwing.Scheme() {
+		return nil, fmt.Errorf("%w: not an X-Wing private key", ErrInvalidKey)
+	}
+	privateKeyBytes, marshalError := privateKey.MarshalBinary()
+	if marshalError != nil {
+		return nil, marshalError
+	}
+	return marshalPKCS8(oidXWing, privateKeyBytes)
+}
+
+// ParseXWingPrivateKeyPKCS8 decodes a DER PKCS#8 OneAsymmetricKey holding an X-Wing private key.
+func ParseXWingPrivateKeyPKCS8(der []byte) (keyPair *XWingKeyPair, parseError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			parseError = newPanicError("ParseXWingPrivateKeyPKCS8", recoveredPanic)
+		}
+	}()
+	oid, privateKeyBytes, _, parseError := parsePKCS8(der)
+	if parseError != nil {
+		return nil, parseError
+	}
+	if !oid.Equal(oidXWing) {
+		return nil, fmt.Errorf("%w: not an X-Wing private key: algorithm %v", ErrUnknownAlgorithm, oid)
+	}
+	return GenerateDeterministicXWingKeyPair(privateKeyBytes)
+}
+
+// MarshalCompositeMLDSAPublicKeyPKIX encodes a composite ML-DSA public key as a DER SubjectPublicKeyInfo under the composite algorithm OID.
+func MarshalCompositeMLDSAPublicKeyPKIX(publicKey *CompositeMLDSAPublicKey) ([]byte, error) {
+	publicKeyBytes, marshalError := MarshalCompositeMLDSAPublicKey(publicKey)
+	if marshalError != nil {
+		return nil, marshalError
+	}
+	oid, oidError := publicKey.Algorithm.OID()
+	if oidError != nil {
+		return nil, oidError
+	}
+	return marshalSubjectPublicKeyInfo(oid, publicKeyBytes)
+}
+
+// ParseCompositeMLDSAPublicKeyPKIX decodes a DER SubjectPublicKeyInfo holding a composite ML-DSA public key.
+func ParseCompositeMLDSAPublicKeyPKIX(der []byte) (publicKey *CompositeMLDSAPublicKey, parseError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			parseError = newPanicError("ParseCompositeMLDSAPublicKeyPKIX", recoveredPanic)
+		}
+	}()
+	oid, publicKeyBytes, parseError := parseSubjectPublicKeyInfo(der)
+	if parseError != nil {
+		return nil, parseError
+	}
+	algorithm, ok := compositeMLDSAAlgorithmOfOID(oid)
+	if !ok {
+		return nil, fmt.Errorf("%w: not a composite ML-DSA public key: algorithm %v", ErrUnknownAlgorithm, oid)
+	}
+	return UnmarshalCompositeMLDSAPublicKey(algorithm, publicKeyBytes)
+}
+
+// MarshalCompositeMLDSAPrivateKeyPKCS8 encodes a composite ML-DSA private key as a DER PKCS#8 OneAsymmetricKey under the composite algorithm OID.
+func MarshalCompositeMLDSAPrivateKeyPKCS8(privateKey *CompositeMLDSAPrivateKey) ([]byte, error) {
+	privateKeyBytes, marshalError := MarshalCompositeMLDSAPrivateKey(privateKey)
+	if marshalError != nil {
+		return nil, marshalError
+	}
+	oid, oidError := privateKey.Algorithm.OID()
+	if oidError != nil {
+		return nil, oidError
+	}
+	return marshalPKCS8(oid, privateKeyBytes)
+}
+
+// ParseCompositeMLDSAPrivateKeyPKCS8 decodes a DER PKCS#8 OneAsymmetricKey holding a composite ML-DSA private key.
+func ParseCompositeMLDSAPrivateKeyPKCS8(der []byte) (privateKey *CompositeMLDSAPrivateKey, parseError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			parseError = newPanicError("ParseCompositeMLDSAPrivateKeyPKCS8", recoveredPanic)
+		}
+	}()
+	oid, privateKeyBytes, _, parseError := parsePKCS8(der)
+	if parseError != nil {
+		return nil, parseError
+	}
+	algorithm, ok := compositeMLDSAAlgorithmOfOID(oid)
+	if !ok {
+		return nil, fmt.Errorf("%w: not a composite ML-DSA private key: algorithm %v", ErrUnknownAlgorithm, oid)
+	}
+	return UnmarshalCompositeMLDSAPrivateKey(algorithm, privateKeyBytes)
+}
+
+// EncodePublicKeyPEM wraps a DER SubjectPublicKeyInfo in a "PUBLIC KEY" PEM block.
+func EncodePublicKeyPEM(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: PublicKeyPEMType, Bytes: der})
+}
+
+// EncodePrivateKeyPEM wraps a DER PKCS#8 private key in a "PRIVATE KEY" PEM block.
+func EncodePrivateKeyPEM(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: PrivateKeyPEMType, Bytes: der})
+}
+
+// DecodePublicKeyPEM returns the DER contents of the first PEM block, which must be a "PUBLIC KEY" block.
+func DecodePublicKeyPEM(pemBytes []byte) ([]byte, error) {
+	return decodePEM(pemBytes, PublicKeyPEMType)
+}
+
+// DecodePrivateKeyPEM returns the DER contents of the first PEM block, which must be a "PRIVATE KEY" block.
+func DecodePrivateKeyPEM(pemBytes []byte) ([]byte, error) {
+	return decodePEM(pemBytes, PrivateKeyPEMType)
+}
+
+// decodePEM returns the DER contents of the first PEM block, checking its type and rejecting encrypted (RFC 1423) headers.
+func decodePEM(pemBytes []byte, blockType string) ([]byte, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrInvalidKey)
+	}
+	if block.Type != blockType {
+		return nil, fmt.Errorf("%w: unexpected PEM block type %q, want %q", ErrInvalidKey, block.Type, blockType)
+	}
+	if len(block.Headers) != 0 {
+		return nil, fmt.Errorf("%w: unexpected PEM headers", ErrInvalidKey)
+	}
+	return block.Bytes, nil
+}
@@ -0,0 +1,37 @@
+package pq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkParseMLDSAPrivateKeyPKCS8(b *testing.B) {
+	for _, format := range []PrivateKeyFormat{PrivateKeyFormatSeed, PrivateKeyFormatExpanded} {
+		b.Run(format.String(), func(b *testing.B) {
+			keyPair, err := GenerateMLDSAKeyPair(MLDSA65)
+			require.NoError(b, err, "Key pair generation should not error")
+			der, err := MarshalMLDSAPrivateKeyPKCS8(keyPair, format)
+			require.NoError(b, err, "Encoding should not error")
+			for b.Loop() {
+				_, err := ParseMLDSAPrivateKeyPKCS8(der)
+				require.NoError(b, err, "Parsing should not error")
+			}
+		})
+	}
+}
+
+func BenchmarkParseMLKEMPrivateKeyPKCS8(b *testing.B) {
+	for _, format := range []PrivateKeyFormat{PrivateKeyFormatSeed, PrivateKeyFormatExpanded} {
+		b.Run(format.String(), func(b *testing.B) {
+			keyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+			require.NoError(b, err, "Key pair generation should not error")
+			der, err := MarshalMLKEMPrivateKeyPKCS8(keyPair, format)
+			require.NoError(b, err, "Encoding should not error")
+			for b.Loop() {
+				_, err := ParseMLKEMPrivateKeyPKCS8(der)
+				require.NoError(b, err, "Parsing should not error")
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package pq
+
+import "testing"
+
+func FuzzParseMLDSAPrivateKeyPKCS8(f *testing.F) {
+	keyPair, _ := GenerateMLDSAKeyPair(MLDSA44)
+	for _, format := range []PrivateKeyFormat{PrivateKeyFormatSeed, PrivateKeyFormatExpanded, PrivateKeyFormatBoth} {
+		der, _ := MarshalMLDSAPrivateKeyPKCS8(keyPair, format)
+		f.Add(der)
+	}
+	f.Fuzz(func(t *testing.T, der []byte) {
+		parsed, err := ParseMLDSAPrivateKeyPKCS8(der)
+		if err != nil {
+			if parsed != nil {
+				t.Error("expected nil key pair on error")
+			}
+			return
+		}
+		if _, err := MarshalMLDSAPrivateKeyPKCS8(parsed, PrivateKeyFormatExpanded); err != nil {
+			t.Errorf("parsed key pair should re-encode: %v", err)
+		}
+	})
+}
+
+func FuzzParseMLKEMPrivateKeyPKCS8(f *testing.F) {
+	keyPair, _ := GenerateMLKEMKeyPair(MLKEM512)
+	for _, format := range []PrivateKeyFormat{PrivateKeyFormatSeed, PrivateKeyFormatExpanded, PrivateKeyFormatBoth} {
+		der, _ := MarshalMLKEMPrivateKeyPKCS8(keyPair, format)
+		f.Add(der)
+	}
+	f.Fuzz(func(t *testing.T, der []byte) {
+		parsed, err := ParseMLKEMPrivateKeyPKCS8(der)
+		if err != nil {
+			if parsed != nil {
+				t.Error("expected nil key pair on error")
+			}
+			return
+		}
+		if _, err := MarshalMLKEMPrivateKeyPKCS8(parsed, PrivateKeyFormatExpanded); err != nil {
+			t.Errorf("parsed key pair should re-encode: %v", err)
+		}
+	})
+}
+
+func FuzzParsePKIXPublicKey(f *testing.F) {
+	mldsaKeyPair, _ := GenerateMLDSAKeyPair(MLDSA44)
+	mldsaDER, _ := MarshalMLDSAPublicKeyPKIX(MLDSA44, mldsaKeyPair.PublicKey)
+	f.Add(mldsaDER)
+	mlkemKeyPair, _ := GenerateMLKEMKeyPair(MLKEM768)
+	mlkemDER, _ := MarshalMLKEMPublicKeyPKIX(mlkemKeyPair.PublicKey)
+	f.Add(mlkemDER)
+	f.Fuzz(func(t *testing.T, der []byte) {
+		if parameterSet, publicKeyBytes, err := ParseMLDSAPublicKeyPKIX(der); err == nil {
+			if _, err := MarshalMLDSAPublicKeyPKIX(parameterSet, publicKeyBytes); err != nil {
+				t.Errorf("parsed ML-DSA public key should re-encode: %v", err)
+			}
+		}
+		if publicKey, err := ParseMLKEMPublicKeyPKIX(der); err == nil {
+			if _, err := MarshalMLKEMPublicKeyPKIX(publicKey); err != nil {
+				t.Errorf("parsed ML-KEM public key should re-encode: %v", err)
+			}
+		}
+	})
+}
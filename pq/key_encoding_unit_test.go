@@ -0,0 +1,327 @@
+package pq
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readPEMFixture returns the DER contents of a PEM file in a testdata directory.
+func readPEMFixture(t *testing.T, directory string, name string, decode func([]byte) ([]byte, error)) []byte {
+	t.Helper()
+	pemBytes, err := os.ReadFile(filepath.Join("testdata", directory, name))
+	require.NoError(t, err, "failed to read fixture")
+	der, err := decode(pemBytes)
+	require.NoError(t, err, "failed to decode PEM fixture")
+	return der
+}
+
+// For TestParseMLDSAPrivateKeyPKCS8RFC9881: the RFC 9881 example keys use the seed 00 01 .. 1f.
+func TestParseMLDSAPrivateKeyPKCS8RFC9881(t *testing.T) {
+	var seed [MLDSASeedSize]byte
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	fixtures := map[MLDSAParameterSet][]string{
+		MLDSA44: {"ml-dsa-44-seed.priv.pem", "ml-dsa-44-expanded.priv.pem", "ml-dsa-44-both.priv.pem"},
+		MLDSA65: {"ml-dsa-65-seed.priv.pem"},
+		MLDSA87: {"ml-dsa-87-seed.priv.pem"},
+	}
+	for _, parameterSet := range MLDSAParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			expected, err := DeriveMLDSAKeyPair(parameterSet, &seed)
+			require.NoError(t, err)
+			publicKeyDER := readPEMFixture(t, "rfc9881", "ml-dsa-"+parameterSet.String()[len("ML-DSA-"):]+".pub.pem", DecodePublicKeyPEM)
+			parsedParameterSet, publicKeyBytes, err := ParseMLDSAPublicKeyPKIX(publicKeyDER)
+			require.NoError(t, err, "failed to parse public key fixture")
+			require.Equal(t, parameterSet, parsedParameterSet)
+			require.Equal(t, expected.PublicKey, publicKeyBytes, "fixture public key does not match the derived key")
+
+			encodedPublicKey, err := MarshalMLDSAPublicKeyPKIX(parameterSet, publicKeyBytes)
+			require.NoError(t, err)
+			require.Equal(t, publicKeyDER, encodedPublicKey, "expected the SubjectPublicKeyInfo encoding to match the fixture")
+
+			for _, name := range fixtures[parameterSet] {
+				der := readPEMFixture(t, "rfc9881", name, DecodePrivateKeyPEM)
+				keyPair, err := ParseMLDSAPrivateKeyPKCS8(der)
+				require.NoError(t, err, "failed to parse %s", name)
+				require.Equal(t, parameterSet, keyPair.ParameterSet)
+				require.Equal(t, expected.PrivateKey, keyPair.PrivateKey, "%s: private key mismatch", name)
+				require.Equal(t, expected.PublicKey, keyPair.PublicKey, "%s: public key mismatch", name)
+
+				var format PrivateKeyFormat
+				switch {
+				case bytes.Contains([]byte(name), []byte("-seed")):
+					format = PrivateKeyFormatSeed
+					require.Equal(t, seed[:], keyPair.Seed)
+				case bytes.Contains([]byte(name), []byte("-expanded")):
+					format = PrivateKeyFormatExpanded
+					require.Nil(t, keyPair.Seed, "expanded keys carry no seed")
+				default:
+					format = PrivateKeyFormatBoth
+					require.Equal(t, seed[:], keyPair.Seed)
+				}
+				encoded, err := MarshalMLDSAPrivateKeyPKCS8(keyPair, format)
+				require.NoError(t, err)
+				require.Equal(t, der, encoded, "%s: expected re-encoding to match the fixture byte for byte", name)
+			}
+		})
+	}
+}
+
+// For TestMLDSAPrivateKeyPKCS8RoundTrip: every format round-trips for freshly generated keys.
+func TestMLDSAPrivateKeyPKCS8RoundTrip(t *testing.T) {
+	for _, parameterSet := range MLDSAParameterSets {
+		keyPair, err := GenerateMLDSAKeyPair(parameterSet)
+		require.NoError(t, err)
+		require.Len(t, keyPair.Seed, MLDSASeedSize, "generated key pairs keep their seed")
+		for _, format := range []PrivateKeyFormat{PrivateKeyFormatSeed, PrivateKeyFormatExpanded, PrivateKeyFormatBoth} {
+			t.Run(parameterSet.String()+"/"+format.String(), func(t *testing.T) {
+				der, err := MarshalMLDSAPrivateKeyPKCS8(keyPair, format)
+				require.NoError(t, err)
+				pemBytes := EncodePrivateKeyPEM(der)
+				decoded, err := DecodePrivateKeyPEM(pemBytes)
+				require.NoError(t, err)
+				parsed, err := ParseMLDSAPrivateKeyPKCS8(decoded)
+				require.NoError(t, err)
+				require.Equal(t, keyPair.PrivateKey, parsed.PrivateKey)
+				require.Equal(t, keyPair.PublicKey, parsed.PublicKey)
+			})
+		}
+	}
+}
+
+// For TestParseMLDSAPrivateKeyPKCS8RejectsInconsistentBoth: the seed and the expanded key of the both form must agree.
+func TestParseMLDSAPrivateKeyPKCS8RejectsInconsistentBoth(t *testing.T) {
+	first, err := GenerateMLDSAKeyPair(MLDSA44)
+	require.NoError(t, err)
+	second, err := GenerateMLDSAKeyPair(MLDSA44)
+	require.NoError(t, err)
+	mixed := &MLDSAKeyPair{ParameterSet: MLDSA44, PublicKey: first.PublicKey, PrivateKey: second.PrivateKey, Seed: first.Seed}
+	der, err := MarshalMLDSAPrivateKeyPKCS8(mixed, PrivateKeyFormatBoth)
+	require.NoError(t, err)
+	_, err = ParseMLDSAPrivateKeyPKCS8(der)
+	require.ErrorContains(t, err, "seed does not match expanded key")
+}
+
+// For TestMLDSAPrivateKeyPKCS8WithoutSeed: keys parsed from the expanded form can only be written back in the expanded form.
+func TestMLDSAPrivateKeyPKCS8WithoutSeed(t *testing.T) {
+	keyPair, err := GenerateMLDSAKeyPair(MLDSA65)
+	require.NoError(t, err)
+	keyPair.Seed = nil
+	_, err = MarshalMLDSAPrivateKeyPKCS8(keyPair, PrivateKeyFormatSeed)
+	require.Error(t, err)
+	_, err = MarshalMLDSAPrivateKeyPKCS8(keyPair, PrivateKeyFormatBoth)
+	require.Error(t, err)
+	_, err = MarshalMLDSAPrivateKeyPKCS8(keyPair, PrivateKeyFormatExpanded)
+	require.NoError(t, err)
+}
+
+// For TestParseMLKEMPrivateKeyPKCS8KyberCertificates: the draft-ietf-lamps-kyber-certificates example keys use the
+// seed 00 01 .. 3f.
+func TestParseMLKEMPrivateKeyPKCS8KyberCertificates(t *testing.T) {
+	seed := make([]byte, MLKEMSeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	fixtures := map[MLKEMParameterSet][]string{
+		MLKEM512:  {"ml-kem-512-seed.priv.pem", "ml-kem-512-expanded.priv.pem", "ml-kem-512-both.priv.pem"},
+		MLKEM768:  {"ml-kem-768-seed.priv.pem"},
+		MLKEM1024: {"ml-kem-1024-seed.priv.pem"},
+	}
+	for _, parameterSet := range MLKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			expected, err := GenerateDeterministicMLKEMKeyPair(parameterSet, seed)
+			require.NoError(t, err)
+			expectedPublicKey, err := expected.PublicKey.MarshalBinary()
+			require.NoError(t, err)
+			expectedPrivateKey, err := expected.PrivateKey.MarshalBinary()
+			require.NoError(t, err)
+
+			publicKeyDER := readPEMFixture(t, "kyber-certificates", "ml-kem-"+parameterSet.String()[len("ML-KEM-"):]+".pub.pem", DecodePublicKeyPEM)
+			publicKey, err := ParseMLKEMPublicKeyPKIX(publicKeyDER)
+			require.NoError(t, err, "failed to parse public key fixture")
+			publicKeyBytes, err := publicKey.MarshalBinary()
+			require.NoError(t, err)
+			require.Equal(t, expectedPublicKey, publicKeyBytes, "fixture public key does not match the derived key")
+
+			encodedPublicKey, err := MarshalMLKEMPublicKeyPKIX(publicKey)
+			require.NoError(t, err)
+			require.Equal(t, publicKeyDER, encodedPublicKey, "expected the SubjectPublicKeyInfo encoding to match the fixture")
+
+			for _, name := range fixtures[parameterSet] {
+				der := readPEMFixture(t, "kyber-certificates", name, DecodePrivateKeyPEM)
+				keyPair, err := ParseMLKEMPrivateKeyPKCS8(der)
+				require.NoError(t, err, "failed to parse %s", name)
+				require.Equal(t, parameterSet, keyPair.ParameterSet)
+				privateKeyBytes, err := keyPair.PrivateKey.MarshalBinary()
+				require.NoError(t, err)
+				require.Equal(t, expectedPrivateKey, privateKeyBytes, "%s: private key mismatch", name)
+
+				var format PrivateKeyFormat
+				switch {
+				case bytes.Contains([]byte(name), []byte("-seed")):
+					format = PrivateKeyFormatSeed
+					require.Equal(t, seed, keyPair.Seed)
+				case bytes.Contains([]byte(name), []byte("-expanded")):
+					format = PrivateKeyFormatExpanded
+					require.Nil(t, keyPair.Seed, "expanded keys carry no seed")
+				default:
+					format = PrivateKeyFormatBoth
+					require.Equal(t, seed, keyPair.Seed)
+				}
+				encoded, err := MarshalMLKEMPrivateKeyPKCS8(keyPair, format)
+				require.NoError(t, err)
+				require.Equal(t, der, encoded, "%s: expected re-encoding to match the fixture byte for byte", name)
+			}
+		})
+	}
+}
+
+// For TestMLKEMPKCS8AndPKIXRoundTrip: ML-KEM keys round-trip through every format and through PEM.
+func TestMLKEMPKCS8AndPKIXRoundTrip(t *testing.T) {
+	for _, parameterSet := range MLKEMParameterSets {
+		keyPair, err := GenerateMLKEMKeyPair(parameterSet)
+		require.NoError(t, err)
+		require.Len(t, keyPair.Seed, MLKEMSeedSize, "generated key pairs keep their seed")
+
+		publicKeyDER, err := MarshalMLKEMPublicKeyPKIX(keyPair.PublicKey)
+		require.NoError(t, err)
+		decodedPublicKey, err := DecodePublicKeyPEM(EncodePublicKeyPEM(publicKeyDER))
+		require.NoError(t, err)
+		publicKey, err := ParseMLKEMPublicKeyPKIX(decodedPublicKey)
+		require.NoError(t, err)
+		require.True(t, publicKey.Equal(keyPair.PublicKey))
+
+		for _, format := range []PrivateKeyFormat{PrivateKeyFormatSeed, PrivateKeyFormatExpanded, PrivateKeyFormatBoth} {
+			t.Run(parameterSet.String()+"/"+format.String(), func(t *testing.T) {
+				der, err := MarshalMLKEMPrivateKeyPKCS8(keyPair, format)
+				require.NoError(t, err)
+				parsed, err := ParseMLKEMPrivateKeyPKCS8(der)
+				require.NoError(t, err)
+				require.Equal(t, parameterSet, parsed.ParameterSet)
+				require.True(t, parsed.PrivateKey.Equal(keyPair.PrivateKey))
+				require.True(t, parsed.PublicKey.Equal(keyPair.PublicKey))
+			})
+		}
+	}
+}
+
+// For TestMLKEMPKCS8SeedEncoding: the seed form is the 64-byte d || z under [0] IMPLICIT, with the NIST OID and absent parameters.
+func TestMLKEMPKCS8SeedEncoding(t *testing.T) {
+	seed := make([]byte, MLKEMSeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	keyPair, err := GenerateDeterministicMLKEMKeyPair(MLKEM768, seed)
+	require.NoError(t, err)
+	der, err := MarshalMLKEMPrivateKeyPKCS8(keyPair, PrivateKeyFormatSeed)
+	require.NoError(t, err)
+	expected := append([]byte{
+		0x30, 0x54, 0x02, 0x01, 0x00,
+		0x30, 0x0b, 0x06, 0x09, 0x60, 0x86, 0x48, 0x01, 0x65, 0x03, 0x04, 0x04, 0x02,
+		0x04, 0x42, 0x80, 0x40,
+	}, seed...)
+	require.Equal(t, expected, der)
+}
+
+// For TestLegacyKyber1024HasNoOID: legacy Kyber keys cannot be encoded.
+func TestLegacyKyber1024HasNoOID(t *testing.T) {
+	keyPair, err := GenerateMLKEMKeyPair(LegacyKyber1024)
+	require.NoError(t, err)
+	_, err = MarshalMLKEMPublicKeyPKIX(keyPair.PublicKey)
+	require.Error(t, err)
+	_, err = MarshalMLKEMPrivateKeyPKCS8(keyPair, PrivateKeyFormatSeed)
+	require.Error(t, err)
+}
+
+// For TestXWingAndCompositePKCS8RoundTrip: X-Wing and composite ML-DSA keys round-trip under their own OIDs.
+func TestXWingAndCompositePKCS8RoundTrip(t *testing.T) {
+	xwingKeyPair, err := GenerateXWingKeyPair()
+	require.NoError(t, err)
+	publicKeyDER, err := MarshalXWingPublicKeyPKIX(xwingKeyPair.PublicKey)
+	require.NoError(t, err)
+	publicKey, err := ParseXWingPublicKeyPKIX(publicKeyDER)
+	require.NoError(t, err)
+	require.True(t, publicKey.Equal(xwingKeyPair.PublicKey))
+	privateKeyDER, err := MarshalXWingPrivateKeyPKCS8(xwingKeyPair.PrivateKey)
+	require.NoError(t, err)
+	parsedXWing, err := ParseXWingPrivateKeyPKCS8(privateKeyDER)
+	require.NoError(t, err)
+	require.True(t, parsedXWing.PrivateKey.Equal(xwingKeyPair.PrivateKey))
+
+	for _, algorithm := range CompositeMLDSAAlgorithms {
+		t.Run(algorithm.String(), func(t *testing.T) {
+			keyPair, err := GenerateCompositeMLDSAKeyPair(algorithm)
+			require.NoError(t, err)
+			publicKeyDER, err := MarshalCompositeMLDSAPublicKeyPKIX(keyPair.PublicKey)
+			require.NoError(t, err)
+			publicKey, err := ParseCompositeMLDSAPublicKeyPKIX(publicKeyDER)
+			require.NoError(t, err)
+			require.Equal(t, keyPair.PublicKey.MLDSAPublicKey, publicKey.MLDSAPublicKey)
+			privateKeyDER, err := MarshalCompositeMLDSAPrivateKeyPKCS8(keyPair.PrivateKey)
+			require.NoError(t, err)
+			privateKey, err := ParseCompositeMLDSAPrivateKeyPKCS8(privateKeyDER)
+			require.NoError(t, err)
+			require.Equal(t, keyPair.PrivateKey.MLDSAPrivateKey, privateKey.MLDSAPrivateKey)
+
+			_, err = ParseMLDSAPrivateKeyPKCS8(privateKeyDER)
+			require.Error(t, err, "a composite key must not parse as a plain ML-DSA key")
+		})
+	}
+}
+
+// For TestKeyEncodingRejectsMalformedInput: wrong algorithms, parameters, sizes, trailing data and PEM types are rejected.
+func TestKeyEncodingRejectsMalformedInput(t *testing.T) {
+	keyPair, err := GenerateMLDSAKeyPair(MLDSA44)
+	require.NoError(t, err)
+	publicKeyDER, err := MarshalMLDSAPublicKeyPKIX(MLDSA44, keyPair.PublicKey)
+	require.NoError(t, err)
+	privateKeyDER, err := MarshalMLDSAPrivateKeyPKCS8(keyPair, PrivateKeyFormatSeed)
+	require.NoError(t, err)
+
+	_, _, err = ParseMLDSAPublicKeyPKIX(append(append([]byte(nil), publicKeyDER...), 0x00))
+	require.Error(t, err, "trailing data")
+	_, err = ParseMLKEMPublicKeyPKIX(publicKeyDER)
+	require.Error(t, err, "wrong algorithm")
+	_, err = ParseMLKEMPrivateKeyPKCS8(privateKeyDER)
+	require.Error(t, err, "wrong algorithm")
+	_, err = MarshalMLDSAPublicKeyPKIX(MLDSA44, keyPair.PublicKey[1:])
+	require.Error(t, err, "wrong public key size")
+
+	oid, _ := MLDSA44.OID()
+	withParameters, err := asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue},
+		PublicKey: asn1.BitString{Bytes: keyPair.PublicKey, BitLength: 8 * len(keyPair.PublicKey)},
+	})
+	require.NoError(t, err)
+	_, _, err = ParseMLDSAPublicKeyPKIX(withParameters)
+	require.Error(t, err, "NULL parameters are not allowed")
+
+	shortSeed, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: make([]byte, MLDSASeedSize-1)})
+	require.NoError(t, err)
+	shortSeedDER, err := marshalPKCS8(oid, shortSeed)
+	require.NoError(t, err)
+	_, err = ParseMLDSAPrivateKeyPKCS8(shortSeedDER)
+	require.Error(t, err, "short seed")
+
+	wrongTag, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 1, Bytes: make([]byte, MLDSASeedSize)})
+	require.NoError(t, err)
+	wrongTagDER, err := marshalPKCS8(oid, wrongTag)
+	require.NoError(t, err)
+	_, err = ParseMLDSAPrivateKeyPKCS8(wrongTagDER)
+	require.Error(t, err, "unknown CHOICE tag")
+
+	_, err = MarshalMLDSAPrivateKeyPKCS8(keyPair, PrivateKeyFormat(0))
+	require.Error(t, err, "unknown format")
+
+	_, err = DecodePrivateKeyPEM(EncodePublicKeyPEM(publicKeyDER))
+	require.Error(t, err, "wrong PEM type")
+	_, err = DecodePublicKeyPEM([]byte("not PEM"))
+	require.Error(t, err, "no PEM block")
+}
@@ -0,0 +1,824 @@
+package pq
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+)
+
+// LMSType is an LMS tree type code of RFC 8554 and NIST SP 800-208. It names the hash function, the node size m and the
+// tree height h; an LMS key can make 2^h one-time signatures.
+type LMSType uint32
+
+// LMS tree types. The SHA-256 M32 types are defined in RFC 8554, the M24 (SHA-256/192) and SHAKE types in SP 800-208.
+const (
+	LMSSHA256M32H5  LMSType = 0x05
+	LMSSHA256M32H10 LMSType = 0x06
+	LMSSHA256M32H15 LMSType = 0x07
+	LMSSHA256M32H20 LMSType = 0x08
+	LMSSHA256M32H25 LMSType = 0x09
+	LMSSHA256M24H5  LMSType = 0x0a
+	LMSSHA256M24H10 LMSType = 0x0b
+	LMSSHA256M24H15 LMSType = 0x0c
+	LMSSHA256M24H20 LMSType = 0x0d
+	LMSSHA256M24H25 LMSType = 0x0e
+	LMSSHAKEM32H5   LMSType = 0x0f
+	LMSSHAKEM32H10  LMSType = 0x10
+	LMSSHAKEM32H15  LMSType = 0x11
+	LMSSHAKEM32H20  LMSType = 0x12
+	LMSSHAKEM32H25  LMSType = 0x13
+	LMSSHAKEM24H5   LMSType = 0x14
+	LMSSHAKEM24H10  LMSType = 0x15
+	LMSSHAKEM24H15  LMSType = 0x16
+	LMSSHAKEM24H20  LMSType = 0x17
+	LMSSHAKEM24H25  LMSType = 0x18
+)
+
+// LMOTSType is an LM-OTS one-time signature type code of RFC 8554 and NIST SP 800-208. It names the hash function, the
+// output size n and the Winternitz parameter w, which trades signature size (small for large w) against speed.
+type LMOTSType uint32
+
+// LM-OTS types. The SHA-256 N32 types are defined in RFC 8554, the N24 (SHA-256/192) and SHAKE types in SP 800-208.
+const (
+	LMOTSSHA256N32W1 LMOTSType = 0x01
+	LMOTSSHA256N32W2 LMOTSType = 0x02
+	LMOTSSHA256N32W4 LMOTSType = 0x03
+	LMOTSSHA256N32W8 LMOTSType = 0x04
+	LMOTSSHA256N24W1 LMOTSType = 0x05
+	LMOTSSHA256N24W2 LMOTSType = 0x06
+	LMOTSSHA256N24W4 LMOTSType = 0x07
+	LMOTSSHA256N24W8 LMOTSType = 0x08
+	LMOTSSHAKEN32W1  LMOTSType = 0x09
+	LMOTSSHAKEN32W2  LMOTSType = 0x0a
+	LMOTSSHAKEN32W4  LMOTSType = 0x0b
+	LMOTSSHAKEN32W8  LMOTSType = 0x0c
+	LMOTSSHAKEN24W1  LMOTSType = 0x0d
+	LMOTSSHAKEN24W2  LMOTSType = 0x0e
+	LMOTSSHAKEN24W4  LMOTSType = 0x0f
+	LMOTSSHAKEN24W8  LMOTSType = 0x10
+)
+
+// HSSMaxLevels is the maximum number of LMS levels of an HSS key (RFC 8554 Section 6).
+const HSSMaxLevels = 8
+
+// LMSMaxTreeHeight is the highest LMS tree that key generation and signing accept. The signer holds every node of the
+// current tree of each level in memory, 2^(h+1) m-byte nodes, and computes 2^h LM-OTS public keys to build one, so the
+// H20 and H25 types would need up to 2 GiB and hours of hashing per tree. Use an HSS key with several levels of lower
+// trees for more signatures. HSSVerify and LMSVerify accept every height.
+const LMSMaxTreeHeight = 15
+
+// RFC 8554 domain separation constants and sizes.
+const (
+	lmsDomainPublicKey   = 0x8080 // D_PBLC
+	lmsDomainMessage     = 0x8181 // D_MESG
+	lmsDomainLeaf        = 0x8282 // D_LEAF
+	lmsDomainInterior    = 0x8383 // D_INTR
+	lmsIdentifierSize    = 16     // the key pair identifier I
+	lmsTypeSize          = 4
+	lmotsRandomizerIndex = 0xfffd // derives the message randomizer C of an internal public key signature
+	lmsChildSeedIndex    = 0xfffe // derives the SEED of a child tree
+	lmsChildIDIndex      = 0xffff // derives the identifier I of a child tree
+)
+
+// lmsHashFamily is the hash function of an LMS or LM-OTS type.
+type lmsHashFamily uint8
+
+const (
+	lmsHashSHA256 lmsHashFamily = iota + 1
+	lmsHashSHAKE256
+)
+
+// String returns the hash function name used in the type names.
+func (family lmsHashFamily) String() string {
+	if family == lmsHashSHAKE256 {
+		return "SHAKE"
+	}
+	return "SHA256"
+}
+
+// digest returns the first n bytes of SHA-256, or n bytes of SHAKE256, over the concatenated parts.
+func (family lmsHashFamily) digest(n int, parts ...[]byte) []byte {
+	if family == lmsHashSHAKE256 {
+		shake := sha3.NewSHAKE256()
+		for _, part := range parts {
+			_, _ = shake.Write(part)
+		}
+		output := make([]byte, n)
+		_, _ = shake.Read(output)
+		return output
+	}
+	hash := sha256.New()
+	for _, part := range parts {
+		_, _ = hash.Write(part)
+	}
+	return hash.Sum(nil)[:n]
+}
+
+// sum returns the digest of a single buffer, without the allocations of the streaming interface.
+func (family lmsHashFamily) sum(data []byte, n int) []byte {
+	if family == lmsHashSHAKE256 {
+		return sha3.SumSHAKE256(data, n)
+	}
+	digest := sha256.Sum256(data)
+	return digest[:n]
+}
+
+// lmsParameters are the parameters of an LMS tree type.
+type lmsParameters struct {
+	family lmsHashFamily
+	m      int
+	h      int
+}
+
+// lmsTreeHeights are the tree heights of the five types of each LMS family, in type code order.
+var lmsTreeHeights = [...]int{5, 10, 15, 20, 25}
+
+// parameters returns the hash function, node size and height of the LMS type.
+func (lmsType LMSType) parameters() (lmsParameters, error) {
+	families := []struct {
+		first  LMSType
+		family lmsHashFamily
+		m      int
+	}{
+		{LMSSHA256M32H5, lmsHashSHA256, 32},
+		{LMSSHA256M24H5, lmsHashSHA256, 24},
+		{LMSSHAKEM32H5, lmsHashSHAKE256, 32},
+		{LMSSHAKEM24H5, lmsHashSHAKE256, 24},
+	}
+	for _, family := range families {
+		if lmsType >= family.first && lmsType < family.first+LMSType(len(lmsTreeHeights)) {
+			return lmsParameters{family: family.family, m: family.m, h: lmsTreeHeights[lmsType-family.first]}, nil
+		}
+	}
+	return lmsParameters{}, fmt.Errorf("unsupported LMS type: %d", uint32(lmsType))
+}
+
+// String returns the RFC 8554 name of the type, for example "LMS_SHA256_M32_H10".
+func (lmsType LMSType) String() string {
+	parameters, err := lmsType.parameters()
+	if err != nil {
+		return fmt.Sprintf("LMSType(%d)", uint32(lmsType))
+	}
+	return fmt.Sprintf("LMS_%s_M%d_H%d", parameters.family, parameters.m, parameters.h)
+}
+
+// lmotsParameters are the parameters of an LM-OTS type: p n-byte chains of Winternitz parameter w, and the left shift
+// ls of the checksum (RFC 8554 Section 4.1 and Appendix B).
+type lmotsParameters struct {
+	family lmsHashFamily
+	n      int
+	w      int
+	p      int
+	ls     int
+}
+
+// lmotsWinternitzParameters are the Winternitz parameters of the four types of each LM-OTS family, in type code order.
+var lmotsWinternitzParameters = [...]int{1, 2, 4, 8}
+
+// parameters returns the parameters of the LM-OTS type, with p and ls computed as in RFC 8554 Appendix B.
+func (otsType LMOTSType) parameters() (lmotsParameters, error) {
+	families := []struct {
+		first  LMOTSType
+		family lmsHashFamily
+		n      int
+	}{
+		{LMOTSSHA256N32W1, lmsHashSHA256, 32},
+		{LMOTSSHA256N24W1, lmsHashSHA256, 24},
+		{LMOTSSHAKEN32W1, lmsHashSHAKE256, 32},
+		{LMOTSSHAKEN24W1, lmsHashSHAKE256, 24},
+	}
+	for _, family := range families {
+		if otsType >= family.first && otsType < family.first+LMOTSType(len(lmotsWinternitzParameters)) {
+			w := lmotsWinternitzParameters[otsType-family.first]
+			u := (8*family.n + w - 1) / w
+			checksumBits := 1
+			for maximum := ((1 << w) - 1) * u; maximum > 1; maximum >>= 1 {
+				checksumBits++
+			}
+			v := (checksumBits + w - 1) / w
+			return lmotsParameters{family: family.family, n: family.n, w: w, p: u + v, ls: 16 - v*w}, nil
+		}
+	}
+	return lmotsParameters{}, fmt.Errorf("unsupported LM-OTS type: %d", uint32(otsType))
+}
+
+// String returns the RFC 8554 name of the type, for example "LMOTS_SHA256_N32_W8".
+func (otsType LMOTSType) String() string {
+	parameters, err := otsType.parameters()
+	if err != nil {
+		return fmt.Sprintf("LMOTSType(%d)", uint32(otsType))
+	}
+	return fmt.Sprintf("LMOTS_%s_N%d_W%d", parameters.family, parameters.n, parameters.w)
+}
+
+// signatureSize returns the size of an LM-OTS signature: type || C || y[0] || ... || y[p-1].
+func (parameters lmotsParameters) signatureSize() int {
+	return lmsTypeSize + parameters.n + parameters.p*parameters.n
+}
+
+// coefficient returns coef(S, i, w) of RFC 8554 Section 3.1.3, the i-th w-bit digit of S.
+func (parameters lmotsParameters) coefficient(data []byte, i int) int {
+	w := parameters.w
+	return int(data[i*w/8]>>(8-(w*(i%(8/w))+w))) & (1<<w - 1)
+}
+
+// checksummed returns Q || Cksm(Q) of RFC 8554 Section 4.4.
+func (parameters lmotsParameters) checksummed(messageHash []byte) []byte {
+	sum := 0
+	for i := 0; i < parameters.n*8/parameters.w; i++ {
+		sum += (1<<parameters.w - 1) - parameters.coefficient(messageHash, i)
+	}
+	return binary.BigEndian.AppendUint16(append([]byte(nil), messageHash...), uint16(sum<<parameters.ls))
+}
+
+// chain applies steps iterations of the LM-OTS chain function to value, starting at iteration start, for chain i of leaf q.
+func (parameters lmotsParameters) chain(identifier []byte, q uint32, i int, start int, steps int, value []byte) []byte {
+	buffer := make([]byte, lmsIdentifierSize+7+parameters.n)
+	copy(buffer, identifier)
+	binary.BigEndian.PutUint32(buffer[16:], q)
+	binary.BigEndian.PutUint16(buffer[20:], uint16(i))
+	copy(buffer[23:], value)
+	for j := start; j < start+steps; j++ {
+		buffer[22] = byte(j)
+		copy(buffer[23:], parameters.family.sum(buffer, parameters.n))
+	}
+	return buffer[23:]
+}
+
+// privateElement returns x_q[i] = H(I || u32str(q) || u16str(i) || u8str(0xff) || SEED), the pseudorandom key
+// generation of RFC 8554 Appendix A.
+func (parameters lmotsParameters) privateElement(identifier []byte, q uint32, i int, seed []byte) []byte {
+	return lmsDerive(parameters.family, parameters.n, identifier, q, uint16(i), seed)
+}
+
+// lmsDerive returns H(I || u32str(q) || u16str(index) || u8str(0xff) || SEED), truncated to n bytes.
+func lmsDerive(family lmsHashFamily, n int, identifier []byte, q uint32, index uint16, seed []byte) []byte {
+	buffer := make([]byte, 0, lmsIdentifierSize+7+len(seed))
+	buffer = append(buffer, identifier...)
+	buffer = binary.BigEndian.AppendUint32(buffer, q)
+	buffer = binary.BigEndian.AppendUint16(buffer, index)
+	buffer = append(buffer, 0xff)
+	buffer = append(buffer, seed...)
+	return family.sum(buffer, n)
+}
+
+// publicKeyHash returns the LM-OTS public key K of leaf q (RFC 8554 Algorithm 1).
+func (parameters lmotsParameters) publicKeyHash(identifier []byte, q uint32, seed []byte) []byte {
+	chainEnds := make([]byte, 0, parameters.p*parameters.n)
+	for i := 0; i < parameters.p; i++ {
+		privateElement := parameters.privateElement(identifier, q, i, seed)
+		chainEnds = append(chainEnds, parameters.chain(identifier, q, i, 0, 1<<parameters.w-1, privateElement)...)
+	}
+	return parameters.family.digest(parameters.n, identifier, binary.BigEndian.AppendUint32(nil, q),
+		binary.BigEndian.AppendUint16(nil, lmsDomainPublicKey), chainEnds)
+}
+
+// sign returns the LM-OTS signature of leaf q over message with randomizer C (RFC 8554 Algorithm 3).
+func (parameters lmotsParameters) sign(otsType LMOTSType, identifier []byte, q uint32, seed []byte, message []byte, randomizer []byte) []byte {
+	messageHash := parameters.family.digest(parameters.n, identifier, binary.BigEndian.AppendUint32(nil, q),
+		binary.BigEndian.AppendUint16(nil, lmsDomainMessage), randomizer, message)
+	digits := parameters.checksummed(messageHash)
+	signature := binary.BigEndian.AppendUint32(make([]byte, 0, parameters.signatureSize()), uint32(otsType))
+	signature = append(signature, randomizer...)
+	for i := 0; i < parameters.p; i++ {
+		privateElement := parameters.privateElement(identifier, q, i, seed)
+		signature = append(signature, parameters.chain(identifier, q, i, 0, parameters.coefficient(digits, i), privateElement)...)
+	}
+	return signature
+}
+
+// candidatePublicKey computes the candidate public key Kc of an LM-OTS signature, without its type code (RFC 8554 Algorithm 4b).
+func (parameters lmotsParameters) candidatePublicKey(identifier []byte, q uint32, signature []byte, message []byte) []byte {
+	randomizer := signature[:parameters.n]
+	chains := signature[parameters.n:]
+	messageHash := parameters.family.digest(parameters.n, identifier, binary.BigEndian.AppendUint32(nil, q),
+		binary.BigEndian.AppendUint16(nil, lmsDomainMessage), randomizer, message)
+	digits := parameters.checksummed(messageHash)
+	chainEnds := make([]byte, 0, parameters.p*parameters.n)
+	for i := 0; i < parameters.p; i++ {
+		digit := parameters.coefficient(digits, i)
+		chainEnds = append(chainEnds, parameters.chain(identifier, q, i, digit, 1<<parameters.w-1-digit, chains[i*parameters.n:(i+1)*parameters.n])...)
+	}
+	return parameters.family.digest(parameters.n, identifier, binary.BigEndian.AppendUint32(nil, q),
+		binary.BigEndian.AppendUint16(nil, lmsDomainPublicKey), chainEnds)
+}
+
+// lmsTree is the private key of one LMS tree with all its nodes: T[r] is nodes[r*m:(r+1)*m] for 1 <= r < 2^(h+1),
+// numbered as in RFC 8554 Section 5.3.
+type lmsTree struct {
+	lmsType         LMSType
+	otsType         LMOTSType
+	lmsParameters   lmsParameters
+	lmotsParameters lmotsParameters
+	identifier      []byte
+	seed            []byte
+	nodes           []byte
+}
+
+// newLMSTree computes the tree of the LMS private key (I, SEED); this costs 2^h LM-OTS key generations.
+func newLMSTree(level HSSLevel, identifier []byte, seed []byte) (*lmsTree, error) {
+	lmsParameters, lmotsParameters, err := level.parameters()
+	if err != nil {
+		return nil, err
+	}
+	m := lmsParameters.m
+	leafCount := uint32(1) << lmsParameters.h
+	tree := &lmsTree{
+		lmsType:         level.LMSType,
+		otsType:         level.LMOTSType,
+		lmsParameters:   lmsParameters,
+		lmotsParameters: lmotsParameters,
+		identifier:      identifier,
+		seed:            seed,
+		nodes:           make([]byte, 2*int(leafCount)*m),
+	}
+	for q := uint32(0); q < leafCount; q++ {
+		r := leafCount + q
+		otsPublicKey := lmotsParameters.publicKeyHash(identifier, q, seed)
+		copy(tree.node(r), lmsParameters.family.digest(m, identifier, binary.BigEndian.AppendUint32(nil, r),
+			binary.BigEndian.AppendUint16(nil, lmsDomainLeaf), otsPublicKey))
+	}
+	for r := leafCount - 1; r >= 1; r-- {
+		copy(tree.node(r), lmsParameters.family.digest(m, identifier, binary.BigEndian.AppendUint32(nil, r),
+			binary.BigEndian.AppendUint16(nil, lmsDomainInterior), tree.node(2*r), tree.node(2*r+1)))
+	}
+	return tree, nil
+}
+
+// node returns T[r].
+func (tree *lmsTree) node(r uint32) []byte {
+	m := uint32(tree.lmsParameters.m)
+	return tree.nodes[r*m : (r+1)*m]
+}
+
+// publicKey returns the LMS public key u32str(type) || u32str(otstype) || I || T[1].
+func (tree *lmsTree) publicKey() []byte {
+	publicKey := binary.BigEndian.AppendUint32(nil, uint32(tree.lmsType))
+	publicKey = binary.BigEndian.AppendUint32(publicKey, uint32(tree.otsType))
+	publicKey = append(publicKey, tree.identifier...)
+	return append(publicKey, tree.node(1)...)
+}
+
+// sign returns the LMS signature u32str(q) || lmots_signature || u32str(type) || path[0] || ... || path[h-1] (RFC 8554 Algorithm 5).
+func (tree *lmsTree) sign(q uint32, message []byte, randomizer []byte) []byte {
+	signature := binary.BigEndian.AppendUint32(nil, q)
+	signature = append(signature, tree.lmotsParameters.sign(tree.otsType, tree.identifier, q, tree.seed, message, randomizer)...)
+	signature = binary.BigEndian.AppendUint32(signature, uint32(tree.lmsType))
+	nodeNumber := uint32(1)<<tree.lmsParameters.h + q
+	for i := 0; i < tree.lmsParameters.h; i++ {
+		signature = append(signature, tree.node((nodeNumber>>i)^1)...)
+	}
+	return signature
+}
+
+// child derives the identifier and SEED of the tree that leaf q of this tree signs. The derivation reuses the RFC 8554
+// Appendix A construction with chain indexes that no LM-OTS type uses, like the hash-sigs reference implementation
+// that generated RFC 8554 Appendix F Test Case 2: SEED from index 0xfffe and I from index 0xffff.
+func (tree *lmsTree) child(q uint32) ([]byte, []byte) {
+	family, n := tree.lmotsParameters.family, tree.lmotsParameters.n
+	return lmsDerive(family, n, tree.identifier, q, lmsChildIDIndex, tree.seed)[:lmsIdentifierSize],
+		lmsDerive(family, n, tree.identifier, q, lmsChildSeedIndex, tree.seed)
+}
+
+// lmsPublicKeySize returns the size of the LMS public key starting publicKey, or false if its type is unknown or it is truncated.
+func lmsPublicKeySize(publicKey []byte) (int, bool) {
+	if len(publicKey) < lmsTypeSize {
+		return 0, false
+	}
+	lmsParameters, err := LMSType(binary.BigEndian.Uint32(publicKey)).parameters()
+	if err != nil {
+		return 0, false
+	}
+	return 2*lmsTypeSize + lmsIdentifierSize + lmsParameters.m, true
+}
+
+// lmsSignatureSize returns the size of the LMS signature starting signature, or false if a type is unknown or it is truncated.
+func lmsSignatureSize(signature []byte) (int, bool) {
+	if len(signature) < 2*lmsTypeSize {
+		return 0, false
+	}
+	lmotsParameters, err := LMOTSType(binary.BigEndian.Uint32(signature[4:])).parameters()
+	if err != nil {
+		return 0, false
+	}
+	lmsTypeOffset := lmsTypeSize + lmotsParameters.signatureSize()
+	if len(signature) < lmsTypeOffset+lmsTypeSize {
+		return 0, false
+	}
+	lmsParameters, err := LMSType(binary.BigEndian.Uint32(signature[lmsTypeOffset:])).parameters()
+	if err != nil {
+		return 0, false
+	}
+	return lmsTypeOffset + lmsTypeSize + lmsParameters.h*lmsParameters.m, true
+}
+
+// verifyLMS verifies an LMS signature (RFC 8554 Algorithm 6). Malformed signatures are invalid, not errors.
+func verifyLMS(publicKey []byte, message []byte, signature []byte) (bool, error) {
+	if len(publicKey) < 2*lmsTypeSize {
+		return false, newSizeError(ErrInvalidKeySize, "LMS public key", len(publicKey), 0)
+	}
+	lmsType := LMSType(binary.BigEndian.Uint32(publicKey))
+	otsType := LMOTSType(binary.BigEndian.Uint32(publicKey[4:]))
+	lmsParameters, lmotsParameters, err := HSSLevel{LMSType: lmsType, LMOTSType: otsType}.parameters()
+	if err != nil {
+		return false, err
+	}
+	publicKeySize := 2*lmsTypeSize + lmsIdentifierSize + lmsParameters.m
+	if len(publicKey) != publicKeySize {
+		return false, newSizeError(ErrInvalidKeySize, lmsType.String()+" public key", len(publicKey), publicKeySize)
+	}
+	identifier := publicKey[2*lmsTypeSize : 2*lmsTypeSize+lmsIdentifierSize]
+	root := publicKey[2*lmsTypeSize+lmsIdentifierSize:]
+
+	signatureSize, ok := lmsSignatureSize(signature)
+	if !ok || len(signature) != signatureSize {
+		return false, nil
+	}
+	q := binary.BigEndian.Uint32(signature)
+	lmsTypeOffset := lmsTypeSize + lmotsParameters.signatureSize()
+	if LMOTSType(binary.BigEndian.Uint32(signature[4:])) != otsType ||
+		LMSType(binary.BigEndian.Uint32(signature[lmsTypeOffset:])) != lmsType ||
+		uint64(q) >= uint64(1)<<lmsParameters.h {
+		return false, nil
+	}
+	otsPublicKey := lmotsParameters.candidatePublicKey(identifier, q, signature[2*lmsTypeSize:lmsTypeOffset], message)
+	path := signature[lmsTypeOffset+lmsTypeSize:]
+	m := lmsParameters.m
+	nodeNumber := uint32(1)<<lmsParameters.h + q
+	node := lmsParameters.family.digest(m, identifier, binary.BigEndian.AppendUint32(nil, nodeNumber),
+		binary.BigEndian.AppendUint16(nil, lmsDomainLeaf), otsPublicKey)
+	for i := 0; nodeNumber > 1; i++ {
+		sibling := path[i*m : (i+1)*m]
+		parent := binary.BigEndian.AppendUint32(nil, nodeNumber/2)
+		interior := binary.BigEndian.AppendUint16(nil, lmsDomainInterior)
+		if nodeNumber%2 == 1 {
+			node = lmsParameters.family.digest(m, identifier, parent, interior, sibling, node)
+		} else {
+			node = lmsParameters.family.digest(m, identifier, parent, interior, node, sibling)
+		}
+		nodeNumber /= 2
+	}
+	return subtle.ConstantTimeCompare(node, root) == 1, nil
+}
+
+// HSSLevel is the LMS and LM-OTS type of one level of an HSS key. Level 0 is the top tree, whose root is the public key;
+// the last level signs the messages.
+type HSSLevel struct {
+	LMSType   LMSType
+	LMOTSType LMOTSType
+}
+
+// parameters returns the LMS and LM-OTS parameters of the level. SP 800-208 requires both to use the same hash function and output size.
+func (level HSSLevel) parameters() (lmsParameters, lmotsParameters, error) {
+	lmsParameters, err := level.LMSType.parameters()
+	if err != nil {
+		return lmsParameters, lmotsParameters{}, err
+	}
+	lmotsParameters, err := level.LMOTSType.parameters()
+	if err != nil {
+		return lmsParameters, lmotsParameters, err
+	}
+	if lmsParameters.family != lmotsParameters.family || lmsParameters.m != lmotsParameters.n {
+		return lmsParameters, lmotsParameters, fmt.Errorf("%v cannot be combined with %v", level.LMSType, level.LMOTSType)
+	}
+	return lmsParameters, lmotsParameters, nil
+}
+
+// validateHSSLevels checks the levels of an HSS key for key generation and signing, and returns the output size n they share.
+func validateHSSLevels(levels []HSSLevel) (int, error) {
+	if len(levels) < 1 || len(levels) > HSSMaxLevels {
+		return 0, fmt.Errorf("unsupported number of HSS levels: %d, want 1 to %d", len(levels), HSSMaxLevels)
+	}
+	_, topParameters, err := levels[0].parameters()
+	if err != nil {
+		return 0, err
+	}
+	for _, level := range levels {
+		lmsParameters, lmotsParameters, levelError := level.parameters()
+		if levelError != nil {
+			return 0, levelError
+		}
+		if lmsParameters.h > LMSMaxTreeHeight {
+			return 0, fmt.Errorf("%w: %v has height %d, maximum is %d", ErrUnsupportedTreeHeight, level.LMSType, lmsParameters.h, LMSMaxTreeHeight)
+		}
+		if lmotsParameters.family != topParameters.family || lmotsParameters.n != topParameters.n {
+			return 0, fmt.Errorf("all HSS levels must use the same hash function and output size: %v and %v", levels[0].LMOTSType, level.LMOTSType)
+		}
+	}
+	return topParameters.n, nil
+}
+
+// HSSKeyPair represents an HSS/LMS key pair (RFC 8554). PublicKey is the standard HSS public key u32str(L) || pub[0].
+// PrivateKey is the gopq encoding u32str(L) || (u32str(LMS type) || u32str(LM-OTS type)) per level || I || SEED; it holds
+// no state, which is kept by the SignatureStateStore given to NewHSSSigner.
+type HSSKeyPair struct {
+	Levels     []HSSLevel
+	PublicKey  []byte
+	PrivateKey []byte
+}
+
+// GenerateHSSKeyPair generates a new HSS key pair with the given levels from a fresh random identifier and SEED.
+// Key generation computes the top tree, which costs 2^h LM-OTS key generations for a top tree of height h.
+func GenerateHSSKeyPair(levels []HSSLevel) (keyPair *HSSKeyPair, keyGenerationError error) {
+	n, levelError := validateHSSLevels(levels)
+	if levelError != nil {
+		return nil, levelError
+	}
+	seed := make([]byte, lmsIdentifierSize+n)
+	if _, randomError := rand.Read(seed); randomError != nil {
+		return nil, fmt.Errorf("rand.Read: %w", randomError)
+	}
+	return DeriveHSSKeyPair(levels, seed)
+}
+
+// DeriveHSSKeyPair deterministically derives an HSS key pair with the given levels from seed = I || SEED, the 16-byte
+// identifier and the n-byte secret seed of the top tree. The LM-OTS private keys are derived as in RFC 8554 Appendix A,
+// and the trees of the lower levels as in RFC 8554 Appendix F Test Case 2.
+func DeriveHSSKeyPair(levels []HSSLevel, seed []byte) (keyPair *HSSKeyPair, keyGenerationError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			keyGenerationError = newPanicError("DeriveHSSKeyPair", recoveredPanic)
+		}
+	}()
+	n, levelError := validateHSSLevels(levels)
+	if levelError != nil {
+		return nil, levelError
+	}
+	if len(seed) != lmsIdentifierSize+n {
+		return nil, newSizeError(ErrInvalidSeedSize, "HSS seed", len(seed), lmsIdentifierSize+n)
+	}
+	privateKey := marshalHSSPrivateKey(levels, seed[:lmsIdentifierSize], seed[lmsIdentifierSize:])
+	topTree, treeError := newLMSTree(levels[0], privateKey.identifier, privateKey.seed)
+	if treeError != nil {
+		return nil, treeError
+	}
+	publicKeyBytes := binary.BigEndian.AppendUint32(nil, uint32(len(levels)))
+	publicKeyBytes = append(publicKeyBytes, topTree.publicKey()...)
+	Logger().Debug("DeriveHSSKeyPair", "levels", len(levels), "public_key_size", len(publicKeyBytes))
+	return &HSSKeyPair{
+		Levels:     append([]HSSLevel(nil), levels...),
+		PublicKey:  publicKeyBytes,
+		PrivateKey: privateKey.bytes,
+	}, nil
+}
+
+// hssPrivateKey is a parsed HSS private key.
+type hssPrivateKey struct {
+	levels     []HSSLevel
+	identifier []byte
+	seed       []byte
+	bytes      []byte
+}
+
+// marshalHSSPrivateKey encodes an HSS private key.
+func marshalHSSPrivateKey(levels []HSSLevel, identifier []byte, seed []byte) *hssPrivateKey {
+	privateKeyBytes := binary.BigEndian.AppendUint32(nil, uint32(len(levels)))
+	for _, level := range levels {
+		privateKeyBytes = binary.BigEndian.AppendUint32(privateKeyBytes, uint32(level.LMSType))
+		privateKeyBytes = binary.BigEndian.AppendUint32(privateKeyBytes, uint32(level.LMOTSType))
+	}
+	privateKeyBytes = append(privateKeyBytes, identifier...)
+	privateKeyBytes = append(privateKeyBytes, seed...)
+	keyOffset := len(privateKeyBytes) - len(identifier) - len(seed)
+	return &hssPrivateKey{
+		levels:     append([]HSSLevel(nil), levels...),
+		identifier: privateKeyBytes[keyOffset : keyOffset+lmsIdentifierSize],
+		seed:       privateKeyBytes[keyOffset+lmsIdentifierSize:],
+		bytes:      privateKeyBytes,
+	}
+}
+
+// unmarshalHSSPrivateKey parses an HSS private key produced by GenerateHSSKeyPair or DeriveHSSKeyPair.
+func unmarshalHSSPrivateKey(privateKeyBytes []byte) (*hssPrivateKey, error) {
+	if len(privateKeyBytes) < lmsTypeSize {
+		return nil, newSizeError(ErrInvalidKeySize, "HSS private key", len(privateKeyBytes), 0)
+	}
+	levelCount := binary.BigEndian.Uint32(privateKeyBytes)
+	if levelCount < 1 || levelCount > HSSMaxLevels {
+		return nil, fmt.Errorf("unsupported number of HSS levels: %d, want 1 to %d", levelCount, HSSMaxLevels)
+	}
+	keyOffset := lmsTypeSize + int(levelCount)*2*lmsTypeSize
+	if len(privateKeyBytes) < keyOffset {
+		return nil, newSizeError(ErrInvalidKeySize, "HSS private key", len(privateKeyBytes), 0)
+	}
+	levels := make([]HSSLevel, levelCount)
+	for i := range levels {
+		levels[i].LMSType = LMSType(binary.BigEndian.Uint32(privateKeyBytes[lmsTypeSize+i*2*lmsTypeSize:]))
+		levels[i].LMOTSType = LMOTSType(binary.BigEndian.Uint32(privateKeyBytes[2*lmsTypeSize+i*2*lmsTypeSize:]))
+	}
+	n, err := validateHSSLevels(levels)
+	if err != nil {
+		return nil, err
+	}
+	if len(privateKeyBytes) != keyOffset+lmsIdentifierSize+n {
+		return nil, newSizeError(ErrInvalidKeySize, "HSS private key", len(privateKeyBytes), keyOffset+lmsIdentifierSize+n)
+	}
+	return marshalHSSPrivateKey(levels, privateKeyBytes[keyOffset:keyOffset+lmsIdentifierSize], privateKeyBytes[keyOffset+lmsIdentifierSize:]), nil
+}
+
+// HSSSigner signs messages with an HSS private key. Every signature uses the next one-time signature index reserved
+// from its SignatureStateStore, so a crash or a second signer on the same store never reuses a leaf. An HSSSigner is
+// safe for concurrent use.
+//
+// The signer keeps the current tree of every level in memory and computes a lower tree again when the signatures move
+// to its next instance; the public key signatures of the upper levels use a deterministic randomizer, so recomputing
+// them after a restart signs exactly the same message again.
+type HSSSigner struct {
+	mu            sync.Mutex
+	privateKey    *hssPrivateKey
+	store         SignatureStateStore
+	publicKey     []byte
+	maxSignatures uint64
+	trees         []*lmsTree
+	treeInstances []uint64
+	rand          io.Reader
+}
+
+// NewHSSSigner returns a signer for an HSS private key that reserves one-time signature indexes from store. The store
+// is bound to the public key of the private key, and a store that counts for another key is rejected with
+// ErrStateMismatch. The store must outlive every copy of the private key.
+func NewHSSSigner(privateKeyBytes []byte, store SignatureStateStore) (signer *HSSSigner, signerError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			signerError = newPanicError("NewHSSSigner", recoveredPanic)
+		}
+	}()
+	if store == nil {
+		return nil, fmt.Errorf("%w: HSS signer requires a SignatureStateStore", ErrInvalidOptions)
+	}
+	privateKey, unmarshalError := unmarshalHSSPrivateKey(privateKeyBytes)
+	if unmarshalError != nil {
+		return nil, unmarshalError
+	}
+	topTree, treeError := newLMSTree(privateKey.levels[0], privateKey.identifier, privateKey.seed)
+	if treeError != nil {
+		return nil, treeError
+	}
+	totalHeight := 0
+	for _, level := range privateKey.levels {
+		lmsParameters, _, _ := level.parameters()
+		totalHeight += lmsParameters.h
+	}
+	maxSignatures := uint64(math.MaxUint64)
+	if totalHeight < 64 {
+		maxSignatures = uint64(1) << totalHeight
+	}
+	signer = &HSSSigner{
+		privateKey:    privateKey,
+		store:         store,
+		publicKey:     append(binary.BigEndian.AppendUint32(nil, uint32(len(privateKey.levels))), topTree.publicKey()...),
+		maxSignatures: maxSignatures,
+		trees:         make([]*lmsTree, len(privateKey.levels)),
+		treeInstances: make([]uint64, len(privateKey.levels)),
+		rand:          rand.Reader,
+	}
+	if bindError := store.Bind(signer.publicKey); bindError != nil {
+		return nil, bindError
+	}
+	signer.trees[0] = topTree
+	return signer, nil
+}
+
+// PublicKey returns the HSS public key of the signer.
+func (signer *HSSSigner) PublicKey() []byte {
+	return append([]byte(nil), signer.publicKey...)
+}
+
+// MaxSignatures returns the number of one-time signatures of the key, the product of 2^h over its levels.
+func (signer *HSSSigner) MaxSignatures() uint64 {
+	return signer.maxSignatures
+}
+
+// Sign reserves the next one-time signature index and returns the HSS signature of message (RFC 8554 Section 6.2).
+// It returns ErrStateExhausted once every index has been used.
+func (signer *HSSSigner) Sign(messageBytes []byte) (signatureBytes []byte, signError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			signError = newPanicError("HSSSigner.Sign", recoveredPanic)
+		}
+	}()
+	index, reserveError := signer.store.Reserve(1, signer.maxSignatures)
+	if reserveError != nil {
+		return nil, reserveError
+	}
+	signer.mu.Lock()
+	defer signer.mu.Unlock()
+	levels := signer.privateKey.levels
+	// The index splits into one leaf per level, top level first; a tree instance is numbered by the leaves above it.
+	leaves := make([]uint32, len(levels))
+	instances := make([]uint64, len(levels))
+	shift := 0
+	for level := len(levels) - 1; level >= 0; level-- {
+		lmsParameters, _, _ := levels[level].parameters()
+		if shift < 64 {
+			leaves[level] = uint32((index >> shift) & (uint64(1)<<lmsParameters.h - 1))
+		}
+		shift += lmsParameters.h
+		if shift < 64 {
+			instances[level] = index >> shift
+		}
+	}
+	for level := 1; level < len(levels); level++ {
+		if signer.trees[level] != nil && signer.treeInstances[level] == instances[level] {
+			continue
+		}
+		identifier, seed := signer.trees[level-1].child(leaves[level-1])
+		tree, treeError := newLMSTree(levels[level], identifier, seed)
+		if treeError != nil {
+			return nil, treeError
+		}
+		signer.trees[level], signer.treeInstances[level] = tree, instances[level]
+	}
+
+	signatureBytes = binary.BigEndian.AppendUint32(nil, uint32(len(levels)-1))
+	for level := 0; level < len(levels)-1; level++ {
+		tree := signer.trees[level]
+		childPublicKey := signer.trees[level+1].publicKey()
+		randomizer := lmsDerive(tree.lmotsParameters.family, tree.lmotsParameters.n, tree.identifier, leaves[level], lmotsRandomizerIndex, tree.seed)
+		signatureBytes = append(signatureBytes, tree.sign(leaves[level], childPublicKey, randomizer)...)
+		signatureBytes = append(signatureBytes, childPublicKey...)
+	}
+	bottomTree := signer.trees[len(levels)-1]
+	randomizer := make([]byte, bottomTree.lmotsParameters.n)
+	if _, randomError := io.ReadFull(signer.rand, randomizer); randomError != nil {
+		return nil, fmt.Errorf("rand.Read: %w", randomError)
+	}
+	signatureBytes = append(signatureBytes, bottomTree.sign(leaves[len(levels)-1], messageBytes, randomizer)...)
+	Logger().Debug("HSSSign", "levels", len(levels), "index", index, "signature_size", len(signatureBytes))
+	return signatureBytes, nil
+}
+
+// HSSVerify verifies an HSS signature (RFC 8554 Algorithm 8) against an HSS public key. A malformed public key returns
+// an error; a malformed or invalid signature returns false.
+func HSSVerify(publicKeyBytes []byte, messageBytes []byte, signatureBytes []byte) (isValid bool, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			verifyError = newPanicError("HSSVerify", recoveredPanic)
+		}
+	}()
+	if len(publicKeyBytes) < lmsTypeSize {
+		return false, newSizeError(ErrInvalidKeySize, "HSS public key", len(publicKeyBytes), 0)
+	}
+	levelCount := binary.BigEndian.Uint32(publicKeyBytes)
+	if levelCount < 1 || levelCount > HSSMaxLevels {
+		return false, fmt.Errorf("unsupported number of HSS levels: %d, want 1 to %d", levelCount, HSSMaxLevels)
+	}
+	publicKey := publicKeyBytes[lmsTypeSize:]
+	if len(signatureBytes) < lmsTypeSize || binary.BigEndian.Uint32(signatureBytes)+1 != levelCount {
+		return verifyLMSPublicKeyOnly(publicKey)
+	}
+	signature := signatureBytes[lmsTypeSize:]
+	for level := uint32(0); level < levelCount; level++ {
+		signatureSize, ok := lmsSignatureSize(signature)
+		if !ok || len(signature) < signatureSize {
+			return verifyLMSPublicKeyOnly(publicKey)
+		}
+		message := messageBytes
+		var childPublicKey []byte
+		if level+1 < levelCount {
+			childPublicKeySize, ok := lmsPublicKeySize(signature[signatureSize:])
+			if !ok || len(signature) < signatureSize+childPublicKeySize {
+				return verifyLMSPublicKeyOnly(publicKey)
+			}
+			childPublicKey = signature[signatureSize : signatureSize+childPublicKeySize]
+			message = childPublicKey
+		} else if len(signature) != signatureSize {
+			return false, nil
+		}
+		isLevelValid, levelError := verifyLMS(publicKey, message, signature[:signatureSize])
+		if levelError != nil && level == 0 {
+			return false, levelError
+		}
+		if levelError != nil || !isLevelValid {
+			Logger().Debug("HSSVerify", "levels", levelCount, "valid", false)
+			return false, nil
+		}
+		publicKey = childPublicKey
+		signature = signature[signatureSize+len(childPublicKey):]
+	}
+	Logger().Debug("HSSVerify", "levels", levelCount, "valid", true)
+	return true, nil
+}
+
+// verifyLMSPublicKeyOnly reports a malformed signature as invalid, or a malformed public key as an error.
+func verifyLMSPublicKeyOnly(publicKey []byte) (bool, error) {
+	_, err := verifyLMS(publicKey, nil, nil)
+	return false, err
+}
+
+// LMSVerify verifies a single-tree LMS signature (RFC 8554 Algorithm 6a) against an LMS public key, without the HSS
+// level count. A malformed public key returns an error; a malformed or invalid signature returns false.
+func LMSVerify(publicKeyBytes []byte, messageBytes []byte, signatureBytes []byte) (isValid bool, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			verifyError = newPanicError("LMSVerify", recoveredPanic)
+		}
+	}()
+	return verifyLMS(publicKeyBytes, messageBytes, signatureBytes)
+}
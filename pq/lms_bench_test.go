@@ -0,0 +1,51 @@
+package pq
+
+import "testing"
+
+// hssBenchmarkLevels is a two-level key with 2^15 signatures whose bottom trees are cheap to replace.
+var hssBenchmarkLevels = []HSSLevel{
+	{LMSType: LMSSHA256M32H10, LMOTSType: LMOTSSHA256N32W4},
+	{LMSType: LMSSHA256M32H5, LMOTSType: LMOTSSHA256N32W8},
+}
+
+func BenchmarkHSSSign(b *testing.B) {
+	keyPair, err := GenerateHSSKeyPair(hssBenchmarkLevels)
+	if err != nil {
+		b.Fatalf("failed to generate HSS key pair: %v", err)
+	}
+	signer, err := NewHSSSigner(keyPair.PrivateKey, &MemoryStateStore{})
+	if err != nil {
+		b.Fatalf("failed to create HSS signer: %v", err)
+	}
+	message := []byte("benchmark message")
+	b.ResetTimer()
+	for b.Loop() {
+		_, signErr := signer.Sign(message)
+		if signErr != nil {
+			b.Fatalf("signing failed: %v", signErr)
+		}
+	}
+}
+
+func BenchmarkHSSVerify(b *testing.B) {
+	keyPair, err := GenerateHSSKeyPair(hssBenchmarkLevels)
+	if err != nil {
+		b.Fatalf("failed to generate HSS key pair: %v", err)
+	}
+	signer, err := NewHSSSigner(keyPair.PrivateKey, &MemoryStateStore{})
+	if err != nil {
+		b.Fatalf("failed to create HSS signer: %v", err)
+	}
+	message := []byte("benchmark message")
+	signature, signErr := signer.Sign(message)
+	if signErr != nil {
+		b.Fatalf("signing failed: %v", signErr)
+	}
+	b.ResetTimer()
+	for b.Loop() {
+		_, verifyErr := HSSVerify(keyPair.PublicKey, message, signature)
+		if verifyErr != nil {
+			b.Fatalf("verifying failed: %v", verifyErr)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package pq
+
+import "testing"
+
+func FuzzHSSVerifyArbitrarySignature(f *testing.F) {
+	keyPair, _ := GenerateHSSKeyPair([]HSSLevel{{LMSType: LMSSHA256M32H5, LMOTSType: LMOTSSHA256N32W8}})
+	signer, _ := NewHSSSigner(keyPair.PrivateKey, &MemoryStateStore{})
+	validSignature, _ := signer.Sign([]byte("msg"))
+	f.Add([]byte("msg"), validSignature)
+	f.Add([]byte("msg"), []byte{0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 4})
+	f.Fuzz(func(t *testing.T, msg []byte, signature []byte) {
+		isValid, verifyErr := HSSVerify(keyPair.PublicKey, msg, signature)
+		if verifyErr != nil {
+			t.Fatalf("verifying must not error on a malformed signature: %v", verifyErr)
+		}
+		if isValid && (string(msg) != "msg" || string(signature) != string(validSignature)) {
+			t.Error("only the valid signature may verify")
+		}
+	})
+}
+
+func FuzzHSSVerifyArbitraryPublicKey(f *testing.F) {
+	f.Add([]byte{0, 0, 0, 1, 0, 0, 0, 5, 0, 0, 0, 4}, []byte("sig"))
+	f.Fuzz(func(t *testing.T, publicKey []byte, signature []byte) {
+		isValid, _ := HSSVerify(publicKey, []byte("msg"), signature)
+		if isValid {
+			t.Error("an arbitrary signature must not verify")
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package pq
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rfc8554TestCase2 holds the private and public key values of RFC 8554 Appendix F Test Case 2 in testdata/hbs.
+type rfc8554TestCase2 struct {
+	Levels          [][2]uint32 `json:"levels"`
+	Identifier      string      `json:"identifier"`
+	Seed            string      `json:"seed"`
+	PublicKey       string      `json:"publicKey"`
+	ChildLeaf       uint32      `json:"childLeaf"`
+	ChildIdentifier string      `json:"childIdentifier"`
+	ChildSeed       string      `json:"childSeed"`
+}
+
+// readHBSVectors reads the JSON vector file name in testdata/hbs into vectors.
+func readHBSVectors(t *testing.T, name string, vectors any) {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "hbs", name))
+	require.NoError(t, err, "failed to read vectors")
+	require.NoError(t, json.Unmarshal(data, vectors))
+}
+
+// For TestHSSRFC8554TestCase2: the top-level private key of RFC 8554 Appendix F Test Case 2 gives its HSS public key,
+// leaf 3 derives the published second-level private key, and the signatures of that tree carry its public key.
+func TestHSSRFC8554TestCase2(t *testing.T) {
+	var vector rfc8554TestCase2
+	readHBSVectors(t, "rfc8554-test-case-2.json", &vector)
+	levels := make([]HSSLevel, len(vector.Levels))
+	for i, level := range vector.Levels {
+		levels[i] = HSSLevel{LMSType: LMSType(level[0]), LMOTSType: LMOTSType(level[1])}
+	}
+	keyPair, err := DeriveHSSKeyPair(levels, mustDecodeHex(t, vector.Identifier+vector.Seed))
+	require.NoError(t, err)
+	require.Equal(t, mustDecodeHex(t, vector.PublicKey), keyPair.PublicKey)
+
+	signer, err := NewHSSSigner(keyPair.PrivateKey, &MemoryStateStore{})
+	require.NoError(t, err)
+	childIdentifier, childSeed := signer.trees[0].child(vector.ChildLeaf)
+	require.Equal(t, mustDecodeHex(t, vector.ChildIdentifier), childIdentifier)
+	require.Equal(t, mustDecodeHex(t, vector.ChildSeed), childSeed)
+
+	childTree, err := newLMSTree(levels[1], childIdentifier, childSeed)
+	require.NoError(t, err)
+	lmsParameters, _, err := levels[1].parameters()
+	require.NoError(t, err)
+	signer.store = &MemoryStateStore{next: uint64(vector.ChildLeaf) << lmsParameters.h}
+	message := []byte("The enumeration in the Constitution, of certain rights, shall not be construed to deny or disparage others retained by the people.\n")
+	signature, err := signer.Sign(message)
+	require.NoError(t, err)
+	require.True(t, bytes.Contains(signature, childTree.publicKey()), "the signature must carry the second-level public key")
+	valid, err := HSSVerify(keyPair.PublicKey, message, signature)
+	require.NoError(t, err)
+	require.True(t, valid)
+	signature[len(signature)-1] ^= 0x01
+	valid, err = HSSVerify(keyPair.PublicKey, message, signature)
+	require.NoError(t, err)
+	require.False(t, valid, "a signature with a flipped bit must be rejected")
+}
+
+// For TestLMOTSParameters: p and ls match the tables of RFC 8554 Section 4.1 and NIST SP 800-208 Section 4.1.
+func TestLMOTSParameters(t *testing.T) {
+	expected := map[LMOTSType][2]int{
+		LMOTSSHA256N32W1: {265, 7}, LMOTSSHA256N32W2: {133, 6}, LMOTSSHA256N32W4: {67, 4}, LMOTSSHA256N32W8: {34, 0},
+		LMOTSSHA256N24W1: {200, 8}, LMOTSSHA256N24W2: {101, 6}, LMOTSSHA256N24W4: {51, 4}, LMOTSSHA256N24W8: {26, 0},
+		LMOTSSHAKEN32W1: {265, 7}, LMOTSSHAKEN24W8: {26, 0},
+	}
+	for otsType, pAndLs := range expected {
+		parameters, err := otsType.parameters()
+		require.NoError(t, err)
+		require.Equal(t, pAndLs, [2]int{parameters.p, parameters.ls}, otsType.String())
+	}
+	require.Equal(t, "LMOTS_SHA256_N32_W8", LMOTSSHA256N32W8.String())
+	require.Equal(t, "LMS_SHAKE_M24_H25", LMSSHAKEM24H25.String())
+	require.Equal(t, "LMSType(99)", LMSType(99).String())
+	require.Equal(t, "LMOTSType(0)", LMOTSType(0).String())
+}
+
+// For TestHSSSignAndVerify: signatures of a two-level key verify across a change of the bottom tree, and tampered
+// signatures, other messages and other keys are rejected.
+func TestHSSSignAndVerify(t *testing.T) {
+	levels := []HSSLevel{
+		{LMSType: LMSSHA256M24H5, LMOTSType: LMOTSSHA256N24W8},
+		{LMSType: LMSSHA256M24H5, LMOTSType: LMOTSSHA256N24W8},
+	}
+	keyPair, err := GenerateHSSKeyPair(levels)
+	require.NoError(t, err)
+	signer, err := NewHSSSigner(keyPair.PrivateKey, &MemoryStateStore{next: 30})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1024), signer.MaxSignatures())
+	require.Equal(t, keyPair.PublicKey, signer.PublicKey())
+	otherKeyPair, err := GenerateHSSKeyPair(levels)
+	require.NoError(t, err)
+
+	message := []byte("firmware image")
+	for range 4 { // leaves 30 to 33 cross from the first to the second bottom tree
+		signature, signErr := signer.Sign(message)
+		require.NoError(t, signErr)
+		valid, verifyErr := HSSVerify(keyPair.PublicKey, message, signature)
+		require.NoError(t, verifyErr)
+		require.True(t, valid)
+
+		valid, verifyErr = HSSVerify(keyPair.PublicKey, []byte("other image"), signature)
+		require.NoError(t, verifyErr)
+		require.False(t, valid)
+		valid, verifyErr = HSSVerify(otherKeyPair.PublicKey, message, signature)
+		require.NoError(t, verifyErr)
+		require.False(t, valid)
+		for _, offset := range []int{3, 10, len(signature) / 2, len(signature) - 1} {
+			tampered := bytes.Clone(signature)
+			tampered[offset] ^= 0x01
+			valid, verifyErr = HSSVerify(keyPair.PublicKey, message, tampered)
+			require.NoError(t, verifyErr, "a malformed signature is invalid, not an error")
+			require.False(t, valid, "tampered byte %d", offset)
+		}
+		valid, verifyErr = HSSVerify(keyPair.PublicKey, message, signature[:len(signature)-1])
+		require.NoError(t, verifyErr)
+		require.False(t, valid)
+	}
+}
+
+// For TestHSSStateExhaustion: a single-level key of height 5 makes exactly 32 signatures.
+func TestHSSStateExhaustion(t *testing.T) {
+	keyPair, err := GenerateHSSKeyPair([]HSSLevel{{LMSType: LMSSHAKEM32H5, LMOTSType: LMOTSSHAKEN32W8}})
+	require.NoError(t, err)
+	signer, err := NewHSSSigner(keyPair.PrivateKey, &MemoryStateStore{next: 30})
+	require.NoError(t, err)
+	for range 2 {
+		_, err = signer.Sign([]byte("message"))
+		require.NoError(t, err)
+	}
+	_, err = signer.Sign([]byte("message"))
+	require.ErrorIs(t, err, ErrStateExhausted)
+}
+
+// For TestHSSInvalidInput: invalid level combinations, seeds, keys and stores are rejected.
+func TestHSSInvalidInput(t *testing.T) {
+	valid := HSSLevel{LMSType: LMSSHA256M32H5, LMOTSType: LMOTSSHA256N32W8}
+	_, err := GenerateHSSKeyPair(nil)
+	require.Error(t, err)
+	_, err = GenerateHSSKeyPair(make([]HSSLevel, HSSMaxLevels+1))
+	require.Error(t, err)
+	_, err = GenerateHSSKeyPair([]HSSLevel{{LMSType: LMSSHA256M32H5, LMOTSType: LMOTSSHA256N24W8}})
+	require.Error(t, err, "SP 800-208 forbids mixing output sizes within a level")
+	_, err = GenerateHSSKeyPair([]HSSLevel{valid, {LMSType: LMSSHAKEM32H5, LMOTSType: LMOTSSHAKEN32W8}})
+	require.Error(t, err, "levels must share the hash function")
+	_, err = DeriveHSSKeyPair([]HSSLevel{valid}, make([]byte, 16))
+	require.ErrorIs(t, err, ErrInvalidSeedSize)
+
+	keyPair, err := GenerateHSSKeyPair([]HSSLevel{valid})
+	require.NoError(t, err)
+	_, err = NewHSSSigner(keyPair.PrivateKey, nil)
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = NewHSSSigner(keyPair.PrivateKey[:len(keyPair.PrivateKey)-1], &MemoryStateStore{})
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	_, err = HSSVerify(keyPair.PublicKey[:len(keyPair.PublicKey)-1], []byte("message"), nil)
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	_, err = HSSVerify([]byte{0, 0, 0, 9}, []byte("message"), nil)
+	require.Error(t, err)
+	_, err = LMSVerify([]byte{0, 0, 0, 5, 0, 0, 0, 7}, []byte("message"), nil)
+	require.Error(t, err)
+}
+
+// For TestHSSTreeHeightLimit: key generation and signing reject trees higher than LMSMaxTreeHeight at any level.
+func TestHSSTreeHeightLimit(t *testing.T) {
+	low := HSSLevel{LMSType: LMSSHA256M32H5, LMOTSType: LMOTSSHA256N32W8}
+	for _, levels := range [][]HSSLevel{
+		{{LMSType: LMSSHA256M32H20, LMOTSType: LMOTSSHA256N32W8}},
+		{{LMSType: LMSSHAKEM24H25, LMOTSType: LMOTSSHAKEN24W4}},
+		{low, {LMSType: LMSSHA256M32H20, LMOTSType: LMOTSSHA256N32W8}},
+	} {
+		_, err := GenerateHSSKeyPair(levels)
+		require.ErrorIs(t, err, ErrUnsupportedTreeHeight, "%v", levels)
+	}
+
+	privateKey := marshalHSSPrivateKey([]HSSLevel{{LMSType: LMSSHA256M32H25, LMOTSType: LMOTSSHA256N32W8}}, make([]byte, 16), make([]byte, 32))
+	_, err := NewHSSSigner(privateKey.bytes, &MemoryStateStore{})
+	require.ErrorIs(t, err, ErrUnsupportedTreeHeight)
+
+	keyPair, err := DeriveHSSKeyPair([]HSSLevel{{LMSType: LMSSHA256M32H15, LMOTSType: LMOTSSHA256N32W1}}, make([]byte, 48))
+	require.NoError(t, err, "LMSMaxTreeHeight itself is supported")
+	require.Len(t, keyPair.PublicKey, 4+8+16+32)
+}
@@ -0,0 +1,176 @@
+package pq
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+)
+
+// packageLogger is the logger set with SetLogger, always wrapped in a redacting handler; nil means silent.
+var packageLogger atomic.Pointer[slog.Logger]
+
+// discardLogger is used when no logger has been configured.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// SetLogger sets the package-wide logger used by every function in this package, replacing the previous one.
+// The logger is wrapped with NewRedactingHandler, so key material, seeds and shared secrets are never emitted.
+// A nil logger restores the default, which is silent.
+//
+// Routine operations log at slog.LevelDebug with sizes only; rejected inputs log at slog.LevelWarn and recovered
+// panics at slog.LevelError, including the stack trace.
+func SetLogger(logger *slog.Logger) {
+	if logger == nil {
+		packageLogger.Store(nil)
+		return
+	}
+	packageLogger.Store(redactingLogger(logger))
+}
+
+// Logger returns the package-wide logger set with SetLogger, or a logger that discards everything.
+func Logger() *slog.Logger {
+	if logger := packageLogger.Load(); logger != nil {
+		return logger
+	}
+	return discardLogger
+}
+
+// loggerOr returns logger, wrapped with a redacting handler, for a per-call override, or the package-wide logger if logger is nil.
+func loggerOr(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return Logger()
+	}
+	return redactingLogger(logger)
+}
+
+// redactingLogger wraps the handler of logger with a redactingHandler unless it already has one.
+func redactingLogger(logger *slog.Logger) *slog.Logger {
+	if _, alreadyRedacting := logger.Handler().(*redactingHandler); alreadyRedacting {
+		return logger
+	}
+	return slog.New(NewRedactingHandler(logger.Handler()))
+}
+
+// logPanic logs a recovered panic with its stack trace at slog.LevelError.
+func logPanic(logger *slog.Logger, function string, recoveredPanic any, stack []byte) {
+	logger.Error("panic in "+function, slog.Any("panic", recoveredPanic), slog.String("stack", string(stack)))
+}
+
+// sensitiveAttributeKeys are substrings of attribute keys whose string values are always redacted.
+var sensitiveAttributeKeys = []string{"private", "secret", "seed", "password", "passphrase", "token"}
+
+// redactingHandler is a slog.Handler that scrubs attribute values which may carry key material before passing records on.
+type redactingHandler struct {
+	next slog.Handler
+}
+
+// NewRedactingHandler returns a slog.Handler that passes records to next after redacting their attributes:
+//   - byte slices and any other non-scalar value are replaced by "[REDACTED <type>]", except errors, which are logged by message;
+//   - string values whose key contains "private", "secret", "seed", "password", "passphrase" or "token" are replaced by "[REDACTED]";
+//   - numbers, booleans, durations and times are passed through, so sizes and flags remain visible.
+//
+// slog.LogValuer values are resolved before redaction, and groups are redacted recursively.
+func NewRedactingHandler(next slog.Handler) slog.Handler {
+	if handler, alreadyRedacting := next.(*redactingHandler); alreadyRedacting {
+		return handler
+	}
+	return &redactingHandler{next: next}
+}
+
+// Enabled reports whether the wrapped handler handles records at the given level.
+func (handler *redactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return handler.next.Enabled(ctx, level)
+}
+
+// Handle redacts the attributes of record and passes it to the wrapped handler.
+func (handler *redactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+	return handler.next.Handle(ctx, redacted)
+}
+
+// WithAttrs redacts attrs and returns a handler that adds them to every record.
+func (handler *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = redactAttr(attr)
+	}
+	return &redactingHandler{next: handler.next.WithAttrs(redacted)}
+}
+
+// WithGroup returns a handler that nests subsequent attributes in the named group.
+func (handler *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{next: handler.next.WithGroup(name)}
+}
+
+// redactAttr returns attr with its value redacted according to the NewRedactingHandler rules.
+func redactAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, member := range group {
+			redacted[i] = redactAttr(member)
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindString:
+		if isSensitiveAttributeKey(attr.Key) {
+			return slog.String(attr.Key, "[REDACTED]")
+		}
+		return slog.Attr{Key: attr.Key, Value: value}
+	case slog.KindAny:
+		if err, isError := value.Any().(error); isError && !isSensitiveAttributeKey(attr.Key) {
+			return slog.String(attr.Key, err.Error())
+		}
+		return slog.String(attr.Key, fmt.Sprintf("[REDACTED %T]", value.Any()))
+	default:
+		return slog.Attr{Key: attr.Key, Value: value}
+	}
+}
+
+// isSensitiveAttributeKey reports whether an attribute key names something that may hold secret material.
+func isSensitiveAttributeKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveAttributeKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
+
+// LogValue implements slog.LogValuer so that logging a key pair with any logger shows only its parameter set and sizes.
+func (keyPair *MLDSAKeyPair) LogValue() slog.Value {
+	if keyPair == nil {
+		return slog.StringValue("<nil>")
+	}
+	return slog.GroupValue(
+		slog.String("parameter_set", keyPair.ParameterSet.String()),
+		slog.Int("public_key_size", len(keyPair.PublicKey)),
+		slog.Bool("has_seed", keyPair.Seed != nil),
+	)
+}
+
+// LogValue implements slog.LogValuer so that logging a key pair with any logger shows only its parameter set.
+func (keyPair *MLKEMKeyPair) LogValue() slog.Value {
+	if keyPair == nil {
+		return slog.StringValue("<nil>")
+	}
+	return slog.GroupValue(
+		slog.String("parameter_set", keyPair.ParameterSet.String()),
+		slog.Bool("has_seed", keyPair.Seed != nil),
+	)
+}
+
+// LogValue implements slog.LogValuer so that logging the key with any logger shows only its parameter set.
+func (privateKey *MLDSAPrivateKey) LogValue() slog.Value {
+	if privateKey == nil {
+		return slog.StringValue("<nil>")
+	}
+	return slog.GroupValue(slog.String("parameter_set", privateKey.parameterSet.String()), slog.Bool("has_seed", privateKey.seed != nil))
+}
@@ -0,0 +1,128 @@
+package pq
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// captureOutput returns everything written to stdout and stderr while run executes.
+func captureOutput(t *testing.T, run func()) string {
+	t.Helper()
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = writer, writer
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+	run()
+	require.NoError(t, writer.Close())
+	output, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	return string(output)
+}
+
+// requireNoKeyMaterial fails if output contains any of the secrets in hex or base64.
+func requireNoKeyMaterial(t *testing.T, output string, secrets ...[]byte) {
+	t.Helper()
+	for _, secret := range secrets {
+		probe := secret[:16]
+		require.NotContains(t, output, hex.EncodeToString(probe))
+		require.NotContains(t, strings.ToLower(output), hex.EncodeToString(probe))
+		require.NotContains(t, output, base64.StdEncoding.EncodeToString(probe)[:20])
+	}
+}
+
+// For TestSilentByDefault: without SetLogger nothing is written to stdout or stderr, even for failures and panics.
+func TestSilentByDefault(t *testing.T) {
+	SetLogger(nil)
+	output := captureOutput(t, func() {
+		keyPair, _ := GenerateMLDSAKeyPair(MLDSA44)
+		signature, _ := MLDSASign(MLDSA44, keyPair.PrivateKey, []byte("message"))
+		_, _ = MLDSAVerify(MLDSA44, keyPair.PublicKey, []byte("message"), signature)
+		_, _ = MLDSAVerify(MLDSA44, []byte("short"), []byte("message"), signature)
+		kemKeyPair, _ := GenerateMLKEMKeyPair(MLKEM768)
+		ciphertext, _, _ := MLKEMEncapsulate(kemKeyPair.PublicKey)
+		_, _ = MLKEMDecapsulate(kemKeyPair.PrivateKey, ciphertext)
+		_, _ = UnmarshalPublicKey(MLKEM768, []byte("short"))
+	})
+	require.Empty(t, output)
+	require.False(t, Logger().Enabled(context.Background(), slog.LevelError))
+}
+
+// For TestSetLoggerRedactsKeyMaterial: a configured logger receives events, but never keys, seeds or shared secrets.
+func TestSetLoggerRedactsKeyMaterial(t *testing.T) {
+	var buffer bytes.Buffer
+	SetLogger(slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer SetLogger(nil)
+
+	keyPair, err := GenerateMLDSAKeyPair(MLDSA65)
+	require.NoError(t, err)
+	signature, err := MLDSASign(MLDSA65, keyPair.PrivateKey, []byte("message"))
+	require.NoError(t, err)
+	_, err = MLDSAVerify(MLDSA65, keyPair.PublicKey, []byte("message"), signature)
+	require.NoError(t, err)
+	kemKeyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	require.NoError(t, err)
+	_, sharedSecret, err := MLKEMEncapsulate(kemKeyPair.PublicKey)
+	require.NoError(t, err)
+	privateKeyBytes, err := MarshalPrivateKey(kemKeyPair.PrivateKey)
+	require.NoError(t, err)
+
+	Logger().Info("caller event", "key_pair", keyPair, "kem_key_pair", kemKeyPair, "raw", keyPair.PrivateKey, "seed", "00112233")
+
+	output := buffer.String()
+	require.Contains(t, output, `"msg":"GenerateMLDSAKeyPair"`)
+	require.Contains(t, output, `"msg":"MLDSASign"`)
+	require.Contains(t, output, `"valid":true`)
+	require.Contains(t, output, `"raw":"[REDACTED []uint8]"`)
+	require.Contains(t, output, `"seed":"[REDACTED]"`)
+	requireNoKeyMaterial(t, output, keyPair.PrivateKey, keyPair.Seed, kemKeyPair.Seed, privateKeyBytes, sharedSecret)
+}
+
+// For TestMLDSASignOptionsLogger: a per-call logger receives the events of that call only.
+func TestMLDSASignOptionsLogger(t *testing.T) {
+	SetLogger(nil)
+	var buffer bytes.Buffer
+	callLogger := slog.New(slog.NewTextHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	keyPair, err := GenerateMLDSAKeyPair(MLDSA44)
+	require.NoError(t, err)
+	require.Zero(t, buffer.Len())
+	_, err = MLDSASignWithOptions(MLDSA44, keyPair.PrivateKey, []byte("message"), &MLDSASignOptions{Logger: callLogger})
+	require.NoError(t, err)
+	require.Contains(t, buffer.String(), "msg=MLDSASign")
+	requireNoKeyMaterial(t, buffer.String(), keyPair.PrivateKey)
+}
+
+// For TestRedactingHandler: byte slices, sensitive strings and opaque values are redacted in records, WithAttrs and groups.
+func TestRedactingHandler(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := slog.New(NewRedactingHandler(slog.NewJSONHandler(&buffer, nil)))
+	require.Same(t, logger.Handler(), NewRedactingHandler(logger.Handler()), "wrapping must be idempotent")
+
+	secret := bytes.Repeat([]byte{0xAB}, 32)
+	logger.With("shared_secret", hex.EncodeToString(secret)).WithGroup("op").Info("event",
+		"size", 32,
+		"ok", true,
+		"name", "ML-KEM-768",
+		"error", errors.New("invalid size"),
+		slog.Group("nested", "private_key", "deadbeef", "bytes", secret),
+		"any", struct{ Secret []byte }{secret},
+	)
+	output := buffer.String()
+	require.Contains(t, output, `"size":32`)
+	require.Contains(t, output, `"ok":true`)
+	require.Contains(t, output, `"name":"ML-KEM-768"`)
+	require.Contains(t, output, `"error":"invalid size"`)
+	require.Contains(t, output, `"private_key":"[REDACTED]"`)
+	require.NotContains(t, output, "deadbeef")
+	requireNoKeyMaterial(t, output, secret)
+}
@@ -2,31 +2,137 @@
 package pq
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/rand"
+	"encoding/asn1"
 	"fmt"
-	"runtime/debug"
+	"io"
+	"log/slog"
 
+	"gopq/pq/internal/mldsa"
+
+	"github.com/cloudflare/circl/sign"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa44"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
 	"github.com/cloudflare/circl/sign/mldsa/mldsa87"
 )
 
-// MLDSAKeyPair represents a key pair for ML-DSA.
+// MLDSAParameterSet identifies an ML-DSA parameter set from FIPS 204.
+type MLDSAParameterSet int
+
+const (
+	// MLDSA44 is the FIPS 204 ML-DSA-44 parameter set (NIST security category 2).
+	MLDSA44 MLDSAParameterSet = iota + 1
+	// MLDSA65 is the FIPS 204 ML-DSA-65 parameter set (NIST security category 3).
+	MLDSA65
+	// MLDSA87 is the FIPS 204 ML-DSA-87 parameter set (NIST security category 5).
+	MLDSA87
+)
+
+const (
+	// MLDSASeedSize is the size in bytes of the seed (xi) used for deterministic key generation, for all parameter sets.
+	MLDSASeedSize = 32
+	// MLDSAMaxContextSize is the maximum size in bytes of a FIPS 204 context string (ctx).
+	MLDSAMaxContextSize = 255
+)
+
+// MLDSAParameterSets lists the FIPS 204 ML-DSA parameter sets.
+var MLDSAParameterSets = []MLDSAParameterSet{MLDSA44, MLDSA65, MLDSA87}
+
+// String returns the name of the parameter set, for example "ML-DSA-65".
+func (parameterSet MLDSAParameterSet) String() string {
+	switch parameterSet {
+	case MLDSA44:
+		return "ML-DSA-44"
+	case MLDSA65:
+		return "ML-DSA-65"
+	case MLDSA87:
+		return "ML-DSA-87"
+	default:
+		return fmt.Sprintf("MLDSAParameterSet(%d)", int(parameterSet))
+	}
+}
+
+// scheme returns the CIRCL signature scheme implementing the parameter set.
+func (parameterSet MLDSAParameterSet) scheme() (sign.Scheme, error) {
+	switch parameterSet {
+	case MLDSA44:
+		return mldsa44.Scheme(), nil
+	case MLDSA65:
+		return mldsa65.Scheme(), nil
+	case MLDSA87:
+		return mldsa87.Scheme(), nil
+	default:
+		return nil, fmt.Errorf("%w: ML-DSA parameter set %v", ErrUnknownAlgorithm, parameterSet)
+	}
+}
+
+// OID returns the NIST object identifier of the parameter set (2.16.840.1.101.3.4.3.17-19), used in SubjectPublicKeyInfo and PKCS#8.
+func (parameterSet MLDSAParameterSet) OID() (asn1.ObjectIdentifier, error) {
+	switch parameterSet {
+	case MLDSA44:
+		return asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 17}, nil
+	case MLDSA65:
+		return asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 18}, nil
+	case MLDSA87:
+		return asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 19}, nil
+	default:
+		return nil, fmt.Errorf("%w: ML-DSA parameter set %v", ErrUnknownAlgorithm, parameterSet)
+	}
+}
+
+// internalParameters returns the parameters of the internal FIPS 204 signing core. Hedged and deterministic signing of a
+// whole message use CIRCL; the core is only used where CIRCL has no entry point: caller-supplied randomness (CIRCL's
+// SignTo reads rnd from crypto/rand itself), an external message representative mu, and HashML-DSA.
+func (parameterSet MLDSAParameterSet) internalParameters() (*mldsa.Parameters, error) {
+	switch parameterSet {
+	case MLDSA44:
+		return mldsa.MLDSA44, nil
+	case MLDSA65:
+		return mldsa.MLDSA65, nil
+	case MLDSA87:
+		return mldsa.MLDSA87, nil
+	default:
+		return nil, fmt.Errorf("%w: ML-DSA parameter set %v", ErrUnknownAlgorithm, parameterSet)
+	}
+}
+
+// MLDSAKeyPair represents a key pair for ML-DSA, and the parameter set it belongs to.
+// Seed is the 32-byte seed (xi) the key pair was derived from, or nil if only the expanded private key is known.
 type MLDSAKeyPair struct {
-	PublicKey  []byte
-	PrivateKey []byte
+	ParameterSet MLDSAParameterSet
+	PublicKey    []byte
+	PrivateKey   []byte
+	Seed         []byte
+}
+
+// GenerateMLDSAKeyPair generates a new ML-DSA key pair for the given parameter set from a fresh random seed.
+func GenerateMLDSAKeyPair(parameterSet MLDSAParameterSet) (keyPair *MLDSAKeyPair, keyGenerationError error) {
+	var seed [MLDSASeedSize]byte
+	if _, randomError := rand.Read(seed[:]); randomError != nil {
+		return nil, fmt.Errorf("rand.Read: %w", randomError)
+	}
+	keyPair, keyGenerationError = DeriveMLDSAKeyPair(parameterSet, &seed)
+	if keyGenerationError != nil {
+		return nil, keyGenerationError
+	}
+	Logger().Debug("GenerateMLDSAKeyPair", "parameter_set", parameterSet.String(), "public_key_size", len(keyPair.PublicKey), "private_key_size", len(keyPair.PrivateKey))
+	return keyPair, nil
 }
 
-// GenerateMLDSAKeyPair generates a new ML-DSA key pair using CIRCL ML-DSA-87.
-func GenerateMLDSAKeyPair() (keyPair *MLDSAKeyPair, keyGenerationError error) {
+// DeriveMLDSAKeyPair deterministically derives a new ML-DSA key pair for the given parameter set using CIRCL with seed size 32-bytes.
+func DeriveMLDSAKeyPair(parameterSet MLDSAParameterSet, seed *[MLDSASeedSize]byte) (keyPair *MLDSAKeyPair, keyGenerationError error) {
 	defer func() {
 		if recoveredPanic := recover(); recoveredPanic != nil {
-			keyGenerationError = fmt.Errorf("panic in GenerateMLDSAKeyPair: %v\n%s", recoveredPanic, debug.Stack())
+			keyGenerationError = newPanicError("DeriveMLDSAKeyPair", recoveredPanic)
 		}
 	}()
-	publicKey, privateKey, keyGenerationError := mldsa87.GenerateKey(rand.Reader)
-	if keyGenerationError != nil {
-		return nil, fmt.Errorf("mldsa87.GenerateKey: %w", keyGenerationError)
+	scheme, schemeError := parameterSet.scheme()
+	if schemeError != nil {
+		return nil, schemeError
 	}
+	publicKey, privateKey := scheme.DeriveKey(seed[:])
 	publicKeyBytes, publicKeyMarshalError := publicKey.MarshalBinary()
 	if publicKeyMarshalError != nil {
 		return nil, fmt.Errorf("publicKey.MarshalBinary: %w", publicKeyMarshalError)
@@ -35,76 +141,166 @@ func GenerateMLDSAKeyPair() (keyPair *MLDSAKeyPair, keyGenerationError error) {
 	if privateKeyMarshalError != nil {
 		return nil, fmt.Errorf("privateKey.MarshalBinary: %w", privateKeyMarshalError)
 	}
-	fmt.Printf("GenerateMLDSAKeyPair: publicKeyBytes len=%d, privateKeyBytes len=%d\n", len(publicKeyBytes), len(privateKeyBytes))
+	Logger().Debug("DeriveMLDSAKeyPair", "parameter_set", parameterSet.String(), "public_key_size", len(publicKeyBytes), "private_key_size", len(privateKeyBytes))
 	keyPair = &MLDSAKeyPair{
-		PublicKey:  publicKeyBytes,
-		PrivateKey: privateKeyBytes,
+		ParameterSet: parameterSet,
+		PublicKey:    publicKeyBytes,
+		PrivateKey:   privateKeyBytes,
+		Seed:         append([]byte(nil), seed[:]...),
 	}
 	return keyPair, nil
 }
 
-// DeriveMLDSAKeyPair deterministically derives a new ML-DSA key pair using CIRCL ML-DSA-87 with seed size 32-bytes.
-func DeriveMLDSAKeyPair(seed *[mldsa87.SeedSize]byte) (keyPair *MLDSAKeyPair, keyGenerationError error) {
-	defer func() {
-		if recoveredPanic := recover(); recoveredPanic != nil {
-			keyGenerationError = fmt.Errorf("panic in DeriveMLDSAKeyPair: %v\n%s", recoveredPanic, debug.Stack())
+// MLDSASignOptions selects how an ML-DSA signature is made. The zero value (and a nil *MLDSASignOptions) selects
+// hedged signing with crypto/rand and an empty context string.
+type MLDSASignOptions struct {
+	// Context is the FIPS 204 context string (ctx), at most MLDSAMaxContextSize bytes; nil and empty are equivalent.
+	Context []byte
+	// Deterministic selects the FIPS 204 deterministic variant (rnd is all zeroes), so signing the same message with the
+	// same key always gives the same signature, as needed for reproducible test fixtures.
+	// It cannot be combined with Rand.
+	Deterministic bool
+	// Rand is the source of the 32 bytes of per-signature randomness (rnd) for hedged signing, for example a DRBG.
+	// If nil, crypto/rand is used.
+	Rand io.Reader
+	// Logger overrides the package-wide logger (see SetLogger) for this call; it is wrapped with NewRedactingHandler.
+	Logger *slog.Logger
+}
+
+// HashFunc returns zero, so that *MLDSASignOptions can be passed as crypto.SignerOpts to select pure ML-DSA.
+func (options *MLDSASignOptions) HashFunc() crypto.Hash {
+	return 0
+}
+
+// context returns the context string of the options, or nil for nil options.
+func (options *MLDSASignOptions) context() []byte {
+	if options == nil {
+		return nil
+	}
+	return options.Context
+}
+
+// logger returns the logger selected by the options, or the package-wide logger.
+func (options *MLDSASignOptions) logger() *slog.Logger {
+	if options == nil {
+		return Logger()
+	}
+	return loggerOr(options.Logger)
+}
+
+// randomness returns the per-signature randomness rnd selected by the options.
+func (options *MLDSASignOptions) randomness() (*[mldsa.RandomSize]byte, error) {
+	var rnd [mldsa.RandomSize]byte
+	if options != nil && options.Deterministic {
+		if options.Rand != nil {
+			return nil, fmt.Errorf("%w: ML-DSA Deterministic and Rand are mutually exclusive", ErrInvalidOptions)
 		}
-	}()
-	publicKey, privateKey := mldsa87.NewKeyFromSeed(seed)
-	publicKeyBytes, publicKeyMarshalError := publicKey.MarshalBinary()
-	if publicKeyMarshalError != nil {
-		return nil, fmt.Errorf("publicKey.MarshalBinary: %w", publicKeyMarshalError)
+		return &rnd, nil
 	}
-	privateKeyBytes, privateKeyMarshalError := privateKey.MarshalBinary()
-	if privateKeyMarshalError != nil {
-		return nil, fmt.Errorf("privateKey.MarshalBinary: %w", privateKeyMarshalError)
+	randomReader := rand.Reader
+	if options != nil && options.Rand != nil {
+		randomReader = options.Rand
 	}
-	fmt.Printf("DeriveMLDSAKeyPair: publicKeyBytes len=%d, privateKeyBytes len=%d\n", len(publicKeyBytes), len(privateKeyBytes))
-	keyPair = &MLDSAKeyPair{
-		PublicKey:  publicKeyBytes,
-		PrivateKey: privateKeyBytes,
+	if _, randomError := io.ReadFull(randomReader, rnd[:]); randomError != nil {
+		return nil, fmt.Errorf("reading ML-DSA signing randomness: %w", randomError)
 	}
-	return keyPair, nil
+	return &rnd, nil
+}
+
+// MLDSASign signs a message using an ML-DSA private key of the given parameter set, with hedged randomness and an empty context string.
+// A private key of a different parameter set is rejected.
+func MLDSASign(parameterSet MLDSAParameterSet, privateKeyBytes []byte, messageBytes []byte) (signatureBytes []byte, signError error) {
+	return MLDSASignWithOptions(parameterSet, privateKeyBytes, messageBytes, nil)
 }
 
-// MLDSASign signs a message using the ML-DSA private key (CIRCL ML-DSA-87).
-func MLDSASign(privateKeyBytes []byte, messageBytes []byte) (signatureBytes []byte, signError error) {
+// MLDSASignWithContext signs a message using an ML-DSA private key of the given parameter set, with hedged randomness, bound to a context string.
+// The context string (FIPS 204 ctx) provides domain separation; a signature made under one context does not verify under any other.
+// The context must be at most MLDSAMaxContextSize bytes; nil and empty are equivalent.
+func MLDSASignWithContext(parameterSet MLDSAParameterSet, privateKeyBytes []byte, messageBytes []byte, contextBytes []byte) (signatureBytes []byte, signError error) {
+	return MLDSASignWithOptions(parameterSet, privateKeyBytes, messageBytes, &MLDSASignOptions{Context: contextBytes})
+}
+
+// MLDSASignWithOptions signs a message using an ML-DSA private key of the given parameter set (FIPS 204 Algorithm 2).
+// The options select the context string and hedged (the default), deterministic or caller-supplied signing randomness; nil selects the defaults.
+func MLDSASignWithOptions(parameterSet MLDSAParameterSet, privateKeyBytes []byte, messageBytes []byte, options *MLDSASignOptions) (signatureBytes []byte, signError error) {
 	defer func() {
 		if recoveredPanic := recover(); recoveredPanic != nil {
-			signError = fmt.Errorf("panic in MLDSASign: %v\n%s", recoveredPanic, debug.Stack())
+			signError = newPanicError("MLDSASignWithOptions", recoveredPanic)
 		}
 	}()
-	var privateKey mldsa87.PrivateKey
-	if unmarshalError := privateKey.UnmarshalBinary(privateKeyBytes); unmarshalError != nil {
-		fmt.Printf("MLDSASign: privateKey.UnmarshalBinary failed: %v\n", unmarshalError)
-		fmt.Printf("privateKeyBytes len: %d\n", len(privateKeyBytes))
-		return nil, fmt.Errorf("privateKey.UnmarshalBinary failed: %w", unmarshalError)
-	}
-	// Use crypto.Hash(0) as required by the CIRCL ML-DSA-87 implementation for opts
-	signatureBytes, signError = privateKey.Sign(nil, messageBytes, crypto.Hash(0))
+	signatureBytes, signError = signMLDSA(parameterSet, privateKeyBytes, messageBytes, options)
+	if signError != nil {
+		return nil, signError
+	}
+	options.logger().Debug("MLDSASign", "parameter_set", parameterSet.String(), "message_size", len(messageBytes), "signature_size", len(signatureBytes))
+	return signatureBytes, nil
+}
+
+// signMLDSA implements pure ML-DSA signing (FIPS 204 Algorithm 2) of a whole message. Hedged and deterministic signing
+// use CIRCL's SignTo; a caller-supplied Rand needs the internal core, because SignTo only draws rnd from crypto/rand.
+func signMLDSA(parameterSet MLDSAParameterSet, privateKeyBytes []byte, messageBytes []byte, options *MLDSASignOptions) ([]byte, error) {
+	if options != nil && options.Rand != nil {
+		return signMLDSAReader(parameterSet, privateKeyBytes, bytes.NewReader(messageBytes), options)
+	}
+	scheme, schemeError := parameterSet.scheme()
+	if schemeError != nil {
+		return nil, schemeError
+	}
+	contextBytes := options.context()
+	if len(contextBytes) > MLDSAMaxContextSize {
+		return nil, fmt.Errorf("%w: got %d bytes, maximum is %d", ErrContextTooLong, len(contextBytes), MLDSAMaxContextSize)
+	}
+	privateKey, unmarshalError := scheme.UnmarshalBinaryPrivateKey(privateKeyBytes)
+	if unmarshalError != nil {
+		return nil, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v private key", parameterSet), len(privateKeyBytes), scheme.PrivateKeySize())
+	}
+	randomized := options == nil || !options.Deterministic
+	signatureBytes := make([]byte, scheme.SignatureSize())
+	var signError error
+	switch privateKey := privateKey.(type) {
+	case *mldsa44.PrivateKey:
+		signError = mldsa44.SignTo(privateKey, messageBytes, contextBytes, randomized, signatureBytes)
+	case *mldsa65.PrivateKey:
+		signError = mldsa65.SignTo(privateKey, messageBytes, contextBytes, randomized, signatureBytes)
+	case *mldsa87.PrivateKey:
+		signError = mldsa87.SignTo(privateKey, messageBytes, contextBytes, randomized, signatureBytes)
+	default:
+		return nil, fmt.Errorf("%w: %T is not an ML-DSA private key", ErrUnknownAlgorithm, privateKey)
+	}
 	if signError != nil {
-		fmt.Printf("MLDSASign: privateKey.Sign failed: %v\n", signError)
-		fmt.Printf("messageBytes len: %d\n", len(messageBytes))
-		return nil, fmt.Errorf("privateKey.Sign failed: %w", signError)
+		return nil, fmt.Errorf("ML-DSA sign failed: %w", signError)
 	}
-	fmt.Printf("MLDSASign: messageBytes len=%d, signatureBytes len=%d\n", len(messageBytes), len(signatureBytes))
 	return signatureBytes, nil
 }
 
-// MLDSAVerify verifies an ML-DSA signature using CIRCL ML-DSA-87.
-func MLDSAVerify(publicKeyBytes []byte, messageBytes []byte, signatureBytes []byte) (isSignatureValid bool, verifyError error) {
+// MLDSAVerify verifies an ML-DSA signature using a public key of the given parameter set (CIRCL), with an empty context string.
+// A public key of a different parameter set is rejected.
+func MLDSAVerify(parameterSet MLDSAParameterSet, publicKeyBytes []byte, messageBytes []byte, signatureBytes []byte) (isSignatureValid bool, verifyError error) {
+	return MLDSAVerifyWithContext(parameterSet, publicKeyBytes, messageBytes, signatureBytes, nil)
+}
+
+// MLDSAVerifyWithContext verifies an ML-DSA signature using a public key of the given parameter set (CIRCL), bound to a context string.
+// The signature is only valid if it was made with exactly the same context string.
+// The context must be at most MLDSAMaxContextSize bytes; nil and empty are equivalent.
+func MLDSAVerifyWithContext(parameterSet MLDSAParameterSet, publicKeyBytes []byte, messageBytes []byte, signatureBytes []byte, contextBytes []byte) (isSignatureValid bool, verifyError error) {
 	defer func() {
 		if recoveredPanic := recover(); recoveredPanic != nil {
-			verifyError = fmt.Errorf("panic in MLDSAVerify: %v\n%s", recoveredPanic, debug.Stack())
+			verifyError = newPanicError("MLDSAVerifyWithContext", recoveredPanic)
 		}
 	}()
-	var publicKey mldsa87.PublicKey
-	if unmarshalError := publicKey.UnmarshalBinary(publicKeyBytes); unmarshalError != nil {
-		fmt.Printf("MLDSAVerify: publicKey.UnmarshalBinary failed: %v\n", unmarshalError)
-		fmt.Printf("publicKeyBytes len: %d\n", len(publicKeyBytes))
-		return false, fmt.Errorf("publicKey.UnmarshalBinary failed: %w", unmarshalError)
-	}
-	isSignatureValid = mldsa87.Verify(&publicKey, messageBytes, nil, signatureBytes)
-	fmt.Printf("MLDSAVerify: messageBytes len=%d, signatureBytes len=%d, isSignatureValid=%v\n", len(messageBytes), len(signatureBytes), isSignatureValid)
+	scheme, schemeError := parameterSet.scheme()
+	if schemeError != nil {
+		return false, schemeError
+	}
+	if len(contextBytes) > MLDSAMaxContextSize {
+		return false, fmt.Errorf("%w: got %d bytes, maximum is %d", ErrContextTooLong, len(contextBytes), MLDSAMaxContextSize)
+	}
+	publicKey, unmarshalError := scheme.UnmarshalBinaryPublicKey(publicKeyBytes)
+	if unmarshalError != nil {
+		Logger().Warn("MLDSAVerify: invalid public key", "parameter_set", parameterSet.String(), "public_key_size", len(publicKeyBytes), "error", unmarshalError)
+		return false, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v public key", parameterSet), len(publicKeyBytes), scheme.PublicKeySize())
+	}
+	isSignatureValid = scheme.Verify(publicKey, messageBytes, signatureBytes, &sign.SignatureOpts{Context: string(contextBytes)})
+	Logger().Debug("MLDSAVerify", "parameter_set", parameterSet.String(), "message_size", len(messageBytes), "context_size", len(contextBytes), "signature_size", len(signatureBytes), "valid", isSignatureValid)
 	return isSignatureValid, nil
 }
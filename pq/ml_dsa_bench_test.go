@@ -3,35 +3,43 @@ package pq
 import "testing"
 
 func BenchmarkMLDSASign(b *testing.B) {
-	key, err := GenerateMLDSAKeyPair()
-	if err != nil {
-		b.Fatalf("failed to generate ML-DSA key pair: %v", err)
-	}
-	message := []byte("benchmark message")
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, signErr := MLDSASign(key.PrivateKey, message)
-		if signErr != nil {
-			b.Fatalf("signing failed: %v", signErr)
-		}
+	for _, parameterSet := range MLDSAParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			key, err := GenerateMLDSAKeyPair(parameterSet)
+			if err != nil {
+				b.Fatalf("failed to generate ML-DSA key pair: %v", err)
+			}
+			message := []byte("benchmark message")
+			b.ResetTimer()
+			for b.Loop() {
+				_, signErr := MLDSASign(parameterSet, key.PrivateKey, message)
+				if signErr != nil {
+					b.Fatalf("signing failed: %v", signErr)
+				}
+			}
+		})
 	}
 }
 
 func BenchmarkMLDSAVerify(b *testing.B) {
-	key, err := GenerateMLDSAKeyPair()
-	if err != nil {
-		b.Fatalf("failed to generate ML-DSA key pair: %v", err)
-	}
-	message := []byte("benchmark message")
-	signature, signErr := MLDSASign(key.PrivateKey, message)
-	if signErr != nil {
-		b.Fatalf("signing failed: %v", signErr)
-	}
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, verifyErr := MLDSAVerify(key.PublicKey, message, signature)
-		if verifyErr != nil {
-			b.Fatalf("verifying failed: %v", verifyErr)
-		}
+	for _, parameterSet := range MLDSAParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			key, err := GenerateMLDSAKeyPair(parameterSet)
+			if err != nil {
+				b.Fatalf("failed to generate ML-DSA key pair: %v", err)
+			}
+			message := []byte("benchmark message")
+			signature, signErr := MLDSASign(parameterSet, key.PrivateKey, message)
+			if signErr != nil {
+				b.Fatalf("signing failed: %v", signErr)
+			}
+			b.ResetTimer()
+			for b.Loop() {
+				_, verifyErr := MLDSAVerify(parameterSet, key.PublicKey, message, signature)
+				if verifyErr != nil {
+					b.Fatalf("verifying failed: %v", verifyErr)
+				}
+			}
+		})
 	}
 }
@@ -1,15 +1,24 @@
 package pq
 
-import "testing"
+import (
+	"bytes"
+	"testing"
+)
 
 func FuzzMLDSASignAndVerify(f *testing.F) {
-	key, _ := GenerateMLDSAKeyPair()
-	f.Fuzz(func(t *testing.T, msg []byte) {
-		signature, signErr := MLDSASign(key.PrivateKey, msg)
+	keys := make(map[MLDSAParameterSet]*MLDSAKeyPair)
+	for _, parameterSet := range MLDSAParameterSets {
+		keys[parameterSet], _ = GenerateMLDSAKeyPair(parameterSet)
+	}
+	f.Add(uint8(MLDSA44), []byte("msg"))
+	f.Fuzz(func(t *testing.T, parameterSetByte uint8, msg []byte) {
+		parameterSet := MLDSAParameterSets[int(parameterSetByte)%len(MLDSAParameterSets)]
+		key := keys[parameterSet]
+		signature, signErr := MLDSASign(parameterSet, key.PrivateKey, msg)
 		if signErr != nil {
 			t.Skip()
 		}
-		isValid, verifyErr := MLDSAVerify(key.PublicKey, msg, signature)
+		isValid, verifyErr := MLDSAVerify(parameterSet, key.PublicKey, msg, signature)
 		if verifyErr != nil {
 			t.Fatalf("verifying failed: %v", verifyErr)
 		}
@@ -18,3 +27,51 @@ func FuzzMLDSASignAndVerify(f *testing.F) {
 		}
 	})
 }
+
+func FuzzMLDSASignAndVerifyWithContext(f *testing.F) {
+	key, _ := GenerateMLDSAKeyPair(MLDSA44)
+	f.Add([]byte("msg"), []byte("ctx"))
+	f.Fuzz(func(t *testing.T, msg []byte, ctx []byte) {
+		signature, signErr := MLDSASignWithContext(MLDSA44, key.PrivateKey, msg, ctx)
+		if len(ctx) > MLDSAMaxContextSize {
+			if signErr == nil {
+				t.Fatalf("expected error for %d byte context", len(ctx))
+			}
+			return
+		}
+		if signErr != nil {
+			t.Fatalf("signing failed: %v", signErr)
+		}
+		isValid, verifyErr := MLDSAVerifyWithContext(MLDSA44, key.PublicKey, msg, signature, ctx)
+		if verifyErr != nil {
+			t.Fatalf("verifying failed: %v", verifyErr)
+		}
+		if !isValid {
+			t.Error("signature should verify for fuzzed input and context")
+		}
+	})
+}
+
+func FuzzMLDSASignWithRand(f *testing.F) {
+	key, _ := GenerateMLDSAKeyPair(MLDSA44)
+	f.Add([]byte("msg"), make([]byte, 32))
+	f.Fuzz(func(t *testing.T, msg []byte, rnd []byte) {
+		signature, signErr := MLDSASignWithOptions(MLDSA44, key.PrivateKey, msg, &MLDSASignOptions{Rand: bytes.NewReader(rnd)})
+		if len(rnd) < 32 {
+			if signErr == nil {
+				t.Fatalf("expected error for %d bytes of randomness", len(rnd))
+			}
+			return
+		}
+		if signErr != nil {
+			t.Fatalf("signing failed: %v", signErr)
+		}
+		isValid, verifyErr := MLDSAVerify(MLDSA44, key.PublicKey, msg, signature)
+		if verifyErr != nil {
+			t.Fatalf("verifying failed: %v", verifyErr)
+		}
+		if !isValid {
+			t.Error("signature should verify for fuzzed input and randomness")
+		}
+	})
+}
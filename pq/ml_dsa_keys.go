@@ -0,0 +1,355 @@
+package pq
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"io"
+
+	"gopq/pq/internal/mldsa"
+)
+
+// MLDSAPrivateKey is a parsed and validated ML-DSA private key. It implements crypto.Signer and crypto.MessageSigner,
+// so it can be used wherever the standard library accepts an opaque signing key.
+//
+// The SignerOpts passed to Sign and SignMessage select the signature variant:
+//   - nil, a zero crypto.Hash or an *MLDSASignOptions selects pure ML-DSA over the message (FIPS 204 Algorithm 2);
+//     an *MLDSASignOptions additionally carries the context string and the randomness choice.
+//   - crypto.SHA256, SHA384, SHA512, SHA3_256, SHA3_384 or SHA3_512 selects HashML-DSA (FIPS 204 Algorithm 4) with an
+//     empty context: Sign expects the digest, SignMessage hashes the message first.
+type MLDSAPrivateKey struct {
+	parameterSet MLDSAParameterSet
+	seed         []byte
+	privateKey   []byte
+	tr           [mldsa.PublicKeyHashSize]byte
+	publicKey    *MLDSAPublicKey
+}
+
+// MLDSAPublicKey is a parsed and validated ML-DSA public key.
+type MLDSAPublicKey struct {
+	parameterSet MLDSAParameterSet
+	publicKey    []byte
+	tr           [mldsa.PublicKeyHashSize]byte
+}
+
+var (
+	_ crypto.Signer        = (*MLDSAPrivateKey)(nil)
+	_ crypto.MessageSigner = (*MLDSAPrivateKey)(nil)
+)
+
+// errMLDSASignerOpts is returned for SignerOpts that select neither pure ML-DSA nor a supported HashML-DSA pre-hash.
+var errMLDSASignerOpts = fmt.Errorf("%w: ML-DSA crypto.SignerOpts hash function", ErrUnknownAlgorithm)
+
+// mldsaPreHashOf returns the HashML-DSA pre-hash function matching a crypto.Hash.
+func mldsaPreHashOf(hash crypto.Hash) (MLDSAPreHash, bool) {
+	switch hash {
+	case crypto.SHA256:
+		return PreHashSHA256, true
+	case crypto.SHA384:
+		return PreHashSHA384, true
+	case crypto.SHA512:
+		return PreHashSHA512, true
+	case crypto.SHA3_256:
+		return PreHashSHA3_256, true
+	case crypto.SHA3_384:
+		return PreHashSHA3_384, true
+	case crypto.SHA3_512:
+		return PreHashSHA3_512, true
+	default:
+		return 0, false
+	}
+}
+
+// mldsaPublicKeyFromPrivateKey recomputes the public key of an expanded ML-DSA private key.
+func mldsaPublicKeyFromPrivateKey(parameterSet MLDSAParameterSet, privateKeyBytes []byte) ([]byte, error) {
+	scheme, schemeError := parameterSet.scheme()
+	if schemeError != nil {
+		return nil, schemeError
+	}
+	if len(privateKeyBytes) != scheme.PrivateKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v private key", parameterSet), len(privateKeyBytes), scheme.PrivateKeySize())
+	}
+	privateKey, unmarshalError := scheme.UnmarshalBinaryPrivateKey(privateKeyBytes)
+	if unmarshalError != nil {
+		return nil, fmt.Errorf("invalid %v private key: %w", parameterSet, unmarshalError)
+	}
+	return privateKey.Public().(interface{ MarshalBinary() ([]byte, error) }).MarshalBinary()
+}
+
+// GenerateMLDSAPrivateKey generates a new ML-DSA private key for the given parameter set from a fresh random seed.
+func GenerateMLDSAPrivateKey(parameterSet MLDSAParameterSet) (*MLDSAPrivateKey, error) {
+	keyPair, keyGenerationError := GenerateMLDSAKeyPair(parameterSet)
+	if keyGenerationError != nil {
+		return nil, keyGenerationError
+	}
+	return NewMLDSAPrivateKeyFromKeyPair(keyPair)
+}
+
+// NewMLDSAPrivateKeyFromSeed derives an ML-DSA private key from its 32-byte seed (FIPS 204 Algorithm 6).
+func NewMLDSAPrivateKeyFromSeed(parameterSet MLDSAParameterSet, seed []byte) (*MLDSAPrivateKey, error) {
+	if len(seed) != MLDSASeedSize {
+		return nil, newSizeError(ErrInvalidSeedSize, "ML-DSA seed", len(seed), MLDSASeedSize)
+	}
+	keyPair, keyGenerationError := DeriveMLDSAKeyPair(parameterSet, (*[MLDSASeedSize]byte)(seed))
+	if keyGenerationError != nil {
+		return nil, keyGenerationError
+	}
+	return NewMLDSAPrivateKeyFromKeyPair(keyPair)
+}
+
+// NewMLDSAPrivateKey parses an expanded ML-DSA private key, as returned by MLDSAKeyPair.PrivateKey, and recomputes its public key.
+// The resulting key has no seed.
+func NewMLDSAPrivateKey(parameterSet MLDSAParameterSet, privateKeyBytes []byte) (privateKey *MLDSAPrivateKey, parseError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			parseError = newPanicError("NewMLDSAPrivateKey", recoveredPanic)
+		}
+	}()
+	publicKeyBytes, publicKeyError := mldsaPublicKeyFromPrivateKey(parameterSet, privateKeyBytes)
+	if publicKeyError != nil {
+		return nil, publicKeyError
+	}
+	return NewMLDSAPrivateKeyFromKeyPair(&MLDSAKeyPair{ParameterSet: parameterSet, PublicKey: publicKeyBytes, PrivateKey: privateKeyBytes})
+}
+
+// NewMLDSAPrivateKeyFromKeyPair wraps an MLDSAKeyPair, for example one returned by ParseMLDSAPrivateKeyPKCS8, as an MLDSAPrivateKey.
+// The key pair bytes are copied, and the public key must belong to the private key.
+func NewMLDSAPrivateKeyFromKeyPair(keyPair *MLDSAKeyPair) (*MLDSAPrivateKey, error) {
+	if keyPair == nil {
+		return nil, fmt.Errorf("%w: ML-DSA key pair is nil", ErrInvalidKey)
+	}
+	parameters, parametersError := keyPair.ParameterSet.internalParameters()
+	if parametersError != nil {
+		return nil, parametersError
+	}
+	tr, trError := mldsaPrivateKeyHash(keyPair.ParameterSet, parameters, keyPair.PrivateKey)
+	if trError != nil {
+		return nil, trError
+	}
+	publicKey, publicKeyError := NewMLDSAPublicKey(keyPair.ParameterSet, keyPair.PublicKey)
+	if publicKeyError != nil {
+		return nil, publicKeyError
+	}
+	// tr = H(pk) is embedded in the expanded private key, but a forged private key can carry any tr, so the public key
+	// is also recomputed from s1, s2 and rho, and, when there is a seed, the whole private key is rederived from it.
+	derivedPublicKey, derivedPublicKeyError := mldsaPublicKeyFromPrivateKey(keyPair.ParameterSet, keyPair.PrivateKey)
+	if derivedPublicKeyError != nil {
+		return nil, derivedPublicKeyError
+	}
+	if subtle.ConstantTimeCompare(tr[:], publicKey.tr[:])&subtle.ConstantTimeCompare(derivedPublicKey, publicKey.publicKey) != 1 {
+		return nil, fmt.Errorf("%w: inconsistent %v key pair: public key does not match private key", ErrInvalidKey, keyPair.ParameterSet)
+	}
+	if keyPair.Seed != nil {
+		if len(keyPair.Seed) != MLDSASeedSize {
+			return nil, newSizeError(ErrInvalidSeedSize, "ML-DSA seed", len(keyPair.Seed), MLDSASeedSize)
+		}
+		derivedKeyPair, deriveError := DeriveMLDSAKeyPair(keyPair.ParameterSet, (*[MLDSASeedSize]byte)(keyPair.Seed))
+		if deriveError != nil {
+			return nil, deriveError
+		}
+		if subtle.ConstantTimeCompare(derivedKeyPair.PrivateKey, keyPair.PrivateKey) != 1 {
+			return nil, fmt.Errorf("%w: inconsistent %v key pair: seed does not match private key", ErrInvalidKey, keyPair.ParameterSet)
+		}
+	}
+	return &MLDSAPrivateKey{
+		parameterSet: keyPair.ParameterSet,
+		seed:         bytes.Clone(keyPair.Seed),
+		privateKey:   bytes.Clone(keyPair.PrivateKey),
+		tr:           tr,
+		publicKey:    publicKey,
+	}, nil
+}
+
+// NewMLDSAPublicKey parses an encoded ML-DSA public key of the given parameter set.
+func NewMLDSAPublicKey(parameterSet MLDSAParameterSet, publicKeyBytes []byte) (*MLDSAPublicKey, error) {
+	parameters, parametersError := parameterSet.internalParameters()
+	if parametersError != nil {
+		return nil, parametersError
+	}
+	if len(publicKeyBytes) != parameters.PublicKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v public key", parameterSet), len(publicKeyBytes), parameters.PublicKeySize())
+	}
+	return &MLDSAPublicKey{
+		parameterSet: parameterSet,
+		publicKey:    bytes.Clone(publicKeyBytes),
+		tr:           mldsa.PublicKeyHash(publicKeyBytes),
+	}, nil
+}
+
+// ParameterSet returns the parameter set of the key.
+func (privateKey *MLDSAPrivateKey) ParameterSet() MLDSAParameterSet {
+	return privateKey.parameterSet
+}
+
+// Bytes returns a copy of the expanded private key encoding (FIPS 204 skEncode).
+func (privateKey *MLDSAPrivateKey) Bytes() []byte {
+	return bytes.Clone(privateKey.privateKey)
+}
+
+// Seed returns a copy of the 32-byte seed the key was derived from, or nil if the key was parsed from its expanded form.
+func (privateKey *MLDSAPrivateKey) Seed() []byte {
+	return bytes.Clone(privateKey.seed)
+}
+
+// KeyPair returns the key as an MLDSAKeyPair, for use with the byte-oriented functions and MarshalMLDSAPrivateKeyPKCS8.
+func (privateKey *MLDSAPrivateKey) KeyPair() *MLDSAKeyPair {
+	return &MLDSAKeyPair{
+		ParameterSet: privateKey.parameterSet,
+		PublicKey:    privateKey.publicKey.Bytes(),
+		PrivateKey:   privateKey.Bytes(),
+		Seed:         privateKey.Seed(),
+	}
+}
+
+// Public returns the *MLDSAPublicKey of the key, implementing crypto.Signer.
+func (privateKey *MLDSAPrivateKey) Public() crypto.PublicKey {
+	return privateKey.publicKey
+}
+
+// PublicKey returns the public key of the key.
+func (privateKey *MLDSAPrivateKey) PublicKey() *MLDSAPublicKey {
+	return privateKey.publicKey
+}
+
+// Equal reports whether other is an *MLDSAPrivateKey with the same parameter set and private key, in constant time.
+func (privateKey *MLDSAPrivateKey) Equal(other crypto.PrivateKey) bool {
+	otherKey, ok := other.(*MLDSAPrivateKey)
+	if !ok || otherKey == nil || privateKey == nil {
+		return false
+	}
+	return privateKey.parameterSet == otherKey.parameterSet &&
+		subtle.ConstantTimeCompare(privateKey.privateKey, otherKey.privateKey) == 1
+}
+
+// Sign signs digest with the key, implementing crypto.Signer. For pure ML-DSA (opts is nil, a zero hash or an
+// *MLDSASignOptions) digest is the message itself; for HashML-DSA it is the digest computed with opts.HashFunc().
+//
+// The signature is hedged. Unless opts is an *MLDSASignOptions that selects Deterministic or its own Rand, the
+// per-signature randomness is read from randomReader, or from crypto/rand if randomReader is nil or crypto/rand.Reader.
+func (privateKey *MLDSAPrivateKey) Sign(randomReader io.Reader, digest []byte, opts crypto.SignerOpts) (signatureBytes []byte, signError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			signError = newPanicError("MLDSAPrivateKey.Sign", recoveredPanic)
+		}
+	}()
+	options, preHash, optionsError := mldsaSignerOptions(randomReader, opts)
+	if optionsError != nil {
+		return nil, optionsError
+	}
+	if preHash == 0 {
+		return signMLDSA(privateKey.parameterSet, privateKey.privateKey, digest, options)
+	}
+	mu, muError := hashMLDSAMessageRepresentative(privateKey.tr[:], preHash, digest, nil)
+	if muError != nil {
+		return nil, muError
+	}
+	parameters, parametersError := privateKey.parameterSet.internalParameters()
+	if parametersError != nil {
+		return nil, parametersError
+	}
+	return signMLDSAMu(parameters, privateKey.privateKey, mu, options)
+}
+
+// SignMessage signs message with the key, implementing crypto.MessageSigner. For HashML-DSA opts the message is hashed
+// with opts.HashFunc() first; otherwise it behaves exactly like Sign.
+func (privateKey *MLDSAPrivateKey) SignMessage(randomReader io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	if opts != nil && opts.HashFunc() != 0 {
+		preHash, ok := mldsaPreHashOf(opts.HashFunc())
+		if !ok {
+			return nil, errMLDSASignerOpts
+		}
+		digest, digestError := preHash.Digest(message)
+		if digestError != nil {
+			return nil, digestError
+		}
+		return privateKey.Sign(randomReader, digest, opts)
+	}
+	return privateKey.Sign(randomReader, message, opts)
+}
+
+// mldsaSignerOptions maps crypto.SignerOpts to sign options and an optional pre-hash function (0 for pure ML-DSA).
+func mldsaSignerOptions(randomReader io.Reader, opts crypto.SignerOpts) (*MLDSASignOptions, MLDSAPreHash, error) {
+	options := &MLDSASignOptions{}
+	if signOptions, ok := opts.(*MLDSASignOptions); ok && signOptions != nil {
+		copied := *signOptions
+		options = &copied
+	} else if opts != nil && opts.HashFunc() != 0 {
+		preHash, ok := mldsaPreHashOf(opts.HashFunc())
+		if !ok {
+			return nil, 0, errMLDSASignerOpts
+		}
+		return &MLDSASignOptions{Rand: customRandomReader(randomReader)}, preHash, nil
+	}
+	if !options.Deterministic && options.Rand == nil {
+		options.Rand = customRandomReader(randomReader)
+	}
+	return options, 0, nil
+}
+
+// customRandomReader returns randomReader unless it is nil or crypto/rand.Reader, so that ordinary hedged signing
+// leaves MLDSASignOptions.Rand unset and goes through CIRCL, which draws its randomness from crypto/rand itself.
+func customRandomReader(randomReader io.Reader) io.Reader {
+	if randomReader == rand.Reader {
+		return nil
+	}
+	return randomReader
+}
+
+// ParameterSet returns the parameter set of the key.
+func (publicKey *MLDSAPublicKey) ParameterSet() MLDSAParameterSet {
+	return publicKey.parameterSet
+}
+
+// Bytes returns a copy of the public key encoding (FIPS 204 pkEncode).
+func (publicKey *MLDSAPublicKey) Bytes() []byte {
+	return bytes.Clone(publicKey.publicKey)
+}
+
+// Equal reports whether other is an *MLDSAPublicKey with the same parameter set and encoding.
+func (publicKey *MLDSAPublicKey) Equal(other crypto.PublicKey) bool {
+	otherKey, ok := other.(*MLDSAPublicKey)
+	if !ok || otherKey == nil || publicKey == nil {
+		return false
+	}
+	return publicKey.parameterSet == otherKey.parameterSet && bytes.Equal(publicKey.publicKey, otherKey.publicKey)
+}
+
+// Verify verifies a signature made by MLDSAPrivateKey.Sign with the same opts: for pure ML-DSA message is the message
+// and the context is taken from an *MLDSASignOptions; for HashML-DSA message is the digest.
+func (publicKey *MLDSAPublicKey) Verify(message []byte, signatureBytes []byte, opts crypto.SignerOpts) (isSignatureValid bool, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			verifyError = newPanicError("MLDSAPublicKey.Verify", recoveredPanic)
+		}
+	}()
+	options, preHash, optionsError := mldsaSignerOptions(nil, opts)
+	if optionsError != nil {
+		return false, optionsError
+	}
+	var mu *[mldsa.MuSize]byte
+	var muError error
+	if preHash == 0 {
+		mu, muError = pureMLDSAMessageRepresentative(publicKey.tr[:], bytes.NewReader(message), options.context())
+	} else {
+		mu, muError = hashMLDSAMessageRepresentative(publicKey.tr[:], preHash, message, nil)
+	}
+	if muError != nil {
+		return false, muError
+	}
+	return verifyMLDSAMu(publicKey.parameterSet, publicKey.publicKey, mu, signatureBytes)
+}
+
+// CheckSignature is like Verify, but reports an invalid signature as an error matching ErrVerificationFailed, so that
+// callers handle a forged signature and a malformed input the same way.
+func (publicKey *MLDSAPublicKey) CheckSignature(message []byte, signatureBytes []byte, opts crypto.SignerOpts) error {
+	isSignatureValid, verifyError := publicKey.Verify(message, signatureBytes, opts)
+	if verifyError != nil {
+		return verifyError
+	}
+	if !isSignatureValid {
+		return fmt.Errorf("%v: %w", publicKey.parameterSet, ErrVerificationFailed)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package pq
+
+import (
+	"crypto"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkMLDSAPrivateKeySign(b *testing.B) {
+	message := []byte("benchmark message")
+	for _, parameterSet := range MLDSAParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			privateKey, err := GenerateMLDSAPrivateKey(parameterSet)
+			require.NoError(b, err, "Key generation should not error")
+			for b.Loop() {
+				_, err := privateKey.Sign(nil, message, crypto.Hash(0))
+				require.NoError(b, err, "Signing should not error")
+			}
+		})
+	}
+}
+
+func BenchmarkMLDSAPublicKeyVerify(b *testing.B) {
+	message := []byte("benchmark message")
+	for _, parameterSet := range MLDSAParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			privateKey, err := GenerateMLDSAPrivateKey(parameterSet)
+			require.NoError(b, err, "Key generation should not error")
+			signature, err := privateKey.Sign(nil, message, nil)
+			require.NoError(b, err, "Signing should not error")
+			for b.Loop() {
+				valid, err := privateKey.PublicKey().Verify(message, signature, nil)
+				require.NoError(b, err, "Verification should not error")
+				require.True(b, valid, "Signature should be valid")
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package pq
+
+import "testing"
+
+func FuzzMLDSAPublicKeyVerify(f *testing.F) {
+	privateKey, _ := GenerateMLDSAPrivateKey(MLDSA44)
+	message := []byte("message")
+	signature, _ := privateKey.Sign(nil, message, nil)
+	f.Add(message, signature)
+	f.Fuzz(func(t *testing.T, fuzzedMessage []byte, fuzzedSignature []byte) {
+		valid, err := privateKey.PublicKey().Verify(fuzzedMessage, fuzzedSignature, nil)
+		if err != nil {
+			t.Fatalf("verify should report invalid signatures without an error: %v", err)
+		}
+		expected, err := MLDSAVerify(MLDSA44, privateKey.PublicKey().Bytes(), fuzzedMessage, fuzzedSignature)
+		if err != nil {
+			t.Fatalf("MLDSAVerify: %v", err)
+		}
+		if valid != expected {
+			t.Errorf("MLDSAPublicKey.Verify = %v, MLDSAVerify = %v", valid, expected)
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package pq
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// signWithSigner signs through the generic crypto.Signer interface, like x509.CreateCertificate-style callers do.
+func signWithSigner(signer crypto.Signer, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return signer.Sign(nil, message, opts)
+}
+
+// For TestMLDSAPrivateKeySigner: the typed key signs through crypto.Signer and crypto.SignMessage, and the signatures
+// verify with both the typed public key and the byte-oriented API.
+func TestMLDSAPrivateKeySigner(t *testing.T) {
+	message := []byte("typed ML-DSA keys")
+	for _, parameterSet := range MLDSAParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			privateKey, err := GenerateMLDSAPrivateKey(parameterSet)
+			require.NoError(t, err)
+			publicKey, ok := privateKey.Public().(*MLDSAPublicKey)
+			require.True(t, ok, "Public must return *MLDSAPublicKey")
+			require.Equal(t, parameterSet, publicKey.ParameterSet())
+
+			signature, err := signWithSigner(privateKey, message, crypto.Hash(0))
+			require.NoError(t, err)
+			valid, err := publicKey.Verify(message, signature, nil)
+			require.NoError(t, err)
+			require.True(t, valid)
+			valid, err = MLDSAVerify(parameterSet, publicKey.Bytes(), message, signature)
+			require.NoError(t, err)
+			require.True(t, valid, "typed signatures must verify with MLDSAVerify")
+
+			signature, err = crypto.SignMessage(privateKey, nil, message, &MLDSASignOptions{Context: []byte("ctx")})
+			require.NoError(t, err)
+			valid, err = MLDSAVerifyWithContext(parameterSet, publicKey.Bytes(), message, signature, []byte("ctx"))
+			require.NoError(t, err)
+			require.True(t, valid)
+			valid, err = publicKey.Verify(message, signature, &MLDSASignOptions{Context: []byte("other")})
+			require.NoError(t, err)
+			require.False(t, valid, "a different context must not verify")
+		})
+	}
+}
+
+// For TestMLDSAPrivateKeyHashSigner: crypto.Hash options select HashML-DSA; Sign takes the digest, SignMessage the message.
+func TestMLDSAPrivateKeyHashSigner(t *testing.T) {
+	message := []byte("pre-hashed message")
+	digest := sha256.Sum256(message)
+	privateKey, err := GenerateMLDSAPrivateKey(MLDSA65)
+	require.NoError(t, err)
+	publicKey := privateKey.PublicKey()
+
+	signature, err := privateKey.Sign(nil, digest[:], crypto.SHA256)
+	require.NoError(t, err)
+	valid, err := HashMLDSAVerify(MLDSA65, PreHashSHA256, publicKey.Bytes(), message, signature, nil)
+	require.NoError(t, err)
+	require.True(t, valid, "Sign with crypto.SHA256 must produce a HashML-DSA signature")
+
+	signature, err = crypto.SignMessage(privateKey, nil, message, crypto.SHA512)
+	require.NoError(t, err)
+	digest512, err := PreHashSHA512.Digest(message)
+	require.NoError(t, err)
+	valid, err = publicKey.Verify(digest512, signature, crypto.SHA512)
+	require.NoError(t, err)
+	require.True(t, valid)
+	valid, err = publicKey.Verify(message, signature, nil)
+	require.NoError(t, err)
+	require.False(t, valid, "a HashML-DSA signature must not verify as pure ML-DSA")
+
+	_, err = privateKey.Sign(nil, digest[:], crypto.MD5)
+	require.Error(t, err, "unsupported hash functions must be rejected")
+	_, err = publicKey.Verify(digest[:], signature, crypto.SHA1)
+	require.Error(t, err)
+}
+
+// For TestMLDSAPrivateKeyRandomness: the io.Reader argument is the randomness source unless the options override it,
+// and deterministic signatures match the byte-oriented API.
+func TestMLDSAPrivateKeyRandomness(t *testing.T) {
+	message := []byte("randomness")
+	privateKey, err := GenerateMLDSAPrivateKey(MLDSA44)
+	require.NoError(t, err)
+
+	first, err := privateKey.Sign(bytes.NewReader(make([]byte, 32)), message, nil)
+	require.NoError(t, err)
+	second, err := privateKey.Sign(bytes.NewReader(make([]byte, 32)), message, nil)
+	require.NoError(t, err)
+	require.Equal(t, first, second, "the same randomness must give the same signature")
+
+	deterministic, err := privateKey.Sign(nil, message, &MLDSASignOptions{Deterministic: true})
+	require.NoError(t, err)
+	expected, err := MLDSASignWithOptions(MLDSA44, privateKey.Bytes(), message, &MLDSASignOptions{Deterministic: true})
+	require.NoError(t, err)
+	require.Equal(t, expected, deterministic)
+	require.Equal(t, expected, first, "all-zero randomness is the deterministic variant")
+
+	hedged, err := privateKey.Sign(nil, message, nil)
+	require.NoError(t, err)
+	require.NotEqual(t, deterministic, hedged, "signing without a reader must use crypto/rand")
+
+	_, err = privateKey.Sign(bytes.NewReader(nil), message, nil)
+	require.Error(t, err, "a short randomness reader must be reported")
+
+	for _, randomReader := range []io.Reader{nil, rand.Reader} {
+		for _, opts := range []crypto.SignerOpts{nil, crypto.SHA256, &MLDSASignOptions{}} {
+			options, _, err := mldsaSignerOptions(randomReader, opts)
+			require.NoError(t, err)
+			require.Nil(t, options.Rand, "crypto/rand must be left to CIRCL rather than passed as a custom reader")
+		}
+	}
+}
+
+// For TestMLDSAKeyConstructors: seed, expanded and key-pair constructors agree, and Equal compares keys.
+func TestMLDSAKeyConstructors(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x42}, MLDSASeedSize)
+	fromSeed, err := NewMLDSAPrivateKeyFromSeed(MLDSA87, seed)
+	require.NoError(t, err)
+	require.Equal(t, seed, fromSeed.Seed())
+
+	fromBytes, err := NewMLDSAPrivateKey(MLDSA87, fromSeed.Bytes())
+	require.NoError(t, err)
+	require.Nil(t, fromBytes.Seed())
+	require.True(t, fromSeed.Equal(fromBytes))
+	require.True(t, fromSeed.PublicKey().Equal(fromBytes.Public()))
+
+	fromKeyPair, err := NewMLDSAPrivateKeyFromKeyPair(fromSeed.KeyPair())
+	require.NoError(t, err)
+	require.True(t, fromSeed.Equal(fromKeyPair))
+
+	other, err := GenerateMLDSAPrivateKey(MLDSA87)
+	require.NoError(t, err)
+	require.False(t, fromSeed.Equal(other))
+	require.False(t, fromSeed.PublicKey().Equal(other.PublicKey()))
+	require.False(t, fromSeed.Equal(nil))
+
+	mismatched := fromSeed.KeyPair()
+	mismatched.PublicKey = other.PublicKey().Bytes()
+	_, err = NewMLDSAPrivateKeyFromKeyPair(mismatched)
+	require.ErrorContains(t, err, "public key does not match")
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	// The other key with this key's tr (after rho and K in skEncode) still does not match this public key.
+	forged := fromSeed.KeyPair()
+	forged.PrivateKey = other.Bytes()
+	copy(forged.PrivateKey[64:128], fromSeed.Bytes()[64:128])
+	forged.Seed = nil
+	_, err = NewMLDSAPrivateKeyFromKeyPair(forged)
+	require.ErrorContains(t, err, "public key does not match")
+
+	wrongSeed := fromSeed.KeyPair()
+	wrongSeed.Seed = bytes.Repeat([]byte{0x43}, MLDSASeedSize)
+	_, err = NewMLDSAPrivateKeyFromKeyPair(wrongSeed)
+	require.ErrorContains(t, err, "seed does not match")
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = NewMLDSAPrivateKeyFromSeed(MLDSA87, seed[1:])
+	require.Error(t, err)
+	_, err = NewMLDSAPrivateKey(MLDSA44, fromSeed.Bytes())
+	require.Error(t, err, "a key of another parameter set must be rejected")
+	_, err = NewMLDSAPublicKey(MLDSA44, fromSeed.PublicKey().Bytes())
+	require.Error(t, err)
+}
+
+// For TestMLDSAPrivateKeyPKCS8Bridge: typed keys round-trip through PKCS#8 via KeyPair.
+func TestMLDSAPrivateKeyPKCS8Bridge(t *testing.T) {
+	privateKey, err := GenerateMLDSAPrivateKey(MLDSA65)
+	require.NoError(t, err)
+	der, err := MarshalMLDSAPrivateKeyPKCS8(privateKey.KeyPair(), PrivateKeyFormatSeed)
+	require.NoError(t, err)
+	keyPair, err := ParseMLDSAPrivateKeyPKCS8(der)
+	require.NoError(t, err)
+	parsed, err := NewMLDSAPrivateKeyFromKeyPair(keyPair)
+	require.NoError(t, err)
+	require.True(t, privateKey.Equal(parsed))
+}
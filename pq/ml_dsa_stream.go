@@ -0,0 +1,184 @@
+package pq
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"gopq/pq/internal/mldsa"
+)
+
+// MLDSAMuSize is the size in bytes of the FIPS 204 message representative mu, for all parameter sets.
+const MLDSAMuSize = mldsa.MuSize
+
+// pureMLDSAMessageRepresentative computes mu = H(tr || M', 64) with the pure ML-DSA formatted message
+// M' = 0 || len(ctx) || ctx || M (FIPS 204 Algorithm 2, line 10), reading M incrementally from messageReader.
+func pureMLDSAMessageRepresentative(tr []byte, messageReader io.Reader, contextBytes []byte) (*[mldsa.MuSize]byte, error) {
+	if len(contextBytes) > MLDSAMaxContextSize {
+		return nil, fmt.Errorf("%w: got %d bytes, maximum is %d", ErrContextTooLong, len(contextBytes), MLDSAMaxContextSize)
+	}
+	messageHash := mldsa.NewMessageHash(tr)
+	_, _ = messageHash.Write([]byte{0, byte(len(contextBytes))})
+	_, _ = messageHash.Write(contextBytes)
+	if _, copyError := io.Copy(messageHash, messageReader); copyError != nil {
+		return nil, fmt.Errorf("reading message: %w", copyError)
+	}
+	var mu [mldsa.MuSize]byte
+	_, _ = messageHash.Read(mu[:])
+	return &mu, nil
+}
+
+// ComputeMLDSAMu computes the pure ML-DSA message representative mu ("external mu") of a message read from messageReader,
+// for a public key of the given parameter set and a context string. Only MLDSAMuSize bytes of state are kept, whatever the message size.
+// mu can be computed where the data lives and sent to wherever the private key lives, to be signed with MLDSASignMu.
+func ComputeMLDSAMu(parameterSet MLDSAParameterSet, publicKeyBytes []byte, messageReader io.Reader, contextBytes []byte) (muBytes []byte, muError error) {
+	parameters, parametersError := parameterSet.internalParameters()
+	if parametersError != nil {
+		return nil, parametersError
+	}
+	if len(publicKeyBytes) != parameters.PublicKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v public key", parameterSet), len(publicKeyBytes), parameters.PublicKeySize())
+	}
+	tr := mldsa.PublicKeyHash(publicKeyBytes)
+	mu, muError := pureMLDSAMessageRepresentative(tr[:], messageReader, contextBytes)
+	if muError != nil {
+		return nil, muError
+	}
+	return mu[:], nil
+}
+
+// MLDSASignMu signs an externally computed message representative mu (see ComputeMLDSAMu), with hedged randomness.
+// The result is a standard ML-DSA signature over the message and context that mu was computed from.
+func MLDSASignMu(parameterSet MLDSAParameterSet, privateKeyBytes []byte, muBytes []byte) (signatureBytes []byte, signError error) {
+	return MLDSASignMuWithOptions(parameterSet, privateKeyBytes, muBytes, nil)
+}
+
+// MLDSASignMuWithOptions signs an externally computed message representative mu with the signing randomness selected by the options.
+// The context string is already bound into mu, so options.Context must be empty.
+func MLDSASignMuWithOptions(parameterSet MLDSAParameterSet, privateKeyBytes []byte, muBytes []byte, options *MLDSASignOptions) (signatureBytes []byte, signError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			signError = newPanicError("MLDSASignMuWithOptions", recoveredPanic)
+		}
+	}()
+	if len(muBytes) != MLDSAMuSize {
+		return nil, newSizeError(ErrInvalidMuSize, "ML-DSA mu", len(muBytes), MLDSAMuSize)
+	}
+	if len(options.context()) != 0 {
+		return nil, fmt.Errorf("%w: the ML-DSA context string is already bound into mu", ErrInvalidOptions)
+	}
+	parameters, parametersError := parameterSet.internalParameters()
+	if parametersError != nil {
+		return nil, parametersError
+	}
+	return signMLDSAMu(parameters, privateKeyBytes, (*[mldsa.MuSize]byte)(muBytes), options)
+}
+
+// MLDSASignReader signs a message read incrementally from messageReader, with hedged randomness, bound to a context string,
+// without holding the message in memory. The result is a standard ML-DSA signature that MLDSAVerify accepts.
+func MLDSASignReader(parameterSet MLDSAParameterSet, privateKeyBytes []byte, messageReader io.Reader, contextBytes []byte) (signatureBytes []byte, signError error) {
+	return MLDSASignReaderWithOptions(parameterSet, privateKeyBytes, messageReader, &MLDSASignOptions{Context: contextBytes})
+}
+
+// MLDSASignReaderWithOptions signs a message read incrementally from messageReader, as selected by the options.
+// Given the same options and randomness, the signature is byte-identical to MLDSASignWithOptions over the same message.
+func MLDSASignReaderWithOptions(parameterSet MLDSAParameterSet, privateKeyBytes []byte, messageReader io.Reader, options *MLDSASignOptions) (signatureBytes []byte, signError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			signError = newPanicError("MLDSASignReaderWithOptions", recoveredPanic)
+		}
+	}()
+	return signMLDSAReader(parameterSet, privateKeyBytes, messageReader, options)
+}
+
+// signMLDSAReader implements pure ML-DSA signing (FIPS 204 Algorithm 2) over a message read from messageReader.
+func signMLDSAReader(parameterSet MLDSAParameterSet, privateKeyBytes []byte, messageReader io.Reader, options *MLDSASignOptions) ([]byte, error) {
+	parameters, parametersError := parameterSet.internalParameters()
+	if parametersError != nil {
+		return nil, parametersError
+	}
+	tr, trError := mldsaPrivateKeyHash(parameterSet, parameters, privateKeyBytes)
+	if trError != nil {
+		return nil, trError
+	}
+	mu, muError := pureMLDSAMessageRepresentative(tr[:], messageReader, options.context())
+	if muError != nil {
+		return nil, muError
+	}
+	return signMLDSAMu(parameters, privateKeyBytes, mu, options)
+}
+
+// mldsaPrivateKeyHash returns tr as stored in an encoded private key, or an error matching ErrInvalidKeySize for a key
+// of the wrong size and ErrInvalidKey for a key whose s1 or s2 coefficients are out of range.
+func mldsaPrivateKeyHash(parameterSet MLDSAParameterSet, parameters *mldsa.Parameters, privateKeyBytes []byte) ([mldsa.PublicKeyHashSize]byte, error) {
+	tr, trError := mldsa.PrivateKeyHash(parameters, privateKeyBytes)
+	if errors.Is(trError, mldsa.ErrPrivateKeyRange) {
+		return tr, fmt.Errorf("%w: %v private key: %w", ErrInvalidKey, parameterSet, trError)
+	}
+	if trError != nil {
+		return tr, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v private key", parameterSet), len(privateKeyBytes), parameters.PrivateKeySize())
+	}
+	return tr, nil
+}
+
+// signMLDSAMu signs mu with the randomness selected by the options.
+func signMLDSAMu(parameters *mldsa.Parameters, privateKeyBytes []byte, mu *[mldsa.MuSize]byte, options *MLDSASignOptions) ([]byte, error) {
+	if len(privateKeyBytes) != parameters.PrivateKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, parameters.String()+" private key", len(privateKeyBytes), parameters.PrivateKeySize())
+	}
+	rnd, randomError := options.randomness()
+	if randomError != nil {
+		return nil, randomError
+	}
+	signatureBytes, signError := mldsa.SignMu(parameters, privateKeyBytes, mu, rnd)
+	if errors.Is(signError, mldsa.ErrPrivateKeyRange) {
+		return nil, fmt.Errorf("%w: %v private key: %w", ErrInvalidKey, parameters, signError)
+	}
+	if signError != nil {
+		return nil, fmt.Errorf("ML-DSA sign failed: %w", signError)
+	}
+	return signatureBytes, nil
+}
+
+// MLDSAVerifyMu verifies an ML-DSA signature over an externally computed message representative mu (see ComputeMLDSAMu).
+func MLDSAVerifyMu(parameterSet MLDSAParameterSet, publicKeyBytes []byte, muBytes []byte, signatureBytes []byte) (isSignatureValid bool, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			verifyError = newPanicError("MLDSAVerifyMu", recoveredPanic)
+		}
+	}()
+	if len(muBytes) != MLDSAMuSize {
+		return false, newSizeError(ErrInvalidMuSize, "ML-DSA mu", len(muBytes), MLDSAMuSize)
+	}
+	return verifyMLDSAMu(parameterSet, publicKeyBytes, (*[mldsa.MuSize]byte)(muBytes), signatureBytes)
+}
+
+// MLDSAVerifyReader verifies an ML-DSA signature over a message read incrementally from messageReader, bound to a context string.
+// It accepts exactly the signatures that MLDSAVerifyWithContext accepts over the same message.
+func MLDSAVerifyReader(parameterSet MLDSAParameterSet, publicKeyBytes []byte, messageReader io.Reader, signatureBytes []byte, contextBytes []byte) (isSignatureValid bool, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			verifyError = newPanicError("MLDSAVerifyReader", recoveredPanic)
+		}
+	}()
+	muBytes, muError := ComputeMLDSAMu(parameterSet, publicKeyBytes, messageReader, contextBytes)
+	if muError != nil {
+		return false, muError
+	}
+	return verifyMLDSAMu(parameterSet, publicKeyBytes, (*[mldsa.MuSize]byte)(muBytes), signatureBytes)
+}
+
+func verifyMLDSAMu(parameterSet MLDSAParameterSet, publicKeyBytes []byte, mu *[mldsa.MuSize]byte, signatureBytes []byte) (bool, error) {
+	parameters, parametersError := parameterSet.internalParameters()
+	if parametersError != nil {
+		return false, parametersError
+	}
+	if len(publicKeyBytes) != parameters.PublicKeySize() {
+		return false, newSizeError(ErrInvalidKeySize, fmt.Sprintf("%v public key", parameterSet), len(publicKeyBytes), parameters.PublicKeySize())
+	}
+	isSignatureValid, verifyError := mldsa.VerifyMu(parameters, publicKeyBytes, mu, signatureBytes)
+	if verifyError != nil {
+		return false, fmt.Errorf("ML-DSA verify failed: %w", verifyError)
+	}
+	return isSignatureValid, nil
+}
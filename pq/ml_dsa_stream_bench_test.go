@@ -0,0 +1,42 @@
+package pq
+
+import (
+	"bytes"
+	"testing"
+)
+
+func BenchmarkMLDSASignReader(b *testing.B) {
+	key, err := GenerateMLDSAKeyPair(MLDSA65)
+	if err != nil {
+		b.Fatalf("failed to generate ML-DSA key pair: %v", err)
+	}
+	message := make([]byte, 16<<20)
+	b.SetBytes(int64(len(message)))
+	b.ResetTimer()
+	for b.Loop() {
+		_, signErr := MLDSASignReader(MLDSA65, key.PrivateKey, bytes.NewReader(message), nil)
+		if signErr != nil {
+			b.Fatalf("signing failed: %v", signErr)
+		}
+	}
+}
+
+func BenchmarkMLDSAVerifyReader(b *testing.B) {
+	key, err := GenerateMLDSAKeyPair(MLDSA65)
+	if err != nil {
+		b.Fatalf("failed to generate ML-DSA key pair: %v", err)
+	}
+	message := make([]byte, 16<<20)
+	signature, signErr := MLDSASignReader(MLDSA65, key.PrivateKey, bytes.NewReader(message), nil)
+	if signErr != nil {
+		b.Fatalf("signing failed: %v", signErr)
+	}
+	b.SetBytes(int64(len(message)))
+	b.ResetTimer()
+	for b.Loop() {
+		_, verifyErr := MLDSAVerifyReader(MLDSA65, key.PublicKey, bytes.NewReader(message), signature, nil)
+		if verifyErr != nil {
+			b.Fatalf("verifying failed: %v", verifyErr)
+		}
+	}
+}
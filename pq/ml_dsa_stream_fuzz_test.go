@@ -0,0 +1,35 @@
+package pq
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzMLDSASignReader(f *testing.F) {
+	key, _ := GenerateMLDSAKeyPair(MLDSA44)
+	f.Add([]byte("msg"), []byte("ctx"))
+	f.Fuzz(func(t *testing.T, msg []byte, ctx []byte) {
+		if len(ctx) > MLDSAMaxContextSize {
+			t.Skip()
+		}
+		options := &MLDSASignOptions{Context: ctx, Deterministic: true}
+		expectedSignature, signErr := MLDSASignWithOptions(MLDSA44, key.PrivateKey, msg, options)
+		if signErr != nil {
+			t.Fatalf("signing failed: %v", signErr)
+		}
+		signature, signErr := MLDSASignReaderWithOptions(MLDSA44, key.PrivateKey, bytes.NewReader(msg), options)
+		if signErr != nil {
+			t.Fatalf("streaming signing failed: %v", signErr)
+		}
+		if !bytes.Equal(signature, expectedSignature) {
+			t.Error("streamed signature should be byte-identical for fuzzed input")
+		}
+		isValid, verifyErr := MLDSAVerifyReader(MLDSA44, key.PublicKey, bytes.NewReader(msg), signature, ctx)
+		if verifyErr != nil {
+			t.Fatalf("streaming verifying failed: %v", verifyErr)
+		}
+		if !isValid {
+			t.Error("signature should verify for fuzzed input and context")
+		}
+	})
+}
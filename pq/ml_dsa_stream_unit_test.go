@@ -0,0 +1,176 @@
+package pq
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+// For TestMLDSASignReaderMatchesMLDSASign:
+func TestMLDSASignReaderMatchesMLDSASign(t *testing.T) {
+	for _, parameterSet := range MLDSAParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			key, err := GenerateMLDSAKeyPair(parameterSet)
+			if err != nil {
+				t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+			}
+			msg := bytes.Repeat([]byte("release artifact "), 100000)
+			for _, ctx := range [][]byte{nil, []byte("release-v1")} {
+				options := &MLDSASignOptions{Context: ctx, Deterministic: true}
+				expectedSig, err := MLDSASignWithOptions(parameterSet, key.PrivateKey, msg, options)
+				if err != nil {
+					t.Fatalf("signing failed: %v", err)
+				}
+				sig, err := MLDSASignReaderWithOptions(parameterSet, key.PrivateKey, bytes.NewReader(msg), options)
+				if err != nil {
+					t.Fatalf("streaming signing failed: %v", err)
+				}
+				if !bytes.Equal(sig, expectedSig) {
+					t.Error("deterministic streamed signature should be byte-identical to MLDSASignWithOptions")
+				}
+				isVerify, err := MLDSAVerifyWithContext(parameterSet, key.PublicKey, msg, sig, ctx)
+				if err != nil {
+					t.Fatalf("verifying failed: %v", err)
+				}
+				if !isVerify {
+					t.Error("streamed signature should verify with MLDSAVerifyWithContext")
+				}
+				isVerify, err = MLDSAVerifyReader(parameterSet, key.PublicKey, bytes.NewReader(msg), expectedSig, ctx)
+				if err != nil {
+					t.Fatalf("streaming verifying failed: %v", err)
+				}
+				if !isVerify {
+					t.Error("MLDSASignWithOptions signature should verify with MLDSAVerifyReader")
+				}
+				isVerify, err = MLDSAVerifyReader(parameterSet, key.PublicKey, bytes.NewReader(msg[1:]), expectedSig, ctx)
+				if err != nil {
+					t.Fatalf("streaming verifying failed: %v", err)
+				}
+				if isVerify {
+					t.Error("signature should not verify for a different message")
+				}
+			}
+		})
+	}
+}
+
+// For TestMLDSAExternalMu:
+func TestMLDSAExternalMu(t *testing.T) {
+	key, err := GenerateMLDSAKeyPair(MLDSA65)
+	if err != nil {
+		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+	}
+	msg := []byte("test message")
+	ctx := []byte("ctx")
+	mu, err := ComputeMLDSAMu(MLDSA65, key.PublicKey, bytes.NewReader(msg), ctx)
+	if err != nil {
+		t.Fatalf("computing mu failed: %v", err)
+	}
+	if len(mu) != MLDSAMuSize {
+		t.Errorf("mu len = %d, want %d", len(mu), MLDSAMuSize)
+	}
+	sig, err := MLDSASignMuWithOptions(MLDSA65, key.PrivateKey, mu, &MLDSASignOptions{Deterministic: true})
+	if err != nil {
+		t.Fatalf("signing mu failed: %v", err)
+	}
+	expectedSig, err := MLDSASignWithOptions(MLDSA65, key.PrivateKey, msg, &MLDSASignOptions{Context: ctx, Deterministic: true})
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if !bytes.Equal(sig, expectedSig) {
+		t.Error("deterministic signature over external mu should be byte-identical to MLDSASignWithOptions")
+	}
+	isVerify, err := MLDSAVerifyMu(MLDSA65, key.PublicKey, mu, sig)
+	if err != nil {
+		t.Fatalf("verifying mu failed: %v", err)
+	}
+	if !isVerify {
+		t.Error("signature should verify over mu")
+	}
+	hedgedSig, err := MLDSASignMu(MLDSA65, key.PrivateKey, mu)
+	if err != nil {
+		t.Fatalf("signing mu failed: %v", err)
+	}
+	isVerify, err = MLDSAVerifyWithContext(MLDSA65, key.PublicKey, msg, hedgedSig, ctx)
+	if err != nil {
+		t.Fatalf("verifying failed: %v", err)
+	}
+	if !isVerify {
+		t.Error("hedged signature over mu should verify over the message")
+	}
+	if sig, err := MLDSASignMuWithOptions(MLDSA65, key.PrivateKey, mu, &MLDSASignOptions{Context: ctx}); err == nil || sig != nil {
+		t.Error("expected error for a context passed with mu")
+	}
+	mu[0] ^= 0x01
+	isVerify, err = MLDSAVerifyMu(MLDSA65, key.PublicKey, mu, sig)
+	if err != nil {
+		t.Fatalf("verifying mu failed: %v", err)
+	}
+	if isVerify {
+		t.Error("signature should not verify over a different mu")
+	}
+}
+
+// errorReader always fails, like a broken network stream.
+type errorReader struct{ err error }
+
+func (r errorReader) Read([]byte) (int, error) { return 0, r.err }
+
+// For TestMLDSAStreamInvalidInput:
+func TestMLDSAStreamInvalidInput(t *testing.T) {
+	key, err := GenerateMLDSAKeyPair(MLDSA44)
+	if err != nil {
+		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+	}
+	readErr := errors.New("stream broken")
+	brokenReader := io.MultiReader(bytes.NewReader([]byte("partial")), errorReader{readErr})
+	if sig, err := MLDSASignReader(MLDSA44, key.PrivateKey, brokenReader, nil); !errors.Is(err, readErr) || sig != nil {
+		t.Errorf("expected read error to be returned, got %v", err)
+	}
+	sig, err := MLDSASign(MLDSA44, key.PrivateKey, []byte("partial"))
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if isVerify, err := MLDSAVerifyReader(MLDSA44, key.PublicKey, errorReader{readErr}, sig, nil); !errors.Is(err, readErr) || isVerify {
+		t.Errorf("expected read error to be returned, got %v", err)
+	}
+	tooLongContext := make([]byte, MLDSAMaxContextSize+1)
+	if sig, err := MLDSASignReader(MLDSA44, key.PrivateKey, bytes.NewReader(nil), tooLongContext); !errors.Is(err, ErrContextTooLong) || sig != nil {
+		t.Errorf("expected ErrContextTooLong, got %v", err)
+	}
+	if _, err := ComputeMLDSAMu(MLDSA44, key.PublicKey, bytes.NewReader(nil), tooLongContext); !errors.Is(err, ErrContextTooLong) {
+		t.Errorf("expected ErrContextTooLong, got %v", err)
+	}
+	if sig, err := MLDSASignReader(MLDSA65, key.PrivateKey, bytes.NewReader(nil), nil); err == nil || sig != nil {
+		t.Error("expected error for a private key of another parameter set")
+	}
+	if sig, err := MLDSASignReader(MLDSAParameterSet(0), key.PrivateKey, bytes.NewReader(nil), nil); err == nil || sig != nil {
+		t.Error("expected error for unsupported parameter set")
+	}
+	if _, err := ComputeMLDSAMu(MLDSA44, []byte{}, bytes.NewReader(nil), nil); err == nil {
+		t.Error("expected error for empty public key")
+	}
+	if sig, err := MLDSASignMu(MLDSA44, key.PrivateKey, make([]byte, MLDSAMuSize-1)); err == nil || sig != nil {
+		t.Error("expected error for a mu of the wrong size")
+	}
+	if isVerify, err := MLDSAVerifyMu(MLDSA44, key.PublicKey, make([]byte, MLDSAMuSize+1), sig); err == nil || isVerify {
+		t.Error("expected error for a mu of the wrong size")
+	}
+	if isVerify, err := MLDSAVerifyMu(MLDSA44, []byte{}, make([]byte, MLDSAMuSize), sig); err == nil || isVerify {
+		t.Error("expected error for empty public key")
+	}
+
+	// An s1 coefficient packed as 7, outside [-eta, eta] for eta = 2, must be rejected rather than signed with.
+	malformedKey := append([]byte(nil), key.PrivateKey...)
+	malformedKey[128] |= 0x07
+	if sig, err := MLDSASignReader(MLDSA44, malformedKey, bytes.NewReader(nil), nil); !errors.Is(err, ErrInvalidKey) || sig != nil {
+		t.Errorf("expected ErrInvalidKey for out-of-range coefficients, got %v", err)
+	}
+	if sig, err := MLDSASignMu(MLDSA44, malformedKey, make([]byte, MLDSAMuSize)); !errors.Is(err, ErrInvalidKey) || sig != nil {
+		t.Errorf("expected ErrInvalidKey for out-of-range coefficients, got %v", err)
+	}
+	if sig, err := HashMLDSASign(MLDSA44, PreHashSHA256, malformedKey, []byte("message"), nil); !errors.Is(err, ErrInvalidKey) || sig != nil {
+		t.Errorf("expected ErrInvalidKey for out-of-range coefficients, got %v", err)
+	}
+}
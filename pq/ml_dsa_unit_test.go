@@ -1,7 +1,18 @@
 package pq
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha3"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/cloudflare/circl/sign"
 )
 
 func init() {
@@ -19,38 +30,96 @@ func init() {
 
 // For TestGenerateMLDSAKeyPair:
 func TestGenerateMLDSAKeyPair(t *testing.T) {
-	key, err := GenerateMLDSAKeyPair()
-	if err != nil {
-		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
-	}
-	if len(key.PublicKey) == 0 || len(key.PrivateKey) == 0 {
-		t.Error("key pair should not be empty")
+	for _, parameterSet := range MLDSAParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			key, err := GenerateMLDSAKeyPair(parameterSet)
+			if err != nil {
+				t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+			}
+			if len(key.PublicKey) == 0 || len(key.PrivateKey) == 0 {
+				t.Error("key pair should not be empty")
+			}
+			if key.ParameterSet != parameterSet {
+				t.Errorf("key pair parameter set = %v, want %v", key.ParameterSet, parameterSet)
+			}
+		})
 	}
 }
 
-// For TestMLDSASignAndVerify:
-func TestMLDSASignAndVerify(t *testing.T) {
-	key, err := GenerateMLDSAKeyPair()
-	if err != nil {
-		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+// For TestGenerateMLDSAKeyPairWithUnsupportedParameterSet:
+func TestGenerateMLDSAKeyPairWithUnsupportedParameterSet(t *testing.T) {
+	key, err := GenerateMLDSAKeyPair(MLDSAParameterSet(0))
+	if err == nil {
+		t.Error("expected error for unsupported parameter set")
 	}
-	msg := []byte("test message")
-	sig, err := MLDSASign(key.PrivateKey, msg)
-	if err != nil {
-		t.Fatalf("signing failed: %v", err)
+	if key != nil {
+		t.Error("expected nil key pair for unsupported parameter set")
 	}
-	isVerify, err := MLDSAVerify(key.PublicKey, msg, sig)
-	if err != nil {
-		t.Fatalf("verifying failed: %v", err)
+	var seed [MLDSASeedSize]byte
+	key, err = DeriveMLDSAKeyPair(MLDSAParameterSet(0), &seed)
+	if err == nil {
+		t.Error("expected error for unsupported parameter set")
 	}
-	if !isVerify {
-		t.Error("signature should verify")
+	if key != nil {
+		t.Error("expected nil key pair for unsupported parameter set")
+	}
+	if got := MLDSAParameterSet(0).String(); got != "MLDSAParameterSet(0)" {
+		t.Errorf("unexpected name for unsupported parameter set: %q", got)
+	}
+}
+
+// For TestDeriveMLDSAKeyPair:
+func TestDeriveMLDSAKeyPair(t *testing.T) {
+	expectedPublicKeySizes := map[MLDSAParameterSet]int{MLDSA44: 1312, MLDSA65: 1952, MLDSA87: 2592}
+	for _, parameterSet := range MLDSAParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			var seed [MLDSASeedSize]byte
+			seed[0] = 0x01
+			key1, err := DeriveMLDSAKeyPair(parameterSet, &seed)
+			if err != nil {
+				t.Fatalf("failed to derive ML-DSA key pair 1: %v", err)
+			}
+			key2, err := DeriveMLDSAKeyPair(parameterSet, &seed)
+			if err != nil {
+				t.Fatalf("failed to derive ML-DSA key pair 2: %v", err)
+			}
+			if string(key1.PublicKey) != string(key2.PublicKey) || string(key1.PrivateKey) != string(key2.PrivateKey) {
+				t.Error("derived key pairs should be identical for identical seeds")
+			}
+			if len(key1.PublicKey) != expectedPublicKeySizes[parameterSet] {
+				t.Errorf("public key len = %d, want %d", len(key1.PublicKey), expectedPublicKeySizes[parameterSet])
+			}
+		})
+	}
+}
+
+// For TestMLDSASignAndVerify:
+func TestMLDSASignAndVerify(t *testing.T) {
+	for _, parameterSet := range MLDSAParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			key, err := GenerateMLDSAKeyPair(parameterSet)
+			if err != nil {
+				t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+			}
+			msg := []byte("test message")
+			sig, err := MLDSASign(parameterSet, key.PrivateKey, msg)
+			if err != nil {
+				t.Fatalf("signing failed: %v", err)
+			}
+			isVerify, err := MLDSAVerify(parameterSet, key.PublicKey, msg, sig)
+			if err != nil {
+				t.Fatalf("verifying failed: %v", err)
+			}
+			if !isVerify {
+				t.Error("signature should verify")
+			}
+		})
 	}
 }
 
 // For TestMLDSASignWithInvalidKey:
 func TestMLDSASignWithInvalidKey(t *testing.T) {
-	_, err := MLDSASign([]byte{}, []byte("msg"))
+	_, err := MLDSASign(MLDSA87, []byte{}, []byte("msg"))
 	if err == nil {
 		t.Error("expected error for empty private key")
 	}
@@ -58,17 +127,17 @@ func TestMLDSASignWithInvalidKey(t *testing.T) {
 
 // For TestMLDSAVerifyWithInvalidKey:
 func TestMLDSAVerifyWithInvalidKey(t *testing.T) {
-	key, err := GenerateMLDSAKeyPair()
+	key, err := GenerateMLDSAKeyPair(MLDSA87)
 	if err != nil {
 		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
 	}
 	msg := []byte("msg")
-	sig, err := MLDSASign(key.PrivateKey, msg)
+	sig, err := MLDSASign(MLDSA87, key.PrivateKey, msg)
 	if err != nil {
 		t.Fatalf("signing failed: %v", err)
 	}
 	invalidPub := []byte{}
-	isVerify, err := MLDSAVerify(invalidPub, msg, sig)
+	isVerify, err := MLDSAVerify(MLDSA87, invalidPub, msg, sig)
 	if err == nil {
 		t.Error("expected error for invalid public key")
 	}
@@ -77,17 +146,62 @@ func TestMLDSAVerifyWithInvalidKey(t *testing.T) {
 	}
 }
 
+// For TestMLDSAWithMismatchedParameterSet:
+func TestMLDSAWithMismatchedParameterSet(t *testing.T) {
+	keys := make(map[MLDSAParameterSet]*MLDSAKeyPair)
+	for _, parameterSet := range MLDSAParameterSets {
+		key, err := GenerateMLDSAKeyPair(parameterSet)
+		if err != nil {
+			t.Fatalf("failed to generate %v key pair: %v", parameterSet, err)
+		}
+		keys[parameterSet] = key
+	}
+	msg := []byte("msg")
+	for _, keyParameterSet := range MLDSAParameterSets {
+		sig, err := MLDSASign(keyParameterSet, keys[keyParameterSet].PrivateKey, msg)
+		if err != nil {
+			t.Fatalf("signing failed: %v", err)
+		}
+		for _, otherParameterSet := range MLDSAParameterSets {
+			if otherParameterSet == keyParameterSet {
+				continue
+			}
+			otherSig, err := MLDSASign(otherParameterSet, keys[keyParameterSet].PrivateKey, msg)
+			if err == nil {
+				t.Errorf("expected error signing with a %v private key as %v", keyParameterSet, otherParameterSet)
+			}
+			if otherSig != nil {
+				t.Errorf("expected nil signature signing with a %v private key as %v", keyParameterSet, otherParameterSet)
+			}
+			isVerify, err := MLDSAVerify(otherParameterSet, keys[keyParameterSet].PublicKey, msg, sig)
+			if err == nil {
+				t.Errorf("expected error verifying with a %v public key as %v", keyParameterSet, otherParameterSet)
+			}
+			if isVerify {
+				t.Errorf("verify should fail with a %v public key as %v", keyParameterSet, otherParameterSet)
+			}
+			isVerify, err = MLDSAVerify(otherParameterSet, keys[otherParameterSet].PublicKey, msg, sig)
+			if err != nil {
+				t.Fatalf("verifying failed: %v", err)
+			}
+			if isVerify {
+				t.Errorf("verify should fail for a %v signature under a %v public key", keyParameterSet, otherParameterSet)
+			}
+		}
+	}
+}
+
 func TestMLDSAVerifyWithTamperedSignature(t *testing.T) {
-	key, err := GenerateMLDSAKeyPair()
+	key, err := GenerateMLDSAKeyPair(MLDSA87)
 	if err != nil {
 		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
 	}
 	msg := []byte("msg")
-	sig, err := MLDSASign(key.PrivateKey, msg)
+	sig, err := MLDSASign(MLDSA87, key.PrivateKey, msg)
 	if err != nil {
 		t.Fatalf("signing failed: %v", err)
 	}
-	isVerify, err := MLDSAVerify(key.PublicKey, msg, sig)
+	isVerify, err := MLDSAVerify(MLDSA87, key.PublicKey, msg, sig)
 	if !isVerify {
 		t.Error("verify should succeed for original signature")
 	}
@@ -97,7 +211,7 @@ func TestMLDSAVerifyWithTamperedSignature(t *testing.T) {
 	if len(sig) > 0 {
 		sig[0] ^= 0xFF // tamper signature
 	}
-	isVerify, err = MLDSAVerify(key.PublicKey, msg, sig)
+	isVerify, err = MLDSAVerify(MLDSA87, key.PublicKey, msg, sig)
 	if isVerify {
 		t.Error("verify should fail with tampered signature")
 	}
@@ -105,3 +219,313 @@ func TestMLDSAVerifyWithTamperedSignature(t *testing.T) {
 		t.Fatalf("verifying failed: %v", err)
 	}
 }
+
+// For TestMLDSASignAndVerifyWithContext:
+func TestMLDSASignAndVerifyWithContext(t *testing.T) {
+	for _, parameterSet := range MLDSAParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			key, err := GenerateMLDSAKeyPair(parameterSet)
+			if err != nil {
+				t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+			}
+			msg := []byte("test message")
+			firmwareContext := []byte("firmware-manifest-v1")
+			tokenContext := []byte("api-token-v1")
+			sig, err := MLDSASignWithContext(parameterSet, key.PrivateKey, msg, firmwareContext)
+			if err != nil {
+				t.Fatalf("signing failed: %v", err)
+			}
+			isVerify, err := MLDSAVerifyWithContext(parameterSet, key.PublicKey, msg, sig, firmwareContext)
+			if err != nil {
+				t.Fatalf("verifying failed: %v", err)
+			}
+			if !isVerify {
+				t.Error("signature should verify under the signing context")
+			}
+			isVerify, err = MLDSAVerifyWithContext(parameterSet, key.PublicKey, msg, sig, tokenContext)
+			if err != nil {
+				t.Fatalf("verifying failed: %v", err)
+			}
+			if isVerify {
+				t.Error("signature should not verify under a different context")
+			}
+			isVerify, err = MLDSAVerify(parameterSet, key.PublicKey, msg, sig)
+			if err != nil {
+				t.Fatalf("verifying failed: %v", err)
+			}
+			if isVerify {
+				t.Error("signature should not verify under the empty context")
+			}
+		})
+	}
+}
+
+// For TestMLDSAEmptyContextMatchesPlainSign:
+func TestMLDSAEmptyContextMatchesPlainSign(t *testing.T) {
+	key, err := GenerateMLDSAKeyPair(MLDSA65)
+	if err != nil {
+		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+	}
+	msg := []byte("test message")
+	sig, err := MLDSASign(MLDSA65, key.PrivateKey, msg)
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	for _, emptyContext := range [][]byte{nil, {}} {
+		isVerify, err := MLDSAVerifyWithContext(MLDSA65, key.PublicKey, msg, sig, emptyContext)
+		if err != nil {
+			t.Fatalf("verifying failed: %v", err)
+		}
+		if !isVerify {
+			t.Error("signature made without a context should verify under an empty context")
+		}
+	}
+}
+
+// For TestMLDSAContextTooLong:
+func TestMLDSAContextTooLong(t *testing.T) {
+	key, err := GenerateMLDSAKeyPair(MLDSA44)
+	if err != nil {
+		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+	}
+	msg := []byte("test message")
+	maximumContext := make([]byte, MLDSAMaxContextSize)
+	sig, err := MLDSASignWithContext(MLDSA44, key.PrivateKey, msg, maximumContext)
+	if err != nil {
+		t.Fatalf("signing with a %d byte context failed: %v", MLDSAMaxContextSize, err)
+	}
+	isVerify, err := MLDSAVerifyWithContext(MLDSA44, key.PublicKey, msg, sig, maximumContext)
+	if err != nil || !isVerify {
+		t.Fatalf("verifying with a %d byte context failed: %v", MLDSAMaxContextSize, err)
+	}
+
+	tooLongContext := make([]byte, MLDSAMaxContextSize+1)
+	sig, err = MLDSASignWithContext(MLDSA44, key.PrivateKey, msg, tooLongContext)
+	if !errors.Is(err, ErrContextTooLong) {
+		t.Errorf("expected ErrContextTooLong signing with a %d byte context, got %v", len(tooLongContext), err)
+	}
+	if sig != nil {
+		t.Error("expected nil signature for a context that is too long")
+	}
+	isVerify, err = MLDSAVerifyWithContext(MLDSA44, key.PublicKey, msg, make([]byte, 2420), tooLongContext)
+	if !errors.Is(err, ErrContextTooLong) {
+		t.Errorf("expected ErrContextTooLong verifying with a %d byte context, got %v", len(tooLongContext), err)
+	}
+	if isVerify {
+		t.Error("verify should fail for a context that is too long")
+	}
+}
+
+// For TestMLDSASignDeterministic:
+func TestMLDSASignDeterministic(t *testing.T) {
+	for _, parameterSet := range MLDSAParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			var seed [MLDSASeedSize]byte
+			key, err := DeriveMLDSAKeyPair(parameterSet, &seed)
+			if err != nil {
+				t.Fatalf("failed to derive ML-DSA key pair: %v", err)
+			}
+			msg := []byte("fixture message")
+			options := &MLDSASignOptions{Context: []byte("fixtures"), Deterministic: true}
+			sig1, err := MLDSASignWithOptions(parameterSet, key.PrivateKey, msg, options)
+			if err != nil {
+				t.Fatalf("signing failed: %v", err)
+			}
+			sig2, err := MLDSASignWithOptions(parameterSet, key.PrivateKey, msg, options)
+			if err != nil {
+				t.Fatalf("signing failed: %v", err)
+			}
+			if !bytes.Equal(sig1, sig2) {
+				t.Error("deterministic signatures should be identical")
+			}
+			// CIRCL's Sign implements the deterministic variant, so both must agree byte for byte.
+			scheme, _ := parameterSet.scheme()
+			circlPrivateKey, err := scheme.UnmarshalBinaryPrivateKey(key.PrivateKey)
+			if err != nil {
+				t.Fatalf("failed to unmarshal private key: %v", err)
+			}
+			if !bytes.Equal(sig1, scheme.Sign(circlPrivateKey, msg, &sign.SignatureOpts{Context: "fixtures"})) {
+				t.Error("deterministic signature should match CIRCL")
+			}
+			isVerify, err := MLDSAVerifyWithContext(parameterSet, key.PublicKey, msg, sig1, options.Context)
+			if err != nil {
+				t.Fatalf("verifying failed: %v", err)
+			}
+			if !isVerify {
+				t.Error("deterministic signature should verify")
+			}
+		})
+	}
+}
+
+// For TestMLDSASignHedgedByDefault:
+func TestMLDSASignHedgedByDefault(t *testing.T) {
+	key, err := GenerateMLDSAKeyPair(MLDSA65)
+	if err != nil {
+		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+	}
+	msg := []byte("test message")
+	sig1, err := MLDSASign(MLDSA65, key.PrivateKey, msg)
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	sig2, err := MLDSASignWithOptions(MLDSA65, key.PrivateKey, msg, &MLDSASignOptions{})
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if bytes.Equal(sig1, sig2) {
+		t.Error("hedged signatures should differ")
+	}
+	for _, sig := range [][]byte{sig1, sig2} {
+		isVerify, err := MLDSAVerify(MLDSA65, key.PublicKey, msg, sig)
+		if err != nil {
+			t.Fatalf("verifying failed: %v", err)
+		}
+		if !isVerify {
+			t.Error("hedged signature should verify")
+		}
+	}
+}
+
+// For TestMLDSASignWithRand:
+func TestMLDSASignWithRand(t *testing.T) {
+	key, err := GenerateMLDSAKeyPair(MLDSA44)
+	if err != nil {
+		t.Fatalf("failed to generate ML-DSA key pair: %v", err)
+	}
+	msg := []byte("test message")
+	rnd := bytes.Repeat([]byte{0xA5}, 32)
+	drbg := bytes.NewReader(append(append([]byte(nil), rnd...), rnd...))
+	sig1, err := MLDSASignWithOptions(MLDSA44, key.PrivateKey, msg, &MLDSASignOptions{Rand: drbg})
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if drbg.Len() != 32 {
+		t.Errorf("signing consumed %d bytes of randomness, want 32", 64-drbg.Len())
+	}
+	sig2, err := MLDSASignWithOptions(MLDSA44, key.PrivateKey, msg, &MLDSASignOptions{Rand: drbg})
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if !bytes.Equal(sig1, sig2) {
+		t.Error("signatures with the same randomness should be identical")
+	}
+	deterministicSig, err := MLDSASignWithOptions(MLDSA44, key.PrivateKey, msg, &MLDSASignOptions{Deterministic: true})
+	if err != nil {
+		t.Fatalf("signing failed: %v", err)
+	}
+	if bytes.Equal(sig1, deterministicSig) {
+		t.Error("signature with supplied randomness should differ from the deterministic signature")
+	}
+	isVerify, err := MLDSAVerify(MLDSA44, key.PublicKey, msg, sig1)
+	if err != nil {
+		t.Fatalf("verifying failed: %v", err)
+	}
+	if !isVerify {
+		t.Error("signature with supplied randomness should verify")
+	}
+
+	sig, err := MLDSASignWithOptions(MLDSA44, key.PrivateKey, msg, &MLDSASignOptions{Rand: bytes.NewReader(rnd[:31])})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF for short randomness, got %v", err)
+	}
+	if sig != nil {
+		t.Error("expected nil signature for short randomness")
+	}
+	sig, err = MLDSASignWithOptions(MLDSA44, key.PrivateKey, msg, &MLDSASignOptions{Deterministic: true, Rand: bytes.NewReader(rnd)})
+	if err == nil {
+		t.Error("expected error for Deterministic with Rand")
+	}
+	if sig != nil {
+		t.Error("expected nil signature for Deterministic with Rand")
+	}
+}
+
+// mldsaACVPSigGen is the NIST ACVP ML-DSA sigGen vector set (FIPS 204, ML-DSA.Sign_internal) stored gzipped in
+// testdata/fips204, as published with CIRCL: the prompt and the expected results are joined by tcId.
+type mldsaACVPSigGen struct {
+	TestGroups []struct {
+		TgID          int    `json:"tgId"`
+		ParameterSet  string `json:"parameterSet"`
+		Deterministic bool   `json:"deterministic"`
+		Tests         []struct {
+			TcID      int    `json:"tcId"`
+			Sk        string `json:"sk"`
+			Message   string `json:"message"`
+			Rnd       string `json:"rnd"`
+			Signature string `json:"signature"`
+		} `json:"tests"`
+	} `json:"testGroups"`
+}
+
+// readMLDSAACVPFile decodes one gzipped ACVP JSON file of testdata/fips204 into vectors.
+func readMLDSAACVPFile(t *testing.T, name string, vectors *mldsaACVPSigGen) {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", "fips204", name))
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", name, err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("failed to decompress %s: %v", name, err)
+	}
+	if err := json.NewDecoder(reader).Decode(vectors); err != nil {
+		t.Fatalf("failed to decode %s: %v", name, err)
+	}
+}
+
+// For TestMLDSAACVPSigGen: every ACVP sigGen vector of the three parameter sets, deterministic and with the given rnd,
+// is reproduced through the external mu entry point. Sign_internal signs M' directly, so mu = H(tr || M', 64).
+func TestMLDSAACVPSigGen(t *testing.T) {
+	var prompt, results mldsaACVPSigGen
+	readMLDSAACVPFile(t, "acvp-sigGen-prompt.json.gz", &prompt)
+	readMLDSAACVPFile(t, "acvp-sigGen-expectedResults.json.gz", &results)
+	signatures := make(map[int]string)
+	for _, group := range results.TestGroups {
+		for _, test := range group.Tests {
+			signatures[test.TcID] = test.Signature
+		}
+	}
+	parameterSets := map[string]MLDSAParameterSet{"ML-DSA-44": MLDSA44, "ML-DSA-65": MLDSA65, "ML-DSA-87": MLDSA87}
+	tested := make(map[MLDSAParameterSet]int)
+	for _, group := range prompt.TestGroups {
+		parameterSet, ok := parameterSets[group.ParameterSet]
+		if !ok {
+			t.Fatalf("unknown parameter set %q", group.ParameterSet)
+		}
+		for _, test := range group.Tests {
+			privateKey, _ := hex.DecodeString(test.Sk)
+			message, _ := hex.DecodeString(test.Message)
+			expected, _ := hex.DecodeString(signatures[test.TcID])
+			options := &MLDSASignOptions{Deterministic: true}
+			if !group.Deterministic {
+				rnd, _ := hex.DecodeString(test.Rnd)
+				options = &MLDSASignOptions{Rand: bytes.NewReader(rnd)}
+			}
+			// tr is the third field of the private key, after rho and K.
+			mu := sha3.SumSHAKE256(append(privateKey[64:128:128], message...), MLDSAMuSize)
+			signature, err := MLDSASignMuWithOptions(parameterSet, privateKey, mu, options)
+			if err != nil {
+				t.Fatalf("tcId %d: signing failed: %v", test.TcID, err)
+			}
+			if !bytes.Equal(signature, expected) {
+				t.Errorf("tcId %d: signature does not match the ACVP result", test.TcID)
+			}
+			publicKey, err := mldsaPublicKeyFromPrivateKey(parameterSet, privateKey)
+			if err != nil {
+				t.Fatalf("tcId %d: failed to derive the public key: %v", test.TcID, err)
+			}
+			isVerify, err := MLDSAVerifyMu(parameterSet, publicKey, mu, expected)
+			if err != nil || !isVerify {
+				t.Errorf("tcId %d: ACVP signature should verify, got %v, %v", test.TcID, isVerify, err)
+			}
+			tested[parameterSet]++
+		}
+	}
+	for _, parameterSet := range MLDSAParameterSets {
+		if tested[parameterSet] == 0 {
+			t.Errorf("no ACVP sigGen vectors for %v", parameterSet)
+		}
+	}
+}
@@ -2,159 +2,274 @@
 package pq
 
 import (
-	"errors"
-	"runtime/debug"
+	"crypto/rand"
+	"encoding/asn1"
+	"fmt"
 
 	"github.com/cloudflare/circl/kem"
 	"github.com/cloudflare/circl/kem/kyber/kyber1024"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem1024"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem512"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
 )
 
-// MLKEMKeyPair represents a key pair for Kyber1024 KEM.
-type MLKEMKeyPair struct {
-	PublicKey  kem.PublicKey
-	PrivateKey kem.PrivateKey
+// MLKEMParameterSet identifies an ML-KEM parameter set from FIPS 203, or the legacy round-3 Kyber1024 scheme.
+type MLKEMParameterSet int
+
+const (
+	// MLKEM512 is the FIPS 203 ML-KEM-512 parameter set (NIST security category 1).
+	MLKEM512 MLKEMParameterSet = iota + 1
+	// MLKEM768 is the FIPS 203 ML-KEM-768 parameter set (NIST security category 3).
+	MLKEM768
+	// MLKEM1024 is the FIPS 203 ML-KEM-1024 parameter set (NIST security category 5).
+	MLKEM1024
+	// LegacyKyber1024 is the pre-standard round-3 Kyber1024 scheme.
+	// It is NOT compatible with FIPS 203 ML-KEM, and is only provided to decrypt data produced by earlier versions of this package.
+	LegacyKyber1024
+)
+
+const (
+	// MLKEMSeedSize is the size in bytes of the seed (d || z) used for deterministic key generation, for all parameter sets.
+	MLKEMSeedSize = 64
+	// MLKEMEncapsulationSeedSize is the size in bytes of the seed (m) used for deterministic encapsulation, for all parameter sets.
+	MLKEMEncapsulationSeedSize = 32
+	// MLKEMSharedSecretSize is the size in bytes of the shared secret, for all parameter sets.
+	MLKEMSharedSecretSize = 32
+)
+
+// MLKEMParameterSets lists the FIPS 203 ML-KEM parameter sets. LegacyKyber1024 is deliberately excluded.
+var MLKEMParameterSets = []MLKEMParameterSet{MLKEM512, MLKEM768, MLKEM1024}
+
+// String returns the name of the parameter set, for example "ML-KEM-768".
+func (parameterSet MLKEMParameterSet) String() string {
+	switch parameterSet {
+	case MLKEM512:
+		return "ML-KEM-512"
+	case MLKEM768:
+		return "ML-KEM-768"
+	case MLKEM1024:
+		return "ML-KEM-1024"
+	case LegacyKyber1024:
+		return "Kyber1024 (legacy)"
+	default:
+		return fmt.Sprintf("MLKEMParameterSet(%d)", int(parameterSet))
+	}
 }
 
-// GenerateDeterministicMLKEMKeyPair generates a Kyber1024 KEM key pair from a seed (for KATs).
-// The seed must be of length kyber1024.Scheme().SeedSize().
-func GenerateDeterministicMLKEMKeyPair(seed []byte) (*MLKEMKeyPair, error) {
-	defer func() {
-		if r := recover(); r != nil {
-			println("panic in GenerateDeterministicMLKEMKeyPair:", r)
-			debug.PrintStack()
-		}
-	}()
-	if len(seed) != kyber1024.Scheme().SeedSize() {
-		return nil, errors.New("invalid seed size")
+// scheme returns the CIRCL KEM scheme implementing the parameter set.
+func (parameterSet MLKEMParameterSet) scheme() (kem.Scheme, error) {
+	switch parameterSet {
+	case MLKEM512:
+		return mlkem512.Scheme(), nil
+	case MLKEM768:
+		return mlkem768.Scheme(), nil
+	case MLKEM1024:
+		return mlkem1024.Scheme(), nil
+	case LegacyKyber1024:
+		return kyber1024.Scheme(), nil
+	default:
+		return nil, fmt.Errorf("%w: ML-KEM parameter set %v", ErrUnknownAlgorithm, parameterSet)
+	}
+}
+
+// OID returns the NIST object identifier of the parameter set (2.16.840.1.101.3.4.4.1-3), used in SubjectPublicKeyInfo and PKCS#8.
+// LegacyKyber1024 has no standard OID.
+func (parameterSet MLKEMParameterSet) OID() (asn1.ObjectIdentifier, error) {
+	switch parameterSet {
+	case MLKEM512:
+		return asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 1}, nil
+	case MLKEM768:
+		return asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 2}, nil
+	case MLKEM1024:
+		return asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 4, 3}, nil
+	default:
+		return nil, fmt.Errorf("%w: no object identifier for ML-KEM parameter set %v", ErrUnknownAlgorithm, parameterSet)
 	}
-	publicKey, privateKey := kyber1024.Scheme().DeriveKeyPair(seed)
-	return &MLKEMKeyPair{
-		PublicKey:  publicKey,
-		PrivateKey: privateKey,
-	}, nil
 }
 
-// GenerateMLKEMKeyPair generates a new Kyber1024 KEM key pair.
-func GenerateMLKEMKeyPair() (*MLKEMKeyPair, error) {
+// mlkemParameterSetOf returns the parameter set of a CIRCL KEM scheme, rejecting schemes not supported by this package.
+func mlkemParameterSetOf(scheme kem.Scheme) (MLKEMParameterSet, error) {
+	switch scheme {
+	case mlkem512.Scheme():
+		return MLKEM512, nil
+	case mlkem768.Scheme():
+		return MLKEM768, nil
+	case mlkem1024.Scheme():
+		return MLKEM1024, nil
+	case kyber1024.Scheme():
+		return LegacyKyber1024, nil
+	default:
+		return 0, fmt.Errorf("%w: KEM scheme is not ML-KEM", ErrUnknownAlgorithm)
+	}
+}
+
+// MLKEMKeyPair represents an ML-KEM key pair, and the parameter set it belongs to.
+// Seed is the 64-byte seed (d || z) the key pair was derived from, or nil if only the expanded private key is known.
+type MLKEMKeyPair struct {
+	ParameterSet MLKEMParameterSet
+	PublicKey    kem.PublicKey
+	PrivateKey   kem.PrivateKey
+	Seed         []byte
+}
+
+// GenerateDeterministicMLKEMKeyPair generates an ML-KEM key pair from a seed (for KATs).
+// The seed must be of length MLKEMSeedSize, and is the concatenation d || z from FIPS 203 Algorithm 16 (ML-KEM.KeyGen_internal).
+func GenerateDeterministicMLKEMKeyPair(parameterSet MLKEMParameterSet, seed []byte) (keyPair *MLKEMKeyPair, keyGenerationError error) {
 	defer func() {
-		if r := recover(); r != nil {
-			println("panic in GenerateMLKEMKeyPair:", r)
-			debug.PrintStack()
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			keyPair, keyGenerationError = nil, newPanicError("GenerateDeterministicMLKEMKeyPair", recoveredPanic)
 		}
 	}()
-	publicKey, privateKey, err := kyber1024.Scheme().GenerateKeyPair()
+	scheme, err := parameterSet.scheme()
 	if err != nil {
 		return nil, err
 	}
+	if len(seed) != scheme.SeedSize() {
+		return nil, newSizeError(ErrInvalidSeedSize, parameterSet.String()+" seed", len(seed), scheme.SeedSize())
+	}
+	publicKey, privateKey := scheme.DeriveKeyPair(seed)
 	return &MLKEMKeyPair{
-		PublicKey:  publicKey,
-		PrivateKey: privateKey,
+		ParameterSet: parameterSet,
+		PublicKey:    publicKey,
+		PrivateKey:   privateKey,
+		Seed:         append([]byte(nil), seed...),
 	}, nil
 }
 
-// MarshalPublicKey serializes a Kyber1024 public key to bytes.
-func MarshalPublicKey(pk kem.PublicKey) ([]byte, error) {
+// GenerateMLKEMKeyPair generates a new ML-KEM key pair for the given parameter set from a fresh random seed.
+func GenerateMLKEMKeyPair(parameterSet MLKEMParameterSet) (*MLKEMKeyPair, error) {
+	seed := make([]byte, MLKEMSeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("ML-KEM key generation: %w", err)
+	}
+	return GenerateDeterministicMLKEMKeyPair(parameterSet, seed)
+}
+
+// MarshalPublicKey serializes an ML-KEM public key (encapsulation key) to bytes.
+func MarshalPublicKey(pk kem.PublicKey) (publicKeyBytes []byte, marshalError error) {
 	defer func() {
-		if r := recover(); r != nil {
-			println("panic in MarshalPublicKey:", r)
-			debug.PrintStack()
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			publicKeyBytes, marshalError = nil, newPanicError("MarshalPublicKey", recoveredPanic)
 		}
 	}()
+	if pk == nil {
+		return nil, fmt.Errorf("%w: ML-KEM public key is nil", ErrInvalidKey)
+	}
 	return pk.MarshalBinary()
 }
 
-// UnmarshalPublicKey deserializes bytes into a Kyber1024 public key.
-func UnmarshalPublicKey(data []byte) (kem.PublicKey, error) {
+// UnmarshalPublicKey deserializes bytes into an ML-KEM public key (encapsulation key) of the given parameter set.
+// The key is checked with ValidateEncapsulationKey first.
+func UnmarshalPublicKey(parameterSet MLKEMParameterSet, data []byte) (publicKey kem.PublicKey, unmarshalError error) {
 	defer func() {
-		if r := recover(); r != nil {
-			println("panic in UnmarshalPublicKey:", r)
-			debug.PrintStack()
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			publicKey, unmarshalError = nil, newPanicError("UnmarshalPublicKey", recoveredPanic)
 		}
 	}()
-	return kyber1024.Scheme().UnmarshalBinaryPublicKey(data)
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return nil, err
+	}
+	if err = ValidateEncapsulationKey(parameterSet, data); err != nil {
+		return nil, err
+	}
+	return scheme.UnmarshalBinaryPublicKey(data)
 }
 
-// MarshalPrivateKey serializes a Kyber1024 private key to bytes.
-func MarshalPrivateKey(sk kem.PrivateKey) ([]byte, error) {
+// MarshalPrivateKey serializes an ML-KEM private key (decapsulation key) to bytes.
+func MarshalPrivateKey(sk kem.PrivateKey) (privateKeyBytes []byte, marshalError error) {
 	defer func() {
-		if r := recover(); r != nil {
-			println("panic in MarshalPrivateKey:", r)
-			debug.PrintStack()
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			privateKeyBytes, marshalError = nil, newPanicError("MarshalPrivateKey", recoveredPanic)
 		}
 	}()
+	if sk == nil {
+		return nil, fmt.Errorf("%w: ML-KEM private key is nil", ErrInvalidKey)
+	}
 	return sk.MarshalBinary()
 }
 
-// UnmarshalPrivateKey deserializes bytes into a Kyber1024 private key.
-func UnmarshalPrivateKey(data []byte) (kem.PrivateKey, error) {
+// UnmarshalPrivateKey deserializes bytes into an ML-KEM private key (decapsulation key) of the given parameter set.
+// The key is checked with ValidateDecapsulationKey first.
+func UnmarshalPrivateKey(parameterSet MLKEMParameterSet, data []byte) (privateKey kem.PrivateKey, unmarshalError error) {
 	defer func() {
-		if r := recover(); r != nil {
-			println("panic in UnmarshalPrivateKey:", r)
-			debug.PrintStack()
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			privateKey, unmarshalError = nil, newPanicError("UnmarshalPrivateKey", recoveredPanic)
 		}
 	}()
-	return kyber1024.Scheme().UnmarshalBinaryPrivateKey(data)
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return nil, err
+	}
+	if err = ValidateDecapsulationKey(parameterSet, data); err != nil {
+		return nil, err
+	}
+	return scheme.UnmarshalBinaryPrivateKey(data)
 }
 
-// MLKEMEncapsulate encapsulates a shared secret using the Kyber1024 public key.
+// MLKEMEncapsulate encapsulates a shared secret using the ML-KEM public key.
+// The parameter set is taken from the public key.
 func MLKEMEncapsulate(publicKey kem.PublicKey) (ciphertext []byte, sharedSecret []byte, err error) {
 	defer func() {
-		if r := recover(); r != nil {
-			println("panic in MLKEMEncapsulate:", r)
-			debug.PrintStack()
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			ciphertext, sharedSecret, err = nil, nil, newPanicError("MLKEMEncapsulate", recoveredPanic)
 		}
 	}()
 	if publicKey == nil {
-		return nil, nil, errors.New("invalid public key")
+		return nil, nil, fmt.Errorf("%w: ML-KEM public key is nil", ErrInvalidKey)
+	}
+	if _, err = mlkemParameterSetOf(publicKey.Scheme()); err != nil {
+		return nil, nil, err
 	}
-	ciphertext, sharedSecret, err = kyber1024.Scheme().Encapsulate(publicKey)
+	ciphertext, sharedSecret, err = publicKey.Scheme().Encapsulate(publicKey)
 	return ciphertext, sharedSecret, err
 }
 
-// MLKEMDecapsulate decapsulates a shared secret using the Kyber1024 private key.
-func MLKEMDecapsulate(privateKey kem.PrivateKey, ciphertext []byte) ([]byte, error) {
+// MLKEMDecapsulate decapsulates a shared secret using the ML-KEM private key.
+// The parameter set is taken from the private key. A ciphertext of the wrong size returns an error matching ErrInvalidCiphertext.
+//
+// Decapsulation follows the implicit rejection of FIPS 203 Algorithm 18 (ML-KEM.Decaps_internal): a ciphertext of the right
+// size that was tampered with, or encapsulated to another key, is not an error. It yields the pseudorandom rejection key
+// J(z || c) instead, selected in constant time, so neither the result nor the timing reveals whether the ciphertext was valid.
+// Only the public lengths of the key and ciphertext are ever branched on.
+func MLKEMDecapsulate(privateKey kem.PrivateKey, ciphertext []byte) (sharedSecret []byte, err error) {
 	defer func() {
-		if r := recover(); r != nil {
-			println("panic in MLKEMDecapsulate:", r)
-			debug.PrintStack()
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			sharedSecret, err = nil, newPanicError("MLKEMDecapsulate", recoveredPanic)
 		}
 	}()
-	if privateKey == nil || len(ciphertext) == 0 {
-		return nil, errors.New("invalid input")
-	}
-	sharedSecret, err := kyber1024.Scheme().Decapsulate(privateKey, ciphertext)
-	if err != nil || sharedSecret == nil || len(sharedSecret) == 0 {
-		return nil, errors.New("decapsulation failed")
-	}
-	// Optionally, check for all-zero shared secret (could indicate failure)
-	allZero := true
-	for _, b := range sharedSecret {
-		if b != 0 {
-			allZero = false
-			break
-		}
+	if privateKey == nil {
+		return nil, fmt.Errorf("%w: ML-KEM private key is nil", ErrInvalidKey)
 	}
-	if allZero {
-		return nil, errors.New("decapsulation failed: all-zero shared secret")
+	parameterSet, err := mlkemParameterSetOf(privateKey.Scheme())
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) != privateKey.Scheme().CiphertextSize() {
+		return nil, newSizeError(ErrInvalidCiphertext, parameterSet.String()+" ciphertext", len(ciphertext), privateKey.Scheme().CiphertextSize())
 	}
-	return sharedSecret, nil
+	// CIRCL only fails on a key of another scheme or a ciphertext of the wrong size, both checked above on public data.
+	return privateKey.Scheme().Decapsulate(privateKey, ciphertext)
 }
 
-// MLKEMEncapsulateDeterministic encapsulates a shared secret using the Kyber1024 public key and a seed (for KATs).
-// The seed must be of length kyber1024.Scheme().EncapsulationSeedSize().
+// MLKEMEncapsulateDeterministic encapsulates a shared secret using the ML-KEM public key and a seed (for KATs).
+// The seed must be of length MLKEMEncapsulationSeedSize, and is the message m from FIPS 203 Algorithm 17 (ML-KEM.Encaps_internal).
 func MLKEMEncapsulateDeterministic(publicKey kem.PublicKey, seed []byte) (ciphertext []byte, sharedSecret []byte, err error) {
 	defer func() {
-		if r := recover(); r != nil {
-			println("panic in MLKEMEncapsulateDeterministic:", r)
-			debug.PrintStack()
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			ciphertext, sharedSecret, err = nil, nil, newPanicError("MLKEMEncapsulateDeterministic", recoveredPanic)
 		}
 	}()
 	if publicKey == nil {
-		return nil, nil, errors.New("invalid public key")
+		return nil, nil, fmt.Errorf("%w: ML-KEM public key is nil", ErrInvalidKey)
+	}
+	parameterSet, err := mlkemParameterSetOf(publicKey.Scheme())
+	if err != nil {
+		return nil, nil, err
 	}
-	if len(seed) != kyber1024.Scheme().EncapsulationSeedSize() {
-		return nil, nil, errors.New("invalid encapsulation seed size")
+	if len(seed) != publicKey.Scheme().EncapsulationSeedSize() {
+		return nil, nil, newSizeError(ErrInvalidSeedSize, parameterSet.String()+" encapsulation seed", len(seed), publicKey.Scheme().EncapsulationSeedSize())
 	}
-	ciphertext, sharedSecret, err = kyber1024.Scheme().EncapsulateDeterministically(publicKey, seed)
+	ciphertext, sharedSecret, err = publicKey.Scheme().EncapsulateDeterministically(publicKey, seed)
 	return ciphertext, sharedSecret, err
 }
@@ -3,7 +3,7 @@ package pq
 import "testing"
 
 func FuzzMLKEMEncapsulateAndDecapsulate(f *testing.F) {
-	key, _ := GenerateMLKEMKeyPair()
+	key, _ := GenerateMLKEMKeyPair(MLKEM768)
 	pubBytes, _ := MarshalPublicKey(key.PublicKey)
 	privBytes, _ := MarshalPrivateKey(key.PrivateKey)
 	f.Fuzz(func(t *testing.T, msg []byte) {
@@ -1,55 +1,188 @@
 package pq
 
 import (
+	"bytes"
+	"crypto/sha3"
 	"crypto/subtle"
 	"testing"
 
+	"github.com/cloudflare/circl/kem"
 	"github.com/stretchr/testify/require"
 )
 
+// allMLKEMParameterSets lists every parameter set supported by this package, including the legacy Kyber1024 mode.
+var allMLKEMParameterSets = []MLKEMParameterSet{MLKEM512, MLKEM768, MLKEM1024, LegacyKyber1024}
+
 func TestMarshallUnmarshallMLKEMKeyPair(t *testing.T) {
-	keyPair, err := GenerateMLKEMKeyPair()
+	for _, parameterSet := range allMLKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			keyPair, err := GenerateMLKEMKeyPair(parameterSet)
+			require.NoError(t, err, "failed to generate ML-KEM key pair")
+			require.NotNil(t, keyPair, "expected non-nil key pair")
+			require.Equal(t, parameterSet, keyPair.ParameterSet, "expected key pair to carry its parameter set")
+			require.NotNil(t, keyPair.PublicKey, "expected non-nil public key")
+			require.NotNil(t, keyPair.PrivateKey, "expected non-nil private key")
+
+			publicKeyBytes, err := MarshalPublicKey(keyPair.PublicKey)
+			require.NoError(t, err, "failed to marshal public key")
+			require.NotEmpty(t, publicKeyBytes, "expected non-empty public key bytes")
+
+			privateKeyBytes, err := MarshalPrivateKey(keyPair.PrivateKey)
+			require.NoError(t, err, "failed to marshal private key")
+			require.NotEmpty(t, privateKeyBytes, "expected non-empty private key bytes")
+
+			publicKey, err := UnmarshalPublicKey(parameterSet, publicKeyBytes)
+			require.NoError(t, err, "failed to unmarshall public key")
+			require.NotNil(t, publicKey, "expected non-nil public key")
+			require.True(t, publicKey.Equal(keyPair.PublicKey), "expected unmarshalled public key to match the original public key")
+
+			privateKey, err := UnmarshalPrivateKey(parameterSet, privateKeyBytes)
+			require.NoError(t, err, "failed to unmarshall private key")
+			require.NotNil(t, privateKey, "expected non-nil private key")
+			require.True(t, privateKey.Equal(keyPair.PrivateKey), "expected unmarshalled private key to match the original private key")
+		})
+	}
+}
+
+func TestUnmarshalMLKEMKeyWithWrongParameterSet(t *testing.T) {
+	keyPair, err := GenerateMLKEMKeyPair(MLKEM768)
 	require.NoError(t, err, "failed to generate ML-KEM key pair")
 	require.NotNil(t, keyPair, "expected non-nil key pair")
-	require.NotNil(t, keyPair.PublicKey, "expected non-nil public key")
-	require.NotNil(t, keyPair.PrivateKey, "expected non-nil private key")
 
 	publicKeyBytes, err := MarshalPublicKey(keyPair.PublicKey)
 	require.NoError(t, err, "failed to marshal public key")
-	require.NotEmpty(t, publicKeyBytes, "expected non-empty public key bytes")
-
 	privateKeyBytes, err := MarshalPrivateKey(keyPair.PrivateKey)
 	require.NoError(t, err, "failed to marshal private key")
-	require.NotEmpty(t, publicKeyBytes, "expected non-empty private key bytes")
 
-	publicKey, err := UnmarshalPublicKey(publicKeyBytes)
-	require.NoError(t, err, "failed to unmarshall public key")
-	require.NotNil(t, keyPair.PublicKey, "expected non-nil public key")
-	require.True(t, publicKey.Equal(keyPair.PublicKey), "expected unmarshalled public key to match the original public key")
+	for _, parameterSet := range []MLKEMParameterSet{MLKEM512, MLKEM1024, LegacyKyber1024} {
+		publicKey, err := UnmarshalPublicKey(parameterSet, publicKeyBytes)
+		require.Error(t, err, "expected error for ML-KEM-768 public key unmarshalled as %v", parameterSet)
+		require.Nil(t, publicKey, "expected nil public key for wrong parameter set %v", parameterSet)
 
-	privateKey, err := UnmarshalPrivateKey(privateKeyBytes)
-	require.NoError(t, err, "failed to unmarshall private key")
-	require.NotNil(t, keyPair.PrivateKey, "expected non-nil private key")
-	require.True(t, privateKey.Equal(keyPair.PrivateKey), "expected unmarshalled private key to match the original private key")
+		privateKey, err := UnmarshalPrivateKey(parameterSet, privateKeyBytes)
+		require.Error(t, err, "expected error for ML-KEM-768 private key unmarshalled as %v", parameterSet)
+		require.Nil(t, privateKey, "expected nil private key for wrong parameter set %v", parameterSet)
+	}
+}
+
+func TestMLKEMWithUnsupportedParameterSet(t *testing.T) {
+	unsupported := MLKEMParameterSet(0)
+
+	keyPair, err := GenerateMLKEMKeyPair(unsupported)
+	require.Error(t, err, "expected error for unsupported parameter set")
+	require.Nil(t, keyPair, "expected nil key pair for unsupported parameter set")
+
+	keyPair, err = GenerateDeterministicMLKEMKeyPair(unsupported, make([]byte, MLKEMSeedSize))
+	require.Error(t, err, "expected error for unsupported parameter set")
+	require.Nil(t, keyPair, "expected nil key pair for unsupported parameter set")
+
+	publicKey, err := UnmarshalPublicKey(unsupported, []byte{0x00})
+	require.Error(t, err, "expected error for unsupported parameter set")
+	require.Nil(t, publicKey, "expected nil public key for unsupported parameter set")
+
+	privateKey, err := UnmarshalPrivateKey(unsupported, []byte{0x00})
+	require.Error(t, err, "expected error for unsupported parameter set")
+	require.Nil(t, privateKey, "expected nil private key for unsupported parameter set")
+
+	require.Equal(t, "MLKEMParameterSet(0)", unsupported.String(), "expected placeholder name for unsupported parameter set")
+}
+
+func TestMLKEM1024IsNotLegacyKyber1024(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x42}, MLKEMSeedSize)
+	encapsulationSeed := bytes.Repeat([]byte{0x24}, MLKEMEncapsulationSeedSize)
+
+	mlkemKeyPair, err := GenerateDeterministicMLKEMKeyPair(MLKEM1024, seed)
+	require.NoError(t, err, "failed to derive ML-KEM-1024 key pair")
+	kyberKeyPair, err := GenerateDeterministicMLKEMKeyPair(LegacyKyber1024, seed)
+	require.NoError(t, err, "failed to derive legacy Kyber1024 key pair")
+
+	mlkemPublicKeyBytes, err := MarshalPublicKey(mlkemKeyPair.PublicKey)
+	require.NoError(t, err, "failed to marshal ML-KEM-1024 public key")
+	kyberPublicKeyBytes, err := MarshalPublicKey(kyberKeyPair.PublicKey)
+	require.NoError(t, err, "failed to marshal legacy Kyber1024 public key")
+	require.Equal(t, len(mlkemPublicKeyBytes), len(kyberPublicKeyBytes), "expected identical public key sizes")
+	require.NotEqual(t, mlkemPublicKeyBytes, kyberPublicKeyBytes, "expected FIPS 203 key generation to differ from round-3 Kyber")
+
+	mlkemCiphertext, mlkemSharedSecret, err := MLKEMEncapsulateDeterministic(mlkemKeyPair.PublicKey, encapsulationSeed)
+	require.NoError(t, err, "failed to encapsulate with ML-KEM-1024")
+	kyberCiphertext, kyberSharedSecret, err := MLKEMEncapsulateDeterministic(kyberKeyPair.PublicKey, encapsulationSeed)
+	require.NoError(t, err, "failed to encapsulate with legacy Kyber1024")
+	require.NotEqual(t, mlkemCiphertext, kyberCiphertext, "expected different ciphertexts")
+	require.NotEqual(t, mlkemSharedSecret, kyberSharedSecret, "expected different shared secrets")
+
+	// An ML-KEM-1024 ciphertext must not decapsulate to the same secret under the legacy Kyber1024 scheme, even with matching key material.
+	kyberRecoveredSecret, err := MLKEMDecapsulate(kyberKeyPair.PrivateKey, mlkemCiphertext)
+	require.NoError(t, err, "failed to decapsulate with legacy Kyber1024")
+	require.NotEqual(t, mlkemSharedSecret, kyberRecoveredSecret, "expected legacy Kyber1024 to be incompatible with ML-KEM-1024")
+}
+
+func TestGenerateDeterministicMLKEMKeyPair(t *testing.T) {
+	for _, parameterSet := range allMLKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			seed := bytes.Repeat([]byte{0x01}, MLKEMSeedSize)
+			keyPair1, err := GenerateDeterministicMLKEMKeyPair(parameterSet, seed)
+			require.NoError(t, err, "failed to derive ML-KEM key pair 1")
+			keyPair2, err := GenerateDeterministicMLKEMKeyPair(parameterSet, seed)
+			require.NoError(t, err, "failed to derive ML-KEM key pair 2")
+			require.Equal(t, parameterSet, keyPair1.ParameterSet, "expected key pair to carry its parameter set")
+			require.True(t, keyPair1.PublicKey.Equal(keyPair2.PublicKey), "expected identical public keys from identical seeds")
+			require.True(t, keyPair1.PrivateKey.Equal(keyPair2.PrivateKey), "expected identical private keys from identical seeds")
+
+			keyPair3, err := GenerateDeterministicMLKEMKeyPair(parameterSet, seed[:MLKEMSeedSize-1])
+			require.Error(t, err, "expected error for short seed")
+			require.Nil(t, keyPair3, "expected nil key pair for short seed")
+		})
+	}
+}
+
+func TestMLKEMEncapsulateDeterministic(t *testing.T) {
+	for _, parameterSet := range allMLKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			keyPair, err := GenerateDeterministicMLKEMKeyPair(parameterSet, bytes.Repeat([]byte{0x02}, MLKEMSeedSize))
+			require.NoError(t, err, "failed to derive ML-KEM key pair")
+
+			encapsulationSeed := bytes.Repeat([]byte{0x03}, MLKEMEncapsulationSeedSize)
+			ciphertext1, sharedSecret1, err := MLKEMEncapsulateDeterministic(keyPair.PublicKey, encapsulationSeed)
+			require.NoError(t, err, "failed to encapsulate 1")
+			ciphertext2, sharedSecret2, err := MLKEMEncapsulateDeterministic(keyPair.PublicKey, encapsulationSeed)
+			require.NoError(t, err, "failed to encapsulate 2")
+			require.Equal(t, ciphertext1, ciphertext2, "expected identical ciphertexts from identical seeds")
+			require.Equal(t, sharedSecret1, sharedSecret2, "expected identical shared secrets from identical seeds")
+			require.Len(t, sharedSecret1, MLKEMSharedSecretSize, "expected shared secret of MLKEMSharedSecretSize bytes")
+
+			recoveredSecret, err := MLKEMDecapsulate(keyPair.PrivateKey, ciphertext1)
+			require.NoError(t, err, "failed to decapsulate")
+			require.Equal(t, sharedSecret1, recoveredSecret, "expected recovered shared secret to match")
+
+			ciphertext3, sharedSecret3, err := MLKEMEncapsulateDeterministic(keyPair.PublicKey, encapsulationSeed[1:])
+			require.Error(t, err, "expected error for short encapsulation seed")
+			require.Nil(t, ciphertext3, "expected nil ciphertext for short encapsulation seed")
+			require.Nil(t, sharedSecret3, "expected nil shared secret for short encapsulation seed")
+		})
+	}
 }
 
 func TestMLKEMEncapsulateAndDecapsulate(t *testing.T) {
-	keyPair, err := GenerateMLKEMKeyPair()
-	require.NoError(t, err, "failed to generate ML-KEM key pair")
-	require.NotNil(t, keyPair, "expected non-nil key pair")
-	require.NotNil(t, keyPair.PublicKey, "expected non-nil public key")
-	require.NotNil(t, keyPair.PrivateKey, "expected non-nil private key")
+	for _, parameterSet := range allMLKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			keyPair, err := GenerateMLKEMKeyPair(parameterSet)
+			require.NoError(t, err, "failed to generate ML-KEM key pair")
+			require.NotNil(t, keyPair, "expected non-nil key pair")
+			require.NotNil(t, keyPair.PublicKey, "expected non-nil public key")
+			require.NotNil(t, keyPair.PrivateKey, "expected non-nil private key")
 
-	ciphertext, sharedSecret, err := MLKEMEncapsulate(keyPair.PublicKey)
-	require.NoError(t, err, "failed to encapsulate public key")
-	require.NotEmpty(t, ciphertext, "expected non-empty ciphertext")
-	require.NotEmpty(t, sharedSecret, "expected non-empty sharedSecret")
+			ciphertext, sharedSecret, err := MLKEMEncapsulate(keyPair.PublicKey)
+			require.NoError(t, err, "failed to encapsulate public key")
+			require.NotEmpty(t, ciphertext, "expected non-empty ciphertext")
+			require.NotEmpty(t, sharedSecret, "expected non-empty sharedSecret")
 
-	sharedSecret2, err := MLKEMDecapsulate(keyPair.PrivateKey, ciphertext)
-	require.NoError(t, err, "failed to decapsulate ciphertext with private key")
-	require.NotEmpty(t, sharedSecret2, "expected non-empty sharedSecret2")
+			sharedSecret2, err := MLKEMDecapsulate(keyPair.PrivateKey, ciphertext)
+			require.NoError(t, err, "failed to decapsulate ciphertext with private key")
+			require.NotEmpty(t, sharedSecret2, "expected non-empty sharedSecret2")
 
-	require.NotZero(t, subtle.ConstantTimeCompare(sharedSecret, sharedSecret2), "expected shared secrets to match")
+			require.NotZero(t, subtle.ConstantTimeCompare(sharedSecret, sharedSecret2), "expected shared secrets to match")
+		})
+	}
 }
 
 func TestMLKEMEncapsulateWithInvalidKey(t *testing.T) {
@@ -71,47 +204,127 @@ func TestMLKEMDecapsulateWithInvalidKey(t *testing.T) {
 }
 
 func TestMLKEMDecapsulateWithTamperedCiphertext(t *testing.T) {
-	keyPair, err := GenerateMLKEMKeyPair()
-	require.NoError(t, err, "failed to generate ML-KEM key pair")
-	require.NotNil(t, keyPair, "expected non-nil key pair")
-	require.NotNil(t, keyPair.PublicKey, "expected non-nil public key")
-	require.NotNil(t, keyPair.PrivateKey, "expected non-nil private key")
+	for _, parameterSet := range allMLKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			keyPair, err := GenerateMLKEMKeyPair(parameterSet)
+			require.NoError(t, err, "failed to generate ML-KEM key pair")
+			require.NotNil(t, keyPair, "expected non-nil key pair")
+			require.NotNil(t, keyPair.PublicKey, "expected non-nil public key")
+			require.NotNil(t, keyPair.PrivateKey, "expected non-nil private key")
 
-	ciphertext, sharedSecret, err := MLKEMEncapsulate(keyPair.PublicKey)
-	require.NoError(t, err, "failed to encapsulate public key")
-	require.NotEmpty(t, ciphertext, "expected non-empty ciphertext")
-	require.NotEmpty(t, sharedSecret, "expected non-empty sharedSecret")
+			ciphertext, sharedSecret, err := MLKEMEncapsulate(keyPair.PublicKey)
+			require.NoError(t, err, "failed to encapsulate public key")
+			require.NotEmpty(t, ciphertext, "expected non-empty ciphertext")
+			require.NotEmpty(t, sharedSecret, "expected non-empty sharedSecret")
+
+			ciphertext[0] ^= 0xFF // tamper ciphertext
+
+			sharedSecretTampered, err := MLKEMDecapsulate(keyPair.PrivateKey, ciphertext)
+			require.NoError(t, err, "failed to decapsulate ciphertext with private key")
+			require.NotEmpty(t, sharedSecretTampered, "expected non-empty sharedSecretTampered")
 
-	ciphertext[0] ^= 0xFF // tamper ciphertext
+			require.Zero(t, subtle.ConstantTimeCompare(sharedSecret, sharedSecretTampered), "expected different shared secret for decapsulate with tampered ciphertext")
+		})
+	}
+}
+
+// mlkemRejectionKey computes the FIPS 203 implicit rejection key J(z || c) = SHAKE256(z || c, 32), where z is the last
+// 32 bytes of the decapsulation key.
+func mlkemRejectionKey(t *testing.T, privateKey kem.PrivateKey, ciphertext []byte) []byte {
+	privateKeyBytes, err := MarshalPrivateKey(privateKey)
+	require.NoError(t, err)
+	shake := sha3.NewSHAKE256()
+	_, err = shake.Write(privateKeyBytes[len(privateKeyBytes)-32:])
+	require.NoError(t, err)
+	_, err = shake.Write(ciphertext)
+	require.NoError(t, err)
+	rejectionKey := make([]byte, MLKEMSharedSecretSize)
+	_, err = shake.Read(rejectionKey)
+	require.NoError(t, err)
+	return rejectionKey
+}
 
-	sharedSecretTampered, err := MLKEMDecapsulate(keyPair.PrivateKey, ciphertext)
-	require.NoError(t, err, "failed to decapsulate ciphertext with private key")
-	require.NotEmpty(t, sharedSecretTampered, "expected non-empty sharedSecretTampered")
+// For TestMLKEMImplicitRejection: every well-sized but invalid ciphertext decapsulates without error to the
+// deterministic rejection key J(z || c), and a valid ciphertext does not.
+func TestMLKEMImplicitRejection(t *testing.T) {
+	for _, parameterSet := range MLKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			keyPair, err := GenerateDeterministicMLKEMKeyPair(parameterSet, bytes.Repeat([]byte{0x01}, MLKEMSeedSize))
+			require.NoError(t, err)
+			otherKeyPair, err := GenerateDeterministicMLKEMKeyPair(parameterSet, bytes.Repeat([]byte{0x02}, MLKEMSeedSize))
+			require.NoError(t, err)
+			ciphertext, sharedSecret, err := MLKEMEncapsulateDeterministic(keyPair.PublicKey, bytes.Repeat([]byte{0x03}, MLKEMEncapsulationSeedSize))
+			require.NoError(t, err)
+			otherCiphertext, _, err := MLKEMEncapsulateDeterministic(otherKeyPair.PublicKey, bytes.Repeat([]byte{0x04}, MLKEMEncapsulationSeedSize))
+			require.NoError(t, err)
 
-	require.Zero(t, subtle.ConstantTimeCompare(sharedSecret, sharedSecretTampered), "expected different shared secret for decapsulate with tampered ciphertext")
+			tampered := append([]byte(nil), ciphertext...)
+			tampered[len(tampered)-1] ^= 0x01
+			random := sha3.SumSHAKE256([]byte("ML-KEM implicit rejection"), len(ciphertext))
+			badCiphertexts := map[string][]byte{
+				"tampered":  tampered,
+				"all zero":  make([]byte, len(ciphertext)),
+				"all ones":  bytes.Repeat([]byte{0xFF}, len(ciphertext)),
+				"random":    random,
+				"other key": otherCiphertext,
+			}
+			for name, badCiphertext := range badCiphertexts {
+				rejected, err := MLKEMDecapsulate(keyPair.PrivateKey, badCiphertext)
+				require.NoError(t, err, "%s: implicit rejection must not return an error", name)
+				require.Equal(t, mlkemRejectionKey(t, keyPair.PrivateKey, badCiphertext), rejected, "%s: expected J(z || c)", name)
+				again, err := MLKEMDecapsulate(keyPair.PrivateKey, badCiphertext)
+				require.NoError(t, err)
+				require.Equal(t, rejected, again, "%s: the rejection key must be deterministic", name)
+			}
+
+			decapsulated, err := MLKEMDecapsulate(keyPair.PrivateKey, ciphertext)
+			require.NoError(t, err)
+			require.Equal(t, sharedSecret, decapsulated)
+			require.NotEqual(t, mlkemRejectionKey(t, keyPair.PrivateKey, ciphertext), decapsulated)
+		})
+	}
 }
 
 func TestMLKEMDecapsulateWithWrongKey(t *testing.T) {
-	keyPair1, err := GenerateMLKEMKeyPair()
-	require.NoError(t, err, "failed to generate ML-KEM key pair 1")
-	require.NotNil(t, keyPair1, "expected non-nil key pair 1")
-	require.NotNil(t, keyPair1.PublicKey, "expected non-nil public key 1")
-	require.NotNil(t, keyPair1.PrivateKey, "expected non-nil private key 1")
-
-	keyPair2, err := GenerateMLKEMKeyPair()
-	require.NoError(t, err, "failed to generate ML-KEM key pair 2")
-	require.NotNil(t, keyPair2, "expected non-nil key pair 2")
-	require.NotNil(t, keyPair2.PublicKey, "expected non-nil public key 2")
-	require.NotNil(t, keyPair2.PrivateKey, "expected non-nil private key 2")
-
-	ciphertext, sharedSecret, err := MLKEMEncapsulate(keyPair1.PublicKey)
-	require.NoError(t, err, "failed to encapsulate public key 1")
-	require.NotEmpty(t, ciphertext, "expected non-empty ciphertext")
-	require.NotEmpty(t, sharedSecret, "expected non-empty sharedSecret")
+	for _, parameterSet := range allMLKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			keyPair1, err := GenerateMLKEMKeyPair(parameterSet)
+			require.NoError(t, err, "failed to generate ML-KEM key pair 1")
+			require.NotNil(t, keyPair1, "expected non-nil key pair 1")
+			require.NotNil(t, keyPair1.PublicKey, "expected non-nil public key 1")
+			require.NotNil(t, keyPair1.PrivateKey, "expected non-nil private key 1")
+
+			keyPair2, err := GenerateMLKEMKeyPair(parameterSet)
+			require.NoError(t, err, "failed to generate ML-KEM key pair 2")
+			require.NotNil(t, keyPair2, "expected non-nil key pair 2")
+			require.NotNil(t, keyPair2.PublicKey, "expected non-nil public key 2")
+			require.NotNil(t, keyPair2.PrivateKey, "expected non-nil private key 2")
 
-	sharedSecretWrong, err := MLKEMDecapsulate(keyPair2.PrivateKey, ciphertext)
-	require.NoError(t, err, "failed to decapsulate ciphertext with private key 2")
-	require.NotEmpty(t, sharedSecretWrong, "expected non-empty sharedSecretWrong")
+			ciphertext, sharedSecret, err := MLKEMEncapsulate(keyPair1.PublicKey)
+			require.NoError(t, err, "failed to encapsulate public key 1")
+			require.NotEmpty(t, ciphertext, "expected non-empty ciphertext")
+			require.NotEmpty(t, sharedSecret, "expected non-empty sharedSecret")
+
+			sharedSecretWrong, err := MLKEMDecapsulate(keyPair2.PrivateKey, ciphertext)
+			require.NoError(t, err, "failed to decapsulate ciphertext with private key 2")
+			require.NotEmpty(t, sharedSecretWrong, "expected non-empty sharedSecretWrong")
+
+			require.Zero(t, subtle.ConstantTimeCompare(sharedSecret, sharedSecretWrong), "expected different shared secret for decapsulate with wrong private key")
+		})
+	}
+}
+
+func TestMLKEMDecapsulateWithMismatchedParameterSet(t *testing.T) {
+	keyPair512, err := GenerateMLKEMKeyPair(MLKEM512)
+	require.NoError(t, err, "failed to generate ML-KEM-512 key pair")
+	keyPair768, err := GenerateMLKEMKeyPair(MLKEM768)
+	require.NoError(t, err, "failed to generate ML-KEM-768 key pair")
+
+	ciphertext, sharedSecret, err := MLKEMEncapsulate(keyPair512.PublicKey)
+	require.NoError(t, err, "failed to encapsulate with ML-KEM-512")
+	require.NotEmpty(t, sharedSecret, "expected non-empty sharedSecret")
 
-	require.Zero(t, subtle.ConstantTimeCompare(sharedSecret, sharedSecretWrong), "expected different shared secret for decapsulate with wrong private key")
+	recoveredSecret, err := MLKEMDecapsulate(keyPair768.PrivateKey, ciphertext)
+	require.Error(t, err, "expected error for ML-KEM-512 ciphertext decapsulated with an ML-KEM-768 private key")
+	require.Nil(t, recoveredSecret, "expected nil shared secret for mismatched parameter set")
 }
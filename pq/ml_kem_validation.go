@@ -0,0 +1,84 @@
+package pq
+
+import (
+	"crypto/sha3"
+	"crypto/subtle"
+	"fmt"
+)
+
+const (
+	// mlkemModulus is the ML-KEM modulus q.
+	mlkemModulus = 3329
+	// mlkemEncodedPolynomialSize is the size in bytes of a polynomial encoded with ByteEncode_12: 256 coefficients of 12 bits.
+	mlkemEncodedPolynomialSize = 384
+	// mlkemHashSize is the size in bytes of H(ek), stored inside the decapsulation key.
+	mlkemHashSize = 32
+)
+
+// ValidateEncapsulationKey performs the FIPS 203 section 7.2 encapsulation key checks on an encoded ML-KEM encapsulation
+// (public) key: the type check, that it has the length of the parameter set, and the modulus check, that every encoded
+// coefficient of t is reduced modulo q, which is ByteEncode_12(ByteDecode_12(ek)) == ek.
+//
+// A key of the wrong length returns a *SizeError matching ErrInvalidKeySize, and a key failing the modulus check returns
+// a *KeyValidationError matching ErrInvalidEncapsulationKey. The checks only read public data.
+func ValidateEncapsulationKey(parameterSet MLKEMParameterSet, encapsulationKey []byte) error {
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return err
+	}
+	if len(encapsulationKey) != scheme.PublicKeySize() {
+		return newSizeError(ErrInvalidKeySize, parameterSet.String()+" encapsulation key", len(encapsulationKey), scheme.PublicKeySize())
+	}
+	if coefficient, reduced := mlkemCoefficientsReduced(encapsulationKey[:len(encapsulationKey)-32]); !reduced {
+		return &KeyValidationError{
+			Err:    ErrInvalidEncapsulationKey,
+			Input:  parameterSet.String() + " encapsulation key",
+			Reason: fmt.Sprintf("coefficient %d is not reduced modulo %d", coefficient, mlkemModulus),
+		}
+	}
+	return nil
+}
+
+// ValidateDecapsulationKey performs the FIPS 203 section 7.3 decapsulation key checks on an encoded ML-KEM decapsulation
+// (private) key dk = dk_PKE || ek || H(ek) || z: the type check, that it has the length of the parameter set, and the
+// hash check, that the stored H(ek) is the SHA3-256 hash of the embedded encapsulation key ek.
+//
+// A key of the wrong length returns a *SizeError matching ErrInvalidKeySize, and a key failing the hash check returns
+// a *KeyValidationError matching ErrInvalidDecapsulationKey. The hashes are compared in constant time.
+func ValidateDecapsulationKey(parameterSet MLKEMParameterSet, decapsulationKey []byte) error {
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return err
+	}
+	if len(decapsulationKey) != scheme.PrivateKeySize() {
+		return newSizeError(ErrInvalidKeySize, parameterSet.String()+" decapsulation key", len(decapsulationKey), scheme.PrivateKeySize())
+	}
+	encapsulationKeySize := scheme.PublicKeySize()
+	encapsulationKeyOffset := encapsulationKeySize - 32 // dk_PKE has the size of ek without rho
+	hashOffset := encapsulationKeyOffset + encapsulationKeySize
+	hash := sha3.Sum256(decapsulationKey[encapsulationKeyOffset:hashOffset])
+	if subtle.ConstantTimeCompare(hash[:], decapsulationKey[hashOffset:hashOffset+mlkemHashSize]) != 1 {
+		return &KeyValidationError{
+			Err:    ErrInvalidDecapsulationKey,
+			Input:  parameterSet.String() + " decapsulation key",
+			Reason: "stored H(ek) does not match the embedded encapsulation key",
+		}
+	}
+	return nil
+}
+
+// mlkemCoefficientsReduced reports whether every 12-bit coefficient of the ByteEncode_12 encoded polynomials is less than q,
+// and if not, the index of the first one that is not.
+func mlkemCoefficientsReduced(encodedPolynomials []byte) (int, bool) {
+	for i := 0; i+3 <= len(encodedPolynomials); i += 3 {
+		first := uint16(encodedPolynomials[i]) | uint16(encodedPolynomials[i+1]&0x0F)<<8
+		second := uint16(encodedPolynomials[i+1])>>4 | uint16(encodedPolynomials[i+2])<<4
+		if first >= mlkemModulus {
+			return i / 3 * 2, false
+		}
+		if second >= mlkemModulus {
+			return i/3*2 + 1, false
+		}
+	}
+	return 0, true
+}
@@ -0,0 +1,35 @@
+package pq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkValidateEncapsulationKey(b *testing.B) {
+	for _, parameterSet := range MLKEMParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			keyPair, err := GenerateMLKEMKeyPair(parameterSet)
+			require.NoError(b, err, "Key pair generation should not error")
+			encapsulationKey, err := MarshalPublicKey(keyPair.PublicKey)
+			require.NoError(b, err, "Marshalling should not error")
+			for b.Loop() {
+				require.NoError(b, ValidateEncapsulationKey(parameterSet, encapsulationKey), "Validation should not error")
+			}
+		})
+	}
+}
+
+func BenchmarkValidateDecapsulationKey(b *testing.B) {
+	for _, parameterSet := range MLKEMParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			keyPair, err := GenerateMLKEMKeyPair(parameterSet)
+			require.NoError(b, err, "Key pair generation should not error")
+			decapsulationKey, err := MarshalPrivateKey(keyPair.PrivateKey)
+			require.NoError(b, err, "Marshalling should not error")
+			for b.Loop() {
+				require.NoError(b, ValidateDecapsulationKey(parameterSet, decapsulationKey), "Validation should not error")
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package pq
+
+import (
+	"testing"
+
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+)
+
+func FuzzValidateEncapsulationKey(f *testing.F) {
+	keyPair, _ := GenerateMLKEMKeyPair(MLKEM768)
+	encapsulationKey, _ := MarshalPublicKey(keyPair.PublicKey)
+	f.Add(encapsulationKey)
+	f.Fuzz(func(t *testing.T, encapsulationKey []byte) {
+		validateError := ValidateEncapsulationKey(MLKEM768, encapsulationKey)
+		_, circlError := mlkem768.Scheme().UnmarshalBinaryPublicKey(encapsulationKey)
+		if (validateError == nil) != (circlError == nil) {
+			t.Errorf("ValidateEncapsulationKey returned %v, but CIRCL returned %v", validateError, circlError)
+		}
+	})
+}
+
+func FuzzValidateDecapsulationKey(f *testing.F) {
+	keyPair, _ := GenerateMLKEMKeyPair(MLKEM768)
+	decapsulationKey, _ := MarshalPrivateKey(keyPair.PrivateKey)
+	f.Add(decapsulationKey)
+	f.Fuzz(func(t *testing.T, decapsulationKey []byte) {
+		validateError := ValidateDecapsulationKey(MLKEM768, decapsulationKey)
+		privateKey, unmarshalError := UnmarshalPrivateKey(MLKEM768, decapsulationKey)
+		if validateError != nil {
+			if unmarshalError == nil || privateKey != nil {
+				t.Error("UnmarshalPrivateKey must reject keys failing ValidateDecapsulationKey")
+			}
+			return
+		}
+		if _, err := MLKEMDecapsulate(privateKey, make([]byte, mlkem768.CiphertextSize)); err != nil {
+			t.Errorf("a validated key should decapsulate: %v", err)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package pq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setMLKEMCoefficient overwrites coefficient index of ByteEncode_12 encoded polynomials with a 12-bit value.
+func setMLKEMCoefficient(encoded []byte, index int, value uint16) {
+	offset := index / 2 * 3
+	if index%2 == 0 {
+		encoded[offset] = byte(value)
+		encoded[offset+1] = encoded[offset+1]&0xF0 | byte(value>>8)
+	} else {
+		encoded[offset+1] = encoded[offset+1]&0x0F | byte(value<<4)
+		encoded[offset+2] = byte(value >> 4)
+	}
+}
+
+// For TestValidateEncapsulationKey: generated keys pass the modulus check, q-1 is accepted, and any coefficient of q or
+// more is rejected with a *KeyValidationError by the validator and every parser.
+func TestValidateEncapsulationKey(t *testing.T) {
+	for _, parameterSet := range allMLKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			keyPair, err := GenerateMLKEMKeyPair(parameterSet)
+			require.NoError(t, err)
+			encapsulationKey, err := MarshalPublicKey(keyPair.PublicKey)
+			require.NoError(t, err)
+			require.NoError(t, ValidateEncapsulationKey(parameterSet, encapsulationKey))
+
+			boundary := append([]byte(nil), encapsulationKey...)
+			setMLKEMCoefficient(boundary, 1, mlkemModulus-1)
+			require.NoError(t, ValidateEncapsulationKey(parameterSet, boundary), "q-1 is reduced")
+
+			for _, value := range []uint16{mlkemModulus, 0xFFF} {
+				for _, index := range []int{0, 7, (len(encapsulationKey)-32)/3*2 - 1} {
+					unreduced := append([]byte(nil), encapsulationKey...)
+					setMLKEMCoefficient(unreduced, index, value)
+					err := ValidateEncapsulationKey(parameterSet, unreduced)
+					require.ErrorIs(t, err, ErrInvalidEncapsulationKey)
+					var keyValidationError *KeyValidationError
+					require.ErrorAs(t, err, &keyValidationError)
+					require.Contains(t, keyValidationError.Reason, "not reduced")
+
+					_, err = UnmarshalPublicKey(parameterSet, unreduced)
+					require.ErrorIs(t, err, ErrInvalidEncapsulationKey, "UnmarshalPublicKey must validate")
+				}
+			}
+
+			err = ValidateEncapsulationKey(parameterSet, encapsulationKey[1:])
+			require.ErrorIs(t, err, ErrInvalidKeySize)
+		})
+	}
+}
+
+// For TestValidateDecapsulationKey: generated keys pass the hash check, and a decapsulation key whose stored H(ek) does
+// not match the embedded encapsulation key is rejected by the validator and every parser.
+func TestValidateDecapsulationKey(t *testing.T) {
+	for _, parameterSet := range allMLKEMParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			keyPair, err := GenerateMLKEMKeyPair(parameterSet)
+			require.NoError(t, err)
+			decapsulationKey, err := MarshalPrivateKey(keyPair.PrivateKey)
+			require.NoError(t, err)
+			require.NoError(t, ValidateDecapsulationKey(parameterSet, decapsulationKey))
+
+			scheme, err := parameterSet.scheme()
+			require.NoError(t, err)
+			encapsulationKeySize := scheme.PublicKeySize()
+			encapsulationKeyOffset := encapsulationKeySize - 32
+			hashOffset := encapsulationKeyOffset + encapsulationKeySize
+			for name, offset := range map[string]int{"embedded ek": encapsulationKeyOffset + 5, "stored H(ek)": hashOffset} {
+				corrupted := append([]byte(nil), decapsulationKey...)
+				corrupted[offset] ^= 0x01
+				err := ValidateDecapsulationKey(parameterSet, corrupted)
+				require.ErrorIs(t, err, ErrInvalidDecapsulationKey, name)
+				var keyValidationError *KeyValidationError
+				require.ErrorAs(t, err, &keyValidationError, name)
+
+				_, err = UnmarshalPrivateKey(parameterSet, corrupted)
+				require.ErrorIs(t, err, ErrInvalidDecapsulationKey, "%s: UnmarshalPrivateKey must validate", name)
+			}
+
+			err = ValidateDecapsulationKey(parameterSet, append(decapsulationKey, 0))
+			require.ErrorIs(t, err, ErrInvalidKeySize)
+		})
+	}
+}
+
+// For TestMLKEMParsersValidate: the PKCS#8, SubjectPublicKeyInfo and hybrid KEM parsers apply the FIPS 203 checks.
+func TestMLKEMParsersValidate(t *testing.T) {
+	keyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	require.NoError(t, err)
+	encapsulationKey, err := MarshalPublicKey(keyPair.PublicKey)
+	require.NoError(t, err)
+	setMLKEMCoefficient(encapsulationKey, 3, 0xFFF)
+	oid, err := MLKEM768.OID()
+	require.NoError(t, err)
+	der, err := marshalSubjectPublicKeyInfo(oid, encapsulationKey)
+	require.NoError(t, err)
+	_, err = ParseMLKEMPublicKeyPKIX(der)
+	require.ErrorIs(t, err, ErrInvalidEncapsulationKey)
+
+	decapsulationKey, err := MarshalPrivateKey(keyPair.PrivateKey)
+	require.NoError(t, err)
+	decapsulationKey[len(decapsulationKey)-33] ^= 0x01 // last byte of H(ek)
+	privateKeyBytes, err := marshalSeedPrivateKey(PrivateKeyFormatExpanded, nil, decapsulationKey)
+	require.NoError(t, err)
+	der, err = marshalPKCS8(oid, privateKeyBytes)
+	require.NoError(t, err)
+	_, err = ParseMLKEMPrivateKeyPKCS8(der)
+	require.ErrorIs(t, err, ErrInvalidDecapsulationKey)
+
+	hybridKeyPair, err := GenerateHybridKEMKeyPair(ECDHP256, MLKEM768)
+	require.NoError(t, err)
+	hybridPublicKey, err := MarshalHybridKEMPublicKey(hybridKeyPair.PublicKey)
+	require.NoError(t, err)
+	setMLKEMCoefficient(hybridPublicKey, 0, mlkemModulus)
+	_, err = UnmarshalHybridKEMPublicKey(ECDHP256, MLKEM768, hybridPublicKey)
+	require.ErrorIs(t, err, ErrInvalidEncapsulationKey)
+}
@@ -0,0 +1,1386 @@
+package pq
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha3"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp/aes/keywrap"
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+	"github.com/cloudflare/circl/sign/mldsa/mldsa65"
+)
+
+// OpenPGP v6 keys (RFC 9580) with the composite algorithms of the OpenPGP PQC draft (draft-ietf-openpgp-pqc):
+// ML-DSA-65+Ed25519 signing keys and ML-KEM-768+X25519 encryption keys. Both components of a composite key are always
+// used together. A composite signature is an Ed25519 and an ML-DSA-65 signature of the same digest, with an empty
+// ML-DSA context, and a session key is wrapped with AES-256 key wrap under the key encryption key
+//
+//	SHA3-256(ML-KEM shared secret || X25519 shared secret || X25519 ephemeral key || X25519 recipient key ||
+//	         35 || "OpenPGPCompositeKDFv1" || 21)
+//
+// The secret key material is the Ed25519 or X25519 secret key followed by the ML-DSA seed (xi) or the ML-KEM seed
+// (d || z). The RFC 9580 Ed25519 and X25519 algorithms are supported as well, so that classical v6 keys and messages
+// can be read. A message is a v6 PKESK packet per recipient and a version 2 SEIPD packet with AES-256-OCB. Signed
+// messages are read and verified but not produced. Encrypted secret keys and v4 keys are not supported.
+
+// OpenPGPAlgorithm is an OpenPGP public-key algorithm ID.
+type OpenPGPAlgorithm byte
+
+const (
+	// OpenPGPX25519 is the RFC 9580 X25519 encryption algorithm.
+	OpenPGPX25519 OpenPGPAlgorithm = 25
+	// OpenPGPEd25519 is the RFC 9580 Ed25519 signature algorithm.
+	OpenPGPEd25519 OpenPGPAlgorithm = 27
+	// OpenPGPMLDSA65Ed25519 is the composite ML-DSA-65+Ed25519 signature algorithm of the OpenPGP PQC draft.
+	OpenPGPMLDSA65Ed25519 OpenPGPAlgorithm = 30
+	// OpenPGPMLKEM768X25519 is the composite ML-KEM-768+X25519 encryption algorithm of the OpenPGP PQC draft.
+	OpenPGPMLKEM768X25519 OpenPGPAlgorithm = 35
+)
+
+// Signature types and key flags.
+const (
+	openPGPSignatureBinary           = 0x00
+	openPGPSignatureGenericCert      = 0x10
+	openPGPSignaturePositiveCert     = 0x13
+	openPGPSignatureSubkeyBinding    = 0x18
+	openPGPSignaturePrimaryBinding   = 0x19
+	openPGPSignatureDirectKey        = 0x1f
+	openPGPSignatureKeyRevocation    = 0x20
+	openPGPSignatureSubkeyRevocation = 0x28
+	openPGPSignatureCertRevocation   = 0x30
+
+	openPGPKeyFlagCertify        = 0x01
+	openPGPKeyFlagSign           = 0x02
+	openPGPKeyFlagEncryptComms   = 0x04
+	openPGPKeyFlagEncryptStorage = 0x08
+)
+
+// Signature subpacket types.
+const (
+	openPGPSubpacketCreationTime        = 2
+	openPGPSubpacketExpirationTime      = 3
+	openPGPSubpacketKeyExpirationTime   = 9
+	openPGPSubpacketPreferredCiphers    = 11
+	openPGPSubpacketIssuerKeyID         = 16
+	openPGPSubpacketPreferredHashes     = 21
+	openPGPSubpacketPrimaryUserID       = 25
+	openPGPSubpacketKeyFlags            = 27
+	openPGPSubpacketRevocationReason    = 29
+	openPGPSubpacketFeatures            = 30
+	openPGPSubpacketEmbeddedSignature   = 32
+	openPGPSubpacketIssuerFingerprint   = 33
+	openPGPSubpacketPreferredAEADSuites = 39
+)
+
+const (
+	// openPGPKeyVersion6 is the only supported key, signature and PKESK version.
+	openPGPKeyVersion6 = 6
+	// openPGPFingerprintSize is the size of a v6 fingerprint.
+	openPGPFingerprintSize = sha256.Size
+	// openPGPSessionKeySize is the size of the AES-256 session keys of encrypted messages.
+	openPGPSessionKeySize = 32
+	// openPGPCompositeKDFDomain is the domain separator of the ML-KEM-768+X25519 key combiner.
+	openPGPCompositeKDFDomain = "OpenPGPCompositeKDFv1"
+	// openPGPX25519KDFInfo is the HKDF info of the RFC 9580 X25519 key encryption key.
+	openPGPX25519KDFInfo = "OpenPGP X25519"
+	// openPGPFeatureSEIPDv2 advertises support for version 2 SEIPD packets.
+	openPGPFeatureSEIPDv2 = 0x08
+)
+
+// String returns the name of the algorithm, for example "ML-KEM-768+X25519".
+func (algorithm OpenPGPAlgorithm) String() string {
+	switch algorithm {
+	case OpenPGPX25519:
+		return "X25519"
+	case OpenPGPEd25519:
+		return "Ed25519"
+	case OpenPGPMLDSA65Ed25519:
+		return "ML-DSA-65+Ed25519"
+	case OpenPGPMLKEM768X25519:
+		return "ML-KEM-768+X25519"
+	default:
+		return fmt.Sprintf("OpenPGPAlgorithm(%d)", byte(algorithm))
+	}
+}
+
+// canSign reports whether the algorithm is a signature algorithm.
+func (algorithm OpenPGPAlgorithm) canSign() bool {
+	return algorithm == OpenPGPEd25519 || algorithm == OpenPGPMLDSA65Ed25519
+}
+
+// canEncrypt reports whether the algorithm is an encryption algorithm.
+func (algorithm OpenPGPAlgorithm) canEncrypt() bool {
+	return algorithm == OpenPGPX25519 || algorithm == OpenPGPMLKEM768X25519
+}
+
+// keySizes returns the sizes of the public and secret key material of the algorithm.
+func (algorithm OpenPGPAlgorithm) keySizes() (publicKeySize int, secretKeySize int, ok bool) {
+	switch algorithm {
+	case OpenPGPX25519, OpenPGPEd25519:
+		return 32, 32, true
+	case OpenPGPMLDSA65Ed25519:
+		return ed25519.PublicKeySize + mldsa65.PublicKeySize, ed25519.SeedSize + MLDSASeedSize, true
+	case OpenPGPMLKEM768X25519:
+		return 32 + mlkem768.PublicKeySize, 32 + MLKEMSeedSize, true
+	default:
+		return 0, 0, false
+	}
+}
+
+// signatureSize returns the size of the signature material of a signature algorithm.
+func (algorithm OpenPGPAlgorithm) signatureSize() int {
+	if algorithm == OpenPGPMLDSA65Ed25519 {
+		return ed25519.SignatureSize + mldsa65.SignatureSize
+	}
+	return ed25519.SignatureSize
+}
+
+// signatureHash returns the hash algorithm signatures of the algorithm are made with.
+func (algorithm OpenPGPAlgorithm) signatureHash() byte {
+	if algorithm == OpenPGPMLDSA65Ed25519 {
+		return openPGPHashSHA3_256
+	}
+	return openPGPHashSHA256
+}
+
+// openPGPKeyPacket is a v6 primary key or subkey, and what its newest valid self-signature says about it.
+type openPGPKeyPacket struct {
+	algorithm OpenPGPAlgorithm
+	created   uint32
+	material  []byte
+	// secret is the secret key material, or nil for a public key.
+	secret      []byte
+	fingerprint []byte
+	// keyFlags is the key flags octet, and hasKeyFlags whether the self-signature has one.
+	keyFlags    byte
+	hasKeyFlags bool
+	// expires is the key lifetime in seconds, or 0 if the key does not expire.
+	expires uint32
+}
+
+// newOpenPGPKeyPacket returns a key and computes its fingerprint.
+func newOpenPGPKeyPacket(algorithm OpenPGPAlgorithm, created uint32, material []byte, secret []byte) *openPGPKeyPacket {
+	key := &openPGPKeyPacket{algorithm: algorithm, created: created, material: material, secret: secret}
+	fingerprint := sha256.Sum256(key.hashInput())
+	key.fingerprint = fingerprint[:]
+	return key
+}
+
+// generateOpenPGPKeyPacket generates a key of the algorithm.
+func generateOpenPGPKeyPacket(algorithm OpenPGPAlgorithm, created uint32) (*openPGPKeyPacket, error) {
+	_, secretKeySize, ok := algorithm.keySizes()
+	if !ok {
+		return nil, fmt.Errorf("%w: OpenPGP algorithm %v", ErrUnknownAlgorithm, algorithm)
+	}
+	secret := make([]byte, secretKeySize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	material, err := openPGPPublicKeyMaterial(algorithm, secret)
+	if err != nil {
+		return nil, err
+	}
+	return newOpenPGPKeyPacket(algorithm, created, material, secret), nil
+}
+
+// openPGPPublicKeyMaterial derives the public key material from the secret key material.
+func openPGPPublicKeyMaterial(algorithm OpenPGPAlgorithm, secret []byte) ([]byte, error) {
+	switch algorithm {
+	case OpenPGPEd25519:
+		return ed25519.NewKeyFromSeed(secret).Public().(ed25519.PublicKey), nil
+	case OpenPGPX25519:
+		x25519PrivateKey, err := ecdh.X25519().NewPrivateKey(secret)
+		if err != nil {
+			return nil, err
+		}
+		return x25519PrivateKey.PublicKey().Bytes(), nil
+	case OpenPGPMLDSA65Ed25519:
+		keyPair, err := DeriveMLDSAKeyPair(MLDSA65, (*[MLDSASeedSize]byte)(secret[ed25519.SeedSize:]))
+		if err != nil {
+			return nil, err
+		}
+		material := ed25519.NewKeyFromSeed(secret[:ed25519.SeedSize]).Public().(ed25519.PublicKey)
+		return append(material, keyPair.PublicKey...), nil
+	case OpenPGPMLKEM768X25519:
+		x25519PrivateKey, err := ecdh.X25519().NewPrivateKey(secret[:32])
+		if err != nil {
+			return nil, err
+		}
+		keyPair, err := GenerateDeterministicMLKEMKeyPair(MLKEM768, secret[32:])
+		if err != nil {
+			return nil, err
+		}
+		mlkemPublicKey, err := MarshalPublicKey(keyPair.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		return append(x25519PrivateKey.PublicKey().Bytes(), mlkemPublicKey...), nil
+	default:
+		return nil, fmt.Errorf("%w: OpenPGP algorithm %v", ErrUnknownAlgorithm, algorithm)
+	}
+}
+
+// body returns the public key packet body: version || creation time || algorithm || material size || material.
+func (key *openPGPKeyPacket) body() []byte {
+	body := make([]byte, 0, 10+len(key.material))
+	body = binary.BigEndian.AppendUint32(append(body, openPGPKeyVersion6), key.created)
+	body = binary.BigEndian.AppendUint32(append(body, byte(key.algorithm)), uint32(len(key.material)))
+	return append(body, key.material...)
+}
+
+// hashInput returns the key as it is hashed into fingerprints and key signatures.
+func (key *openPGPKeyPacket) hashInput() []byte {
+	body := key.body()
+	return append(binary.BigEndian.AppendUint32([]byte{0x9b}, uint32(len(body))), body...)
+}
+
+// secretBody returns the secret key packet body, with the S2K usage octet 0 for unencrypted secret key material.
+func (key *openPGPKeyPacket) secretBody() []byte {
+	return append(append(key.body(), 0), key.secret...)
+}
+
+// public returns a copy of the key without its secret key material.
+func (key *openPGPKeyPacket) public() *openPGPKeyPacket {
+	public := *key
+	public.secret = nil
+	return &public
+}
+
+// usable reports whether the key may be used for the key flags at the time, according to its self-signature.
+func (key *openPGPKeyPacket) usable(keyFlags byte, now time.Time) bool {
+	if key.hasKeyFlags && key.keyFlags&keyFlags == 0 {
+		return false
+	}
+	return key.expires == 0 || now.Unix() < int64(key.created)+int64(key.expires)
+}
+
+// parseOpenPGPKeyPacket parses a v6 public key or, if secret, secret key packet.
+func parseOpenPGPKeyPacket(packet *openPGPPacket, secret bool) (*openPGPKeyPacket, error) {
+	fields := packet.fields()
+	version, ok := fields.take(1)
+	if !ok {
+		return nil, fields.formatError("truncated key version")
+	}
+	if version[0] != openPGPKeyVersion6 {
+		return nil, fields.formatError("unsupported key version %d, only version 6 is supported", version[0])
+	}
+	created, ok := fields.take(4)
+	if !ok {
+		return nil, fields.formatError("truncated key creation time")
+	}
+	algorithmByte, ok := fields.take(1)
+	if !ok {
+		return nil, fields.formatError("truncated key algorithm")
+	}
+	algorithm := OpenPGPAlgorithm(algorithmByte[0])
+	publicKeySize, secretKeySize, ok := algorithm.keySizes()
+	if !ok {
+		return nil, fields.formatError("unsupported public-key algorithm %d", algorithmByte[0])
+	}
+	materialSize, ok := fields.take(4)
+	if !ok {
+		return nil, fields.formatError("truncated key material size")
+	}
+	if binary.BigEndian.Uint32(materialSize) != uint32(publicKeySize) {
+		return nil, fields.formatError("%v key material of %d bytes, want %d", algorithm, binary.BigEndian.Uint32(materialSize), publicKeySize)
+	}
+	material, ok := fields.take(publicKeySize)
+	if !ok {
+		return nil, fields.formatError("truncated %v key material", algorithm)
+	}
+	if algorithm == OpenPGPMLKEM768X25519 {
+		if err := ValidateEncapsulationKey(MLKEM768, material[32:]); err != nil {
+			return nil, fmt.Errorf("%w: %w", fields.formatError("invalid %v key material", algorithm), err)
+		}
+	}
+	key := newOpenPGPKeyPacket(algorithm, binary.BigEndian.Uint32(created), material, nil)
+	if !secret {
+		if _, ok := fields.take(1); ok {
+			return nil, fields.formatError("trailing data after key material")
+		}
+		return key, nil
+	}
+
+	usage, ok := fields.take(1)
+	if !ok {
+		return nil, fields.formatError("truncated S2K usage")
+	}
+	if usage[0] != 0 {
+		return nil, fields.formatError("encrypted secret keys are not supported")
+	}
+	key.secret, ok = fields.take(secretKeySize)
+	if !ok {
+		return nil, fields.formatError("truncated %v secret key material", algorithm)
+	}
+	if _, ok := fields.take(1); ok {
+		return nil, fields.formatError("trailing data after secret key material")
+	}
+	derivedMaterial, err := openPGPPublicKeyMaterial(algorithm, key.secret)
+	if err != nil || !bytes.Equal(derivedMaterial, material) {
+		return nil, fields.formatError("%v secret key material does not match the public key", algorithm)
+	}
+	return key, nil
+}
+
+// sign signs a digest with the secret key.
+func (key *openPGPKeyPacket) sign(digest []byte) ([]byte, error) {
+	if key.secret == nil {
+		return nil, fmt.Errorf("%w: OpenPGP key has no secret key material", ErrInvalidKey)
+	}
+	switch key.algorithm {
+	case OpenPGPEd25519:
+		return ed25519.Sign(ed25519.NewKeyFromSeed(key.secret), digest), nil
+	case OpenPGPMLDSA65Ed25519:
+		keyPair, err := DeriveMLDSAKeyPair(MLDSA65, (*[MLDSASeedSize]byte)(key.secret[ed25519.SeedSize:]))
+		if err != nil {
+			return nil, err
+		}
+		mldsaSignature, err := MLDSASign(MLDSA65, keyPair.PrivateKey, digest)
+		if err != nil {
+			return nil, err
+		}
+		return append(ed25519.Sign(ed25519.NewKeyFromSeed(key.secret[:ed25519.SeedSize]), digest), mldsaSignature...), nil
+	default:
+		return nil, fmt.Errorf("%w: OpenPGP algorithm %v cannot sign", ErrInvalidKey, key.algorithm)
+	}
+}
+
+// verify reports whether the signature material is a valid signature of the digest. A composite signature is only
+// valid if both component signatures are.
+func (key *openPGPKeyPacket) verify(digest []byte, signatureMaterial []byte) bool {
+	switch key.algorithm {
+	case OpenPGPEd25519:
+		return ed25519.Verify(key.material, digest, signatureMaterial)
+	case OpenPGPMLDSA65Ed25519:
+		if !ed25519.Verify(key.material[:ed25519.PublicKeySize], digest, signatureMaterial[:ed25519.SignatureSize]) {
+			return false
+		}
+		isSignatureValid, err := MLDSAVerify(MLDSA65, key.material[ed25519.PublicKeySize:], digest, signatureMaterial[ed25519.SignatureSize:])
+		return err == nil && isSignatureValid
+	default:
+		return false
+	}
+}
+
+// openPGPCompositeKEK returns the ML-KEM-768+X25519 key encryption key, the multiKeyCombine function of the OpenPGP
+// PQC draft.
+func openPGPCompositeKEK(mlkemSharedSecret, x25519SharedSecret, x25519Ephemeral, x25519Recipient []byte) []byte {
+	kekHash := sha3.New256()
+	kekHash.Write(mlkemSharedSecret)
+	kekHash.Write(x25519SharedSecret)
+	kekHash.Write(x25519Ephemeral)
+	kekHash.Write(x25519Recipient)
+	kekHash.Write([]byte{byte(OpenPGPMLKEM768X25519)})
+	kekHash.Write([]byte(openPGPCompositeKDFDomain))
+	kekHash.Write([]byte{byte(len(openPGPCompositeKDFDomain))})
+	return kekHash.Sum(nil)
+}
+
+// openPGPX25519KEK returns the RFC 9580 X25519 key encryption key.
+func openPGPX25519KEK(x25519SharedSecret, x25519Ephemeral, x25519Recipient []byte) ([]byte, error) {
+	inputKeyMaterial := append(append(append([]byte(nil), x25519Ephemeral...), x25519Recipient...), x25519SharedSecret...)
+	return hkdf.Key(sha256.New, inputKeyMaterial, nil, openPGPX25519KDFInfo, 16)
+}
+
+// encryptSessionKey returns the algorithm-specific fields of a v6 PKESK packet wrapping the session key for the key.
+func (key *openPGPKeyPacket) encryptSessionKey(sessionKey []byte) ([]byte, error) {
+	x25519PublicKey, err := ecdh.X25519().NewPublicKey(key.material[:32])
+	if err != nil {
+		return nil, err
+	}
+	ephemeralKey, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	x25519SharedSecret, err := ephemeralKey.ECDH(x25519PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	fields := ephemeralKey.PublicKey().Bytes()
+
+	var kek []byte
+	switch key.algorithm {
+	case OpenPGPX25519:
+		if kek, err = openPGPX25519KEK(x25519SharedSecret, fields, key.material); err != nil {
+			return nil, err
+		}
+	case OpenPGPMLKEM768X25519:
+		mlkemPublicKey, err := UnmarshalPublicKey(MLKEM768, key.material[32:])
+		if err != nil {
+			return nil, err
+		}
+		mlkemCiphertext, mlkemSharedSecret, err := MLKEMEncapsulate(mlkemPublicKey)
+		if err != nil {
+			return nil, err
+		}
+		kek = openPGPCompositeKEK(mlkemSharedSecret, x25519SharedSecret, fields, key.material[:32])
+		fields = append(fields, mlkemCiphertext...)
+	default:
+		return nil, fmt.Errorf("%w: OpenPGP algorithm %v cannot encrypt", ErrInvalidKey, key.algorithm)
+	}
+	wrappedKey, err := keywrap.Wrap(kek, sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(fields, byte(len(wrappedKey))), wrappedKey...), nil
+}
+
+// openPGPPKESK is a parsed v6 PKESK packet.
+type openPGPPKESK struct {
+	// fingerprint is the recipient key fingerprint, or nil for an anonymous recipient.
+	fingerprint     []byte
+	algorithm       OpenPGPAlgorithm
+	x25519Ephemeral []byte
+	mlkemCiphertext []byte
+	wrappedKey      []byte
+}
+
+// parseOpenPGPPKESK parses a PKESK packet. It returns nil for a PKESK of another version or algorithm, which no v6 key
+// of this package can decrypt.
+func parseOpenPGPPKESK(packet *openPGPPacket) (*openPGPPKESK, error) {
+	fields := packet.fields()
+	header, ok := fields.take(3)
+	if !ok {
+		return nil, fields.formatError("truncated PKESK header")
+	}
+	if header[0] != openPGPKeyVersion6 {
+		return nil, nil
+	}
+	pkesk := &openPGPPKESK{}
+	switch header[1] {
+	case 0:
+		pkesk.algorithm = OpenPGPAlgorithm(header[2])
+	case 1 + openPGPFingerprintSize:
+		if header[2] != openPGPKeyVersion6 {
+			return nil, nil
+		}
+		if pkesk.fingerprint, ok = fields.take(openPGPFingerprintSize); !ok {
+			return nil, fields.formatError("truncated PKESK fingerprint")
+		}
+		algorithm, ok := fields.take(1)
+		if !ok {
+			return nil, fields.formatError("truncated PKESK algorithm")
+		}
+		pkesk.algorithm = OpenPGPAlgorithm(algorithm[0])
+	default:
+		return nil, nil
+	}
+	if !pkesk.algorithm.canEncrypt() {
+		return nil, nil
+	}
+	if pkesk.x25519Ephemeral, ok = fields.take(32); !ok {
+		return nil, fields.formatError("truncated PKESK ephemeral key")
+	}
+	if pkesk.algorithm == OpenPGPMLKEM768X25519 {
+		if pkesk.mlkemCiphertext, ok = fields.take(mlkem768.CiphertextSize); !ok {
+			return nil, fields.formatError("truncated PKESK ML-KEM ciphertext")
+		}
+	}
+	wrappedKeySize, ok := fields.take(1)
+	if !ok {
+		return nil, fields.formatError("truncated PKESK wrapped key size")
+	}
+	if pkesk.wrappedKey, ok = fields.take(int(wrappedKeySize[0])); !ok {
+		return nil, fields.formatError("truncated PKESK wrapped key")
+	}
+	if len(pkesk.wrappedKey) < 24 || len(pkesk.wrappedKey)%8 != 0 {
+		return nil, fields.formatError("invalid PKESK wrapped key size %d", len(pkesk.wrappedKey))
+	}
+	if _, ok := fields.take(1); ok {
+		return nil, fields.formatError("trailing data after PKESK")
+	}
+	return pkesk, nil
+}
+
+// decryptSessionKey unwraps the session key of the PKESK with the secret key. A PKESK for another key returns
+// ErrDecryptionFailed.
+func (key *openPGPKeyPacket) decryptSessionKey(pkesk *openPGPPKESK) ([]byte, error) {
+	if pkesk.algorithm != key.algorithm {
+		return nil, fmt.Errorf("%w: %v PKESK for a %v key", ErrDecryptionFailed, pkesk.algorithm, key.algorithm)
+	}
+	x25519PrivateKey, err := ecdh.X25519().NewPrivateKey(key.secret[:32])
+	if err != nil {
+		return nil, err
+	}
+	ephemeralKey, err := ecdh.X25519().NewPublicKey(pkesk.x25519Ephemeral)
+	if err != nil {
+		return nil, err
+	}
+	x25519SharedSecret, err := x25519PrivateKey.ECDH(ephemeralKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecryptionFailed, err)
+	}
+
+	var kek []byte
+	switch key.algorithm {
+	case OpenPGPX25519:
+		if kek, err = openPGPX25519KEK(x25519SharedSecret, pkesk.x25519Ephemeral, key.material); err != nil {
+			return nil, err
+		}
+	case OpenPGPMLKEM768X25519:
+		keyPair, err := GenerateDeterministicMLKEMKeyPair(MLKEM768, key.secret[32:])
+		if err != nil {
+			return nil, err
+		}
+		mlkemSharedSecret, err := MLKEMDecapsulate(keyPair.PrivateKey, pkesk.mlkemCiphertext)
+		if err != nil {
+			return nil, err
+		}
+		kek = openPGPCompositeKEK(mlkemSharedSecret, x25519SharedSecret, pkesk.x25519Ephemeral, key.material[:32])
+	default:
+		return nil, fmt.Errorf("%w: OpenPGP algorithm %v cannot decrypt", ErrInvalidKey, key.algorithm)
+	}
+	sessionKey, err := keywrap.Unwrap(kek, pkesk.wrappedKey)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return sessionKey, nil
+}
+
+// openPGPSignature is a parsed v6 signature packet.
+type openPGPSignature struct {
+	sigType   byte
+	algorithm OpenPGPAlgorithm
+	hashID    byte
+	// hashedSize is the size of the hashed subpacket area.
+	hashedSize int
+	salt       []byte
+	material   []byte
+	// body is the packet body, which is also hashed up to the end of the hashed subpacket area.
+	body []byte
+	// The fields below come from the hashed subpackets, except issuer and embedded, which may be unhashed.
+	created     uint32
+	expires     uint32
+	keyExpires  uint32
+	keyFlags    byte
+	hasKeyFlags bool
+	issuer      []byte
+	embedded    []byte
+	// unknownCritical is set if a critical subpacket is not understood, which makes the signature invalid.
+	unknownCritical bool
+}
+
+// openPGPSignatureSupported reports whether a signature packet body is of the version and an algorithm this package
+// verifies, so that other signatures on a certificate can be skipped.
+func openPGPSignatureSupported(body []byte) bool {
+	return len(body) >= 3 && body[0] == openPGPKeyVersion6 && OpenPGPAlgorithm(body[2]).canSign()
+}
+
+// parseOpenPGPSignature parses a v6 signature packet body; offset is its offset in the parsed data.
+func parseOpenPGPSignature(body []byte, offset int) (*openPGPSignature, error) {
+	fields := &openPGPFields{data: body, base: offset}
+	header, ok := fields.take(4)
+	if !ok {
+		return nil, fields.formatError("truncated signature header")
+	}
+	if header[0] != openPGPKeyVersion6 {
+		return nil, fields.formatError("unsupported signature version %d, only version 6 is supported", header[0])
+	}
+	signature := &openPGPSignature{sigType: header[1], algorithm: OpenPGPAlgorithm(header[2]), hashID: header[3], body: body}
+	if !signature.algorithm.canSign() {
+		return nil, fields.formatError("unsupported signature algorithm %d", header[2])
+	}
+	hashedArea, ok := takeOpenPGPSubpacketArea(fields)
+	if !ok {
+		return nil, fields.formatError("truncated hashed subpackets")
+	}
+	signature.hashedSize = len(hashedArea)
+	hashedSubpacketsOffset := fields.fieldOffset
+	unhashedArea, ok := takeOpenPGPSubpacketArea(fields)
+	if !ok {
+		return nil, fields.formatError("truncated unhashed subpackets")
+	}
+	unhashedSubpacketsOffset := fields.fieldOffset
+	if _, ok := fields.take(2); !ok {
+		return nil, fields.formatError("truncated digest prefix")
+	}
+	saltSize, ok := fields.take(1)
+	if !ok {
+		return nil, fields.formatError("truncated salt size")
+	}
+	_, wantSaltSize, err := newOpenPGPHash(signature.hashID)
+	if err != nil {
+		return nil, fields.formatError("%v", err)
+	}
+	if int(saltSize[0]) != wantSaltSize {
+		return nil, fields.formatError("salt of %d bytes, want %d for hash algorithm %d", saltSize[0], wantSaltSize, signature.hashID)
+	}
+	if signature.salt, ok = fields.take(wantSaltSize); !ok {
+		return nil, fields.formatError("truncated salt")
+	}
+	signature.material = fields.rest()
+	if len(signature.material) != signature.algorithm.signatureSize() {
+		return nil, fields.formatError("%v signature of %d bytes, want %d", signature.algorithm, len(signature.material), signature.algorithm.signatureSize())
+	}
+
+	for _, area := range []struct {
+		subpackets []byte
+		offset     int
+		hashed     bool
+	}{{hashedArea, hashedSubpacketsOffset, true}, {unhashedArea, unhashedSubpacketsOffset, false}} {
+		subpackets, err := parseOpenPGPSubpackets(area.subpackets)
+		if err != nil {
+			return nil, &FormatError{Err: ErrInvalidOpenPGPPacket, Offset: offset + area.offset, Reason: err.Error()}
+		}
+		for _, subpacket := range subpackets {
+			if !signature.applySubpacket(subpacket, area.hashed) && subpacket.critical {
+				signature.unknownCritical = true
+			}
+		}
+	}
+	if signature.created == 0 {
+		return nil, &FormatError{Err: ErrInvalidOpenPGPPacket, Offset: offset + hashedSubpacketsOffset, Reason: "missing signature creation time"}
+	}
+	return signature, nil
+}
+
+// takeOpenPGPSubpacketArea takes a subpacket area with a four-octet size.
+func takeOpenPGPSubpacketArea(fields *openPGPFields) ([]byte, bool) {
+	size, ok := fields.take(4)
+	if !ok {
+		return nil, false
+	}
+	return fields.take(int(binary.BigEndian.Uint32(size)))
+}
+
+// applySubpacket records what the signature needs from a subpacket, and reports whether the subpacket is understood.
+// Subpackets with a meaning for verification are only honored in the hashed area.
+func (signature *openPGPSignature) applySubpacket(subpacket openPGPSubpacket, hashed bool) bool {
+	switch {
+	case subpacket.kind == openPGPSubpacketIssuerFingerprint:
+		if len(subpacket.data) == 1+openPGPFingerprintSize && subpacket.data[0] == openPGPKeyVersion6 && signature.issuer == nil {
+			signature.issuer = subpacket.data[1:]
+		}
+	case subpacket.kind == openPGPSubpacketEmbeddedSignature:
+		if signature.embedded == nil {
+			signature.embedded = subpacket.data
+		}
+	case subpacket.kind == openPGPSubpacketIssuerKeyID, subpacket.kind == openPGPSubpacketRevocationReason:
+	case !hashed:
+		return false
+	case subpacket.kind == openPGPSubpacketCreationTime && len(subpacket.data) == 4:
+		signature.created = binary.BigEndian.Uint32(subpacket.data)
+	case subpacket.kind == openPGPSubpacketExpirationTime && len(subpacket.data) == 4:
+		signature.expires = binary.BigEndian.Uint32(subpacket.data)
+	case subpacket.kind == openPGPSubpacketKeyExpirationTime && len(subpacket.data) == 4:
+		signature.keyExpires = binary.BigEndian.Uint32(subpacket.data)
+	case subpacket.kind == openPGPSubpacketKeyFlags && len(subpacket.data) > 0:
+		signature.keyFlags, signature.hasKeyFlags = subpacket.data[0], true
+	case subpacket.kind == openPGPSubpacketPreferredCiphers, subpacket.kind == openPGPSubpacketPreferredHashes,
+		subpacket.kind == openPGPSubpacketPrimaryUserID, subpacket.kind == openPGPSubpacketFeatures,
+		subpacket.kind == openPGPSubpacketPreferredAEADSuites:
+	default:
+		return false
+	}
+	return true
+}
+
+// digest returns the hash of the salt, the signed data and the signature trailer.
+func (signature *openPGPSignature) digest(signedData ...[]byte) ([]byte, error) {
+	signatureHash, _, err := newOpenPGPHash(signature.hashID)
+	if err != nil {
+		return nil, err
+	}
+	signatureHash.Write(signature.salt)
+	for _, data := range signedData {
+		signatureHash.Write(data)
+	}
+	hashedSize := 8 + signature.hashedSize
+	signatureHash.Write(signature.body[:hashedSize])
+	signatureHash.Write(binary.BigEndian.AppendUint32([]byte{openPGPKeyVersion6, 0xff}, uint32(hashedSize)))
+	return signatureHash.Sum(nil), nil
+}
+
+// verify reports whether the signature by the key over the signed data is valid and unexpired at the time.
+func (signature *openPGPSignature) verify(key *openPGPKeyPacket, now time.Time, signedData ...[]byte) bool {
+	if signature.algorithm != key.algorithm || signature.unknownCritical {
+		return false
+	}
+	if signature.issuer != nil && !bytes.Equal(signature.issuer, key.fingerprint) {
+		return false
+	}
+	if signature.expires != 0 && now.Unix() >= int64(signature.created)+int64(signature.expires) {
+		return false
+	}
+	digest, err := signature.digest(signedData...)
+	if err != nil || len(digest) < 32 {
+		return false
+	}
+	return key.verify(digest, signature.material)
+}
+
+// newOpenPGPSignature returns the body of a v6 signature of the signed data by the key, with the creation time and
+// issuer fingerprint subpackets followed by the extra hashed subpackets.
+func newOpenPGPSignature(key *openPGPKeyPacket, sigType byte, created uint32, extraSubpackets []byte, signedData ...[]byte) ([]byte, error) {
+	hashed := appendOpenPGPSubpacket(nil, openPGPSubpacketCreationTime, true, binary.BigEndian.AppendUint32(nil, created))
+	hashed = appendOpenPGPSubpacket(hashed, openPGPSubpacketIssuerFingerprint, true, append([]byte{openPGPKeyVersion6}, key.fingerprint...))
+	hashed = append(hashed, extraSubpackets...)
+	body := []byte{openPGPKeyVersion6, sigType, byte(key.algorithm), key.algorithm.signatureHash()}
+	body = append(binary.BigEndian.AppendUint32(body, uint32(len(hashed))), hashed...)
+	signature := &openPGPSignature{hashID: key.algorithm.signatureHash(), hashedSize: len(hashed), body: body}
+	_, saltSize, err := newOpenPGPHash(signature.hashID)
+	if err != nil {
+		return nil, err
+	}
+	signature.salt = make([]byte, saltSize)
+	if _, err := rand.Read(signature.salt); err != nil {
+		return nil, err
+	}
+	digest, err := signature.digest(signedData...)
+	if err != nil {
+		return nil, err
+	}
+	material, err := key.sign(digest)
+	if err != nil {
+		return nil, err
+	}
+	body = binary.BigEndian.AppendUint32(body, 0)
+	body = append(append(body, digest[:2]...), byte(saltSize))
+	return append(append(body, signature.salt...), material...), nil
+}
+
+// openPGPUserIDHashInput returns a user ID as it is hashed into certifications.
+func openPGPUserIDHashInput(userID string) []byte {
+	return append(binary.BigEndian.AppendUint32([]byte{0xb4}, uint32(len(userID))), userID...)
+}
+
+// OpenPGPKey is a v6 OpenPGP key: a primary signing key with its user IDs and subkeys, and their self-signatures. It
+// holds secret key material if it was generated or read from a secret key. Only self-signatures that verify are kept,
+// and user IDs and subkeys without one are dropped.
+type OpenPGPKey struct {
+	primary          *openPGPKeyPacket
+	directSignatures [][]byte
+	userIDs          []openPGPUserID
+	subkeys          []openPGPSubkey
+}
+
+// openPGPUserID is a user ID and its valid certifications.
+type openPGPUserID struct {
+	userID     string
+	signatures [][]byte
+}
+
+// openPGPSubkey is a subkey and its valid binding signatures.
+type openPGPSubkey struct {
+	key        *openPGPKeyPacket
+	signatures [][]byte
+}
+
+// GenerateOpenPGPKey generates a v6 key with an ML-DSA-65+Ed25519 primary key for certification and signing and an
+// ML-KEM-768+X25519 subkey for encryption. The user ID, for example "Alice <alice@example.org>", may be empty.
+func GenerateOpenPGPKey(userID string) (key *OpenPGPKey, keyGenerationError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			key, keyGenerationError = nil, newPanicError("GenerateOpenPGPKey", recoveredPanic)
+		}
+	}()
+	created := uint32(time.Now().Unix())
+	primary, err := generateOpenPGPKeyPacket(OpenPGPMLDSA65Ed25519, created)
+	if err != nil {
+		return nil, err
+	}
+	primary.keyFlags, primary.hasKeyFlags = openPGPKeyFlagCertify|openPGPKeyFlagSign, true
+	subkey, err := generateOpenPGPKeyPacket(OpenPGPMLKEM768X25519, created)
+	if err != nil {
+		return nil, err
+	}
+	subkey.keyFlags, subkey.hasKeyFlags = openPGPKeyFlagEncryptComms|openPGPKeyFlagEncryptStorage, true
+	key = &OpenPGPKey{primary: primary}
+
+	preferences := appendOpenPGPSubpacket(nil, openPGPSubpacketKeyFlags, true, []byte{primary.keyFlags})
+	preferences = appendOpenPGPSubpacket(preferences, openPGPSubpacketFeatures, false, []byte{openPGPFeatureSEIPDv2})
+	preferences = appendOpenPGPSubpacket(preferences, openPGPSubpacketPreferredCiphers, false, []byte{openPGPCipherAES256, openPGPCipherAES128})
+	preferences = appendOpenPGPSubpacket(preferences, openPGPSubpacketPreferredHashes, false, []byte{openPGPHashSHA3_256, openPGPHashSHA3_512, openPGPHashSHA512, openPGPHashSHA256})
+	preferences = appendOpenPGPSubpacket(preferences, openPGPSubpacketPreferredAEADSuites, false, []byte{openPGPCipherAES256, openPGPAEADOCB, openPGPCipherAES128, openPGPAEADOCB})
+	directSignature, err := newOpenPGPSignature(primary, openPGPSignatureDirectKey, created, preferences, primary.hashInput())
+	if err != nil {
+		return nil, err
+	}
+	key.directSignatures = [][]byte{directSignature}
+
+	if userID != "" {
+		certification := appendOpenPGPSubpacket(nil, openPGPSubpacketKeyFlags, true, []byte{primary.keyFlags})
+		certification = appendOpenPGPSubpacket(certification, openPGPSubpacketPrimaryUserID, false, []byte{1})
+		userIDSignature, err := newOpenPGPSignature(primary, openPGPSignaturePositiveCert, created, certification, primary.hashInput(), openPGPUserIDHashInput(userID))
+		if err != nil {
+			return nil, err
+		}
+		key.userIDs = []openPGPUserID{{userID: userID, signatures: [][]byte{userIDSignature}}}
+	}
+
+	binding := appendOpenPGPSubpacket(nil, openPGPSubpacketKeyFlags, true, []byte{subkey.keyFlags})
+	bindingSignature, err := newOpenPGPSignature(primary, openPGPSignatureSubkeyBinding, created, binding, primary.hashInput(), subkey.hashInput())
+	if err != nil {
+		return nil, err
+	}
+	key.subkeys = []openPGPSubkey{{key: subkey, signatures: [][]byte{bindingSignature}}}
+	Logger().Debug("GenerateOpenPGPKey", "fingerprint", fmt.Sprintf("%X", primary.fingerprint), "user_ids", len(key.userIDs))
+	return key, nil
+}
+
+// ReadOpenPGPKey reads a v6 certificate or secret key, ASCII armored or binary. It verifies the self-signatures and
+// keeps the user IDs and subkeys that have a valid one.
+//
+// Malformed or unsupported packets return a *FormatError matching ErrInvalidOpenPGPPacket, a key without a valid
+// self-signature returns ErrVerificationFailed, and a revoked key returns an error.
+func ReadOpenPGPKey(data []byte) (key *OpenPGPKey, readError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			key, readError = nil, newPanicError("ReadOpenPGPKey", recoveredPanic)
+		}
+	}()
+	binaryData, err := openPGPBinary(data, OpenPGPArmorPublicKey, OpenPGPArmorPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	packets, err := parseOpenPGPPackets(binaryData)
+	if err != nil {
+		return nil, err
+	}
+	key, err = readOpenPGPKeyPackets(packets, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	Logger().Debug("ReadOpenPGPKey", "fingerprint", fmt.Sprintf("%X", key.primary.fingerprint), "private", key.IsPrivate(), "user_ids", len(key.userIDs), "subkeys", len(key.subkeys))
+	return key, nil
+}
+
+// readOpenPGPKeyPackets assembles and verifies a key from its packets.
+func readOpenPGPKeyPackets(packets []openPGPPacket, now time.Time) (*OpenPGPKey, error) {
+	if len(packets) == 0 {
+		return nil, &FormatError{Err: ErrInvalidOpenPGPPacket, Offset: 0, Reason: "no key packet"}
+	}
+	if packets[0].tag != openPGPTagPublicKey && packets[0].tag != openPGPTagSecretKey {
+		return nil, packets[0].fields().formatError("expected a key packet, got a tag %d packet", packets[0].tag)
+	}
+	primary, err := parseOpenPGPKeyPacket(&packets[0], packets[0].tag == openPGPTagSecretKey)
+	if err != nil {
+		return nil, err
+	}
+	if !primary.algorithm.canSign() {
+		return nil, packets[0].fields().formatError("%v cannot be a primary key algorithm", primary.algorithm)
+	}
+
+	// Group the packets into components: the primary key itself, user IDs and subkeys, each with its signatures.
+	type component struct {
+		tag        byte
+		userID     string
+		subkey     *openPGPKeyPacket
+		signatures []*openPGPSignature
+	}
+	components := []*component{{tag: packets[0].tag}}
+	for i := 1; i < len(packets); i++ {
+		packet := &packets[i]
+		current := components[len(components)-1]
+		switch packet.tag {
+		case openPGPTagSignature:
+			if !openPGPSignatureSupported(packet.body) {
+				continue
+			}
+			signature, err := parseOpenPGPSignature(packet.body, packet.offset)
+			if err != nil {
+				return nil, err
+			}
+			current.signatures = append(current.signatures, signature)
+		case openPGPTagUserID:
+			components = append(components, &component{tag: packet.tag, userID: string(packet.body)})
+		case openPGPTagUserAttribute:
+			components = append(components, &component{tag: packet.tag})
+		case openPGPTagPublicSubkey, openPGPTagSecretSubkey:
+			subkey, err := parseOpenPGPKeyPacket(packet, packet.tag == openPGPTagSecretSubkey)
+			if err != nil {
+				return nil, err
+			}
+			components = append(components, &component{tag: packet.tag, subkey: subkey})
+		case openPGPTagTrust, openPGPTagMarker, openPGPTagPadding:
+		case openPGPTagPublicKey, openPGPTagSecretKey:
+			return nil, packet.fields().formatError("more than one key")
+		default:
+			return nil, packet.fields().formatError("unexpected tag %d packet in key", packet.tag)
+		}
+	}
+
+	key := &OpenPGPKey{primary: primary}
+	// selfSignature is the newest valid self-signature that configures the primary key.
+	var selfSignature *openPGPSignature
+	newer := func(signature *openPGPSignature, than *openPGPSignature) bool {
+		return than == nil || signature.created >= than.created
+	}
+	for _, signature := range components[0].signatures {
+		switch {
+		case signature.sigType == openPGPSignatureKeyRevocation && signature.verify(primary, now, primary.hashInput()):
+			return nil, fmt.Errorf("%w: OpenPGP key %X is revoked", ErrInvalidKey, primary.fingerprint)
+		case signature.sigType == openPGPSignatureDirectKey && signature.verify(primary, now, primary.hashInput()):
+			key.directSignatures = append(key.directSignatures, signature.body)
+			if newer(signature, selfSignature) {
+				selfSignature = signature
+			}
+		}
+	}
+	directSelfSignature := selfSignature
+	for _, userIDComponent := range components[1:] {
+		if userIDComponent.tag != openPGPTagUserID {
+			continue
+		}
+		userID := openPGPUserID{userID: userIDComponent.userID}
+		revoked := false
+		var newestCertification *openPGPSignature
+		for _, signature := range userIDComponent.signatures {
+			isCertification := signature.sigType >= openPGPSignatureGenericCert && signature.sigType <= openPGPSignaturePositiveCert
+			if !isCertification && signature.sigType != openPGPSignatureCertRevocation {
+				continue
+			}
+			if !signature.verify(primary, now, primary.hashInput(), openPGPUserIDHashInput(userID.userID)) {
+				continue
+			}
+			if !isCertification {
+				revoked = true
+				break
+			}
+			userID.signatures = append(userID.signatures, signature.body)
+			if newer(signature, newestCertification) {
+				newestCertification = signature
+			}
+		}
+		if revoked || newestCertification == nil {
+			continue
+		}
+		key.userIDs = append(key.userIDs, userID)
+		// The direct-key signature configures a v6 primary key; user ID certifications only without one.
+		if directSelfSignature == nil && newer(newestCertification, selfSignature) {
+			selfSignature = newestCertification
+		}
+	}
+	if selfSignature == nil {
+		return nil, fmt.Errorf("%w: OpenPGP key %X has no valid self-signature", ErrVerificationFailed, primary.fingerprint)
+	}
+	primary.keyFlags, primary.hasKeyFlags, primary.expires = selfSignature.keyFlags, selfSignature.hasKeyFlags, selfSignature.keyExpires
+
+	for _, subkeyComponent := range components[1:] {
+		if subkeyComponent.subkey == nil {
+			continue
+		}
+		subkey := openPGPSubkey{key: subkeyComponent.subkey}
+		revoked := false
+		var binding *openPGPSignature
+		for _, signature := range subkeyComponent.signatures {
+			if signature.sigType != openPGPSignatureSubkeyBinding && signature.sigType != openPGPSignatureSubkeyRevocation {
+				continue
+			}
+			if !signature.verify(primary, now, primary.hashInput(), subkey.key.hashInput()) {
+				continue
+			}
+			if signature.sigType == openPGPSignatureSubkeyRevocation {
+				revoked = true
+				break
+			}
+			if signature.hasKeyFlags && signature.keyFlags&openPGPKeyFlagSign != 0 && !verifyOpenPGPPrimaryBinding(signature, primary, subkey.key, now) {
+				continue
+			}
+			subkey.signatures = append(subkey.signatures, signature.body)
+			if newer(signature, binding) {
+				binding = signature
+			}
+		}
+		if revoked || binding == nil {
+			continue
+		}
+		subkey.key.keyFlags, subkey.key.hasKeyFlags, subkey.key.expires = binding.keyFlags, binding.hasKeyFlags, binding.keyExpires
+		key.subkeys = append(key.subkeys, subkey)
+	}
+	return key, nil
+}
+
+// verifyOpenPGPPrimaryBinding reports whether a subkey binding signature embeds a valid primary key binding signature
+// by the subkey, which a signing subkey needs so that nobody can claim another's subkey.
+func verifyOpenPGPPrimaryBinding(binding *openPGPSignature, primary *openPGPKeyPacket, subkey *openPGPKeyPacket, now time.Time) bool {
+	if binding.embedded == nil || !openPGPSignatureSupported(binding.embedded) {
+		return false
+	}
+	embedded, err := parseOpenPGPSignature(binding.embedded, 0)
+	if err != nil {
+		return false
+	}
+	return embedded.sigType == openPGPSignaturePrimaryBinding && embedded.verify(subkey, now, primary.hashInput(), subkey.hashInput())
+}
+
+// Fingerprint returns the 32-byte v6 fingerprint of the primary key.
+func (key *OpenPGPKey) Fingerprint() []byte {
+	return append([]byte(nil), key.primary.fingerprint...)
+}
+
+// Algorithm returns the algorithm of the primary key.
+func (key *OpenPGPKey) Algorithm() OpenPGPAlgorithm {
+	return key.primary.algorithm
+}
+
+// UserIDs returns the user IDs with a valid certification.
+func (key *OpenPGPKey) UserIDs() []string {
+	userIDs := make([]string, len(key.userIDs))
+	for i, userID := range key.userIDs {
+		userIDs[i] = userID.userID
+	}
+	return userIDs
+}
+
+// IsPrivate reports whether the key holds secret key material.
+func (key *OpenPGPKey) IsPrivate() bool {
+	return key.primary.secret != nil
+}
+
+// Public returns the key without its secret key material.
+func (key *OpenPGPKey) Public() *OpenPGPKey {
+	public := &OpenPGPKey{primary: key.primary.public(), directSignatures: key.directSignatures, userIDs: key.userIDs}
+	for _, subkey := range key.subkeys {
+		public.subkeys = append(public.subkeys, openPGPSubkey{key: subkey.key.public(), signatures: subkey.signatures})
+	}
+	return public
+}
+
+// serialize returns the transferable public key or, if private, secret key.
+func (key *OpenPGPKey) serialize(private bool) []byte {
+	var data []byte
+	if private {
+		data = appendOpenPGPPacket(data, openPGPTagSecretKey, key.primary.secretBody())
+	} else {
+		data = appendOpenPGPPacket(data, openPGPTagPublicKey, key.primary.body())
+	}
+	for _, signature := range key.directSignatures {
+		data = appendOpenPGPPacket(data, openPGPTagSignature, signature)
+	}
+	for _, userID := range key.userIDs {
+		data = appendOpenPGPPacket(data, openPGPTagUserID, []byte(userID.userID))
+		for _, signature := range userID.signatures {
+			data = appendOpenPGPPacket(data, openPGPTagSignature, signature)
+		}
+	}
+	for _, subkey := range key.subkeys {
+		if private && subkey.key.secret != nil {
+			data = appendOpenPGPPacket(data, openPGPTagSecretSubkey, subkey.key.secretBody())
+		} else {
+			data = appendOpenPGPPacket(data, openPGPTagPublicSubkey, subkey.key.body())
+		}
+		for _, signature := range subkey.signatures {
+			data = appendOpenPGPPacket(data, openPGPTagSignature, signature)
+		}
+	}
+	return data
+}
+
+// PublicKeyBytes returns the binary transferable public key (certificate).
+func (key *OpenPGPKey) PublicKeyBytes() []byte {
+	return key.serialize(false)
+}
+
+// PrivateKeyBytes returns the binary transferable secret key, with unencrypted secret key material.
+func (key *OpenPGPKey) PrivateKeyBytes() ([]byte, error) {
+	if !key.IsPrivate() {
+		return nil, fmt.Errorf("%w: OpenPGP key has no secret key material", ErrInvalidKey)
+	}
+	return key.serialize(true), nil
+}
+
+// ArmoredPublicKey returns the ASCII armored transferable public key (certificate).
+func (key *OpenPGPKey) ArmoredPublicKey() ([]byte, error) {
+	return ArmorOpenPGP(OpenPGPArmorPublicKey, key.PublicKeyBytes())
+}
+
+// ArmoredPrivateKey returns the ASCII armored transferable secret key, with unencrypted secret key material.
+func (key *OpenPGPKey) ArmoredPrivateKey() ([]byte, error) {
+	privateKeyBytes, err := key.PrivateKeyBytes()
+	if err != nil {
+		return nil, err
+	}
+	return ArmorOpenPGP(OpenPGPArmorPrivateKey, privateKeyBytes)
+}
+
+// encryptionKey returns the newest subkey valid for encryption at the time.
+func (key *OpenPGPKey) encryptionKey(now time.Time) (*openPGPKeyPacket, error) {
+	if !key.primary.usable(0xff, now) {
+		return nil, fmt.Errorf("%w: OpenPGP key %X has expired", ErrInvalidKey, key.primary.fingerprint)
+	}
+	var encryptionKey *openPGPKeyPacket
+	for _, subkey := range key.subkeys {
+		if subkey.key.algorithm.canEncrypt() && subkey.key.usable(openPGPKeyFlagEncryptComms|openPGPKeyFlagEncryptStorage, now) &&
+			(encryptionKey == nil || subkey.key.created >= encryptionKey.created) {
+			encryptionKey = subkey.key
+		}
+	}
+	if encryptionKey == nil {
+		return nil, fmt.Errorf("%w: OpenPGP key %X has no valid encryption subkey", ErrInvalidKey, key.primary.fingerprint)
+	}
+	return encryptionKey, nil
+}
+
+// signingKeys returns the primary key and subkeys valid for signing at the time, primary key first.
+func (key *OpenPGPKey) signingKeys(now time.Time) []*openPGPKeyPacket {
+	if !key.primary.usable(0xff, now) {
+		return nil
+	}
+	var signingKeys []*openPGPKeyPacket
+	if key.primary.usable(openPGPKeyFlagSign, now) {
+		signingKeys = append(signingKeys, key.primary)
+	}
+	for _, subkey := range key.subkeys {
+		// Subkeys are only kept with signing flags if they have a valid primary key binding signature.
+		if subkey.key.algorithm.canSign() && subkey.key.hasKeyFlags && subkey.key.usable(openPGPKeyFlagSign, now) {
+			signingKeys = append(signingKeys, subkey.key)
+		}
+	}
+	return signingKeys
+}
+
+// EncryptOpenPGPMessage encrypts plaintext to the encryption subkey of every recipient and returns the binary message,
+// which ArmorOpenPGP with OpenPGPArmorMessage armors. The message is a v6 PKESK packet per recipient and a version 2
+// SEIPD packet with AES-256-OCB holding a binary literal data packet.
+func EncryptOpenPGPMessage(recipients []*OpenPGPKey, plaintext []byte) (message []byte, encryptError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			message, encryptError = nil, newPanicError("EncryptOpenPGPMessage", recoveredPanic)
+		}
+	}()
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("%w: an OpenPGP message needs at least one recipient", ErrInvalidOptions)
+	}
+	sessionKey := make([]byte, openPGPSessionKeySize)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, recipient := range recipients {
+		if recipient == nil {
+			return nil, fmt.Errorf("%w: nil OpenPGP recipient", ErrInvalidKey)
+		}
+		encryptionKey, err := recipient.encryptionKey(now)
+		if err != nil {
+			return nil, err
+		}
+		fields, err := encryptionKey.encryptSessionKey(sessionKey)
+		if err != nil {
+			return nil, err
+		}
+		pkesk := append([]byte{openPGPKeyVersion6, 1 + openPGPFingerprintSize, openPGPKeyVersion6}, encryptionKey.fingerprint...)
+		message = appendOpenPGPPacket(message, openPGPTagPKESK, append(append(pkesk, byte(encryptionKey.algorithm)), fields...))
+	}
+	seipd, err := sealOpenPGPSEIPD(sessionKey, appendOpenPGPLiteral(nil, plaintext))
+	if err != nil {
+		return nil, err
+	}
+	message = appendOpenPGPPacket(message, openPGPTagSEIPD, seipd)
+	Logger().Debug("EncryptOpenPGPMessage", "recipients", len(recipients), "plaintext_size", len(plaintext), "message_size", len(message))
+	return message, nil
+}
+
+// DecryptOpenPGPMessage decrypts an ASCII armored or binary message with a secret key and returns the literal data.
+// The signatures of a signed message are not verified; DecryptAndVerifyOpenPGPMessage verifies them.
+//
+// A malformed or unsupported message returns a *FormatError matching ErrInvalidOpenPGPPacket, a message with no PKESK
+// for a subkey of the key returns ErrRecipientNotFound, and any change to the encrypted data returns
+// ErrDecryptionFailed.
+func DecryptOpenPGPMessage(key *OpenPGPKey, message []byte) (plaintext []byte, decryptError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			plaintext, decryptError = nil, newPanicError("DecryptOpenPGPMessage", recoveredPanic)
+		}
+	}()
+	literalMessage, recipients, err := decryptOpenPGPMessage(key, message)
+	if err != nil {
+		return nil, err
+	}
+	Logger().Debug("DecryptOpenPGPMessage", "recipients", recipients, "plaintext_size", len(literalMessage.data))
+	return literalMessage.data, nil
+}
+
+// DecryptAndVerifyOpenPGPMessage decrypts a signed message like DecryptOpenPGPMessage, and returns the literal data
+// only if one of its binary signatures is valid and made by the primary key or a signing subkey of signer. Otherwise
+// it returns ErrVerificationFailed, or a *FormatError matching ErrInvalidOpenPGPPacket for a malformed signature.
+func DecryptAndVerifyOpenPGPMessage(key *OpenPGPKey, signer *OpenPGPKey, message []byte) (plaintext []byte, decryptError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			plaintext, decryptError = nil, newPanicError("DecryptAndVerifyOpenPGPMessage", recoveredPanic)
+		}
+	}()
+	if signer == nil {
+		return nil, fmt.Errorf("%w: nil OpenPGP key", ErrInvalidKey)
+	}
+	literalMessage, recipients, err := decryptOpenPGPMessage(key, message)
+	if err != nil {
+		return nil, err
+	}
+	signingKey, err := signer.verifyingKey(literalMessage.signatures, literalMessage.data, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if signingKey == nil {
+		return nil, fmt.Errorf("%w: no valid signature by OpenPGP key %X", ErrVerificationFailed, signer.primary.fingerprint)
+	}
+	Logger().Debug("DecryptAndVerifyOpenPGPMessage", "recipients", recipients, "algorithm", signingKey.algorithm.String(),
+		"plaintext_size", len(literalMessage.data))
+	return literalMessage.data, nil
+}
+
+// decryptOpenPGPMessage decrypts a message with a secret key, and returns the literal message and the number of PKESK
+// packets.
+func decryptOpenPGPMessage(key *OpenPGPKey, message []byte) (*openPGPLiteralMessage, int, error) {
+	if key == nil || !key.IsPrivate() {
+		return nil, 0, fmt.Errorf("%w: OpenPGP key has no secret key material", ErrInvalidKey)
+	}
+	binaryMessage, err := openPGPBinary(message, OpenPGPArmorMessage)
+	if err != nil {
+		return nil, 0, err
+	}
+	packets, err := parseOpenPGPPackets(binaryMessage)
+	if err != nil {
+		return nil, 0, err
+	}
+	var pkesks []*openPGPPKESK
+	var seipd *parsedOpenPGPSEIPD
+	for i := range packets {
+		packet := &packets[i]
+		switch {
+		case seipd != nil && packet.tag != openPGPTagPadding:
+			return nil, 0, packet.fields().formatError("unexpected tag %d packet after encrypted data", packet.tag)
+		case packet.tag == openPGPTagPKESK:
+			pkesk, err := parseOpenPGPPKESK(packet)
+			if err != nil {
+				return nil, 0, err
+			}
+			if pkesk != nil {
+				pkesks = append(pkesks, pkesk)
+			}
+		case packet.tag == openPGPTagSEIPD:
+			if seipd, err = parseOpenPGPSEIPD(packet); err != nil {
+				return nil, 0, err
+			}
+		case packet.tag == openPGPTagMarker, packet.tag == openPGPTagPadding:
+		default:
+			return nil, 0, packet.fields().formatError("unexpected tag %d packet in encrypted message", packet.tag)
+		}
+	}
+	if seipd == nil {
+		return nil, 0, &FormatError{Err: ErrInvalidOpenPGPPacket, Offset: len(binaryMessage), Reason: "no encrypted data packet"}
+	}
+
+	decryptionError := error(nil)
+	for _, pkesk := range pkesks {
+		for _, subkey := range key.subkeys {
+			if subkey.key.secret == nil || (pkesk.fingerprint != nil && !bytes.Equal(pkesk.fingerprint, subkey.key.fingerprint)) {
+				continue
+			}
+			sessionKey, err := subkey.key.decryptSessionKey(pkesk)
+			var decrypted []byte
+			if err == nil {
+				decrypted, err = seipd.open(sessionKey)
+			}
+			if err != nil {
+				decryptionError = err
+				continue
+			}
+			literalMessage, err := parseOpenPGPLiteralMessage(decrypted, 0)
+			if err != nil {
+				return nil, 0, err
+			}
+			return literalMessage, len(pkesks), nil
+		}
+	}
+	if decryptionError != nil {
+		return nil, 0, decryptionError
+	}
+	return nil, 0, fmt.Errorf("%w: no PKESK for a subkey of OpenPGP key %X", ErrRecipientNotFound, key.primary.fingerprint)
+}
+
+// SignOpenPGPDetached returns a binary detached signature of data by the signing key of key, which ArmorOpenPGP with
+// OpenPGPArmorSignature armors.
+func SignOpenPGPDetached(key *OpenPGPKey, data []byte) (signature []byte, signError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			signature, signError = nil, newPanicError("SignOpenPGPDetached", recoveredPanic)
+		}
+	}()
+	if key == nil {
+		return nil, fmt.Errorf("%w: nil OpenPGP key", ErrInvalidKey)
+	}
+	now := time.Now()
+	for _, signingKey := range key.signingKeys(now) {
+		if signingKey.secret == nil {
+			continue
+		}
+		signatureBody, err := newOpenPGPSignature(signingKey, openPGPSignatureBinary, uint32(now.Unix()), nil, data)
+		if err != nil {
+			return nil, err
+		}
+		Logger().Debug("SignOpenPGPDetached", "algorithm", signingKey.algorithm.String(), "data_size", len(data))
+		return appendOpenPGPPacket(nil, openPGPTagSignature, signatureBody), nil
+	}
+	return nil, fmt.Errorf("%w: OpenPGP key %X has no valid signing key with secret key material", ErrInvalidKey, key.primary.fingerprint)
+}
+
+// VerifyOpenPGPDetached verifies an ASCII armored or binary detached signature of data by the primary key or a signing
+// subkey of key. It succeeds if any of the signatures is valid, and otherwise returns ErrVerificationFailed, or a
+// *FormatError matching ErrInvalidOpenPGPPacket for a malformed signature.
+func VerifyOpenPGPDetached(key *OpenPGPKey, data []byte, signature []byte) (verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			verifyError = newPanicError("VerifyOpenPGPDetached", recoveredPanic)
+		}
+	}()
+	if key == nil {
+		return fmt.Errorf("%w: nil OpenPGP key", ErrInvalidKey)
+	}
+	binarySignature, err := openPGPBinary(signature, OpenPGPArmorSignature)
+	if err != nil {
+		return err
+	}
+	packets, err := parseOpenPGPPackets(binarySignature)
+	if err != nil {
+		return err
+	}
+	if len(packets) == 0 {
+		return &FormatError{Err: ErrInvalidOpenPGPPacket, Offset: 0, Reason: "no signature packet"}
+	}
+	signatures := make([]*openPGPPacket, 0, len(packets))
+	for i := range packets {
+		packet := &packets[i]
+		if packet.tag != openPGPTagSignature {
+			return packet.fields().formatError("unexpected tag %d packet in detached signature", packet.tag)
+		}
+		signatures = append(signatures, packet)
+	}
+	signingKey, err := key.verifyingKey(signatures, data, time.Now())
+	if err != nil {
+		return err
+	}
+	if signingKey == nil {
+		return fmt.Errorf("%w: no valid signature by OpenPGP key %X", ErrVerificationFailed, key.primary.fingerprint)
+	}
+	Logger().Debug("VerifyOpenPGPDetached", "algorithm", signingKey.algorithm.String(), "data_size", len(data))
+	return nil
+}
+
+// verifyingKey returns the primary key or signing subkey of key that made one of the binary signatures of data, or nil
+// if none of them is valid. A malformed signature packet returns a *FormatError.
+func (key *OpenPGPKey) verifyingKey(signatures []*openPGPPacket, data []byte, now time.Time) (*openPGPKeyPacket, error) {
+	signingKeys := key.signingKeys(now)
+	for _, packet := range signatures {
+		parsedSignature, err := parseOpenPGPSignature(packet.body, packet.offset)
+		if err != nil {
+			return nil, err
+		}
+		if parsedSignature.sigType != openPGPSignatureBinary {
+			continue
+		}
+		for _, signingKey := range signingKeys {
+			if parsedSignature.verify(signingKey, now, data) {
+				return signingKey, nil
+			}
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,83 @@
+package pq
+
+import "testing"
+
+func BenchmarkGenerateOpenPGPKey(b *testing.B) {
+	for b.Loop() {
+		if _, err := GenerateOpenPGPKey("bench <bench@example.org>"); err != nil {
+			b.Fatalf("failed to generate OpenPGP key: %v", err)
+		}
+	}
+}
+
+func BenchmarkReadOpenPGPKey(b *testing.B) {
+	key, err := GenerateOpenPGPKey("bench <bench@example.org>")
+	if err != nil {
+		b.Fatalf("failed to generate OpenPGP key: %v", err)
+	}
+	publicKeyBytes := key.PublicKeyBytes()
+	for b.Loop() {
+		if _, err := ReadOpenPGPKey(publicKeyBytes); err != nil {
+			b.Fatalf("failed to read OpenPGP key: %v", err)
+		}
+	}
+}
+
+func BenchmarkEncryptOpenPGPMessage(b *testing.B) {
+	key, err := GenerateOpenPGPKey("")
+	if err != nil {
+		b.Fatalf("failed to generate OpenPGP key: %v", err)
+	}
+	plaintext := make([]byte, 1024)
+	for b.Loop() {
+		if _, err := EncryptOpenPGPMessage([]*OpenPGPKey{key}, plaintext); err != nil {
+			b.Fatalf("failed to encrypt: %v", err)
+		}
+	}
+}
+
+func BenchmarkDecryptOpenPGPMessage(b *testing.B) {
+	key, err := GenerateOpenPGPKey("")
+	if err != nil {
+		b.Fatalf("failed to generate OpenPGP key: %v", err)
+	}
+	message, err := EncryptOpenPGPMessage([]*OpenPGPKey{key}, make([]byte, 1024))
+	if err != nil {
+		b.Fatalf("failed to encrypt: %v", err)
+	}
+	for b.Loop() {
+		if _, err := DecryptOpenPGPMessage(key, message); err != nil {
+			b.Fatalf("failed to decrypt: %v", err)
+		}
+	}
+}
+
+func BenchmarkSignOpenPGPDetached(b *testing.B) {
+	key, err := GenerateOpenPGPKey("")
+	if err != nil {
+		b.Fatalf("failed to generate OpenPGP key: %v", err)
+	}
+	data := make([]byte, 1024)
+	for b.Loop() {
+		if _, err := SignOpenPGPDetached(key, data); err != nil {
+			b.Fatalf("failed to sign: %v", err)
+		}
+	}
+}
+
+func BenchmarkVerifyOpenPGPDetached(b *testing.B) {
+	key, err := GenerateOpenPGPKey("")
+	if err != nil {
+		b.Fatalf("failed to generate OpenPGP key: %v", err)
+	}
+	data := make([]byte, 1024)
+	signature, err := SignOpenPGPDetached(key, data)
+	if err != nil {
+		b.Fatalf("failed to sign: %v", err)
+	}
+	for b.Loop() {
+		if err := VerifyOpenPGPDetached(key, data, signature); err != nil {
+			b.Fatalf("failed to verify: %v", err)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package pq
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func FuzzReadOpenPGPKey(f *testing.F) {
+	key, _ := GenerateOpenPGPKey("fuzz <fuzz@example.org>")
+	privateKeyBytes, _ := key.PrivateKeyBytes()
+	armoredPublicKey, _ := key.ArmoredPublicKey()
+	f.Add(key.PublicKeyBytes())
+	f.Add(privateKeyBytes)
+	f.Add(armoredPublicKey)
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		parsedKey, err := ReadOpenPGPKey(data)
+		if errors.Is(err, ErrInternalPanic) {
+			t.Fatalf("panic reading key: %v", err)
+		}
+		if err != nil {
+			return
+		}
+		reparsedKey, err := ReadOpenPGPKey(parsedKey.PublicKeyBytes())
+		if err != nil {
+			t.Fatalf("failed to read exported public key: %v", err)
+		}
+		if !bytes.Equal(reparsedKey.Fingerprint(), parsedKey.Fingerprint()) {
+			t.Errorf("exported public key has fingerprint %X, want %X", reparsedKey.Fingerprint(), parsedKey.Fingerprint())
+		}
+	})
+}
+
+func FuzzDecryptOpenPGPMessage(f *testing.F) {
+	key, _ := GenerateOpenPGPKey("")
+	message, _ := EncryptOpenPGPMessage([]*OpenPGPKey{key}, []byte("fuzz"))
+	f.Add(message)
+	f.Add(message[:len(message)-1])
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		plaintext, err := DecryptOpenPGPMessage(key, data)
+		if errors.Is(err, ErrInternalPanic) {
+			t.Fatalf("panic decrypting message: %v", err)
+		}
+		if err == nil && string(plaintext) != "fuzz" {
+			t.Errorf("decrypted a forged message: %q", plaintext)
+		}
+	})
+}
+
+func FuzzParseOpenPGPLiteralMessage(f *testing.F) {
+	literal := appendOpenPGPLiteral(nil, []byte("fuzz"))
+	onePass := appendOpenPGPPacket(nil, openPGPTagOnePassSignature, []byte{openPGPKeyVersion6})
+	signature := appendOpenPGPPacket(nil, openPGPTagSignature, []byte{openPGPKeyVersion6})
+	f.Add(literal)
+	f.Add(bytes.Join([][]byte{onePass, literal, signature}, nil))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		message, err := parseOpenPGPLiteralMessage(data, 0)
+		if err != nil {
+			return
+		}
+		for _, packet := range message.signatures {
+			if packet.tag != openPGPTagSignature {
+				t.Errorf("tag %d packet returned as a signature", packet.tag)
+			}
+		}
+	})
+}
+
+func FuzzVerifyOpenPGPDetached(f *testing.F) {
+	key, _ := GenerateOpenPGPKey("")
+	signature, _ := SignOpenPGPDetached(key, []byte("fuzz"))
+	f.Add([]byte("fuzz"), signature)
+	f.Add([]byte("fuzz"), []byte{})
+	f.Fuzz(func(t *testing.T, data []byte, signatureData []byte) {
+		err := VerifyOpenPGPDetached(key, data, signatureData)
+		if errors.Is(err, ErrInternalPanic) {
+			t.Fatalf("panic verifying signature: %v", err)
+		}
+		if err == nil && !bytes.Equal(data, []byte("fuzz")) {
+			t.Errorf("verified a signature over %q", data)
+		}
+	})
+}
@@ -0,0 +1,648 @@
+package pq
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/ProtonMail/go-crypto/eax"
+	"github.com/ProtonMail/go-crypto/ocb"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+// OpenPGP (RFC 9580) packet framing, ASCII armor, signature subpackets, and the version 2 Symmetrically Encrypted and
+// Integrity Protected Data packet. Only what v6 keys and messages need is implemented; openpgp.go builds keys,
+// signatures and messages on top of it.
+
+// Packet tags.
+const (
+	openPGPTagPKESK            = 1
+	openPGPTagSignature        = 2
+	openPGPTagOnePassSignature = 4
+	openPGPTagSecretKey        = 5
+	openPGPTagPublicKey        = 6
+	openPGPTagSecretSubkey     = 7
+	openPGPTagCompressedData   = 8
+	openPGPTagMarker           = 10
+	openPGPTagLiteralData      = 11
+	openPGPTagTrust            = 12
+	openPGPTagUserID           = 13
+	openPGPTagPublicSubkey     = 14
+	openPGPTagUserAttribute    = 17
+	openPGPTagSEIPD            = 18
+	openPGPTagPadding          = 21
+)
+
+// Symmetric ciphers, AEAD modes and compression algorithms.
+const (
+	openPGPCipherAES128 = 7
+	openPGPCipherAES192 = 8
+	openPGPCipherAES256 = 9
+
+	openPGPAEADEAX = 1
+	openPGPAEADOCB = 2
+	openPGPAEADGCM = 3
+
+	openPGPCompressionNone = 0
+	openPGPCompressionZIP  = 1
+	openPGPCompressionZLIB = 2
+)
+
+// Hash algorithms allowed in v6 signatures.
+const (
+	openPGPHashSHA256   = 8
+	openPGPHashSHA384   = 9
+	openPGPHashSHA512   = 10
+	openPGPHashSHA224   = 11
+	openPGPHashSHA3_256 = 12
+	openPGPHashSHA3_512 = 14
+)
+
+const (
+	// openPGPSEIPDVersion2 is the AEAD version of the SEIPD packet.
+	openPGPSEIPDVersion2 = 2
+	// openPGPSEIPDSaltSize is the size of the salt of a version 2 SEIPD packet.
+	openPGPSEIPDSaltSize = 32
+	// openPGPChunkSizeOctet selects 64 KiB chunks, the size of StreamChunkSize.
+	openPGPChunkSizeOctet = 10
+	// openPGPMaxChunkSizeOctet is the largest chunk size octet, selecting 4 MiB chunks.
+	openPGPMaxChunkSizeOctet = 16
+	// openPGPMaxCompressionDepth bounds compressed data packets nested in each other.
+	openPGPMaxCompressionDepth = 2
+)
+
+// Armor block types.
+const (
+	// OpenPGPArmorPublicKey is the armor block type of a certificate.
+	OpenPGPArmorPublicKey = "PGP PUBLIC KEY BLOCK"
+	// OpenPGPArmorPrivateKey is the armor block type of a secret key.
+	OpenPGPArmorPrivateKey = "PGP PRIVATE KEY BLOCK"
+	// OpenPGPArmorMessage is the armor block type of an encrypted message.
+	OpenPGPArmorMessage = "PGP MESSAGE"
+	// OpenPGPArmorSignature is the armor block type of a detached signature.
+	OpenPGPArmorSignature = "PGP SIGNATURE"
+)
+
+// openPGPPacket is a parsed packet. The body of a packet with partial lengths is reassembled.
+type openPGPPacket struct {
+	tag byte
+	// offset is the offset of the packet body in the parsed data.
+	offset int
+	body   []byte
+}
+
+// fields returns a reader of the fields of the packet body.
+func (packet *openPGPPacket) fields() *openPGPFields {
+	return &openPGPFields{data: packet.body, base: packet.offset}
+}
+
+// openPGPFields reads the fields of a packet body, and reports errors at the offset of the field in the parsed data.
+type openPGPFields struct {
+	data []byte
+	// base is the offset of data in the parsed data.
+	base int
+	// offset is the offset of the next field and fieldOffset the offset of the last field taken.
+	offset, fieldOffset int
+}
+
+// take returns the next size bytes, or false if the body is too short.
+func (fields *openPGPFields) take(size int) ([]byte, bool) {
+	fields.fieldOffset = fields.offset
+	if size < 0 || size > len(fields.data)-fields.offset {
+		return nil, false
+	}
+	field := fields.data[fields.offset : fields.offset+size]
+	fields.offset += size
+	return field, true
+}
+
+// rest returns the remaining bytes.
+func (fields *openPGPFields) rest() []byte {
+	fields.fieldOffset = fields.offset
+	rest := fields.data[fields.offset:]
+	fields.offset = len(fields.data)
+	return rest
+}
+
+// formatError returns a *FormatError at the offset of the last field taken.
+func (fields *openPGPFields) formatError(reason string, arguments ...any) error {
+	return &FormatError{Err: ErrInvalidOpenPGPPacket, Offset: fields.base + fields.fieldOffset, Reason: fmt.Sprintf(reason, arguments...)}
+}
+
+// parseOpenPGPPackets splits data into packets, in either the legacy or the OpenPGP packet format.
+func parseOpenPGPPackets(data []byte) ([]openPGPPacket, error) {
+	var packets []openPGPPacket
+	offset := 0
+	formatError := func(reason string, arguments ...any) error {
+		return &FormatError{Err: ErrInvalidOpenPGPPacket, Offset: offset, Reason: fmt.Sprintf(reason, arguments...)}
+	}
+	// takeBody returns the next size bytes of data, if there are that many.
+	takeBody := func(size uint64) ([]byte, bool) {
+		if size > uint64(len(data)-offset) {
+			return nil, false
+		}
+		body := data[offset : offset+int(size)]
+		offset += int(size)
+		return body, true
+	}
+
+	for offset < len(data) {
+		header := data[offset]
+		if header&0x80 == 0 {
+			return nil, formatError("packet header 0x%02x lacks the always-set bit", header)
+		}
+		offset++
+		if header&0x40 == 0 {
+			// Legacy format: the tag is in bits 5-2 and the length type in bits 1-0.
+			tag, lengthType := header>>2&0x0f, header&0x03
+			if lengthType == 3 {
+				packets = append(packets, openPGPPacket{tag: tag, offset: offset, body: data[offset:]})
+				offset = len(data)
+				break
+			}
+			lengthBytes, ok := takeBody(1 << lengthType)
+			if !ok {
+				return nil, formatError("truncated packet length")
+			}
+			length := uint64(0)
+			for _, lengthByte := range lengthBytes {
+				length = length<<8 | uint64(lengthByte)
+			}
+			bodyOffset := offset
+			body, ok := takeBody(length)
+			if !ok {
+				return nil, formatError("truncated tag %d packet of %d bytes", tag, length)
+			}
+			packets = append(packets, openPGPPacket{tag: tag, offset: bodyOffset, body: body})
+			continue
+		}
+
+		tag := header & 0x3f
+		bodyOffset := -1
+		var body []byte
+		for partial := true; partial; {
+			length, lengthSize, isPartial, ok := parseOpenPGPLength(data[offset:], true)
+			if !ok {
+				return nil, formatError("truncated packet length")
+			}
+			offset += lengthSize
+			partial = isPartial
+			if bodyOffset < 0 {
+				bodyOffset = offset
+			}
+			chunk, ok := takeBody(length)
+			if !ok {
+				return nil, formatError("truncated tag %d packet of %d bytes", tag, length)
+			}
+			if body == nil && !partial {
+				body = chunk
+			} else {
+				body = append(body, chunk...)
+			}
+		}
+		packets = append(packets, openPGPPacket{tag: tag, offset: bodyOffset, body: body})
+	}
+	return packets, nil
+}
+
+// parseOpenPGPLength parses a one-, two- or five-octet length, or a partial body length if partialAllowed, and returns
+// it with the size of its encoding.
+func parseOpenPGPLength(data []byte, partialAllowed bool) (length uint64, lengthSize int, partial bool, ok bool) {
+	if len(data) == 0 {
+		return 0, 0, false, false
+	}
+	switch first := data[0]; {
+	case first < 192:
+		return uint64(first), 1, false, true
+	case first < 224:
+		if len(data) < 2 {
+			return 0, 0, false, false
+		}
+		return uint64(first-192)<<8 + uint64(data[1]) + 192, 2, false, true
+	case first == 255:
+		if len(data) < 5 {
+			return 0, 0, false, false
+		}
+		return uint64(binary.BigEndian.Uint32(data[1:5])), 5, false, true
+	default:
+		return 1 << (first & 0x1f), 1, true, partialAllowed
+	}
+}
+
+// appendOpenPGPLength appends a one-, two- or five-octet length.
+func appendOpenPGPLength(dst []byte, length int) []byte {
+	switch {
+	case length < 192:
+		return append(dst, byte(length))
+	case length < 8384:
+		length -= 192
+		return append(dst, byte(length>>8)+192, byte(length))
+	default:
+		return binary.BigEndian.AppendUint32(append(dst, 255), uint32(length))
+	}
+}
+
+// appendOpenPGPPacket appends a packet in the OpenPGP packet format.
+func appendOpenPGPPacket(dst []byte, tag byte, body []byte) []byte {
+	dst = appendOpenPGPLength(append(dst, 0xc0|tag), len(body))
+	return append(dst, body...)
+}
+
+// openPGPSubpacket is a signature subpacket.
+type openPGPSubpacket struct {
+	kind     byte
+	critical bool
+	data     []byte
+}
+
+// parseOpenPGPSubpackets parses a subpacket area.
+func parseOpenPGPSubpackets(area []byte) ([]openPGPSubpacket, error) {
+	var subpackets []openPGPSubpacket
+	for offset := 0; offset < len(area); {
+		length, lengthSize, _, ok := parseOpenPGPLength(area[offset:], false)
+		if !ok {
+			return nil, fmt.Errorf("truncated subpacket length at subpacket offset %d", offset)
+		}
+		offset += lengthSize
+		if length == 0 || length > uint64(len(area)-offset) {
+			return nil, fmt.Errorf("invalid subpacket length %d at subpacket offset %d", length, offset)
+		}
+		subpacket := area[offset : offset+int(length)]
+		offset += int(length)
+		subpackets = append(subpackets, openPGPSubpacket{kind: subpacket[0] & 0x7f, critical: subpacket[0]&0x80 != 0, data: subpacket[1:]})
+	}
+	return subpackets, nil
+}
+
+// appendOpenPGPSubpacket appends a subpacket.
+func appendOpenPGPSubpacket(dst []byte, kind byte, critical bool, data []byte) []byte {
+	if critical {
+		kind |= 0x80
+	}
+	dst = appendOpenPGPLength(dst, 1+len(data))
+	return append(append(dst, kind), data...)
+}
+
+// newOpenPGPHash returns a hash of a hash algorithm allowed in v6 signatures, and the salt size it requires.
+func newOpenPGPHash(hashID byte) (hash.Hash, int, error) {
+	switch hashID {
+	case openPGPHashSHA256:
+		return sha256.New(), 16, nil
+	case openPGPHashSHA384:
+		return sha512.New384(), 24, nil
+	case openPGPHashSHA512:
+		return sha512.New(), 32, nil
+	case openPGPHashSHA224:
+		return sha256.New224(), 16, nil
+	case openPGPHashSHA3_256:
+		return sha3.New256(), 16, nil
+	case openPGPHashSHA3_512:
+		return sha3.New512(), 32, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported OpenPGP hash algorithm %d", hashID)
+	}
+}
+
+// ArmorOpenPGP returns the ASCII armor (RFC 9580 section 6) of binary OpenPGP data, for example
+// ArmorOpenPGP(OpenPGPArmorMessage, message). As RFC 9580 recommends, it has no CRC-24 checksum.
+func ArmorOpenPGP(blockType string, data []byte) ([]byte, error) {
+	var armored bytes.Buffer
+	writer, err := armor.EncodeWithChecksumOption(&armored, blockType, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	armored.WriteByte('\n')
+	return armored.Bytes(), nil
+}
+
+// DearmorOpenPGP decodes ASCII armored OpenPGP data and returns its block type and binary data. A CRC-24 checksum is
+// checked if present.
+func DearmorOpenPGP(armored []byte) (blockType string, data []byte, err error) {
+	block, err := armor.Decode(bytes.NewReader(armored))
+	if err != nil {
+		return "", nil, &FormatError{Err: ErrInvalidOpenPGPPacket, Offset: 0, Reason: fmt.Sprintf("malformed armor: %v", err)}
+	}
+	data, err = io.ReadAll(block.Body)
+	if err != nil {
+		return "", nil, &FormatError{Err: ErrInvalidOpenPGPPacket, Offset: 0, Reason: fmt.Sprintf("malformed armor: %v", err)}
+	}
+	return block.Type, data, nil
+}
+
+// openPGPBinary returns data, dearmored if it is ASCII armored with one of the block types.
+func openPGPBinary(data []byte, blockTypes ...string) ([]byte, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("-----BEGIN ")) {
+		return data, nil
+	}
+	blockType, binaryData, err := DearmorOpenPGP(data)
+	if err != nil {
+		return nil, err
+	}
+	for _, allowedType := range blockTypes {
+		if blockType == allowedType {
+			return binaryData, nil
+		}
+	}
+	return nil, &FormatError{Err: ErrInvalidOpenPGPPacket, Offset: 0, Reason: fmt.Sprintf("unexpected armor block type %q", blockType)}
+}
+
+// openPGPCipherKeySize returns the key size of an AEAD-capable symmetric cipher.
+func openPGPCipherKeySize(cipherID byte) (int, bool) {
+	switch cipherID {
+	case openPGPCipherAES128:
+		return 16, true
+	case openPGPCipherAES192:
+		return 24, true
+	case openPGPCipherAES256:
+		return 32, true
+	default:
+		return 0, false
+	}
+}
+
+// newOpenPGPAEAD returns the AEAD mode with AES keyed with key.
+func newOpenPGPAEAD(mode byte, key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case openPGPAEADEAX:
+		return eax.NewEAX(block)
+	case openPGPAEADOCB:
+		return ocb.NewOCB(block)
+	case openPGPAEADGCM:
+		return cipher.NewGCM(block)
+	default:
+		return nil, fmt.Errorf("unsupported OpenPGP AEAD mode %d", mode)
+	}
+}
+
+// openPGPSEIPDCrypter encrypts or decrypts the chunks of a version 2 SEIPD packet: HKDF-SHA256 derives the message key
+// and the first bytes of the nonce from the session key and the salt, and the last 8 bytes of the nonce are the chunk
+// index. Every chunk is authenticated with the packet header, and a final tag also with the plaintext size.
+type openPGPSEIPDCrypter struct {
+	aead           cipher.AEAD
+	iv             []byte
+	associatedData []byte
+	chunkSize      int
+}
+
+// newOpenPGPSEIPDCrypter derives the keys of a version 2 SEIPD packet from its header fields.
+func newOpenPGPSEIPDCrypter(sessionKey []byte, cipherID, mode, chunkSizeOctet byte, salt []byte) (*openPGPSEIPDCrypter, error) {
+	keySize, ok := openPGPCipherKeySize(cipherID)
+	if !ok {
+		return nil, fmt.Errorf("unsupported OpenPGP AEAD cipher %d", cipherID)
+	}
+	if len(sessionKey) != keySize {
+		return nil, fmt.Errorf("%w: session key of %d bytes for cipher %d", ErrDecryptionFailed, len(sessionKey), cipherID)
+	}
+	associatedData := []byte{0xc0 | openPGPTagSEIPD, openPGPSEIPDVersion2, cipherID, mode, chunkSizeOctet}
+	// The nonce size is known once the mode is; derive with the largest and cut.
+	keyMaterial, err := hkdf.Key(sha256.New, sessionKey, salt, string(associatedData), keySize+16-8)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newOpenPGPAEAD(mode, keyMaterial[:keySize])
+	if err != nil {
+		return nil, err
+	}
+	return &openPGPSEIPDCrypter{
+		aead:           aead,
+		iv:             keyMaterial[keySize : keySize+aead.NonceSize()-8],
+		associatedData: associatedData,
+		chunkSize:      1 << (chunkSizeOctet + 6),
+	}, nil
+}
+
+// nonce returns the nonce of the chunk with the index.
+func (crypter *openPGPSEIPDCrypter) nonce(index uint64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte(nil), crypter.iv...), index)
+}
+
+// finalAssociatedData returns the associated data of the final tag.
+func (crypter *openPGPSEIPDCrypter) finalAssociatedData(plaintextSize uint64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte(nil), crypter.associatedData...), plaintextSize)
+}
+
+// sealOpenPGPSEIPD returns the body of a version 2 SEIPD packet encrypting plaintext with AES-256-OCB.
+func sealOpenPGPSEIPD(sessionKey []byte, plaintext []byte) ([]byte, error) {
+	salt := make([]byte, openPGPSEIPDSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	crypter, err := newOpenPGPSEIPDCrypter(sessionKey, openPGPCipherAES256, openPGPAEADOCB, openPGPChunkSizeOctet, salt)
+	if err != nil {
+		return nil, err
+	}
+	body := append(crypter.associatedData[1:len(crypter.associatedData):len(crypter.associatedData)], salt...)
+	index := uint64(0)
+	// An empty plaintext is one empty chunk.
+	for offset := 0; offset < len(plaintext) || index == 0; offset += crypter.chunkSize {
+		chunk := plaintext[offset:min(offset+crypter.chunkSize, len(plaintext))]
+		body = crypter.aead.Seal(body, crypter.nonce(index), chunk, crypter.associatedData)
+		index++
+	}
+	return crypter.aead.Seal(body, crypter.nonce(index), nil, crypter.finalAssociatedData(uint64(len(plaintext)))), nil
+}
+
+// parsedOpenPGPSEIPD is the header of a version 2 SEIPD packet and its encrypted chunks and final tag.
+type parsedOpenPGPSEIPD struct {
+	cipherID, mode, chunkSizeOctet byte
+	salt                           []byte
+	ciphertext                     []byte
+}
+
+// parseOpenPGPSEIPD parses the body of a version 2 SEIPD packet.
+func parseOpenPGPSEIPD(packet *openPGPPacket) (*parsedOpenPGPSEIPD, error) {
+	fields := packet.fields()
+	version, ok := fields.take(1)
+	if !ok {
+		return nil, fields.formatError("truncated SEIPD version")
+	}
+	if version[0] != openPGPSEIPDVersion2 {
+		return nil, fields.formatError("unsupported SEIPD version %d, only version 2 is supported", version[0])
+	}
+	header, ok := fields.take(3)
+	if !ok {
+		return nil, fields.formatError("truncated SEIPD header")
+	}
+	if _, ok := openPGPCipherKeySize(header[0]); !ok {
+		return nil, fields.formatError("unsupported SEIPD cipher %d", header[0])
+	}
+	if header[1] < openPGPAEADEAX || header[1] > openPGPAEADGCM {
+		return nil, fields.formatError("unsupported SEIPD AEAD mode %d", header[1])
+	}
+	if header[2] > openPGPMaxChunkSizeOctet {
+		return nil, fields.formatError("invalid SEIPD chunk size octet %d", header[2])
+	}
+	salt, ok := fields.take(openPGPSEIPDSaltSize)
+	if !ok {
+		return nil, fields.formatError("truncated SEIPD salt")
+	}
+	ciphertext := fields.rest()
+	if len(ciphertext) < 16 {
+		return nil, fields.formatError("truncated SEIPD final tag")
+	}
+	return &parsedOpenPGPSEIPD{cipherID: header[0], mode: header[1], chunkSizeOctet: header[2], salt: salt, ciphertext: ciphertext}, nil
+}
+
+// open decrypts the packet with the session key. Any authentication failure, including a truncated or reordered chunk,
+// returns ErrDecryptionFailed.
+func (seipd *parsedOpenPGPSEIPD) open(sessionKey []byte) ([]byte, error) {
+	crypter, err := newOpenPGPSEIPDCrypter(sessionKey, seipd.cipherID, seipd.mode, seipd.chunkSizeOctet, seipd.salt)
+	if err != nil {
+		return nil, err
+	}
+	tagSize := crypter.aead.Overhead()
+	if len(seipd.ciphertext) < tagSize {
+		return nil, ErrDecryptionFailed
+	}
+	chunks, finalTag := seipd.ciphertext[:len(seipd.ciphertext)-tagSize], seipd.ciphertext[len(seipd.ciphertext)-tagSize:]
+	var plaintext []byte
+	index := uint64(0)
+	for offset := 0; offset < len(chunks); offset += crypter.chunkSize + tagSize {
+		chunk := chunks[offset:min(offset+crypter.chunkSize+tagSize, len(chunks))]
+		plaintext, err = crypter.aead.Open(plaintext, crypter.nonce(index), chunk, crypter.associatedData)
+		if err != nil {
+			return nil, fmt.Errorf("%w: chunk %d", ErrDecryptionFailed, index)
+		}
+		index++
+	}
+	if _, err := crypter.aead.Open(nil, crypter.nonce(index), finalTag, crypter.finalAssociatedData(uint64(len(plaintext)))); err != nil {
+		return nil, fmt.Errorf("%w: final tag", ErrDecryptionFailed)
+	}
+	return plaintext, nil
+}
+
+// appendOpenPGPLiteral appends a binary literal data packet with no file name and a zero date.
+func appendOpenPGPLiteral(dst []byte, data []byte) []byte {
+	body := make([]byte, 0, 6+len(data))
+	body = append(body, 'b', 0, 0, 0, 0, 0)
+	return appendOpenPGPPacket(dst, openPGPTagLiteralData, append(body, data...))
+}
+
+// openPGPLiteralMessage is the literal data of a decrypted message and the signature packets over it.
+type openPGPLiteralMessage struct {
+	data       []byte
+	signatures []*openPGPPacket
+}
+
+// parseOpenPGPLiteralMessage returns the literal data packet a decrypted message consists of, possibly compressed,
+// padded and signed, with its signature packets. A signed message is either one-pass signature packets, the literal
+// data and as many signature packets, or signature packets followed by the literal data. Offsets of errors are in the
+// decrypted data.
+func parseOpenPGPLiteralMessage(decrypted []byte, depth int) (*openPGPLiteralMessage, error) {
+	packets, err := parseOpenPGPPackets(decrypted)
+	if err != nil {
+		return nil, err
+	}
+	var message *openPGPLiteralMessage
+	var signatures []*openPGPPacket
+	onePassSignatures := 0
+	for i := range packets {
+		packet := &packets[i]
+		fields := packet.fields()
+		switch packet.tag {
+		case openPGPTagMarker, openPGPTagPadding:
+			continue
+		case openPGPTagOnePassSignature:
+			if message != nil || len(signatures) > 0 {
+				return nil, fields.formatError("unexpected one-pass signature packet")
+			}
+			onePassSignatures++
+		case openPGPTagSignature:
+			if message == nil && onePassSignatures > 0 {
+				return nil, fields.formatError("signature packet before the signed data")
+			}
+			signatures = append(signatures, packet)
+		case openPGPTagCompressedData:
+			if depth >= openPGPMaxCompressionDepth || message != nil {
+				return nil, fields.formatError("unexpected compressed data packet")
+			}
+			decompressed, err := decompressOpenPGP(fields)
+			if err != nil {
+				return nil, err
+			}
+			if message, err = parseOpenPGPLiteralMessage(decompressed, depth+1); err != nil {
+				return nil, err
+			}
+		case openPGPTagLiteralData:
+			if message != nil {
+				return nil, fields.formatError("more than one literal data packet")
+			}
+			format, ok := fields.take(1)
+			if !ok {
+				return nil, fields.formatError("truncated literal data format")
+			}
+			if format[0] != 'b' && format[0] != 'u' && format[0] != 't' {
+				return nil, fields.formatError("unsupported literal data format 0x%02x", format[0])
+			}
+			fileNameSize, ok := fields.take(1)
+			if !ok {
+				return nil, fields.formatError("truncated literal data file name")
+			}
+			if _, ok := fields.take(int(fileNameSize[0]) + 4); !ok {
+				return nil, fields.formatError("truncated literal data file name or date")
+			}
+			message = &openPGPLiteralMessage{data: fields.rest()}
+		default:
+			return nil, fields.formatError("unexpected tag %d packet in message", packet.tag)
+		}
+	}
+	if message == nil {
+		return nil, &FormatError{Err: ErrInvalidOpenPGPPacket, Offset: len(decrypted), Reason: "no literal data packet"}
+	}
+	if onePassSignatures > 0 && onePassSignatures != len(signatures) {
+		return nil, &FormatError{Err: ErrInvalidOpenPGPPacket, Offset: len(decrypted),
+			Reason: fmt.Sprintf("%d one-pass signature packets but %d signature packets", onePassSignatures, len(signatures))}
+	}
+	message.signatures = append(message.signatures, signatures...)
+	return message, nil
+}
+
+// decompressOpenPGP decompresses the body of a compressed data packet.
+func decompressOpenPGP(fields *openPGPFields) ([]byte, error) {
+	algorithm, ok := fields.take(1)
+	if !ok {
+		return nil, fields.formatError("truncated compression algorithm")
+	}
+	compressed := fields.rest()
+	var reader io.Reader
+	switch algorithm[0] {
+	case openPGPCompressionNone:
+		return compressed, nil
+	case openPGPCompressionZIP:
+		reader = flate.NewReader(bytes.NewReader(compressed))
+	case openPGPCompressionZLIB:
+		zlibReader, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, fields.formatError("malformed ZLIB data: %v", err)
+		}
+		reader = zlibReader
+	default:
+		return nil, fields.formatError("unsupported compression algorithm %d", algorithm[0])
+	}
+	decompressed, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fields.formatError("malformed compressed data: %v", err)
+	}
+	return decompressed, nil
+}
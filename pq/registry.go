@@ -0,0 +1,589 @@
+package pq
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// SignatureScheme is a signature algorithm operating on encoded keys, so that application code can select it by name or
+// OID from configuration or negotiation, and swap it without code changes.
+type SignatureScheme interface {
+	// Name returns the stable name of the algorithm, for example "ML-DSA-65".
+	Name() string
+	// OID returns the object identifier of the algorithm, or nil if it has none.
+	OID() asn1.ObjectIdentifier
+	// GenerateKey generates a new key pair and returns its encoded public and private keys.
+	GenerateKey() (publicKey []byte, privateKey []byte, err error)
+	// Sign signs message with the encoded private key under the context string, which may be empty.
+	Sign(privateKey []byte, message []byte, context []byte) ([]byte, error)
+	// Verify reports whether signature is a valid signature of message under the encoded public key and context string.
+	Verify(publicKey []byte, message []byte, signature []byte, context []byte) (bool, error)
+}
+
+// KEMScheme is a key encapsulation mechanism operating on encoded keys, so that application code can select it by name
+// or OID from configuration or negotiation, and swap it without code changes.
+type KEMScheme interface {
+	// Name returns the stable name of the algorithm, for example "ML-KEM-768".
+	Name() string
+	// OID returns the object identifier of the algorithm, or nil if it has none.
+	OID() asn1.ObjectIdentifier
+	// GenerateKey generates a new key pair and returns its encoded public and private keys.
+	GenerateKey() (publicKey []byte, privateKey []byte, err error)
+	// Encapsulate generates a shared secret and its ciphertext for the encoded public key.
+	Encapsulate(publicKey []byte) (ciphertext []byte, sharedSecret []byte, err error)
+	// Decapsulate recovers the shared secret from ciphertext with the encoded private key.
+	Decapsulate(privateKey []byte, ciphertext []byte) ([]byte, error)
+	// CiphertextSize returns the size in bytes of a ciphertext.
+	CiphertextSize() int
+	// SharedSecretSize returns the size in bytes of a shared secret.
+	SharedSecretSize() int
+}
+
+// schemeRegistry indexes registered schemes by lower-case name and by dotted OID, keeping registration order.
+type schemeRegistry[Scheme interface {
+	Name() string
+	OID() asn1.ObjectIdentifier
+}] struct {
+	mutex   sync.RWMutex
+	ordered []Scheme
+	byName  map[string]Scheme
+	byOID   map[string]Scheme
+}
+
+// register adds scheme, rejecting a name or OID that is already taken.
+func (registry *schemeRegistry[Scheme]) register(scheme Scheme) error {
+	name := strings.ToLower(scheme.Name())
+	if name == "" {
+		return errors.New("algorithm name must not be empty")
+	}
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	if registry.byName == nil {
+		registry.byName, registry.byOID = map[string]Scheme{}, map[string]Scheme{}
+	}
+	if _, taken := registry.byName[name]; taken {
+		return fmt.Errorf("algorithm %q is already registered", scheme.Name())
+	}
+	oid := scheme.OID()
+	if oid != nil {
+		if existing, taken := registry.byOID[oid.String()]; taken {
+			return fmt.Errorf("OID %v of %q is already registered for %q", oid, scheme.Name(), existing.Name())
+		}
+		registry.byOID[oid.String()] = scheme
+	}
+	registry.byName[name] = scheme
+	registry.ordered = append(registry.ordered, scheme)
+	return nil
+}
+
+// lookup returns the scheme whose name (case-insensitively) or dotted OID is identifier.
+func (registry *schemeRegistry[Scheme]) lookup(identifier string) (Scheme, error) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	if scheme, found := registry.byName[strings.ToLower(identifier)]; found {
+		return scheme, nil
+	}
+	if scheme, found := registry.byOID[identifier]; found {
+		return scheme, nil
+	}
+	var none Scheme
+	return none, fmt.Errorf("%w: %q", ErrUnknownAlgorithm, identifier)
+}
+
+// all returns the registered schemes in registration order.
+func (registry *schemeRegistry[Scheme]) all() []Scheme {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+	return append([]Scheme(nil), registry.ordered...)
+}
+
+var (
+	signatureSchemes schemeRegistry[SignatureScheme]
+	kemSchemes       schemeRegistry[KEMScheme]
+)
+
+// RegisterSignatureScheme adds a signature scheme to the registry, for example one implemented outside this package.
+// Names are matched case-insensitively; registering a name or OID that is already taken returns an error.
+func RegisterSignatureScheme(scheme SignatureScheme) error {
+	if scheme == nil {
+		return fmt.Errorf("%w: nil signature scheme", ErrUnknownAlgorithm)
+	}
+	return signatureSchemes.register(scheme)
+}
+
+// LookupSignatureScheme returns the registered signature scheme with the given name, for example "ML-DSA-65", or dotted
+// OID, for example "2.16.840.1.101.3.4.3.18". An unknown identifier returns an error matching ErrUnknownAlgorithm.
+func LookupSignatureScheme(identifier string) (SignatureScheme, error) {
+	return signatureSchemes.lookup(identifier)
+}
+
+// SignatureSchemeByOID returns the registered signature scheme with the given object identifier.
+func SignatureSchemeByOID(oid asn1.ObjectIdentifier) (SignatureScheme, error) {
+	return signatureSchemes.lookup(oid.String())
+}
+
+// SignatureSchemes returns every registered signature scheme, the built-in ones first.
+func SignatureSchemes() []SignatureScheme {
+	return signatureSchemes.all()
+}
+
+// RegisterKEMScheme adds a KEM scheme to the registry, for example one implemented outside this package.
+// Names are matched case-insensitively; registering a name or OID that is already taken returns an error.
+func RegisterKEMScheme(scheme KEMScheme) error {
+	if scheme == nil {
+		return fmt.Errorf("%w: nil KEM scheme", ErrUnknownAlgorithm)
+	}
+	return kemSchemes.register(scheme)
+}
+
+// LookupKEMScheme returns the registered KEM scheme with the given name, for example "ML-KEM-768", or dotted OID, for
+// example "2.16.840.1.101.3.4.4.2". An unknown identifier returns an error matching ErrUnknownAlgorithm.
+func LookupKEMScheme(identifier string) (KEMScheme, error) {
+	return kemSchemes.lookup(identifier)
+}
+
+// KEMSchemeByOID returns the registered KEM scheme with the given object identifier.
+func KEMSchemeByOID(oid asn1.ObjectIdentifier) (KEMScheme, error) {
+	return kemSchemes.lookup(oid.String())
+}
+
+// KEMSchemes returns every registered KEM scheme, the built-in ones first.
+func KEMSchemes() []KEMScheme {
+	return kemSchemes.all()
+}
+
+// init registers every algorithm implemented by this package. LegacyKyber1024 is left out: it is only for decrypting
+// old data, so it must never be offered or accepted during negotiation.
+func init() {
+	for _, parameterSet := range MLDSAParameterSets {
+		mustRegister(RegisterSignatureScheme(mldsaSignatureScheme{parameterSet: parameterSet}))
+	}
+	for _, parameterSet := range MLDSAParameterSets {
+		mustRegister(RegisterSignatureScheme(hashMLDSASignatureScheme{parameterSet: parameterSet, preHash: PreHashSHA512}))
+	}
+	for _, algorithm := range CompositeMLDSAAlgorithms {
+		mustRegister(RegisterSignatureScheme(compositeMLDSASignatureScheme{algorithm: algorithm}))
+	}
+	for _, parameterSet := range SLHDSAParameterSets {
+		mustRegister(RegisterSignatureScheme(slhdsaSignatureScheme{parameterSet: parameterSet}))
+	}
+	for _, parameterSet := range MLKEMParameterSets {
+		mustRegister(RegisterKEMScheme(mlkemKEMScheme{parameterSet: parameterSet}))
+	}
+	mustRegister(RegisterKEMScheme(xwingKEMScheme{}))
+	for _, curve := range ECDHCurves {
+		for _, parameterSet := range MLKEMParameterSets {
+			mustRegister(RegisterKEMScheme(hybridKEMScheme{curve: curve, parameterSet: parameterSet}))
+		}
+	}
+	for _, parameterSet := range FrodoKEMParameterSets {
+		mustRegister(RegisterKEMScheme(frodoKEMScheme{parameterSet: parameterSet}))
+	}
+	for _, parameterSet := range ClassicMcElieceParameterSets {
+		mustRegister(RegisterKEMScheme(classicMcElieceKEMScheme{parameterSet: parameterSet}))
+	}
+}
+
+// mustRegister panics if registering a built-in scheme failed, which is a programming error.
+func mustRegister(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+// oidOrNil returns oid, or nil if looking it up failed.
+func oidOrNil(oid asn1.ObjectIdentifier, err error) asn1.ObjectIdentifier {
+	if err != nil {
+		return nil
+	}
+	return oid
+}
+
+// mldsaSignatureScheme is pure ML-DSA of one parameter set, with expanded private keys.
+type mldsaSignatureScheme struct {
+	parameterSet MLDSAParameterSet
+}
+
+func (scheme mldsaSignatureScheme) Name() string { return scheme.parameterSet.String() }
+
+func (scheme mldsaSignatureScheme) OID() asn1.ObjectIdentifier {
+	return oidOrNil(scheme.parameterSet.OID())
+}
+
+func (scheme mldsaSignatureScheme) GenerateKey() ([]byte, []byte, error) {
+	keyPair, err := GenerateMLDSAKeyPair(scheme.parameterSet)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keyPair.PublicKey, keyPair.PrivateKey, nil
+}
+
+func (scheme mldsaSignatureScheme) Sign(privateKey []byte, message []byte, context []byte) ([]byte, error) {
+	return MLDSASignWithContext(scheme.parameterSet, privateKey, message, context)
+}
+
+func (scheme mldsaSignatureScheme) Verify(publicKey []byte, message []byte, signature []byte, context []byte) (bool, error) {
+	return MLDSAVerifyWithContext(scheme.parameterSet, publicKey, message, signature, context)
+}
+
+// hashMLDSASignatureScheme is HashML-DSA of one parameter set and pre-hash function, with expanded private keys.
+type hashMLDSASignatureScheme struct {
+	parameterSet MLDSAParameterSet
+	preHash      MLDSAPreHash
+}
+
+func (scheme hashMLDSASignatureScheme) Name() string {
+	return fmt.Sprintf("Hash%v-%v", scheme.parameterSet, scheme.preHash)
+}
+
+// OID returns id-hash-ml-dsa-44-with-sha512 and its siblings (2.16.840.1.101.3.4.3.32-34); other pre-hashes have none.
+func (scheme hashMLDSASignatureScheme) OID() asn1.ObjectIdentifier {
+	if scheme.preHash != PreHashSHA512 {
+		return nil
+	}
+	switch scheme.parameterSet {
+	case MLDSA44:
+		return asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 32}
+	case MLDSA65:
+		return asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 33}
+	case MLDSA87:
+		return asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 34}
+	default:
+		return nil
+	}
+}
+
+func (scheme hashMLDSASignatureScheme) GenerateKey() ([]byte, []byte, error) {
+	return mldsaSignatureScheme{parameterSet: scheme.parameterSet}.GenerateKey()
+}
+
+func (scheme hashMLDSASignatureScheme) Sign(privateKey []byte, message []byte, context []byte) ([]byte, error) {
+	return HashMLDSASign(scheme.parameterSet, scheme.preHash, privateKey, message, context)
+}
+
+func (scheme hashMLDSASignatureScheme) Verify(publicKey []byte, message []byte, signature []byte, context []byte) (bool, error) {
+	return HashMLDSAVerify(scheme.parameterSet, scheme.preHash, publicKey, message, signature, context)
+}
+
+// compositeMLDSASignatureScheme is a composite ML-DSA algorithm, with keys encoded by MarshalCompositeMLDSAPublicKey and
+// MarshalCompositeMLDSAPrivateKey.
+type compositeMLDSASignatureScheme struct {
+	algorithm CompositeMLDSAAlgorithm
+}
+
+func (scheme compositeMLDSASignatureScheme) Name() string { return scheme.algorithm.String() }
+
+func (scheme compositeMLDSASignatureScheme) OID() asn1.ObjectIdentifier {
+	return oidOrNil(scheme.algorithm.OID())
+}
+
+func (scheme compositeMLDSASignatureScheme) GenerateKey() ([]byte, []byte, error) {
+	keyPair, err := GenerateCompositeMLDSAKeyPair(scheme.algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err := MarshalCompositeMLDSAPublicKey(keyPair.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey, err := MarshalCompositeMLDSAPrivateKey(keyPair.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return publicKey, privateKey, nil
+}
+
+func (scheme compositeMLDSASignatureScheme) Sign(privateKey []byte, message []byte, context []byte) ([]byte, error) {
+	compositePrivateKey, err := UnmarshalCompositeMLDSAPrivateKey(scheme.algorithm, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return CompositeMLDSASign(compositePrivateKey, message, context)
+}
+
+func (scheme compositeMLDSASignatureScheme) Verify(publicKey []byte, message []byte, signature []byte, context []byte) (bool, error) {
+	compositePublicKey, err := UnmarshalCompositeMLDSAPublicKey(scheme.algorithm, publicKey)
+	if err != nil {
+		return false, err
+	}
+	return CompositeMLDSAVerify(compositePublicKey, message, signature, context)
+}
+
+// slhdsaSignatureScheme is pure SLH-DSA of one parameter set.
+type slhdsaSignatureScheme struct {
+	parameterSet SLHDSAParameterSet
+}
+
+func (scheme slhdsaSignatureScheme) Name() string { return scheme.parameterSet.String() }
+
+func (scheme slhdsaSignatureScheme) OID() asn1.ObjectIdentifier {
+	return oidOrNil(scheme.parameterSet.OID())
+}
+
+func (scheme slhdsaSignatureScheme) GenerateKey() ([]byte, []byte, error) {
+	keyPair, err := GenerateSLHDSAKeyPair(scheme.parameterSet)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keyPair.PublicKey, keyPair.PrivateKey, nil
+}
+
+func (scheme slhdsaSignatureScheme) Sign(privateKey []byte, message []byte, context []byte) ([]byte, error) {
+	return SLHDSASignWithContext(scheme.parameterSet, privateKey, message, context)
+}
+
+func (scheme slhdsaSignatureScheme) Verify(publicKey []byte, message []byte, signature []byte, context []byte) (bool, error) {
+	return SLHDSAVerifyWithContext(scheme.parameterSet, publicKey, message, signature, context)
+}
+
+// mlkemKEMScheme is ML-KEM of one parameter set, or legacy Kyber1024, with keys encoded by MarshalPublicKey and MarshalPrivateKey.
+type mlkemKEMScheme struct {
+	parameterSet MLKEMParameterSet
+}
+
+func (scheme mlkemKEMScheme) Name() string { return scheme.parameterSet.String() }
+
+func (scheme mlkemKEMScheme) OID() asn1.ObjectIdentifier {
+	return oidOrNil(scheme.parameterSet.OID())
+}
+
+func (scheme mlkemKEMScheme) GenerateKey() ([]byte, []byte, error) {
+	keyPair, err := GenerateMLKEMKeyPair(scheme.parameterSet)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err := MarshalPublicKey(keyPair.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey, err := MarshalPrivateKey(keyPair.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return publicKey, privateKey, nil
+}
+
+func (scheme mlkemKEMScheme) Encapsulate(publicKey []byte) ([]byte, []byte, error) {
+	mlkemPublicKey, err := UnmarshalPublicKey(scheme.parameterSet, publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return MLKEMEncapsulate(mlkemPublicKey)
+}
+
+func (scheme mlkemKEMScheme) Decapsulate(privateKey []byte, ciphertext []byte) ([]byte, error) {
+	mlkemPrivateKey, err := UnmarshalPrivateKey(scheme.parameterSet, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return MLKEMDecapsulate(mlkemPrivateKey, ciphertext)
+}
+
+func (scheme mlkemKEMScheme) CiphertextSize() int {
+	kemScheme, _ := scheme.parameterSet.scheme()
+	return kemScheme.CiphertextSize()
+}
+
+func (scheme mlkemKEMScheme) SharedSecretSize() int { return MLKEMSharedSecretSize }
+
+// xwingKEMScheme is X-Wing, with the 32-byte seed as the private key.
+type xwingKEMScheme struct{}
+
+func (xwingKEMScheme) Name() string { return "X-Wing" }
+
+func (xwingKEMScheme) OID() asn1.ObjectIdentifier { return oidXWing }
+
+func (xwingKEMScheme) GenerateKey() ([]byte, []byte, error) {
+	keyPair, err := GenerateXWingKeyPair()
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err := MarshalPublicKey(keyPair.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey, err := MarshalPrivateKey(keyPair.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return publicKey, privateKey, nil
+}
+
+func (xwingKEMScheme) Encapsulate(publicKey []byte) ([]byte, []byte, error) {
+	xwingPublicKey, err := UnmarshalXWingPublicKey(publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return XWingEncapsulate(xwingPublicKey)
+}
+
+func (xwingKEMScheme) Decapsulate(privateKey []byte, ciphertext []byte) ([]byte, error) {
+	xwingPrivateKey, err := UnmarshalXWingPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return XWingDecapsulate(xwingPrivateKey, ciphertext)
+}
+
+func (xwingKEMScheme) CiphertextSize() int { return XWingCiphertextSize }
+
+func (xwingKEMScheme) SharedSecretSize() int { return XWingSharedSecretSize }
+
+// hybridKEMScheme is the generic ECDH + ML-KEM combiner for one curve and parameter set, with keys encoded by
+// MarshalHybridKEMPublicKey and MarshalHybridKEMPrivateKey. It has no OID.
+type hybridKEMScheme struct {
+	curve        ECDHCurve
+	parameterSet MLKEMParameterSet
+}
+
+func (scheme hybridKEMScheme) Name() string {
+	return fmt.Sprintf("%v+%v", scheme.parameterSet, scheme.curve)
+}
+
+func (hybridKEMScheme) OID() asn1.ObjectIdentifier { return nil }
+
+func (scheme hybridKEMScheme) GenerateKey() ([]byte, []byte, error) {
+	keyPair, err := GenerateHybridKEMKeyPair(scheme.curve, scheme.parameterSet)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err := MarshalHybridKEMPublicKey(keyPair.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey, err := MarshalHybridKEMPrivateKey(keyPair.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return publicKey, privateKey, nil
+}
+
+func (scheme hybridKEMScheme) Encapsulate(publicKey []byte) ([]byte, []byte, error) {
+	hybridPublicKey, err := UnmarshalHybridKEMPublicKey(scheme.curve, scheme.parameterSet, publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return HybridKEMEncapsulate(hybridPublicKey)
+}
+
+func (scheme hybridKEMScheme) Decapsulate(privateKey []byte, ciphertext []byte) ([]byte, error) {
+	hybridPrivateKey, err := UnmarshalHybridKEMPrivateKey(scheme.curve, scheme.parameterSet, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return HybridKEMDecapsulate(hybridPrivateKey, ciphertext)
+}
+
+func (scheme hybridKEMScheme) CiphertextSize() int {
+	kemScheme, _ := scheme.parameterSet.scheme()
+	return kemScheme.CiphertextSize() + scheme.curve.publicKeySize()
+}
+
+func (hybridKEMScheme) SharedSecretSize() int { return HybridKEMSharedSecretSize }
+
+// frodoKEMScheme is FrodoKEM of one parameter set, with keys encoded by MarshalPublicKey and MarshalPrivateKey.
+// FrodoKEM has no standard OID.
+type frodoKEMScheme struct {
+	parameterSet FrodoKEMParameterSet
+}
+
+func (scheme frodoKEMScheme) Name() string { return scheme.parameterSet.String() }
+
+func (frodoKEMScheme) OID() asn1.ObjectIdentifier { return nil }
+
+func (scheme frodoKEMScheme) GenerateKey() ([]byte, []byte, error) {
+	keyPair, err := GenerateFrodoKEMKeyPair(scheme.parameterSet)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err := MarshalPublicKey(keyPair.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey, err := MarshalPrivateKey(keyPair.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return publicKey, privateKey, nil
+}
+
+func (scheme frodoKEMScheme) Encapsulate(publicKey []byte) ([]byte, []byte, error) {
+	frodoPublicKey, err := UnmarshalFrodoKEMPublicKey(scheme.parameterSet, publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return FrodoKEMEncapsulate(frodoPublicKey)
+}
+
+func (scheme frodoKEMScheme) Decapsulate(privateKey []byte, ciphertext []byte) ([]byte, error) {
+	frodoPrivateKey, err := UnmarshalFrodoKEMPrivateKey(scheme.parameterSet, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return FrodoKEMDecapsulate(frodoPrivateKey, ciphertext)
+}
+
+func (scheme frodoKEMScheme) CiphertextSize() int {
+	kemScheme, _ := scheme.parameterSet.scheme()
+	return kemScheme.CiphertextSize()
+}
+
+func (scheme frodoKEMScheme) SharedSecretSize() int {
+	kemScheme, _ := scheme.parameterSet.scheme()
+	return kemScheme.SharedKeySize()
+}
+
+// classicMcElieceKEMScheme is Classic McEliece of one parameter set, with keys encoded by MarshalPublicKey and
+// MarshalPrivateKey. Classic McEliece has no standard OID.
+type classicMcElieceKEMScheme struct {
+	parameterSet ClassicMcElieceParameterSet
+}
+
+func (scheme classicMcElieceKEMScheme) Name() string { return scheme.parameterSet.String() }
+
+func (classicMcElieceKEMScheme) OID() asn1.ObjectIdentifier { return nil }
+
+func (scheme classicMcElieceKEMScheme) GenerateKey() ([]byte, []byte, error) {
+	keyPair, err := GenerateClassicMcElieceKeyPair(scheme.parameterSet)
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err := MarshalPublicKey(keyPair.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	privateKey, err := MarshalPrivateKey(keyPair.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return publicKey, privateKey, nil
+}
+
+func (scheme classicMcElieceKEMScheme) Encapsulate(publicKey []byte) ([]byte, []byte, error) {
+	mceliecePublicKey, err := UnmarshalClassicMcEliecePublicKey(scheme.parameterSet, publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ClassicMcElieceEncapsulate(mceliecePublicKey)
+}
+
+func (scheme classicMcElieceKEMScheme) Decapsulate(privateKey []byte, ciphertext []byte) ([]byte, error) {
+	mceliecePrivateKey, err := UnmarshalClassicMcEliecePrivateKey(scheme.parameterSet, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return ClassicMcElieceDecapsulate(mceliecePrivateKey, ciphertext)
+}
+
+func (scheme classicMcElieceKEMScheme) CiphertextSize() int {
+	kemScheme, _ := scheme.parameterSet.scheme()
+	return kemScheme.CiphertextSize()
+}
+
+func (scheme classicMcElieceKEMScheme) SharedSecretSize() int {
+	kemScheme, _ := scheme.parameterSet.scheme()
+	return kemScheme.SharedKeySize()
+}
@@ -0,0 +1,25 @@
+package pq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkLookupKEMScheme(b *testing.B) {
+	for b.Loop() {
+		_, err := LookupKEMScheme("ML-KEM-768")
+		require.NoError(b, err, "Lookup should not error")
+	}
+}
+
+func BenchmarkKEMSchemeEncapsulate(b *testing.B) {
+	scheme, err := LookupKEMScheme("ML-KEM-768")
+	require.NoError(b, err, "Lookup should not error")
+	publicKey, _, err := scheme.GenerateKey()
+	require.NoError(b, err, "Key pair generation should not error")
+	for b.Loop() {
+		_, _, err := scheme.Encapsulate(publicKey)
+		require.NoError(b, err, "Encapsulation should not error")
+	}
+}
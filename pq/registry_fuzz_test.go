@@ -0,0 +1,18 @@
+package pq
+
+import "testing"
+
+func FuzzLookupScheme(f *testing.F) {
+	f.Add("ML-DSA-65")
+	f.Add("2.16.840.1.101.3.4.4.2")
+	f.Fuzz(func(t *testing.T, identifier string) {
+		signatureScheme, err := LookupSignatureScheme(identifier)
+		if err == nil && signatureScheme == nil {
+			t.Error("expected a scheme without error")
+		}
+		kemScheme, err := LookupKEMScheme(identifier)
+		if err == nil && kemScheme == nil {
+			t.Error("expected a scheme without error")
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package pq
+
+import (
+	"encoding/asn1"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// For TestSignatureSchemeRegistry: every registered signature scheme generates keys, signs and verifies through the
+// interface, binds the context string, and is found again by its name and OID.
+func TestSignatureSchemeRegistry(t *testing.T) {
+	message := []byte("algorithm agility")
+	schemes := SignatureSchemes()
+	require.GreaterOrEqual(t, len(schemes), len(MLDSAParameterSets)*2+len(CompositeMLDSAAlgorithms)+len(SLHDSAParameterSets))
+	for _, scheme := range schemes {
+		t.Run(scheme.Name(), func(t *testing.T) {
+			publicKey, privateKey, err := scheme.GenerateKey()
+			require.NoError(t, err)
+			signature, err := scheme.Sign(privateKey, message, []byte("ctx"))
+			require.NoError(t, err)
+			valid, err := scheme.Verify(publicKey, message, signature, []byte("ctx"))
+			require.NoError(t, err)
+			require.True(t, valid)
+			valid, err = scheme.Verify(publicKey, message, signature, nil)
+			require.NoError(t, err)
+			require.False(t, valid, "the context string must be bound")
+
+			found, err := LookupSignatureScheme(scheme.Name())
+			require.NoError(t, err)
+			require.Equal(t, scheme, found)
+			if oid := scheme.OID(); oid != nil {
+				found, err = SignatureSchemeByOID(oid)
+				require.NoError(t, err)
+				require.Equal(t, scheme, found)
+				found, err = LookupSignatureScheme(oid.String())
+				require.NoError(t, err)
+				require.Equal(t, scheme, found)
+			}
+		})
+	}
+}
+
+// For TestKEMSchemeRegistry: every registered KEM scheme encapsulates and decapsulates through the interface with the
+// advertised sizes, and is found again by its name and OID.
+func TestKEMSchemeRegistry(t *testing.T) {
+	schemes := KEMSchemes()
+	require.GreaterOrEqual(t, len(schemes), len(MLKEMParameterSets)+1+len(ECDHCurves)*len(MLKEMParameterSets)+len(FrodoKEMParameterSets)+len(ClassicMcElieceParameterSets))
+	for _, scheme := range schemes {
+		t.Run(scheme.Name(), func(t *testing.T) {
+			publicKey, privateKey, err := scheme.GenerateKey()
+			require.NoError(t, err)
+			ciphertext, sharedSecret, err := scheme.Encapsulate(publicKey)
+			require.NoError(t, err)
+			require.Len(t, ciphertext, scheme.CiphertextSize())
+			require.Len(t, sharedSecret, scheme.SharedSecretSize())
+			decapsulated, err := scheme.Decapsulate(privateKey, ciphertext)
+			require.NoError(t, err)
+			require.Equal(t, sharedSecret, decapsulated)
+
+			found, err := LookupKEMScheme(scheme.Name())
+			require.NoError(t, err)
+			require.Equal(t, scheme, found)
+			if oid := scheme.OID(); oid != nil {
+				found, err = KEMSchemeByOID(oid)
+				require.NoError(t, err)
+				require.Equal(t, scheme, found)
+			}
+		})
+	}
+}
+
+// For TestSchemeLookup: names are matched case-insensitively, standard OIDs resolve, and unknown identifiers, including
+// the unregistered legacy Kyber1024, fail with ErrUnknownAlgorithm.
+func TestSchemeLookup(t *testing.T) {
+	scheme, err := LookupKEMScheme("ml-kem-768")
+	require.NoError(t, err)
+	require.Equal(t, "ML-KEM-768", scheme.Name())
+	scheme, err = LookupKEMScheme("2.16.840.1.101.3.4.4.3")
+	require.NoError(t, err)
+	require.Equal(t, "ML-KEM-1024", scheme.Name())
+	scheme, err = LookupKEMScheme("ML-KEM-768+X25519")
+	require.NoError(t, err)
+	require.Nil(t, scheme.OID())
+
+	signatureScheme, err := SignatureSchemeByOID(asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 18})
+	require.NoError(t, err)
+	require.Equal(t, "ML-DSA-65", signatureScheme.Name())
+	signatureScheme, err = SignatureSchemeByOID(asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 33})
+	require.NoError(t, err)
+	require.Equal(t, "HashML-DSA-65-SHA-512", signatureScheme.Name())
+
+	_, err = LookupSignatureScheme("RSA-2048")
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+	_, err = LookupKEMScheme("")
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+	_, err = KEMSchemeByOID(asn1.ObjectIdentifier{1, 2, 3})
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+	for _, name := range []string{"Kyber1024-legacy", LegacyKyber1024.String()} {
+		_, err = LookupKEMScheme(name)
+		require.ErrorIs(t, err, ErrUnknownAlgorithm, "legacy Kyber must not be negotiable")
+	}
+}
+
+// renamedKEMScheme registers an existing KEM under another name, as an application-defined scheme would.
+type renamedKEMScheme struct {
+	KEMScheme
+	name string
+	oid  asn1.ObjectIdentifier
+}
+
+func (scheme renamedKEMScheme) Name() string               { return scheme.name }
+func (scheme renamedKEMScheme) OID() asn1.ObjectIdentifier { return scheme.oid }
+
+// For TestRegisterKEMScheme: applications can register their own schemes, and names and OIDs cannot be registered twice.
+func TestRegisterKEMScheme(t *testing.T) {
+	base, err := LookupKEMScheme("ML-KEM-512")
+	require.NoError(t, err)
+	require.NoError(t, RegisterKEMScheme(renamedKEMScheme{KEMScheme: base, name: "test-registry-kem", oid: asn1.ObjectIdentifier{1, 3, 9999, 1}}))
+	found, err := LookupKEMScheme("TEST-registry-KEM")
+	require.NoError(t, err)
+	require.Equal(t, "test-registry-kem", found.Name())
+	found, err = LookupKEMScheme("1.3.9999.1")
+	require.NoError(t, err)
+	require.Equal(t, "test-registry-kem", found.Name())
+
+	require.ErrorContains(t, RegisterKEMScheme(renamedKEMScheme{KEMScheme: base, name: "ML-KEM-512"}), "already registered")
+	require.ErrorContains(t, RegisterKEMScheme(renamedKEMScheme{KEMScheme: base, name: "another", oid: base.OID()}), "already registered")
+	require.Error(t, RegisterKEMScheme(renamedKEMScheme{KEMScheme: base}))
+	require.ErrorIs(t, RegisterKEMScheme(nil), ErrUnknownAlgorithm)
+	require.ErrorIs(t, RegisterSignatureScheme(nil), ErrUnknownAlgorithm)
+}
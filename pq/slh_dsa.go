@@ -0,0 +1,426 @@
+package pq
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"encoding/asn1"
+	"fmt"
+	"io"
+
+	"github.com/cloudflare/circl/sign"
+	"github.com/cloudflare/circl/sign/slhdsa"
+	"github.com/cloudflare/circl/xof"
+)
+
+// SLHDSAParameterSet identifies an SLH-DSA parameter set from FIPS 205. The "s" sets give small signatures and slow
+// signing, the "f" sets fast signing and larger signatures.
+type SLHDSAParameterSet int
+
+const (
+	// SLHDSASHA2_128s is SLH-DSA-SHA2-128s (NIST security category 1).
+	SLHDSASHA2_128s SLHDSAParameterSet = iota + 1
+	// SLHDSASHA2_128f is SLH-DSA-SHA2-128f (NIST security category 1).
+	SLHDSASHA2_128f
+	// SLHDSASHA2_192s is SLH-DSA-SHA2-192s (NIST security category 3).
+	SLHDSASHA2_192s
+	// SLHDSASHA2_192f is SLH-DSA-SHA2-192f (NIST security category 3).
+	SLHDSASHA2_192f
+	// SLHDSASHA2_256s is SLH-DSA-SHA2-256s (NIST security category 5).
+	SLHDSASHA2_256s
+	// SLHDSASHA2_256f is SLH-DSA-SHA2-256f (NIST security category 5).
+	SLHDSASHA2_256f
+	// SLHDSASHAKE_128s is SLH-DSA-SHAKE-128s (NIST security category 1).
+	SLHDSASHAKE_128s
+	// SLHDSASHAKE_128f is SLH-DSA-SHAKE-128f (NIST security category 1).
+	SLHDSASHAKE_128f
+	// SLHDSASHAKE_192s is SLH-DSA-SHAKE-192s (NIST security category 3).
+	SLHDSASHAKE_192s
+	// SLHDSASHAKE_192f is SLH-DSA-SHAKE-192f (NIST security category 3).
+	SLHDSASHAKE_192f
+	// SLHDSASHAKE_256s is SLH-DSA-SHAKE-256s (NIST security category 5).
+	SLHDSASHAKE_256s
+	// SLHDSASHAKE_256f is SLH-DSA-SHAKE-256f (NIST security category 5).
+	SLHDSASHAKE_256f
+)
+
+// SLHDSAMaxContextSize is the maximum size in bytes of a FIPS 205 context string (ctx).
+const SLHDSAMaxContextSize = 255
+
+// SLHDSAParameterSets lists the FIPS 205 SLH-DSA parameter sets, in the order of their NIST object identifiers.
+var SLHDSAParameterSets = []SLHDSAParameterSet{
+	SLHDSASHA2_128s, SLHDSASHA2_128f, SLHDSASHA2_192s, SLHDSASHA2_192f, SLHDSASHA2_256s, SLHDSASHA2_256f,
+	SLHDSASHAKE_128s, SLHDSASHAKE_128f, SLHDSASHAKE_192s, SLHDSASHAKE_192f, SLHDSASHAKE_256s, SLHDSASHAKE_256f,
+}
+
+// circlID returns the CIRCL identifier of the parameter set.
+func (parameterSet SLHDSAParameterSet) circlID() (slhdsa.ID, error) {
+	switch parameterSet {
+	case SLHDSASHA2_128s:
+		return slhdsa.SHA2_128s, nil
+	case SLHDSASHA2_128f:
+		return slhdsa.SHA2_128f, nil
+	case SLHDSASHA2_192s:
+		return slhdsa.SHA2_192s, nil
+	case SLHDSASHA2_192f:
+		return slhdsa.SHA2_192f, nil
+	case SLHDSASHA2_256s:
+		return slhdsa.SHA2_256s, nil
+	case SLHDSASHA2_256f:
+		return slhdsa.SHA2_256f, nil
+	case SLHDSASHAKE_128s:
+		return slhdsa.SHAKE_128s, nil
+	case SLHDSASHAKE_128f:
+		return slhdsa.SHAKE_128f, nil
+	case SLHDSASHAKE_192s:
+		return slhdsa.SHAKE_192s, nil
+	case SLHDSASHAKE_192f:
+		return slhdsa.SHAKE_192f, nil
+	case SLHDSASHAKE_256s:
+		return slhdsa.SHAKE_256s, nil
+	case SLHDSASHAKE_256f:
+		return slhdsa.SHAKE_256f, nil
+	default:
+		return 0, fmt.Errorf("unsupported SLH-DSA parameter set: %d", int(parameterSet))
+	}
+}
+
+// String returns the name of the parameter set, for example "SLH-DSA-SHAKE-128f".
+func (parameterSet SLHDSAParameterSet) String() string {
+	id, err := parameterSet.circlID()
+	if err != nil {
+		return fmt.Sprintf("SLHDSAParameterSet(%d)", int(parameterSet))
+	}
+	return id.String()
+}
+
+// OID returns the NIST object identifier of the parameter set (2.16.840.1.101.3.4.3.20-31), used in SubjectPublicKeyInfo and PKCS#8.
+func (parameterSet SLHDSAParameterSet) OID() (asn1.ObjectIdentifier, error) {
+	if parameterSet < SLHDSASHA2_128s || parameterSet > SLHDSASHAKE_256f {
+		return nil, fmt.Errorf("unsupported SLH-DSA parameter set: %v", parameterSet)
+	}
+	return asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 3, 19 + int(parameterSet)}, nil
+}
+
+// scheme returns the CIRCL signature scheme implementing the parameter set.
+func (parameterSet SLHDSAParameterSet) scheme() (sign.Scheme, error) {
+	id, err := parameterSet.circlID()
+	if err != nil {
+		return nil, err
+	}
+	return id.Scheme(), nil
+}
+
+// SeedSize returns the size in bytes of the seed SK.seed || SK.prf || PK.seed used for deterministic key generation,
+// three times the security parameter n, or 0 for an unsupported parameter set.
+func (parameterSet SLHDSAParameterSet) SeedSize() int {
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return 0
+	}
+	return scheme.PublicKeySize() / 2 * 3
+}
+
+// PublicKeySize returns the size in bytes of a public key, or 0 for an unsupported parameter set.
+func (parameterSet SLHDSAParameterSet) PublicKeySize() int {
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return 0
+	}
+	return scheme.PublicKeySize()
+}
+
+// PrivateKeySize returns the size in bytes of a private key, or 0 for an unsupported parameter set.
+func (parameterSet SLHDSAParameterSet) PrivateKeySize() int {
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return 0
+	}
+	return scheme.PrivateKeySize()
+}
+
+// SignatureSize returns the size in bytes of a signature, or 0 for an unsupported parameter set.
+func (parameterSet SLHDSAParameterSet) SignatureSize() int {
+	scheme, err := parameterSet.scheme()
+	if err != nil {
+		return 0
+	}
+	return scheme.SignatureSize()
+}
+
+// SLHDSAKeyPair represents a key pair for SLH-DSA, and the parameter set it belongs to.
+// Seed is the seed SK.seed || SK.prf || PK.seed the key pair was derived from, or nil if only the private key is known.
+type SLHDSAKeyPair struct {
+	ParameterSet SLHDSAParameterSet
+	PublicKey    []byte
+	PrivateKey   []byte
+	Seed         []byte
+}
+
+// GenerateSLHDSAKeyPair generates a new SLH-DSA key pair for the given parameter set from a fresh random seed.
+func GenerateSLHDSAKeyPair(parameterSet SLHDSAParameterSet) (keyPair *SLHDSAKeyPair, keyGenerationError error) {
+	if _, idError := parameterSet.circlID(); idError != nil {
+		return nil, idError
+	}
+	seed := make([]byte, parameterSet.SeedSize())
+	if _, randomError := rand.Read(seed); randomError != nil {
+		return nil, fmt.Errorf("rand.Read: %w", randomError)
+	}
+	keyPair, keyGenerationError = DeriveSLHDSAKeyPair(parameterSet, seed)
+	if keyGenerationError != nil {
+		return nil, keyGenerationError
+	}
+	Logger().Debug("GenerateSLHDSAKeyPair", "parameter_set", parameterSet.String(), "public_key_size", len(keyPair.PublicKey), "private_key_size", len(keyPair.PrivateKey))
+	return keyPair, nil
+}
+
+// DeriveSLHDSAKeyPair deterministically derives an SLH-DSA key pair for the given parameter set (FIPS 205 Algorithm 18,
+// slh_keygen_internal). The seed is SK.seed || SK.prf || PK.seed, of length parameterSet.SeedSize(), as in the NIST
+// ACVP key generation vectors.
+func DeriveSLHDSAKeyPair(parameterSet SLHDSAParameterSet, seed []byte) (keyPair *SLHDSAKeyPair, keyGenerationError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			keyGenerationError = newPanicError("DeriveSLHDSAKeyPair", recoveredPanic)
+		}
+	}()
+	id, idError := parameterSet.circlID()
+	if idError != nil {
+		return nil, idError
+	}
+	if len(seed) != parameterSet.SeedSize() {
+		return nil, newSizeError(ErrInvalidSeedSize, parameterSet.String()+" seed", len(seed), parameterSet.SeedSize())
+	}
+	// GenerateKey reads SK.seed, SK.prf and PK.seed, in this order, from the random source.
+	publicKey, privateKey, keyGenerationError := slhdsa.GenerateKey(bytes.NewReader(seed), id)
+	if keyGenerationError != nil {
+		return nil, fmt.Errorf("%v GenerateKey: %w", parameterSet, keyGenerationError)
+	}
+	publicKeyBytes, publicKeyMarshalError := publicKey.MarshalBinary()
+	if publicKeyMarshalError != nil {
+		return nil, fmt.Errorf("publicKey.MarshalBinary: %w", publicKeyMarshalError)
+	}
+	privateKeyBytes, privateKeyMarshalError := privateKey.MarshalBinary()
+	if privateKeyMarshalError != nil {
+		return nil, fmt.Errorf("privateKey.MarshalBinary: %w", privateKeyMarshalError)
+	}
+	Logger().Debug("DeriveSLHDSAKeyPair", "parameter_set", parameterSet.String(), "public_key_size", len(publicKeyBytes), "private_key_size", len(privateKeyBytes))
+	return &SLHDSAKeyPair{
+		ParameterSet: parameterSet,
+		PublicKey:    publicKeyBytes,
+		PrivateKey:   privateKeyBytes,
+		Seed:         append([]byte(nil), seed...),
+	}, nil
+}
+
+// SLHDSASignOptions selects how an SLH-DSA signature is made. The zero value (and a nil *SLHDSASignOptions) selects
+// hedged signing with crypto/rand and an empty context string.
+type SLHDSASignOptions struct {
+	// Context is the FIPS 205 context string (ctx), at most SLHDSAMaxContextSize bytes; nil and empty are equivalent.
+	Context []byte
+	// Deterministic selects the FIPS 205 deterministic variant (opt_rand is PK.seed), so signing the same message with
+	// the same key always gives the same signature. It cannot be combined with Rand.
+	Deterministic bool
+	// Rand is the source of the n bytes of per-signature randomness (opt_rand) for hedged signing. If nil, crypto/rand is used.
+	Rand io.Reader
+}
+
+// context returns the context string of the options, or nil for nil options.
+func (options *SLHDSASignOptions) context() []byte {
+	if options == nil {
+		return nil
+	}
+	return options.Context
+}
+
+// unmarshalSLHDSAPrivateKey parses a private key of the given parameter set.
+func unmarshalSLHDSAPrivateKey(parameterSet SLHDSAParameterSet, privateKeyBytes []byte) (*slhdsa.PrivateKey, error) {
+	id, idError := parameterSet.circlID()
+	if idError != nil {
+		return nil, idError
+	}
+	if len(privateKeyBytes) != parameterSet.PrivateKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, parameterSet.String()+" private key", len(privateKeyBytes), parameterSet.PrivateKeySize())
+	}
+	privateKey := &slhdsa.PrivateKey{ID: id}
+	if unmarshalError := privateKey.UnmarshalBinary(privateKeyBytes); unmarshalError != nil {
+		return nil, fmt.Errorf("invalid %v private key: %w", parameterSet, unmarshalError)
+	}
+	return privateKey, nil
+}
+
+// unmarshalSLHDSAPublicKey parses a public key of the given parameter set.
+func unmarshalSLHDSAPublicKey(parameterSet SLHDSAParameterSet, publicKeyBytes []byte) (*slhdsa.PublicKey, error) {
+	id, idError := parameterSet.circlID()
+	if idError != nil {
+		return nil, idError
+	}
+	if len(publicKeyBytes) != parameterSet.PublicKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, parameterSet.String()+" public key", len(publicKeyBytes), parameterSet.PublicKeySize())
+	}
+	publicKey := &slhdsa.PublicKey{ID: id}
+	if unmarshalError := publicKey.UnmarshalBinary(publicKeyBytes); unmarshalError != nil {
+		return nil, fmt.Errorf("invalid %v public key: %w", parameterSet, unmarshalError)
+	}
+	return publicKey, nil
+}
+
+// slhdsaPreHashMessage hashes messageBytes with preHash into the HashSLH-DSA message OID(PH) || PH(M) (FIPS 205 Algorithm 23).
+func slhdsaPreHashMessage(preHash MLDSAPreHash, messageBytes []byte) (*slhdsa.Message, error) {
+	var preHasher *slhdsa.PreHash
+	var preHashError error
+	switch preHash {
+	case PreHashSHA256:
+		preHasher, preHashError = slhdsa.NewPreHashWithHash(crypto.SHA256)
+	case PreHashSHA384:
+		preHasher, preHashError = slhdsa.NewPreHashWithHash(crypto.SHA384)
+	case PreHashSHA512:
+		preHasher, preHashError = slhdsa.NewPreHashWithHash(crypto.SHA512)
+	case PreHashSHA3_256:
+		preHasher, preHashError = slhdsa.NewPreHashWithHash(crypto.SHA3_256)
+	case PreHashSHA3_384:
+		preHasher, preHashError = slhdsa.NewPreHashWithHash(crypto.SHA3_384)
+	case PreHashSHA3_512:
+		preHasher, preHashError = slhdsa.NewPreHashWithHash(crypto.SHA3_512)
+	case PreHashSHAKE128:
+		preHasher, preHashError = slhdsa.NewPreHashWithXof(xof.SHAKE128)
+	case PreHashSHAKE256:
+		preHasher, preHashError = slhdsa.NewPreHashWithXof(xof.SHAKE256)
+	default:
+		return nil, fmt.Errorf("%w: HashSLH-DSA pre-hash function %v", ErrUnknownAlgorithm, preHash)
+	}
+	if preHashError != nil {
+		return nil, fmt.Errorf("HashSLH-DSA %v pre-hash: %w", preHash, preHashError)
+	}
+	if _, writeError := preHasher.Write(messageBytes); writeError != nil {
+		return nil, fmt.Errorf("HashSLH-DSA %v pre-hash: %w", preHash, writeError)
+	}
+	return preHasher.BuildMessage()
+}
+
+// signSLHDSA signs a pure or pre-hashed message with the options' context string and randomness.
+func signSLHDSA(parameterSet SLHDSAParameterSet, privateKeyBytes []byte, message *slhdsa.Message, options *SLHDSASignOptions) ([]byte, error) {
+	privateKey, unmarshalError := unmarshalSLHDSAPrivateKey(parameterSet, privateKeyBytes)
+	if unmarshalError != nil {
+		return nil, unmarshalError
+	}
+	contextBytes := options.context()
+	if len(contextBytes) > SLHDSAMaxContextSize {
+		return nil, fmt.Errorf("%w: got %d bytes, maximum is %d", ErrContextTooLong, len(contextBytes), SLHDSAMaxContextSize)
+	}
+	if options != nil && options.Deterministic {
+		if options.Rand != nil {
+			return nil, fmt.Errorf("%w: SLH-DSA Deterministic and Rand are mutually exclusive", ErrInvalidOptions)
+		}
+		return slhdsa.SignDeterministic(privateKey, message, contextBytes)
+	}
+	randomReader := rand.Reader
+	if options != nil && options.Rand != nil {
+		randomReader = readFullReader{options.Rand}
+	}
+	signatureBytes, signError := slhdsa.SignRandomized(privateKey, randomReader, message, contextBytes)
+	if signError != nil {
+		return nil, fmt.Errorf("%v sign: %w", parameterSet, signError)
+	}
+	return signatureBytes, nil
+}
+
+// verifySLHDSA verifies a pure or pre-hashed message under the context string.
+func verifySLHDSA(parameterSet SLHDSAParameterSet, publicKeyBytes []byte, message *slhdsa.Message, signatureBytes []byte, contextBytes []byte) (bool, error) {
+	publicKey, unmarshalError := unmarshalSLHDSAPublicKey(parameterSet, publicKeyBytes)
+	if unmarshalError != nil {
+		return false, unmarshalError
+	}
+	if len(contextBytes) > SLHDSAMaxContextSize {
+		return false, fmt.Errorf("%w: got %d bytes, maximum is %d", ErrContextTooLong, len(contextBytes), SLHDSAMaxContextSize)
+	}
+	isSignatureValid := slhdsa.Verify(publicKey, message, signatureBytes, contextBytes)
+	Logger().Debug("SLHDSAVerify", "parameter_set", parameterSet.String(), "signature_size", len(signatureBytes), "valid", isSignatureValid)
+	return isSignatureValid, nil
+}
+
+// readFullReader reads the full buffer from a caller-supplied randomness source, so a short read is reported as an error
+// instead of leaving part of opt_rand unset.
+type readFullReader struct {
+	reader io.Reader
+}
+
+func (randomReader readFullReader) Read(buffer []byte) (int, error) {
+	return io.ReadFull(randomReader.reader, buffer)
+}
+
+// SLHDSASign signs a message using an SLH-DSA private key of the given parameter set, with hedged randomness and an empty context string.
+func SLHDSASign(parameterSet SLHDSAParameterSet, privateKeyBytes []byte, messageBytes []byte) (signatureBytes []byte, signError error) {
+	return SLHDSASignWithOptions(parameterSet, privateKeyBytes, messageBytes, nil)
+}
+
+// SLHDSASignWithContext signs a message using an SLH-DSA private key of the given parameter set, with hedged randomness,
+// bound to a context string. The context must be at most SLHDSAMaxContextSize bytes; nil and empty are equivalent.
+func SLHDSASignWithContext(parameterSet SLHDSAParameterSet, privateKeyBytes []byte, messageBytes []byte, contextBytes []byte) (signatureBytes []byte, signError error) {
+	return SLHDSASignWithOptions(parameterSet, privateKeyBytes, messageBytes, &SLHDSASignOptions{Context: contextBytes})
+}
+
+// SLHDSASignWithOptions signs a message using an SLH-DSA private key of the given parameter set (FIPS 205 Algorithm 22).
+// The options select the context string and hedged (the default), deterministic or caller-supplied signing randomness; nil selects the defaults.
+func SLHDSASignWithOptions(parameterSet SLHDSAParameterSet, privateKeyBytes []byte, messageBytes []byte, options *SLHDSASignOptions) (signatureBytes []byte, signError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			signError = newPanicError("SLHDSASignWithOptions", recoveredPanic)
+		}
+	}()
+	signatureBytes, signError = signSLHDSA(parameterSet, privateKeyBytes, slhdsa.NewMessage(messageBytes), options)
+	if signError != nil {
+		return nil, signError
+	}
+	Logger().Debug("SLHDSASign", "parameter_set", parameterSet.String(), "message_size", len(messageBytes), "signature_size", len(signatureBytes))
+	return signatureBytes, nil
+}
+
+// SLHDSAVerify verifies an SLH-DSA signature using a public key of the given parameter set, with an empty context string.
+func SLHDSAVerify(parameterSet SLHDSAParameterSet, publicKeyBytes []byte, messageBytes []byte, signatureBytes []byte) (isSignatureValid bool, verifyError error) {
+	return SLHDSAVerifyWithContext(parameterSet, publicKeyBytes, messageBytes, signatureBytes, nil)
+}
+
+// SLHDSAVerifyWithContext verifies an SLH-DSA signature using a public key of the given parameter set (FIPS 205
+// Algorithm 24), bound to a context string. The signature is only valid if it was made with exactly the same context string.
+func SLHDSAVerifyWithContext(parameterSet SLHDSAParameterSet, publicKeyBytes []byte, messageBytes []byte, signatureBytes []byte, contextBytes []byte) (isSignatureValid bool, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			verifyError = newPanicError("SLHDSAVerifyWithContext", recoveredPanic)
+		}
+	}()
+	return verifySLHDSA(parameterSet, publicKeyBytes, slhdsa.NewMessage(messageBytes), signatureBytes, contextBytes)
+}
+
+// HashSLHDSASign signs a message with HashSLH-DSA (FIPS 205 Algorithm 23), using the hedged variant.
+// The message is first hashed with preHash, and the signature binds the pre-hash function's OID, so it never verifies as a pure SLH-DSA signature.
+func HashSLHDSASign(parameterSet SLHDSAParameterSet, preHash MLDSAPreHash, privateKeyBytes []byte, messageBytes []byte, contextBytes []byte) (signatureBytes []byte, signError error) {
+	return HashSLHDSASignWithOptions(parameterSet, preHash, privateKeyBytes, messageBytes, &SLHDSASignOptions{Context: contextBytes})
+}
+
+// HashSLHDSASignWithOptions is like HashSLHDSASign, with the context string and signing randomness selected by the options.
+func HashSLHDSASignWithOptions(parameterSet SLHDSAParameterSet, preHash MLDSAPreHash, privateKeyBytes []byte, messageBytes []byte, options *SLHDSASignOptions) (signatureBytes []byte, signError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			signError = newPanicError("HashSLHDSASignWithOptions", recoveredPanic)
+		}
+	}()
+	message, preHashError := slhdsaPreHashMessage(preHash, messageBytes)
+	if preHashError != nil {
+		return nil, preHashError
+	}
+	return signSLHDSA(parameterSet, privateKeyBytes, message, options)
+}
+
+// HashSLHDSAVerify verifies a HashSLH-DSA signature (FIPS 205 Algorithm 25) made with the same pre-hash function and context string.
+func HashSLHDSAVerify(parameterSet SLHDSAParameterSet, preHash MLDSAPreHash, publicKeyBytes []byte, messageBytes []byte, signatureBytes []byte, contextBytes []byte) (isSignatureValid bool, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			verifyError = newPanicError("HashSLHDSAVerify", recoveredPanic)
+		}
+	}()
+	message, preHashError := slhdsaPreHashMessage(preHash, messageBytes)
+	if preHashError != nil {
+		return false, preHashError
+	}
+	return verifySLHDSA(parameterSet, publicKeyBytes, message, signatureBytes, contextBytes)
+}
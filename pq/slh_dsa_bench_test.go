@@ -0,0 +1,61 @@
+package pq
+
+import "testing"
+
+// slhdsaBenchmarkParameterSets are the fast parameter sets; the small variants take seconds per signature.
+var slhdsaBenchmarkParameterSets = []SLHDSAParameterSet{SLHDSASHA2_128f, SLHDSASHAKE_128f, SLHDSASHAKE_256f}
+
+func BenchmarkSLHDSAGenerateKeyPair(b *testing.B) {
+	for _, parameterSet := range slhdsaBenchmarkParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			for b.Loop() {
+				_, err := GenerateSLHDSAKeyPair(parameterSet)
+				if err != nil {
+					b.Fatalf("failed to generate SLH-DSA key pair: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSLHDSASign(b *testing.B) {
+	for _, parameterSet := range slhdsaBenchmarkParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			key, err := GenerateSLHDSAKeyPair(parameterSet)
+			if err != nil {
+				b.Fatalf("failed to generate SLH-DSA key pair: %v", err)
+			}
+			message := []byte("benchmark message")
+			b.ResetTimer()
+			for b.Loop() {
+				_, signErr := SLHDSASign(parameterSet, key.PrivateKey, message)
+				if signErr != nil {
+					b.Fatalf("signing failed: %v", signErr)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkSLHDSAVerify(b *testing.B) {
+	for _, parameterSet := range slhdsaBenchmarkParameterSets {
+		b.Run(parameterSet.String(), func(b *testing.B) {
+			key, err := GenerateSLHDSAKeyPair(parameterSet)
+			if err != nil {
+				b.Fatalf("failed to generate SLH-DSA key pair: %v", err)
+			}
+			message := []byte("benchmark message")
+			signature, signErr := SLHDSASign(parameterSet, key.PrivateKey, message)
+			if signErr != nil {
+				b.Fatalf("signing failed: %v", signErr)
+			}
+			b.ResetTimer()
+			for b.Loop() {
+				_, verifyErr := SLHDSAVerify(parameterSet, key.PublicKey, message, signature)
+				if verifyErr != nil {
+					b.Fatalf("verifying failed: %v", verifyErr)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package pq
+
+import "testing"
+
+func FuzzSLHDSASignAndVerifyWithContext(f *testing.F) {
+	key, _ := GenerateSLHDSAKeyPair(SLHDSASHAKE_128f)
+	f.Add([]byte("msg"), []byte("ctx"))
+	f.Fuzz(func(t *testing.T, msg []byte, ctx []byte) {
+		signature, signErr := SLHDSASignWithContext(SLHDSASHAKE_128f, key.PrivateKey, msg, ctx)
+		if len(ctx) > SLHDSAMaxContextSize {
+			if signErr == nil {
+				t.Fatalf("expected error for %d byte context", len(ctx))
+			}
+			return
+		}
+		if signErr != nil {
+			t.Fatalf("signing failed: %v", signErr)
+		}
+		isValid, verifyErr := SLHDSAVerifyWithContext(SLHDSASHAKE_128f, key.PublicKey, msg, signature, ctx)
+		if verifyErr != nil {
+			t.Fatalf("verifying failed: %v", verifyErr)
+		}
+		if !isValid {
+			t.Error("signature should verify for fuzzed input and context")
+		}
+	})
+}
+
+func FuzzSLHDSAVerifyArbitrarySignature(f *testing.F) {
+	key, _ := GenerateSLHDSAKeyPair(SLHDSASHA2_128f)
+	f.Add([]byte("msg"), []byte("sig"))
+	f.Fuzz(func(t *testing.T, msg []byte, signature []byte) {
+		isValid, verifyErr := SLHDSAVerify(SLHDSASHA2_128f, key.PublicKey, msg, signature)
+		if verifyErr != nil {
+			t.Fatalf("verifying must not error on a malformed signature: %v", verifyErr)
+		}
+		if isValid {
+			t.Error("an arbitrary signature must not verify")
+		}
+	})
+}
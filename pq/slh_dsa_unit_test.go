@@ -0,0 +1,238 @@
+package pq
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// slhdsaACVPVectors is the subset of the NIST ACVP SLH-DSA vectors stored in testdata/fips205.
+type slhdsaACVPVectors struct {
+	KeyGen []struct {
+		ParameterSet string `json:"parameterSet"`
+		SKSeed       string `json:"skSeed"`
+		SKPrf        string `json:"skPrf"`
+		PKSeed       string `json:"pkSeed"`
+		SK           string `json:"sk"`
+		PK           string `json:"pk"`
+	} `json:"keyGen"`
+	SigGen []struct {
+		ParameterSet         string `json:"parameterSet"`
+		Deterministic        bool   `json:"deterministic"`
+		PreHash              string `json:"preHash"`
+		SK                   string `json:"sk"`
+		Message              string `json:"message"`
+		Context              string `json:"context"`
+		AdditionalRandomness string `json:"additionalRandomness"`
+		Signature            string `json:"signature"`
+	} `json:"sigGen"`
+}
+
+// slhdsaParameterSetByName returns the parameter set with the given FIPS 205 name.
+func slhdsaParameterSetByName(t *testing.T, name string) SLHDSAParameterSet {
+	t.Helper()
+	for _, parameterSet := range SLHDSAParameterSets {
+		if parameterSet.String() == name {
+			return parameterSet
+		}
+	}
+	t.Fatalf("unknown SLH-DSA parameter set %q", name)
+	return 0
+}
+
+// mustDecodeHex decodes a hexadecimal test vector field.
+func mustDecodeHex(t *testing.T, field string) []byte {
+	t.Helper()
+	decoded, err := hex.DecodeString(field)
+	require.NoError(t, err)
+	return decoded
+}
+
+// readSLHDSAACVPVectors reads testdata/fips205/acvp-subset.json.
+func readSLHDSAACVPVectors(t *testing.T) *slhdsaACVPVectors {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "fips205", "acvp-subset.json"))
+	require.NoError(t, err, "failed to read vectors")
+	var vectors slhdsaACVPVectors
+	require.NoError(t, json.Unmarshal(data, &vectors))
+	return &vectors
+}
+
+// For TestSLHDSAACVPKeyGen: DeriveSLHDSAKeyPair on SK.seed || SK.prf || PK.seed reproduces the ACVP keys of every parameter set.
+func TestSLHDSAACVPKeyGen(t *testing.T) {
+	vectors := readSLHDSAACVPVectors(t)
+	require.Len(t, vectors.KeyGen, len(SLHDSAParameterSets))
+	for _, vector := range vectors.KeyGen {
+		t.Run(vector.ParameterSet, func(t *testing.T) {
+			parameterSet := slhdsaParameterSetByName(t, vector.ParameterSet)
+			seed := mustDecodeHex(t, vector.SKSeed+vector.SKPrf+vector.PKSeed)
+			keyPair, err := DeriveSLHDSAKeyPair(parameterSet, seed)
+			require.NoError(t, err)
+			require.Equal(t, mustDecodeHex(t, vector.PK), keyPair.PublicKey)
+			require.Equal(t, mustDecodeHex(t, vector.SK), keyPair.PrivateKey)
+			require.Equal(t, seed, keyPair.Seed)
+		})
+	}
+}
+
+// For TestSLHDSAACVPSigGen: pure and pre-hashed signatures, deterministic and with given randomness, match the ACVP vectors.
+func TestSLHDSAACVPSigGen(t *testing.T) {
+	preHashes := map[string]MLDSAPreHash{
+		"SHA2-256": PreHashSHA256, "SHA2-384": PreHashSHA384, "SHA2-512": PreHashSHA512,
+		"SHA3-256": PreHashSHA3_256, "SHA3-384": PreHashSHA3_384, "SHA3-512": PreHashSHA3_512,
+		"SHAKE-128": PreHashSHAKE128, "SHAKE-256": PreHashSHAKE256,
+	}
+	vectors := readSLHDSAACVPVectors(t)
+	for _, vector := range vectors.SigGen {
+		t.Run(strings.TrimSuffix(vector.ParameterSet+" "+vector.PreHash, " "), func(t *testing.T) {
+			parameterSet := slhdsaParameterSetByName(t, vector.ParameterSet)
+			privateKey := mustDecodeHex(t, vector.SK)
+			message := mustDecodeHex(t, vector.Message)
+			options := &SLHDSASignOptions{Context: mustDecodeHex(t, vector.Context), Deterministic: vector.Deterministic}
+			if !vector.Deterministic {
+				options.Rand = bytes.NewReader(mustDecodeHex(t, vector.AdditionalRandomness))
+			}
+			var signature []byte
+			var err error
+			if vector.PreHash == "" {
+				signature, err = SLHDSASignWithOptions(parameterSet, privateKey, message, options)
+			} else {
+				signature, err = HashSLHDSASignWithOptions(parameterSet, preHashes[vector.PreHash], privateKey, message, options)
+			}
+			require.NoError(t, err)
+			require.Equal(t, mustDecodeHex(t, vector.Signature), signature)
+
+			publicKey := privateKey[len(privateKey)-parameterSet.PublicKeySize():]
+			var valid bool
+			if vector.PreHash == "" {
+				valid, err = SLHDSAVerifyWithContext(parameterSet, publicKey, message, signature, options.Context)
+			} else {
+				valid, err = HashSLHDSAVerify(parameterSet, preHashes[vector.PreHash], publicKey, message, signature, options.Context)
+			}
+			require.NoError(t, err)
+			require.True(t, valid)
+		})
+	}
+}
+
+// For TestSLHDSASignAndVerify: every parameter set signs and verifies, binds the context string, and rejects a tampered
+// signature or message.
+func TestSLHDSASignAndVerify(t *testing.T) {
+	message := []byte("long-lived root key")
+	for _, parameterSet := range SLHDSAParameterSets {
+		t.Run(parameterSet.String(), func(t *testing.T) {
+			t.Parallel()
+			keyPair, err := GenerateSLHDSAKeyPair(parameterSet)
+			require.NoError(t, err)
+			require.Len(t, keyPair.PublicKey, parameterSet.PublicKeySize())
+			require.Len(t, keyPair.PrivateKey, parameterSet.PrivateKeySize())
+			require.Len(t, keyPair.Seed, parameterSet.SeedSize())
+
+			signature, err := SLHDSASignWithContext(parameterSet, keyPair.PrivateKey, message, []byte("ctx"))
+			require.NoError(t, err)
+			require.Len(t, signature, parameterSet.SignatureSize())
+			valid, err := SLHDSAVerifyWithContext(parameterSet, keyPair.PublicKey, message, signature, []byte("ctx"))
+			require.NoError(t, err)
+			require.True(t, valid)
+			valid, err = SLHDSAVerify(parameterSet, keyPair.PublicKey, message, signature)
+			require.NoError(t, err)
+			require.False(t, valid, "a signature made under a context must not verify without it")
+			valid, err = SLHDSAVerifyWithContext(parameterSet, keyPair.PublicKey, []byte("other"), signature, []byte("ctx"))
+			require.NoError(t, err)
+			require.False(t, valid)
+			signature[len(signature)/2] ^= 0x01
+			valid, err = SLHDSAVerifyWithContext(parameterSet, keyPair.PublicKey, message, signature, []byte("ctx"))
+			require.NoError(t, err)
+			require.False(t, valid)
+		})
+	}
+}
+
+// For TestSLHDSADeterministicAndHedged: deterministic signing is reproducible, hedged signing is not, and derivation is deterministic.
+func TestSLHDSADeterministicAndHedged(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x07}, SLHDSASHAKE_128f.SeedSize())
+	keyPair, err := DeriveSLHDSAKeyPair(SLHDSASHAKE_128f, seed)
+	require.NoError(t, err)
+	again, err := DeriveSLHDSAKeyPair(SLHDSASHAKE_128f, seed)
+	require.NoError(t, err)
+	require.Equal(t, keyPair, again)
+
+	message := []byte("reproducible")
+	first, err := SLHDSASignWithOptions(SLHDSASHAKE_128f, keyPair.PrivateKey, message, &SLHDSASignOptions{Deterministic: true})
+	require.NoError(t, err)
+	second, err := SLHDSASignWithOptions(SLHDSASHAKE_128f, keyPair.PrivateKey, message, &SLHDSASignOptions{Deterministic: true})
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+	hedged, err := SLHDSASign(SLHDSASHAKE_128f, keyPair.PrivateKey, message)
+	require.NoError(t, err)
+	require.NotEqual(t, first, hedged)
+}
+
+// For TestHashSLHDSAIsDomainSeparated: a HashSLH-DSA signature verifies only with the same pre-hash function and never as pure SLH-DSA.
+func TestHashSLHDSAIsDomainSeparated(t *testing.T) {
+	message := []byte("pre-hashed")
+	keyPair, err := GenerateSLHDSAKeyPair(SLHDSASHA2_128f)
+	require.NoError(t, err)
+	for _, preHash := range MLDSAPreHashes {
+		t.Run(preHash.String(), func(t *testing.T) {
+			signature, err := HashSLHDSASign(SLHDSASHA2_128f, preHash, keyPair.PrivateKey, message, []byte("ctx"))
+			require.NoError(t, err)
+			valid, err := HashSLHDSAVerify(SLHDSASHA2_128f, preHash, keyPair.PublicKey, message, signature, []byte("ctx"))
+			require.NoError(t, err)
+			require.True(t, valid)
+			valid, err = SLHDSAVerifyWithContext(SLHDSASHA2_128f, keyPair.PublicKey, message, signature, []byte("ctx"))
+			require.NoError(t, err)
+			require.False(t, valid, "a HashSLH-DSA signature must not verify as pure SLH-DSA")
+			otherPreHash := PreHashSHA256
+			if preHash == PreHashSHA256 {
+				otherPreHash = PreHashSHA512
+			}
+			valid, err = HashSLHDSAVerify(SLHDSASHA2_128f, otherPreHash, keyPair.PublicKey, message, signature, []byte("ctx"))
+			require.NoError(t, err)
+			require.False(t, valid)
+		})
+	}
+}
+
+// For TestSLHDSAInvalidInput: malformed keys, seeds, contexts, options and parameter sets are rejected with typed errors.
+func TestSLHDSAInvalidInput(t *testing.T) {
+	keyPair, err := GenerateSLHDSAKeyPair(SLHDSASHAKE_128f)
+	require.NoError(t, err)
+	message := []byte("message")
+
+	_, err = DeriveSLHDSAKeyPair(SLHDSASHAKE_128f, make([]byte, 10))
+	require.ErrorIs(t, err, ErrInvalidSeedSize)
+	_, err = SLHDSASign(SLHDSASHAKE_128f, keyPair.PrivateKey[1:], message)
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	_, err = SLHDSASign(SLHDSASHA2_192f, keyPair.PrivateKey, message)
+	require.ErrorIs(t, err, ErrInvalidKeySize, "a key of another parameter set must be rejected")
+	_, err = SLHDSAVerify(SLHDSASHAKE_128f, keyPair.PublicKey[1:], message, nil)
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	_, err = SLHDSASignWithContext(SLHDSASHAKE_128f, keyPair.PrivateKey, message, make([]byte, SLHDSAMaxContextSize+1))
+	require.ErrorIs(t, err, ErrContextTooLong)
+	_, err = SLHDSAVerifyWithContext(SLHDSASHAKE_128f, keyPair.PublicKey, message, nil, make([]byte, SLHDSAMaxContextSize+1))
+	require.ErrorIs(t, err, ErrContextTooLong)
+	_, err = SLHDSASignWithOptions(SLHDSASHAKE_128f, keyPair.PrivateKey, message, &SLHDSASignOptions{Deterministic: true, Rand: bytes.NewReader(nil)})
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = SLHDSASignWithOptions(SLHDSASHAKE_128f, keyPair.PrivateKey, message, &SLHDSASignOptions{Rand: bytes.NewReader(make([]byte, 3))})
+	require.Error(t, err, "a short randomness reader must be reported")
+	_, err = HashSLHDSASign(SLHDSASHAKE_128f, MLDSAPreHash(0), keyPair.PrivateKey, message, nil)
+	require.ErrorIs(t, err, ErrUnknownAlgorithm)
+
+	valid, err := SLHDSAVerify(SLHDSASHAKE_128f, keyPair.PublicKey, message, []byte("short"))
+	require.NoError(t, err)
+	require.False(t, valid, "a malformed signature is invalid, not an error")
+
+	_, err = GenerateSLHDSAKeyPair(SLHDSAParameterSet(0))
+	require.Error(t, err)
+	_, err = SLHDSAParameterSet(13).OID()
+	require.Error(t, err)
+	require.Equal(t, "SLHDSAParameterSet(13)", SLHDSAParameterSet(13).String())
+	require.Zero(t, SLHDSAParameterSet(13).SeedSize())
+}
@@ -0,0 +1,249 @@
+package pq
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// SignatureStateStore durably records how many one-time signature indexes of a stateful hash-based signature key
+// (HSS/LMS, XMSS, XMSS^MT) have been handed out. A one-time signature index must never be used twice: the signers
+// reserve every index through the store before the signature using it is computed or released, so a crash can only
+// lose unused indexes, never reuse one.
+//
+// A state counts for exactly one key: the signers bind it to the public key before they reserve any index, and a state
+// that already belongs to another key is rejected with ErrStateMismatch.
+//
+// Implementations must make Reserve atomic across every signer sharing the state and durable before it returns.
+type SignatureStateStore interface {
+	// Bind ties the state to the public key of the key it counts for. It returns ErrStateMismatch if the state already
+	// belongs to another key.
+	Bind(publicKey []byte) error
+	// Reserve advances the stored next unused index by count and returns the first reserved index. It returns
+	// ErrStateExhausted if fewer than count unused indexes below limit are left.
+	Reserve(count uint64, limit uint64) (first uint64, err error)
+}
+
+// stateKeyID identifies the key a state belongs to by the SHA-256 hash of its public key.
+func stateKeyID(publicKey []byte) [sha256.Size]byte {
+	return sha256.Sum256(publicKey)
+}
+
+// reserveStateIndexes returns the new next index after reserving count indexes from next, or ErrStateExhausted.
+func reserveStateIndexes(next uint64, count uint64, limit uint64) (uint64, error) {
+	if count == 0 || next > limit || limit-next < count {
+		return 0, fmt.Errorf("%w: %d of %d one-time signatures used", ErrStateExhausted, next, limit)
+	}
+	return next + count, nil
+}
+
+// MemoryStateStore is a SignatureStateStore kept in memory only. It is safe for concurrent use, but the state is lost
+// with the process, so it is only suitable for tests and keys that are discarded after use. The zero value is a fresh
+// state that is bound to the first key it is used with.
+type MemoryStateStore struct {
+	mu    sync.Mutex
+	next  uint64
+	bound bool
+	keyID [sha256.Size]byte
+}
+
+// Bind ties a fresh store to the public key, and checks that a store already in use belongs to it.
+func (store *MemoryStateStore) Bind(publicKey []byte) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	keyID := stateKeyID(publicKey)
+	if !store.bound {
+		store.bound = true
+		store.keyID = keyID
+		return nil
+	}
+	if keyID != store.keyID {
+		return fmt.Errorf("%w: the in-memory state counts for another public key", ErrStateMismatch)
+	}
+	return nil
+}
+
+// Reserve advances the next unused index by count and returns the first reserved index.
+func (store *MemoryStateStore) Reserve(count uint64, limit uint64) (uint64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	next, err := reserveStateIndexes(store.next, count, limit)
+	if err != nil {
+		return 0, err
+	}
+	first := store.next
+	store.next = next
+	return first, nil
+}
+
+// fileStateSize is the size of a state file: the SHA-256 hash of the public key of the key it counts for, followed by
+// the next unused index as a big-endian uint64.
+const fileStateSize = sha256.Size + 8
+
+// FileStateStore is a SignatureStateStore kept in a file holding the public key hash of its key and the next unused
+// index. Every reservation is written to a temporary file, synced and renamed over the state file before Reserve returns.
+//
+// The state file of a new key is created once with InitFileStateStore. OpenFileStateStore refuses a missing state file
+// instead of starting again from index 0, because a deleted or misplaced file would otherwise reuse every index.
+//
+// OpenFileStateStore takes exclusive ownership of the state by creating path + ".lock", and Close releases it. A lock
+// file left behind by a crash makes OpenFileStateStore fail with ErrStateLocked until an operator has checked that no
+// other signer uses the state and removed it; this fails closed instead of risking two signers on one key.
+type FileStateStore struct {
+	mu       sync.Mutex
+	path     string
+	lockPath string
+	keyID    [sha256.Size]byte
+	closed   bool
+}
+
+// InitFileStateStore creates the state file at path for a new key with the given public key, starting at index 0. It
+// fails if the file already exists, so the state of a key in use can never be reset.
+func InitFileStateStore(path string, publicKey []byte) error {
+	stateFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to initialize signature state: %w", err)
+	}
+	keyID := stateKeyID(publicKey)
+	_, writeErr := stateFile.Write(binary.BigEndian.AppendUint64(keyID[:], 0))
+	if writeErr == nil {
+		writeErr = stateFile.Sync()
+	}
+	if closeErr := stateFile.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("failed to initialize signature state: %w", writeErr)
+	}
+	return syncDirectory(path)
+}
+
+// OpenFileStateStore opens the state file at path, created by InitFileStateStore, and locks it for this signer. A
+// missing state file is an error matching fs.ErrNotExist.
+func OpenFileStateStore(path string) (*FileStateStore, error) {
+	lockPath := path + ".lock"
+	lockFile, err := os.OpenFile(lockPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		return nil, fmt.Errorf("%w: %s exists", ErrStateLocked, lockPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock signature state: %w", err)
+	}
+	if closeErr := lockFile.Close(); closeErr != nil {
+		_ = os.Remove(lockPath)
+		return nil, fmt.Errorf("failed to lock signature state: %w", closeErr)
+	}
+	store := &FileStateStore{path: path, lockPath: lockPath}
+	keyID, _, loadErr := store.load()
+	if loadErr != nil {
+		_ = os.Remove(lockPath)
+		return nil, loadErr
+	}
+	store.keyID = keyID
+	return store, nil
+}
+
+// Bind checks that the state file was initialized for the public key.
+func (store *FileStateStore) Bind(publicKey []byte) error {
+	if stateKeyID(publicKey) != store.keyID {
+		return fmt.Errorf("%w: %s counts for another public key", ErrStateMismatch, store.path)
+	}
+	return nil
+}
+
+// Reserve durably advances the next unused index by count and returns the first reserved index.
+func (store *FileStateStore) Reserve(count uint64, limit uint64) (uint64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.closed {
+		return 0, fmt.Errorf("signature state %s is closed", store.path)
+	}
+	keyID, first, err := store.load()
+	if err != nil {
+		return 0, err
+	}
+	if keyID != store.keyID {
+		return 0, fmt.Errorf("%w: %s was replaced by the state of another key", ErrStateMismatch, store.path)
+	}
+	next, err := reserveStateIndexes(first, count, limit)
+	if err != nil {
+		return 0, err
+	}
+	if err := store.save(next); err != nil {
+		return 0, err
+	}
+	return first, nil
+}
+
+// Close releases the lock on the state file. The store cannot reserve indexes afterwards.
+func (store *FileStateStore) Close() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.closed {
+		return nil
+	}
+	store.closed = true
+	return os.Remove(store.lockPath)
+}
+
+// load reads the public key hash and the next unused index. A missing or corrupt state file is an error.
+func (store *FileStateStore) load() ([sha256.Size]byte, uint64, error) {
+	var keyID [sha256.Size]byte
+	stateBytes, err := os.ReadFile(store.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return keyID, 0, fmt.Errorf("signature state %s does not exist; create it with InitFileStateStore for a new key: %w", store.path, err)
+	}
+	if err != nil {
+		return keyID, 0, fmt.Errorf("failed to read signature state: %w", err)
+	}
+	if len(stateBytes) != fileStateSize {
+		return keyID, 0, fmt.Errorf("corrupt signature state %s: got %d bytes, want %d", store.path, len(stateBytes), fileStateSize)
+	}
+	copy(keyID[:], stateBytes)
+	return keyID, binary.BigEndian.Uint64(stateBytes[sha256.Size:]), nil
+}
+
+// save atomically replaces the state file with next and syncs it and its directory to stable storage.
+func (store *FileStateStore) save(next uint64) error {
+	temporaryPath := store.path + ".tmp"
+	temporaryFile, err := os.OpenFile(temporaryPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write signature state: %w", err)
+	}
+	_, writeErr := temporaryFile.Write(binary.BigEndian.AppendUint64(store.keyID[:], next))
+	if writeErr == nil {
+		writeErr = temporaryFile.Sync()
+	}
+	if closeErr := temporaryFile.Close(); writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		return fmt.Errorf("failed to write signature state: %w", writeErr)
+	}
+	if err := os.Rename(temporaryPath, store.path); err != nil {
+		return fmt.Errorf("failed to write signature state: %w", err)
+	}
+	return syncDirectory(store.path)
+}
+
+// syncDirectory syncs the directory holding path, so that a created or renamed state file survives a crash.
+func syncDirectory(path string) error {
+	directory, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return fmt.Errorf("failed to sync signature state: %w", err)
+	}
+	syncErr := directory.Sync()
+	if closeErr := directory.Close(); syncErr == nil {
+		syncErr = closeErr
+	}
+	if syncErr != nil {
+		return fmt.Errorf("failed to sync signature state: %w", syncErr)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package pq
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// For TestMemoryStateStoreReserve: reservations are consecutive and stop with ErrStateExhausted at the limit.
+func TestMemoryStateStoreReserve(t *testing.T) {
+	store := &MemoryStateStore{}
+	first, err := store.Reserve(1, 4)
+	require.NoError(t, err)
+	require.Zero(t, first)
+	first, err = store.Reserve(2, 4)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), first)
+	_, err = store.Reserve(2, 4)
+	require.ErrorIs(t, err, ErrStateExhausted)
+	first, err = store.Reserve(1, 4)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), first)
+	_, err = store.Reserve(1, 4)
+	require.ErrorIs(t, err, ErrStateExhausted)
+	_, err = (&MemoryStateStore{}).Reserve(0, 4)
+	require.ErrorIs(t, err, ErrStateExhausted, "an empty reservation must be rejected")
+}
+
+// For TestMemoryStateStoreBind: a memory store is bound to the first key and rejects every other key.
+func TestMemoryStateStoreBind(t *testing.T) {
+	store := &MemoryStateStore{}
+	require.NoError(t, store.Bind([]byte("first key")))
+	require.NoError(t, store.Bind([]byte("first key")))
+	require.ErrorIs(t, store.Bind([]byte("second key")), ErrStateMismatch)
+}
+
+// For TestFileStateStorePersistsAndLocks: reservations survive closing and reopening, a second open of the same state
+// fails with ErrStateLocked, and a corrupt state file fails closed.
+func TestFileStateStorePersistsAndLocks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.state")
+	require.NoError(t, InitFileStateStore(path, []byte("public key")))
+	store, err := OpenFileStateStore(path)
+	require.NoError(t, err)
+	first, err := store.Reserve(3, 100)
+	require.NoError(t, err)
+	require.Zero(t, first)
+
+	_, err = OpenFileStateStore(path)
+	require.ErrorIs(t, err, ErrStateLocked)
+	require.NoError(t, store.Close())
+	_, err = store.Reserve(1, 100)
+	require.Error(t, err, "a closed store must not reserve")
+
+	reopened, err := OpenFileStateStore(path)
+	require.NoError(t, err)
+	first, err = reopened.Reserve(1, 100)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), first, "a reopened store must continue after the last reservation")
+	require.NoError(t, reopened.Close())
+
+	require.NoError(t, os.WriteFile(path, []byte("corrupt"), 0o600))
+	_, err = OpenFileStateStore(path)
+	require.Error(t, err)
+	_, statErr := os.Stat(path + ".lock")
+	require.ErrorIs(t, statErr, os.ErrNotExist, "a failed open must release the lock")
+}
+
+// For TestFileStateStoreInitAndBind: a missing state file is an error instead of a fresh state, an existing state can
+// not be initialized again, and the state is bound to the public key it was initialized with.
+func TestFileStateStoreInitAndBind(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.state")
+	_, err := OpenFileStateStore(path)
+	require.ErrorIs(t, err, fs.ErrNotExist, "a missing state file must not start again from index 0")
+	_, statErr := os.Stat(path + ".lock")
+	require.ErrorIs(t, statErr, fs.ErrNotExist, "a failed open must release the lock")
+
+	require.NoError(t, InitFileStateStore(path, []byte("first key")))
+	require.ErrorIs(t, InitFileStateStore(path, []byte("first key")), fs.ErrExist, "an existing state must not be reset")
+	store, err := OpenFileStateStore(path)
+	require.NoError(t, err)
+	require.NoError(t, store.Bind([]byte("first key")))
+	require.ErrorIs(t, store.Bind([]byte("second key")), ErrStateMismatch)
+	_, err = store.Reserve(2, 10)
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	otherPath := filepath.Join(t.TempDir(), "other.state")
+	require.NoError(t, InitFileStateStore(otherPath, []byte("second key")))
+	store, err = OpenFileStateStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	otherState, err := os.ReadFile(otherPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, otherState, 0o600))
+	_, err = store.Reserve(1, 10)
+	require.ErrorIs(t, err, ErrStateMismatch, "a state file replaced by the state of another key must be rejected")
+}
+
+// For TestFileStateStoreConcurrentReserve: concurrent reservations never return the same index.
+func TestFileStateStoreConcurrentReserve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.state")
+	require.NoError(t, InitFileStateStore(path, []byte("public key")))
+	store, err := OpenFileStateStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+	const reservations = 32
+	indexes := make(chan uint64, reservations)
+	var waitGroup sync.WaitGroup
+	for range reservations {
+		waitGroup.Go(func() {
+			index, reserveErr := store.Reserve(1, reservations)
+			if reserveErr != nil {
+				t.Error(reserveErr)
+				return
+			}
+			indexes <- index
+		})
+	}
+	waitGroup.Wait()
+	close(indexes)
+	seen := make(map[uint64]bool)
+	for index := range indexes {
+		require.False(t, seen[index], "index %d was reserved twice", index)
+		seen[index] = true
+	}
+	require.Len(t, seen, reservations)
+	_, err = store.Reserve(1, reservations)
+	require.ErrorIs(t, err, ErrStateExhausted)
+}
+
+// For TestStatefulSignerRestartNeverReusesLeaf: a signer that is restarted on the same state file, as after a crash,
+// continues with the next leaf instead of reusing one.
+func TestStatefulSignerRestartNeverReusesLeaf(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hss.state")
+	keyPair, err := GenerateHSSKeyPair([]HSSLevel{{LMSType: LMSSHA256M32H5, LMOTSType: LMOTSSHA256N32W8}})
+	require.NoError(t, err)
+	require.NoError(t, InitFileStateStore(path, keyPair.PublicKey))
+	usedLeaves := make(map[string]bool)
+	signWithFreshSigner := func(count int) {
+		store, openErr := OpenFileStateStore(path)
+		require.NoError(t, openErr)
+		defer store.Close()
+		signer, signerErr := NewHSSSigner(keyPair.PrivateKey, store)
+		require.NoError(t, signerErr)
+		for range count {
+			signature, signErr := signer.Sign([]byte("firmware"))
+			require.NoError(t, signErr)
+			leaf := string(signature[4:8]) // q of the single LMS signature
+			require.False(t, usedLeaves[leaf], "leaf reused after restart")
+			usedLeaves[leaf] = true
+		}
+	}
+	signWithFreshSigner(3)
+	signWithFreshSigner(3)
+	require.Len(t, usedLeaves, 6)
+}
+
+// For TestStatefulSignersRejectForeignState: every signer refuses a store that already counts for another key.
+func TestStatefulSignersRejectForeignState(t *testing.T) {
+	hssKey, err := GenerateHSSKeyPair([]HSSLevel{{LMSType: LMSSHA256M32H5, LMOTSType: LMOTSSHA256N32W8}})
+	require.NoError(t, err)
+	otherHSSKey, err := GenerateHSSKeyPair([]HSSLevel{{LMSType: LMSSHA256M32H5, LMOTSType: LMOTSSHA256N32W8}})
+	require.NoError(t, err)
+	store := &MemoryStateStore{}
+	_, err = NewHSSSigner(hssKey.PrivateKey, store)
+	require.NoError(t, err)
+	_, err = NewHSSSigner(hssKey.PrivateKey, store)
+	require.NoError(t, err, "the same key may open the store again")
+	_, err = NewHSSSigner(otherHSSKey.PrivateKey, store)
+	require.ErrorIs(t, err, ErrStateMismatch)
+
+	path := filepath.Join(t.TempDir(), "hss.state")
+	require.NoError(t, InitFileStateStore(path, hssKey.PublicKey))
+	fileStore, err := OpenFileStateStore(path)
+	require.NoError(t, err)
+	defer fileStore.Close()
+	_, err = NewHSSSigner(otherHSSKey.PrivateKey, fileStore)
+	require.ErrorIs(t, err, ErrStateMismatch)
+
+	xmssKey, err := DeriveXMSSMTKeyPair(XMSSMTSHA2_20_4_256, make([]byte, 96))
+	require.NoError(t, err)
+	_, err = NewXMSSMTSigner(xmssKey.PrivateKey, store)
+	require.ErrorIs(t, err, ErrStateMismatch)
+	_, err = NewXMSSMTSigner(xmssKey.PrivateKey, &MemoryStateStore{})
+	require.NoError(t, err)
+}
@@ -0,0 +1,396 @@
+package pq
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cloudflare/circl/kem"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// A stream encrypts data of any size in constant memory with the STREAM construction of Hoang, Reyhanitabar, Rogaway and
+// Vizár. A fresh random file key is wrapped for every recipient in an envelope, and the data is split into chunks of
+// StreamChunkSize bytes, each sealed with ChaCha20-Poly1305 under the file key. Version 1 is encoded as:
+//
+//	"PQST" || version (1 byte) || envelope size (uint32) || envelope of the file key, with "PQST" || version as AAD
+//	per chunk: ChaCha20-Poly1305(file key, nonce = chunk index (11 bytes) || final flag (1 byte), chunk)
+//
+// Integers are big-endian. Every chunk but the last holds exactly StreamChunkSize bytes, and only the last has the final
+// flag set, so a reordered, dropped or truncated chunk fails authentication. The last chunk is empty only if the whole
+// stream is.
+
+const (
+	// StreamChunkSize is the plaintext size of every chunk of a stream but the last.
+	StreamChunkSize = 64 * 1024
+	// streamMagic starts every stream.
+	streamMagic = "PQST"
+	// streamVersion1 is the only stream version.
+	streamVersion1 = 1
+	// streamPreambleSize is the size of the magic, version and envelope size.
+	streamPreambleSize = len(streamMagic) + 1 + 4
+	// streamMaxEnvelopeSize bounds the envelope size read from an untrusted header.
+	streamMaxEnvelopeSize = 16 << 20
+	// streamEncryptedChunkSize is the size of every encrypted chunk but the last.
+	streamEncryptedChunkSize = StreamChunkSize + chacha20poly1305.Overhead
+)
+
+// errStreamWriterClosed is returned by writes to a closed StreamWriter.
+var errStreamWriterClosed = errors.New("stream writer is closed")
+
+// streamNonce returns the nonce of the chunk with the given index.
+func streamNonce(chunkIndex uint64, final bool) []byte {
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.BigEndian.PutUint64(nonce[3:11], chunkIndex)
+	if final {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// newStreamFormatError returns a *FormatError matching ErrInvalidStream.
+func newStreamFormatError(offset int64, reason string, arguments ...any) error {
+	return &FormatError{Err: ErrInvalidStream, Offset: int(offset), Reason: fmt.Sprintf(reason, arguments...)}
+}
+
+// StreamWriter encrypts a stream. Data is written in chunks as it arrives; Close must be called to write the last one.
+type StreamWriter struct {
+	destination io.Writer
+	aead        cipher.AEAD
+	chunkIndex  uint64
+	plaintext   []byte
+	ciphertext  []byte
+	err         error
+}
+
+// NewStreamWriter writes a stream header for the recipients to destination and returns a writer that encrypts to it.
+// Each recipient is an ML-KEM key with a key ID, as for SealEnvelope.
+func NewStreamWriter(destination io.Writer, recipients []EnvelopeRecipient) (streamWriter *StreamWriter, setupError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			streamWriter, setupError = nil, newPanicError("NewStreamWriter", recoveredPanic)
+		}
+	}()
+	fileKey := make([]byte, chacha20poly1305.KeySize)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, fmt.Errorf("generating stream file key: %w", err)
+	}
+	preamble := append([]byte(streamMagic), streamVersion1)
+	envelope, err := SealEnvelope(recipients, fileKey, preamble)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := chacha20poly1305.New(fileKey)
+	if err != nil {
+		return nil, fmt.Errorf("stream cipher: %w", err)
+	}
+	header := binary.BigEndian.AppendUint32(preamble, uint32(len(envelope)))
+	if _, err := destination.Write(append(header, envelope...)); err != nil {
+		return nil, fmt.Errorf("writing stream header: %w", err)
+	}
+	Logger().Debug("NewStreamWriter", "recipients", len(recipients), "header_size", len(header)+len(envelope))
+	return &StreamWriter{
+		destination: destination,
+		aead:        aead,
+		plaintext:   make([]byte, 0, StreamChunkSize),
+		ciphertext:  make([]byte, 0, streamEncryptedChunkSize),
+	}, nil
+}
+
+// Write encrypts data. A chunk is written once it is full and more data follows, so the last chunk is always held
+// until Close.
+func (w *StreamWriter) Write(data []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	written := 0
+	for len(data) > 0 {
+		if len(w.plaintext) == StreamChunkSize {
+			if err := w.writeChunk(false); err != nil {
+				w.err = err
+				return written, err
+			}
+		}
+		copied := copy(w.plaintext[len(w.plaintext):StreamChunkSize], data)
+		w.plaintext = w.plaintext[:len(w.plaintext)+copied]
+		data = data[copied:]
+		written += copied
+	}
+	return written, nil
+}
+
+// Close writes the last chunk. It does not close the destination.
+func (w *StreamWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.writeChunk(true); err != nil {
+		w.err = err
+		return err
+	}
+	Logger().Debug("StreamWriter.Close", "chunks", w.chunkIndex)
+	w.err = errStreamWriterClosed
+	return nil
+}
+
+// writeChunk seals and writes the buffered plaintext as the next chunk.
+func (w *StreamWriter) writeChunk(final bool) error {
+	w.ciphertext = w.aead.Seal(w.ciphertext[:0], streamNonce(w.chunkIndex, final), w.plaintext, nil)
+	if _, err := w.destination.Write(w.ciphertext); err != nil {
+		return err
+	}
+	w.chunkIndex++
+	w.plaintext = w.plaintext[:0]
+	return nil
+}
+
+// readStreamHeader reads a stream header and returns its envelope and size.
+func readStreamHeader(source io.Reader) ([]byte, int64, error) {
+	preamble := make([]byte, streamPreambleSize)
+	if _, err := io.ReadFull(source, preamble); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, 0, newStreamFormatError(0, "truncated header")
+		}
+		return nil, 0, err
+	}
+	if string(preamble[:len(streamMagic)]) != streamMagic {
+		return nil, 0, newStreamFormatError(0, "missing %q magic", streamMagic)
+	}
+	if preamble[len(streamMagic)] != streamVersion1 {
+		return nil, 0, newStreamFormatError(int64(len(streamMagic)), "unsupported version %d", preamble[len(streamMagic)])
+	}
+	envelopeSize := binary.BigEndian.Uint32(preamble[len(streamMagic)+1:])
+	if envelopeSize == 0 || envelopeSize > streamMaxEnvelopeSize {
+		return nil, 0, newStreamFormatError(int64(len(streamMagic)+1), "envelope size %d out of range", envelopeSize)
+	}
+	envelope := make([]byte, envelopeSize)
+	if _, err := io.ReadFull(source, envelope); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, 0, newStreamFormatError(int64(streamPreambleSize), "truncated envelope")
+		}
+		return nil, 0, err
+	}
+	return envelope, int64(streamPreambleSize) + int64(envelopeSize), nil
+}
+
+// openStreamHeader reads a stream header and returns the AEAD keyed with its file key, and the header size.
+func openStreamHeader(source io.Reader, privateKey kem.PrivateKey, keyID string) (cipher.AEAD, int64, error) {
+	envelope, headerSize, err := readStreamHeader(source)
+	if err != nil {
+		return nil, 0, err
+	}
+	fileKey, err := OpenEnvelope(privateKey, keyID, envelope, append([]byte(streamMagic), streamVersion1))
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(fileKey) != chacha20poly1305.KeySize {
+		return nil, 0, newStreamFormatError(int64(streamPreambleSize), "file key of %d bytes", len(fileKey))
+	}
+	aead, err := chacha20poly1305.New(fileKey)
+	return aead, headerSize, err
+}
+
+// StreamReader decrypts a stream sequentially. It returns data only after its chunk is authenticated, and io.EOF only
+// after the final chunk.
+type StreamReader struct {
+	source     io.Reader
+	aead       cipher.AEAD
+	chunkIndex uint64
+	offset     int64
+	ciphertext []byte
+	plaintext  []byte
+	final      bool
+	err        error
+}
+
+// NewStreamReader reads the stream header from source with the private key of the recipient with the given key ID, and
+// returns a reader of the decrypted data.
+//
+// A malformed header or chunk layout returns a *FormatError matching ErrInvalidStream, and a recipient missing from the
+// header returns ErrRecipientNotFound. A modified, reordered or missing chunk, including a stream truncated at a chunk
+// boundary, returns ErrDecryptionFailed from Read.
+func NewStreamReader(source io.Reader, privateKey kem.PrivateKey, keyID string) (streamReader *StreamReader, setupError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			streamReader, setupError = nil, newPanicError("NewStreamReader", recoveredPanic)
+		}
+	}()
+	aead, headerSize, err := openStreamHeader(source, privateKey, keyID)
+	if err != nil {
+		return nil, err
+	}
+	Logger().Debug("NewStreamReader", "header_size", headerSize)
+	return &StreamReader{
+		source:     source,
+		aead:       aead,
+		offset:     headerSize,
+		ciphertext: make([]byte, streamEncryptedChunkSize),
+	}, nil
+}
+
+// Read decrypts the next data of the stream.
+func (r *StreamReader) Read(data []byte) (int, error) {
+	for len(r.plaintext) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.final {
+			r.err = io.EOF
+			continue
+		}
+		r.err = r.readChunk()
+	}
+	read := copy(data, r.plaintext)
+	r.plaintext = r.plaintext[read:]
+	return read, nil
+}
+
+// readChunk reads and authenticates the next chunk. A full chunk is tried as a non-final chunk first, and as the final
+// chunk only if that fails, in which case the stream must end there.
+func (r *StreamReader) readChunk() error {
+	size, err := io.ReadFull(r.source, r.ciphertext)
+	switch {
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("%w: stream truncated after %d chunks", ErrDecryptionFailed, r.chunkIndex)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		r.final = true
+	case err != nil:
+		return err
+	}
+	if size < chacha20poly1305.Overhead {
+		return newStreamFormatError(r.offset, "truncated chunk %d", r.chunkIndex)
+	}
+	chunk := r.ciphertext[:size]
+	plaintext, err := r.aead.Open(chunk[:0:0], streamNonce(r.chunkIndex, r.final), chunk, nil)
+	if err != nil && !r.final {
+		plaintext, err = r.aead.Open(chunk[:0:0], streamNonce(r.chunkIndex, true), chunk, nil)
+		if err == nil {
+			r.final = true
+			if err := r.checkEnd(size); err != nil {
+				return err
+			}
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%w: chunk %d", ErrDecryptionFailed, r.chunkIndex)
+	}
+	if r.final && len(plaintext) == 0 && r.chunkIndex > 0 {
+		return newStreamFormatError(r.offset, "empty final chunk %d", r.chunkIndex)
+	}
+	r.plaintext = plaintext
+	r.chunkIndex++
+	r.offset += int64(size)
+	return nil
+}
+
+// checkEnd checks that nothing follows a full final chunk.
+func (r *StreamReader) checkEnd(chunkSize int) error {
+	var next [1]byte
+	_, err := io.ReadFull(r.source, next[:])
+	switch {
+	case err == nil:
+		return newStreamFormatError(r.offset+int64(chunkSize), "data after final chunk %d", r.chunkIndex)
+	case errors.Is(err, io.EOF):
+		return nil
+	default:
+		return err
+	}
+}
+
+// StreamReaderAt decrypts any part of a stream stored in an io.ReaderAt, decrypting only the chunks it needs. It is
+// safe for concurrent use if its source is.
+type StreamReaderAt struct {
+	source        io.ReaderAt
+	aead          cipher.AEAD
+	payloadOffset int64
+	payloadSize   int64
+	chunkCount    int64
+	size          int64
+}
+
+// NewStreamReaderAt reads the stream header from the first size bytes of source with the private key of the recipient
+// with the given key ID. It authenticates the final chunk, so that Size is the size of the complete stream, and reports
+// errors as NewStreamReader does.
+func NewStreamReaderAt(source io.ReaderAt, size int64, privateKey kem.PrivateKey, keyID string) (streamReaderAt *StreamReaderAt, setupError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			streamReaderAt, setupError = nil, newPanicError("NewStreamReaderAt", recoveredPanic)
+		}
+	}()
+	aead, headerSize, err := openStreamHeader(io.NewSectionReader(source, 0, size), privateKey, keyID)
+	if err != nil {
+		return nil, err
+	}
+	payloadSize := size - headerSize
+	if payloadSize == 0 {
+		return nil, fmt.Errorf("%w: stream truncated after 0 chunks", ErrDecryptionFailed)
+	}
+	chunkCount := (payloadSize + streamEncryptedChunkSize - 1) / streamEncryptedChunkSize
+	finalChunkSize := payloadSize - (chunkCount-1)*streamEncryptedChunkSize
+	finalChunkOffset := headerSize + (chunkCount-1)*streamEncryptedChunkSize
+	if finalChunkSize < chacha20poly1305.Overhead {
+		return nil, newStreamFormatError(finalChunkOffset, "truncated chunk %d", chunkCount-1)
+	}
+	if finalChunkSize == chacha20poly1305.Overhead && chunkCount > 1 {
+		return nil, newStreamFormatError(finalChunkOffset, "empty final chunk %d", chunkCount-1)
+	}
+	streamReaderAt = &StreamReaderAt{
+		source:        source,
+		aead:          aead,
+		payloadOffset: headerSize,
+		payloadSize:   payloadSize,
+		chunkCount:    chunkCount,
+		size:          payloadSize - chunkCount*chacha20poly1305.Overhead,
+	}
+	if _, err := streamReaderAt.chunk(chunkCount - 1); err != nil {
+		return nil, err
+	}
+	Logger().Debug("NewStreamReaderAt", "header_size", headerSize, "chunks", chunkCount, "size", streamReaderAt.size)
+	return streamReaderAt, nil
+}
+
+// Size returns the size of the decrypted stream.
+func (r *StreamReaderAt) Size() int64 {
+	return r.size
+}
+
+// ReadAt decrypts len(data) bytes of the stream starting at offset, as io.ReaderAt.
+func (r *StreamReaderAt) ReadAt(data []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, fmt.Errorf("%w: negative offset %d", ErrInvalidOptions, offset)
+	}
+	read := 0
+	for read < len(data) && offset < r.size {
+		plaintext, err := r.chunk(offset / StreamChunkSize)
+		if err != nil {
+			return read, err
+		}
+		copied := copy(data[read:], plaintext[offset%StreamChunkSize:])
+		read += copied
+		offset += int64(copied)
+	}
+	if read < len(data) {
+		return read, io.EOF
+	}
+	return read, nil
+}
+
+// chunk reads and authenticates the chunk with the given index.
+func (r *StreamReaderAt) chunk(chunkIndex int64) ([]byte, error) {
+	chunkOffset := chunkIndex * streamEncryptedChunkSize
+	ciphertext := make([]byte, min(streamEncryptedChunkSize, r.payloadSize-chunkOffset))
+	if read, err := r.source.ReadAt(ciphertext, r.payloadOffset+chunkOffset); read < len(ciphertext) {
+		if err == nil || errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	plaintext, err := r.aead.Open(ciphertext[:0], streamNonce(uint64(chunkIndex), chunkIndex == r.chunkCount-1), ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: chunk %d", ErrDecryptionFailed, chunkIndex)
+	}
+	return plaintext, nil
+}
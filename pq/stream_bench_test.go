@@ -0,0 +1,85 @@
+package pq
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func BenchmarkStreamWriter(b *testing.B) {
+	keyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	if err != nil {
+		b.Fatalf("failed to generate ML-KEM key pair: %v", err)
+	}
+	recipients := []EnvelopeRecipient{{KeyID: "key", PublicKey: keyPair.PublicKey}}
+	plaintext := make([]byte, 1<<20)
+	b.SetBytes(int64(len(plaintext)))
+	for b.Loop() {
+		streamWriter, err := NewStreamWriter(io.Discard, recipients)
+		if err != nil {
+			b.Fatalf("failed to create stream writer: %v", err)
+		}
+		if _, err := streamWriter.Write(plaintext); err != nil {
+			b.Fatalf("failed to write: %v", err)
+		}
+		if err := streamWriter.Close(); err != nil {
+			b.Fatalf("failed to close: %v", err)
+		}
+	}
+}
+
+func BenchmarkStreamReader(b *testing.B) {
+	keyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	if err != nil {
+		b.Fatalf("failed to generate ML-KEM key pair: %v", err)
+	}
+	var encrypted bytes.Buffer
+	streamWriter, err := NewStreamWriter(&encrypted, []EnvelopeRecipient{{KeyID: "key", PublicKey: keyPair.PublicKey}})
+	if err != nil {
+		b.Fatalf("failed to create stream writer: %v", err)
+	}
+	if _, err := streamWriter.Write(make([]byte, 1<<20)); err != nil {
+		b.Fatalf("failed to write: %v", err)
+	}
+	if err := streamWriter.Close(); err != nil {
+		b.Fatalf("failed to close: %v", err)
+	}
+	b.SetBytes(1 << 20)
+	for b.Loop() {
+		streamReader, err := NewStreamReader(bytes.NewReader(encrypted.Bytes()), keyPair.PrivateKey, "key")
+		if err != nil {
+			b.Fatalf("failed to create stream reader: %v", err)
+		}
+		if _, err := io.Copy(io.Discard, streamReader); err != nil {
+			b.Fatalf("failed to read: %v", err)
+		}
+	}
+}
+
+func BenchmarkStreamReaderAt(b *testing.B) {
+	keyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	if err != nil {
+		b.Fatalf("failed to generate ML-KEM key pair: %v", err)
+	}
+	var encrypted bytes.Buffer
+	streamWriter, err := NewStreamWriter(&encrypted, []EnvelopeRecipient{{KeyID: "key", PublicKey: keyPair.PublicKey}})
+	if err != nil {
+		b.Fatalf("failed to create stream writer: %v", err)
+	}
+	if _, err := streamWriter.Write(make([]byte, 1<<20)); err != nil {
+		b.Fatalf("failed to write: %v", err)
+	}
+	if err := streamWriter.Close(); err != nil {
+		b.Fatalf("failed to close: %v", err)
+	}
+	streamReaderAt, err := NewStreamReaderAt(bytes.NewReader(encrypted.Bytes()), int64(encrypted.Len()), keyPair.PrivateKey, "key")
+	if err != nil {
+		b.Fatalf("failed to create stream reader: %v", err)
+	}
+	data := make([]byte, 4096)
+	for b.Loop() {
+		if _, err := streamReaderAt.ReadAt(data, 500_000); err != nil {
+			b.Fatalf("failed to read: %v", err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package pq
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func FuzzStreamReader(f *testing.F) {
+	keyPair, _ := GenerateMLKEMKeyPair(MLKEM768)
+	var encrypted bytes.Buffer
+	streamWriter, _ := NewStreamWriter(&encrypted, []EnvelopeRecipient{{KeyID: "fuzz", PublicKey: keyPair.PublicKey}})
+	_, _ = streamWriter.Write([]byte("seed message"))
+	_ = streamWriter.Close()
+	f.Add(encrypted.Bytes())
+	f.Add(encrypted.Bytes()[:encrypted.Len()-1])
+	f.Add([]byte(streamMagic))
+	f.Fuzz(func(t *testing.T, encrypted []byte) {
+		streamReader, err := NewStreamReader(bytes.NewReader(encrypted), keyPair.PrivateKey, "fuzz")
+		if err != nil {
+			return
+		}
+		plaintext, err := io.ReadAll(streamReader)
+		if err == nil && string(plaintext) != "seed message" {
+			t.Errorf("decrypted a forged stream: %x", plaintext)
+		}
+		streamReaderAt, err := NewStreamReaderAt(bytes.NewReader(encrypted), int64(len(encrypted)), keyPair.PrivateKey, "fuzz")
+		if err != nil {
+			return
+		}
+		plaintext, err = io.ReadAll(io.NewSectionReader(streamReaderAt, 0, streamReaderAt.Size()))
+		if err == nil && string(plaintext) != "seed message" {
+			t.Errorf("random access decrypted a forged stream: %x", plaintext)
+		}
+	})
+}
+
+func FuzzStreamRoundTrip(f *testing.F) {
+	keyPair, _ := GenerateMLKEMKeyPair(MLKEM512)
+	recipients := []EnvelopeRecipient{{KeyID: "fuzz", PublicKey: keyPair.PublicKey}}
+	f.Add([]byte("message"), uint16(3))
+	f.Add([]byte{}, uint16(0))
+	f.Fuzz(func(t *testing.T, message []byte, repeat uint16) {
+		plaintext := bytes.Repeat(message, int(repeat)%(4*StreamChunkSize/(len(message)+1)+1))
+		var encrypted bytes.Buffer
+		streamWriter, err := NewStreamWriter(&encrypted, recipients)
+		if err != nil {
+			t.Fatalf("failed to create stream writer: %v", err)
+		}
+		if _, err := streamWriter.Write(plaintext); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+		if err := streamWriter.Close(); err != nil {
+			t.Fatalf("failed to close: %v", err)
+		}
+		streamReader, err := NewStreamReader(bytes.NewReader(encrypted.Bytes()), keyPair.PrivateKey, "fuzz")
+		if err != nil {
+			t.Fatalf("failed to create stream reader: %v", err)
+		}
+		decrypted, err := io.ReadAll(streamReader)
+		if err != nil || !bytes.Equal(plaintext, decrypted) {
+			t.Errorf("round trip of %d bytes failed: %v", len(plaintext), err)
+		}
+	})
+}
@@ -0,0 +1,274 @@
+package pq
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+// For the stream tests: plaintext sizes around the chunk boundaries.
+var streamTestSizes = []int{0, 1, StreamChunkSize - 1, StreamChunkSize, StreamChunkSize + 1, 2 * StreamChunkSize, 3*StreamChunkSize + 100}
+
+// encryptTestStream encrypts plaintext for the recipients, writing it in pieces of 1000 bytes.
+func encryptTestStream(t *testing.T, recipients []EnvelopeRecipient, plaintext []byte) []byte {
+	t.Helper()
+	var encrypted bytes.Buffer
+	streamWriter, err := NewStreamWriter(&encrypted, recipients)
+	require.NoError(t, err)
+	for remaining := plaintext; len(remaining) > 0; {
+		piece := remaining[:min(1000, len(remaining))]
+		written, err := streamWriter.Write(piece)
+		require.NoError(t, err)
+		require.Equal(t, len(piece), written)
+		remaining = remaining[len(piece):]
+	}
+	require.NoError(t, streamWriter.Close())
+	return encrypted.Bytes()
+}
+
+// decryptTestStream decrypts a stream with io.ReadAll.
+func decryptTestStream(recipient envelopeTestRecipient, encrypted []byte) ([]byte, error) {
+	streamReader, err := NewStreamReader(bytes.NewReader(encrypted), recipient.keyPair.PrivateKey, recipient.keyID)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(streamReader)
+}
+
+// streamTestHeaderSize returns the header size of an encrypted stream.
+func streamTestHeaderSize(encrypted []byte) int {
+	return streamPreambleSize + int(binary.BigEndian.Uint32(encrypted[len(streamMagic)+1:]))
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	testRecipients, recipients := newEnvelopeTestRecipients(t)
+	for _, size := range streamTestSizes {
+		plaintext := make([]byte, size)
+		_, err := rand.Read(plaintext)
+		require.NoError(t, err)
+		encrypted := encryptTestStream(t, recipients, plaintext)
+
+		chunkCount := max(1, (size+StreamChunkSize-1)/StreamChunkSize)
+		require.Equal(t, streamTestHeaderSize(encrypted)+size+chunkCount*16, len(encrypted), "size %d", size)
+
+		for _, recipient := range testRecipients {
+			decrypted, err := decryptTestStream(recipient, encrypted)
+			require.NoError(t, err, "size %d, %s", size, recipient.keyID)
+			require.True(t, bytes.Equal(plaintext, decrypted), "size %d, %s", size, recipient.keyID)
+		}
+	}
+}
+
+// For TestStreamReaderSmallReads: the reader works with readers that return one byte at a time, and with reads smaller
+// than a chunk.
+func TestStreamReaderSmallReads(t *testing.T) {
+	testRecipients, recipients := newEnvelopeTestRecipients(t)
+	plaintext := make([]byte, 2*StreamChunkSize+7)
+	_, err := rand.Read(plaintext)
+	require.NoError(t, err)
+	encrypted := encryptTestStream(t, recipients[:1], plaintext)
+
+	streamReader, err := NewStreamReader(iotest.OneByteReader(bytes.NewReader(encrypted)), testRecipients[0].keyPair.PrivateKey, testRecipients[0].keyID)
+	require.NoError(t, err)
+	require.NoError(t, iotest.TestReader(streamReader, plaintext))
+}
+
+func TestStreamWriterClose(t *testing.T) {
+	_, recipients := newEnvelopeTestRecipients(t)
+	streamWriter, err := NewStreamWriter(io.Discard, recipients[:1])
+	require.NoError(t, err)
+	require.NoError(t, streamWriter.Close())
+	_, err = streamWriter.Write([]byte("late"))
+	require.Error(t, err)
+	require.Error(t, streamWriter.Close())
+
+	_, err = NewStreamWriter(io.Discard, nil)
+	require.ErrorIs(t, err, ErrInvalidOptions)
+
+	writeErr := errors.New("disk full")
+	_, err = NewStreamWriter(failingWriter{writeErr}, recipients[:1])
+	require.ErrorIs(t, err, writeErr)
+	require.ErrorContains(t, err, "stream header")
+}
+
+// failingWriter always fails, like a full disk.
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write([]byte) (int, error) { return 0, w.err }
+
+// For TestStreamChunkTampering: a flipped bit in any chunk, and swapped, dropped or duplicated chunks, fail to decrypt,
+// whether read sequentially or with random access.
+func TestStreamChunkTampering(t *testing.T) {
+	testRecipients, recipients := newEnvelopeTestRecipients(t)
+	recipient := testRecipients[1]
+	plaintext := make([]byte, 3*StreamChunkSize+100)
+	encrypted := encryptTestStream(t, recipients, plaintext)
+	headerSize := streamTestHeaderSize(encrypted)
+	chunk := func(index int) []byte {
+		start := headerSize + index*streamEncryptedChunkSize
+		return encrypted[start:min(start+streamEncryptedChunkSize, len(encrypted))]
+	}
+	concatenate := func(parts ...[]byte) []byte {
+		return bytes.Join(append([][]byte{encrypted[:headerSize]}, parts...), nil)
+	}
+
+	testCases := []struct {
+		name      string
+		encrypted []byte
+	}{
+		{"swapped chunks", concatenate(chunk(1), chunk(0), chunk(2), chunk(3))},
+		{"dropped chunk", concatenate(chunk(0), chunk(2), chunk(3))},
+		{"duplicated chunk", concatenate(chunk(0), chunk(0), chunk(1), chunk(2), chunk(3))},
+		{"dropped final chunk", concatenate(chunk(0), chunk(1), chunk(2))},
+		{"final chunk only", concatenate(chunk(3))},
+		{"no chunks", concatenate()},
+	}
+	for _, offset := range []int{headerSize, headerSize + StreamChunkSize, headerSize + streamEncryptedChunkSize + 5, len(encrypted) - 1} {
+		tampered := append([]byte(nil), encrypted...)
+		tampered[offset] ^= 0x01
+		testCases = append(testCases, struct {
+			name      string
+			encrypted []byte
+		}{"flipped bit", tampered})
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			decrypted, err := decryptTestStream(recipient, testCase.encrypted)
+			require.ErrorIs(t, err, ErrDecryptionFailed)
+			require.LessOrEqual(t, len(decrypted), len(plaintext))
+			streamReaderAt, err := NewStreamReaderAt(bytes.NewReader(testCase.encrypted), int64(len(testCase.encrypted)), recipient.keyPair.PrivateKey, recipient.keyID)
+			if err == nil {
+				_, err = io.ReadAll(io.NewSectionReader(streamReaderAt, 0, streamReaderAt.Size()))
+			}
+			require.ErrorIs(t, err, ErrDecryptionFailed)
+		})
+	}
+}
+
+// For TestStreamTruncation: a stream truncated anywhere after its header fails with ErrDecryptionFailed or, within
+// a chunk's tag, a *FormatError; it never decrypts successfully.
+func TestStreamTruncation(t *testing.T) {
+	testRecipients, recipients := newEnvelopeTestRecipients(t)
+	recipient := testRecipients[0]
+	plaintext := make([]byte, 2*StreamChunkSize)
+	encrypted := encryptTestStream(t, recipients[:1], plaintext)
+	headerSize := streamTestHeaderSize(encrypted)
+
+	for _, size := range []int{headerSize, headerSize + 1, headerSize + 15, headerSize + 16, headerSize + StreamChunkSize, headerSize + streamEncryptedChunkSize, headerSize + streamEncryptedChunkSize + 15, len(encrypted) - 1} {
+		_, err := decryptTestStream(recipient, encrypted[:size])
+		require.Error(t, err, "size %d", size)
+		if !errors.Is(err, ErrDecryptionFailed) {
+			var formatError *FormatError
+			require.ErrorAs(t, err, &formatError, "size %d: %v", size, err)
+			require.ErrorIs(t, err, ErrInvalidStream)
+		}
+		_, err = NewStreamReaderAt(bytes.NewReader(encrypted[:size]), int64(size), recipient.keyPair.PrivateKey, recipient.keyID)
+		require.Error(t, err, "size %d", size)
+	}
+	for size := range headerSize {
+		_, err := decryptTestStream(recipient, encrypted[:size])
+		require.Error(t, err, "size %d", size)
+	}
+
+	_, err := decryptTestStream(recipient, append(append([]byte(nil), encrypted...), 0))
+	require.Error(t, err)
+}
+
+func TestStreamHeaderErrors(t *testing.T) {
+	testRecipients, recipients := newEnvelopeTestRecipients(t)
+	recipient := testRecipients[0]
+	encrypted := encryptTestStream(t, recipients[:2], []byte("message"))
+
+	testCases := []struct {
+		name     string
+		modify   func(encrypted []byte)
+		sentinel error
+		offset   int
+	}{
+		{"magic", func(encrypted []byte) { encrypted[0] = 'X' }, ErrInvalidStream, 0},
+		{"version", func(encrypted []byte) { encrypted[4] = 2 }, ErrInvalidStream, 4},
+		{"empty envelope", func(encrypted []byte) { binary.BigEndian.PutUint32(encrypted[5:], 0) }, ErrInvalidStream, 5},
+		{"huge envelope", func(encrypted []byte) { binary.BigEndian.PutUint32(encrypted[5:], 1<<31) }, ErrInvalidStream, 5},
+		{"long envelope", func(encrypted []byte) { binary.BigEndian.PutUint32(encrypted[5:], uint32(len(encrypted))) }, ErrInvalidStream, 9},
+		{"envelope magic", func(encrypted []byte) { encrypted[streamPreambleSize] = 'X' }, ErrInvalidEnvelope, 0},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			modified := append([]byte(nil), encrypted...)
+			testCase.modify(modified)
+			_, err := decryptTestStream(recipient, modified)
+			var formatError *FormatError
+			require.ErrorAs(t, err, &formatError)
+			require.ErrorIs(t, err, testCase.sentinel)
+			require.Equal(t, testCase.offset, formatError.Offset)
+		})
+	}
+
+	_, err := decryptTestStream(envelopeTestRecipient{keyID: "unknown", keyPair: recipient.keyPair}, encrypted)
+	require.ErrorIs(t, err, ErrRecipientNotFound)
+	_, err = decryptTestStream(envelopeTestRecipient{keyID: recipient.keyID, keyPair: testRecipients[2].keyPair}, encrypted)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+}
+
+func TestStreamReaderAt(t *testing.T) {
+	testRecipients, recipients := newEnvelopeTestRecipients(t)
+	recipient := testRecipients[2]
+	for _, size := range streamTestSizes {
+		plaintext := make([]byte, size)
+		_, err := rand.Read(plaintext)
+		require.NoError(t, err)
+		encrypted := encryptTestStream(t, recipients, plaintext)
+
+		streamReaderAt, err := NewStreamReaderAt(bytes.NewReader(encrypted), int64(len(encrypted)), recipient.keyPair.PrivateKey, recipient.keyID)
+		require.NoError(t, err, "size %d", size)
+		require.Equal(t, int64(size), streamReaderAt.Size())
+
+		for _, offset := range []int{0, 1, StreamChunkSize - 1, StreamChunkSize, 2*StreamChunkSize + 50, size - 1, size} {
+			if offset < 0 || offset > size {
+				continue
+			}
+			data := make([]byte, 100)
+			read, err := streamReaderAt.ReadAt(data, int64(offset))
+			expected := plaintext[offset:min(offset+100, size)]
+			require.Equal(t, len(expected), read, "size %d, offset %d", size, offset)
+			require.Equal(t, expected, data[:read])
+			if read < len(data) {
+				require.ErrorIs(t, err, io.EOF)
+			} else {
+				require.NoError(t, err)
+			}
+		}
+
+		decrypted, err := io.ReadAll(io.NewSectionReader(streamReaderAt, 0, streamReaderAt.Size()))
+		require.NoError(t, err)
+		require.True(t, bytes.Equal(plaintext, decrypted), "size %d", size)
+	}
+}
+
+func TestStreamReaderAtChunkTampering(t *testing.T) {
+	testRecipients, recipients := newEnvelopeTestRecipients(t)
+	recipient := testRecipients[0]
+	encrypted := encryptTestStream(t, recipients[:1], make([]byte, 3*StreamChunkSize))
+	headerSize := streamTestHeaderSize(encrypted)
+	encrypted[headerSize+streamEncryptedChunkSize+10] ^= 0x01
+
+	streamReaderAt, err := NewStreamReaderAt(bytes.NewReader(encrypted), int64(len(encrypted)), recipient.keyPair.PrivateKey, recipient.keyID)
+	require.NoError(t, err)
+	data := make([]byte, 10)
+	_, err = streamReaderAt.ReadAt(data, 0)
+	require.NoError(t, err)
+	_, err = streamReaderAt.ReadAt(data, 2*StreamChunkSize)
+	require.NoError(t, err)
+	_, err = streamReaderAt.ReadAt(data, StreamChunkSize+20)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+	_, err = streamReaderAt.ReadAt(data, StreamChunkSize-5)
+	require.ErrorIs(t, err, ErrDecryptionFailed)
+	_, err = streamReaderAt.ReadAt(data, -1)
+	require.ErrorIs(t, err, ErrInvalidOptions)
+}
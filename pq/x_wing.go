@@ -0,0 +1,123 @@
+package pq
+
+import (
+	"fmt"
+
+	"github.com/cloudflare/circl/kem"
+	"github.com/cloudflare/circl/kem/xwing"
+)
+
+const (
+	// XWingSeedSize is the size in bytes of the seed used for deterministic X-Wing key generation.
+	// The seed is the X-Wing private key itself.
+	XWingSeedSize = xwing.SeedSize
+	// XWingEncapsulationSeedSize is the size in bytes of the seed used for deterministic X-Wing encapsulation:
+	// the ML-KEM-768 message m followed by the ephemeral X25519 private key.
+	XWingEncapsulationSeedSize = xwing.EncapsulationSeedSize
+	// XWingSharedSecretSize is the size in bytes of the X-Wing shared secret.
+	XWingSharedSecretSize = xwing.SharedKeySize
+	// XWingCiphertextSize is the size in bytes of an X-Wing ciphertext (ML-KEM-768 ciphertext || X25519 ephemeral public key).
+	XWingCiphertextSize = xwing.CiphertextSize
+)
+
+// XWingKeyPair represents an X-Wing key pair.
+// X-Wing (draft-connolly-cfrg-xwing-kem) is a hybrid KEM combining X25519 and ML-KEM-768; its shared secret stays secure
+// as long as either component is unbroken.
+type XWingKeyPair struct {
+	PublicKey  kem.PublicKey
+	PrivateKey kem.PrivateKey
+}
+
+// GenerateXWingKeyPair generates a new X-Wing key pair.
+func GenerateXWingKeyPair() (keyPair *XWingKeyPair, keyGenerationError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			keyGenerationError = newPanicError("GenerateXWingKeyPair", recoveredPanic)
+		}
+	}()
+	publicKey, privateKey, keyGenerationError := xwing.Scheme().GenerateKeyPair()
+	if keyGenerationError != nil {
+		return nil, fmt.Errorf("X-Wing GenerateKeyPair: %w", keyGenerationError)
+	}
+	return &XWingKeyPair{PublicKey: publicKey, PrivateKey: privateKey}, nil
+}
+
+// GenerateDeterministicXWingKeyPair generates an X-Wing key pair from a seed of length XWingSeedSize (for KATs).
+func GenerateDeterministicXWingKeyPair(seed []byte) (keyPair *XWingKeyPair, keyGenerationError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			keyGenerationError = newPanicError("GenerateDeterministicXWingKeyPair", recoveredPanic)
+		}
+	}()
+	if len(seed) != XWingSeedSize {
+		return nil, newSizeError(ErrInvalidSeedSize, "X-Wing seed", len(seed), XWingSeedSize)
+	}
+	publicKey, privateKey := xwing.Scheme().DeriveKeyPair(seed)
+	return &XWingKeyPair{PublicKey: publicKey, PrivateKey: privateKey}, nil
+}
+
+// UnmarshalXWingPublicKey deserializes bytes into an X-Wing public key. Use MarshalPublicKey to serialize it.
+func UnmarshalXWingPublicKey(data []byte) (publicKey kem.PublicKey, unmarshalError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			unmarshalError = newPanicError("UnmarshalXWingPublicKey", recoveredPanic)
+		}
+	}()
+	return xwing.Scheme().UnmarshalBinaryPublicKey(data)
+}
+
+// UnmarshalXWingPrivateKey deserializes bytes into an X-Wing private key. Use MarshalPrivateKey to serialize it.
+func UnmarshalXWingPrivateKey(data []byte) (privateKey kem.PrivateKey, unmarshalError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			unmarshalError = newPanicError("UnmarshalXWingPrivateKey", recoveredPanic)
+		}
+	}()
+	return xwing.Scheme().UnmarshalBinaryPrivateKey(data)
+}
+
+// XWingEncapsulate encapsulates a shared secret to an X-Wing public key.
+func XWingEncapsulate(publicKey kem.PublicKey) (ciphertext []byte, sharedSecret []byte, encapsulateError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			encapsulateError = newPanicError("XWingEncapsulate", recoveredPanic)
+		}
+	}()
+	if publicKey == nil || publicKey.Scheme() != xwing.Scheme() {
+		return nil, nil, fmt.Errorf("%w: nil or non-X-Wing public key", ErrInvalidKey)
+	}
+	return xwing.Scheme().Encapsulate(publicKey)
+}
+
+// XWingEncapsulateDeterministic encapsulates a shared secret to an X-Wing public key from a seed of length XWingEncapsulationSeedSize (for KATs).
+func XWingEncapsulateDeterministic(publicKey kem.PublicKey, seed []byte) (ciphertext []byte, sharedSecret []byte, encapsulateError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			encapsulateError = newPanicError("XWingEncapsulateDeterministic", recoveredPanic)
+		}
+	}()
+	if publicKey == nil || publicKey.Scheme() != xwing.Scheme() {
+		return nil, nil, fmt.Errorf("%w: nil or non-X-Wing public key", ErrInvalidKey)
+	}
+	if len(seed) != XWingEncapsulationSeedSize {
+		return nil, nil, newSizeError(ErrInvalidSeedSize, "X-Wing encapsulation seed", len(seed), XWingEncapsulationSeedSize)
+	}
+	return xwing.Scheme().EncapsulateDeterministically(publicKey, seed)
+}
+
+// XWingDecapsulate decapsulates a shared secret from an X-Wing ciphertext.
+// Like ML-KEM, a well-formed but tampered ciphertext does not return an error; it yields an unrelated shared secret.
+func XWingDecapsulate(privateKey kem.PrivateKey, ciphertext []byte) (sharedSecret []byte, decapsulateError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			decapsulateError = newPanicError("XWingDecapsulate", recoveredPanic)
+		}
+	}()
+	if privateKey == nil || privateKey.Scheme() != xwing.Scheme() {
+		return nil, fmt.Errorf("%w: nil or non-X-Wing private key", ErrInvalidKey)
+	}
+	if len(ciphertext) != XWingCiphertextSize {
+		return nil, newSizeError(ErrInvalidCiphertext, "X-Wing ciphertext", len(ciphertext), XWingCiphertextSize)
+	}
+	return xwing.Scheme().Decapsulate(privateKey, ciphertext)
+}
@@ -0,0 +1,48 @@
+package pq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func BenchmarkGenerateXWingKeyPair(b *testing.B) {
+	for b.Loop() {
+		_, err := GenerateXWingKeyPair()
+		require.NoError(b, err, "Key pair generation should not error")
+	}
+}
+
+func BenchmarkXWingEncapsulate(b *testing.B) {
+	keyPair, err := GenerateXWingKeyPair()
+	require.NoError(b, err, "Key pair generation should not error")
+	b.ResetTimer()
+	for b.Loop() {
+		_, _, err := XWingEncapsulate(keyPair.PublicKey)
+		require.NoError(b, err, "Encapsulation should not error")
+	}
+}
+
+func BenchmarkXWingDecapsulate(b *testing.B) {
+	keyPair, err := GenerateXWingKeyPair()
+	require.NoError(b, err, "Key pair generation should not error")
+	ciphertext, _, err := XWingEncapsulate(keyPair.PublicKey)
+	require.NoError(b, err, "Encapsulation should not error")
+	b.ResetTimer()
+	for b.Loop() {
+		_, err := XWingDecapsulate(keyPair.PrivateKey, ciphertext)
+		require.NoError(b, err, "Decapsulation should not error")
+	}
+}
+
+func BenchmarkUnmarshalXWingPublicKey(b *testing.B) {
+	keyPair, err := GenerateXWingKeyPair()
+	require.NoError(b, err, "Key pair generation should not error")
+	publicKeyBytes, err := MarshalPublicKey(keyPair.PublicKey)
+	require.NoError(b, err, "Marshaling the public key should not error")
+	b.ResetTimer()
+	for b.Loop() {
+		_, err := UnmarshalXWingPublicKey(publicKeyBytes)
+		require.NoError(b, err, "Unmarshaling the public key should not error")
+	}
+}
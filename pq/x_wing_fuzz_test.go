@@ -0,0 +1,61 @@
+package pq
+
+import (
+	"bytes"
+	"testing"
+)
+
+func FuzzXWingDecapsulate(f *testing.F) {
+	keyPair, _ := GenerateXWingKeyPair()
+	ciphertext, _, _ := XWingEncapsulate(keyPair.PublicKey)
+	f.Add(ciphertext)
+	f.Fuzz(func(t *testing.T, fuzzedCiphertext []byte) {
+		sharedSecret, err := XWingDecapsulate(keyPair.PrivateKey, fuzzedCiphertext)
+		if err != nil {
+			if len(fuzzedCiphertext) == XWingCiphertextSize {
+				t.Fatalf("decapsulation of a well-sized ciphertext should not fail: %v", err)
+			}
+			return
+		}
+		if len(sharedSecret) != XWingSharedSecretSize {
+			t.Errorf("shared secret len = %d, want %d", len(sharedSecret), XWingSharedSecretSize)
+		}
+	})
+}
+
+func FuzzUnmarshalXWingPublicKey(f *testing.F) {
+	keyPair, _ := GenerateXWingKeyPair()
+	publicKeyBytes, _ := MarshalPublicKey(keyPair.PublicKey)
+	f.Add(publicKeyBytes)
+	f.Add([]byte{0x01})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		publicKey, err := UnmarshalXWingPublicKey(data)
+		if err != nil {
+			return
+		}
+		roundTrip, err := MarshalPublicKey(publicKey)
+		if err != nil || !bytes.Equal(roundTrip, data) {
+			t.Error("an accepted public key should marshal back to the same bytes")
+		}
+		if _, _, err := XWingEncapsulate(publicKey); err != nil {
+			t.Errorf("encapsulation should not fail for an accepted public key: %v", err)
+		}
+	})
+}
+
+func FuzzUnmarshalXWingPrivateKey(f *testing.F) {
+	keyPair, _ := GenerateXWingKeyPair()
+	privateKeyBytes, _ := MarshalPrivateKey(keyPair.PrivateKey)
+	f.Add(privateKeyBytes)
+	f.Add([]byte{0x01})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		privateKey, err := UnmarshalXWingPrivateKey(data)
+		if err != nil {
+			return
+		}
+		roundTrip, err := MarshalPrivateKey(privateKey)
+		if err != nil || !bytes.Equal(roundTrip, data) {
+			t.Error("an accepted private key should marshal back to the same bytes")
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package pq
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/sha3"
+	"testing"
+
+	"github.com/cloudflare/circl/kem/mlkem/mlkem768"
+	"github.com/stretchr/testify/require"
+)
+
+func TestXWingEncapsulateAndDecapsulate(t *testing.T) {
+	keyPair, err := GenerateXWingKeyPair()
+	require.NoError(t, err, "failed to generate X-Wing key pair")
+	require.NotNil(t, keyPair, "expected non-nil key pair")
+
+	ciphertext, sharedSecret, err := XWingEncapsulate(keyPair.PublicKey)
+	require.NoError(t, err, "failed to encapsulate")
+	require.Len(t, ciphertext, XWingCiphertextSize, "unexpected ciphertext size")
+	require.Len(t, sharedSecret, XWingSharedSecretSize, "unexpected shared secret size")
+
+	recoveredSecret, err := XWingDecapsulate(keyPair.PrivateKey, ciphertext)
+	require.NoError(t, err, "failed to decapsulate")
+	require.Equal(t, sharedSecret, recoveredSecret, "expected decapsulated shared secret to match")
+
+	publicKeyBytes, err := MarshalPublicKey(keyPair.PublicKey)
+	require.NoError(t, err, "failed to marshal public key")
+	publicKey, err := UnmarshalXWingPublicKey(publicKeyBytes)
+	require.NoError(t, err, "failed to unmarshal public key")
+	require.True(t, publicKey.Equal(keyPair.PublicKey), "expected unmarshalled public key to match")
+
+	privateKeyBytes, err := MarshalPrivateKey(keyPair.PrivateKey)
+	require.NoError(t, err, "failed to marshal private key")
+	require.Len(t, privateKeyBytes, XWingSeedSize, "expected the X-Wing private key to be its seed")
+	privateKey, err := UnmarshalXWingPrivateKey(privateKeyBytes)
+	require.NoError(t, err, "failed to unmarshal private key")
+	recoveredSecret, err = XWingDecapsulate(privateKey, ciphertext)
+	require.NoError(t, err, "failed to decapsulate with unmarshalled private key")
+	require.Equal(t, sharedSecret, recoveredSecret, "expected decapsulated shared secret to match")
+}
+
+// TestXWingMatchesSpecification recomputes X-Wing from its ML-KEM-768 and X25519 components, following
+// draft-connolly-cfrg-xwing-kem: expandDecapsulationKey, Encapsulate and the SHA3-256 combiner with the label "\.//^\".
+func TestXWingMatchesSpecification(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x42}, XWingSeedSize)
+	encapsulationSeed := make([]byte, XWingEncapsulationSeedSize)
+	for i := range encapsulationSeed {
+		encapsulationSeed[i] = byte(i)
+	}
+	keyPair, err := GenerateDeterministicXWingKeyPair(seed)
+	require.NoError(t, err, "failed to derive X-Wing key pair")
+	ciphertext, sharedSecret, err := XWingEncapsulateDeterministic(keyPair.PublicKey, encapsulationSeed)
+	require.NoError(t, err, "failed to encapsulate")
+
+	expanded := sha3.SumSHAKE256(seed, 96)
+	mlkemPublicKey, _ := mlkem768.NewKeyFromSeed(expanded[:64])
+	x25519PrivateKey, err := ecdh.X25519().NewPrivateKey(expanded[64:96])
+	require.NoError(t, err, "failed to create X25519 private key")
+	mlkemPublicKeyBytes, err := mlkemPublicKey.MarshalBinary()
+	require.NoError(t, err, "failed to marshal ML-KEM public key")
+	x25519PublicKeyBytes := x25519PrivateKey.PublicKey().Bytes()
+
+	publicKeyBytes, err := MarshalPublicKey(keyPair.PublicKey)
+	require.NoError(t, err, "failed to marshal public key")
+	require.Equal(t, append(append([]byte(nil), mlkemPublicKeyBytes...), x25519PublicKeyBytes...), publicKeyBytes, "expected pk = pk_M || pk_X")
+
+	mlkemCiphertext := make([]byte, mlkem768.CiphertextSize)
+	mlkemSharedSecret := make([]byte, mlkem768.SharedKeySize)
+	mlkemPublicKey.EncapsulateTo(mlkemCiphertext, mlkemSharedSecret, encapsulationSeed[:32])
+	ephemeralPrivateKey, err := ecdh.X25519().NewPrivateKey(encapsulationSeed[32:])
+	require.NoError(t, err, "failed to create ephemeral X25519 private key")
+	x25519SharedSecret, err := ephemeralPrivateKey.ECDH(x25519PrivateKey.PublicKey())
+	require.NoError(t, err, "failed to compute X25519 shared secret")
+	x25519Ciphertext := ephemeralPrivateKey.PublicKey().Bytes()
+	require.Equal(t, append(append([]byte(nil), mlkemCiphertext...), x25519Ciphertext...), ciphertext, "expected ct = ct_M || ct_X")
+
+	combiner := sha3.New256()
+	combiner.Write(mlkemSharedSecret)
+	combiner.Write(x25519SharedSecret)
+	combiner.Write(x25519Ciphertext)
+	combiner.Write(x25519PublicKeyBytes)
+	combiner.Write([]byte(`\.//^\`))
+	require.Equal(t, combiner.Sum(nil), sharedSecret, "expected ss = SHA3-256(ss_M || ss_X || ct_X || pk_X || XWingLabel)")
+}
+
+func TestXWingInvalidInput(t *testing.T) {
+	keyPair, err := GenerateXWingKeyPair()
+	require.NoError(t, err, "failed to generate X-Wing key pair")
+	mlkemKeyPair, err := GenerateMLKEMKeyPair(MLKEM768)
+	require.NoError(t, err, "failed to generate ML-KEM key pair")
+
+	_, err = GenerateDeterministicXWingKeyPair(make([]byte, XWingSeedSize-1))
+	require.Error(t, err, "expected error for short seed")
+
+	_, _, err = XWingEncapsulate(nil)
+	require.Error(t, err, "expected error for nil public key")
+	_, _, err = XWingEncapsulate(mlkemKeyPair.PublicKey)
+	require.Error(t, err, "expected error for an ML-KEM-768 public key")
+	_, _, err = XWingEncapsulateDeterministic(keyPair.PublicKey, make([]byte, XWingEncapsulationSeedSize+1))
+	require.Error(t, err, "expected error for long encapsulation seed")
+
+	ciphertext, sharedSecret, err := XWingEncapsulate(keyPair.PublicKey)
+	require.NoError(t, err, "failed to encapsulate")
+	_, err = XWingDecapsulate(mlkemKeyPair.PrivateKey, ciphertext)
+	require.Error(t, err, "expected error for an ML-KEM-768 private key")
+	_, err = XWingDecapsulate(keyPair.PrivateKey, ciphertext[1:])
+	require.Error(t, err, "expected error for truncated ciphertext")
+
+	ciphertext[0] ^= 0x01
+	recoveredSecret, err := XWingDecapsulate(keyPair.PrivateKey, ciphertext)
+	require.NoError(t, err, "expected implicit rejection for tampered ciphertext")
+	require.NotEqual(t, sharedSecret, recoveredSecret, "expected tampered ciphertext to yield a different shared secret")
+
+	_, err = UnmarshalXWingPublicKey(make([]byte, 10))
+	require.Error(t, err, "expected error for short public key")
+	_, err = UnmarshalXWingPrivateKey(make([]byte, 10))
+	require.Error(t, err, "expected error for short private key")
+}
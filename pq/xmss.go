@@ -0,0 +1,786 @@
+package pq
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha3"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"sync"
+)
+
+// XMSSParameterSet is an XMSS parameter set, identified by its RFC 8391 OID. An XMSS key can make 2^h one-time signatures.
+type XMSSParameterSet uint32
+
+// XMSS parameter sets with n = 32 and w = 16 (RFC 8391 Section 5.3). The SHAKE sets use SHAKE128.
+const (
+	XMSSSHA2_10_256  XMSSParameterSet = 0x01
+	XMSSSHA2_16_256  XMSSParameterSet = 0x02
+	XMSSSHA2_20_256  XMSSParameterSet = 0x03
+	XMSSSHAKE_10_256 XMSSParameterSet = 0x07
+	XMSSSHAKE_16_256 XMSSParameterSet = 0x08
+	XMSSSHAKE_20_256 XMSSParameterSet = 0x09
+)
+
+// XMSSParameterSets lists the supported XMSS parameter sets.
+var XMSSParameterSets = []XMSSParameterSet{
+	XMSSSHA2_10_256, XMSSSHA2_16_256, XMSSSHA2_20_256, XMSSSHAKE_10_256, XMSSSHAKE_16_256, XMSSSHAKE_20_256,
+}
+
+// XMSSMTParameterSet is an XMSS^MT parameter set, identified by its RFC 8391 OID. An XMSS^MT key is a hypertree of d
+// layers of XMSS trees of height h/d, and can make 2^h one-time signatures.
+type XMSSMTParameterSet uint32
+
+// XMSS^MT parameter sets with n = 32 and w = 16 (RFC 8391 Section 5.4). The names give the total height h and the layers d.
+const (
+	XMSSMTSHA2_20_2_256   XMSSMTParameterSet = 0x01
+	XMSSMTSHA2_20_4_256   XMSSMTParameterSet = 0x02
+	XMSSMTSHA2_40_2_256   XMSSMTParameterSet = 0x03
+	XMSSMTSHA2_40_4_256   XMSSMTParameterSet = 0x04
+	XMSSMTSHA2_40_8_256   XMSSMTParameterSet = 0x05
+	XMSSMTSHA2_60_3_256   XMSSMTParameterSet = 0x06
+	XMSSMTSHA2_60_6_256   XMSSMTParameterSet = 0x07
+	XMSSMTSHA2_60_12_256  XMSSMTParameterSet = 0x08
+	XMSSMTSHAKE_20_2_256  XMSSMTParameterSet = 0x11
+	XMSSMTSHAKE_20_4_256  XMSSMTParameterSet = 0x12
+	XMSSMTSHAKE_40_2_256  XMSSMTParameterSet = 0x13
+	XMSSMTSHAKE_40_4_256  XMSSMTParameterSet = 0x14
+	XMSSMTSHAKE_40_8_256  XMSSMTParameterSet = 0x15
+	XMSSMTSHAKE_60_3_256  XMSSMTParameterSet = 0x16
+	XMSSMTSHAKE_60_6_256  XMSSMTParameterSet = 0x17
+	XMSSMTSHAKE_60_12_256 XMSSMTParameterSet = 0x18
+)
+
+// XMSSMTParameterSets lists the supported XMSS^MT parameter sets.
+var XMSSMTParameterSets = []XMSSMTParameterSet{
+	XMSSMTSHA2_20_2_256, XMSSMTSHA2_20_4_256, XMSSMTSHA2_40_2_256, XMSSMTSHA2_40_4_256,
+	XMSSMTSHA2_40_8_256, XMSSMTSHA2_60_3_256, XMSSMTSHA2_60_6_256, XMSSMTSHA2_60_12_256,
+	XMSSMTSHAKE_20_2_256, XMSSMTSHAKE_20_4_256, XMSSMTSHAKE_40_2_256, XMSSMTSHAKE_40_4_256,
+	XMSSMTSHAKE_40_8_256, XMSSMTSHAKE_60_3_256, XMSSMTSHAKE_60_6_256, XMSSMTSHAKE_60_12_256,
+}
+
+// XMSSMaxTreeHeight is the highest XMSS tree that key generation and signing accept, h for XMSS and h/d for XMSS^MT.
+// The signer holds every node of the current tree of each layer in memory and computes 2^(h/d) WOTS+ public keys to
+// build one, so XMSS-*_20_256, XMSSMT-*_40/2_256 and XMSSMT-*_60/3_256 are rejected. Verification accepts every height.
+const XMSSMaxTreeHeight = 16
+
+// XMSS and WOTS+ constants for n = 32 and w = 16 (RFC 8391 Sections 3.1.1 and 5).
+const (
+	xmssN            = 32
+	xmssOIDSize      = 4
+	wotsLogW         = 4
+	wotsW            = 1 << wotsLogW
+	wotsLen1         = 8 * xmssN / wotsLogW
+	wotsLen2         = 3
+	wotsLen          = wotsLen1 + wotsLen2
+	xmssAddressSize  = 32
+	xmssPaddingF     = 0
+	xmssPaddingH     = 1
+	xmssPaddingHMsg  = 2
+	xmssPaddingPRF   = 3
+	xmssPaddingKeyGn = 4 // PRF_keygen of NIST SP 800-208 Section 7.2.1
+)
+
+// xmssParameters are the parameters of an XMSS or XMSS^MT parameter set.
+type xmssParameters struct {
+	name      string
+	oid       uint32
+	shake     bool
+	height    int
+	layers    int
+	multiTree bool
+}
+
+// treeHeight returns the height h/d of each XMSS tree.
+func (parameters xmssParameters) treeHeight() int {
+	return parameters.height / parameters.layers
+}
+
+// indexSize returns the size of the signature index: 4 bytes for XMSS, ceil(h / 8) bytes for XMSS^MT.
+func (parameters xmssParameters) indexSize() int {
+	if !parameters.multiTree {
+		return 4
+	}
+	return (parameters.height + 7) / 8
+}
+
+// signatureSize returns the size of a signature: idx_sig || r || d * (sig_ots || auth).
+func (parameters xmssParameters) signatureSize() int {
+	return parameters.indexSize() + xmssN + parameters.layers*(wotsLen+parameters.treeHeight())*xmssN
+}
+
+// publicKeySize returns the size of a public key: OID || root || SEED.
+func (parameters xmssParameters) publicKeySize() int {
+	return xmssOIDSize + 2*xmssN
+}
+
+// privateKeySize returns the size of a gopq private key: OID || SK_SEED || SK_PRF || PUB_SEED || root.
+func (parameters xmssParameters) privateKeySize() int {
+	return xmssOIDSize + 4*xmssN
+}
+
+// maxSignatures returns 2^h.
+func (parameters xmssParameters) maxSignatures() uint64 {
+	return uint64(1) << parameters.height
+}
+
+// parameters returns the parameters of the XMSS parameter set.
+func (parameterSet XMSSParameterSet) parameters() (xmssParameters, error) {
+	heights := map[XMSSParameterSet]int{
+		XMSSSHA2_10_256: 10, XMSSSHA2_16_256: 16, XMSSSHA2_20_256: 20,
+		XMSSSHAKE_10_256: 10, XMSSSHAKE_16_256: 16, XMSSSHAKE_20_256: 20,
+	}
+	height, ok := heights[parameterSet]
+	if !ok {
+		return xmssParameters{}, fmt.Errorf("unsupported XMSS parameter set: %d", uint32(parameterSet))
+	}
+	shake := parameterSet >= XMSSSHAKE_10_256
+	hashName := "SHA2"
+	if shake {
+		hashName = "SHAKE"
+	}
+	return xmssParameters{
+		name:   fmt.Sprintf("XMSS-%s_%d_256", hashName, height),
+		oid:    uint32(parameterSet),
+		shake:  shake,
+		height: height,
+		layers: 1,
+	}, nil
+}
+
+// String returns the RFC 8391 name of the parameter set, for example "XMSS-SHA2_10_256".
+func (parameterSet XMSSParameterSet) String() string {
+	parameters, err := parameterSet.parameters()
+	if err != nil {
+		return fmt.Sprintf("XMSSParameterSet(%d)", uint32(parameterSet))
+	}
+	return parameters.name
+}
+
+// parameters returns the parameters of the XMSS^MT parameter set.
+func (parameterSet XMSSMTParameterSet) parameters() (xmssParameters, error) {
+	shapes := [...]struct{ height, layers int }{{20, 2}, {20, 4}, {40, 2}, {40, 4}, {40, 8}, {60, 3}, {60, 6}, {60, 12}}
+	var offset XMSSMTParameterSet
+	shake := false
+	switch {
+	case parameterSet >= XMSSMTSHA2_20_2_256 && parameterSet <= XMSSMTSHA2_60_12_256:
+		offset = parameterSet - XMSSMTSHA2_20_2_256
+	case parameterSet >= XMSSMTSHAKE_20_2_256 && parameterSet <= XMSSMTSHAKE_60_12_256:
+		offset, shake = parameterSet-XMSSMTSHAKE_20_2_256, true
+	default:
+		return xmssParameters{}, fmt.Errorf("unsupported XMSS^MT parameter set: %d", uint32(parameterSet))
+	}
+	hashName := "SHA2"
+	if shake {
+		hashName = "SHAKE"
+	}
+	shape := shapes[offset]
+	return xmssParameters{
+		name:      fmt.Sprintf("XMSSMT-%s_%d/%d_256", hashName, shape.height, shape.layers),
+		oid:       uint32(parameterSet),
+		shake:     shake,
+		height:    shape.height,
+		layers:    shape.layers,
+		multiTree: true,
+	}, nil
+}
+
+// String returns the RFC 8391 name of the parameter set, for example "XMSSMT-SHA2_20/4_256".
+func (parameterSet XMSSMTParameterSet) String() string {
+	parameters, err := parameterSet.parameters()
+	if err != nil {
+		return fmt.Sprintf("XMSSMTParameterSet(%d)", uint32(parameterSet))
+	}
+	return parameters.name
+}
+
+// xmssAddress is the 32-byte hash function address ADRS of RFC 8391 Section 2.5.
+type xmssAddress [xmssAddressSize]byte
+
+// Address types.
+const (
+	xmssAddressOTS      = 0
+	xmssAddressLTree    = 1
+	xmssAddressHashTree = 2
+)
+
+func (address *xmssAddress) setLayer(layer uint32) {
+	binary.BigEndian.PutUint32(address[0:], layer)
+}
+
+func (address *xmssAddress) setTree(tree uint64) {
+	binary.BigEndian.PutUint64(address[4:], tree)
+}
+
+// setType sets the address type and zeroes the words that depend on it.
+func (address *xmssAddress) setType(addressType uint32) {
+	binary.BigEndian.PutUint32(address[12:], addressType)
+	clear(address[16:])
+}
+
+// setWord sets word 4 to 7: the OTS, L-tree or padding word, the chain or tree height, the hash or tree index, and keyAndMask.
+func (address *xmssAddress) setWord(word int, value uint32) {
+	binary.BigEndian.PutUint32(address[4*word:], value)
+}
+
+// Words of the type-specific part of an address.
+const (
+	xmssWordOTS        = 4 // OTS address or L-tree address
+	xmssWordChain      = 5 // chain address or tree height
+	xmssWordHash       = 6 // hash address or tree index
+	xmssWordKeyAndMask = 7
+)
+
+// xmssHash is the tweakable hash function core: SHA2-256 or SHAKE128 with 256-bit output over toByte(padding, 32) || key || message.
+type xmssHash struct {
+	shake bool
+}
+
+func (hash xmssHash) sum(padding byte, parts ...[]byte) []byte {
+	var prefix [xmssN]byte
+	prefix[xmssN-1] = padding
+	if hash.shake {
+		shake := sha3.NewSHAKE128()
+		_, _ = shake.Write(prefix[:])
+		for _, part := range parts {
+			_, _ = shake.Write(part)
+		}
+		output := make([]byte, xmssN)
+		_, _ = shake.Read(output)
+		return output
+	}
+	digest := sha256.New()
+	_, _ = digest.Write(prefix[:])
+	for _, part := range parts {
+		_, _ = digest.Write(part)
+	}
+	return digest.Sum(nil)
+}
+
+// prf returns PRF(key, address).
+func (hash xmssHash) prf(key []byte, address *xmssAddress) []byte {
+	return hash.sum(xmssPaddingPRF, key, address[:])
+}
+
+// chainStep returns F(KEY, value XOR BM) for the current hash address (RFC 8391 Algorithm 2).
+func (hash xmssHash) chainStep(value []byte, publicSeed []byte, address *xmssAddress) []byte {
+	address.setWord(xmssWordKeyAndMask, 0)
+	key := hash.prf(publicSeed, address)
+	address.setWord(xmssWordKeyAndMask, 1)
+	bitmask := hash.prf(publicSeed, address)
+	subtle.XORBytes(bitmask, value, bitmask)
+	return hash.sum(xmssPaddingF, key, bitmask)
+}
+
+// randomizedHash returns RAND_HASH(left, right, SEED, ADRS) (RFC 8391 Algorithm 7).
+func (hash xmssHash) randomizedHash(left []byte, right []byte, publicSeed []byte, address *xmssAddress) []byte {
+	address.setWord(xmssWordKeyAndMask, 0)
+	key := hash.prf(publicSeed, address)
+	address.setWord(xmssWordKeyAndMask, 1)
+	leftMask := hash.prf(publicSeed, address)
+	address.setWord(xmssWordKeyAndMask, 2)
+	rightMask := hash.prf(publicSeed, address)
+	subtle.XORBytes(leftMask, left, leftMask)
+	subtle.XORBytes(rightMask, right, rightMask)
+	return hash.sum(xmssPaddingH, key, leftMask, rightMask)
+}
+
+// chain applies steps iterations of the WOTS+ chaining function to value, starting at iteration start.
+func (hash xmssHash) chain(value []byte, start int, steps int, publicSeed []byte, address *xmssAddress) []byte {
+	for i := start; i < start+steps; i++ {
+		address.setWord(xmssWordHash, uint32(i))
+		value = hash.chainStep(value, publicSeed, address)
+	}
+	return value
+}
+
+// wotsDigits returns the base-w digits of the message followed by those of its checksum (RFC 8391 Algorithm 5).
+func wotsDigits(message []byte) []int {
+	digits := make([]int, 0, wotsLen)
+	for _, messageByte := range message {
+		digits = append(digits, int(messageByte>>4), int(messageByte&0x0f))
+	}
+	checksum := 0
+	for _, digit := range digits {
+		checksum += wotsW - 1 - digit
+	}
+	// The checksum is shifted left to fill len2 * lg(w) = 12 bits of two bytes, and its first three digits are used.
+	checksum <<= 4
+	return append(digits, checksum>>12&0x0f, checksum>>8&0x0f, checksum>>4&0x0f)
+}
+
+// wotsSecretKey returns the i-th WOTS+ secret key chain start PRF_keygen(SK_SEED, PUB_SEED || ADRS) (NIST SP 800-208 Section 7.2.1).
+func (hash xmssHash) wotsSecretKey(secretSeed []byte, publicSeed []byte, address *xmssAddress, i int) []byte {
+	address.setWord(xmssWordChain, uint32(i))
+	address.setWord(xmssWordHash, 0)
+	address.setWord(xmssWordKeyAndMask, 0)
+	return hash.sum(xmssPaddingKeyGn, secretSeed, publicSeed, address[:])
+}
+
+// leaf returns the L-tree compressed WOTS+ public key of the leaf at the OTS address of address (RFC 8391 Algorithms 4 and 8).
+func (hash xmssHash) leaf(secretSeed []byte, publicSeed []byte, layer uint32, tree uint64, leafIndex uint32) []byte {
+	var address xmssAddress
+	address.setLayer(layer)
+	address.setTree(tree)
+	address.setType(xmssAddressOTS)
+	address.setWord(xmssWordOTS, leafIndex)
+	publicKey := make([][]byte, wotsLen)
+	for i := range publicKey {
+		secretKey := hash.wotsSecretKey(secretSeed, publicSeed, &address, i)
+		publicKey[i] = hash.chain(secretKey, 0, wotsW-1, publicSeed, &address)
+	}
+	return hash.lTree(publicKey, publicSeed, layer, tree, leafIndex)
+}
+
+// lTree compresses a WOTS+ public key into a single node (RFC 8391 Algorithm 8).
+func (hash xmssHash) lTree(publicKey [][]byte, publicSeed []byte, layer uint32, tree uint64, leafIndex uint32) []byte {
+	var address xmssAddress
+	address.setLayer(layer)
+	address.setTree(tree)
+	address.setType(xmssAddressLTree)
+	address.setWord(xmssWordOTS, leafIndex)
+	for length, height := len(publicKey), 0; length > 1; height++ {
+		address.setWord(xmssWordChain, uint32(height))
+		for i := 0; i < length/2; i++ {
+			address.setWord(xmssWordHash, uint32(i))
+			publicKey[i] = hash.randomizedHash(publicKey[2*i], publicKey[2*i+1], publicSeed, &address)
+		}
+		if length%2 == 1 {
+			publicKey[length/2] = publicKey[length-1]
+		}
+		length = (length + 1) / 2
+	}
+	return publicKey[0]
+}
+
+// xmssTree is one XMSS tree of a key with all its nodes, in heap order: node 1 is the root and the node at height k and
+// index j is node 2^(treeHeight-k) + j.
+type xmssTree struct {
+	layer uint32
+	tree  uint64
+	nodes [][]byte
+}
+
+// newXMSSTree computes the XMSS tree at the given layer and tree address; this costs 2^(h/d) WOTS+ key generations.
+func newXMSSTree(parameters xmssParameters, secretSeed []byte, publicSeed []byte, layer uint32, tree uint64) *xmssTree {
+	hash := xmssHash{shake: parameters.shake}
+	treeHeight := parameters.treeHeight()
+	leafCount := 1 << treeHeight
+	nodes := make([][]byte, 2*leafCount)
+	for leafIndex := 0; leafIndex < leafCount; leafIndex++ {
+		nodes[leafCount+leafIndex] = hash.leaf(secretSeed, publicSeed, layer, tree, uint32(leafIndex))
+	}
+	var address xmssAddress
+	address.setLayer(layer)
+	address.setTree(tree)
+	address.setType(xmssAddressHashTree)
+	for height := 0; height < treeHeight; height++ {
+		address.setWord(xmssWordChain, uint32(height))
+		first := leafCount >> (height + 1)
+		for index := 0; index < first; index++ {
+			address.setWord(xmssWordHash, uint32(index))
+			parent := first + index
+			nodes[parent] = hash.randomizedHash(nodes[2*parent], nodes[2*parent+1], publicSeed, &address)
+		}
+	}
+	return &xmssTree{layer: layer, tree: tree, nodes: nodes}
+}
+
+// sign returns the WOTS+ signature of message by leaf leafIndex followed by the authentication path of the leaf.
+func (tree *xmssTree) sign(parameters xmssParameters, secretSeed []byte, publicSeed []byte, leafIndex uint32, message []byte) []byte {
+	hash := xmssHash{shake: parameters.shake}
+	var address xmssAddress
+	address.setLayer(tree.layer)
+	address.setTree(tree.tree)
+	address.setType(xmssAddressOTS)
+	address.setWord(xmssWordOTS, leafIndex)
+	signature := make([]byte, 0, (wotsLen+parameters.treeHeight())*xmssN)
+	for i, digit := range wotsDigits(message) {
+		secretKey := hash.wotsSecretKey(secretSeed, publicSeed, &address, i)
+		signature = append(signature, hash.chain(secretKey, 0, digit, publicSeed, &address)...)
+	}
+	nodeNumber := 1<<parameters.treeHeight() + int(leafIndex)
+	for height := 0; height < parameters.treeHeight(); height++ {
+		signature = append(signature, tree.nodes[(nodeNumber>>height)^1]...)
+	}
+	return signature
+}
+
+// xmssRootFromSignature computes the root of the tree at the given layer and tree address from a WOTS+ signature of
+// message and the authentication path of leaf leafIndex (RFC 8391 Algorithm 13).
+func xmssRootFromSignature(parameters xmssParameters, publicSeed []byte, layer uint32, tree uint64, leafIndex uint32, signature []byte, message []byte) []byte {
+	hash := xmssHash{shake: parameters.shake}
+	var address xmssAddress
+	address.setLayer(layer)
+	address.setTree(tree)
+	address.setType(xmssAddressOTS)
+	address.setWord(xmssWordOTS, leafIndex)
+	publicKey := make([][]byte, wotsLen)
+	for i, digit := range wotsDigits(message) {
+		address.setWord(xmssWordChain, uint32(i))
+		publicKey[i] = hash.chain(signature[i*xmssN:(i+1)*xmssN], digit, wotsW-1-digit, publicSeed, &address)
+	}
+	node := hash.lTree(publicKey, publicSeed, layer, tree, leafIndex)
+	authenticationPath := signature[wotsLen*xmssN:]
+	address.setType(xmssAddressHashTree)
+	index := leafIndex
+	for height := 0; height < parameters.treeHeight(); height++ {
+		address.setWord(xmssWordChain, uint32(height))
+		address.setWord(xmssWordHash, index>>1)
+		sibling := authenticationPath[height*xmssN : (height+1)*xmssN]
+		if index%2 == 0 {
+			node = hash.randomizedHash(node, sibling, publicSeed, &address)
+		} else {
+			node = hash.randomizedHash(sibling, node, publicSeed, &address)
+		}
+		index >>= 1
+	}
+	return node
+}
+
+// xmssMessageHash returns H_msg(r || root || toByte(idx, n), M) (RFC 8391 Algorithm 12).
+func xmssMessageHash(parameters xmssParameters, randomness []byte, root []byte, index uint64, message []byte) []byte {
+	var indexBytes [xmssN]byte
+	binary.BigEndian.PutUint64(indexBytes[xmssN-8:], index)
+	return xmssHash{shake: parameters.shake}.sum(xmssPaddingHMsg, randomness, root, indexBytes[:], message)
+}
+
+// xmssMessageRandomness returns r = PRF(SK_PRF, toByte(idx, 32)).
+func xmssMessageRandomness(parameters xmssParameters, secretPRF []byte, index uint64) []byte {
+	var indexBytes [32]byte
+	binary.BigEndian.PutUint64(indexBytes[24:], index)
+	return xmssHash{shake: parameters.shake}.sum(xmssPaddingPRF, secretPRF, indexBytes[:])
+}
+
+// xmssPrivateKey is a parsed XMSS or XMSS^MT private key.
+type xmssPrivateKey struct {
+	parameters xmssParameters
+	secretSeed []byte
+	secretPRF  []byte
+	publicSeed []byte
+	root       []byte
+}
+
+// publicKey returns OID || root || PUB_SEED.
+func (privateKey *xmssPrivateKey) publicKey() []byte {
+	publicKey := binary.BigEndian.AppendUint32(nil, privateKey.parameters.oid)
+	publicKey = append(publicKey, privateKey.root...)
+	return append(publicKey, privateKey.publicSeed...)
+}
+
+// bytes returns OID || SK_SEED || SK_PRF || PUB_SEED || root.
+func (privateKey *xmssPrivateKey) bytes() []byte {
+	privateKeyBytes := binary.BigEndian.AppendUint32(nil, privateKey.parameters.oid)
+	privateKeyBytes = append(privateKeyBytes, privateKey.secretSeed...)
+	privateKeyBytes = append(privateKeyBytes, privateKey.secretPRF...)
+	privateKeyBytes = append(privateKeyBytes, privateKey.publicSeed...)
+	return append(privateKeyBytes, privateKey.root...)
+}
+
+// checkTreeHeight rejects parameters whose trees are higher than XMSSMaxTreeHeight.
+func (parameters xmssParameters) checkTreeHeight() error {
+	if parameters.treeHeight() > XMSSMaxTreeHeight {
+		return fmt.Errorf("%w: %s has trees of height %d, maximum is %d", ErrUnsupportedTreeHeight, parameters.name, parameters.treeHeight(), XMSSMaxTreeHeight)
+	}
+	return nil
+}
+
+// deriveXMSSKey derives the key with seed SK_SEED || SK_PRF || PUB_SEED, and returns its top tree.
+func deriveXMSSKey(parameters xmssParameters, seed []byte) (*xmssPrivateKey, *xmssTree, error) {
+	if err := parameters.checkTreeHeight(); err != nil {
+		return nil, nil, err
+	}
+	if len(seed) != 3*xmssN {
+		return nil, nil, newSizeError(ErrInvalidSeedSize, parameters.name+" seed", len(seed), 3*xmssN)
+	}
+	privateKey := &xmssPrivateKey{
+		parameters: parameters,
+		secretSeed: append([]byte(nil), seed[:xmssN]...),
+		secretPRF:  append([]byte(nil), seed[xmssN:2*xmssN]...),
+		publicSeed: append([]byte(nil), seed[2*xmssN:]...),
+	}
+	topTree := newXMSSTree(parameters, privateKey.secretSeed, privateKey.publicSeed, uint32(parameters.layers-1), 0)
+	privateKey.root = topTree.nodes[1]
+	return privateKey, topTree, nil
+}
+
+// unmarshalXMSSPrivateKey parses a private key, checking that its OID names a parameter set.
+func unmarshalXMSSPrivateKey(privateKeyBytes []byte, parametersOf func(uint32) (xmssParameters, error)) (*xmssPrivateKey, error) {
+	if len(privateKeyBytes) < xmssOIDSize {
+		return nil, newSizeError(ErrInvalidKeySize, "XMSS private key", len(privateKeyBytes), 0)
+	}
+	parameters, err := parametersOf(binary.BigEndian.Uint32(privateKeyBytes))
+	if err != nil {
+		return nil, err
+	}
+	if len(privateKeyBytes) != parameters.privateKeySize() {
+		return nil, newSizeError(ErrInvalidKeySize, parameters.name+" private key", len(privateKeyBytes), parameters.privateKeySize())
+	}
+	keyBytes := append([]byte(nil), privateKeyBytes[xmssOIDSize:]...)
+	return &xmssPrivateKey{
+		parameters: parameters,
+		secretSeed: keyBytes[:xmssN],
+		secretPRF:  keyBytes[xmssN : 2*xmssN],
+		publicSeed: keyBytes[2*xmssN : 3*xmssN],
+		root:       keyBytes[3*xmssN:],
+	}, nil
+}
+
+// xmssParametersByOID returns the XMSS parameters of an OID.
+func xmssParametersByOID(oid uint32) (xmssParameters, error) {
+	return XMSSParameterSet(oid).parameters()
+}
+
+// xmssMTParametersByOID returns the XMSS^MT parameters of an OID.
+func xmssMTParametersByOID(oid uint32) (xmssParameters, error) {
+	return XMSSMTParameterSet(oid).parameters()
+}
+
+// XMSSKeyPair represents an XMSS key pair (RFC 8391). PublicKey is the standard OID || root || SEED. PrivateKey is the
+// gopq encoding OID || SK_SEED || SK_PRF || PUB_SEED || root; it holds no index, which is kept by the
+// SignatureStateStore given to NewXMSSSigner.
+type XMSSKeyPair struct {
+	ParameterSet XMSSParameterSet
+	PublicKey    []byte
+	PrivateKey   []byte
+}
+
+// XMSSMTKeyPair represents an XMSS^MT key pair (RFC 8391), encoded like an XMSSKeyPair with an XMSS^MT OID.
+type XMSSMTKeyPair struct {
+	ParameterSet XMSSMTParameterSet
+	PublicKey    []byte
+	PrivateKey   []byte
+}
+
+// GenerateXMSSKeyPair generates a new XMSS key pair for the given parameter set from a fresh random seed. Key generation
+// computes the whole tree, which costs 2^h WOTS+ key generations.
+func GenerateXMSSKeyPair(parameterSet XMSSParameterSet) (keyPair *XMSSKeyPair, keyGenerationError error) {
+	if _, parametersError := parameterSet.parameters(); parametersError != nil {
+		return nil, parametersError
+	}
+	seed := make([]byte, 3*xmssN)
+	if _, randomError := rand.Read(seed); randomError != nil {
+		return nil, fmt.Errorf("rand.Read: %w", randomError)
+	}
+	return DeriveXMSSKeyPair(parameterSet, seed)
+}
+
+// DeriveXMSSKeyPair deterministically derives an XMSS key pair for the given parameter set from the 96-byte seed
+// SK_SEED || SK_PRF || PUB_SEED, with the WOTS+ keys derived as in NIST SP 800-208 and the XMSS reference implementation.
+func DeriveXMSSKeyPair(parameterSet XMSSParameterSet, seed []byte) (keyPair *XMSSKeyPair, keyGenerationError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			keyGenerationError = newPanicError("DeriveXMSSKeyPair", recoveredPanic)
+		}
+	}()
+	parameters, parametersError := parameterSet.parameters()
+	if parametersError != nil {
+		return nil, parametersError
+	}
+	privateKey, _, deriveError := deriveXMSSKey(parameters, seed)
+	if deriveError != nil {
+		return nil, deriveError
+	}
+	Logger().Debug("DeriveXMSSKeyPair", "parameter_set", parameters.name)
+	return &XMSSKeyPair{ParameterSet: parameterSet, PublicKey: privateKey.publicKey(), PrivateKey: privateKey.bytes()}, nil
+}
+
+// GenerateXMSSMTKeyPair generates a new XMSS^MT key pair for the given parameter set from a fresh random seed. Key
+// generation computes the top tree, which costs 2^(h/d) WOTS+ key generations.
+func GenerateXMSSMTKeyPair(parameterSet XMSSMTParameterSet) (keyPair *XMSSMTKeyPair, keyGenerationError error) {
+	if _, parametersError := parameterSet.parameters(); parametersError != nil {
+		return nil, parametersError
+	}
+	seed := make([]byte, 3*xmssN)
+	if _, randomError := rand.Read(seed); randomError != nil {
+		return nil, fmt.Errorf("rand.Read: %w", randomError)
+	}
+	return DeriveXMSSMTKeyPair(parameterSet, seed)
+}
+
+// DeriveXMSSMTKeyPair deterministically derives an XMSS^MT key pair for the given parameter set from the 96-byte seed
+// SK_SEED || SK_PRF || PUB_SEED.
+func DeriveXMSSMTKeyPair(parameterSet XMSSMTParameterSet, seed []byte) (keyPair *XMSSMTKeyPair, keyGenerationError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			keyGenerationError = newPanicError("DeriveXMSSMTKeyPair", recoveredPanic)
+		}
+	}()
+	parameters, parametersError := parameterSet.parameters()
+	if parametersError != nil {
+		return nil, parametersError
+	}
+	privateKey, _, deriveError := deriveXMSSKey(parameters, seed)
+	if deriveError != nil {
+		return nil, deriveError
+	}
+	Logger().Debug("DeriveXMSSMTKeyPair", "parameter_set", parameters.name)
+	return &XMSSMTKeyPair{ParameterSet: parameterSet, PublicKey: privateKey.publicKey(), PrivateKey: privateKey.bytes()}, nil
+}
+
+// XMSSSigner signs messages with an XMSS or XMSS^MT private key. Every signature uses the next index reserved from its
+// SignatureStateStore, so a crash or a second signer on the same store never reuses a leaf. An XMSSSigner is safe for
+// concurrent use.
+//
+// The signer keeps the current tree of every layer in memory and computes a lower tree again when the signatures move
+// to its next tree. XMSS signatures are deterministic, so recomputing an upper layer after a restart signs the same root again.
+type XMSSSigner struct {
+	mu         sync.Mutex
+	privateKey *xmssPrivateKey
+	store      SignatureStateStore
+	trees      []*xmssTree
+}
+
+// NewXMSSSigner returns a signer for an XMSS private key that reserves indexes from store. The store is bound to the
+// public key, and a store that counts for another key is rejected with ErrStateMismatch. The signer computes the whole
+// tree first, which costs as much as key generation.
+func NewXMSSSigner(privateKeyBytes []byte, store SignatureStateStore) (*XMSSSigner, error) {
+	return newXMSSSigner(privateKeyBytes, store, xmssParametersByOID)
+}
+
+// NewXMSSMTSigner returns a signer for an XMSS^MT private key that reserves indexes from store. The store is bound to
+// the public key like in NewXMSSSigner.
+func NewXMSSMTSigner(privateKeyBytes []byte, store SignatureStateStore) (*XMSSSigner, error) {
+	return newXMSSSigner(privateKeyBytes, store, xmssMTParametersByOID)
+}
+
+func newXMSSSigner(privateKeyBytes []byte, store SignatureStateStore, parametersOf func(uint32) (xmssParameters, error)) (signer *XMSSSigner, signerError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			signerError = newPanicError("NewXMSSSigner", recoveredPanic)
+		}
+	}()
+	if store == nil {
+		return nil, fmt.Errorf("%w: XMSS signer requires a SignatureStateStore", ErrInvalidOptions)
+	}
+	privateKey, unmarshalError := unmarshalXMSSPrivateKey(privateKeyBytes, parametersOf)
+	if unmarshalError != nil {
+		return nil, unmarshalError
+	}
+	parameters := privateKey.parameters
+	if err := parameters.checkTreeHeight(); err != nil {
+		return nil, err
+	}
+	topTree := newXMSSTree(parameters, privateKey.secretSeed, privateKey.publicSeed, uint32(parameters.layers-1), 0)
+	if subtle.ConstantTimeCompare(topTree.nodes[1], privateKey.root) != 1 {
+		return nil, fmt.Errorf("invalid %s private key: root does not match the seeds", parameters.name)
+	}
+	if bindError := store.Bind(privateKey.publicKey()); bindError != nil {
+		return nil, bindError
+	}
+	signer = &XMSSSigner{privateKey: privateKey, store: store, trees: make([]*xmssTree, parameters.layers)}
+	signer.trees[parameters.layers-1] = topTree
+	return signer, nil
+}
+
+// PublicKey returns the public key OID || root || SEED of the signer.
+func (signer *XMSSSigner) PublicKey() []byte {
+	return signer.privateKey.publicKey()
+}
+
+// MaxSignatures returns the number of one-time signatures of the key, 2^h.
+func (signer *XMSSSigner) MaxSignatures() uint64 {
+	return signer.privateKey.parameters.maxSignatures()
+}
+
+// Sign reserves the next index and returns the XMSS or XMSS^MT signature of message (RFC 8391 Algorithms 11 and 15).
+// It returns ErrStateExhausted once every index has been used.
+func (signer *XMSSSigner) Sign(messageBytes []byte) (signatureBytes []byte, signError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			signError = newPanicError("XMSSSigner.Sign", recoveredPanic)
+		}
+	}()
+	privateKey := signer.privateKey
+	parameters := privateKey.parameters
+	index, reserveError := signer.store.Reserve(1, parameters.maxSignatures())
+	if reserveError != nil {
+		return nil, reserveError
+	}
+	signer.mu.Lock()
+	defer signer.mu.Unlock()
+
+	randomness := xmssMessageRandomness(parameters, privateKey.secretPRF, index)
+	indexBytes := binary.BigEndian.AppendUint64(nil, index)
+	signatureBytes = append(signatureBytes, indexBytes[8-parameters.indexSize():]...)
+	signatureBytes = append(signatureBytes, randomness...)
+	message := xmssMessageHash(parameters, randomness, privateKey.root, index, messageBytes)
+	treeHeight := parameters.treeHeight()
+	tree := index
+	for layer := 0; layer < parameters.layers; layer++ {
+		leafIndex := uint32(tree & (uint64(1)<<treeHeight - 1))
+		tree >>= treeHeight
+		cachedTree := signer.trees[layer]
+		if cachedTree == nil || cachedTree.tree != tree {
+			cachedTree = newXMSSTree(parameters, privateKey.secretSeed, privateKey.publicSeed, uint32(layer), tree)
+			signer.trees[layer] = cachedTree
+		}
+		signatureBytes = append(signatureBytes, cachedTree.sign(parameters, privateKey.secretSeed, privateKey.publicSeed, leafIndex, message)...)
+		message = cachedTree.nodes[1]
+	}
+	Logger().Debug("XMSSSign", "parameter_set", parameters.name, "index", index, "signature_size", len(signatureBytes))
+	return signatureBytes, nil
+}
+
+// verifyXMSS verifies an XMSS or XMSS^MT signature (RFC 8391 Algorithms 14 and 16).
+func verifyXMSS(publicKeyBytes []byte, messageBytes []byte, signatureBytes []byte, parametersOf func(uint32) (xmssParameters, error)) (bool, error) {
+	if len(publicKeyBytes) < xmssOIDSize {
+		return false, newSizeError(ErrInvalidKeySize, "XMSS public key", len(publicKeyBytes), 0)
+	}
+	parameters, err := parametersOf(binary.BigEndian.Uint32(publicKeyBytes))
+	if err != nil {
+		return false, err
+	}
+	if len(publicKeyBytes) != parameters.publicKeySize() {
+		return false, newSizeError(ErrInvalidKeySize, parameters.name+" public key", len(publicKeyBytes), parameters.publicKeySize())
+	}
+	root := publicKeyBytes[xmssOIDSize : xmssOIDSize+xmssN]
+	publicSeed := publicKeyBytes[xmssOIDSize+xmssN:]
+	if len(signatureBytes) != parameters.signatureSize() {
+		return false, nil
+	}
+	var indexBytes [8]byte
+	copy(indexBytes[8-parameters.indexSize():], signatureBytes)
+	index := binary.BigEndian.Uint64(indexBytes[:])
+	if index >= parameters.maxSignatures() {
+		return false, nil
+	}
+	signature := signatureBytes[parameters.indexSize():]
+	node := xmssMessageHash(parameters, signature[:xmssN], root, index, messageBytes)
+	signature = signature[xmssN:]
+	layerSignatureSize := (wotsLen + parameters.treeHeight()) * xmssN
+	treeHeight := parameters.treeHeight()
+	tree := index
+	for layer := 0; layer < parameters.layers; layer++ {
+		leafIndex := uint32(tree & (uint64(1)<<treeHeight - 1))
+		tree >>= treeHeight
+		node = xmssRootFromSignature(parameters, publicSeed, uint32(layer), tree, leafIndex, signature[:layerSignatureSize], node)
+		signature = signature[layerSignatureSize:]
+	}
+	isSignatureValid := subtle.ConstantTimeCompare(node, root) == 1
+	Logger().Debug("XMSSVerify", "parameter_set", parameters.name, "valid", isSignatureValid)
+	return isSignatureValid, nil
+}
+
+// XMSSVerify verifies an XMSS signature against an XMSS public key. A malformed public key returns an error; a
+// malformed or invalid signature returns false.
+func XMSSVerify(publicKeyBytes []byte, messageBytes []byte, signatureBytes []byte) (isValid bool, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			verifyError = newPanicError("XMSSVerify", recoveredPanic)
+		}
+	}()
+	return verifyXMSS(publicKeyBytes, messageBytes, signatureBytes, xmssParametersByOID)
+}
+
+// XMSSMTVerify verifies an XMSS^MT signature against an XMSS^MT public key. A malformed public key returns an error; a
+// malformed or invalid signature returns false.
+func XMSSMTVerify(publicKeyBytes []byte, messageBytes []byte, signatureBytes []byte) (isValid bool, verifyError error) {
+	defer func() {
+		if recoveredPanic := recover(); recoveredPanic != nil {
+			verifyError = newPanicError("XMSSMTVerify", recoveredPanic)
+		}
+	}()
+	return verifyXMSS(publicKeyBytes, messageBytes, signatureBytes, xmssMTParametersByOID)
+}
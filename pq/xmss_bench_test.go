@@ -0,0 +1,45 @@
+package pq
+
+import "testing"
+
+func BenchmarkXMSSMTSign(b *testing.B) {
+	keyPair, err := GenerateXMSSMTKeyPair(XMSSMTSHA2_20_4_256)
+	if err != nil {
+		b.Fatalf("failed to generate XMSS^MT key pair: %v", err)
+	}
+	signer, err := NewXMSSMTSigner(keyPair.PrivateKey, &MemoryStateStore{})
+	if err != nil {
+		b.Fatalf("failed to create XMSS^MT signer: %v", err)
+	}
+	message := []byte("benchmark message")
+	b.ResetTimer()
+	for b.Loop() {
+		_, signErr := signer.Sign(message)
+		if signErr != nil {
+			b.Fatalf("signing failed: %v", signErr)
+		}
+	}
+}
+
+func BenchmarkXMSSMTVerify(b *testing.B) {
+	keyPair, err := GenerateXMSSMTKeyPair(XMSSMTSHA2_20_4_256)
+	if err != nil {
+		b.Fatalf("failed to generate XMSS^MT key pair: %v", err)
+	}
+	signer, err := NewXMSSMTSigner(keyPair.PrivateKey, &MemoryStateStore{})
+	if err != nil {
+		b.Fatalf("failed to create XMSS^MT signer: %v", err)
+	}
+	message := []byte("benchmark message")
+	signature, signErr := signer.Sign(message)
+	if signErr != nil {
+		b.Fatalf("signing failed: %v", signErr)
+	}
+	b.ResetTimer()
+	for b.Loop() {
+		_, verifyErr := XMSSMTVerify(keyPair.PublicKey, message, signature)
+		if verifyErr != nil {
+			b.Fatalf("verifying failed: %v", verifyErr)
+		}
+	}
+}
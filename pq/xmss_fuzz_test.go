@@ -0,0 +1,29 @@
+package pq
+
+import "testing"
+
+func FuzzXMSSMTVerifyArbitrarySignature(f *testing.F) {
+	keyPair, _ := GenerateXMSSMTKeyPair(XMSSMTSHA2_20_4_256)
+	signer, _ := NewXMSSMTSigner(keyPair.PrivateKey, &MemoryStateStore{})
+	validSignature, _ := signer.Sign([]byte("msg"))
+	f.Add([]byte("msg"), validSignature)
+	f.Fuzz(func(t *testing.T, msg []byte, signature []byte) {
+		isValid, verifyErr := XMSSMTVerify(keyPair.PublicKey, msg, signature)
+		if verifyErr != nil {
+			t.Fatalf("verifying must not error on a malformed signature: %v", verifyErr)
+		}
+		if isValid && (string(msg) != "msg" || string(signature) != string(validSignature)) {
+			t.Error("only the valid signature may verify")
+		}
+	})
+}
+
+func FuzzXMSSVerifyArbitraryPublicKey(f *testing.F) {
+	f.Add(make([]byte, 68), []byte("sig"))
+	f.Fuzz(func(t *testing.T, publicKey []byte, signature []byte) {
+		isValid, _ := XMSSVerify(publicKey, []byte("msg"), signature)
+		if isValid {
+			t.Error("an arbitrary signature must not verify")
+		}
+	})
+}
@@ -0,0 +1,227 @@
+package pq
+
+import (
+	"bytes"
+	"crypto/sha3"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// xmssReferenceVectors are the digests of the XMSS reference implementation in testdata/hbs.
+type xmssReferenceVectors struct {
+	Vectors []struct {
+		ParameterSet    string `json:"parameterSet"`
+		OID             uint32 `json:"oid"`
+		MultiTree       bool   `json:"multiTree"`
+		PublicKeyDigest string `json:"publicKeyDigest"`
+		SignatureDigest string `json:"signatureDigest"`
+	} `json:"vectors"`
+}
+
+// xmssReferenceSignature is a full signature of the XMSS reference implementation in testdata/hbs.
+type xmssReferenceSignature struct {
+	ParameterSet string `json:"parameterSet"`
+	Message      string `json:"message"`
+	PublicKey    string `json:"publicKey"`
+	Signature    string `json:"signature"`
+}
+
+// For TestXMSSReferenceVectors: key derivation and signing reproduce the public keys and signatures of the XMSS
+// reference implementation for XMSS and XMSS^MT, with SHA2-256 and SHAKE128.
+func TestXMSSReferenceVectors(t *testing.T) {
+	var vectors xmssReferenceVectors
+	readHBSVectors(t, "xmss-reference-vectors.json", &vectors)
+	require.NotEmpty(t, vectors.Vectors)
+	seed := make([]byte, 3*xmssN)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+	message := []byte{0x25}
+	for _, vector := range vectors.Vectors {
+		t.Run(vector.ParameterSet, func(t *testing.T) {
+			var publicKey, signature []byte
+			var valid bool
+			if vector.MultiTree {
+				keyPair, err := DeriveXMSSMTKeyPair(XMSSMTParameterSet(vector.OID), seed)
+				require.NoError(t, err)
+				require.Equal(t, vector.ParameterSet, keyPair.ParameterSet.String())
+				publicKey = keyPair.PublicKey
+				parameters, err := keyPair.ParameterSet.parameters()
+				require.NoError(t, err)
+				signer, err := NewXMSSMTSigner(keyPair.PrivateKey, &MemoryStateStore{next: parameters.maxSignatures() / 2})
+				require.NoError(t, err)
+				signature, err = signer.Sign(message)
+				require.NoError(t, err)
+				valid, err = XMSSMTVerify(publicKey, message, signature)
+				require.NoError(t, err)
+			} else {
+				keyPair, err := DeriveXMSSKeyPair(XMSSParameterSet(vector.OID), seed)
+				require.NoError(t, err)
+				require.Equal(t, vector.ParameterSet, keyPair.ParameterSet.String())
+				publicKey = keyPair.PublicKey
+				parameters, err := keyPair.ParameterSet.parameters()
+				require.NoError(t, err)
+				signer, err := NewXMSSSigner(keyPair.PrivateKey, &MemoryStateStore{next: parameters.maxSignatures() / 2})
+				require.NoError(t, err)
+				signature, err = signer.Sign(message)
+				require.NoError(t, err)
+				valid, err = XMSSVerify(publicKey, message, signature)
+				require.NoError(t, err)
+			}
+			require.Equal(t, mustDecodeHex(t, vector.PublicKeyDigest), sha3.SumSHAKE128(publicKey[4:], 10))
+			require.Equal(t, mustDecodeHex(t, vector.SignatureDigest), sha3.SumSHAKE128(signature, 10))
+			require.True(t, valid)
+		})
+	}
+}
+
+// For TestXMSSReferenceSignature: a full signature whose digests match the XMSS reference implementation verifies,
+// and is rejected after a one-bit flip of the signature or the message.
+func TestXMSSReferenceSignature(t *testing.T) {
+	var vector xmssReferenceSignature
+	readHBSVectors(t, "xmss-reference-signature.json", &vector)
+	var vectors xmssReferenceVectors
+	readHBSVectors(t, "xmss-reference-vectors.json", &vectors)
+	publicKey := mustDecodeHex(t, vector.PublicKey)
+	message := mustDecodeHex(t, vector.Message)
+	signature := mustDecodeHex(t, vector.Signature)
+	for _, digests := range vectors.Vectors {
+		if digests.ParameterSet == vector.ParameterSet {
+			require.Equal(t, mustDecodeHex(t, digests.PublicKeyDigest), sha3.SumSHAKE128(publicKey[4:], 10))
+			require.Equal(t, mustDecodeHex(t, digests.SignatureDigest), sha3.SumSHAKE128(signature, 10))
+		}
+	}
+
+	valid, err := XMSSVerify(publicKey, message, signature)
+	require.NoError(t, err)
+	require.True(t, valid)
+	signature[len(signature)/2] ^= 0x01
+	valid, err = XMSSVerify(publicKey, message, signature)
+	require.NoError(t, err)
+	require.False(t, valid, "a signature with a flipped bit must be rejected")
+	signature[len(signature)/2] ^= 0x01
+	message[0] ^= 0x01
+	valid, err = XMSSVerify(publicKey, message, signature)
+	require.NoError(t, err)
+	require.False(t, valid, "a signature over another message must be rejected")
+}
+
+// For TestXMSSParameterSets: names and sizes match RFC 8391 Section 5.
+func TestXMSSParameterSets(t *testing.T) {
+	signatureSizes := map[string]int{
+		XMSSSHA2_10_256.String():      2500,
+		XMSSSHAKE_20_256.String():     2820,
+		XMSSMTSHA2_20_2_256.String():  4963,
+		XMSSMTSHA2_60_12_256.String(): 27688,
+	}
+	for _, parameterSet := range XMSSParameterSets {
+		parameters, err := parameterSet.parameters()
+		require.NoError(t, err)
+		if size, ok := signatureSizes[parameters.name]; ok {
+			require.Equal(t, size, parameters.signatureSize(), parameters.name)
+		}
+	}
+	for _, parameterSet := range XMSSMTParameterSets {
+		parameters, err := parameterSet.parameters()
+		require.NoError(t, err)
+		require.Equal(t, 64, parameters.publicKeySize()-4)
+		if size, ok := signatureSizes[parameters.name]; ok {
+			require.Equal(t, size, parameters.signatureSize(), parameters.name)
+		}
+	}
+	require.Equal(t, "XMSSMT-SHAKE_60/12_256", XMSSMTSHAKE_60_12_256.String())
+	require.Equal(t, "XMSSParameterSet(4)", XMSSParameterSet(4).String())
+	require.Equal(t, "XMSSMTParameterSet(9)", XMSSMTParameterSet(9).String())
+}
+
+// For TestXMSSMTSignAndVerify: signatures verify across a change of the bottom tree, and tampered signatures, other
+// messages, other keys and the XMSS verifier are rejected.
+func TestXMSSMTSignAndVerify(t *testing.T) {
+	keyPair, err := GenerateXMSSMTKeyPair(XMSSMTSHAKE_20_4_256)
+	require.NoError(t, err)
+	signer, err := NewXMSSMTSigner(keyPair.PrivateKey, &MemoryStateStore{next: 30})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1)<<20, signer.MaxSignatures())
+	require.Equal(t, keyPair.PublicKey, signer.PublicKey())
+	otherKeyPair, err := GenerateXMSSMTKeyPair(XMSSMTSHAKE_20_4_256)
+	require.NoError(t, err)
+
+	message := []byte("firmware image")
+	for range 4 { // leaves 30 to 33 cross from the first to the second bottom tree
+		signature, signErr := signer.Sign(message)
+		require.NoError(t, signErr)
+		valid, verifyErr := XMSSMTVerify(keyPair.PublicKey, message, signature)
+		require.NoError(t, verifyErr)
+		require.True(t, valid)
+
+		valid, verifyErr = XMSSMTVerify(keyPair.PublicKey, []byte("other image"), signature)
+		require.NoError(t, verifyErr)
+		require.False(t, valid)
+		valid, verifyErr = XMSSMTVerify(otherKeyPair.PublicKey, message, signature)
+		require.NoError(t, verifyErr)
+		require.False(t, valid)
+		for _, offset := range []int{0, 5, len(signature) / 2, len(signature) - 1} {
+			tampered := bytes.Clone(signature)
+			tampered[offset] ^= 0x01
+			valid, verifyErr = XMSSMTVerify(keyPair.PublicKey, message, tampered)
+			require.NoError(t, verifyErr)
+			require.False(t, valid, "tampered byte %d", offset)
+		}
+		valid, verifyErr = XMSSMTVerify(keyPair.PublicKey, message, signature[1:])
+		require.NoError(t, verifyErr)
+		require.False(t, valid)
+	}
+}
+
+// For TestXMSSInvalidInput: unknown OIDs, wrong sizes, a corrupted private key and an out-of-range index are rejected.
+func TestXMSSInvalidInput(t *testing.T) {
+	_, err := GenerateXMSSKeyPair(XMSSParameterSet(4))
+	require.Error(t, err)
+	_, err = GenerateXMSSMTKeyPair(XMSSMTParameterSet(0))
+	require.Error(t, err)
+	_, err = DeriveXMSSMTKeyPair(XMSSMTSHA2_20_4_256, make([]byte, 64))
+	require.ErrorIs(t, err, ErrInvalidSeedSize)
+
+	keyPair, err := DeriveXMSSMTKeyPair(XMSSMTSHA2_20_4_256, make([]byte, 96))
+	require.NoError(t, err)
+	_, err = NewXMSSMTSigner(keyPair.PrivateKey, nil)
+	require.ErrorIs(t, err, ErrInvalidOptions)
+	_, err = NewXMSSMTSigner(keyPair.PrivateKey[:len(keyPair.PrivateKey)-1], &MemoryStateStore{})
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	corrupted := bytes.Clone(keyPair.PrivateKey)
+	corrupted[10] ^= 0x01
+	_, err = NewXMSSMTSigner(corrupted, &MemoryStateStore{})
+	require.Error(t, err, "a private key whose root does not match its seeds must be rejected")
+	_, err = XMSSMTVerify(keyPair.PublicKey[:len(keyPair.PublicKey)-1], nil, nil)
+	require.ErrorIs(t, err, ErrInvalidKeySize)
+	_, err = XMSSVerify([]byte{0, 0, 0, 4}, nil, nil)
+	require.Error(t, err)
+
+	signer, err := NewXMSSMTSigner(keyPair.PrivateKey, &MemoryStateStore{next: 1<<20 - 1})
+	require.NoError(t, err)
+	signature, err := signer.Sign([]byte("last"))
+	require.NoError(t, err)
+	_, err = signer.Sign([]byte("one too many"))
+	require.ErrorIs(t, err, ErrStateExhausted)
+	signature[0] |= 0x10 // index 2^20 + 2^20 - 1 is out of range
+	valid, err := XMSSMTVerify(keyPair.PublicKey, []byte("last"), signature)
+	require.NoError(t, err)
+	require.False(t, valid)
+}
+
+// For TestXMSSTreeHeightLimit: key generation and signing reject trees higher than XMSSMaxTreeHeight.
+func TestXMSSTreeHeightLimit(t *testing.T) {
+	for _, parameterSet := range []XMSSParameterSet{XMSSSHA2_20_256, XMSSSHAKE_20_256} {
+		_, err := GenerateXMSSKeyPair(parameterSet)
+		require.ErrorIs(t, err, ErrUnsupportedTreeHeight, "%v", parameterSet)
+	}
+	for _, parameterSet := range []XMSSMTParameterSet{XMSSMTSHA2_40_2_256, XMSSMTSHAKE_60_3_256} {
+		_, err := DeriveXMSSMTKeyPair(parameterSet, make([]byte, 96))
+		require.ErrorIs(t, err, ErrUnsupportedTreeHeight, "%v", parameterSet)
+	}
+
+	privateKey := append([]byte{0, 0, 0, byte(XMSSSHA2_20_256)}, make([]byte, 4*32)...)
+	_, err := NewXMSSSigner(privateKey, &MemoryStateStore{})
+	require.ErrorIs(t, err, ErrUnsupportedTreeHeight)
+}